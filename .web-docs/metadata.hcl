@@ -13,6 +13,11 @@ integration {
     name = "Docker"
     slug = "docker"
   }
+  component {
+    type = "builder"
+    name = "Docker Compose"
+    slug = "docker-compose"
+  }
   component {
     type = "post-processor"
     name = "Docker Import"
@@ -33,4 +38,9 @@ integration {
     name = "Docker Push"
     slug = "docker-push"
   }
+  component {
+    type = "provisioner"
+    name = "Docker Snapshot"
+    slug = "docker-snapshot"
+  }
 }
@@ -0,0 +1,117 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package dockercompose
+
+import (
+	"context"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-docker/builder/docker"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+)
+
+const BuilderId = "packer.docker-compose"
+
+// Builder brings up a compose project, provisions the container of one of
+// its services, commits it to an image and tears the project down.
+type Builder struct {
+	config Config
+	runner multistep.Runner
+}
+
+func (b *Builder) ConfigSpec() hcldec.ObjectSpec { return b.config.FlatMapstructure().HCL2Spec() }
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
+	warnings, errs := b.config.Prepare(raws...)
+	if errs != nil {
+		return nil, warnings, errs
+	}
+
+	return []string{
+		"ImageSha256",
+		"ImagePlatform",
+		"ImageID",
+		"ContainerID",
+	}, warnings, nil
+}
+
+func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	dockerConfig := b.config.dockerConfig()
+	driver, err := docker.NewDriver(docker.DriverCLI, b.config.Executable, "", docker.DockerHostConfig{},
+		docker.RegistryTLSConfig{}, docker.ProxyEnvConfig{}, &b.config.ctx, ui)
+	if err != nil {
+		return nil, err
+	}
+	if err := driver.Verify(); err != nil {
+		return nil, err
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", dockerConfig)
+	state.Put("compose_config", &b.config)
+	state.Put("driver", driver)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
+
+	steps := []multistep.Step{
+		&docker.StepDefaultGeneratedData{
+			GeneratedData: generatedData,
+		},
+		&docker.StepTempDir{},
+		&StepComposeUp{
+			GeneratedData: generatedData,
+		},
+		&communicator.StepConnect{
+			Config:    &b.config.Comm,
+			Host:      containerIP,
+			SSHConfig: b.config.Comm.SSHConfigFunc(),
+			CustomConnect: map[string]multistep.Step{
+				"docker": &docker.StepConnectDocker{},
+			},
+		},
+		&commonsteps.StepProvision{},
+		&commonsteps.StepCleanupTempKeys{
+			Comm: &b.config.Comm,
+		},
+		&docker.StepCommit{
+			GeneratedData: generatedData,
+		},
+	}
+
+	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(ctx, state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	// If it was cancelled, then just return
+	if _, ok := state.GetOk(multistep.StateCancelled); ok {
+		return nil, nil
+	}
+
+	// The artifact is the one of the docker builder, so that the docker
+	// post-processors accept it.
+	return &docker.ImportArtifact{
+		BuilderIdValue: docker.BuilderId,
+		Driver:         driver,
+		IdValue:        state.Get("image_id").(string),
+		StateData: map[string]interface{}{
+			"generated_data": state.Get("generated_data"),
+		},
+	}, nil
+}
+
+// containerIP returns the IP address of the container of the service, which
+// the ssh communicator connects to.
+func containerIP(state multistep.StateBag) (string, error) {
+	driver := state.Get("driver").(docker.Driver)
+	return driver.IPAddress(state.Get("container_id").(string))
+}
@@ -0,0 +1,144 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package dockercompose
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-docker/builder/docker"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+)
+
+// projectNameRe matches the names of the compose projects: lowercase
+// letters, digits, dashes and underscores, starting with a letter or a
+// digit.
+var projectNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	Comm                communicator.Config `mapstructure:",squash"`
+
+	// The compose files of the project, like `["compose.yaml"]`. Later
+	// files override the earlier ones, like with `docker compose --file`.
+	ComposeFiles []string `mapstructure:"compose_files" required:"true"`
+	// The service of the project whose container is provisioned and
+	// committed. It must keep running, like with a `command` of `sleep
+	// infinity`, for the provisioners to run in it.
+	Service string `mapstructure:"service" required:"true"`
+	// The name of the compose project. Defaults to a random name, so that
+	// concurrent builds don't share their containers.
+	ProjectName string `mapstructure:"project_name" required:"false"`
+	// The compose profiles to enable, like `docker compose --profile`.
+	Profiles []string `mapstructure:"profiles" required:"false"`
+	// The docker executable, whose `compose` subcommand runs the project.
+	// Defaults to `docker`.
+	Executable string `mapstructure:"docker_path" required:"false"`
+	// The directory of the container the files are uploaded to. Defaults
+	// to `/packer-files`.
+	ContainerDir string `mapstructure:"container_dir" required:"false"`
+	// The user the remote commands run as, like `docker exec --user`.
+	// Defaults to the user of the service.
+	ExecUser string `mapstructure:"exec_user" required:"false"`
+	// Set the author (e-mail) of the commit.
+	Author string `mapstructure:"author" required:"false"`
+	// Dockerfile instructions to add to the commit, with the same
+	// instructions as the `changes` of the docker builder, like `["CMD
+	// [\"nginx\", \"-g\", \"daemon off;\"]"]`.
+	Changes []string `mapstructure:"changes" required:"false"`
+	// Set a message for the commit.
+	Message string `mapstructure:"message" required:"false"`
+	// If true, the volumes of the project are kept when it is torn down,
+	// like caches shared between builds. Defaults to false, the volumes
+	// are removed with the project.
+	KeepVolumes bool `mapstructure:"keep_volumes" required:"false"`
+
+	ctx interpolate.Context
+}
+
+func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
+	err := config.Decode(c, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Executable == "" {
+		c.Executable = "docker"
+	}
+	if c.ContainerDir == "" {
+		c.ContainerDir = "/packer-files"
+	}
+	if c.ProjectName == "" {
+		c.ProjectName = "packer-" + strings.ToLower(uuid.TimeOrderedUUID()[:8])
+	}
+	if c.Comm.Type == "" {
+		c.Comm.Type = "docker"
+	}
+
+	var errs *packersdk.MultiError
+	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, es...)
+	}
+	if c.Comm.Type != "docker" && c.Comm.Type != "ssh" && c.Comm.Type != "none" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("the %s communicator is not supported, expected docker, ssh or none", c.Comm.Type))
+	}
+
+	if len(c.ComposeFiles) == 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`compose_files` is required"))
+	}
+	for _, f := range c.ComposeFiles {
+		if _, err := os.Stat(f); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("compose file %s not found: %s", f, err))
+		}
+	}
+	if c.Service == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`service` is required"))
+	}
+	if !projectNameRe.MatchString(c.ProjectName) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("%q is not a valid project name, expected lowercase letters, digits, "+
+			"dashes and underscores", c.ProjectName))
+	}
+	for _, change := range c.Changes {
+		if err := docker.ValidateChange(change); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, errs
+	}
+
+	return nil, nil
+}
+
+// dockerConfig returns the configuration of the docker builder the steps
+// shared with it run with.
+func (c *Config) dockerConfig() *docker.Config {
+	return &docker.Config{
+		PackerConfig:   c.PackerConfig,
+		Comm:           c.Comm,
+		Author:         c.Author,
+		Changes:        c.Changes,
+		Message:        c.Message,
+		Executable:     c.Executable,
+		ContainerDir:   c.ContainerDir,
+		ExecUser:       c.ExecUser,
+		DriverType:     docker.DriverCLI,
+		FixUploadOwner: true,
+	}
+}
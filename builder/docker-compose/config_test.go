@@ -0,0 +1,57 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package dockercompose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testConfig(t *testing.T) map[string]interface{} {
+	composeFile := filepath.Join(t.TempDir(), "compose.yaml")
+	if err := os.WriteFile(composeFile, []byte("services: {}\n"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return map[string]interface{}{
+		"compose_files": []string{composeFile},
+		"service":       "app",
+	}
+}
+
+func TestConfigPrepare(t *testing.T) {
+	var c Config
+	if _, err := c.Prepare(testConfig(t)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.Executable != "docker" || c.ContainerDir != "/packer-files" || c.Comm.Type != "docker" {
+		t.Fatalf("bad defaults: %#v", c)
+	}
+	if !strings.HasPrefix(c.ProjectName, "packer-") || !projectNameRe.MatchString(c.ProjectName) {
+		t.Fatalf("bad project name: %q", c.ProjectName)
+	}
+
+	dockerConfig := c.dockerConfig()
+	if dockerConfig.Executable != "docker" || !dockerConfig.FixUploadOwner {
+		t.Fatalf("bad docker config: %#v", dockerConfig)
+	}
+}
+
+func TestConfigPrepare_errors(t *testing.T) {
+	for name, edit := range map[string]func(map[string]interface{}){
+		"no compose files":   func(raw map[string]interface{}) { delete(raw, "compose_files") },
+		"missing file":       func(raw map[string]interface{}) { raw["compose_files"] = []string{"missing.yaml"} },
+		"no service":         func(raw map[string]interface{}) { delete(raw, "service") },
+		"bad project name":   func(raw map[string]interface{}) { raw["project_name"] = "Packer App" },
+		"bad change":         func(raw map[string]interface{}) { raw["changes"] = []string{"RUN apt-get update"} },
+		"winrm communicator": func(raw map[string]interface{}) { raw["communicator"] = "winrm" },
+	} {
+		raw := testConfig(t)
+		edit(raw)
+		if _, err := new(Config).Prepare(raw); err == nil {
+			t.Errorf("%s: should error", name)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package dockercompose
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+)
+
+// StepComposeUp brings up the compose project, and tears it down, with its
+// volumes unless they are kept, once the build is complete.
+type StepComposeUp struct {
+	GeneratedData *packerbuilderdata.GeneratedData
+
+	up bool
+}
+
+func (s *StepComposeUp) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("compose_config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say(fmt.Sprintf("Bringing up the compose project %s...", config.ProjectName))
+	s.up = true
+	if _, err := runCompose(ctx, config, "up", "--detach"); err != nil {
+		err := fmt.Errorf("Error bringing up the compose project: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	out, err := runCompose(ctx, config, "ps", "--quiet", config.Service)
+	if err != nil {
+		err := fmt.Errorf("Error finding the container of service %s: %s", config.Service, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	containerId := strings.TrimSpace(out)
+	if containerId == "" || strings.Contains(containerId, "\n") {
+		err := fmt.Errorf("Expected one running container for service %s, got %q; "+
+			"the service must keep running for the provisioners", config.Service, containerId)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("container_id", containerId)
+	state.Put("instance_id", containerId)
+	s.GeneratedData.Put("ContainerID", containerId)
+	ui.Message(fmt.Sprintf("Container ID: %s", containerId))
+	return multistep.ActionContinue
+}
+
+func (s *StepComposeUp) Cleanup(state multistep.StateBag) {
+	if !s.up {
+		return
+	}
+
+	config := state.Get("compose_config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say(fmt.Sprintf("Tearing down the compose project %s", config.ProjectName))
+	args := []string{"down", "--remove-orphans"}
+	if !config.KeepVolumes {
+		args = append(args, "--volumes")
+	}
+	if _, err := runCompose(context.Background(), config, args...); err != nil {
+		ui.Error(fmt.Sprintf("Error tearing down the compose project: %s", err))
+	}
+	s.up = false
+}
+
+// composeArgs returns the arguments of the `docker compose` command of the
+// project.
+func composeArgs(config *Config, args ...string) []string {
+	composeArgs := []string{"compose", "--project-name", config.ProjectName}
+	for _, f := range config.ComposeFiles {
+		composeArgs = append(composeArgs, "--file", f)
+	}
+	for _, profile := range config.Profiles {
+		composeArgs = append(composeArgs, "--profile", profile)
+	}
+	return append(composeArgs, args...)
+}
+
+// runCompose runs the `docker compose` command of the project, and returns
+// its output.
+func runCompose(ctx context.Context, config *Config, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, config.Executable, composeArgs(config, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s\n\nStderr: %s", err, stderr.String())
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,34 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package dockercompose
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepComposeUp_impl(t *testing.T) {
+	var _ multistep.Step = new(StepComposeUp)
+}
+
+func TestComposeArgs(t *testing.T) {
+	config := &Config{
+		ProjectName:  "packer-app",
+		ComposeFiles: []string{"compose.yaml", "compose.build.yaml"},
+		Profiles:     []string{"build"},
+	}
+
+	args := composeArgs(config, "up", "--detach")
+	expected := []string{
+		"compose", "--project-name", "packer-app",
+		"--file", "compose.yaml", "--file", "compose.build.yaml",
+		"--profile", "build",
+		"up", "--detach",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad args: %v", args)
+	}
+}
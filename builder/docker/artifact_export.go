@@ -6,10 +6,13 @@ package docker
 import (
 	"fmt"
 	"os"
+	"strings"
+
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
 )
 
 // ExportArtifact is an Artifact implementation for when a container is
-// exported from docker into a single flat file.
+// exported from docker into a single flat file, or an OCI image layout.
 type ExportArtifact struct {
 	path string
 	// StateData should store data such as GeneratedData
@@ -34,12 +37,45 @@ func (a *ExportArtifact) String() string {
 }
 
 func (a *ExportArtifact) State(name string) interface{} {
+	if name == registryimage.ArtifactStateURI {
+		return a.stateHCPPackerRegistryMetadata()
+	}
 	return a.StateData[name]
 }
 
+// stateHCPPackerRegistryMetadata describes the export for HCP Packer. The
+// export has no image ID, so its path is used as the ID, and the ID of the
+// image is a label when it was committed for an OCI image layout.
+func (a *ExportArtifact) stateHCPPackerRegistryMetadata() interface{} {
+	labels := map[string]interface{}{
+		"ExportPath": a.path,
+	}
+	if source, ok := a.StateData["source_image"].(string); ok && source != "" {
+		labels["SourceImage"] = source
+	}
+
+	img, _ := registryimage.FromArtifact(a,
+		registryimage.WithID(a.path),
+		registryimage.WithRegion("docker"),
+		registryimage.WithProvider("docker"),
+		registryimage.SetLabels(labels),
+	)
+
+	if data := generatedData(a.StateData); data != nil {
+		img.SourceImageID, _ = data["SourceImageSha256"].(string)
+		for _, key := range []string{"SourceImageDigest", "ImageSha256", "ImagePlatform"} {
+			if v, ok := data[key].(string); ok && v != "" && !strings.HasPrefix(v, "ERR_") {
+				img.Labels[key] = v
+			}
+		}
+	}
+
+	return img
+}
+
 func (a *ExportArtifact) Destroy() error {
 	if a.path != "" {
-		return os.Remove(a.path)
+		return os.RemoveAll(a.path)
 	}
 	return nil
 }
@@ -7,8 +7,41 @@ import (
 	"testing"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
+	"github.com/mitchellh/mapstructure"
 )
 
 func TestExportArtifact_impl(t *testing.T) {
 	var _ packersdk.Artifact = new(ExportArtifact)
 }
+
+func TestExportArtifactState_RegistryImageMetadata(t *testing.T) {
+	artifact := &ExportArtifact{
+		path: "image.tar",
+		StateData: map[string]interface{}{
+			"source_image": "ubuntu:24.04",
+			"generated_data": map[interface{}]interface{}{
+				"SourceImageSha256": "sha256:source",
+				"SourceImageDigest": "ERR_SOURCE_IMAGE_DIGEST_NOT_FOUND",
+			},
+		},
+	}
+
+	var image registryimage.Image
+	if err := mapstructure.Decode(artifact.State(registryimage.ArtifactStateURI), &image); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if image.ImageID != "image.tar" || image.ProviderName != "docker" {
+		t.Errorf("bad image: %#v", image)
+	}
+	if image.SourceImageID != "sha256:source" {
+		t.Errorf("bad source image ID: %q", image.SourceImageID)
+	}
+	if image.Labels["SourceImage"] != "ubuntu:24.04" || image.Labels["ExportPath"] != "image.tar" {
+		t.Errorf("bad labels: %#v", image.Labels)
+	}
+	if _, ok := image.Labels["SourceImageDigest"]; ok {
+		t.Errorf("the placeholders shouldn't be labels: %#v", image.Labels)
+	}
+}
@@ -6,6 +6,7 @@ package docker
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
@@ -36,12 +37,84 @@ func (a *ImportArtifact) Id() string {
 }
 
 func (a *ImportArtifact) String() string {
-	tags := a.loadTags()
-	if len(tags) > 0 {
-		return fmt.Sprintf("Imported Docker image: %s with tags %s",
-			a.Id(), strings.Join(tags, " "))
+	s := fmt.Sprintf("Imported Docker image: %s", a.Id())
+	if tags := a.loadTags(); len(tags) > 0 {
+		s += fmt.Sprintf(" with tags %s", strings.Join(tags, " "))
+	}
+	if details := a.metadataString(); details != "" {
+		s += fmt.Sprintf(" (%s)", details)
+	}
+	return s
+}
+
+// imageMetadataState returns the artifact state of the metadata of the
+// image: its size in bytes, the digests of its layers and its creation
+// time. The size is left out if the driver doesn't report it.
+func imageMetadataState(metadata *ImageMetadata) map[string]interface{} {
+	state := map[string]interface{}{
+		"image_layers":  metadata.RootFS.Layers,
+		"image_created": metadata.Created,
+	}
+	if metadata.Size > 0 {
+		state["image_size"] = metadata.Size
+	}
+	return state
+}
+
+// metadataString describes the metadata of the image in the state, like
+// `120.5 MB, 3 layers, created 2024-01-02T03:04:05Z`.
+func (a *ImportArtifact) metadataString() string {
+	var details []string
+	if size, ok := a.imageSize(); ok {
+		details = append(details, formatSize(size))
+	}
+	if layers, ok := a.imageLayers(); ok {
+		details = append(details, fmt.Sprintf("%d layers", layers))
+	}
+	if created, ok := a.StateData["image_created"].(string); ok && created != "" {
+		details = append(details, "created "+created)
+	}
+	return strings.Join(details, ", ")
+}
+
+// imageSize returns the size of the image in the state, whose type depends
+// on whether it went through the RPC between the plugins.
+func (a *ImportArtifact) imageSize() (int64, bool) {
+	switch size := a.StateData["image_size"].(type) {
+	case int64:
+		return size, true
+	case int:
+		return int64(size), true
+	case float64:
+		return int64(size), true
+	}
+	return 0, false
+}
+
+// imageLayers returns the number of layers of the image in the state.
+func (a *ImportArtifact) imageLayers() (int, bool) {
+	switch layers := a.StateData["image_layers"].(type) {
+	case []string:
+		return len(layers), true
+	case []interface{}:
+		return len(layers), true
 	}
-	return fmt.Sprintf("Imported Docker image: %s", a.Id())
+	return 0, false
+}
+
+// formatSize formats a size in bytes with decimal units, like docker does.
+func formatSize(size int64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1000 && unit < len(units)-1 {
+		value /= 1000
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
 }
 
 func (a *ImportArtifact) State(name string) interface{} {
@@ -85,6 +158,20 @@ func (a *ImportArtifact) stateHCPPackerRegistryMetadata() interface{} {
 	tags := a.loadTags()
 	if len(tags) > 0 {
 		labels["tags"] = strings.Join(tags, ",")
+		// The image can be pulled with the first of its repo:tag names.
+		labels["RepoTag"] = tags[0]
+	}
+	if source, ok := a.StateData["source_image"].(string); ok && source != "" {
+		labels["SourceImage"] = source
+	}
+	if created, ok := a.StateData["image_created"].(string); ok && created != "" {
+		labels["ImageCreated"] = created
+	}
+	if size, ok := a.imageSize(); ok {
+		labels["ImageSize"] = strconv.FormatInt(size, 10)
+	}
+	if layers, ok := a.imageLayers(); ok {
+		labels["ImageLayers"] = strconv.Itoa(layers)
 	}
 
 	img, _ := registryimage.FromArtifact(a,
@@ -93,31 +180,59 @@ func (a *ImportArtifact) stateHCPPackerRegistryMetadata() interface{} {
 		registryimage.SetLabels(labels),
 	)
 
-	data, ok := a.StateData["generated_data"].(map[string]interface{})
-	if !ok {
+	data := generatedData(a.StateData)
+	if data == nil {
 		log.Printf("No generated data exists in state. Artifact: %#v", a)
 		return img
 	}
 
-	img.SourceImageID = data["SourceImageSha256"].(string)
-	img.Labels["SourceImageDigest"] = data["SourceImageDigest"].(string)
+	// The generated data has placeholders for the values that weren't
+	// found, which aren't worth a label.
+	setLabel := func(label, key string) {
+		if v, ok := data[key].(string); ok && v != "" && !strings.HasPrefix(v, "ERR_") {
+			img.Labels[label] = v
+		}
+	}
+
+	img.SourceImageID, _ = data["SourceImageSha256"].(string)
+	setLabel("SourceImageDigest", "SourceImageDigest")
+	setLabel("SourceImagePlatform", "SourceImagePlatform")
 	// This is the image's sha that we store as the image id. We store it
 	// here as well becasue there is no guarantee this is the value stored
 	// on the main artifact id value.
-	img.Labels["ImageSha256"] = data["ImageSha256"].(string)
+	setLabel("ImageSha256", "ImageSha256")
 	// The docker tag and docker push post-processors store the repo:tag
 	// combination here, whereas the docker builder stores the image's
-	// sha256 id. We store this for posterity, but the image id needs to be
-	// the sha256.
+	// sha256 id, or the repo digest when it pushes the image. We store this
+	// for posterity, but the image id needs to be the sha256.
 	img.Labels["PackerArtifactID"] = a.Id()
-	// Digest exists in state if we ran the packer push postprocessor.
-	digest, ok := data["Digest"].(string)
-	if ok && digest != "" {
-		img.Labels["ImageDigest"] = digest
-	}
+	// Digest exists in state if the image was pushed, by the builder or
+	// the docker push post-processor.
+	setLabel("ImageDigest", "Digest")
+	setLabel("ImagePlatform", "ImagePlatform")
 
 	// Overwrite ID with image sha
-	img.ImageID = data["ImageSha256"].(string)
+	if id, ok := data["ImageSha256"].(string); ok && id != "" {
+		img.ImageID = id
+	}
 
 	return img
 }
+
+// generatedData returns the generated data of the state, which the RPC
+// between the plugins turns into a map[interface{}]interface{}.
+func generatedData(state map[string]interface{}) map[string]interface{} {
+	switch data := state["generated_data"].(type) {
+	case map[string]interface{}:
+		return data
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(data))
+		for k, v := range data {
+			if k, ok := k.(string); ok {
+				converted[k] = v
+			}
+		}
+		return converted
+	}
+	return nil
+}
@@ -38,6 +38,42 @@ func TestImportArtifactId(t *testing.T) {
 	}
 }
 
+func TestImportArtifactString(t *testing.T) {
+	a := &ImportArtifact{
+		IdValue: "sha256:1234",
+		StateData: map[string]interface{}{
+			"docker_tags": []string{"app:1.0"},
+		},
+	}
+	if s := a.String(); s != "Imported Docker image: sha256:1234 with tags app:1.0" {
+		t.Fatalf("bad: %s", s)
+	}
+
+	for k, v := range imageMetadataState(&ImageMetadata{
+		Size:    120500000,
+		Created: "2024-01-02T03:04:05Z",
+		RootFS:  ImageRootFS{Layers: []string{"sha256:1", "sha256:2", "sha256:3"}},
+	}) {
+		a.StateData[k] = v
+	}
+	expected := "Imported Docker image: sha256:1234 with tags app:1.0 (120.5 MB, 3 layers, created 2024-01-02T03:04:05Z)"
+	if s := a.String(); s != expected {
+		t.Fatalf("bad: %s", s)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for size, expected := range map[int64]string{
+		512:        "512 B",
+		1500:       "1.5 kB",
+		2000000000: "2.0 GB",
+	} {
+		if s := formatSize(size); s != expected {
+			t.Errorf("formatSize(%d): expected %s, got %s", size, expected, s)
+		}
+	}
+}
+
 func TestImportArtifactDestroy(t *testing.T) {
 	d := new(MockDriver)
 	a := &ImportArtifact{
@@ -150,3 +186,54 @@ func TestArtifactState_RegistryImageMetadataWithGenData(t *testing.T) {
 	}
 
 }
+
+func TestArtifactState_RegistryImageMetadataDetails(t *testing.T) {
+	artifact := &ImportArtifact{
+		Driver:  new(MockDriver),
+		IdValue: "ghcr.io/example/app@sha256:5678",
+		StateData: map[string]interface{}{
+			"docker_tags":   []interface{}{"ghcr.io/example/app:1.0"},
+			"source_image":  "ubuntu:24.04",
+			"image_size":    int64(1234),
+			"image_layers":  []interface{}{"sha256:1", "sha256:2"},
+			"image_created": "2024-01-02T03:04:05Z",
+			// The generated data after the RPC between the plugins
+			"generated_data": map[interface{}]interface{}{
+				"SourceImageSha256": "sha256:source",
+				"SourceImageDigest": "ERR_SOURCE_IMAGE_DIGEST_NOT_FOUND",
+				"ImageSha256":       "sha256:1234",
+				"ImagePlatform":     "linux/amd64",
+				"Digest":            "ghcr.io/example/app@sha256:5678",
+			},
+		},
+	}
+
+	var image registryimage.Image
+	if err := mapstructure.Decode(artifact.State(registryimage.ArtifactStateURI), &image); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if image.ImageID != "sha256:1234" {
+		t.Errorf("bad ImageID: %q", image.ImageID)
+	}
+	if image.SourceImageID != "sha256:source" {
+		t.Errorf("bad SourceImageID: %q", image.SourceImageID)
+	}
+	for k, v := range map[string]string{
+		"RepoTag":          "ghcr.io/example/app:1.0",
+		"SourceImage":      "ubuntu:24.04",
+		"ImageSize":        "1234",
+		"ImageLayers":      "2",
+		"ImageCreated":     "2024-01-02T03:04:05Z",
+		"ImagePlatform":    "linux/amd64",
+		"ImageDigest":      "ghcr.io/example/app@sha256:5678",
+		"PackerArtifactID": "ghcr.io/example/app@sha256:5678",
+	} {
+		if image.Labels[k] != v {
+			t.Errorf("bad label %s: expected %q, got %q", k, v, image.Labels[k])
+		}
+	}
+	if _, ok := image.Labels["SourceImageDigest"]; ok {
+		t.Errorf("the placeholders shouldn't be labels: %#v", image.Labels)
+	}
+}
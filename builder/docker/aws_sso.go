@@ -0,0 +1,220 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsCredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sso"
+	"github.com/aws/aws-sdk-go/service/sso/ssoiface"
+)
+
+// ssoProviderName is the name of the provider of the credentials of the
+// profiles of an IAM Identity Center session.
+const ssoProviderName = "SSOSessionProvider"
+
+// ssoSessionProfile is a profile of the shared AWS config that gets its
+// credentials from an IAM Identity Center session, as written by `aws
+// configure sso` with the `sso_session` setting, which the legacy SSO
+// profiles of the AWS SDK don't understand.
+type ssoSessionProfile struct {
+	Session   string
+	AccountID string
+	RoleName  string
+	StartURL  string
+	Region    string
+}
+
+// awsConfigFile returns the path of the shared AWS config file.
+func awsConfigFile() (string, error) {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".aws", "config"), nil
+}
+
+// readAwsConfigSections returns the settings of the sections of the shared
+// AWS config file, by section name, like `profile dev` or `default`.
+func readAwsConfigSections(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sections := map[string]map[string]string{}
+	var section map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			if sections[name] == nil {
+				sections[name] = map[string]string{}
+			}
+			section = sections[name]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section == nil {
+			continue
+		}
+		section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return sections, scanner.Err()
+}
+
+// lookupSSOSessionProfile returns the IAM Identity Center session settings of
+// the profile in the shared AWS config file, or nil if the profile doesn't
+// use an `sso_session`.
+func lookupSSOSessionProfile(profile string) (*ssoSessionProfile, error) {
+	path, err := awsConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	sections, err := readAwsConfigSections(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the AWS config file %s: %s", path, err)
+	}
+
+	settings, ok := sections["profile "+profile]
+	if !ok && profile == "default" {
+		settings = sections["default"]
+	}
+	if settings["sso_session"] == "" {
+		return nil, nil
+	}
+
+	p := &ssoSessionProfile{
+		Session:   settings["sso_session"],
+		AccountID: settings["sso_account_id"],
+		RoleName:  settings["sso_role_name"],
+	}
+	ssoSession, ok := sections["sso-session "+p.Session]
+	if !ok {
+		return nil, fmt.Errorf("The AWS profile %s uses the sso-session %s, which isn't in %s", profile, p.Session, path)
+	}
+	p.StartURL = ssoSession["sso_start_url"]
+	p.Region = ssoSession["sso_region"]
+
+	var missing []string
+	for _, setting := range []struct{ name, value string }{
+		{"sso_account_id", p.AccountID},
+		{"sso_role_name", p.RoleName},
+		{"sso_start_url", p.StartURL},
+		{"sso_region", p.Region},
+	} {
+		if setting.value == "" {
+			missing = append(missing, setting.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("The AWS profile %s is missing the SSO settings %s", profile, strings.Join(missing, ", "))
+	}
+	return p, nil
+}
+
+// ssoCachedToken is the access token of an IAM Identity Center session
+// cached by `aws sso login`.
+type ssoCachedToken struct {
+	AccessToken string    `json:"accessToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// ssoTokenFile returns the path of the token `aws sso login` caches for the
+// session, named after the SHA-1 of the name of the session.
+func ssoTokenFile(sessionName string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(sessionName))
+	return filepath.Join(home, ".aws", "sso", "cache", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// ssoSessionProvider exchanges the cached access token of an IAM Identity
+// Center session for the credentials of the role of the profile.
+type ssoSessionProvider struct {
+	awsCredentials.Expiry
+
+	client  ssoiface.SSOAPI
+	profile *ssoSessionProfile
+}
+
+func (p *ssoSessionProvider) Retrieve() (awsCredentials.Value, error) {
+	path, err := ssoTokenFile(p.profile.Session)
+	if err != nil {
+		return awsCredentials.Value{}, err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return awsCredentials.Value{}, fmt.Errorf("No cached token for the SSO session %s, run `aws sso login --sso-session %s`: %s",
+			p.profile.Session, p.profile.Session, err)
+	}
+	var token ssoCachedToken
+	if err := json.Unmarshal(raw, &token); err != nil {
+		return awsCredentials.Value{}, fmt.Errorf("Error reading the cached token of the SSO session %s: %s", p.profile.Session, err)
+	}
+	if token.AccessToken == "" || !time.Now().Before(token.ExpiresAt) {
+		return awsCredentials.Value{}, fmt.Errorf("The SSO session %s has expired, run `aws sso login --sso-session %s`",
+			p.profile.Session, p.profile.Session)
+	}
+
+	out, err := p.client.GetRoleCredentials(&sso.GetRoleCredentialsInput{
+		AccessToken: aws.String(token.AccessToken),
+		AccountId:   aws.String(p.profile.AccountID),
+		RoleName:    aws.String(p.profile.RoleName),
+	})
+	if err != nil {
+		return awsCredentials.Value{}, fmt.Errorf("Error getting the credentials of role %s in account %s from the SSO session %s: %s",
+			p.profile.RoleName, p.profile.AccountID, p.profile.Session, err)
+	}
+
+	creds := out.RoleCredentials
+	p.SetExpiration(time.UnixMilli(aws.Int64Value(creds.Expiration)), time.Minute)
+	return awsCredentials.Value{
+		AccessKeyID:     aws.StringValue(creds.AccessKeyId),
+		SecretAccessKey: aws.StringValue(creds.SecretAccessKey),
+		SessionToken:    aws.StringValue(creds.SessionToken),
+		ProviderName:    ssoProviderName,
+	}, nil
+}
+
+// ssoSessionCredentials returns the credentials of the profile from its IAM
+// Identity Center session.
+func ssoSessionCredentials(config *aws.Config, profile *ssoSessionProfile) (*awsCredentials.Credentials, error) {
+	sess, err := session.NewSession(config.Copy().WithRegion(profile.Region))
+	if err != nil {
+		return nil, err
+	}
+	creds := awsCredentials.NewCredentials(&ssoSessionProvider{
+		client:  sso.New(sess),
+		profile: profile,
+	})
+	if _, err := creds.Get(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
@@ -0,0 +1,152 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sso"
+	"github.com/aws/aws-sdk-go/service/sso/ssoiface"
+)
+
+const testAwsConfig = `[default]
+region = eu-west-1
+
+[profile dev]
+sso_session = corp
+sso_account_id = 111122223333
+sso_role_name = ECRPush
+region = us-west-2
+
+# A legacy SSO profile, left to the AWS SDK
+[profile legacy]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 111122223333
+sso_role_name = ECRPush
+
+[profile broken]
+sso_session = corp
+
+[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+`
+
+// testAwsHome writes the shared AWS config file to a temporary home
+// directory, and returns the directory.
+func testAwsHome(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	if err := os.MkdirAll(filepath.Join(home, ".aws", "sso", "cache"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(testAwsConfig), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return home
+}
+
+func TestLookupSSOSessionProfile(t *testing.T) {
+	testAwsHome(t)
+
+	p, err := lookupSSOSessionProfile("dev")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := &ssoSessionProfile{
+		Session:   "corp",
+		AccountID: "111122223333",
+		RoleName:  "ECRPush",
+		StartURL:  "https://corp.awsapps.com/start",
+		Region:    "us-east-1",
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatalf("bad profile: %#v", p)
+	}
+
+	for _, profile := range []string{"default", "legacy", "missing"} {
+		if p, err := lookupSSOSessionProfile(profile); err != nil || p != nil {
+			t.Fatalf("%s shouldn't be an SSO session profile: %#v, %v", profile, p, err)
+		}
+	}
+	if _, err := lookupSSOSessionProfile("broken"); err == nil {
+		t.Fatal("should've failed on the missing SSO settings")
+	}
+
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	if p, err := lookupSSOSessionProfile("dev"); err != nil || p != nil {
+		t.Fatalf("a missing config file has no profiles: %#v, %v", p, err)
+	}
+}
+
+type mockSSOClient struct {
+	ssoiface.SSOAPI
+
+	input *sso.GetRoleCredentialsInput
+}
+
+func (c *mockSSOClient) GetRoleCredentials(input *sso.GetRoleCredentialsInput) (*sso.GetRoleCredentialsOutput, error) {
+	c.input = input
+	return &sso.GetRoleCredentialsOutput{
+		RoleCredentials: &sso.RoleCredentials{
+			AccessKeyId:     aws.String("AKIA"),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("session"),
+			Expiration:      aws.Int64(time.Now().Add(time.Hour).UnixMilli()),
+		},
+	}, nil
+}
+
+func TestSSOSessionProvider(t *testing.T) {
+	home := testAwsHome(t)
+	profile, err := lookupSSOSessionProfile("dev")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client := &mockSSOClient{}
+	p := &ssoSessionProvider{client: client, profile: profile}
+
+	if _, err := p.Retrieve(); err == nil {
+		t.Fatal("should've failed without a cached token")
+	}
+
+	// The token cached by `aws sso login`, named after the SHA-1 of "corp"
+	tokenFile := filepath.Join(home, ".aws", "sso", "cache", "ee0bfd2552fbd840c02cc48b6e823320543c450f.json")
+	writeToken := func(expiresAt time.Time) {
+		token := fmt.Sprintf(`{"startUrl": "https://corp.awsapps.com/start", "accessToken": "token", "expiresAt": %q}`,
+			expiresAt.UTC().Format(time.RFC3339))
+		if err := os.WriteFile(tokenFile, []byte(token), 0600); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	writeToken(time.Now().Add(-time.Minute))
+	if _, err := p.Retrieve(); err == nil {
+		t.Fatal("should've failed with an expired token")
+	}
+
+	writeToken(time.Now().Add(time.Hour))
+	value, err := p.Retrieve()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if value.AccessKeyID != "AKIA" || value.SecretAccessKey != "secret" || value.SessionToken != "session" || value.ProviderName != ssoProviderName {
+		t.Fatalf("bad credentials: %#v", value)
+	}
+	if aws.StringValue(client.input.AccessToken) != "token" || aws.StringValue(client.input.AccountId) != "111122223333" ||
+		aws.StringValue(client.input.RoleName) != "ECRPush" {
+		t.Fatalf("bad request: %#v", client.input)
+	}
+	if p.IsExpired() {
+		t.Fatal("the credentials shouldn't have expired")
+	}
+}
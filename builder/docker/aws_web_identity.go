@@ -0,0 +1,159 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsCredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// defaultWebIdentitySessionName is the name of the sessions of the roles
+// assumed with a web identity token.
+const defaultWebIdentitySessionName = "packer"
+
+// stsAudience is the audience of the OIDC tokens requested for STS.
+const stsAudience = "sts.amazonaws.com"
+
+var (
+	// roleARNRe matches the ARNs of IAM roles, in all the partitions.
+	roleARNRe = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`)
+	// roleSessionNameRe matches the names STS accepts for role sessions.
+	roleSessionNameRe = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+)
+
+// prepareWebIdentity validates the web identity settings.
+func (c *AwsAccessConfig) prepareWebIdentity() []error {
+	if c.WebIdentityRoleARN == "" {
+		if c.WebIdentityTokenFile != "" || c.WebIdentitySessionName != "" || c.WebIdentityDuration != 0 {
+			return []error{errors.New("the `aws_web_identity_*` options require `aws_web_identity_role_arn` to be set")}
+		}
+		return nil
+	}
+
+	var errs []error
+	if !roleARNRe.MatchString(c.WebIdentityRoleARN) {
+		errs = append(errs, fmt.Errorf("`aws_web_identity_role_arn` %q is not the ARN of a role, like `arn:aws:iam::123456789012:role/packer`", c.WebIdentityRoleARN))
+	}
+	if c.AccessKey != "" || c.Profile != "" {
+		errs = append(errs, errors.New("`aws_web_identity_role_arn` can't be set with `aws_access_key` or `aws_profile`"))
+	}
+	if c.WebIdentitySessionName != "" && !roleSessionNameRe.MatchString(c.WebIdentitySessionName) {
+		errs = append(errs, fmt.Errorf("`aws_web_identity_session_name` %q must have 2 to 64 letters, digits or characters of +=,.@_-", c.WebIdentitySessionName))
+	}
+	if c.WebIdentityDuration != 0 && (c.WebIdentityDuration < 15*time.Minute || c.WebIdentityDuration > 12*time.Hour) {
+		errs = append(errs, fmt.Errorf("`aws_web_identity_duration` must be between 15m and 12h, got %s", c.WebIdentityDuration))
+	}
+	return errs
+}
+
+// githubActionsTokenFetcher requests the OIDC token of the GitHub Actions
+// job, which requires the `id-token: write` permission.
+type githubActionsTokenFetcher struct {
+	requestURL   string
+	requestToken string
+}
+
+func (f githubActionsTokenFetcher) FetchToken(ctx awsCredentials.Context) ([]byte, error) {
+	u, err := url.Parse(f.requestURL)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing the OIDC token request URL of GitHub Actions: %s", err)
+	}
+	query := u.Query()
+	query.Set("audience", stsAudience)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+f.requestToken)
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error requesting the OIDC token of the GitHub Actions job: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error requesting the OIDC token of the GitHub Actions job: %s", resp.Status)
+	}
+
+	var token struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("Error reading the OIDC token of the GitHub Actions job: %s", err)
+	}
+	return []byte(token.Value), nil
+}
+
+// webIdentityTokenFetcher returns the source of the web identity token: the
+// `aws_web_identity_token_file`, the file of the environment, or the OIDC
+// token of the GitHub Actions job.
+func (c *AwsAccessConfig) webIdentityTokenFetcher() (stscreds.TokenFetcher, error) {
+	path := c.WebIdentityTokenFile
+	if path == "" {
+		path = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+	if path != "" {
+		return stscreds.FetchTokenPath(path), nil
+	}
+
+	requestURL, requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL != "" && requestToken != "" {
+		return githubActionsTokenFetcher{requestURL: requestURL, requestToken: requestToken}, nil
+	}
+	return nil, errors.New("No web identity token to assume `aws_web_identity_role_arn` with: set `aws_web_identity_token_file`, " +
+		"or run in a GitHub Actions job with the `id-token: write` permission")
+}
+
+// webIdentityProvider returns the provider of the credentials of the role
+// assumed with the web identity token through the STS client.
+func (c *AwsAccessConfig) webIdentityProvider(client stsiface.STSAPI) (*stscreds.WebIdentityRoleProvider, error) {
+	fetcher, err := c.webIdentityTokenFetcher()
+	if err != nil {
+		return nil, err
+	}
+	sessionName := c.WebIdentitySessionName
+	if sessionName == "" {
+		sessionName = defaultWebIdentitySessionName
+	}
+	return stscreds.NewWebIdentityRoleProviderWithOptions(client, c.WebIdentityRoleARN, sessionName, fetcher,
+		func(p *stscreds.WebIdentityRoleProvider) {
+			p.Duration = c.WebIdentityDuration
+		}), nil
+}
+
+// webIdentityCredentials returns the credentials of the role assumed with
+// the web identity token.
+func (c *AwsAccessConfig) webIdentityCredentials(config *aws.Config) (*awsCredentials.Credentials, error) {
+	sess, err := session.NewSession(config.Copy())
+	if err != nil {
+		return nil, err
+	}
+	provider, err := c.webIdentityProvider(sts.New(sess))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Assuming role %s with a web identity token", c.WebIdentityRoleARN)
+
+	creds := awsCredentials.NewCredentials(provider)
+	if _, err := creds.Get(); err != nil {
+		return nil, fmt.Errorf("Error assuming role %s with the web identity token: %s", c.WebIdentityRoleARN, err)
+	}
+	return creds, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func TestAwsAccessConfig_prepareWebIdentity(t *testing.T) {
+	valid := []AwsAccessConfig{
+		{},
+		{WebIdentityRoleARN: "arn:aws:iam::123456789012:role/packer"},
+		{
+			WebIdentityRoleARN:     "arn:aws-us-gov:iam::123456789012:role/ci/packer",
+			WebIdentityTokenFile:   "/var/run/secrets/eks.amazonaws.com/serviceaccount/token",
+			WebIdentitySessionName: "packer@ci",
+			WebIdentityDuration:    2 * time.Hour,
+		},
+	}
+	for _, c := range valid {
+		if errs := c.prepareWebIdentity(); len(errs) > 0 {
+			t.Fatalf("%#v: %v", c, errs)
+		}
+	}
+
+	invalid := []AwsAccessConfig{
+		{WebIdentityTokenFile: "/token"},
+		{WebIdentityRoleARN: "packer"},
+		{WebIdentityRoleARN: "arn:aws:iam::123456789012:role/packer", AccessKey: "AKIA"},
+		{WebIdentityRoleARN: "arn:aws:iam::123456789012:role/packer", WebIdentitySessionName: "packer build"},
+		{WebIdentityRoleARN: "arn:aws:iam::123456789012:role/packer", WebIdentityDuration: time.Minute},
+	}
+	for _, c := range invalid {
+		if errs := c.prepareWebIdentity(); len(errs) == 0 {
+			t.Fatalf("%#v should be invalid", c)
+		}
+	}
+}
+
+func TestGithubActionsTokenFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"value": "oidc-token-for-%s"}`, r.URL.Query().Get("audience"))
+	}))
+	defer server.Close()
+
+	f := githubActionsTokenFetcher{requestURL: server.URL + "/token?api-version=2.0", requestToken: "request-token"}
+	token, err := f.FetchToken(context.Background())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(token) != "oidc-token-for-sts.amazonaws.com" {
+		t.Fatalf("bad token: %s", token)
+	}
+
+	f.requestToken = "expired"
+	if _, err := f.FetchToken(context.Background()); err == nil {
+		t.Fatal("should've failed with a bad request token")
+	}
+}
+
+func TestAwsAccessConfig_webIdentityTokenFetcher(t *testing.T) {
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "")
+	c := &AwsAccessConfig{WebIdentityRoleARN: "arn:aws:iam::123456789012:role/packer"}
+	if _, err := c.webIdentityTokenFetcher(); err == nil {
+		t.Fatal("should've failed without a token")
+	}
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "https://token.actions.githubusercontent.com/token")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+	if f, err := c.webIdentityTokenFetcher(); err != nil {
+		t.Fatalf("err: %s", err)
+	} else if _, ok := f.(githubActionsTokenFetcher); !ok {
+		t.Fatalf("should use the token of the GitHub Actions job: %#v", f)
+	}
+
+	// A token file, set by EKS for IRSA, comes first
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "/var/run/secrets/eks.amazonaws.com/serviceaccount/token")
+	if f, err := c.webIdentityTokenFetcher(); err != nil {
+		t.Fatalf("err: %s", err)
+	} else if f != stscreds.FetchTokenPath("/var/run/secrets/eks.amazonaws.com/serviceaccount/token") {
+		t.Fatalf("should read the token file of the environment: %#v", f)
+	}
+}
+
+func TestAwsAccessConfig_webIdentityProvider(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("oidc-token"), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var form map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		form = map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		fmt.Fprint(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIA</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().WithRegion("us-east-1").WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c := &AwsAccessConfig{
+		WebIdentityRoleARN:   "arn:aws:iam::123456789012:role/packer",
+		WebIdentityTokenFile: tokenFile,
+		WebIdentityDuration:  2 * time.Hour,
+	}
+	provider, err := c.webIdentityProvider(sts.New(sess))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	value, err := provider.Retrieve()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if value.AccessKeyID != "ASIA" || value.SecretAccessKey != "secret" || value.SessionToken != "session" {
+		t.Fatalf("bad credentials: %#v", value)
+	}
+
+	expected := map[string]string{
+		"RoleArn":          "arn:aws:iam::123456789012:role/packer",
+		"RoleSessionName":  defaultWebIdentitySessionName,
+		"WebIdentityToken": "oidc-token",
+		"DurationSeconds":  "7200",
+	}
+	for key, value := range expected {
+		if form[key] != value {
+			t.Fatalf("bad %s: %q", key, form[key])
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// BuildahCommunicator talks to buildah working containers. Those don't run
+// any process, so commands are run with `buildah run` instead of `docker
+// exec`, and files are copied with `buildah copy` instead of `docker cp`.
+//
+// This reuses the normal Docker Communicator, and only overrides the methods
+// that run buildah commands.
+type BuildahCommunicator struct {
+	Communicator
+}
+
+func (c *BuildahCommunicator) Start(ctx context.Context, remote *packersdk.RemoteCmd) error {
+	if handled, err := c.interceptCommand(remote); handled {
+		return err
+	}
+
+	args := []string{"run"}
+
+	if c.Config.Pty {
+		args = append(args, "-t")
+	}
+
+	if c.Config.ExecUser != "" {
+		args = append(args, "--user", c.Config.ExecUser)
+	}
+
+	if c.Config.Workdir != "" {
+		args = append(args, "--workingdir", c.Config.Workdir)
+	}
+
+	args = append(args, c.Config.ExecArgs...)
+
+	env, err := execEnv(c.Config)
+	if err != nil {
+		return err
+	}
+	for _, pair := range env {
+		args = append(args, "--env", pair)
+	}
+
+	args = append(args, c.ContainerID, "--")
+	args = append(args, c.EntryPoint...)
+	args = append(args, fmt.Sprintf("(%s)", remote.Command))
+
+	cmd := exec.CommandContext(ctx, c.Executable, args...)
+
+	stdin_w, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr_r, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	stdout_r, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	// Run the actual command in a goroutine so that Start doesn't block
+	go c.run(cmd, remote, stdin_w, stdout_r, stderr_r)
+
+	return nil
+}
+
+// Upload writes the file to a temporary file, and copies it in the
+// container with buildah copy.
+func (c *BuildahCommunicator) Upload(dst string, src io.Reader, fi *os.FileInfo) error {
+	tempfile, err := ioutil.TempFile(c.HostDir, "upload")
+	if err != nil {
+		return fmt.Errorf("Failed to open temp file for writing: %s", err)
+	}
+	defer os.Remove(tempfile.Name())
+
+	if _, err := io.Copy(tempfile, src); err != nil {
+		tempfile.Close()
+		return fmt.Errorf("Failed to copy upload file to tempfile: %s", err)
+	}
+	if fi != nil {
+		//nolint:errcheck
+		tempfile.Chmod((*fi).Mode())
+	}
+	tempfile.Close()
+
+	log.Printf("Copying to %s on container %s.", dst, c.ContainerID)
+	return c.copy(tempfile.Name(), dst)
+}
+
+// UploadDir copies the directory in the container with buildah copy.
+//
+// buildah copy always copies the contents of a directory, so if the
+// source does not end with a slash, the directory itself is copied by
+// adding its name to the destination.
+func (c *BuildahCommunicator) UploadDir(dst string, src string, exclude []string) error {
+	if !strings.HasSuffix(src, "/") {
+		dst = filepath.ToSlash(filepath.Join(dst, filepath.Base(src)))
+	}
+
+	return c.copy(src, dst)
+}
+
+func (c *BuildahCommunicator) copy(src string, dst string) error {
+	if c.Snapshots.skipping() {
+		log.Printf("Skipping upload to %s, it's in the resumed snapshot", dst)
+		return nil
+	}
+
+	args := []string{"copy"}
+
+	if c.Config.FixUploadOwner {
+		args = append(args, "--chown", c.uploadOwner())
+	}
+
+	args = append(args, c.ContainerID, src, dst)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(c.Executable, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Failed to upload to '%s' in container: %s. %s.", dst, stderr.String(), err)
+	}
+
+	return nil
+}
+
+// Download reads the file from the container with cat, since buildah
+// cannot copy files out of a working container.
+func (c *BuildahCommunicator) Download(src string, dst io.Writer) error {
+	if c.Snapshots.skipping() {
+		log.Printf("Skipping download of %s, it's in the resumed snapshot", src)
+		return nil
+	}
+	log.Printf("Downloading file from container: %s:%s", c.ContainerID, src)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(c.Executable, "run", c.ContainerID, "--", "cat", src)
+	cmd.Stdout = dst
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Failed to download '%s' from container: %s. %s", src, stderr.String(), err)
+	}
+
+	return nil
+}
@@ -5,6 +5,8 @@ package docker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
@@ -35,19 +37,32 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 
 	return []string{
 		"ImageSha256",
+		"ImagePlatform",
+		"Digest",
+		"ImageID",
+		"ContainerID",
 		"SourceImageDigest",
+		"SourceImagePlatform",
 	}, warnings, nil
 }
 
 func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
-	driver := &DockerDriver{
-		Executable: b.config.Executable,
-		Ctx:        &b.config.ctx,
-		Ui:         ui,
+	host, stopSSHAgent, err := b.config.DockerHostConfig.StartSSHAgent()
+	if err != nil {
+		return nil, err
+	}
+	defer stopSSHAgent()
+
+	driver, err := NewDriver(b.config.DriverType, b.config.Executable, "", host, b.config.RegistryTLSConfig, b.config.ProxyEnv, &b.config.ctx, ui)
+	if err != nil {
+		return nil, err
 	}
 	if err := driver.Verify(); err != nil {
 		return nil, err
 	}
+	if err := verifyCommunicatorCLI(&b.config); err != nil {
+		return nil, err
+	}
 
 	version, err := driver.Version()
 	if err != nil {
@@ -55,61 +70,218 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	}
 	log.Printf("[DEBUG] Docker version: %s", version.String())
 
+	if !b.config.Rootless {
+		rootless, err := driver.Rootless()
+		if err != nil {
+			log.Printf("[WARN] Failed to detect a rootless daemon, set `rootless` if it is one: %s", err)
+		} else if rootless {
+			ui.Say("The Docker daemon runs rootless, the uploaded files will not be given to the container user")
+			b.config.Rootless = true
+			if err := validateRootless(&b.config); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if b.config.Gpus != "" {
+		if err := verifyGPUs(driver); err != nil {
+			return nil, err
+		}
+	}
+	if b.config.Runtime != "" {
+		if err := verifyRuntime(driver, b.config.Runtime); err != nil {
+			return nil, err
+		}
+	}
+
+	// When building for several platforms, the whole build is run once for
+	// each of them, with the platform set accordingly.
+	platforms := b.config.BuildConfig.Platforms
+	if len(platforms) == 0 {
+		platforms = []string{b.config.Platform}
+	}
+
+	if b.config.BuildTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.config.BuildTimeout)
+		defer cancel()
+	}
+
+	var state multistep.StateBag
+	platformImages := make(map[string]string)
+	for _, platform := range platforms {
+		config := b.config
+		config.Changes = append([]string{}, b.config.Changes...)
+		if len(b.config.BuildConfig.Platforms) > 0 {
+			ui.Say(fmt.Sprintf("Building for platform %s", platform))
+			config.Platform = platform
+			config.BuildConfig.Platform = platform
+		}
+
+		platformState, err := b.runSteps(ctx, ui, hook, driver, host, &config)
+		if b.config.BuildTimeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("The build timed out after %s", b.config.BuildTimeout)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// If it was cancelled, then just return
+		if _, ok := platformState.GetOk(multistep.StateCancelled); ok {
+			return nil, nil
+		}
+
+		if config.Commit {
+			platformImages[platform] = platformState.Get("image_id").(string)
+		}
+
+		// The artifact is built from the state of the first platform
+		if state == nil {
+			state = platformState
+		}
+	}
+
+	// No errors, must've worked. Build the artifact.
+	stateData := map[string]interface{}{
+		"generated_data": state.Get("generated_data"),
+	}
+
+	if len(b.config.BuildConfig.Platforms) > 0 {
+		stateData["platform_images"] = platformImages
+	}
+
+	if b.config.Image != "" {
+		stateData["source_image"] = b.config.Image
+	}
+
+	if metadata, ok := state.GetOk("image_metadata"); ok {
+		for k, v := range imageMetadataState(metadata.(*ImageMetadata)) {
+			stateData[k] = v
+		}
+	}
+
+	var artifact packersdk.Artifact
+	if digest, ok := state.GetOk("pushed_digest"); ok {
+		stateData["docker_tags"] = state.Get("docker_tags")
+		artifact = &ImportArtifact{
+			IdValue:        digest.(string),
+			BuilderIdValue: BuilderIdImport,
+			Driver:         driver,
+			StateData:      stateData,
+		}
+	} else if b.config.Commit {
+		artifact = &ImportArtifact{
+			IdValue:        state.Get("image_id").(string),
+			BuilderIdValue: BuilderIdImport,
+			Driver:         driver,
+			StateData:      stateData,
+		}
+	} else {
+		artifact = &ExportArtifact{
+			path:      exportDestination(&b.config),
+			StateData: stateData,
+		}
+	}
+
+	return artifact, nil
+}
+
+// runSteps runs the steps of the build with the given config, and returns
+// the resulting state, along with the error that made the build fail, if any.
+func (b *Builder) runSteps(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook, driver Driver, host DockerHostConfig, config *Config) (multistep.StateBag, error) {
 	// Setup the state bag and initial state for the steps
 	state := new(multistep.BasicStateBag)
-	state.Put("config", &b.config)
+	state.Put("config", config)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 	generatedData := &packerbuilderdata.GeneratedData{State: state}
 
 	// Setup the driver that will talk to Docker
 	state.Put("driver", driver)
+	state.Put("docker_host", host)
 
 	steps := []multistep.Step{
 		&StepDefaultGeneratedData{
 			GeneratedData: generatedData,
 		},
 		&StepTempDir{},
+		&StepBinfmt{},
 		&stepBuild{
-			buildArgs: b.config.BuildConfig,
+			buildArgs: config.BuildConfig,
 		},
+		&StepSnapshots{},
 		&StepPull{
-			bootstrapped:  !b.config.BuildConfig.IsDefault(),
+			bootstrapped:  !config.BuildConfig.IsDefault(),
+			GeneratedData: generatedData,
+		},
+		&StepVerifySignature{},
+		&StepContentHash{
+			GeneratedData: generatedData,
+		},
+		&StepScanImage{},
+		&StepPrePull{},
+		&StepNetwork{},
+		&StepVolumes{},
+		&StepSidecars{},
+		&StepRun{
 			GeneratedData: generatedData,
 		},
-		&StepRun{},
+		&StepWaitFor{},
+		&StepSetupWinRM{},
 		&communicator.StepConnect{
-			Config:    &b.config.Comm,
-			Host:      commHost(b.config.Comm.Host()),
-			SSHConfig: b.config.Comm.SSHConfigFunc(),
+			Config:    &config.Comm,
+			Host:      commHost(config),
+			SSHConfig: config.Comm.SSHConfigFunc(),
+			SSHPort:   commPort(config),
+			WinRMPort: commPort(config),
 			CustomConnect: map[string]multistep.Step{
 				"docker":                 &StepConnectDocker{},
 				"dockerWindowsContainer": &StepConnectDocker{},
 			},
 		},
+		&StepSecrets{},
+		&StepCopyFiles{},
 		&commonsteps.StepProvision{},
+		&StepPauseAfterProvision{},
+		&StepVerifySnapshots{},
+		&StepPreCommit{},
+		&StepVerifySecrets{},
 		&commonsteps.StepCleanupTempKeys{
-			Comm: &b.config.Comm,
+			Comm: &config.Comm,
 		},
 	}
 
-	if b.config.Discard {
+	if config.Discard {
 		log.Print("[DEBUG] Container will be discarded")
-	} else if b.config.Commit {
+	} else if config.Commit {
 		log.Print("[DEBUG] Container will be committed")
 		steps = append(steps, &StepSetDefaults{})
 		steps = append(steps, &StepCommit{
 			GeneratedData: generatedData,
 		})
-	} else if b.config.ExportPath != "" {
-		log.Printf("[DEBUG] Container will be exported to %s", b.config.ExportPath)
+		if !config.Push.IsDefault() {
+			steps = append(steps, &StepPush{
+				GeneratedData: generatedData,
+			})
+		}
+	} else if config.ExportFormat == ExportFormatOCI {
+		log.Printf("[DEBUG] Image will be exported to %s", exportDestination(config))
+		steps = append(steps, &StepSetDefaults{})
+		steps = append(steps, &StepCommit{
+			GeneratedData: generatedData,
+		})
+		steps = append(steps, new(StepExport))
+	} else if config.ExportPath != "" {
+		log.Printf("[DEBUG] Container will be exported to %s", config.ExportPath)
 		steps = append(steps, new(StepExport))
 	} else {
 		return nil, errArtifactNotUsed
 	}
 
+	steps = unlessUnchanged(withTimeouts(steps, config), config)
+
 	// Run!
-	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner = commonsteps.NewRunner(steps, config.PackerConfig, ui)
 	b.runner.Run(ctx, state)
 
 	// If there was an error, return that
@@ -117,30 +289,5 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		return nil, rawErr.(error)
 	}
 
-	// If it was cancelled, then just return
-	if _, ok := state.GetOk(multistep.StateCancelled); ok {
-		return nil, nil
-	}
-
-	// No errors, must've worked. Build the artifact.
-	stateData := map[string]interface{}{
-		"generated_data": state.Get("generated_data"),
-	}
-
-	var artifact packersdk.Artifact
-	if b.config.Commit {
-		artifact = &ImportArtifact{
-			IdValue:        state.Get("image_id").(string),
-			BuilderIdValue: BuilderIdImport,
-			Driver:         driver,
-			StateData:      stateData,
-		}
-	} else {
-		artifact = &ExportArtifact{
-			path:      b.config.ExportPath,
-			StateData: stateData,
-		}
-	}
-
-	return artifact, nil
+	return state, nil
 }
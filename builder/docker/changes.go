@@ -0,0 +1,141 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The instructions `docker commit --change` applies.
+var commitInstructions = []string{
+	"CMD", "ENTRYPOINT", "ENV", "EXPOSE", "LABEL", "MAINTAINER", "ONBUILD",
+	"STOPSIGNAL", "USER", "VOLUME", "WORKDIR",
+}
+
+// The instructions `nerdctl commit --change` applies.
+var nerdctlCommitInstructions = []string{"CMD", "ENTRYPOINT"}
+
+// The instructions `docker commit --change` does not apply, which are
+// applied by building the committed image with them instead.
+var buildInstructions = []string{"HEALTHCHECK", "SHELL"}
+
+// changeInstruction returns the instruction of the change, in upper case,
+// and its value.
+func changeInstruction(change string) (string, string) {
+	instruction, value, _ := strings.Cut(strings.TrimSpace(change), " ")
+	return strings.ToUpper(instruction), strings.TrimSpace(value)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateChange returns an error if the change is not a supported
+// Dockerfile instruction, or if its value is invalid.
+func ValidateChange(change string) error {
+	instruction, value := changeInstruction(change)
+	if !containsString(commitInstructions, instruction) && !containsString(buildInstructions, instruction) {
+		return fmt.Errorf("the %q change uses the unsupported %s instruction, expected one of %s",
+			change, instruction, strings.Join(append(append([]string{}, commitInstructions...), buildInstructions...), ", "))
+	}
+	if value == "" {
+		return fmt.Errorf("the %q change has no value", change)
+	}
+
+	switch instruction {
+	case "HEALTHCHECK":
+		if _, err := parseHealthcheck(value); err != nil {
+			return fmt.Errorf("the %q change is invalid: %s", change, err)
+		}
+	case "SHELL":
+		var shell []string
+		if err := json.Unmarshal([]byte(value), &shell); err != nil || len(shell) == 0 {
+			return fmt.Errorf("the %q change is invalid: SHELL expects a JSON array, like [\"/bin/bash\", \"-c\"]", change)
+		}
+	case "ONBUILD":
+		trigger, _ := changeInstruction(value)
+		if trigger == "ONBUILD" || trigger == "FROM" || trigger == "MAINTAINER" {
+			return fmt.Errorf("the %q change is invalid: ONBUILD can't trigger %s", change, trigger)
+		}
+	}
+
+	return nil
+}
+
+// splitChanges splits the changes applied by `docker commit` from the ones
+// that have to be built on top of the committed image.
+func splitChanges(changes []string) (commit []string, build []string) {
+	for _, change := range changes {
+		instruction, _ := changeInstruction(change)
+		if containsString(buildInstructions, instruction) {
+			build = append(build, change)
+		} else {
+			commit = append(commit, change)
+		}
+	}
+	return commit, build
+}
+
+// healthcheck is a parsed HEALTHCHECK instruction.
+type healthcheck struct {
+	// The options of the instruction, like `interval`, without the dashes.
+	Options map[string]string
+	// The command to run, `NONE` to disable the health check of the base
+	// image, or the command after `CMD`.
+	Cmd string
+}
+
+// parseHealthcheck parses the value of a HEALTHCHECK instruction, either
+// `NONE`, or `[--option=value...] CMD command`.
+func parseHealthcheck(value string) (*healthcheck, error) {
+	hc := &healthcheck{Options: map[string]string{}}
+
+	rest := strings.TrimSpace(value)
+	if strings.ToUpper(rest) == "NONE" {
+		hc.Cmd = "NONE"
+		return hc, nil
+	}
+
+	for strings.HasPrefix(rest, "--") {
+		var option string
+		option, rest, _ = strings.Cut(rest, " ")
+		rest = strings.TrimSpace(rest)
+
+		name, v, ok := strings.Cut(strings.TrimPrefix(option, "--"), "=")
+		if !ok {
+			return nil, fmt.Errorf("option %s has no value, expected --%s=value", option, name)
+		}
+		switch name {
+		case "interval", "timeout", "start-period", "start-interval":
+			if _, err := time.ParseDuration(v); err != nil {
+				return nil, fmt.Errorf("option --%s: %q is not a duration", name, v)
+			}
+		case "retries":
+			if n, err := strconv.Atoi(v); err != nil || n < 0 {
+				return nil, fmt.Errorf("option --retries: %q is not a number", v)
+			}
+		default:
+			return nil, fmt.Errorf("unknown option --%s", name)
+		}
+		hc.Options[name] = v
+	}
+
+	// The command is kept as written, it may be a JSON array.
+	keyword, cmd, _ := strings.Cut(rest, " ")
+	hc.Cmd = strings.TrimSpace(cmd)
+	if strings.ToUpper(keyword) != "CMD" || hc.Cmd == "" {
+		return nil, fmt.Errorf("HEALTHCHECK expects NONE, or CMD followed by a command")
+	}
+
+	return hc, nil
+}
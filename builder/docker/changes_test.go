@@ -0,0 +1,74 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateChange(t *testing.T) {
+	tc := []struct {
+		change string
+		err    bool
+	}{
+		{"CMD /bin/sh", false},
+		{"stopsignal SIGTERM", false},
+		{`SHELL ["/bin/bash", "-c"]`, false},
+		{"ONBUILD RUN make", false},
+		{"HEALTHCHECK NONE", false},
+		{"HEALTHCHECK --interval=30s --timeout=3s --start-period=5s --retries=3 CMD curl -f http://localhost/", false},
+		{`HEALTHCHECK CMD ["curl", "-f", "http://localhost/"]`, false},
+		{"ADD foo /foo", true},
+		{"RUN make", true},
+		{"USER", true},
+		{"SHELL /bin/bash", true},
+		{"ONBUILD ONBUILD RUN make", true},
+		{"HEALTHCHECK --interval=often CMD true", true},
+		{"HEALTHCHECK --frequency=5s CMD true", true},
+		{"HEALTHCHECK --retries CMD true", true},
+		{"HEALTHCHECK curl -f http://localhost/", true},
+		{"HEALTHCHECK CMD", true},
+	}
+
+	for _, c := range tc {
+		err := ValidateChange(c.change)
+		if c.err && err == nil {
+			t.Errorf("%s: should error", c.change)
+		}
+		if !c.err && err != nil {
+			t.Errorf("%s: unexpected error: %s", c.change, err)
+		}
+	}
+}
+
+func TestParseHealthcheck(t *testing.T) {
+	hc, err := parseHealthcheck("--interval=30s  --retries=3 CMD  curl -f  http://localhost/")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := &healthcheck{
+		Options: map[string]string{"interval": "30s", "retries": "3"},
+		Cmd:     "curl -f  http://localhost/",
+	}
+	if !reflect.DeepEqual(hc, expected) {
+		t.Fatalf("bad healthcheck: %#v", hc)
+	}
+}
+
+func TestSplitChanges(t *testing.T) {
+	commit, build := splitChanges([]string{
+		"CMD /bin/sh",
+		"HEALTHCHECK NONE",
+		"ENV FOO=bar",
+		`shell ["/bin/bash", "-c"]`,
+	})
+
+	if !reflect.DeepEqual(commit, []string{"CMD /bin/sh", "ENV FOO=bar"}) {
+		t.Errorf("bad commit changes: %v", commit)
+	}
+	if !reflect.DeepEqual(build, []string{"HEALTHCHECK NONE", `shell ["/bin/bash", "-c"]`}) {
+		t.Errorf("bad build changes: %v", build)
+	}
+}
@@ -5,18 +5,71 @@ package docker
 
 import (
 	"log"
+	"net"
+	"net/url"
+	"os"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 )
 
-func commHost(host string) func(multistep.StateBag) (string, error) {
+func commHost(config *Config) func(multistep.StateBag) (string, error) {
+	host := config.Comm.Host()
+	var daemon string
+	if config.PublishCommPort {
+		daemon = daemonHost(config.DockerHostConfig.endpoint())
+	}
 	return func(state multistep.StateBag) (string, error) {
 		if host != "" {
 			log.Printf("Using host value: %s", host)
 			return host, nil
 		}
+		if config.PublishCommPort {
+			log.Printf("Using the host of the daemon: %s", daemon)
+			return daemon, nil
+		}
 		containerId := state.Get("container_id").(string)
 		driver := state.Get("driver").(Driver)
 		return driver.IPAddress(containerId)
 	}
 }
+
+// commPort returns the port to connect to the communicator on: the port the
+// communicator port of the container is published on, if it is published.
+func commPort(config *Config) func(multistep.StateBag) (int, error) {
+	return func(state multistep.StateBag) (int, error) {
+		if !config.PublishCommPort {
+			return config.Comm.Port(), nil
+		}
+		containerId := state.Get("container_id").(string)
+		driver := state.Get("driver").(Driver)
+		return driver.PublishedPort(containerId, config.Comm.Port())
+	}
+}
+
+// daemonHost returns the host the ports of the containers are published on:
+// the host of the daemon for remote daemons, or the loopback address for
+// local ones.
+func daemonHost(dockerHost string) string {
+	if dockerHost == "" {
+		dockerHost = os.Getenv("DOCKER_HOST")
+	}
+
+	u, err := url.Parse(dockerHost)
+	if err != nil || (u.Scheme != "tcp" && u.Scheme != "ssh") || u.Hostname() == "" {
+		return "127.0.0.1"
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && ip.IsUnspecified() {
+		return "127.0.0.1"
+	}
+	return u.Hostname()
+}
+
+// localDaemon returns true if the daemon runs on the host of Packer, which
+// can then bind mount its files into the containers.
+func localDaemon(dockerHost string) bool {
+	host := daemonHost(dockerHost)
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+	return host == "localhost"
+}
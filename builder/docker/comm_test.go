@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"testing"
+)
+
+func TestDaemonHost(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+
+	for dockerHost, expected := range map[string]string{
+		"":                             "127.0.0.1",
+		"unix:///var/run/docker.sock":  "127.0.0.1",
+		"npipe:////./pipe/docker":      "127.0.0.1",
+		"tcp://0.0.0.0:2375":           "127.0.0.1",
+		"tcp://build.example.com:2376": "build.example.com",
+		"ssh://ci@10.0.0.5:2222":       "10.0.0.5",
+	} {
+		if got := daemonHost(dockerHost); got != expected {
+			t.Errorf("daemonHost(%q): expected %s, got %s", dockerHost, expected, got)
+		}
+	}
+
+	t.Setenv("DOCKER_HOST", "tcp://remote:2375")
+	if got := daemonHost(""); got != "remote" {
+		t.Errorf("should use DOCKER_HOST, got %s", got)
+	}
+}
+
+func TestLocalDaemon(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+
+	for dockerHost, expected := range map[string]bool{
+		"":                             true,
+		"unix:///var/run/docker.sock":  true,
+		"tcp://localhost:2375":         true,
+		"tcp://127.0.0.1:2375":         true,
+		"tcp://build.example.com:2376": false,
+		"ssh://ci@10.0.0.5:2222":       false,
+	} {
+		if got := localDaemon(dockerHost); got != expected {
+			t.Errorf("localDaemon(%q): expected %t, got %t", dockerHost, expected, got)
+		}
+	}
+}
+
+func TestCommPort(t *testing.T) {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	config := state.Get("config").(*Config)
+	config.Comm.Type = "ssh"
+	config.Comm.SSHPort = 22
+	driver := state.Get("driver").(*MockDriver)
+	driver.PublishedPortResult = 49153
+
+	port, err := commPort(config)(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if port != 22 || driver.PublishedPortCalled {
+		t.Fatalf("should use the port of the container: %d", port)
+	}
+
+	config.PublishCommPort = true
+	port, err = commPort(config)(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if port != 49153 || driver.PublishedPortId != "foo" || driver.PublishedPortPort != 22 {
+		t.Fatalf("should use the published port: %d", port)
+	}
+}
+
+func TestCommHost_remoteContext(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	testDockerContext(t, configDir, "b71199ebd070b36beab7317920c2c2f1d777df8d05e5527d8458fda57cb17a7a",
+		`{"Name": "remote", "Endpoints": {"docker": {"Host": "tcp://docker.example.com:2376", "SkipTLSVerify": false}}}`)
+
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Comm.Type = "ssh"
+	config.PublishCommPort = true
+	config.DockerContext = "remote"
+
+	host, err := commHost(config)(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if host != "docker.example.com" {
+		t.Fatalf("should use the host of the daemon of the context: %s", host)
+	}
+
+	// The context selected by the environment
+	config.DockerContext = ""
+	t.Setenv("DOCKER_CONTEXT", "remote")
+	if host, err := commHost(config)(state); err != nil || host != "docker.example.com" {
+		t.Fatalf("should use the host of the daemon of DOCKER_CONTEXT: %s, %v", host, err)
+	}
+}
@@ -6,6 +6,7 @@ package docker
 import (
 	"archive/tar"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,6 +14,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"syscall"
@@ -22,20 +24,27 @@ import (
 )
 
 type Communicator struct {
-	Executable    string
-	ContainerID   string
-	HostDir       string
-	ContainerDir  string
-	Version       *version.Version
-	Config        *Config
+	Executable   string
+	ContainerID  string
+	HostDir      string
+	ContainerDir string
+	Version      *version.Version
+	Config       *Config
+	// The options of the daemon the container runs on.
+	HostConfig    DockerHostConfig
 	ContainerUser string
 	lock          sync.Mutex
 	EntryPoint    []string
+	Snapshots     *snapshotter
 }
 
 var _ packersdk.Communicator = new(Communicator)
 
 func (c *Communicator) Start(ctx context.Context, remote *packersdk.RemoteCmd) error {
+	if handled, err := c.interceptCommand(remote); handled {
+		return err
+	}
+
 	dockerArgs := []string{
 		"exec",
 		"-i",
@@ -53,12 +62,43 @@ func (c *Communicator) Start(ctx context.Context, remote *packersdk.RemoteCmd) e
 			append([]string{"-u", c.Config.ExecUser}, dockerArgs[2:]...)...)
 	}
 
-	cmd := exec.Command(c.Executable, dockerArgs...)
+	if c.Config.Workdir != "" {
+		dockerArgs = append(dockerArgs[:2],
+			append([]string{"-w", c.Config.Workdir}, dockerArgs[2:]...)...)
+	}
+
+	if c.Config.ExecPrivileged {
+		dockerArgs = append(dockerArgs[:2],
+			append([]string{"--privileged"}, dockerArgs[2:]...)...)
+	}
+
+	if len(c.Config.ExecArgs) > 0 {
+		dockerArgs = append(dockerArgs[:2],
+			append(append([]string{}, c.Config.ExecArgs...), dockerArgs[2:]...)...)
+	}
+
+	env, err := execEnv(c.Config)
+	if err != nil {
+		return err
+	}
+	// Only the names are given to docker exec, which reads the values from
+	// its environment, so that they don't show in the logs.
+	var envArgs []string
+	for _, pair := range env {
+		name, _, _ := strings.Cut(pair, "=")
+		envArgs = append(envArgs, "-e", name)
+	}
+	dockerArgs = append(dockerArgs[:2], append(envArgs, dockerArgs[2:]...)...)
+
+	cmd := c.commandContext(ctx, dockerArgs...)
+	if len(env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
 
-	var (
-		stdin_w io.WriteCloser
-		err     error
-	)
+	var stdin_w io.WriteCloser
 
 	stdin_w, err = cmd.StdinPipe()
 	if err != nil {
@@ -83,14 +123,28 @@ func (c *Communicator) Start(ctx context.Context, remote *packersdk.RemoteCmd) e
 
 // Upload uploads a file to the docker container
 func (c *Communicator) Upload(dst string, src io.Reader, fi *os.FileInfo) error {
+	if c.Snapshots.skipping() {
+		log.Printf("Skipping upload to %s, it's in the resumed snapshot", dst)
+		return nil
+	}
 	if fi == nil {
 		return c.uploadReader(dst, src)
 	}
 	return c.uploadFile(dst, src, fi)
 }
 
-// uploadReader writes an io.Reader to a temporary file before uploading
+// uploadReader writes an io.Reader to a temporary file before uploading,
+// since the size of the file has to be known to stream it. Files are
+// streamed as they are.
 func (c *Communicator) uploadReader(dst string, src io.Reader) error {
+	if f, ok := src.(*os.File); ok {
+		fi, err := f.Stat()
+		offset, seekErr := f.Seek(0, io.SeekCurrent)
+		if err == nil && seekErr == nil && offset == 0 && fi.Mode().IsRegular() {
+			return c.uploadFile(dst, f, &fi)
+		}
+	}
+
 	// Create a temporary file to store the upload
 	tempfile, err := ioutil.TempFile(c.HostDir, "upload")
 	if err != nil {
@@ -118,8 +172,8 @@ func (c *Communicator) uploadFile(dst string, src io.Reader, fi *os.FileInfo) er
 	// command format: docker cp /path/to/infile containerid:/path/to/outfile
 	log.Printf("Copying to %s on container %s.", dst, c.ContainerID)
 
-	localCmd := exec.Command(c.Executable, "cp", "-",
-		fmt.Sprintf("%s:%s", c.ContainerID, filepath.Dir(dst)))
+	localCmd := c.command(c.cpArgs("-",
+		fmt.Sprintf("%s:%s", c.ContainerID, filepath.Dir(dst)))...)
 
 	stderrP, err := localCmd.StderrPipe()
 	if err != nil {
@@ -135,46 +189,113 @@ func (c *Communicator) uploadFile(dst string, src io.Reader, fi *os.FileInfo) er
 		return err
 	}
 
+	stderrOut, err := writeUploadArchive(stdin, stderrP, dst, src, fi)
+	if err != nil {
+		stopCopy(localCmd)
+		return err
+	}
+
+	if err := localCmd.Wait(); err != nil {
+		return fmt.Errorf("Failed to upload to '%s' in container: %s. %s.", dst, stderrOut, err)
+	}
+
+	if err := c.fixDestinationOwner(dst); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// verifyCommunicatorCLI returns an error if the docker communicator can't
+// find the binary it runs commands and copies files with. The api driver
+// doesn't otherwise need the binary, so Verify doesn't look for it.
+func verifyCommunicatorCLI(config *Config) error {
+	if config.DriverType != DriverAPI {
+		return nil
+	}
+	if config.Comm.Type != "docker" && config.Comm.Type != "dockerWindowsContainer" {
+		return nil
+	}
+
+	if _, err := exec.LookPath(config.Executable); err != nil {
+		return fmt.Errorf("The %s communicator runs `docker exec` and `docker cp`, even with the api driver, "+
+			"and %s can't be found: %s. Install the docker CLI, set `docker_path`, or use the ssh communicator",
+			config.Comm.Type, config.Executable, err)
+	}
+	return nil
+}
+
+// stopCopy kills a docker cp that failed midway and waits for it, so that
+// it's neither left running nor left unreaped.
+func stopCopy(cmd *exec.Cmd) {
+	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		log.Printf("Error killing %s: %s", strings.Join(cmd.Args, " "), err)
+	}
+	_ = cmd.Wait()
+}
+
+// writeUploadArchive writes the file as a tar archive to the stdin of the
+// copy, then reads what the copy wrote on stderr.
+func writeUploadArchive(stdin io.WriteCloser, stderr io.Reader, dst string, src io.Reader, fi *os.FileInfo) ([]byte, error) {
 	archive := tar.NewWriter(stdin)
 	header, err := tar.FileInfoHeader(*fi, "")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	header.Name = filepath.Base(dst)
 	if err := archive.WriteHeader(header); err != nil {
-		return fmt.Errorf("Failed to write header: %s", err)
+		return nil, fmt.Errorf("Failed to write header: %s", err)
 	}
 
-	numBytes, err := io.Copy(archive, src)
+	progress := &progressWriter{w: archive, name: dst, total: header.Size}
+	numBytes, err := io.Copy(progress, src)
 	if err != nil {
-		return fmt.Errorf("Failed to pipe upload: %s", err)
+		return nil, fmt.Errorf("Failed to pipe upload: %s", err)
 	}
 	log.Printf("Copied %d bytes for %s", numBytes, dst)
 
 	if err := archive.Close(); err != nil {
-		return fmt.Errorf("Failed to close archive: %s", err)
+		return nil, fmt.Errorf("Failed to close archive: %s", err)
 	}
 	if err := stdin.Close(); err != nil {
-		return fmt.Errorf("Failed to close stdin: %s", err)
+		return nil, fmt.Errorf("Failed to close stdin: %s", err)
 	}
 
-	stderrOut, err := ioutil.ReadAll(stderrP)
-	if err != nil {
-		return err
-	}
+	return ioutil.ReadAll(stderr)
+}
 
-	if err := localCmd.Wait(); err != nil {
-		return fmt.Errorf("Failed to upload to '%s' in container: %s. %s.", dst, stderrOut, err)
-	}
+// progressWriter logs the progress of the copy of a large file, every tenth
+// of its size.
+type progressWriter struct {
+	w     io.Writer
+	name  string
+	total int64
 
-	if err := c.fixDestinationOwner(dst); err != nil {
-		return err
-	}
+	written  int64
+	reported int64
+}
 
-	return nil
+// The size from which the progress of copies is logged.
+const progressMinSize = 64 * 1024 * 1024
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.total >= progressMinSize {
+		if tenth := p.written * 10 / p.total; tenth > p.reported {
+			p.reported = tenth
+			log.Printf("Copied %d of %d bytes for %s (%d%%)", p.written, p.total, p.name, tenth*10)
+		}
+	}
+	return n, err
 }
 
 func (c *Communicator) UploadDir(dst string, src string, exclude []string) error {
+	if c.Snapshots.skipping() {
+		log.Printf("Skipping upload to %s, it's in the resumed snapshot", dst)
+		return nil
+	}
+
 	/*
 		from https://docs.docker.com/engine/reference/commandline/cp/#extended-description
 		SRC_PATH specifies a directory
@@ -202,7 +323,7 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 	}
 
 	// Make the directory, then copy into it
-	localCmd := exec.Command(c.Executable, "cp", dockerSource, fmt.Sprintf("%s:%s", c.ContainerID, dst))
+	localCmd := c.command(c.cpArgs(dockerSource, fmt.Sprintf("%s:%s", c.ContainerID, dst))...)
 
 	stderrP, err := localCmd.StderrPipe()
 	if err != nil {
@@ -213,6 +334,7 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 	}
 	stderrOut, err := ioutil.ReadAll(stderrP)
 	if err != nil {
+		stopCopy(localCmd)
 		return err
 	}
 
@@ -232,8 +354,12 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 // path and want to write to an io.Writer, not a file. We use - to make docker
 // cp to write to stdout, and then copy the stream to our destination io.Writer.
 func (c *Communicator) Download(src string, dst io.Writer) error {
+	if c.Snapshots.skipping() {
+		log.Printf("Skipping download of %s, it's in the resumed snapshot", src)
+		return nil
+	}
 	log.Printf("Downloading file from container: %s:%s", c.ContainerID, src)
-	localCmd := exec.Command(c.Executable, "cp", fmt.Sprintf("%s:%s", c.ContainerID, src), "-")
+	localCmd := c.command("cp", fmt.Sprintf("%s:%s", c.ContainerID, src), "-")
 
 	pipe, err := localCmd.StdoutPipe()
 	if err != nil {
@@ -258,16 +384,17 @@ func (c *Communicator) Download(src string, dst io.Writer) error {
 	if err != nil {
 		// see if we can get a useful error message from stderr, since stdout
 		// is messed up.
-		if stderrOut, err := ioutil.ReadAll(stderrP); err == nil {
-			if string(stderrOut) != "" {
-				return fmt.Errorf("Error downloading file: %s", string(stderrOut))
-			}
+		stderrOut, readErr := ioutil.ReadAll(stderrP)
+		stopCopy(localCmd)
+		if readErr == nil && string(stderrOut) != "" {
+			return fmt.Errorf("Error downloading file: %s", string(stderrOut))
 		}
 		return fmt.Errorf("Failed to read header from tar stream: %s", err)
 	}
 
 	numBytes, err := io.Copy(dst, archive)
 	if err != nil {
+		stopCopy(localCmd)
 		return fmt.Errorf("Failed to pipe download: %s", err)
 	}
 	log.Printf("Copied %d bytes for %s", numBytes, src)
@@ -284,6 +411,23 @@ func (c *Communicator) DownloadDir(src string, dst string, exclude []string) err
 }
 
 // Runs the given command and blocks until completion
+// interceptCommand handles the snapshot requests of the docker-snapshot
+// provisioner, and skips the commands that ran before the resumed snapshot.
+// It returns false if the command has to run in the container.
+func (c *Communicator) interceptCommand(remote *packersdk.RemoteCmd) (bool, error) {
+	if name, ok := snapshotName(remote.Command); ok {
+		return true, c.Snapshots.handle(remote, name)
+	}
+
+	if c.Snapshots.skipping() {
+		log.Printf("Skipping command, it's in the resumed snapshot: %s", remote.Command)
+		remote.SetExited(0)
+		return true, nil
+	}
+
+	return false, nil
+}
+
 func (c *Communicator) run(cmd *exec.Cmd, remote *packersdk.RemoteCmd, stdin io.WriteCloser, stdout, stderr io.ReadCloser) {
 	// For Docker, remote communication must be serialized since it
 	// only supports single execution.
@@ -344,22 +488,90 @@ func (c *Communicator) run(cmd *exec.Cmd, remote *packersdk.RemoteCmd, stdin io.
 	remote.SetExited(exitStatus)
 }
 
+// command returns a command running the docker executable against the
+// daemon the container runs on.
+func (c *Communicator) command(args ...string) *exec.Cmd {
+	return c.commandContext(context.Background(), args...)
+}
+
+// commandContext returns a command like command that is killed once the
+// context is done.
+func (c *Communicator) commandContext(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, c.Executable, args...)
+	c.HostConfig.Apply(cmd)
+	return cmd
+}
+
+// uploadOwner returns the user the uploaded files are given to: the
+// `upload_owner`, or else the user the provisioners run as, which defaults
+// to the user of the container.
+func (c *Communicator) uploadOwner() string {
+	if c.Config.UploadOwner != "" {
+		return c.Config.UploadOwner
+	}
+	if c.Config.ExecUser != "" {
+		return c.Config.ExecUser
+	}
+	if c.ContainerUser != "" {
+		return c.ContainerUser
+	}
+	return "root"
+}
+
+// missingShellRe matches the errors of the engines when the shell a command
+// runs with doesn't exist in the container.
+var missingShellRe = regexp.MustCompile(`(?i)(executable file.*not found|stat /bin/sh: no such file)`)
+
+// archiveMode returns true if the uploaded files are given to the user of
+// the container by `cp --archive`, which the engines of the docker, api and
+// podman drivers support. This doesn't need a shell in the container, so
+// that images without one, like distroless images, can be provisioned.
+func (c *Communicator) archiveMode() bool {
+	if !c.Config.FixUploadOwner || c.Config.Rootless || c.Config.ExecUser != "" || c.Config.UploadOwner != "" {
+		return false
+	}
+	switch c.Config.DriverType {
+	case "", DriverCLI, DriverAPI, DriverPodman:
+		return true
+	}
+	return false
+}
+
+// cpArgs returns the arguments of the cp command uploading src to dst.
+func (c *Communicator) cpArgs(src, dst string) []string {
+	if c.archiveMode() {
+		return []string{"cp", "--archive", src, dst}
+	}
+	return []string{"cp", src, dst}
+}
+
 // TODO Workaround for #5307. Remove once #5409 is fixed.
 func (c *Communicator) fixDestinationOwner(destination string) error {
 	if !c.Config.FixUploadOwner {
 		return nil
 	}
 
-	owner := c.ContainerUser
-	if owner == "" {
-		owner = "root"
+	// Files can't be given away in the user namespace of rootless daemons.
+	if c.Config.Rootless {
+		log.Printf("Not setting the owner of %s, the daemon is rootless", destination)
+		return nil
+	}
+
+	// The owner was already set by cp.
+	if c.archiveMode() {
+		return nil
 	}
 
+	owner := c.uploadOwner()
 	chownArgs := []string{
-		c.Executable, "exec", "--user", "root", c.ContainerID, "/bin/sh", "-c",
+		"exec", "--user", "root", c.ContainerID, "/bin/sh", "-c",
 		fmt.Sprintf("chown -R %s %s", owner, destination),
 	}
-	if output, err := exec.Command(chownArgs[0], chownArgs[1:]...).CombinedOutput(); err != nil {
+	if output, err := c.command(chownArgs...).CombinedOutput(); err != nil {
+		if missingShellRe.Match(output) {
+			return fmt.Errorf("Failed to set owner of the uploaded file: the image has no /bin/sh to run chown with. "+
+				"Set `fix_upload_owner` to false, or unset `exec_user` and `upload_owner` to give the file to the user of the container: %s", output)
+		}
 		return fmt.Errorf("Failed to set owner of the uploaded file: %s, %s", err, output)
 	}
 
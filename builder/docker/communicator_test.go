@@ -4,15 +4,20 @@
 package docker
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/acctest"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
 // TestUploadDownload verifies that basic upload / download functionality works
@@ -178,3 +183,159 @@ func TestFixUploadOwner(t *testing.T) {
 		})
 	}
 }
+
+func TestCommunicator_uploadOwner(t *testing.T) {
+	comm := &Communicator{Config: testConfigStruct(t)}
+	if owner := comm.uploadOwner(); owner != "root" {
+		t.Fatalf("bad owner: %s", owner)
+	}
+
+	comm.ContainerUser = "app"
+	if owner := comm.uploadOwner(); owner != "app" {
+		t.Fatalf("bad owner: %s", owner)
+	}
+
+	// The files are given to the user the provisioners run as
+	comm.Config.ExecUser = "1000:1000"
+	if owner := comm.uploadOwner(); owner != "1000:1000" {
+		t.Fatalf("bad owner: %s", owner)
+	}
+
+	comm.Config.UploadOwner = "app:app"
+	if owner := comm.uploadOwner(); owner != "app:app" {
+		t.Fatalf("bad owner: %s", owner)
+	}
+}
+
+func TestCommunicator_archiveMode(t *testing.T) {
+	comm := &Communicator{Config: testConfigStruct(t)}
+	if args := comm.cpArgs("-", "foo:/tmp"); !reflect.DeepEqual(args, []string{"cp", "--archive", "-", "foo:/tmp"}) {
+		t.Fatalf("bad args: %v", args)
+	}
+
+	// The files are given to the exec_user with chown
+	comm.Config.ExecUser = "app"
+	if comm.archiveMode() {
+		t.Fatal("should chown the files to the exec_user")
+	}
+
+	// The files are given to the upload_owner with chown
+	comm.Config.ExecUser = ""
+	comm.Config.UploadOwner = "app"
+	if comm.archiveMode() {
+		t.Fatal("should chown the files to the upload_owner")
+	}
+
+	comm.Config.UploadOwner = ""
+	comm.Config.DriverType = DriverNerdctl
+	if args := comm.cpArgs("-", "foo:/tmp"); !reflect.DeepEqual(args, []string{"cp", "-", "foo:/tmp"}) {
+		t.Fatalf("bad args: %v", args)
+	}
+}
+
+func TestVerifyCommunicatorCLI(t *testing.T) {
+	config := testConfigStruct(t)
+	config.DriverType = DriverAPI
+	config.Executable = "packer-docker-missing"
+	if err := verifyCommunicatorCLI(config); err == nil {
+		t.Fatal("expected an error without the docker binary")
+	}
+
+	// The ssh communicator doesn't run the docker binary
+	config.Comm.Type = "ssh"
+	if err := verifyCommunicatorCLI(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config.Comm.Type = "docker"
+	config.Executable = "true"
+	if err := verifyCommunicatorCLI(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestStopCopy(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("can't run sleep: %s", err)
+	}
+
+	stopCopy(cmd)
+	if cmd.ProcessState == nil {
+		t.Fatal("expected the copy to be waited for")
+	}
+
+	// A copy that already exited is only waited for
+	cmd = exec.Command("true")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("can't run true: %s", err)
+	}
+	stopCopy(cmd)
+	if cmd.ProcessState == nil {
+		t.Fatal("expected the copy to be waited for")
+	}
+}
+
+func TestMissingShellRe(t *testing.T) {
+	outputs := []string{
+		`OCI runtime exec failed: exec failed: unable to start container process: exec: "/bin/sh": stat /bin/sh: no such file or directory: unknown`,
+		"Error: crun: executable file `/bin/sh` not found in $PATH: No such file or directory: OCI runtime attempted to invoke a command that was not found",
+	}
+	for _, output := range outputs {
+		if !missingShellRe.MatchString(output) {
+			t.Errorf("should match %q", output)
+		}
+	}
+	if missingShellRe.MatchString("chown: invalid user: 'app'") {
+		t.Error("should not match other errors")
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	var buf bytes.Buffer
+	progress := &progressWriter{w: &buf, name: "/tmp/big", total: progressMinSize}
+
+	chunk := make([]byte, progressMinSize/4)
+	for i := 0; i < 4; i++ {
+		if _, err := progress.Write(chunk); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if int64(buf.Len()) != progressMinSize {
+		t.Fatalf("bad size: %d", buf.Len())
+	}
+	if progress.reported != 10 {
+		t.Fatalf("should've reported the whole copy: %d", progress.reported)
+	}
+}
+
+func TestCommunicator_snapshots(t *testing.T) {
+	comm := &Communicator{Config: testConfigStruct(t)}
+
+	remote := &packersdk.RemoteCmd{Command: SnapshotCommand + " after-deps"}
+	if err := comm.Start(context.Background(), remote); err == nil {
+		t.Fatal("snapshots should require a snapshot repository")
+	}
+
+	comm.Snapshots = &snapshotter{driver: &MockDriver{}, repository: "build", resumeFrom: "after-deps"}
+
+	// The commands before the resumed snapshot are skipped
+	remote = &packersdk.RemoteCmd{Command: "apt-get install -y make"}
+	if err := comm.Start(context.Background(), remote); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if remote.ExitStatus() != 0 {
+		t.Fatalf("bad exit status: %d", remote.ExitStatus())
+	}
+	if err := comm.Upload("/tmp/script.sh", strings.NewReader("make"), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	remote = &packersdk.RemoteCmd{Command: SnapshotCommand + " after-deps"}
+	if err := comm.Start(context.Background(), remote); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if comm.Snapshots.skipping() {
+		t.Fatal("should run the commands after the resumed snapshot")
+	}
+}
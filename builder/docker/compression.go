@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// The compressions of the exported archives.
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ValidateCompression returns an error if the compression or its level are
+// unknown. An empty compression doesn't compress, and a zero level selects
+// the default level of the compression.
+func ValidateCompression(compression string, level int) error {
+	switch compression {
+	case "":
+		if level != 0 {
+			return fmt.Errorf("a compression level requires a compression")
+		}
+	case CompressionGzip:
+		if level < 0 || level > gzip.BestCompression {
+			return fmt.Errorf("the gzip compression level must be between 1 and %d, got %d", gzip.BestCompression, level)
+		}
+	case CompressionZstd:
+		if level < 0 || level > 22 {
+			return fmt.Errorf("the zstd compression level must be between 1 and 22, got %d", level)
+		}
+	default:
+		return fmt.Errorf("unknown compression %q, expected %s or %s", compression, CompressionGzip, CompressionZstd)
+	}
+	return nil
+}
+
+// nopWriteCloser is a writer whose Close does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// CompressWriter returns a writer compressing what is written to it to dst.
+// It has to be closed to flush the compressed data, which doesn't close dst.
+func CompressWriter(dst io.Writer, compression string, level int) (io.WriteCloser, error) {
+	switch compression {
+	case "":
+		return nopWriteCloser{dst}, nil
+	case CompressionGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(dst, level)
+	case CompressionZstd:
+		var opts []zstd.EOption
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		return zstd.NewWriter(dst, opts...)
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// decompressedReader is a reader of the decompressed contents of a file.
+type decompressedReader struct {
+	io.Reader
+	close func() error
+}
+
+func (r *decompressedReader) Close() error { return r.close() }
+
+// DecompressReader returns a reader of the decompressed contents of src, if
+// it was compressed with gzip or zstd, or else of src itself.
+func DecompressReader(src io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(src)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &decompressedReader{zr, func() error { zr.Close(); return nil }}, nil
+	}
+	return io.NopCloser(br), nil
+}
+
+// openArchive opens the archive at the given path, and decompresses it if
+// it is compressed, since not all the container engines import compressed
+// archives.
+func openArchive(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := DecompressReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Error decompressing %s: %s", path, err)
+	}
+	return &decompressedReader{r, func() error {
+		r.Close()
+		return f.Close()
+	}}, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateCompression(t *testing.T) {
+	for _, tc := range []struct {
+		compression string
+		level       int
+		ok          bool
+	}{
+		{"", 0, true},
+		{"", 3, false},
+		{CompressionGzip, 0, true},
+		{CompressionGzip, 9, true},
+		{CompressionGzip, 10, false},
+		{CompressionZstd, 19, true},
+		{CompressionZstd, 23, false},
+		{CompressionZstd, -1, false},
+		{"xz", 0, false},
+	} {
+		err := ValidateCompression(tc.compression, tc.level)
+		if (err == nil) != tc.ok {
+			t.Errorf("ValidateCompression(%q, %d): unexpected error %v", tc.compression, tc.level, err)
+		}
+	}
+}
+
+func TestCompressWriter(t *testing.T) {
+	data := bytes.Repeat([]byte("layer data "), 1000)
+
+	for _, compression := range []string{"", CompressionGzip, CompressionZstd} {
+		var compressed bytes.Buffer
+		w, err := CompressWriter(&compressed, compression, 0)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if compression != "" && compressed.Len() >= len(data) {
+			t.Errorf("%s: the data should be compressed", compression)
+		}
+
+		r, err := DecompressReader(&compressed)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		decompressed, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !bytes.Equal(decompressed, data) {
+			t.Errorf("%q: the data should round-trip", compression)
+		}
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.tar.zst")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	w, err := CompressWriter(f, CompressionZstd, 3)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := w.Write([]byte("data!")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	w.Close()
+	f.Close()
+
+	r, err := openArchive(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer r.Close()
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(contents) != "data!" {
+		t.Fatalf("bad: %q", contents)
+	}
+}
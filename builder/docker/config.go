@@ -2,14 +2,21 @@
 // SPDX-License-Identifier: MPL-2.0
 
 //go:generate packer-sdc struct-markdown
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config,AwsAccessConfig
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,AwsAccessConfig,DockerHostConfig,RegistryTLSConfig
 
 package docker
 
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
@@ -19,9 +26,22 @@ import (
 	"github.com/mitchellh/mapstructure"
 )
 
+// The policies of the `pull_policy` option.
+const (
+	PullAlways       = "always"
+	PullIfNotPresent = "if-not-present"
+	PullNever        = "never"
+)
+
+// The time to wait before the first retry of a pull.
+const defaultPullRetryBackoff = 5 * time.Second
+
+// The time to wait for the container to be healthy.
+const defaultHealthyTimeout = 5 * time.Minute
+
 var (
-	errArtifactNotUsed     = fmt.Errorf("No instructions given for handling the artifact; expected commit, discard, or export_path")
-	errArtifactUseConflict = fmt.Errorf("Cannot specify more than one of commit, discard, and export_path")
+	errArtifactNotUsed     = fmt.Errorf("No instructions given for handling the artifact; expected commit, discard, export_path or oci_layout_path")
+	errArtifactUseConflict = fmt.Errorf("Cannot specify more than one of commit, discard, export_path and oci_layout_path")
 	errExportPathNotFile   = fmt.Errorf("export_path must be a file, not a directory")
 )
 
@@ -40,33 +60,318 @@ type Config struct {
 	BuildConfig DockerfileBootstrapConfig `mapstructure:"build"`
 	// Set the author (e-mail) of a commit.
 	Author string `mapstructure:"author"`
-	// Dockerfile instructions to add to the commit. Example of instructions
-	// are CMD, ENTRYPOINT, ENV, and EXPOSE. Example: [ "USER ubuntu", "WORKDIR
-	// /app", "EXPOSE 8080" ]
+	// Dockerfile instructions to add to the commit. The supported
+	// instructions are CMD, ENTRYPOINT, ENV, EXPOSE, HEALTHCHECK, LABEL,
+	// MAINTAINER, ONBUILD, SHELL, STOPSIGNAL, USER, VOLUME and WORKDIR. Example: [ "USER
+	// ubuntu", "WORKDIR /app", "EXPOSE 8080" ]. Since `docker commit` can't
+	// apply HEALTHCHECK and SHELL, the committed image is built again with
+	// them.
 	Changes []string `mapstructure:"changes"`
+	// A mapping of labels set on the committed image, rendered when it is
+	// committed, so that OCI `org.opencontainers.image.*` labels can be set
+	// without a LABEL change for each. The values can use the build
+	// metadata: `{{.BuildName}}`, `{{.SourceImage}}`,
+	// `{{.SourceImageDigest}}`, `{{.PackerVersion}}`, `{{.Created}}`, the
+	// time of the commit in RFC 3339 format, `{{.GitCommit}}`,
+	// `{{.GitBranch}}` and `{{.GitRepository}}`, read from the environment
+	// of the CI system, and `{{.Vars.name}}`, the user variable `name`, as
+	// well as the `timestamp` and `isotime` functions. Requires `commit` or
+	// an OCI export, and can't be used with `commit` and the nerdctl
+	// driver. See the section on image labels.
+	Labels map[string]string `mapstructure:"labels" required:"false"`
+	// A mapping of OCI annotations set on the manifest of the image, which
+	// registries and policy engines read rather than the labels. The values
+	// are rendered like the `labels`. The images committed to the image
+	// store of Docker have no manifest until they are pushed, so this
+	// requires the `oci` `export_format`, whose manifest is rewritten with
+	// the annotations. See the section on image labels.
+	Annotations map[string]string `mapstructure:"annotations" required:"false"`
 	// If true, the container will be committed to an image rather than exported.
 	// Default `false`. If `commit` is `false`, then either `discard` must be
 	// set to `true` or an `export_path` must be provided.
 	Commit bool `mapstructure:"commit" required:"true"`
+	// If true, the committed image is flattened to a single layer, by
+	// exporting the container and importing it again with the configuration
+	// of the committed image. This keeps files that provisioners created and
+	// deleted out of the image, at the cost of not sharing the layers of the
+	// base image anymore. Requires `commit`, and is not supported by the
+	// buildah driver or for Windows containers. Default `false`.
+	Squash bool `mapstructure:"squash" required:"false"`
+	// If true, the container is paused before it is committed, and only
+	// unpaused when the build cleans it up, so that daemons left running by
+	// the provisioners can't change its filesystem while it's committed and
+	// squashed. Requires `commit`, and is not supported for Windows
+	// containers. Default `false`.
+	PauseBeforeCommit bool `mapstructure:"pause_before_commit" required:"false"`
+	// The signal sent to the container to stop it, like with `docker run
+	// --stop-signal`, for example `SIGINT` or `SIGQUIT`. When `stop_signal`
+	// or `stop_timeout` is set, the container is stopped before it is
+	// committed or exported, and before it is removed, so that the services
+	// started by the provisioners can shut down cleanly instead of being
+	// killed in the middle of a write. Defaults to the stop signal of the
+	// image, or `SIGTERM`. Not supported by the buildah, podman and nerdctl
+	// drivers, or for Windows containers.
+	StopSignal string `mapstructure:"stop_signal" required:"false"`
+	// The time the container has to stop after the `stop_signal`, like
+	// `30s`, before it is killed. Like `docker run --stop-timeout`, it's
+	// rounded to the second. Defaults to the default of the daemon, `10s`.
+	// Not supported by the buildah driver.
+	StopTimeout time.Duration `mapstructure:"stop_timeout" required:"false"`
+	// If true, the inputs of the build are hashed: the ID of the source
+	// image, the configuration of the builder and the `content_hash_files`.
+	// The hash is set as the `io.packer.content-hash` label of the committed
+	// image, and if a local image has the label with the same hash, it is
+	// the artifact of the build, without starting a container. Requires
+	// `commit`, and can't be used with `push` or the nerdctl driver. See the
+	// section on skipping unchanged builds. Default `false`.
+	SkipUnchanged bool `mapstructure:"skip_unchanged" required:"false"`
+	// The files the provisioners use, like their scripts, hashed with
+	// `skip_unchanged`, as glob patterns like `scripts/*.sh`. The
+	// directories matched are hashed with all their files.
+	ContentHashFiles []string `mapstructure:"content_hash_files" required:"false"`
+	// The repository the docker-snapshot provisioner tags the snapshots of
+	// the build container in, as `<repository>:<snapshot name>`. The last
+	// snapshot taken is also tagged as `<repository>:latest`. Required to
+	// take snapshots. Use a repository dedicated to the build, since the
+	// snapshots are removed once the build succeeds.
+	SnapshotRepository string `mapstructure:"snapshot_repository" required:"false"`
+	// If true, and a previous run of the build left snapshots in
+	// `snapshot_repository`, the container is started from the last snapshot
+	// taken instead of `image`, and the provisioners that ran before it are
+	// skipped. Default `false`.
+	Resume bool `mapstructure:"resume" required:"false"`
+	// Keep the snapshots after the build succeeds, instead of removing them.
+	// Default `false`.
+	KeepSnapshots bool `mapstructure:"keep_snapshots" required:"false"`
+	// Experimental. If true, the processes of the build container are
+	// checkpointed with CRIU along with each snapshot, and restored when the
+	// build resumes from it, so that the services the provisioners started
+	// before the snapshot are running again. Requires `snapshot_repository`,
+	// the docker or api driver, and a local daemon with the experimental
+	// features enabled and CRIU installed. Default `false`.
+	Checkpoint bool `mapstructure:"checkpoint" required:"false"`
+	// The absolute path of the directory the checkpoints are written to, on
+	// the host of the daemon. Defaults to a directory of the Packer cache
+	// named after the `snapshot_repository`.
+	CheckpointDir string `mapstructure:"checkpoint_dir" required:"false"`
 	// The directory inside container to mount temp directory from host server
 	// for work [file provisioner](/packer/docs/provisioners/file). This defaults
 	// to c:/packer-files on windows and /packer-files on other systems.
 	ContainerDir string `mapstructure:"container_dir" required:"false"`
 	// An array of devices which will be accessible in container when it's run
-	// without `--privileged` flag.
+	// without `--privileged` flag, in the same format as `docker run
+	// --device`: `host_path[:container_path[:permissions]]`, for example
+	// `/dev/kvm` or `/dev/fuse:/dev/fuse:rwm`.
 	Device []string `mapstructure:"device" required:"false"`
+	// An array of rules added to the device cgroup of the container, in the
+	// same format as `docker run --device-cgroup-rule`: `type major:minor
+	// permissions`, for example `c 189:* rwm` to allow access to the USB
+	// devices plugged in once the container started.
+	DeviceCgroupRules []string `mapstructure:"device_cgroup_rules" required:"false"`
+	// The GPUs to give to the container, in the same format as `docker run
+	// --gpus`, for example `all` or `"device=0,1"`. This lets provisioners
+	// compile CUDA code or install ML runtimes, and requires the
+	// [NVIDIA Container
+	// Toolkit](https://docs.nvidia.com/datacenter/cloud-native/container-toolkit/)
+	// on the Docker host.
+	Gpus string `mapstructure:"gpus" required:"false"`
+	// The name of the Docker network to attach the container to, so that it
+	// can reach other containers of that network by name, like databases or
+	// registries used by the provisioners. This can also be a network mode
+	// like `host`.
+	Network string `mapstructure:"network" required:"false"`
+	// If true, the `network` is created before the container starts if it
+	// doesn't exist, and removed once the build is complete. A network that
+	// already existed is joined, and not removed. Defaults to false.
+	NetworkCreate bool `mapstructure:"network_create" required:"false"`
+	// If true, the port of the `ssh` or `winrm` communicator is published
+	// on a random port of the Docker host, like `docker run --publish`, and
+	// Packer connects to it on the Docker host rather than to the address of
+	// the container, which isn't reachable with remote daemons or Docker
+	// Desktop. The image must run the server of the communicator. Defaults
+	// to false.
+	PublishCommPort bool `mapstructure:"publish_comm_port" required:"false"`
+	// Additional names the container can be reached with on the `network`.
+	NetworkAliases []string `mapstructure:"network_aliases" required:"false"`
+	// An array of additional entries of the `/etc/hosts` file of the
+	// container, in the same format as `docker run --add-host`:
+	// `hostname:ip`. The `host-gateway` ip resolves to the address of the
+	// Docker host, for example `host.docker.internal:host-gateway`.
+	ExtraHosts []string `mapstructure:"extra_hosts" required:"false"`
+	// An array of DNS servers the container resolves names with, like with
+	// `docker run --dns`. Defaults to the DNS servers of the Docker host.
+	Dns []string `mapstructure:"dns" required:"false"`
+	// An array of DNS search domains of the container, like with `docker run
+	// --dns-search`.
+	DnsSearch []string `mapstructure:"dns_search" required:"false"`
+	// An array of options of the resolver of the container, like with
+	// `docker run --dns-option`, for example `ndots:2`.
+	DnsOptions []string `mapstructure:"dns_options" required:"false"`
+	// The hostname of the container, like with `docker run --hostname`, so
+	// that the software that writes the hostname in its configuration
+	// during the provisioning, like databases or license managers, doesn't
+	// bake the random ID of the container into the image. Not supported by
+	// the buildah driver, or with the `host` network.
+	Hostname string `mapstructure:"hostname" required:"false"`
+	// The domain name of the container, like with `docker run
+	// --domainname`. Not supported by the buildah driver, or with the
+	// `host` network.
+	Domainname string `mapstructure:"domainname" required:"false"`
+	// The IPC namespace of the container, like with `docker run --ipc`:
+	// `host` to share the shared memory segments of the host, or
+	// `container:<name|id>` to share the ones of another container. Docker
+	// also supports `none`, `private` and `shareable`, and podman `private`
+	// and `ns:<path>`. With buildah, the modes are `host`, `container` or
+	// the path of a namespace.
+	IpcMode string `mapstructure:"ipc_mode" required:"false"`
+	// The PID namespace of the container, like with `docker run --pid`:
+	// `host` to see the processes of the host, or `container:<name|id>` to
+	// see the ones of another container. Podman also supports `private` and
+	// `ns:<path>`. With buildah, the modes are `host`, `container` or the
+	// path of a namespace.
+	PidMode string `mapstructure:"pid_mode" required:"false"`
+	// The cgroup namespace of the container, like with `docker run
+	// --cgroupns`: `host` to see the cgroups of the host, or `private` for a
+	// namespace of its own. Podman also supports `container:<name|id>` and
+	// `ns:<path>`. Defaults to the default of the daemon. Not supported for
+	// Windows containers.
+	CgroupnsMode string `mapstructure:"cgroupns" required:"false"`
+	// The parent cgroup of the container, like with `docker run
+	// --cgroup-parent`, so that it's accounted in the cgroup hierarchy of the
+	// CI system, like `/ci/jobs` or `ci-jobs.slice` with the systemd cgroup
+	// driver. Not supported for Windows containers.
+	CgroupParent string `mapstructure:"cgroup_parent" required:"false"`
 	// Throw away the container when the build is complete. This is useful for
 	// the [artifice
 	// post-processor](/packer/docs/post-processors/artifice).
 	Discard bool `mapstructure:"discard" required:"true"`
+	// If true, the container is left running when the build fails, rather
+	// than removed, so that the failure can be investigated in it, with
+	// `docker exec`. The ID of the container is printed, and its network and
+	// named volumes are kept too. The container must then be removed by
+	// hand. Unlike `-on-error=abort`, the rest of the build is cleaned up.
+	// Defaults to false.
+	KeepContainerOnError bool `mapstructure:"keep_container_on_error" required:"false"`
+	// If true, the output of the container, the stdout and stderr of its
+	// entrypoint, is shown in the Packer output as the container writes it,
+	// each line prefixed with `container:`. This tells why a container whose
+	// entrypoint exits right away can't be connected to. Not supported by the
+	// buildah driver. Defaults to false.
+	StreamLogs bool `mapstructure:"stream_logs" required:"false"`
+	// If true, the build pauses once the provisioners ran, and prints the
+	// command that opens a shell in the container, as the `exec_user` and in
+	// the `workdir`, so that the container can be inspected before it is
+	// committed or exported. The build continues when enter is pressed.
+	// Defaults to false.
+	PauseAfterProvision bool `mapstructure:"pause_after_provision" required:"false"`
+	// The commands run in the container once the provisioners ran, right
+	// before it is committed or exported, like `["apt-get clean", "rm -rf
+	// /var/lib/apt/lists/*"]`. They run with the communicator, like the
+	// `inline` commands of a shell provisioner, so that the cleanups are the
+	// same for all the templates. The build fails if one of them fails. Not
+	// supported with `discard` or the `none` communicator.
+	PreCommitCommands []string `mapstructure:"pre_commit_commands" required:"false"`
 	// An array of additional [Linux
 	// capabilities](https://docs.docker.com/engine/reference/run/#runtime-privilege-and-linux-capabilities)
-	// to grant to the container.
+	// to grant to the container, like `SYS_ADMIN`. This is a finer grained
+	// alternative to `privileged`.
 	CapAdd []string `mapstructure:"cap_add" required:"false"`
 	// An array of [Linux
 	// capabilities](https://docs.docker.com/engine/reference/run/#runtime-privilege-and-linux-capabilities)
 	// to drop from the container.
 	CapDrop []string `mapstructure:"cap_drop" required:"false"`
+	// An array of security options of the container, in the same format as
+	// `docker run --security-opt`, for example `seccomp=profile.json` to use
+	// a custom seccomp profile, `apparmor=unconfined`, or
+	// `label=type:container_runtime_t` to set an SELinux label. This is
+	// needed to run systemd or nested container tools in provisioners.
+	SecurityOpts []string `mapstructure:"security_opts" required:"false"`
+	// A mapping of the ulimits of the container, like with `docker run
+	// --ulimit`. The key is the name of the limit, like `nofile`, `nproc` or
+	// `memlock`, and the value is either the limit, or the soft and hard
+	// limits separated by a colon, like `"1024:65536"`. `-1` means unlimited.
+	Ulimits map[string]string `mapstructure:"ulimits" required:"false"`
+	// The number of CPUs the container can use, like with `docker run
+	// --cpus`, for example `1.5`. Defaults to all the CPUs of the host.
+	Cpus string `mapstructure:"cpus" required:"false"`
+	// The CPUs the container can run on, like with `docker run
+	// --cpuset-cpus`, for example `0-3` or `0,2`.
+	CpusetCpus string `mapstructure:"cpuset_cpus" required:"false"`
+	// The maximum amount of memory the container can use, like with `docker
+	// run --memory`, for example `2g`. If the container runs out of memory,
+	// the build fails with an error telling so.
+	Memory string `mapstructure:"memory" required:"false"`
+	// The maximum amount of memory and swap the container can use, like with
+	// `docker run --memory-swap`, for example `4g`, or `-1` for unlimited
+	// swap. Requires `memory`.
+	MemorySwap string `mapstructure:"memory_swap" required:"false"`
+	// The size of `/dev/shm` in the container, like with `docker run
+	// --shm-size`, for example `1g`. Headless browsers and some compilers
+	// need more than the default of `64m`.
+	ShmSize string `mapstructure:"shm_size" required:"false"`
+	// The adjustment of the OOM score of the processes of the container,
+	// like with `docker run --oom-score-adj`, from `-1000` to `1000`. A
+	// negative value makes the kernel kill other processes first when the
+	// host runs out of memory, so that memory-hungry compile steps on a busy
+	// CI host are not the first victims. Not supported by the buildah driver
+	// or for Windows containers.
+	OomScoreAdj int `mapstructure:"oom_score_adj" required:"false"`
+	// If true, the kernel doesn't kill the processes of the container when
+	// it reaches its `memory` limit, like with `docker run
+	// --oom-kill-disable`; they wait for memory to be freed instead. Only
+	// supported with cgroup v1, and requires `memory`, since the host could
+	// otherwise run out of memory. Not supported by the buildah driver or for
+	// Windows containers. Defaults to false.
+	OomKillDisable bool `mapstructure:"oom_kill_disable" required:"false"`
+	// A mapping of labels set on the build container, but not on the
+	// committed image, so that tooling like janitors or cost attribution can
+	// identify the build containers. The `io.packer.build-name` and
+	// `io.packer.run-uuid` labels are always set, with the name of the build
+	// and the UUID of the Packer run.
+	RunLabels map[string]string `mapstructure:"run_labels" required:"false"`
+	// The name of the build container, instead of a random one, so that
+	// monitoring and debugging tooling can find it, and cleanups of stale
+	// containers can match it. Like the `run_command`, it can use
+	// `{{.BuildName}}`, `{{.RunUUID}}`, `{{.Platform}}` and `{{.Vars.name}}`,
+	// like `packer-{{.BuildName}}-{{.RunUUID}}`. Since the names are unique,
+	// the builds that run at the same time need different names. Can't be
+	// used with a `--name` in `run_command`.
+	ContainerName string `mapstructure:"container_name" required:"false"`
+	// The driver to use to talk to Docker. Can be either `cli`, to run
+	// commands with the docker binary, `api`, to talk directly to the
+	// Docker Engine API, `podman`, to run commands with the podman binary,
+	// `nerdctl`, to run commands with nerdctl against containerd, or
+	// `buildah`, to run commands with buildah. Defaults to `cli`.
+	//
+	// The `podman` driver takes care of the differences between the podman
+	// and docker CLIs, and makes `docker_path` default to `podman`. To talk
+	// to a podman socket instead, use the `api` driver with `DOCKER_HOST`
+	// pointing to the socket.
+	//
+	// The `nerdctl` driver makes `docker_path` default to `nerdctl`, and
+	// works on hosts that run containerd without dockerd. Builds from a
+	// Dockerfile require BuildKit. When committing, `changes` are limited to
+	// `CMD` and `ENTRYPOINT`, and `HEALTHCHECK` and `SHELL`, which are
+	// applied by building the image, and `labels` and `skip_unchanged` are
+	// not supported.
+	//
+	// The `buildah` driver makes `docker_path` default to `buildah`, and
+	// builds images without a daemon, and without root privileges: the
+	// container is created with `buildah from`, provisioned with `buildah
+	// run`, and committed. `run_command` is ignored since no process runs
+	// in the container, and `export_path` is not supported.
+	//
+	// The `api` driver connects to the daemon set in the `DOCKER_HOST`
+	// environment variable, or to the default unix socket, with the client
+	// of the Docker SDK, which negotiates the version of the API with the
+	// daemon. It reports structured errors and progress from the daemon,
+	// but only supports the `-d`, `-i`, `-t` and `--entrypoint` arguments
+	// in `run_command`, and does not support `build.buildx`.
+	// **Note**: the docker communicator still uses the docker binary to run
+	// commands in the container and transfer files, so the build fails if
+	// `docker_path` can't be found, unless the ssh or winrm communicator is
+	// used.
+	DriverType string `mapstructure:"driver" required:"false"`
 	// Sets the docker binary to use for running commands.
 	//
 	// If you want to use a specific version of the docker binary, or a
@@ -79,10 +384,85 @@ type Config struct {
 	Executable string `mapstructure:"docker_path"`
 	// Username (UID) to run remote commands with. You can also set the group
 	// name/ID if you want: (UID or UID:GID). You may need this if you get
-	// permission errors trying to run the shell or other provisioners.
+	// permission errors trying to run the shell or other provisioners. The
+	// container itself still runs as the user of the image, and the uploaded
+	// files are given to this user, unless `fix_upload_owner` is false.
 	ExecUser string `mapstructure:"exec_user" required:"false"`
+	// The absolute path of the directory to run remote commands in, so that
+	// the provisioners don't have to `cd` to it first. Unlike a `WORKDIR`
+	// change, this doesn't change the working directory of the committed
+	// image. The directory must exist in the container. Defaults to the
+	// working directory of the image.
+	Workdir string `mapstructure:"workdir" required:"false"`
+	// If true, the remote commands run with a TTY, like `docker exec -t`,
+	// for the installers that refuse to run without one. Their output is
+	// then not split between stdout and stderr. Defaults to false.
+	ExecTty bool `mapstructure:"exec_tty" required:"false"`
+	// If true, the remote commands run with extended privileges, like
+	// `docker exec --privileged`, even if the container doesn't. Not
+	// supported by the buildah driver. Defaults to false.
+	ExecPrivileged bool `mapstructure:"exec_privileged" required:"false"`
+	// Additional arguments of `docker exec` for the remote commands, like
+	// `["--detach-keys", "ctrl-x"]`, or of `buildah run` with the buildah
+	// driver.
+	ExecArgs []string `mapstructure:"exec_args" required:"false"`
+	// A mapping of environment variables set for the commands run in the
+	// container during the build, like proxy variables or feature flags.
+	// Unlike `ENV` changes, they are not baked into the committed image.
+	ContainerEnv map[string]string `mapstructure:"container_env" required:"false"`
+	// A file of environment variables set for the commands run in the
+	// container during the build, in the same format as `docker run
+	// --env-file`: one `KEY=value` per line. `container_env` takes
+	// precedence over the variables of the file. They are not baked into the
+	// committed image either.
+	EnvFile string `mapstructure:"env_file" required:"false"`
+	// If true, the ssh agent of the host, the `SSH_AUTH_SOCK` socket, is
+	// mounted in the container, and `SSH_AUTH_SOCK` is set for the commands
+	// run in it, so that the provisioners can clone private git
+	// repositories without writing keys into the image. Requires the docker
+	// communicator and a local daemon. Defaults to false.
+	ForwardSSHAgent bool `mapstructure:"forward_ssh_agent" required:"false"`
+	// The proxy variables of the pulls, builds and pushes of the container
+	// engine, and optionally of the commands run in the container. See the
+	// section on proxies.
+	ProxyEnv ProxyEnvConfig `mapstructure:"proxy_env" required:"false"`
 	// The path where the final container will be exported as a tar file.
 	ExportPath string `mapstructure:"export_path" required:"true"`
+	// The format the container is exported in: `tar`, a flat tar file of the
+	// filesystem of the container, like `docker export`, or `oci`, the image
+	// committed from the container with the `changes`, as an OCI image
+	// layout. The layout is written to the `oci_layout_path` directory, or
+	// else to the `export_path` as a tar file. `oci` requires Docker 25 or
+	// later with the cli and api drivers. Defaults to `tar`, or to `oci` if
+	// `oci_layout_path` is set.
+	ExportFormat string `mapstructure:"export_format" required:"false"`
+	// The directory the image committed from the container is exported to,
+	// as an OCI image layout, which tools like crane, skopeo or ORAS take as
+	// input. The directory must be empty if it exists. This is set instead
+	// of `export_path`.
+	OCILayoutPath string `mapstructure:"oci_layout_path" required:"false"`
+	// The compression of the `export_path` file, either `gzip` or `zstd`.
+	// The docker-import post-processor decompresses the compressed exports.
+	// By default, the export isn't compressed.
+	ExportCompression string `mapstructure:"export_compression" required:"false"`
+	// The level of the `export_compression`, from 1 to 9 with gzip, and from
+	// 1 to 22 with zstd. Defaults to the default level of the compression.
+	ExportCompressionLevel int `mapstructure:"export_compression_level" required:"false"`
+	// Export only what the build changed, for pipelines that distribute the
+	// changes on top of a base the consumers already have. The `tar` export
+	// only has the files the container added or changed, and the `oci`
+	// export leaves out the layers of the source `image`. Defaults to false.
+	ExportDiffOnly bool `mapstructure:"export_diff_only" required:"false"`
+	// The digests of the layers left out of the `oci` export, either the
+	// digests of their blobs or their diff IDs, like `sha256:4f4fb700...`.
+	// The manifests still reference them, so that the export is completed
+	// with the blobs of these layers from elsewhere.
+	ExportExcludeLayers []string `mapstructure:"export_exclude_layers" required:"false"`
+	// A mapping of labels of local images, whose layers are left out of the
+	// `oci` export like with `export_exclude_layers`. For example, with
+	// `{ "com.example.base" = "true" }`, the layers of all the local images
+	// labelled as bases.
+	ExportExcludeLabeledLayers map[string]string `mapstructure:"export_exclude_labeled_layers" required:"false"`
 	// The base image for the Docker container that will be started. This image
 	// will be pulled from the Docker registry if it doesn't already exist.
 	// Any value format that you can provide to `docker pull` is valid.
@@ -93,6 +473,30 @@ type Config struct {
 	//
 	// This cannot be used at the same time as `build`
 	Image string `mapstructure:"image" required:"false"`
+	// The digest the source image must have, like `sha256:a0d9e826...`.
+	// The build fails if the image that is pulled, or found locally, has
+	// another digest, for example because its tag was moved to another
+	// image. When `image` is pinned with a digest, both must match.
+	//
+	// This cannot be used at the same time as `build`
+	SourceImageDigest string `mapstructure:"source_image_digest" required:"false"`
+	// Verifies the signature of the source image with cosign or notation
+	// before the container is started. See the section on signatures.
+	//
+	// This cannot be used at the same time as `build`
+	VerifySignature SignatureConfig `mapstructure:"verify_signature" required:"false"`
+	// Scans the source image for vulnerabilities with trivy, grype or docker
+	// scout before the container is started. See the section on
+	// vulnerability scans.
+	ScanImage ScanConfig `mapstructure:"scan_image" required:"false"`
+	// Pushes the committed image to a registry at the end of the build. The
+	// container is committed even if `commit` isn't set. See the section on
+	// pushing images.
+	Push PushConfig `mapstructure:"push" required:"false"`
+	// Waits for a port of the container to be listening, or for a line of
+	// its logs to match, before the provisioners run. See the section on
+	// waiting for containers.
+	WaitFor WaitForConfig `mapstructure:"wait_for" required:"false"`
 	// Set a message for the commit.
 	Message string `mapstructure:"message" required:"true"`
 	// If true, run the docker container with the `--privileged` flag. This
@@ -105,20 +509,103 @@ type Config struct {
 	// `runsc` for [gVisor](https://gvisor.dev/),
 	// `kata-runtime` for [Kata Containers](https://katacontainers.io/),
 	// `sysbox-runc` for [Nestybox](https://www.nestybox.com/).
+	// Packer checks that the daemon has the runtime before the build starts.
+	// See the section on sandboxed runtimes.
 	Runtime string `mapstructure:"runtime" required:"false"`
-	// If true, the configured image will be pulled using `docker pull` prior
-	// to use. Otherwise, it is assumed the image already exists and can be
-	// used. This defaults to true if not set.
+	// The user namespace of the container, like `docker run --userns`. With
+	// the cli and api drivers, the only mode is `host`, which runs the
+	// container in the user namespace of the host on daemons with
+	// `userns-remap` enabled, so that the uploaded files and the volumes
+	// keep the owners the communicator gives them. Podman also supports
+	// `keep-id`, `auto`, `nomap`, `private` and `ns:<path>`, and buildah
+	// `container`. Not supported by the nerdctl driver.
+	Userns string `mapstructure:"userns" required:"false"`
+	// Ranges of container user IDs to map to the IDs of the host with
+	// podman and buildah, as `container_id:host_id:amount`, like
+	// `0:100000:65536`. The container runs in a new user namespace, so
+	// this can't be set with `userns`.
+	UIDMap []string `mapstructure:"uidmap" required:"false"`
+	// The ranges of the group IDs, like `uidmap`.
+	GIDMap []string `mapstructure:"gidmap" required:"false"`
+	// If true, run an init as the PID 1 of the container, like `docker run
+	// --init`, which reaps the zombie processes the provisioners leave
+	// behind, so that they don't hang the container or the commit. Not
+	// supported by the buildah driver, which runs no process in the
+	// container, or with Windows containers.
+	Init bool `mapstructure:"init" required:"false"`
+	// If true, wait for the container to be healthy before connecting to it
+	// and running the provisioners, for the images whose `HEALTHCHECK` tells
+	// when their service is up, like databases. The build fails if the
+	// container becomes unhealthy, or if its image has no healthcheck. Not
+	// supported by the buildah driver.
+	WaitForHealthy bool `mapstructure:"wait_for_healthy" required:"false"`
+	// The time to wait for the container to be healthy with
+	// `wait_for_healthy`, like `10m`. Defaults to `5m`.
+	HealthyTimeout time.Duration `mapstructure:"healthy_timeout" required:"false"`
+	// Deprecated, use `pull_policy` instead. `true` is the same as
+	// `always`, and `false` as `if-not-present`, since the image missing
+	// locally used to be pulled when the container was run.
+	Pull bool `mapstructure:"pull" required:"false"`
+	// Pull-through caches to pull the image from instead of its registry,
+	// like the mirrors large organizations mandate to avoid the rate limits of
+	// Docker Hub. An entry is either the host of a mirror of Docker Hub, like
+	// `mirror.example.com`, or `<registry>=<mirror>` for other registries,
+	// like `quay.io=quay-mirror.example.com`. The mirror can be followed by
+	// the path the registry is mirrored under, like
+	// `harbor.example.com/dockerhub`. The mirrors are tried in order, and the
+	// image is pulled from its registry if none of them has it. Images built
+	// from a Dockerfile don't use the mirrors.
+	RegistryMirrors []string `mapstructure:"registry_mirrors" required:"false"`
+	// When to pull the configured image with `docker pull` before using it:
+	// `always`, `if-not-present` to only pull it if it's missing locally,
+	// like on air-gapped runners with pre-seeded images, or `never`, which
+	// fails the build if the image is missing locally. Defaults to `always`.
+	// The builds of a `packer build` run that use the same image pull it one
+	// at a time, and with `always`, only the first of them pulls it.
 	//
 	// If using `build`, this field will be ignored, as the `pull` option for
 	// this operation will instead have precedence.
-	Pull bool `mapstructure:"pull" required:"false"`
+	PullPolicy string `mapstructure:"pull_policy" required:"false"`
+	// The number of times a pull is retried when it fails with an error
+	// that looks transient, like a dropped connection, a TLS handshake
+	// timeout, a server error or a rate limit of the registry. Defaults to
+	// 0, the pull isn't retried.
+	PullRetries int `mapstructure:"pull_retries" required:"false"`
+	// The time to wait before the first retry of a pull, doubled after every
+	// retry up to a minute. Defaults to `5s`.
+	PullRetryBackoff time.Duration `mapstructure:"pull_retry_backoff" required:"false"`
+	// The time the pull of the image can take, retries included, like `10m`.
+	// A pull that takes longer is stopped, and the build fails. Defaults to
+	// no timeout.
+	PullTimeout time.Duration `mapstructure:"pull_timeout" required:"false"`
+	// Images pulled in parallel before the build container starts, like the
+	// images the provisioners run with a Docker daemon of the container, so
+	// that the provisioning doesn't wait for their pulls one after the
+	// other. The images follow the `pull_policy`, the `platform` and the
+	// `pull_retries`, and the `pull_timeout` applies to all of them.
+	PrePullImages []string `mapstructure:"pre_pull_images" required:"false"`
+	// The time the provisioners can run for, like `1h`. Defaults to no
+	// timeout.
+	ProvisionTimeout time.Duration `mapstructure:"provision_timeout" required:"false"`
+	// The time the commit or the export of the container can take. Defaults
+	// to no timeout.
+	CommitTimeout time.Duration `mapstructure:"commit_timeout" required:"false"`
+	// The time the whole build can take, like `2h`. The command that runs
+	// when it times out is stopped, and the build is cleaned up and fails.
+	// Defaults to no timeout.
+	BuildTimeout time.Duration `mapstructure:"build_timeout" required:"false"`
 	// An array of arguments to pass to docker run in order to run the
 	// container. By default this is set to `["-d", "-i", "-t",
 	// "--entrypoint=/bin/sh", "--", "{{.Image}}"]` if you are using a linux
 	// container, and `["-d", "-i", "-t", "--entrypoint=powershell", "--",
-	// "{{.Image}}"]` if you are running a windows container. `{{.Image}}` is a
-	// template variable that corresponds to the image template option. Passing
+	// "{{.Image}}"]` if you are running a windows container, with the
+	// `windows_shell` as entrypoint. `{{.Image}}` is a
+	// template variable that corresponds to the image template option. The
+	// arguments can also use `{{.BuildName}}`, the name of the build,
+	// `{{.RunUUID}}`, the UUID of the `packer build` run, `{{.Platform}}`, the
+	// `platform` option, and `{{.Vars.name}}`, the user variable `name`, for
+	// example to name or label the container with
+	// `--name={{.BuildName}}-{{.RunUUID}}`. Passing
 	// the entrypoint option this way will make it the default entrypoint of
 	// the resulting image, so running docker run -it --rm  will start the
 	// docker image from the /bin/sh shell interpreter; you could run a script
@@ -126,21 +613,118 @@ type Config struct {
 	// docker image embeds a binary intended to be run often, you should
 	// consider changing the default entrypoint to point to it.
 	RunCommand []string `mapstructure:"run_command" required:"false"`
+	// The entrypoint of the build container, in exec form, like
+	// `["/busybox/sh"]`. Unlike `run_command`, the values are not
+	// templated, and the entrypoint can have arguments. When set, the
+	// default `run_command` no longer overrides the entrypoint with
+	// `/bin/sh`, and `run_command` can't set `--entrypoint`. Set it to
+	// `[""]` to clear the entrypoint of the image.
+	Entrypoint []string `mapstructure:"entrypoint" required:"false"`
+	// The command of the build container, in exec form, given as arguments
+	// to the entrypoint. When set without `entrypoint`, the entrypoint of
+	// the image is kept.
+	Cmd []string `mapstructure:"cmd" required:"false"`
 	// An array of additional tmpfs volumes to mount into this container.
 	TmpFs []string `mapstructure:"tmpfs" required:"false"`
+	// If true, the root filesystem of the container is mounted read-only,
+	// like with `docker run --read-only`, so that the provisioners can only
+	// write to the `container_dir`, the volumes, the mounts and the `tmpfs`
+	// directories, like `/tmp` or `/run`, which must be given. A provisioner
+	// that writes anywhere else fails, rather than baking state in the
+	// image. Not supported by the buildah driver or for Windows containers.
+	// Defaults to false.
+	ReadOnly bool `mapstructure:"read_only" required:"false"`
 	// A mapping of additional volumes to mount into this container. The key of
-	// the object is the host path, the value is the container path.
+	// the object is the host path, or the name of a named volume, and the
+	// value is the container path.
 	Volumes map[string]string `mapstructure:"volumes" required:"false"`
-	// If true, files uploaded to the container will be owned by the user the
-	// container is running as. If false, the owner will depend on the version
-	// of docker installed in the system. Defaults to true.
+	// Additional mounts of the container, with the same options as `docker
+	// run --mount`. See the [mounts](#mounts) section.
+	Mounts []MountConfig `mapstructure:"mounts" required:"false"`
+	// Files and directories of the host copied into the container with
+	// `docker cp` before the provisioners run, which is much faster than the
+	// file provisioner for large files. See the [copy files](#copy-files)
+	// section.
+	CopyFiles []CopyFileConfig `mapstructure:"copy_files" required:"false"`
+	// Auxiliary containers started on the network of the build before the
+	// build container, and removed once the build is complete. See the
+	// section on sidecars.
+	Sidecars []SidecarConfig `mapstructure:"sidecar" required:"false"`
+	// Secrets the provisioners can read in `/run/secrets`, a tmpfs that is
+	// never committed. The build fails if a secret is found in the changes
+	// of the container before it is committed. See the section on build
+	// secrets.
+	Secrets []SecretConfig `mapstructure:"secrets" required:"false"`
+	// If true, the named volumes created for the build are not removed once
+	// it is complete, so they can be reused as caches by the next builds.
+	// Named volumes that existed before the build are never removed.
+	// Defaults to false.
+	KeepVolumes bool `mapstructure:"keep_volumes" required:"false"`
+	// A mapping of cache keys to the container paths the caches are mounted
+	// on, like `{ maven = "/root/.m2/repository" }`. Each cache is the named
+	// volume `packer-cache-<key>`, created by the first build and reused by
+	// the next ones, which is never removed by Packer nor committed. Give a
+	// new key, like one ending with a hash of the lock file, to start from
+	// an empty cache. Not supported by the buildah driver.
+	CacheVolumes map[string]string `mapstructure:"cache_volumes" required:"false"`
+	// If true, files uploaded to the container will be owned by the
+	// `upload_owner` or the `exec_user` if set, or else by the user the
+	// container is running as.
+	// The files are given to the user of the container by `docker cp
+	// --archive`, which works with images that have no shell, like
+	// distroless images, while the `exec_user` and the nerdctl and buildah
+	// drivers need a `chown` in the container. If false, the owner will
+	// depend on the version of docker installed in the system. Defaults to
+	// true.
 	FixUploadOwner bool `mapstructure:"fix_upload_owner" required:"false"`
+	// The owner the uploaded files are given to with `fix_upload_owner`,
+	// as `user`, `user:group` or numeric IDs, rather than the `exec_user`
+	// or the user of the container. This is for example the user the image
+	// runs as, when the provisioners run as root. The files are given to it
+	// with a `chown` in the container, except with the buildah driver, so
+	// the image needs a shell.
+	UploadOwner string `mapstructure:"upload_owner" required:"false"`
 	// If "true", tells Packer that you are building a Windows container
 	// running on a windows host. This is necessary for building Windows
 	// containers, because our normal docker bindings do not work for them.
+	// Defaults to true if `platform` is a windows platform, like
+	// `windows/amd64`. Requires a local daemon, unless the communicator is
+	// `none`, since the files are copied through a mounted directory.
 	WindowsContainer bool `mapstructure:"windows_container" required:"false"`
-	// Set platform if server is multi-platform capable
+	// The shell used to run commands in a Windows container, either
+	// `powershell` or `cmd`. This is also the entrypoint of the default
+	// `run_command`. Defaults to `powershell`.
+	WindowsShell string `mapstructure:"windows_shell" required:"false"`
+	// If true, Packer doesn't set up the WinRM service of a Windows container
+	// for the `winrm` communicator. By default, Packer creates the
+	// `winrm_username`, which defaults to `packer`, as an administrator with
+	// the `winrm_password`, which defaults to a random password, and enables
+	// a WinRM listener on the `winrm_port` that accepts them. Set it for
+	// images that run a WinRM service of their own. Defaults to false.
+	SkipWinRMSetup bool `mapstructure:"skip_winrm_setup" required:"false"`
+	// If true, tells Packer that the Docker daemon runs rootless, in a user
+	// namespace. The uploaded files are then not given to the container user,
+	// since the chown fails in the user namespace, and the options that need
+	// root on the host, like `privileged`, are rejected. Packer detects
+	// rootless daemons, so this only needs to be set when the detection is
+	// ambiguous, like with some remote hosts.
+	Rootless bool `mapstructure:"rootless" required:"false"`
+	// The platform to pull and run the image for, as `os/arch[/variant]`,
+	// like `linux/arm64`. Set it to build for another platform than the one
+	// of the host, with qemu emulation set up with binfmt_misc on the host.
+	// The platform that was used is available in the `SourceImagePlatform`
+	// and `ImagePlatform` generated variables.
 	Platform string `mapstructure:"platform" required:"false"`
+	// If true, and the `platform`, or a platform of `build.platforms`, has
+	// another architecture than the host of the daemon, the QEMU emulators
+	// of the architectures are installed on the host with the
+	// `binfmt_image`, before the build, so that emulated builds work on
+	// fresh runners. It runs a privileged container, so the daemon can't be
+	// rootless. Not supported by the buildah driver. Defaults to false.
+	BinfmtInstall bool `mapstructure:"binfmt_install" required:"false"`
+	// The image installing the QEMU emulators with `binfmt_install`, which
+	// is run with `--install <archs>`. Defaults to `tonistiigi/binfmt`.
+	BinfmtImage string `mapstructure:"binfmt_image" required:"false"`
 
 	// This is used to login to a private docker repository (e.g., dockerhub)
 	// to build or pull a private base container. For pushing to a private
@@ -157,10 +741,35 @@ type Config struct {
 	// only logs in for the duration of the build or pull step. If true,
 	// login_server is required and login, login_username, and login_password
 	// will be ignored. For more information see the section on ECR.
-	EcrLogin        bool `mapstructure:"ecr_login" required:"false"`
-	AwsAccessConfig `mapstructure:",squash"`
+	EcrLogin         bool `mapstructure:"ecr_login" required:"false"`
+	AwsAccessConfig  `mapstructure:",squash"`
+	DockerHostConfig `mapstructure:",squash"`
+
+	RegistryTLSConfig `mapstructure:",squash"`
+
+	// If true, the builder logs in to Google Artifact Registry, or Container
+	// Registry, with an access token of the Google application default
+	// credentials, like the ones of `GOOGLE_APPLICATION_CREDENTIALS` or of
+	// `gcloud auth application-default login`. `login_server` is required,
+	// like `us-docker.pkg.dev`, and login, login_username, and
+	// login_password will be ignored.
+	GarLogin bool `mapstructure:"gar_login" required:"false"`
+	// If true, the builder logs in to Azure Container Registry with a token
+	// exchanged for an Azure access token, from the Azure CLI if it's
+	// installed, or else from the managed identity of the virtual machine.
+	// `login_server` is required, like `myregistry.azurecr.io`, and login,
+	// login_username, and login_password will be ignored.
+	AcrLogin bool `mapstructure:"acr_login" required:"false"`
+	// If true, the builder logs in to the GitHub Container Registry with the
+	// token of the `GITHUB_TOKEN` or `GH_TOKEN` environment variable, like the
+	// one GitHub Actions provide. `login_server` defaults to `ghcr.io`, and
+	// login, login_username, and login_password will be ignored.
+	GhcrLogin bool `mapstructure:"ghcr_login" required:"false"`
 
 	ctx interpolate.Context
+
+	// The hash of the configuration, with `skip_unchanged`.
+	configHash string
 }
 
 func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
@@ -176,6 +785,9 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"run_command",
+				"labels",
+				"annotations",
+				"container_name",
 			},
 		},
 	}, raws...)
@@ -184,15 +796,40 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 	}
 
 	// Defaults
+	if strings.HasPrefix(c.Platform, "windows/") {
+		c.WindowsContainer = true
+	}
+
+	if c.WindowsContainer && c.WindowsShell == "" {
+		c.WindowsShell = "powershell"
+	}
+
 	if len(c.RunCommand) == 0 {
 		c.RunCommand = []string{"-d", "-i", "-t", "--entrypoint=/bin/sh", "--", "{{.Image}}"}
-		if c.WindowsContainer {
-			c.RunCommand = []string{"-d", "-i", "-t", "--entrypoint=powershell", "--", "{{.Image}}"}
+		if len(c.Entrypoint) > 0 || len(c.Cmd) > 0 {
+			c.RunCommand = []string{"-d", "-i", "-t", "--", "{{.Image}}"}
+		} else if c.WindowsContainer {
+			c.RunCommand = []string{"-d", "-i", "-t", "--entrypoint=" + c.WindowsShell, "--", "{{.Image}}"}
+		}
+	}
+
+	if c.DriverType == "" {
+		c.DriverType = DriverCLI
+	}
+
+	if c.BinfmtImage == "" {
+		c.BinfmtImage = defaultBinfmtImage
+	}
+
+	if c.ExportFormat == "" {
+		c.ExportFormat = ExportFormatTar
+		if c.OCILayoutPath != "" {
+			c.ExportFormat = ExportFormatOCI
 		}
 	}
 
 	if c.Executable == "" {
-		c.Executable = "docker"
+		c.Executable = DefaultExecutable(c.DriverType)
 	}
 
 	// Default to the normal Docker type
@@ -203,9 +840,50 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	// The address of a Windows container is only reachable from the Docker
+	// host, so the WinRM port is published, and the user Packer connects as
+	// is created in the container.
+	if c.WindowsContainer && c.Comm.Type == "winrm" {
+		c.PublishCommPort = true
+	}
+	if winrmSetup(c) {
+		if c.Comm.WinRMUser == "" {
+			c.Comm.WinRMUser = defaultWinRMUser
+		}
+		if c.Comm.WinRMPassword == "" {
+			password, err := randomWinRMPassword()
+			if err != nil {
+				return nil, err
+			}
+			c.Comm.WinRMPassword = password
+			packersdk.LogSecretFilter.Set(password)
+		}
+	}
+
 	var errs *packersdk.MultiError
 	var warnings []string
 
+	if err := ValidateDriverType(c.DriverType); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+
+	if c.DriverType == DriverAPI && c.BuildConfig.Buildx {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`build.buildx` is not supported by the api driver"))
+	}
+
+	errs = packersdk.MultiErrorAppend(errs, c.DockerHostConfig.Prepare(c.DriverType)...)
+	errs = packersdk.MultiErrorAppend(errs, c.RegistryTLSConfig.Prepare(c.DriverType)...)
+
+	if c.DriverType == DriverBuildah {
+		if c.ExportPath != "" && c.ExportFormat == ExportFormatTar {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`export_path` is not supported by the buildah driver, "+
+				"use `commit` or the `oci` `export_format` instead"))
+		}
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`windows_container` is not supported by the buildah driver"))
+		}
+	}
+
 	if !c.BuildConfig.IsDefault() {
 		_, err := c.BuildConfig.Prepare()
 		if err != nil {
@@ -216,51 +894,750 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 			errs = packersdk.MultiErrorAppend(errs, errors.New("`image` cannot be specified with a build config"))
 		}
 
-		if c.Pull {
-			warnings = append(warnings, "when running a bootstrap build, the `pull` option is ignored and is replaced by `build.pull` (true by default)")
+		if c.SourceImageDigest != "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`source_image_digest` cannot be specified with a build config"))
+		}
+
+		if !c.VerifySignature.IsDefault() {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`verify_signature` cannot be specified with a build config"))
+		}
+
+		if c.Pull || c.PullPolicy != "" {
+			warnings = append(warnings, "when running a bootstrap build, the `pull` and `pull_policy` options are ignored and are replaced by `build.pull` (true by default)")
 			c.Pull = false
 		}
+		c.PullPolicy = PullNever
 
 		c.BuildConfig.Platform = c.Platform
 
+		if len(c.BuildConfig.Platforms) > 0 {
+			if c.Platform != "" {
+				errs = packersdk.MultiErrorAppend(errs, errors.New("`platform` cannot be specified with `build.platforms`"))
+			}
+
+			if !c.Commit {
+				errs = packersdk.MultiErrorAppend(errs, errors.New("`build.platforms` requires `commit` to be set, as one image is committed per platform"))
+			}
+		}
+
 	} else {
 		// Default Pull if it wasn't set
-		hasPull := false
+		hasPull, hasPullPolicy := false, false
 		for _, k := range md.Keys {
-			if k == "pull" {
+			switch k {
+			case "pull":
 				hasPull = true
-				break
+			case "pull_policy":
+				hasPullPolicy = true
 			}
 		}
 
-		if !hasPull {
-			c.Pull = true
+		switch {
+		case hasPull && hasPullPolicy:
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`pull` and `pull_policy` cannot both be set, use `pull_policy`"))
+		case hasPull:
+			c.PullPolicy = PullAlways
+			if !c.Pull {
+				c.PullPolicy = PullIfNotPresent
+			}
+		case !hasPullPolicy:
+			c.PullPolicy = PullAlways
+		}
+
+		switch c.PullPolicy {
+		case PullAlways, PullIfNotPresent, PullNever:
+		default:
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`pull_policy` must be one of %s, %s or %s, got %q",
+				PullAlways, PullIfNotPresent, PullNever, c.PullPolicy))
+		}
+		c.Pull = c.PullPolicy != PullNever
+
+		if c.PullRetries < 0 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`pull_retries` can't be negative"))
+		}
+		if c.PullRetryBackoff < 0 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`pull_retry_backoff` can't be negative"))
+		} else if c.PullRetryBackoff == 0 {
+			c.PullRetryBackoff = defaultPullRetryBackoff
 		}
 
 		if c.Image == "" {
 			errs = packersdk.MultiErrorAppend(errs,
 				errors.New("missing 'image' attribute or 'build' section, either needs to be specified for a build to run."))
 		}
+
+		errs = packersdk.MultiErrorAppend(errs, c.VerifySignature.Prepare()...)
+
+		if c.SourceImageDigest != "" {
+			if !digestRe.MatchString(c.SourceImageDigest) {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`source_image_digest` %q is not a digest, like `sha256:<64 hex characters>`", c.SourceImageDigest))
+			} else if _, pinned, ok := strings.Cut(c.Image, "@"); ok && pinned != c.SourceImageDigest {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`image` is pinned to digest %s, not to the `source_image_digest` %s", pinned, c.SourceImageDigest))
+			}
+		}
+	}
+
+	for _, image := range c.PrePullImages {
+		if image == "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`pre_pull_images` can't have an empty image"))
+		}
 	}
 
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packersdk.MultiErrorAppend(errs, es...)
 	}
 
-	if (c.ExportPath != "" && c.Commit) || (c.ExportPath != "" && c.Discard) || (c.Commit && c.Discard) {
+	exporting := c.ExportPath != "" || c.OCILayoutPath != ""
+	if !c.Push.IsDefault() {
+		// Pushing commits the container, unless the build is discarded or
+		// exported instead, which it can't be pushed with.
+		if !exporting && !c.Discard {
+			c.Commit = true
+		}
+		if !c.Commit {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`push` requires `commit` to be enabled"))
+		}
+		if len(c.BuildConfig.Platforms) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`push` is not supported with `build.platforms`, "+
+				"use the docker-push post-processor"))
+		}
+		errs = packersdk.MultiErrorAppend(errs, c.Push.Prepare()...)
+	}
+	if (c.ExportPath != "" && c.OCILayoutPath != "") || (exporting && c.Commit) || (exporting && c.Discard) || (c.Commit && c.Discard) {
 		errs = packersdk.MultiErrorAppend(errs, errArtifactUseConflict)
 	}
 
-	if c.ExportPath == "" && !c.Commit && !c.Discard {
+	if !exporting && !c.Commit && !c.Discard {
 		errs = packersdk.MultiErrorAppend(errs, errArtifactNotUsed)
 	}
 
+	switch c.ExportFormat {
+	case ExportFormatTar, ExportFormatOCI:
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`export_format` must be either %s or %s, got %q",
+			ExportFormatTar, ExportFormatOCI, c.ExportFormat))
+	}
+
+	if err := ValidateCompression(c.ExportCompression, c.ExportCompressionLevel); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`export_compression`: %s", err))
+	}
+	if c.ExportCompression != "" && c.ExportPath == "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`export_compression` requires `export_path`"))
+	}
+
+	if c.filtersExport() && !exporting {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`export_diff_only`, `export_exclude_layers` and "+
+			"`export_exclude_labeled_layers` require `export_path` or `oci_layout_path`"))
+	}
+	if (len(c.ExportExcludeLayers) > 0 || len(c.ExportExcludeLabeledLayers) > 0) && c.ExportFormat != ExportFormatOCI {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`export_exclude_layers` and `export_exclude_labeled_layers` "+
+			"require the oci `export_format`, the tar export having no layers"))
+	}
+	for _, digest := range c.ExportExcludeLayers {
+		if !digestRe.MatchString(digest) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`export_exclude_layers`: %q is not a sha256 digest", digest))
+		}
+	}
+
+	if c.OCILayoutPath != "" {
+		if c.ExportFormat != ExportFormatOCI {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`oci_layout_path` requires the oci `export_format`"))
+		}
+		if fi, err := os.Stat(c.OCILayoutPath); err == nil {
+			if !fi.IsDir() {
+				errs = packersdk.MultiErrorAppend(errs, errors.New("`oci_layout_path` must be a directory, not a file"))
+			} else if entries, err := os.ReadDir(c.OCILayoutPath); err == nil && len(entries) > 0 {
+				errs = packersdk.MultiErrorAppend(errs, errors.New("`oci_layout_path` must be an empty directory"))
+			}
+		}
+	}
+
 	if c.ExportPath != "" {
 		if fi, err := os.Stat(c.ExportPath); err == nil && fi.IsDir() {
 			errs = packersdk.MultiErrorAppend(errs, errExportPathNotFile)
 		}
 	}
 
+	for _, change := range c.Changes {
+		if err := ValidateChange(change); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	if c.DriverType == DriverNerdctl && c.Commit {
+		for _, change := range c.Changes {
+			instruction, _ := changeInstruction(change)
+			if !containsString(nerdctlCommitInstructions, instruction) && !containsString(buildInstructions, instruction) {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`changes`: %s is not supported by the nerdctl driver, "+
+					"whose `nerdctl commit` only applies %s, and %s by building the image",
+					instruction, strings.Join(nerdctlCommitInstructions, " and "), strings.Join(buildInstructions, " and ")))
+			}
+		}
+		if len(c.Labels) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`labels` is not supported by the nerdctl driver when committing, "+
+				"since `nerdctl commit` can't set labels"))
+		}
+	}
+
+	if c.PublishCommPort {
+		if c.Comm.Type != "ssh" && c.Comm.Type != "winrm" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`publish_comm_port` requires the ssh or winrm communicator"))
+		}
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`publish_comm_port` is not supported by the buildah driver"))
+		}
+		if c.Network == "host" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`publish_comm_port` can't be set with the host network, "+
+				"the communicator is reachable on the port of the container"))
+		}
+	}
+
+	if len(c.PreCommitCommands) > 0 {
+		if c.Discard {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`pre_commit_commands` can't be used with `discard`"))
+		}
+		if c.Comm.Type == "none" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`pre_commit_commands` require a communicator"))
+		}
+	}
+
+	if c.SkipWinRMSetup && (!c.WindowsContainer || c.Comm.Type != "winrm") {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`skip_winrm_setup` requires `windows_container` and the winrm communicator"))
+	}
+
+	if c.SnapshotRepository != "" {
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`snapshot_repository` is not supported by windows containers"))
+		}
+		if c.Comm.Type != "docker" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`snapshot_repository` requires the docker communicator"))
+		}
+		if len(c.BuildConfig.Platforms) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`snapshot_repository` is not supported with `build.platforms`"))
+		}
+	}
+	if c.Checkpoint {
+		if c.SnapshotRepository == "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`checkpoint` requires `snapshot_repository` to be set"))
+		}
+		if c.DriverType != DriverCLI && c.DriverType != DriverAPI {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`checkpoint` is not supported by the %s driver", c.DriverType))
+		}
+		if c.CheckpointDir == "" && c.SnapshotRepository != "" {
+			dir, err := packersdk.CachePath("docker-checkpoints", checkpointDirName(c.SnapshotRepository))
+			if err != nil {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error finding the checkpoint directory: %s", err))
+			}
+			c.CheckpointDir = dir
+		}
+		if c.CheckpointDir != "" && !filepath.IsAbs(c.CheckpointDir) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`checkpoint_dir` must be an absolute path, got %q", c.CheckpointDir))
+		}
+	} else if c.CheckpointDir != "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`checkpoint_dir` requires `checkpoint`"))
+	}
+	if c.Resume {
+		if c.SnapshotRepository == "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`resume` requires `snapshot_repository` to be set"))
+		}
+		if !c.BuildConfig.IsDefault() {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`resume` is not supported with a build config, since the snapshots replace the built image"))
+		}
+	}
+
+	for _, mirror := range c.RegistryMirrors {
+		if _, err := parseRegistryMirror(mirror); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
+	if c.Platform != "" && !platformRe.MatchString(c.Platform) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`platform`: %q is not a platform, expected `os/arch[/variant]`, like `linux/arm64`", c.Platform))
+	}
+
+	if c.PauseBeforeCommit {
+		if !c.Commit {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`pause_before_commit` requires `commit` to be enabled"))
+		}
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`pause_before_commit` is not supported by windows containers"))
+		}
+	}
+
+	if c.StopSignal != "" && !signalRe.MatchString(c.StopSignal) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`stop_signal`: %q is not a signal, like `SIGTERM` or `15`", c.StopSignal))
+	}
+	if c.StopTimeout < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`stop_timeout` can't be negative"))
+	}
+	if c.stopsContainer() {
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`stop_signal` and `stop_timeout` are not supported by the buildah driver, which runs no process in the container"))
+		}
+		if c.PauseBeforeCommit {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`pause_before_commit` can't be used with `stop_signal` or `stop_timeout`, "+
+				"which stop the container before the commit"))
+		}
+	}
+	if c.StopSignal != "" && c.WindowsContainer {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`stop_signal` is not supported by windows containers"))
+	}
+	if c.StopSignal != "" && c.DriverType == DriverPodman {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`stop_signal` is not supported by the podman driver, "+
+			"whose `podman stop` only sends the stop signal of the image"))
+	}
+	if c.StopSignal != "" && c.DriverType == DriverNerdctl {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`stop_signal` is not supported by the nerdctl driver, "+
+			"whose `nerdctl stop` has no `--signal` flag"))
+	}
+
+	if c.BinfmtInstall {
+		if c.Platform == "" && len(c.BuildConfig.Platforms) == 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`binfmt_install` requires a `platform` to emulate"))
+		}
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`binfmt_install` is not supported by the buildah driver"))
+		}
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`binfmt_install` is not supported by windows containers"))
+		}
+	}
+
+	if len(c.Labels) > 0 && !c.Commit && c.ExportFormat != ExportFormatOCI {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`labels` requires `commit` or an OCI export, "+
+			"use `run_labels` to label the build container"))
+	}
+	for _, key := range sortedKeys(c.Labels) {
+		if key == "" || strings.ContainsAny(key, " =\"'") {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`labels`: %q is not a valid label key", key))
+		}
+		if err := interpolate.Validate(c.Labels[key], &c.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`labels`: the value of %s is not a valid template: %s", key, err))
+		}
+	}
+
+	if len(c.Annotations) > 0 && (c.ExportFormat != ExportFormatOCI || !exporting) {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`annotations` requires the oci `export_format`, "+
+			"images committed to the image store having no manifest"))
+	}
+	for _, key := range sortedKeys(c.Annotations) {
+		if key == "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`annotations`: the keys can't be empty"))
+		}
+		if err := interpolate.Validate(c.Annotations[key], &c.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`annotations`: the value of %s is not a valid template: %s", key, err))
+		}
+	}
+
+	if c.SkipUnchanged {
+		if !c.Commit {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`skip_unchanged` requires `commit` to be enabled"))
+		}
+		if c.DriverType == DriverNerdctl {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`skip_unchanged` is not supported by the nerdctl driver, "+
+				"since `nerdctl commit` can't set the content hash label"))
+		}
+		if !c.Push.IsDefault() {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`skip_unchanged` can't be used with `push`, "+
+				"use the docker-push post-processor"))
+		}
+		hash, err := configHash(raws)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error hashing the configuration: %s", err))
+		}
+		c.configHash = hash
+	} else if len(c.ContentHashFiles) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`content_hash_files` requires `skip_unchanged`"))
+	}
+	for _, pattern := range c.ContentHashFiles {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`content_hash_files`: %q is not a valid pattern: %s", pattern, err))
+		}
+	}
+
+	if c.Squash {
+		if !c.Commit {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`squash` requires `commit` to be enabled"))
+		}
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`squash` is not supported by the buildah driver"))
+		}
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`squash` is not supported by windows containers"))
+		}
+	}
+
+	if c.WindowsContainer {
+		switch c.WindowsShell {
+		case "powershell", "cmd":
+		default:
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`windows_shell` must be either powershell or cmd, got %q", c.WindowsShell))
+		}
+
+		for _, change := range c.Changes {
+			if err := validateWindowsChange(change); err != nil {
+				errs = packersdk.MultiErrorAppend(errs, err)
+			}
+		}
+
+		// The communicator of windows containers, and the setup of WinRM,
+		// copy the files through the temporary directory mounted in the
+		// container.
+		if c.Comm.Type != "none" && !localDaemon(c.DockerHostConfig.endpoint()) {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("windows containers require a local daemon, "+
+				"the temporary directory the files are copied through can't be mounted from a remote host"))
+		}
+	} else if c.WindowsShell != "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`windows_shell` requires `windows_container` to be enabled"))
+	}
+
+	if c.Gpus != "" {
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`gpus` is not supported by the buildah driver"))
+		}
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`gpus` is not supported by windows containers"))
+		}
+		if _, err := parseGPUs(c.Gpus); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
+	capabilities := map[string][]string{"cap_add": c.CapAdd, "cap_drop": c.CapDrop}
+	for _, name := range sortedKeys(capabilities) {
+		for _, capability := range capabilities[name] {
+			if !capabilityRe.MatchString(capability) {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`%s`: %q is not a Linux capability, expected a name like `SYS_ADMIN` or `ALL`", name, capability))
+			}
+		}
+	}
+
+	if c.EnvFile != "" {
+		if _, err := readEnvFile(c.EnvFile); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	for k := range c.ContainerEnv {
+		if k == "" || strings.ContainsAny(k, "= ") {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`container_env`: %q is not a valid variable name", k))
+		}
+	}
+	errs = packersdk.MultiErrorAppend(errs, c.ProxyEnv.Prepare()...)
+
+	if c.ForwardSSHAgent {
+		if os.Getenv("SSH_AUTH_SOCK") == "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`forward_ssh_agent` requires an ssh agent, SSH_AUTH_SOCK is not set"))
+		}
+		if c.Comm.Type != "docker" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`forward_ssh_agent` requires the docker communicator"))
+		}
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`forward_ssh_agent` is not supported by the buildah driver"))
+		}
+		if !localDaemon(c.DockerHostConfig.endpoint()) {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`forward_ssh_agent` requires a local daemon, "+
+				"the socket of the ssh agent can't be mounted from a remote host"))
+		}
+	}
+	if len(c.Entrypoint) > 0 {
+		for _, arg := range c.RunCommand {
+			if arg == "--" {
+				break
+			}
+			if arg == "--entrypoint" || strings.HasPrefix(arg, "--entrypoint=") {
+				errs = packersdk.MultiErrorAppend(errs, errors.New("`entrypoint` can't be set with an `--entrypoint` in `run_command`"))
+				break
+			}
+		}
+	}
+	if c.ContainerName != "" {
+		if err := interpolate.Validate(c.ContainerName, &c.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`container_name` is not a valid template: %s", err))
+		}
+		for _, arg := range c.RunCommand {
+			if arg == "--" {
+				break
+			}
+			if arg == "--name" || strings.HasPrefix(arg, "--name=") {
+				errs = packersdk.MultiErrorAppend(errs, errors.New("`container_name` can't be set with a `--name` in `run_command`"))
+				break
+			}
+		}
+	}
+	errs = packersdk.MultiErrorAppend(errs, c.ScanImage.Prepare(c.DriverType)...)
+	errs = packersdk.MultiErrorAppend(errs, c.WaitFor.Prepare(c.DriverType)...)
+
+	if c.Cpus != "" {
+		if _, err := parseCpus(c.Cpus); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	if c.CpusetCpus != "" && !cpusetRe.MatchString(c.CpusetCpus) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`cpuset_cpus` must be a list of CPUs like `0-3` or `0,2`, got %q", c.CpusetCpus))
+	}
+	var memory int64
+	if c.Memory != "" {
+		memory, err = parseBytes(c.Memory)
+		if err != nil || memory == -1 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`memory`: %q is not a valid size", c.Memory))
+		}
+	}
+	if c.MemorySwap != "" {
+		swap, err := parseBytes(c.MemorySwap)
+		switch {
+		case err != nil:
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`memory_swap`: %s", err))
+		case c.Memory == "":
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`memory_swap` requires `memory`"))
+		case swap != -1 && swap < memory:
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`memory_swap` must be greater than or equal to `memory`"))
+		}
+	}
+
+	if c.ShmSize != "" {
+		if size, err := parseBytes(c.ShmSize); err != nil || size == -1 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`shm_size`: %q is not a valid size", c.ShmSize))
+		}
+	}
+
+	if c.OomScoreAdj < -1000 || c.OomScoreAdj > 1000 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`oom_score_adj` must be between -1000 and 1000, got %d", c.OomScoreAdj))
+	}
+	if c.OomKillDisable && c.Memory == "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`oom_kill_disable` requires `memory`, or the host could run out of memory"))
+	}
+	if c.OomScoreAdj != 0 || c.OomKillDisable {
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`oom_score_adj` and `oom_kill_disable` are not supported by the buildah driver"))
+		}
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`oom_score_adj` and `oom_kill_disable` are not supported by windows containers"))
+		}
+	}
+
+	for name, value := range c.Ulimits {
+		if _, err := parseUlimit(name, value); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
+	for _, opt := range c.SecurityOpts {
+		if err := validateSecurityOpt(opt); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
+	for _, device := range c.Device {
+		if err := validateDevice(device); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	for _, rule := range c.DeviceCgroupRules {
+		if !deviceCgroupRuleRe.MatchString(rule) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`device_cgroup_rules`: %q is invalid, expected a rule like `c 189:* rwm`", rule))
+		}
+	}
+	if len(c.DeviceCgroupRules) > 0 && (c.DriverType == DriverBuildah || c.DriverType == DriverNerdctl) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`device_cgroup_rules` is not supported by the %s driver", c.DriverType))
+	}
+
+	for i := range c.Mounts {
+		for _, err := range c.Mounts[i].Prepare() {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	for i := range c.CopyFiles {
+		for _, err := range c.CopyFiles[i].Prepare() {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	if len(c.CopyFiles) > 0 && c.WindowsContainer {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`copy_files` is not supported for Windows containers, which can't be copied to while running"))
+	}
+	for key, target := range c.CacheVolumes {
+		if !idRe.MatchString(key) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`cache_volumes`: %q is not a valid cache key", key))
+		}
+		if !c.WindowsContainer && !path.IsAbs(target) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`cache_volumes`: the path %q of the cache %s must be absolute", target, key))
+		}
+	}
+
+	if c.ReadOnly {
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`read_only` is not supported by the buildah driver"))
+		}
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`read_only` is not supported by windows containers"))
+		}
+	}
+
+	if c.DriverType == DriverBuildah {
+		if len(c.CacheVolumes) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`cache_volumes` is not supported by the buildah driver"))
+		}
+		if len(c.Mounts) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`mounts` is not supported by the buildah driver, use `volumes` instead"))
+		}
+		for host := range c.Volumes {
+			if isNamedVolume(host) {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("named volume %q is not supported by the buildah driver", host))
+			}
+		}
+	}
+
+	if c.Network == "" && (c.NetworkCreate || len(c.NetworkAliases) > 0) {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`network_create` and `network_aliases` require a `network`"))
+	}
+	for _, host := range c.ExtraHosts {
+		if err := validateExtraHost(host); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
+	for _, server := range c.Dns {
+		if net.ParseIP(server) == nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`dns`: %q is not an ip address", server))
+		}
+	}
+
+	sidecars := make(map[string]bool)
+	for i := range c.Sidecars {
+		errs = packersdk.MultiErrorAppend(errs, c.Sidecars[i].Prepare(c.Network)...)
+		if name := c.Sidecars[i].Name; sidecars[name] {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`sidecar`: the name %q is used more than once", name))
+		}
+		sidecars[c.Sidecars[i].Name] = true
+	}
+	if len(c.Sidecars) > 0 && c.DriverType == DriverBuildah {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`sidecar` is not supported by the buildah driver"))
+	}
+
+	secrets := make(map[string]bool)
+	targets := make(map[string]bool)
+	for i := range c.Secrets {
+		errs = packersdk.MultiErrorAppend(errs, c.Secrets[i].Prepare()...)
+		if id := c.Secrets[i].ID; secrets[id] {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`secrets`: the id %q is used more than once", id))
+		}
+		if target := c.Secrets[i].Target; targets[target] {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`secrets`: the target %s is used more than once", target))
+		}
+		secrets[c.Secrets[i].ID] = true
+		targets[c.Secrets[i].Target] = true
+	}
+	if len(c.Secrets) > 0 {
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`secrets` are not supported by the buildah driver, which mounts no tmpfs"))
+		}
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`secrets` are not supported with Windows containers"))
+		}
+		if c.Comm.Type == "none" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`secrets` require a communicator to be written to the container"))
+		}
+	}
+
+	if c.Network != "" && c.DriverType == DriverBuildah {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`network` is not supported by the buildah driver"))
+	}
+
+	if c.UploadOwner != "" {
+		if !c.FixUploadOwner {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`upload_owner` requires `fix_upload_owner`"))
+		}
+		if c.WindowsContainer {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`upload_owner` is not supported for Windows containers"))
+		}
+		if !ownerRe.MatchString(c.UploadOwner) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`upload_owner`: %q is invalid, expected `user` or `user:group`", c.UploadOwner))
+		}
+	}
+
+	if c.Hostname != "" || c.Domainname != "" {
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`hostname` and `domainname` are not supported by the buildah driver"))
+		}
+		if c.Network == "host" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`hostname` and `domainname` can't be set with the host network, "+
+				"the container has the hostname of the host"))
+		}
+	}
+	for _, name := range []string{c.Hostname, c.Domainname} {
+		if name != "" && !hostnameRe.MatchString(name) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("%q is not a valid hostname", name))
+		}
+	}
+
+	timeouts := []struct {
+		option  string
+		timeout time.Duration
+	}{
+		{"pull_timeout", c.PullTimeout},
+		{"provision_timeout", c.ProvisionTimeout},
+		{"commit_timeout", c.CommitTimeout},
+		{"build_timeout", c.BuildTimeout},
+		{"healthy_timeout", c.HealthyTimeout},
+	}
+	for _, t := range timeouts {
+		if t.timeout < 0 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`%s` can't be negative", t.option))
+		}
+	}
+
+	if c.Workdir != "" && !c.WindowsContainer && !path.IsAbs(c.Workdir) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("`workdir` must be an absolute path, got %q", c.Workdir))
+	}
+
+	if c.ExecTty {
+		c.Pty = true
+	}
+	if c.ExecPrivileged && c.DriverType == DriverBuildah {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`exec_privileged` is not supported by the buildah driver"))
+	}
+
+	if c.WaitForHealthy {
+		if c.DriverType == DriverBuildah {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("`wait_for_healthy` is not supported by the buildah driver, which doesn't run healthchecks"))
+		}
+		if c.HealthyTimeout == 0 {
+			c.HealthyTimeout = defaultHealthyTimeout
+		}
+	}
+
+	if c.Init && c.DriverType == DriverBuildah {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`init` is not supported by the buildah driver, which runs no process in the container"))
+	}
+	if c.StreamLogs && c.DriverType == DriverBuildah {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`stream_logs` is not supported by the buildah driver, which runs no process in the container"))
+	}
+	if c.Init && c.WindowsContainer {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`init` is not supported by windows containers"))
+	}
+
+	if err := validateNamespaceMode("ipc_mode", c.IpcMode, c.DriverType); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	if err := validateNamespaceMode("pid_mode", c.PidMode, c.DriverType); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+
+	if err := validateCgroupnsMode(c.CgroupnsMode, c.DriverType); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	if (c.CgroupnsMode != "" || c.CgroupParent != "") && c.WindowsContainer {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("`cgroupns` and `cgroup_parent` are not supported by windows containers"))
+	}
+
+	for _, err := range validateUserns(c) {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+
+	if c.Rootless {
+		if err := validateRootless(c); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
 	if c.ContainerDir == "" {
 		if c.WindowsContainer {
 			c.ContainerDir = "c:/packer-files"
@@ -269,9 +1646,25 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	// ECR Public has a single login server.
+	if c.EcrLogin && c.PublicEcrGallery && c.LoginServer == "" {
+		c.LoginServer = strings.TrimSuffix(EcrPublicHost, "/")
+	}
 	if c.EcrLogin && c.LoginServer == "" {
 		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("ECR login requires login server to be provided."))
 	}
+	errs = packersdk.MultiErrorAppend(errs, c.AwsAccessConfig.Prepare()...)
+	if c.GhcrLogin && c.LoginServer == "" {
+		c.LoginServer = GhcrLoginServer
+	}
+	for _, registry := range []string{registryGAR, registryACR} {
+		if c.cloudRegistry() == registry && c.LoginServer == "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("%s login requires login server to be provided.", registry))
+		}
+	}
+	if registries := c.cloudRegistries(); len(registries) > 1 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("only one of `ecr_login`, `gar_login`, `acr_login` and `ghcr_login` can be set, got %s", strings.Join(registries, ", ")))
+	}
 
 	if errs != nil && len(errs.Errors) > 0 {
 		return warnings, errs
@@ -279,3 +1672,231 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 
 	return warnings, nil
 }
+
+// capabilityRe matches the names of the Linux capabilities accepted by
+// `docker run --cap-add`, with or without the `CAP_` prefix.
+var capabilityRe = regexp.MustCompile(`^(?i)(ALL|(CAP_)?[A-Z_]+)$`)
+
+// deviceCgroupRuleRe matches the rules of the device cgroup, like
+// `c 189:* rwm`.
+var deviceCgroupRuleRe = regexp.MustCompile(`^[abc] (\d+|\*):(\d+|\*) [rwm]{1,3}$`)
+
+// hostnameRe matches the hostnames and domain names of RFC 1123, like
+// `build-01.example.com`.
+var hostnameRe = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+// idMapRe matches the ranges of the user namespace mappings, like
+// `0:100000:65536`.
+var idMapRe = regexp.MustCompile(`^\d+:\d+:\d+$`)
+
+// ownerRe matches the owners of files, as `user` or `user:group`, by name
+// or ID.
+var ownerRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*(:[A-Za-z0-9_][A-Za-z0-9_.-]*)?$`)
+
+// platformRe matches platforms, like `linux/arm64/v8`.
+var platformRe = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// signalRe matches the signals of `docker run --stop-signal`, by name, like
+// `SIGTERM` or `TERM`, or by number.
+var signalRe = regexp.MustCompile(`^(SIG)?[A-Z][A-Z0-9+-]*$|^[0-9]+$`)
+
+// digestRe matches the digests of images, like `sha256:a0d9e826...`.
+var digestRe = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// stopsContainer returns true if the container is stopped with the
+// `stop_signal` and `stop_timeout` before it is committed or removed.
+func (c *Config) stopsContainer() bool {
+	return c.StopSignal != "" || c.StopTimeout != 0
+}
+
+// validateCgroupnsMode returns an error if the mode of the cgroup namespace
+// is not supported by the engine of the driver.
+func validateCgroupnsMode(mode, driverType string) error {
+	if mode == "" || mode == "host" || mode == "private" {
+		return nil
+	}
+
+	modes := "host, private"
+	if driverType == DriverPodman {
+		name, value, _ := strings.Cut(mode, ":")
+		if (name == "container" || name == "ns") && value != "" {
+			return nil
+		}
+		modes += ", container:<name|id>, ns:<path>"
+	}
+	return fmt.Errorf("`cgroupns`: unknown mode %q, expected one of %s", mode, modes)
+}
+
+// validateNamespaceMode returns an error if the mode of the IPC or PID
+// namespace option is not supported by the engine of the driver.
+func validateNamespaceMode(option, mode, driverType string) error {
+	if mode == "" {
+		return nil
+	}
+
+	name, value, _ := strings.Cut(mode, ":")
+	var modes []string
+	switch driverType {
+	case DriverBuildah:
+		if mode == "host" || mode == "container" || path.IsAbs(mode) {
+			return nil
+		}
+		return fmt.Errorf("`%s`: %q is not supported by buildah, expected host, container or the path of a namespace", option, mode)
+	case DriverPodman:
+		modes = []string{"host", "private", "container", "ns"}
+		if option == "ipc_mode" {
+			modes = append(modes, "none", "shareable")
+		}
+	default:
+		modes = []string{"host", "container"}
+		if option == "ipc_mode" {
+			modes = append(modes, "none", "private", "shareable")
+		}
+	}
+
+	for _, m := range modes {
+		if name != m {
+			continue
+		}
+		if (m == "container" || m == "ns") && value == "" {
+			return fmt.Errorf("`%s`: %q requires a value, like %s:<name>", option, mode, m)
+		}
+		return nil
+	}
+	return fmt.Errorf("`%s`: unknown mode %q, expected one of %s", option, mode, strings.Join(modes, ", "))
+}
+
+// validateUserns returns the errors of the user namespace options, which
+// depend on the engine of the driver.
+func validateUserns(c *Config) []error {
+	var errs []error
+
+	mode, _, _ := strings.Cut(c.Userns, ":")
+	switch c.DriverType {
+	case "", DriverCLI, DriverAPI:
+		if c.Userns != "" && c.Userns != "host" {
+			errs = append(errs, fmt.Errorf("`userns`: %q is not supported by docker, the only mode is host", c.Userns))
+		}
+		if len(c.UIDMap) > 0 || len(c.GIDMap) > 0 {
+			errs = append(errs, errors.New("`uidmap` and `gidmap` are not supported by docker, "+
+				"the users are remapped by the daemon with userns-remap"))
+		}
+	case DriverPodman:
+		switch mode {
+		case "", "host", "keep-id", "auto", "nomap", "private", "ns":
+		default:
+			errs = append(errs, fmt.Errorf("`userns`: unknown mode %q, expected host, keep-id, auto, nomap, private or ns:<path>", c.Userns))
+		}
+	case DriverBuildah:
+		switch c.Userns {
+		case "", "host", "container":
+		default:
+			errs = append(errs, fmt.Errorf("`userns`: %q is not supported by buildah, expected host or container", c.Userns))
+		}
+	default:
+		if c.Userns != "" || len(c.UIDMap) > 0 || len(c.GIDMap) > 0 {
+			errs = append(errs, fmt.Errorf("`userns`, `uidmap` and `gidmap` are not supported by the %s driver", c.DriverType))
+		}
+	}
+
+	if c.Userns != "" && (len(c.UIDMap) > 0 || len(c.GIDMap) > 0) {
+		errs = append(errs, errors.New("`uidmap` and `gidmap` can't be set with `userns`"))
+	}
+	for _, m := range append(append([]string{}, c.UIDMap...), c.GIDMap...) {
+		if !idMapRe.MatchString(m) {
+			errs = append(errs, fmt.Errorf("%q is not a valid id mapping, expected container_id:host_id:amount", m))
+		}
+	}
+
+	return errs
+}
+
+// validateExtraHost returns an error if the host is not in the format of
+// `docker run --add-host`.
+func validateExtraHost(host string) error {
+	// Hostnames can't contain colons, so the rest is the address, which
+	// can be an IPv6 address.
+	name, ip, ok := strings.Cut(host, ":")
+	if !ok {
+		name, ip, ok = strings.Cut(host, "=")
+	}
+	if !ok || name == "" {
+		return fmt.Errorf("`extra_hosts`: %q is invalid, expected hostname:ip", host)
+	}
+	if ip != "host-gateway" && net.ParseIP(strings.Trim(ip, "[]")) == nil {
+		return fmt.Errorf("`extra_hosts`: %q is not an ip address or host-gateway", ip)
+	}
+	return nil
+}
+
+// validateSecurityOpt returns an error if the security option is not one
+// of the options of `docker run --security-opt`, or if its seccomp profile
+// doesn't exist.
+func validateSecurityOpt(opt string) error {
+	if opt == "no-new-privileges" {
+		return nil
+	}
+
+	key, value, ok := strings.Cut(opt, "=")
+	if !ok {
+		// The legacy format uses a colon as separator
+		key, value, ok = strings.Cut(opt, ":")
+	}
+	if !ok {
+		return fmt.Errorf("`security_opts`: %q is invalid, expected key=value", opt)
+	}
+
+	switch key {
+	case "seccomp":
+		if value != "unconfined" {
+			if _, err := os.Stat(value); err != nil {
+				return fmt.Errorf("`security_opts`: seccomp profile %q: %s", value, err)
+			}
+		}
+	case "apparmor", "label", "no-new-privileges", "systempaths", "mask", "unmask", "proc-opts":
+	default:
+		return fmt.Errorf("`security_opts`: unknown security option %q", key)
+	}
+
+	return nil
+}
+
+// validateDevice returns an error if the device is not in the format of
+// `docker run --device`.
+func validateDevice(device string) error {
+	parts := strings.Split(device, ":")
+	if len(parts) > 3 || parts[0] == "" {
+		return fmt.Errorf("`device` %q is invalid, expected host_path[:container_path[:permissions]]", device)
+	}
+	if len(parts) == 3 && strings.Trim(parts[2], "rwm") != "" {
+		return fmt.Errorf("`device` %q has invalid permissions %q, expected a combination of r, w and m", device, parts[2])
+	}
+	return nil
+}
+
+// validateRootless returns an error if an option can't be used with a
+// rootless daemon.
+func validateRootless(c *Config) error {
+	if c.Privileged {
+		return errors.New("`privileged` is not supported by rootless Docker daemons, since they have no root privileges to give to the container")
+	}
+	return nil
+}
+
+// validateWindowsChange returns an error if the commit change can't apply
+// to a Windows image.
+func validateWindowsChange(change string) error {
+	instruction, value, _ := strings.Cut(strings.TrimSpace(change), " ")
+	value = strings.TrimSpace(value)
+
+	if strings.ToUpper(instruction) == "USER" {
+		// Windows users are names, like ContainerAdministrator, there are
+		// no numeric user or group IDs.
+		user, _, _ := strings.Cut(value, ":")
+		if _, err := strconv.Atoi(user); err == nil {
+			return fmt.Errorf("the %q change uses a numeric user ID, which is not valid for Windows containers", change)
+		}
+	}
+
+	return nil
+}
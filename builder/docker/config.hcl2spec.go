@@ -10,11 +10,16 @@ import (
 // FlatAwsAccessConfig is an auto-generated flat version of AwsAccessConfig.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatAwsAccessConfig struct {
-	AccessKey        *string `mapstructure:"aws_access_key" required:"false" cty:"aws_access_key" hcl:"aws_access_key"`
-	SecretKey        *string `mapstructure:"aws_secret_key" required:"false" cty:"aws_secret_key" hcl:"aws_secret_key"`
-	Token            *string `mapstructure:"aws_token" required:"false" cty:"aws_token" hcl:"aws_token"`
-	Profile          *string `mapstructure:"aws_profile" required:"false" cty:"aws_profile" hcl:"aws_profile"`
-	PublicEcrGallery *bool   `mapstructure:"aws_force_use_public_ecr" required:"false" cty:"aws_force_use_public_ecr" hcl:"aws_force_use_public_ecr"`
+	AccessKey              *string `mapstructure:"aws_access_key" required:"false" cty:"aws_access_key" hcl:"aws_access_key"`
+	SecretKey              *string `mapstructure:"aws_secret_key" required:"false" cty:"aws_secret_key" hcl:"aws_secret_key"`
+	Token                  *string `mapstructure:"aws_token" required:"false" cty:"aws_token" hcl:"aws_token"`
+	Profile                *string `mapstructure:"aws_profile" required:"false" cty:"aws_profile" hcl:"aws_profile"`
+	WebIdentityRoleARN     *string `mapstructure:"aws_web_identity_role_arn" required:"false" cty:"aws_web_identity_role_arn" hcl:"aws_web_identity_role_arn"`
+	WebIdentityTokenFile   *string `mapstructure:"aws_web_identity_token_file" required:"false" cty:"aws_web_identity_token_file" hcl:"aws_web_identity_token_file"`
+	WebIdentitySessionName *string `mapstructure:"aws_web_identity_session_name" required:"false" cty:"aws_web_identity_session_name" hcl:"aws_web_identity_session_name"`
+	WebIdentityDuration    *string `mapstructure:"aws_web_identity_duration" required:"false" cty:"aws_web_identity_duration" hcl:"aws_web_identity_duration"`
+	PublicEcrGallery       *bool   `mapstructure:"aws_force_use_public_ecr" required:"false" cty:"aws_force_use_public_ecr" hcl:"aws_force_use_public_ecr"`
+	EcrEndpoint            *string `mapstructure:"ecr_endpoint" required:"false" cty:"ecr_endpoint" hcl:"ecr_endpoint"`
 }
 
 // FlatMapstructure returns a new FlatAwsAccessConfig.
@@ -29,11 +34,16 @@ func (*AwsAccessConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcld
 // The decoded values from this spec will then be applied to a FlatAwsAccessConfig.
 func (*FlatAwsAccessConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"aws_access_key":           &hcldec.AttrSpec{Name: "aws_access_key", Type: cty.String, Required: false},
-		"aws_secret_key":           &hcldec.AttrSpec{Name: "aws_secret_key", Type: cty.String, Required: false},
-		"aws_token":                &hcldec.AttrSpec{Name: "aws_token", Type: cty.String, Required: false},
-		"aws_profile":              &hcldec.AttrSpec{Name: "aws_profile", Type: cty.String, Required: false},
-		"aws_force_use_public_ecr": &hcldec.AttrSpec{Name: "aws_force_use_public_ecr", Type: cty.Bool, Required: false},
+		"aws_access_key":                &hcldec.AttrSpec{Name: "aws_access_key", Type: cty.String, Required: false},
+		"aws_secret_key":                &hcldec.AttrSpec{Name: "aws_secret_key", Type: cty.String, Required: false},
+		"aws_token":                     &hcldec.AttrSpec{Name: "aws_token", Type: cty.String, Required: false},
+		"aws_profile":                   &hcldec.AttrSpec{Name: "aws_profile", Type: cty.String, Required: false},
+		"aws_web_identity_role_arn":     &hcldec.AttrSpec{Name: "aws_web_identity_role_arn", Type: cty.String, Required: false},
+		"aws_web_identity_token_file":   &hcldec.AttrSpec{Name: "aws_web_identity_token_file", Type: cty.String, Required: false},
+		"aws_web_identity_session_name": &hcldec.AttrSpec{Name: "aws_web_identity_session_name", Type: cty.String, Required: false},
+		"aws_web_identity_duration":     &hcldec.AttrSpec{Name: "aws_web_identity_duration", Type: cty.String, Required: false},
+		"aws_force_use_public_ecr":      &hcldec.AttrSpec{Name: "aws_force_use_public_ecr", Type: cty.Bool, Required: false},
+		"ecr_endpoint":                  &hcldec.AttrSpec{Name: "ecr_endpoint", Type: cty.String, Required: false},
 	}
 	return s
 }
@@ -41,97 +51,211 @@ func (*FlatAwsAccessConfig) HCL2Spec() map[string]hcldec.Spec {
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName           *string                        `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType         *string                        `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion         *string                        `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug               *bool                          `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce               *bool                          `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError             *string                        `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars            map[string]string              `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars       []string                       `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	Type                      *string                        `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
-	PauseBeforeConnect        *string                        `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
-	SSHHost                   *string                        `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
-	SSHPort                   *int                           `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
-	SSHUsername               *string                        `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
-	SSHPassword               *string                        `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
-	SSHKeyPairName            *string                        `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
-	SSHTemporaryKeyPairName   *string                        `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
-	SSHTemporaryKeyPairType   *string                        `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
-	SSHTemporaryKeyPairBits   *int                           `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
-	SSHCiphers                []string                       `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
-	SSHClearAuthorizedKeys    *bool                          `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
-	SSHKEXAlgos               []string                       `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
-	SSHPrivateKeyFile         *string                        `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
-	SSHCertificateFile        *string                        `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
-	SSHPty                    *bool                          `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
-	SSHTimeout                *string                        `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
-	SSHWaitTimeout            *string                        `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
-	SSHAgentAuth              *bool                          `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
-	SSHDisableAgentForwarding *bool                          `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
-	SSHHandshakeAttempts      *int                           `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
-	SSHBastionHost            *string                        `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
-	SSHBastionPort            *int                           `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
-	SSHBastionAgentAuth       *bool                          `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
-	SSHBastionUsername        *string                        `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
-	SSHBastionPassword        *string                        `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
-	SSHBastionInteractive     *bool                          `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
-	SSHBastionPrivateKeyFile  *string                        `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
-	SSHBastionCertificateFile *string                        `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
-	SSHFileTransferMethod     *string                        `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
-	SSHProxyHost              *string                        `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
-	SSHProxyPort              *int                           `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
-	SSHProxyUsername          *string                        `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
-	SSHProxyPassword          *string                        `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
-	SSHKeepAliveInterval      *string                        `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
-	SSHReadWriteTimeout       *string                        `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
-	SSHRemoteTunnels          []string                       `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
-	SSHLocalTunnels           []string                       `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
-	SSHPublicKey              []byte                         `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
-	SSHPrivateKey             []byte                         `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
-	WinRMUser                 *string                        `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
-	WinRMPassword             *string                        `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
-	WinRMHost                 *string                        `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
-	WinRMNoProxy              *bool                          `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
-	WinRMPort                 *int                           `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
-	WinRMTimeout              *string                        `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
-	WinRMUseSSL               *bool                          `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
-	WinRMInsecure             *bool                          `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
-	WinRMUseNTLM              *bool                          `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
-	BuildConfig               *FlatDockerfileBootstrapConfig `mapstructure:"build" cty:"build" hcl:"build"`
-	Author                    *string                        `mapstructure:"author" cty:"author" hcl:"author"`
-	Changes                   []string                       `mapstructure:"changes" cty:"changes" hcl:"changes"`
-	Commit                    *bool                          `mapstructure:"commit" required:"true" cty:"commit" hcl:"commit"`
-	ContainerDir              *string                        `mapstructure:"container_dir" required:"false" cty:"container_dir" hcl:"container_dir"`
-	Device                    []string                       `mapstructure:"device" required:"false" cty:"device" hcl:"device"`
-	Discard                   *bool                          `mapstructure:"discard" required:"true" cty:"discard" hcl:"discard"`
-	CapAdd                    []string                       `mapstructure:"cap_add" required:"false" cty:"cap_add" hcl:"cap_add"`
-	CapDrop                   []string                       `mapstructure:"cap_drop" required:"false" cty:"cap_drop" hcl:"cap_drop"`
-	Executable                *string                        `mapstructure:"docker_path" cty:"docker_path" hcl:"docker_path"`
-	ExecUser                  *string                        `mapstructure:"exec_user" required:"false" cty:"exec_user" hcl:"exec_user"`
-	ExportPath                *string                        `mapstructure:"export_path" required:"true" cty:"export_path" hcl:"export_path"`
-	Image                     *string                        `mapstructure:"image" required:"false" cty:"image" hcl:"image"`
-	Message                   *string                        `mapstructure:"message" required:"true" cty:"message" hcl:"message"`
-	Privileged                *bool                          `mapstructure:"privileged" required:"false" cty:"privileged" hcl:"privileged"`
-	Pty                       *bool                          `cty:"pty" hcl:"pty"`
-	Runtime                   *string                        `mapstructure:"runtime" required:"false" cty:"runtime" hcl:"runtime"`
-	Pull                      *bool                          `mapstructure:"pull" required:"false" cty:"pull" hcl:"pull"`
-	RunCommand                []string                       `mapstructure:"run_command" required:"false" cty:"run_command" hcl:"run_command"`
-	TmpFs                     []string                       `mapstructure:"tmpfs" required:"false" cty:"tmpfs" hcl:"tmpfs"`
-	Volumes                   map[string]string              `mapstructure:"volumes" required:"false" cty:"volumes" hcl:"volumes"`
-	FixUploadOwner            *bool                          `mapstructure:"fix_upload_owner" required:"false" cty:"fix_upload_owner" hcl:"fix_upload_owner"`
-	WindowsContainer          *bool                          `mapstructure:"windows_container" required:"false" cty:"windows_container" hcl:"windows_container"`
-	Platform                  *string                        `mapstructure:"platform" required:"false" cty:"platform" hcl:"platform"`
-	Login                     *bool                          `mapstructure:"login" required:"false" cty:"login" hcl:"login"`
-	LoginPassword             *string                        `mapstructure:"login_password" required:"false" cty:"login_password" hcl:"login_password"`
-	LoginServer               *string                        `mapstructure:"login_server" required:"false" cty:"login_server" hcl:"login_server"`
-	LoginUsername             *string                        `mapstructure:"login_username" required:"false" cty:"login_username" hcl:"login_username"`
-	EcrLogin                  *bool                          `mapstructure:"ecr_login" required:"false" cty:"ecr_login" hcl:"ecr_login"`
-	AccessKey                 *string                        `mapstructure:"aws_access_key" required:"false" cty:"aws_access_key" hcl:"aws_access_key"`
-	SecretKey                 *string                        `mapstructure:"aws_secret_key" required:"false" cty:"aws_secret_key" hcl:"aws_secret_key"`
-	Token                     *string                        `mapstructure:"aws_token" required:"false" cty:"aws_token" hcl:"aws_token"`
-	Profile                   *string                        `mapstructure:"aws_profile" required:"false" cty:"aws_profile" hcl:"aws_profile"`
-	PublicEcrGallery          *bool                          `mapstructure:"aws_force_use_public_ecr" required:"false" cty:"aws_force_use_public_ecr" hcl:"aws_force_use_public_ecr"`
+	PackerBuildName             *string                        `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType           *string                        `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion           *string                        `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug                 *bool                          `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce                 *bool                          `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError               *string                        `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars              map[string]string              `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars         []string                       `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	Type                        *string                        `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
+	PauseBeforeConnect          *string                        `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
+	SSHHost                     *string                        `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
+	SSHPort                     *int                           `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
+	SSHUsername                 *string                        `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
+	SSHPassword                 *string                        `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
+	SSHKeyPairName              *string                        `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
+	SSHTemporaryKeyPairName     *string                        `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
+	SSHTemporaryKeyPairType     *string                        `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
+	SSHTemporaryKeyPairBits     *int                           `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
+	SSHCiphers                  []string                       `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
+	SSHClearAuthorizedKeys      *bool                          `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
+	SSHKEXAlgos                 []string                       `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
+	SSHPrivateKeyFile           *string                        `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
+	SSHCertificateFile          *string                        `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
+	SSHPty                      *bool                          `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
+	SSHTimeout                  *string                        `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
+	SSHWaitTimeout              *string                        `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
+	SSHAgentAuth                *bool                          `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
+	SSHDisableAgentForwarding   *bool                          `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
+	SSHHandshakeAttempts        *int                           `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
+	SSHBastionHost              *string                        `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
+	SSHBastionPort              *int                           `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
+	SSHBastionAgentAuth         *bool                          `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
+	SSHBastionUsername          *string                        `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
+	SSHBastionPassword          *string                        `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
+	SSHBastionInteractive       *bool                          `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
+	SSHBastionPrivateKeyFile    *string                        `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
+	SSHBastionCertificateFile   *string                        `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
+	SSHFileTransferMethod       *string                        `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
+	SSHProxyHost                *string                        `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
+	SSHProxyPort                *int                           `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
+	SSHProxyUsername            *string                        `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
+	SSHProxyPassword            *string                        `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
+	SSHKeepAliveInterval        *string                        `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
+	SSHReadWriteTimeout         *string                        `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
+	SSHRemoteTunnels            []string                       `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
+	SSHLocalTunnels             []string                       `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
+	SSHPublicKey                []byte                         `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
+	SSHPrivateKey               []byte                         `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
+	WinRMUser                   *string                        `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
+	WinRMPassword               *string                        `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
+	WinRMHost                   *string                        `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
+	WinRMNoProxy                *bool                          `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
+	WinRMPort                   *int                           `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
+	WinRMTimeout                *string                        `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
+	WinRMUseSSL                 *bool                          `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
+	WinRMInsecure               *bool                          `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
+	WinRMUseNTLM                *bool                          `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
+	BuildConfig                 *FlatDockerfileBootstrapConfig `mapstructure:"build" cty:"build" hcl:"build"`
+	Author                      *string                        `mapstructure:"author" cty:"author" hcl:"author"`
+	Changes                     []string                       `mapstructure:"changes" cty:"changes" hcl:"changes"`
+	Labels                      map[string]string              `mapstructure:"labels" required:"false" cty:"labels" hcl:"labels"`
+	Annotations                 map[string]string              `mapstructure:"annotations" required:"false" cty:"annotations" hcl:"annotations"`
+	Commit                      *bool                          `mapstructure:"commit" required:"true" cty:"commit" hcl:"commit"`
+	Squash                      *bool                          `mapstructure:"squash" required:"false" cty:"squash" hcl:"squash"`
+	PauseBeforeCommit           *bool                          `mapstructure:"pause_before_commit" required:"false" cty:"pause_before_commit" hcl:"pause_before_commit"`
+	StopSignal                  *string                        `mapstructure:"stop_signal" required:"false" cty:"stop_signal" hcl:"stop_signal"`
+	StopTimeout                 *string                        `mapstructure:"stop_timeout" required:"false" cty:"stop_timeout" hcl:"stop_timeout"`
+	SkipUnchanged               *bool                          `mapstructure:"skip_unchanged" required:"false" cty:"skip_unchanged" hcl:"skip_unchanged"`
+	ContentHashFiles            []string                       `mapstructure:"content_hash_files" required:"false" cty:"content_hash_files" hcl:"content_hash_files"`
+	SnapshotRepository          *string                        `mapstructure:"snapshot_repository" required:"false" cty:"snapshot_repository" hcl:"snapshot_repository"`
+	Resume                      *bool                          `mapstructure:"resume" required:"false" cty:"resume" hcl:"resume"`
+	KeepSnapshots               *bool                          `mapstructure:"keep_snapshots" required:"false" cty:"keep_snapshots" hcl:"keep_snapshots"`
+	Checkpoint                  *bool                          `mapstructure:"checkpoint" required:"false" cty:"checkpoint" hcl:"checkpoint"`
+	CheckpointDir               *string                        `mapstructure:"checkpoint_dir" required:"false" cty:"checkpoint_dir" hcl:"checkpoint_dir"`
+	ContainerDir                *string                        `mapstructure:"container_dir" required:"false" cty:"container_dir" hcl:"container_dir"`
+	Device                      []string                       `mapstructure:"device" required:"false" cty:"device" hcl:"device"`
+	DeviceCgroupRules           []string                       `mapstructure:"device_cgroup_rules" required:"false" cty:"device_cgroup_rules" hcl:"device_cgroup_rules"`
+	Gpus                        *string                        `mapstructure:"gpus" required:"false" cty:"gpus" hcl:"gpus"`
+	Network                     *string                        `mapstructure:"network" required:"false" cty:"network" hcl:"network"`
+	NetworkCreate               *bool                          `mapstructure:"network_create" required:"false" cty:"network_create" hcl:"network_create"`
+	PublishCommPort             *bool                          `mapstructure:"publish_comm_port" required:"false" cty:"publish_comm_port" hcl:"publish_comm_port"`
+	NetworkAliases              []string                       `mapstructure:"network_aliases" required:"false" cty:"network_aliases" hcl:"network_aliases"`
+	ExtraHosts                  []string                       `mapstructure:"extra_hosts" required:"false" cty:"extra_hosts" hcl:"extra_hosts"`
+	Dns                         []string                       `mapstructure:"dns" required:"false" cty:"dns" hcl:"dns"`
+	DnsSearch                   []string                       `mapstructure:"dns_search" required:"false" cty:"dns_search" hcl:"dns_search"`
+	DnsOptions                  []string                       `mapstructure:"dns_options" required:"false" cty:"dns_options" hcl:"dns_options"`
+	Hostname                    *string                        `mapstructure:"hostname" required:"false" cty:"hostname" hcl:"hostname"`
+	Domainname                  *string                        `mapstructure:"domainname" required:"false" cty:"domainname" hcl:"domainname"`
+	IpcMode                     *string                        `mapstructure:"ipc_mode" required:"false" cty:"ipc_mode" hcl:"ipc_mode"`
+	PidMode                     *string                        `mapstructure:"pid_mode" required:"false" cty:"pid_mode" hcl:"pid_mode"`
+	CgroupnsMode                *string                        `mapstructure:"cgroupns" required:"false" cty:"cgroupns" hcl:"cgroupns"`
+	CgroupParent                *string                        `mapstructure:"cgroup_parent" required:"false" cty:"cgroup_parent" hcl:"cgroup_parent"`
+	Discard                     *bool                          `mapstructure:"discard" required:"true" cty:"discard" hcl:"discard"`
+	KeepContainerOnError        *bool                          `mapstructure:"keep_container_on_error" required:"false" cty:"keep_container_on_error" hcl:"keep_container_on_error"`
+	StreamLogs                  *bool                          `mapstructure:"stream_logs" required:"false" cty:"stream_logs" hcl:"stream_logs"`
+	PauseAfterProvision         *bool                          `mapstructure:"pause_after_provision" required:"false" cty:"pause_after_provision" hcl:"pause_after_provision"`
+	PreCommitCommands           []string                       `mapstructure:"pre_commit_commands" required:"false" cty:"pre_commit_commands" hcl:"pre_commit_commands"`
+	CapAdd                      []string                       `mapstructure:"cap_add" required:"false" cty:"cap_add" hcl:"cap_add"`
+	CapDrop                     []string                       `mapstructure:"cap_drop" required:"false" cty:"cap_drop" hcl:"cap_drop"`
+	SecurityOpts                []string                       `mapstructure:"security_opts" required:"false" cty:"security_opts" hcl:"security_opts"`
+	Ulimits                     map[string]string              `mapstructure:"ulimits" required:"false" cty:"ulimits" hcl:"ulimits"`
+	Cpus                        *string                        `mapstructure:"cpus" required:"false" cty:"cpus" hcl:"cpus"`
+	CpusetCpus                  *string                        `mapstructure:"cpuset_cpus" required:"false" cty:"cpuset_cpus" hcl:"cpuset_cpus"`
+	Memory                      *string                        `mapstructure:"memory" required:"false" cty:"memory" hcl:"memory"`
+	MemorySwap                  *string                        `mapstructure:"memory_swap" required:"false" cty:"memory_swap" hcl:"memory_swap"`
+	ShmSize                     *string                        `mapstructure:"shm_size" required:"false" cty:"shm_size" hcl:"shm_size"`
+	OomScoreAdj                 *int                           `mapstructure:"oom_score_adj" required:"false" cty:"oom_score_adj" hcl:"oom_score_adj"`
+	OomKillDisable              *bool                          `mapstructure:"oom_kill_disable" required:"false" cty:"oom_kill_disable" hcl:"oom_kill_disable"`
+	RunLabels                   map[string]string              `mapstructure:"run_labels" required:"false" cty:"run_labels" hcl:"run_labels"`
+	ContainerName               *string                        `mapstructure:"container_name" required:"false" cty:"container_name" hcl:"container_name"`
+	DriverType                  *string                        `mapstructure:"driver" required:"false" cty:"driver" hcl:"driver"`
+	Executable                  *string                        `mapstructure:"docker_path" cty:"docker_path" hcl:"docker_path"`
+	ExecUser                    *string                        `mapstructure:"exec_user" required:"false" cty:"exec_user" hcl:"exec_user"`
+	Workdir                     *string                        `mapstructure:"workdir" required:"false" cty:"workdir" hcl:"workdir"`
+	ExecTty                     *bool                          `mapstructure:"exec_tty" required:"false" cty:"exec_tty" hcl:"exec_tty"`
+	ExecPrivileged              *bool                          `mapstructure:"exec_privileged" required:"false" cty:"exec_privileged" hcl:"exec_privileged"`
+	ExecArgs                    []string                       `mapstructure:"exec_args" required:"false" cty:"exec_args" hcl:"exec_args"`
+	ContainerEnv                map[string]string              `mapstructure:"container_env" required:"false" cty:"container_env" hcl:"container_env"`
+	EnvFile                     *string                        `mapstructure:"env_file" required:"false" cty:"env_file" hcl:"env_file"`
+	ForwardSSHAgent             *bool                          `mapstructure:"forward_ssh_agent" required:"false" cty:"forward_ssh_agent" hcl:"forward_ssh_agent"`
+	ProxyEnv                    *FlatProxyEnvConfig            `mapstructure:"proxy_env" required:"false" cty:"proxy_env" hcl:"proxy_env"`
+	ExportPath                  *string                        `mapstructure:"export_path" required:"true" cty:"export_path" hcl:"export_path"`
+	ExportFormat                *string                        `mapstructure:"export_format" required:"false" cty:"export_format" hcl:"export_format"`
+	OCILayoutPath               *string                        `mapstructure:"oci_layout_path" required:"false" cty:"oci_layout_path" hcl:"oci_layout_path"`
+	ExportCompression           *string                        `mapstructure:"export_compression" required:"false" cty:"export_compression" hcl:"export_compression"`
+	ExportCompressionLevel      *int                           `mapstructure:"export_compression_level" required:"false" cty:"export_compression_level" hcl:"export_compression_level"`
+	ExportDiffOnly              *bool                          `mapstructure:"export_diff_only" required:"false" cty:"export_diff_only" hcl:"export_diff_only"`
+	ExportExcludeLayers         []string                       `mapstructure:"export_exclude_layers" required:"false" cty:"export_exclude_layers" hcl:"export_exclude_layers"`
+	ExportExcludeLabeledLayers  map[string]string              `mapstructure:"export_exclude_labeled_layers" required:"false" cty:"export_exclude_labeled_layers" hcl:"export_exclude_labeled_layers"`
+	Image                       *string                        `mapstructure:"image" required:"false" cty:"image" hcl:"image"`
+	SourceImageDigest           *string                        `mapstructure:"source_image_digest" required:"false" cty:"source_image_digest" hcl:"source_image_digest"`
+	VerifySignature             *FlatSignatureConfig           `mapstructure:"verify_signature" required:"false" cty:"verify_signature" hcl:"verify_signature"`
+	ScanImage                   *FlatScanConfig                `mapstructure:"scan_image" required:"false" cty:"scan_image" hcl:"scan_image"`
+	Push                        *FlatPushConfig                `mapstructure:"push" required:"false" cty:"push" hcl:"push"`
+	WaitFor                     *FlatWaitForConfig             `mapstructure:"wait_for" required:"false" cty:"wait_for" hcl:"wait_for"`
+	Message                     *string                        `mapstructure:"message" required:"true" cty:"message" hcl:"message"`
+	Privileged                  *bool                          `mapstructure:"privileged" required:"false" cty:"privileged" hcl:"privileged"`
+	Pty                         *bool                          `cty:"pty" hcl:"pty"`
+	Runtime                     *string                        `mapstructure:"runtime" required:"false" cty:"runtime" hcl:"runtime"`
+	Userns                      *string                        `mapstructure:"userns" required:"false" cty:"userns" hcl:"userns"`
+	UIDMap                      []string                       `mapstructure:"uidmap" required:"false" cty:"uidmap" hcl:"uidmap"`
+	GIDMap                      []string                       `mapstructure:"gidmap" required:"false" cty:"gidmap" hcl:"gidmap"`
+	Init                        *bool                          `mapstructure:"init" required:"false" cty:"init" hcl:"init"`
+	WaitForHealthy              *bool                          `mapstructure:"wait_for_healthy" required:"false" cty:"wait_for_healthy" hcl:"wait_for_healthy"`
+	HealthyTimeout              *string                        `mapstructure:"healthy_timeout" required:"false" cty:"healthy_timeout" hcl:"healthy_timeout"`
+	Pull                        *bool                          `mapstructure:"pull" required:"false" cty:"pull" hcl:"pull"`
+	RegistryMirrors             []string                       `mapstructure:"registry_mirrors" required:"false" cty:"registry_mirrors" hcl:"registry_mirrors"`
+	PullPolicy                  *string                        `mapstructure:"pull_policy" required:"false" cty:"pull_policy" hcl:"pull_policy"`
+	PullRetries                 *int                           `mapstructure:"pull_retries" required:"false" cty:"pull_retries" hcl:"pull_retries"`
+	PullRetryBackoff            *string                        `mapstructure:"pull_retry_backoff" required:"false" cty:"pull_retry_backoff" hcl:"pull_retry_backoff"`
+	PullTimeout                 *string                        `mapstructure:"pull_timeout" required:"false" cty:"pull_timeout" hcl:"pull_timeout"`
+	PrePullImages               []string                       `mapstructure:"pre_pull_images" required:"false" cty:"pre_pull_images" hcl:"pre_pull_images"`
+	ProvisionTimeout            *string                        `mapstructure:"provision_timeout" required:"false" cty:"provision_timeout" hcl:"provision_timeout"`
+	CommitTimeout               *string                        `mapstructure:"commit_timeout" required:"false" cty:"commit_timeout" hcl:"commit_timeout"`
+	BuildTimeout                *string                        `mapstructure:"build_timeout" required:"false" cty:"build_timeout" hcl:"build_timeout"`
+	RunCommand                  []string                       `mapstructure:"run_command" required:"false" cty:"run_command" hcl:"run_command"`
+	Entrypoint                  []string                       `mapstructure:"entrypoint" required:"false" cty:"entrypoint" hcl:"entrypoint"`
+	Cmd                         []string                       `mapstructure:"cmd" required:"false" cty:"cmd" hcl:"cmd"`
+	TmpFs                       []string                       `mapstructure:"tmpfs" required:"false" cty:"tmpfs" hcl:"tmpfs"`
+	ReadOnly                    *bool                          `mapstructure:"read_only" required:"false" cty:"read_only" hcl:"read_only"`
+	Volumes                     map[string]string              `mapstructure:"volumes" required:"false" cty:"volumes" hcl:"volumes"`
+	Mounts                      []FlatMountConfig              `mapstructure:"mounts" required:"false" cty:"mounts" hcl:"mounts"`
+	CopyFiles                   []FlatCopyFileConfig           `mapstructure:"copy_files" required:"false" cty:"copy_files" hcl:"copy_files"`
+	Sidecars                    []FlatSidecarConfig            `mapstructure:"sidecar" required:"false" cty:"sidecar" hcl:"sidecar"`
+	Secrets                     []FlatSecretConfig             `mapstructure:"secrets" required:"false" cty:"secrets" hcl:"secrets"`
+	KeepVolumes                 *bool                          `mapstructure:"keep_volumes" required:"false" cty:"keep_volumes" hcl:"keep_volumes"`
+	CacheVolumes                map[string]string              `mapstructure:"cache_volumes" required:"false" cty:"cache_volumes" hcl:"cache_volumes"`
+	FixUploadOwner              *bool                          `mapstructure:"fix_upload_owner" required:"false" cty:"fix_upload_owner" hcl:"fix_upload_owner"`
+	UploadOwner                 *string                        `mapstructure:"upload_owner" required:"false" cty:"upload_owner" hcl:"upload_owner"`
+	WindowsContainer            *bool                          `mapstructure:"windows_container" required:"false" cty:"windows_container" hcl:"windows_container"`
+	WindowsShell                *string                        `mapstructure:"windows_shell" required:"false" cty:"windows_shell" hcl:"windows_shell"`
+	SkipWinRMSetup              *bool                          `mapstructure:"skip_winrm_setup" required:"false" cty:"skip_winrm_setup" hcl:"skip_winrm_setup"`
+	Rootless                    *bool                          `mapstructure:"rootless" required:"false" cty:"rootless" hcl:"rootless"`
+	Platform                    *string                        `mapstructure:"platform" required:"false" cty:"platform" hcl:"platform"`
+	BinfmtInstall               *bool                          `mapstructure:"binfmt_install" required:"false" cty:"binfmt_install" hcl:"binfmt_install"`
+	BinfmtImage                 *string                        `mapstructure:"binfmt_image" required:"false" cty:"binfmt_image" hcl:"binfmt_image"`
+	Login                       *bool                          `mapstructure:"login" required:"false" cty:"login" hcl:"login"`
+	LoginPassword               *string                        `mapstructure:"login_password" required:"false" cty:"login_password" hcl:"login_password"`
+	LoginServer                 *string                        `mapstructure:"login_server" required:"false" cty:"login_server" hcl:"login_server"`
+	LoginUsername               *string                        `mapstructure:"login_username" required:"false" cty:"login_username" hcl:"login_username"`
+	EcrLogin                    *bool                          `mapstructure:"ecr_login" required:"false" cty:"ecr_login" hcl:"ecr_login"`
+	AccessKey                   *string                        `mapstructure:"aws_access_key" required:"false" cty:"aws_access_key" hcl:"aws_access_key"`
+	SecretKey                   *string                        `mapstructure:"aws_secret_key" required:"false" cty:"aws_secret_key" hcl:"aws_secret_key"`
+	Token                       *string                        `mapstructure:"aws_token" required:"false" cty:"aws_token" hcl:"aws_token"`
+	Profile                     *string                        `mapstructure:"aws_profile" required:"false" cty:"aws_profile" hcl:"aws_profile"`
+	WebIdentityRoleARN          *string                        `mapstructure:"aws_web_identity_role_arn" required:"false" cty:"aws_web_identity_role_arn" hcl:"aws_web_identity_role_arn"`
+	WebIdentityTokenFile        *string                        `mapstructure:"aws_web_identity_token_file" required:"false" cty:"aws_web_identity_token_file" hcl:"aws_web_identity_token_file"`
+	WebIdentitySessionName      *string                        `mapstructure:"aws_web_identity_session_name" required:"false" cty:"aws_web_identity_session_name" hcl:"aws_web_identity_session_name"`
+	WebIdentityDuration         *string                        `mapstructure:"aws_web_identity_duration" required:"false" cty:"aws_web_identity_duration" hcl:"aws_web_identity_duration"`
+	PublicEcrGallery            *bool                          `mapstructure:"aws_force_use_public_ecr" required:"false" cty:"aws_force_use_public_ecr" hcl:"aws_force_use_public_ecr"`
+	EcrEndpoint                 *string                        `mapstructure:"ecr_endpoint" required:"false" cty:"ecr_endpoint" hcl:"ecr_endpoint"`
+	DockerHost                  *string                        `mapstructure:"docker_host" required:"false" cty:"docker_host" hcl:"docker_host"`
+	DockerContext               *string                        `mapstructure:"docker_context" required:"false" cty:"docker_context" hcl:"docker_context"`
+	TLSVerify                   *bool                          `mapstructure:"tls_verify" required:"false" cty:"tls_verify" hcl:"tls_verify"`
+	CertPath                    *string                        `mapstructure:"cert_path" required:"false" cty:"cert_path" hcl:"cert_path"`
+	CACert                      *string                        `mapstructure:"ca_cert" required:"false" cty:"ca_cert" hcl:"ca_cert"`
+	ClientCert                  *string                        `mapstructure:"client_cert" required:"false" cty:"client_cert" hcl:"client_cert"`
+	ClientKey                   *string                        `mapstructure:"client_key" required:"false" cty:"client_key" hcl:"client_key"`
+	DockerHostSSHPrivateKeyFile *string                        `mapstructure:"docker_host_ssh_private_key_file" required:"false" cty:"docker_host_ssh_private_key_file" hcl:"docker_host_ssh_private_key_file"`
+	DockerHostSSHAgentSocket    *string                        `mapstructure:"docker_host_ssh_agent_socket" required:"false" cty:"docker_host_ssh_agent_socket" hcl:"docker_host_ssh_agent_socket"`
+	InsecureRegistries          []string                       `mapstructure:"insecure_registries" required:"false" cty:"insecure_registries" hcl:"insecure_registries"`
+	RegistryCAFile              *string                        `mapstructure:"registry_ca_file" required:"false" cty:"registry_ca_file" hcl:"registry_ca_file"`
+	GarLogin                    *bool                          `mapstructure:"gar_login" required:"false" cty:"gar_login" hcl:"gar_login"`
+	AcrLogin                    *bool                          `mapstructure:"acr_login" required:"false" cty:"acr_login" hcl:"acr_login"`
+	GhcrLogin                   *bool                          `mapstructure:"ghcr_login" required:"false" cty:"ghcr_login" hcl:"ghcr_login"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -146,97 +270,275 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":            &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":          &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":          &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":                 &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":                 &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":              &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":        &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables":   &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"communicator":                 &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
-		"pause_before_connecting":      &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
-		"ssh_host":                     &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
-		"ssh_port":                     &hcldec.AttrSpec{Name: "ssh_port", Type: cty.Number, Required: false},
-		"ssh_username":                 &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
-		"ssh_password":                 &hcldec.AttrSpec{Name: "ssh_password", Type: cty.String, Required: false},
-		"ssh_keypair_name":             &hcldec.AttrSpec{Name: "ssh_keypair_name", Type: cty.String, Required: false},
-		"temporary_key_pair_name":      &hcldec.AttrSpec{Name: "temporary_key_pair_name", Type: cty.String, Required: false},
-		"temporary_key_pair_type":      &hcldec.AttrSpec{Name: "temporary_key_pair_type", Type: cty.String, Required: false},
-		"temporary_key_pair_bits":      &hcldec.AttrSpec{Name: "temporary_key_pair_bits", Type: cty.Number, Required: false},
-		"ssh_ciphers":                  &hcldec.AttrSpec{Name: "ssh_ciphers", Type: cty.List(cty.String), Required: false},
-		"ssh_clear_authorized_keys":    &hcldec.AttrSpec{Name: "ssh_clear_authorized_keys", Type: cty.Bool, Required: false},
-		"ssh_key_exchange_algorithms":  &hcldec.AttrSpec{Name: "ssh_key_exchange_algorithms", Type: cty.List(cty.String), Required: false},
-		"ssh_private_key_file":         &hcldec.AttrSpec{Name: "ssh_private_key_file", Type: cty.String, Required: false},
-		"ssh_certificate_file":         &hcldec.AttrSpec{Name: "ssh_certificate_file", Type: cty.String, Required: false},
-		"ssh_pty":                      &hcldec.AttrSpec{Name: "ssh_pty", Type: cty.Bool, Required: false},
-		"ssh_timeout":                  &hcldec.AttrSpec{Name: "ssh_timeout", Type: cty.String, Required: false},
-		"ssh_wait_timeout":             &hcldec.AttrSpec{Name: "ssh_wait_timeout", Type: cty.String, Required: false},
-		"ssh_agent_auth":               &hcldec.AttrSpec{Name: "ssh_agent_auth", Type: cty.Bool, Required: false},
-		"ssh_disable_agent_forwarding": &hcldec.AttrSpec{Name: "ssh_disable_agent_forwarding", Type: cty.Bool, Required: false},
-		"ssh_handshake_attempts":       &hcldec.AttrSpec{Name: "ssh_handshake_attempts", Type: cty.Number, Required: false},
-		"ssh_bastion_host":             &hcldec.AttrSpec{Name: "ssh_bastion_host", Type: cty.String, Required: false},
-		"ssh_bastion_port":             &hcldec.AttrSpec{Name: "ssh_bastion_port", Type: cty.Number, Required: false},
-		"ssh_bastion_agent_auth":       &hcldec.AttrSpec{Name: "ssh_bastion_agent_auth", Type: cty.Bool, Required: false},
-		"ssh_bastion_username":         &hcldec.AttrSpec{Name: "ssh_bastion_username", Type: cty.String, Required: false},
-		"ssh_bastion_password":         &hcldec.AttrSpec{Name: "ssh_bastion_password", Type: cty.String, Required: false},
-		"ssh_bastion_interactive":      &hcldec.AttrSpec{Name: "ssh_bastion_interactive", Type: cty.Bool, Required: false},
-		"ssh_bastion_private_key_file": &hcldec.AttrSpec{Name: "ssh_bastion_private_key_file", Type: cty.String, Required: false},
-		"ssh_bastion_certificate_file": &hcldec.AttrSpec{Name: "ssh_bastion_certificate_file", Type: cty.String, Required: false},
-		"ssh_file_transfer_method":     &hcldec.AttrSpec{Name: "ssh_file_transfer_method", Type: cty.String, Required: false},
-		"ssh_proxy_host":               &hcldec.AttrSpec{Name: "ssh_proxy_host", Type: cty.String, Required: false},
-		"ssh_proxy_port":               &hcldec.AttrSpec{Name: "ssh_proxy_port", Type: cty.Number, Required: false},
-		"ssh_proxy_username":           &hcldec.AttrSpec{Name: "ssh_proxy_username", Type: cty.String, Required: false},
-		"ssh_proxy_password":           &hcldec.AttrSpec{Name: "ssh_proxy_password", Type: cty.String, Required: false},
-		"ssh_keep_alive_interval":      &hcldec.AttrSpec{Name: "ssh_keep_alive_interval", Type: cty.String, Required: false},
-		"ssh_read_write_timeout":       &hcldec.AttrSpec{Name: "ssh_read_write_timeout", Type: cty.String, Required: false},
-		"ssh_remote_tunnels":           &hcldec.AttrSpec{Name: "ssh_remote_tunnels", Type: cty.List(cty.String), Required: false},
-		"ssh_local_tunnels":            &hcldec.AttrSpec{Name: "ssh_local_tunnels", Type: cty.List(cty.String), Required: false},
-		"ssh_public_key":               &hcldec.AttrSpec{Name: "ssh_public_key", Type: cty.List(cty.Number), Required: false},
-		"ssh_private_key":              &hcldec.AttrSpec{Name: "ssh_private_key", Type: cty.List(cty.Number), Required: false},
-		"winrm_username":               &hcldec.AttrSpec{Name: "winrm_username", Type: cty.String, Required: false},
-		"winrm_password":               &hcldec.AttrSpec{Name: "winrm_password", Type: cty.String, Required: false},
-		"winrm_host":                   &hcldec.AttrSpec{Name: "winrm_host", Type: cty.String, Required: false},
-		"winrm_no_proxy":               &hcldec.AttrSpec{Name: "winrm_no_proxy", Type: cty.Bool, Required: false},
-		"winrm_port":                   &hcldec.AttrSpec{Name: "winrm_port", Type: cty.Number, Required: false},
-		"winrm_timeout":                &hcldec.AttrSpec{Name: "winrm_timeout", Type: cty.String, Required: false},
-		"winrm_use_ssl":                &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
-		"winrm_insecure":               &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
-		"winrm_use_ntlm":               &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
-		"build":                        &hcldec.BlockSpec{TypeName: "build", Nested: hcldec.ObjectSpec((*FlatDockerfileBootstrapConfig)(nil).HCL2Spec())},
-		"author":                       &hcldec.AttrSpec{Name: "author", Type: cty.String, Required: false},
-		"changes":                      &hcldec.AttrSpec{Name: "changes", Type: cty.List(cty.String), Required: false},
-		"commit":                       &hcldec.AttrSpec{Name: "commit", Type: cty.Bool, Required: false},
-		"container_dir":                &hcldec.AttrSpec{Name: "container_dir", Type: cty.String, Required: false},
-		"device":                       &hcldec.AttrSpec{Name: "device", Type: cty.List(cty.String), Required: false},
-		"discard":                      &hcldec.AttrSpec{Name: "discard", Type: cty.Bool, Required: false},
-		"cap_add":                      &hcldec.AttrSpec{Name: "cap_add", Type: cty.List(cty.String), Required: false},
-		"cap_drop":                     &hcldec.AttrSpec{Name: "cap_drop", Type: cty.List(cty.String), Required: false},
-		"docker_path":                  &hcldec.AttrSpec{Name: "docker_path", Type: cty.String, Required: false},
-		"exec_user":                    &hcldec.AttrSpec{Name: "exec_user", Type: cty.String, Required: false},
-		"export_path":                  &hcldec.AttrSpec{Name: "export_path", Type: cty.String, Required: false},
-		"image":                        &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
-		"message":                      &hcldec.AttrSpec{Name: "message", Type: cty.String, Required: false},
-		"privileged":                   &hcldec.AttrSpec{Name: "privileged", Type: cty.Bool, Required: false},
-		"pty":                          &hcldec.AttrSpec{Name: "pty", Type: cty.Bool, Required: false},
-		"runtime":                      &hcldec.AttrSpec{Name: "runtime", Type: cty.String, Required: false},
-		"pull":                         &hcldec.AttrSpec{Name: "pull", Type: cty.Bool, Required: false},
-		"run_command":                  &hcldec.AttrSpec{Name: "run_command", Type: cty.List(cty.String), Required: false},
-		"tmpfs":                        &hcldec.AttrSpec{Name: "tmpfs", Type: cty.List(cty.String), Required: false},
-		"volumes":                      &hcldec.AttrSpec{Name: "volumes", Type: cty.Map(cty.String), Required: false},
-		"fix_upload_owner":             &hcldec.AttrSpec{Name: "fix_upload_owner", Type: cty.Bool, Required: false},
-		"windows_container":            &hcldec.AttrSpec{Name: "windows_container", Type: cty.Bool, Required: false},
-		"platform":                     &hcldec.AttrSpec{Name: "platform", Type: cty.String, Required: false},
-		"login":                        &hcldec.AttrSpec{Name: "login", Type: cty.Bool, Required: false},
-		"login_password":               &hcldec.AttrSpec{Name: "login_password", Type: cty.String, Required: false},
-		"login_server":                 &hcldec.AttrSpec{Name: "login_server", Type: cty.String, Required: false},
-		"login_username":               &hcldec.AttrSpec{Name: "login_username", Type: cty.String, Required: false},
-		"ecr_login":                    &hcldec.AttrSpec{Name: "ecr_login", Type: cty.Bool, Required: false},
-		"aws_access_key":               &hcldec.AttrSpec{Name: "aws_access_key", Type: cty.String, Required: false},
-		"aws_secret_key":               &hcldec.AttrSpec{Name: "aws_secret_key", Type: cty.String, Required: false},
-		"aws_token":                    &hcldec.AttrSpec{Name: "aws_token", Type: cty.String, Required: false},
-		"aws_profile":                  &hcldec.AttrSpec{Name: "aws_profile", Type: cty.String, Required: false},
-		"aws_force_use_public_ecr":     &hcldec.AttrSpec{Name: "aws_force_use_public_ecr", Type: cty.Bool, Required: false},
+		"packer_build_name":                &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":              &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":              &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                     &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                     &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":                  &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":            &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":       &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"communicator":                     &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
+		"pause_before_connecting":          &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
+		"ssh_host":                         &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
+		"ssh_port":                         &hcldec.AttrSpec{Name: "ssh_port", Type: cty.Number, Required: false},
+		"ssh_username":                     &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
+		"ssh_password":                     &hcldec.AttrSpec{Name: "ssh_password", Type: cty.String, Required: false},
+		"ssh_keypair_name":                 &hcldec.AttrSpec{Name: "ssh_keypair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_name":          &hcldec.AttrSpec{Name: "temporary_key_pair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_type":          &hcldec.AttrSpec{Name: "temporary_key_pair_type", Type: cty.String, Required: false},
+		"temporary_key_pair_bits":          &hcldec.AttrSpec{Name: "temporary_key_pair_bits", Type: cty.Number, Required: false},
+		"ssh_ciphers":                      &hcldec.AttrSpec{Name: "ssh_ciphers", Type: cty.List(cty.String), Required: false},
+		"ssh_clear_authorized_keys":        &hcldec.AttrSpec{Name: "ssh_clear_authorized_keys", Type: cty.Bool, Required: false},
+		"ssh_key_exchange_algorithms":      &hcldec.AttrSpec{Name: "ssh_key_exchange_algorithms", Type: cty.List(cty.String), Required: false},
+		"ssh_private_key_file":             &hcldec.AttrSpec{Name: "ssh_private_key_file", Type: cty.String, Required: false},
+		"ssh_certificate_file":             &hcldec.AttrSpec{Name: "ssh_certificate_file", Type: cty.String, Required: false},
+		"ssh_pty":                          &hcldec.AttrSpec{Name: "ssh_pty", Type: cty.Bool, Required: false},
+		"ssh_timeout":                      &hcldec.AttrSpec{Name: "ssh_timeout", Type: cty.String, Required: false},
+		"ssh_wait_timeout":                 &hcldec.AttrSpec{Name: "ssh_wait_timeout", Type: cty.String, Required: false},
+		"ssh_agent_auth":                   &hcldec.AttrSpec{Name: "ssh_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_disable_agent_forwarding":     &hcldec.AttrSpec{Name: "ssh_disable_agent_forwarding", Type: cty.Bool, Required: false},
+		"ssh_handshake_attempts":           &hcldec.AttrSpec{Name: "ssh_handshake_attempts", Type: cty.Number, Required: false},
+		"ssh_bastion_host":                 &hcldec.AttrSpec{Name: "ssh_bastion_host", Type: cty.String, Required: false},
+		"ssh_bastion_port":                 &hcldec.AttrSpec{Name: "ssh_bastion_port", Type: cty.Number, Required: false},
+		"ssh_bastion_agent_auth":           &hcldec.AttrSpec{Name: "ssh_bastion_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_bastion_username":             &hcldec.AttrSpec{Name: "ssh_bastion_username", Type: cty.String, Required: false},
+		"ssh_bastion_password":             &hcldec.AttrSpec{Name: "ssh_bastion_password", Type: cty.String, Required: false},
+		"ssh_bastion_interactive":          &hcldec.AttrSpec{Name: "ssh_bastion_interactive", Type: cty.Bool, Required: false},
+		"ssh_bastion_private_key_file":     &hcldec.AttrSpec{Name: "ssh_bastion_private_key_file", Type: cty.String, Required: false},
+		"ssh_bastion_certificate_file":     &hcldec.AttrSpec{Name: "ssh_bastion_certificate_file", Type: cty.String, Required: false},
+		"ssh_file_transfer_method":         &hcldec.AttrSpec{Name: "ssh_file_transfer_method", Type: cty.String, Required: false},
+		"ssh_proxy_host":                   &hcldec.AttrSpec{Name: "ssh_proxy_host", Type: cty.String, Required: false},
+		"ssh_proxy_port":                   &hcldec.AttrSpec{Name: "ssh_proxy_port", Type: cty.Number, Required: false},
+		"ssh_proxy_username":               &hcldec.AttrSpec{Name: "ssh_proxy_username", Type: cty.String, Required: false},
+		"ssh_proxy_password":               &hcldec.AttrSpec{Name: "ssh_proxy_password", Type: cty.String, Required: false},
+		"ssh_keep_alive_interval":          &hcldec.AttrSpec{Name: "ssh_keep_alive_interval", Type: cty.String, Required: false},
+		"ssh_read_write_timeout":           &hcldec.AttrSpec{Name: "ssh_read_write_timeout", Type: cty.String, Required: false},
+		"ssh_remote_tunnels":               &hcldec.AttrSpec{Name: "ssh_remote_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_local_tunnels":                &hcldec.AttrSpec{Name: "ssh_local_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_public_key":                   &hcldec.AttrSpec{Name: "ssh_public_key", Type: cty.List(cty.Number), Required: false},
+		"ssh_private_key":                  &hcldec.AttrSpec{Name: "ssh_private_key", Type: cty.List(cty.Number), Required: false},
+		"winrm_username":                   &hcldec.AttrSpec{Name: "winrm_username", Type: cty.String, Required: false},
+		"winrm_password":                   &hcldec.AttrSpec{Name: "winrm_password", Type: cty.String, Required: false},
+		"winrm_host":                       &hcldec.AttrSpec{Name: "winrm_host", Type: cty.String, Required: false},
+		"winrm_no_proxy":                   &hcldec.AttrSpec{Name: "winrm_no_proxy", Type: cty.Bool, Required: false},
+		"winrm_port":                       &hcldec.AttrSpec{Name: "winrm_port", Type: cty.Number, Required: false},
+		"winrm_timeout":                    &hcldec.AttrSpec{Name: "winrm_timeout", Type: cty.String, Required: false},
+		"winrm_use_ssl":                    &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
+		"winrm_insecure":                   &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
+		"winrm_use_ntlm":                   &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
+		"build":                            &hcldec.BlockSpec{TypeName: "build", Nested: hcldec.ObjectSpec((*FlatDockerfileBootstrapConfig)(nil).HCL2Spec())},
+		"author":                           &hcldec.AttrSpec{Name: "author", Type: cty.String, Required: false},
+		"changes":                          &hcldec.AttrSpec{Name: "changes", Type: cty.List(cty.String), Required: false},
+		"labels":                           &hcldec.AttrSpec{Name: "labels", Type: cty.Map(cty.String), Required: false},
+		"annotations":                      &hcldec.AttrSpec{Name: "annotations", Type: cty.Map(cty.String), Required: false},
+		"commit":                           &hcldec.AttrSpec{Name: "commit", Type: cty.Bool, Required: false},
+		"squash":                           &hcldec.AttrSpec{Name: "squash", Type: cty.Bool, Required: false},
+		"pause_before_commit":              &hcldec.AttrSpec{Name: "pause_before_commit", Type: cty.Bool, Required: false},
+		"stop_signal":                      &hcldec.AttrSpec{Name: "stop_signal", Type: cty.String, Required: false},
+		"stop_timeout":                     &hcldec.AttrSpec{Name: "stop_timeout", Type: cty.String, Required: false},
+		"skip_unchanged":                   &hcldec.AttrSpec{Name: "skip_unchanged", Type: cty.Bool, Required: false},
+		"content_hash_files":               &hcldec.AttrSpec{Name: "content_hash_files", Type: cty.List(cty.String), Required: false},
+		"snapshot_repository":              &hcldec.AttrSpec{Name: "snapshot_repository", Type: cty.String, Required: false},
+		"resume":                           &hcldec.AttrSpec{Name: "resume", Type: cty.Bool, Required: false},
+		"keep_snapshots":                   &hcldec.AttrSpec{Name: "keep_snapshots", Type: cty.Bool, Required: false},
+		"checkpoint":                       &hcldec.AttrSpec{Name: "checkpoint", Type: cty.Bool, Required: false},
+		"checkpoint_dir":                   &hcldec.AttrSpec{Name: "checkpoint_dir", Type: cty.String, Required: false},
+		"container_dir":                    &hcldec.AttrSpec{Name: "container_dir", Type: cty.String, Required: false},
+		"device":                           &hcldec.AttrSpec{Name: "device", Type: cty.List(cty.String), Required: false},
+		"device_cgroup_rules":              &hcldec.AttrSpec{Name: "device_cgroup_rules", Type: cty.List(cty.String), Required: false},
+		"gpus":                             &hcldec.AttrSpec{Name: "gpus", Type: cty.String, Required: false},
+		"network":                          &hcldec.AttrSpec{Name: "network", Type: cty.String, Required: false},
+		"network_create":                   &hcldec.AttrSpec{Name: "network_create", Type: cty.Bool, Required: false},
+		"publish_comm_port":                &hcldec.AttrSpec{Name: "publish_comm_port", Type: cty.Bool, Required: false},
+		"network_aliases":                  &hcldec.AttrSpec{Name: "network_aliases", Type: cty.List(cty.String), Required: false},
+		"extra_hosts":                      &hcldec.AttrSpec{Name: "extra_hosts", Type: cty.List(cty.String), Required: false},
+		"dns":                              &hcldec.AttrSpec{Name: "dns", Type: cty.List(cty.String), Required: false},
+		"dns_search":                       &hcldec.AttrSpec{Name: "dns_search", Type: cty.List(cty.String), Required: false},
+		"dns_options":                      &hcldec.AttrSpec{Name: "dns_options", Type: cty.List(cty.String), Required: false},
+		"hostname":                         &hcldec.AttrSpec{Name: "hostname", Type: cty.String, Required: false},
+		"domainname":                       &hcldec.AttrSpec{Name: "domainname", Type: cty.String, Required: false},
+		"ipc_mode":                         &hcldec.AttrSpec{Name: "ipc_mode", Type: cty.String, Required: false},
+		"pid_mode":                         &hcldec.AttrSpec{Name: "pid_mode", Type: cty.String, Required: false},
+		"cgroupns":                         &hcldec.AttrSpec{Name: "cgroupns", Type: cty.String, Required: false},
+		"cgroup_parent":                    &hcldec.AttrSpec{Name: "cgroup_parent", Type: cty.String, Required: false},
+		"discard":                          &hcldec.AttrSpec{Name: "discard", Type: cty.Bool, Required: false},
+		"keep_container_on_error":          &hcldec.AttrSpec{Name: "keep_container_on_error", Type: cty.Bool, Required: false},
+		"stream_logs":                      &hcldec.AttrSpec{Name: "stream_logs", Type: cty.Bool, Required: false},
+		"pause_after_provision":            &hcldec.AttrSpec{Name: "pause_after_provision", Type: cty.Bool, Required: false},
+		"pre_commit_commands":              &hcldec.AttrSpec{Name: "pre_commit_commands", Type: cty.List(cty.String), Required: false},
+		"cap_add":                          &hcldec.AttrSpec{Name: "cap_add", Type: cty.List(cty.String), Required: false},
+		"cap_drop":                         &hcldec.AttrSpec{Name: "cap_drop", Type: cty.List(cty.String), Required: false},
+		"security_opts":                    &hcldec.AttrSpec{Name: "security_opts", Type: cty.List(cty.String), Required: false},
+		"ulimits":                          &hcldec.AttrSpec{Name: "ulimits", Type: cty.Map(cty.String), Required: false},
+		"cpus":                             &hcldec.AttrSpec{Name: "cpus", Type: cty.String, Required: false},
+		"cpuset_cpus":                      &hcldec.AttrSpec{Name: "cpuset_cpus", Type: cty.String, Required: false},
+		"memory":                           &hcldec.AttrSpec{Name: "memory", Type: cty.String, Required: false},
+		"memory_swap":                      &hcldec.AttrSpec{Name: "memory_swap", Type: cty.String, Required: false},
+		"shm_size":                         &hcldec.AttrSpec{Name: "shm_size", Type: cty.String, Required: false},
+		"oom_score_adj":                    &hcldec.AttrSpec{Name: "oom_score_adj", Type: cty.Number, Required: false},
+		"oom_kill_disable":                 &hcldec.AttrSpec{Name: "oom_kill_disable", Type: cty.Bool, Required: false},
+		"run_labels":                       &hcldec.AttrSpec{Name: "run_labels", Type: cty.Map(cty.String), Required: false},
+		"container_name":                   &hcldec.AttrSpec{Name: "container_name", Type: cty.String, Required: false},
+		"driver":                           &hcldec.AttrSpec{Name: "driver", Type: cty.String, Required: false},
+		"docker_path":                      &hcldec.AttrSpec{Name: "docker_path", Type: cty.String, Required: false},
+		"exec_user":                        &hcldec.AttrSpec{Name: "exec_user", Type: cty.String, Required: false},
+		"workdir":                          &hcldec.AttrSpec{Name: "workdir", Type: cty.String, Required: false},
+		"exec_tty":                         &hcldec.AttrSpec{Name: "exec_tty", Type: cty.Bool, Required: false},
+		"exec_privileged":                  &hcldec.AttrSpec{Name: "exec_privileged", Type: cty.Bool, Required: false},
+		"exec_args":                        &hcldec.AttrSpec{Name: "exec_args", Type: cty.List(cty.String), Required: false},
+		"container_env":                    &hcldec.AttrSpec{Name: "container_env", Type: cty.Map(cty.String), Required: false},
+		"env_file":                         &hcldec.AttrSpec{Name: "env_file", Type: cty.String, Required: false},
+		"forward_ssh_agent":                &hcldec.AttrSpec{Name: "forward_ssh_agent", Type: cty.Bool, Required: false},
+		"proxy_env":                        &hcldec.BlockSpec{TypeName: "proxy_env", Nested: hcldec.ObjectSpec((*FlatProxyEnvConfig)(nil).HCL2Spec())},
+		"export_path":                      &hcldec.AttrSpec{Name: "export_path", Type: cty.String, Required: false},
+		"export_format":                    &hcldec.AttrSpec{Name: "export_format", Type: cty.String, Required: false},
+		"oci_layout_path":                  &hcldec.AttrSpec{Name: "oci_layout_path", Type: cty.String, Required: false},
+		"export_compression":               &hcldec.AttrSpec{Name: "export_compression", Type: cty.String, Required: false},
+		"export_compression_level":         &hcldec.AttrSpec{Name: "export_compression_level", Type: cty.Number, Required: false},
+		"export_diff_only":                 &hcldec.AttrSpec{Name: "export_diff_only", Type: cty.Bool, Required: false},
+		"export_exclude_layers":            &hcldec.AttrSpec{Name: "export_exclude_layers", Type: cty.List(cty.String), Required: false},
+		"export_exclude_labeled_layers":    &hcldec.AttrSpec{Name: "export_exclude_labeled_layers", Type: cty.Map(cty.String), Required: false},
+		"image":                            &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
+		"source_image_digest":              &hcldec.AttrSpec{Name: "source_image_digest", Type: cty.String, Required: false},
+		"verify_signature":                 &hcldec.BlockSpec{TypeName: "verify_signature", Nested: hcldec.ObjectSpec((*FlatSignatureConfig)(nil).HCL2Spec())},
+		"scan_image":                       &hcldec.BlockSpec{TypeName: "scan_image", Nested: hcldec.ObjectSpec((*FlatScanConfig)(nil).HCL2Spec())},
+		"push":                             &hcldec.BlockSpec{TypeName: "push", Nested: hcldec.ObjectSpec((*FlatPushConfig)(nil).HCL2Spec())},
+		"wait_for":                         &hcldec.BlockSpec{TypeName: "wait_for", Nested: hcldec.ObjectSpec((*FlatWaitForConfig)(nil).HCL2Spec())},
+		"message":                          &hcldec.AttrSpec{Name: "message", Type: cty.String, Required: false},
+		"privileged":                       &hcldec.AttrSpec{Name: "privileged", Type: cty.Bool, Required: false},
+		"pty":                              &hcldec.AttrSpec{Name: "pty", Type: cty.Bool, Required: false},
+		"runtime":                          &hcldec.AttrSpec{Name: "runtime", Type: cty.String, Required: false},
+		"userns":                           &hcldec.AttrSpec{Name: "userns", Type: cty.String, Required: false},
+		"uidmap":                           &hcldec.AttrSpec{Name: "uidmap", Type: cty.List(cty.String), Required: false},
+		"gidmap":                           &hcldec.AttrSpec{Name: "gidmap", Type: cty.List(cty.String), Required: false},
+		"init":                             &hcldec.AttrSpec{Name: "init", Type: cty.Bool, Required: false},
+		"wait_for_healthy":                 &hcldec.AttrSpec{Name: "wait_for_healthy", Type: cty.Bool, Required: false},
+		"healthy_timeout":                  &hcldec.AttrSpec{Name: "healthy_timeout", Type: cty.String, Required: false},
+		"pull":                             &hcldec.AttrSpec{Name: "pull", Type: cty.Bool, Required: false},
+		"registry_mirrors":                 &hcldec.AttrSpec{Name: "registry_mirrors", Type: cty.List(cty.String), Required: false},
+		"pull_policy":                      &hcldec.AttrSpec{Name: "pull_policy", Type: cty.String, Required: false},
+		"pull_retries":                     &hcldec.AttrSpec{Name: "pull_retries", Type: cty.Number, Required: false},
+		"pull_retry_backoff":               &hcldec.AttrSpec{Name: "pull_retry_backoff", Type: cty.String, Required: false},
+		"pull_timeout":                     &hcldec.AttrSpec{Name: "pull_timeout", Type: cty.String, Required: false},
+		"pre_pull_images":                  &hcldec.AttrSpec{Name: "pre_pull_images", Type: cty.List(cty.String), Required: false},
+		"provision_timeout":                &hcldec.AttrSpec{Name: "provision_timeout", Type: cty.String, Required: false},
+		"commit_timeout":                   &hcldec.AttrSpec{Name: "commit_timeout", Type: cty.String, Required: false},
+		"build_timeout":                    &hcldec.AttrSpec{Name: "build_timeout", Type: cty.String, Required: false},
+		"run_command":                      &hcldec.AttrSpec{Name: "run_command", Type: cty.List(cty.String), Required: false},
+		"entrypoint":                       &hcldec.AttrSpec{Name: "entrypoint", Type: cty.List(cty.String), Required: false},
+		"cmd":                              &hcldec.AttrSpec{Name: "cmd", Type: cty.List(cty.String), Required: false},
+		"tmpfs":                            &hcldec.AttrSpec{Name: "tmpfs", Type: cty.List(cty.String), Required: false},
+		"read_only":                        &hcldec.AttrSpec{Name: "read_only", Type: cty.Bool, Required: false},
+		"volumes":                          &hcldec.AttrSpec{Name: "volumes", Type: cty.Map(cty.String), Required: false},
+		"mounts":                           &hcldec.BlockListSpec{TypeName: "mounts", Nested: hcldec.ObjectSpec((*FlatMountConfig)(nil).HCL2Spec())},
+		"copy_files":                       &hcldec.BlockListSpec{TypeName: "copy_files", Nested: hcldec.ObjectSpec((*FlatCopyFileConfig)(nil).HCL2Spec())},
+		"sidecar":                          &hcldec.BlockListSpec{TypeName: "sidecar", Nested: hcldec.ObjectSpec((*FlatSidecarConfig)(nil).HCL2Spec())},
+		"secrets":                          &hcldec.BlockListSpec{TypeName: "secrets", Nested: hcldec.ObjectSpec((*FlatSecretConfig)(nil).HCL2Spec())},
+		"keep_volumes":                     &hcldec.AttrSpec{Name: "keep_volumes", Type: cty.Bool, Required: false},
+		"cache_volumes":                    &hcldec.AttrSpec{Name: "cache_volumes", Type: cty.Map(cty.String), Required: false},
+		"fix_upload_owner":                 &hcldec.AttrSpec{Name: "fix_upload_owner", Type: cty.Bool, Required: false},
+		"upload_owner":                     &hcldec.AttrSpec{Name: "upload_owner", Type: cty.String, Required: false},
+		"windows_container":                &hcldec.AttrSpec{Name: "windows_container", Type: cty.Bool, Required: false},
+		"windows_shell":                    &hcldec.AttrSpec{Name: "windows_shell", Type: cty.String, Required: false},
+		"skip_winrm_setup":                 &hcldec.AttrSpec{Name: "skip_winrm_setup", Type: cty.Bool, Required: false},
+		"rootless":                         &hcldec.AttrSpec{Name: "rootless", Type: cty.Bool, Required: false},
+		"platform":                         &hcldec.AttrSpec{Name: "platform", Type: cty.String, Required: false},
+		"binfmt_install":                   &hcldec.AttrSpec{Name: "binfmt_install", Type: cty.Bool, Required: false},
+		"binfmt_image":                     &hcldec.AttrSpec{Name: "binfmt_image", Type: cty.String, Required: false},
+		"login":                            &hcldec.AttrSpec{Name: "login", Type: cty.Bool, Required: false},
+		"login_password":                   &hcldec.AttrSpec{Name: "login_password", Type: cty.String, Required: false},
+		"login_server":                     &hcldec.AttrSpec{Name: "login_server", Type: cty.String, Required: false},
+		"login_username":                   &hcldec.AttrSpec{Name: "login_username", Type: cty.String, Required: false},
+		"ecr_login":                        &hcldec.AttrSpec{Name: "ecr_login", Type: cty.Bool, Required: false},
+		"aws_access_key":                   &hcldec.AttrSpec{Name: "aws_access_key", Type: cty.String, Required: false},
+		"aws_secret_key":                   &hcldec.AttrSpec{Name: "aws_secret_key", Type: cty.String, Required: false},
+		"aws_token":                        &hcldec.AttrSpec{Name: "aws_token", Type: cty.String, Required: false},
+		"aws_profile":                      &hcldec.AttrSpec{Name: "aws_profile", Type: cty.String, Required: false},
+		"aws_web_identity_role_arn":        &hcldec.AttrSpec{Name: "aws_web_identity_role_arn", Type: cty.String, Required: false},
+		"aws_web_identity_token_file":      &hcldec.AttrSpec{Name: "aws_web_identity_token_file", Type: cty.String, Required: false},
+		"aws_web_identity_session_name":    &hcldec.AttrSpec{Name: "aws_web_identity_session_name", Type: cty.String, Required: false},
+		"aws_web_identity_duration":        &hcldec.AttrSpec{Name: "aws_web_identity_duration", Type: cty.String, Required: false},
+		"aws_force_use_public_ecr":         &hcldec.AttrSpec{Name: "aws_force_use_public_ecr", Type: cty.Bool, Required: false},
+		"ecr_endpoint":                     &hcldec.AttrSpec{Name: "ecr_endpoint", Type: cty.String, Required: false},
+		"docker_host":                      &hcldec.AttrSpec{Name: "docker_host", Type: cty.String, Required: false},
+		"docker_context":                   &hcldec.AttrSpec{Name: "docker_context", Type: cty.String, Required: false},
+		"tls_verify":                       &hcldec.AttrSpec{Name: "tls_verify", Type: cty.Bool, Required: false},
+		"cert_path":                        &hcldec.AttrSpec{Name: "cert_path", Type: cty.String, Required: false},
+		"ca_cert":                          &hcldec.AttrSpec{Name: "ca_cert", Type: cty.String, Required: false},
+		"client_cert":                      &hcldec.AttrSpec{Name: "client_cert", Type: cty.String, Required: false},
+		"client_key":                       &hcldec.AttrSpec{Name: "client_key", Type: cty.String, Required: false},
+		"docker_host_ssh_private_key_file": &hcldec.AttrSpec{Name: "docker_host_ssh_private_key_file", Type: cty.String, Required: false},
+		"docker_host_ssh_agent_socket":     &hcldec.AttrSpec{Name: "docker_host_ssh_agent_socket", Type: cty.String, Required: false},
+		"insecure_registries":              &hcldec.AttrSpec{Name: "insecure_registries", Type: cty.List(cty.String), Required: false},
+		"registry_ca_file":                 &hcldec.AttrSpec{Name: "registry_ca_file", Type: cty.String, Required: false},
+		"gar_login":                        &hcldec.AttrSpec{Name: "gar_login", Type: cty.Bool, Required: false},
+		"acr_login":                        &hcldec.AttrSpec{Name: "acr_login", Type: cty.Bool, Required: false},
+		"ghcr_login":                       &hcldec.AttrSpec{Name: "ghcr_login", Type: cty.Bool, Required: false},
+	}
+	return s
+}
+
+// FlatDockerHostConfig is an auto-generated flat version of DockerHostConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatDockerHostConfig struct {
+	DockerHost                  *string `mapstructure:"docker_host" required:"false" cty:"docker_host" hcl:"docker_host"`
+	DockerContext               *string `mapstructure:"docker_context" required:"false" cty:"docker_context" hcl:"docker_context"`
+	TLSVerify                   *bool   `mapstructure:"tls_verify" required:"false" cty:"tls_verify" hcl:"tls_verify"`
+	CertPath                    *string `mapstructure:"cert_path" required:"false" cty:"cert_path" hcl:"cert_path"`
+	CACert                      *string `mapstructure:"ca_cert" required:"false" cty:"ca_cert" hcl:"ca_cert"`
+	ClientCert                  *string `mapstructure:"client_cert" required:"false" cty:"client_cert" hcl:"client_cert"`
+	ClientKey                   *string `mapstructure:"client_key" required:"false" cty:"client_key" hcl:"client_key"`
+	DockerHostSSHPrivateKeyFile *string `mapstructure:"docker_host_ssh_private_key_file" required:"false" cty:"docker_host_ssh_private_key_file" hcl:"docker_host_ssh_private_key_file"`
+	DockerHostSSHAgentSocket    *string `mapstructure:"docker_host_ssh_agent_socket" required:"false" cty:"docker_host_ssh_agent_socket" hcl:"docker_host_ssh_agent_socket"`
+}
+
+// FlatMapstructure returns a new FlatDockerHostConfig.
+// FlatDockerHostConfig is an auto-generated flat version of DockerHostConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*DockerHostConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatDockerHostConfig)
+}
+
+// HCL2Spec returns the hcl spec of a DockerHostConfig.
+// This spec is used by HCL to read the fields of DockerHostConfig.
+// The decoded values from this spec will then be applied to a FlatDockerHostConfig.
+func (*FlatDockerHostConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"docker_host":                      &hcldec.AttrSpec{Name: "docker_host", Type: cty.String, Required: false},
+		"docker_context":                   &hcldec.AttrSpec{Name: "docker_context", Type: cty.String, Required: false},
+		"tls_verify":                       &hcldec.AttrSpec{Name: "tls_verify", Type: cty.Bool, Required: false},
+		"cert_path":                        &hcldec.AttrSpec{Name: "cert_path", Type: cty.String, Required: false},
+		"ca_cert":                          &hcldec.AttrSpec{Name: "ca_cert", Type: cty.String, Required: false},
+		"client_cert":                      &hcldec.AttrSpec{Name: "client_cert", Type: cty.String, Required: false},
+		"client_key":                       &hcldec.AttrSpec{Name: "client_key", Type: cty.String, Required: false},
+		"docker_host_ssh_private_key_file": &hcldec.AttrSpec{Name: "docker_host_ssh_private_key_file", Type: cty.String, Required: false},
+		"docker_host_ssh_agent_socket":     &hcldec.AttrSpec{Name: "docker_host_ssh_agent_socket", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatRegistryTLSConfig is an auto-generated flat version of RegistryTLSConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatRegistryTLSConfig struct {
+	InsecureRegistries []string `mapstructure:"insecure_registries" required:"false" cty:"insecure_registries" hcl:"insecure_registries"`
+	RegistryCAFile     *string  `mapstructure:"registry_ca_file" required:"false" cty:"registry_ca_file" hcl:"registry_ca_file"`
+}
+
+// FlatMapstructure returns a new FlatRegistryTLSConfig.
+// FlatRegistryTLSConfig is an auto-generated flat version of RegistryTLSConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*RegistryTLSConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatRegistryTLSConfig)
+}
+
+// HCL2Spec returns the hcl spec of a RegistryTLSConfig.
+// This spec is used by HCL to read the fields of RegistryTLSConfig.
+// The decoded values from this spec will then be applied to a FlatRegistryTLSConfig.
+func (*FlatRegistryTLSConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"insecure_registries": &hcldec.AttrSpec{Name: "insecure_registries", Type: cty.List(cty.String), Required: false},
+		"registry_ca_file":    &hcldec.AttrSpec{Name: "registry_ca_file", Type: cty.String, Required: false},
 	}
 	return s
 }
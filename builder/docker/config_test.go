@@ -6,7 +6,11 @@ package docker
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func testConfig() map[string]interface{} {
@@ -144,12 +148,1452 @@ func TestConfigPrepare_pull(t *testing.T) {
 	raw["pull"] = false
 	warns, errs = c.Prepare(raw)
 	testConfigOk(t, warns, errs)
-	if c.Pull {
-		t.Fatal("should not pull")
+	if c.PullPolicy != PullIfNotPresent {
+		t.Fatalf("pull = false should pull the image missing locally, got %q", c.PullPolicy)
 	}
+
+	// Pull policy set
+	delete(raw, "pull")
+	raw["pull_policy"] = PullIfNotPresent
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.PullPolicy != PullIfNotPresent {
+		t.Fatalf("bad pull policy: %q", c.PullPolicy)
+	}
+
+	raw["pull_policy"] = "sometimes"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["pull_policy"] = PullNever
+	raw["pull"] = false
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_driver(t *testing.T) {
+	raw := testConfig()
+
+	// Default driver
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.DriverType != "cli" {
+		t.Fatalf("bad default driver: %s", c.DriverType)
+	}
+
+	// API driver
+	raw["driver"] = "api"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// Podman driver defaults to the podman binary
+	raw["driver"] = "podman"
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.Executable != "podman" {
+		t.Fatalf("bad default docker_path for podman: %s", c.Executable)
+	}
+
+	// nerdctl driver defaults to the nerdctl binary
+	raw["driver"] = "nerdctl"
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.Executable != "nerdctl" {
+		t.Fatalf("bad default docker_path for nerdctl: %s", c.Executable)
+	}
+
+	// buildah driver defaults to the buildah binary, and can't export
+	raw["driver"] = "buildah"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "export_path")
+	raw["commit"] = true
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.Executable != "buildah" {
+		t.Fatalf("bad default docker_path for buildah: %s", c.Executable)
+	}
+	delete(raw, "commit")
+	raw["export_path"] = "foo"
+
+	// Unknown driver
+	raw["driver"] = "nope"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+// Test variations of a build bootstrap config; including unset
+func TestConfigPrepare_changes(t *testing.T) {
+	raw := testConfig()
+	raw["changes"] = []string{
+		"HEALTHCHECK --interval=30s CMD curl -f http://localhost/",
+		`SHELL ["/bin/bash", "-c"]`,
+		"STOPSIGNAL SIGTERM",
+		"ONBUILD RUN make",
+	}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["changes"] = []string{"RUN make"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_nerdctlChanges(t *testing.T) {
+	raw := testConfig()
+	delete(raw, "export_path")
+	raw["commit"] = true
+	raw["driver"] = "nerdctl"
+	raw["changes"] = []string{
+		`CMD ["/bin/app"]`,
+		"ENTRYPOINT /bin/sh",
+		"HEALTHCHECK --interval=30s CMD curl -f http://localhost/",
+	}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["changes"] = []string{"EXPOSE 80"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "changes")
+	raw["labels"] = map[string]string{"org.opencontainers.image.version": "1.0"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "labels")
+	raw["skip_unchanged"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_pauseBeforeCommit(t *testing.T) {
+	raw := testConfig()
+	raw["pause_before_commit"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "export_path")
+	raw["commit"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+}
+
+func TestConfigPrepare_squash(t *testing.T) {
+	raw := testConfig()
+	raw["squash"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "export_path")
+	raw["commit"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_snapshots(t *testing.T) {
+	raw := testConfig()
+	raw["resume"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["snapshot_repository"] = "packer-snapshots/app"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["communicator"] = "ssh"
+	raw["ssh_username"] = "root"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_checkpoint(t *testing.T) {
+	raw := testConfig()
+	raw["checkpoint"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["snapshot_repository"] = "packer-snapshots/app"
+	raw["checkpoint_dir"] = "checkpoints"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	t.Setenv("PACKER_CACHE_DIR", t.TempDir())
+	delete(raw, "checkpoint_dir")
+	var c Config
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if !filepath.IsAbs(c.CheckpointDir) || filepath.Base(c.CheckpointDir) != "packer-snapshots_app" {
+		t.Fatalf("bad default checkpoint directory: %q", c.CheckpointDir)
+	}
+
+	raw["driver"] = "buildah"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "driver")
+	raw["checkpoint"] = false
+	raw["checkpoint_dir"] = "/var/cache/checkpoints"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_prePullImages(t *testing.T) {
+	raw := testConfig()
+	raw["pre_pull_images"] = []string{"postgres:16", "redis:7"}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["pre_pull_images"] = []string{"postgres:16", ""}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_ecrEndpoint(t *testing.T) {
+	raw := testConfig()
+	raw["ecr_login"] = true
+	raw["login_server"] = "https://123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com"
+	raw["ecr_endpoint"] = "https://ecr-fips.us-gov-west-1.amazonaws.com"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["ecr_endpoint"] = "ecr-fips.us-gov-west-1.amazonaws.com"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_platform(t *testing.T) {
+	raw := testConfig()
+	raw["platform"] = "linux/arm64/v8"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["platform"] = "arm64"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_cloudRegistryLogin(t *testing.T) {
+	raw := testConfig()
+	raw["acr_login"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["login_server"] = "myregistry.azurecr.io"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["gar_login"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// GHCR has a single login server
+	raw = testConfig()
+	raw["ghcr_login"] = true
+	var c Config
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.LoginServer != "ghcr.io" {
+		t.Fatalf("bad login server: %q", c.LoginServer)
+	}
+}
+
+func TestConfigPrepare_registryMirrors(t *testing.T) {
+	raw := testConfig()
+	raw["registry_mirrors"] = []string{"mirror.example.com", "quay.io=quay-mirror.example.com"}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["registry_mirrors"] = []string{"quay.io="}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_registryTLS(t *testing.T) {
+	raw := testConfig()
+	raw["driver"] = DriverPodman
+	raw["insecure_registries"] = []string{"registry.example.com:5000"}
+	raw["registry_ca_file"] = testRegistryCAFile(t)
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["driver"] = DriverCLI
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_proxyEnv(t *testing.T) {
+	raw := testConfig()
+	raw["proxy_env"] = map[string]interface{}{
+		"https_proxy": "http://proxy.example.com:3128",
+		"passthrough": false,
+		"inject":      true,
+	}
+	c := &Config{}
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if !c.ProxyEnv.Passthrough.False() || !c.ProxyEnv.Inject {
+		t.Fatalf("bad proxy_env: %#v", c.ProxyEnv)
+	}
+
+	raw["proxy_env"] = map[string]interface{}{"http_proxy": "proxy.example.com"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_pullRetries(t *testing.T) {
+	raw := testConfig()
+	c := &Config{}
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.PullRetries != 0 || c.PullRetryBackoff != defaultPullRetryBackoff {
+		t.Fatalf("bad defaults: %d, %s", c.PullRetries, c.PullRetryBackoff)
+	}
+
+	raw["pull_retries"] = 5
+	raw["pull_retry_backoff"] = "30s"
+	c = &Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.PullRetries != 5 || c.PullRetryBackoff != 30*time.Second {
+		t.Fatalf("bad retries: %d, %s", c.PullRetries, c.PullRetryBackoff)
+	}
+
+	raw["pull_retries"] = -1
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_sourceImageDigest(t *testing.T) {
+	const digest = "sha256:a0d9e826ab87bd665cfc640598a871b748b4b70a01a4f3d174d4fb02adad07a9"
+
+	raw := testConfig()
+	raw["source_image_digest"] = digest
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["image"] = "ubuntu@" + digest
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["image"] = "ubuntu@sha256:b0d9e826ab87bd665cfc640598a871b748b4b70a01a4f3d174d4fb02adad07a9"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["image"] = "ubuntu"
+	raw["source_image_digest"] = "a0d9e826"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_verifySignature(t *testing.T) {
+	raw := testConfig()
+	raw["verify_signature"] = map[string]interface{}{"key": "cosign.pub"}
+	c := &Config{}
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.VerifySignature.Tool != SignatureToolCosign {
+		t.Fatalf("bad tool: %s", c.VerifySignature.Tool)
+	}
+
+	raw["verify_signature"] = map[string]interface{}{"tool": "cosign"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_scanImage(t *testing.T) {
+	raw := testConfig()
+	raw["scan_image"] = map[string]interface{}{"tool": "grype", "severity": "high"}
+	c := &Config{}
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.ScanImage.Path != "grype" {
+		t.Fatalf("bad path: %s", c.ScanImage.Path)
+	}
+
+	raw["scan_image"] = map[string]interface{}{"severity": "urgent"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_entrypoint(t *testing.T) {
+	raw := testConfig()
+	raw["entrypoint"] = []string{"/busybox/sh", "-c"}
+	raw["cmd"] = []string{"sleep infinity"}
+	c := &Config{}
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if !reflect.DeepEqual(c.RunCommand, []string{"-d", "-i", "-t", "--", "{{.Image}}"}) {
+		t.Fatalf("bad run command: %v", c.RunCommand)
+	}
+
+	raw["run_command"] = []string{"-d", "--entrypoint=/bin/sh", "{{.Image}}"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "entrypoint")
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+}
+
+func TestConfigPrepare_userns(t *testing.T) {
+	raw := testConfig()
+	raw["userns"] = "host"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["userns"] = "keep-id"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["driver"] = DriverPodman
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["uidmap"] = []string{"0:100000:65536"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "userns")
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["gidmap"] = []string{"0:100000"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_init(t *testing.T) {
+	raw := testConfig()
+	raw["init"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "driver")
+	delete(raw, "export_path")
+	raw["commit"] = true
+	raw["windows_container"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_streamLogs(t *testing.T) {
+	raw := testConfig()
+	raw["stream_logs"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_waitForHealthy(t *testing.T) {
+	raw := testConfig()
+	raw["wait_for_healthy"] = true
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.HealthyTimeout != defaultHealthyTimeout {
+		t.Fatalf("bad timeout: %s", c.HealthyTimeout)
+	}
+
+	raw["healthy_timeout"] = "-1m"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "healthy_timeout")
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_preCommitCommands(t *testing.T) {
+	raw := testConfig()
+	raw["pre_commit_commands"] = []string{"apt-get clean"}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["communicator"] = "none"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "communicator")
+	delete(raw, "export_path")
+	raw["discard"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_sidecar(t *testing.T) {
+	raw := testConfig()
+	raw["network"] = "packer"
+	raw["sidecar"] = []map[string]interface{}{
+		{"name": "db", "image": "postgres:16", "env": map[string]string{"POSTGRES_PASSWORD": "packer"}},
+		{"name": "cache", "image": "redis:7"},
+	}
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.Sidecars[1].Network != "packer" {
+		t.Fatalf("bad network: %q", c.Sidecars[1].Network)
+	}
+
+	raw["sidecar"] = []map[string]interface{}{
+		{"name": "db", "image": "postgres:16"},
+		{"name": "db", "image": "mysql:8"},
+	}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["sidecar"] = []map[string]interface{}{{"name": "db", "image": "postgres:16"}}
+	raw["driver"] = DriverBuildah
+	delete(raw, "network")
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_secrets(t *testing.T) {
+	t.Setenv("PACKER_TEST_TOKEN", "s3cr3t")
+	raw := testConfig()
+	raw["secrets"] = []map[string]interface{}{
+		{"id": "token", "env": "PACKER_TEST_TOKEN"},
+		{"id": "npmrc", "env": "PACKER_TEST_TOKEN", "target": "/run/secrets/npm/npmrc"},
+	}
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.Secrets[0].Target != "/run/secrets/token" {
+		t.Fatalf("bad target: %q", c.Secrets[0].Target)
+	}
+
+	raw["secrets"] = []map[string]interface{}{
+		{"id": "token", "env": "PACKER_TEST_TOKEN"},
+		{"id": "token", "env": "PACKER_TEST_TOKEN", "target": "/run/secrets/other"},
+	}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["secrets"] = []map[string]interface{}{
+		{"id": "token", "env": "PACKER_TEST_TOKEN"},
+		{"id": "other", "env": "PACKER_TEST_TOKEN", "target": "/run/secrets/token"},
+	}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["secrets"] = []map[string]interface{}{{"id": "token", "env": "PACKER_TEST_TOKEN"}}
+	raw["communicator"] = "none"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "communicator")
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_forwardSSHAgent(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	t.Setenv("SSH_AUTH_SOCK", "/tmp/ssh-XXXX/agent.1234")
+	raw := testConfig()
+	raw["forward_ssh_agent"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["docker_host"] = "tcp://build.example.com:2376"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// A remote daemon selected by a context or the environment
+	delete(raw, "docker_host")
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	testDockerContext(t, configDir, "b71199ebd070b36beab7317920c2c2f1d777df8d05e5527d8458fda57cb17a7a",
+		`{"Name": "remote", "Endpoints": {"docker": {"Host": "tcp://docker.example.com:2376", "SkipTLSVerify": false}}}`)
+	raw["docker_context"] = "remote"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "docker_context")
+	t.Setenv("DOCKER_HOST", "tcp://build.example.com:2376")
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	t.Setenv("DOCKER_HOST", "")
+	raw["communicator"] = "ssh"
+	raw["ssh_username"] = "root"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "communicator")
+	delete(raw, "ssh_username")
+	t.Setenv("SSH_AUTH_SOCK", "")
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_cacheVolumes(t *testing.T) {
+	raw := testConfig()
+	raw["cache_volumes"] = map[string]string{"maven": "/root/.m2/repository"}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["cache_volumes"] = map[string]string{"maven/repo": "/root/.m2/repository"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["cache_volumes"] = map[string]string{"maven": ".m2"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["cache_volumes"] = map[string]string{"maven": "/root/.m2/repository"}
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_skipUnchanged(t *testing.T) {
+	raw := testConfig()
+	delete(raw, "export_path")
+	raw["commit"] = true
+	raw["skip_unchanged"] = true
+	raw["content_hash_files"] = []string{"scripts/*.sh"}
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.configHash == "" {
+		t.Fatal("the configuration should be hashed")
+	}
+
+	raw["content_hash_files"] = []string{"scripts/[.sh"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "content_hash_files")
+	raw["push"] = map[string]interface{}{"tags": []string{"app:latest"}}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "push")
+	delete(raw, "commit")
+	raw["discard"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw = testConfig()
+	delete(raw, "export_path")
+	raw["commit"] = true
+	raw["content_hash_files"] = []string{"scripts/*.sh"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_workdir(t *testing.T) {
+	raw := testConfig()
+	raw["workdir"] = "/app"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["workdir"] = "app"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_hostname(t *testing.T) {
+	raw := testConfig()
+	raw["hostname"] = "build-01"
+	raw["domainname"] = "example.com"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["network"] = "host"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "network")
+	raw["hostname"] = "build_01"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_namespaceModes(t *testing.T) {
+	raw := testConfig()
+	raw["ipc_mode"] = "shareable"
+	raw["pid_mode"] = "container:db"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["pid_mode"] = "container"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["pid_mode"] = "ns:/proc/1/ns/pid"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["driver"] = DriverPodman
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+}
+
+func TestConfigPrepare_cgroups(t *testing.T) {
+	raw := testConfig()
+	raw["cgroupns"] = "private"
+	raw["cgroup_parent"] = "ci-jobs.slice"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["cgroupns"] = "container:db"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["driver"] = DriverPodman
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	delete(raw, "driver")
+	raw["cgroupns"] = "host"
+	raw["windows_container"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_readOnly(t *testing.T) {
+	raw := testConfig()
+	raw["read_only"] = true
+	raw["tmpfs"] = []string{"/tmp", "/run"}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "driver")
+	delete(raw, "tmpfs")
+	raw["windows_container"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_containerName(t *testing.T) {
+	raw := testConfig()
+	raw["container_name"] = "packer-{{.BuildName}}-{{.RunUUID}}"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["container_name"] = "packer-{{.Nope"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["container_name"] = "packer"
+	raw["run_command"] = []string{"-d", "-i", "-t", "--name=build", "--", "{{.Image}}"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_copyFiles(t *testing.T) {
+	raw := testConfig()
+	raw["copy_files"] = []map[string]interface{}{
+		{"source": t.TempDir(), "destination": "/srv/models"},
+	}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["windows_container"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "windows_container")
+	raw["copy_files"] = []map[string]interface{}{
+		{"source": t.TempDir(), "destination": "srv/models"},
+	}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_exportFilters(t *testing.T) {
+	raw := testConfig()
+	raw["export_diff_only"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["export_exclude_layers"] = []string{"sha256:" + strings.Repeat("a", 64)}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["export_format"] = ExportFormatOCI
+	raw["export_exclude_labeled_layers"] = map[string]string{"com.example.base": "true"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["export_exclude_layers"] = []string{"4f4fb700"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "export_exclude_layers")
+	delete(raw, "export_path")
+	delete(raw, "export_format")
+	raw["commit"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_annotations(t *testing.T) {
+	raw := testConfig()
+	raw["export_format"] = ExportFormatOCI
+	raw["annotations"] = map[string]string{
+		"org.opencontainers.image.revision": "{{.GitCommit}}",
+	}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["annotations"] = map[string]string{"a": "{{.Nope"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["annotations"] = map[string]string{"a": "1"}
+	delete(raw, "export_format")
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_uploadOwner(t *testing.T) {
+	raw := testConfig()
+	raw["upload_owner"] = "app:1000"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["upload_owner"] = "app:app:app"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["upload_owner"] = "app"
+	raw["fix_upload_owner"] = false
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_oom(t *testing.T) {
+	raw := testConfig()
+	raw["oom_score_adj"] = -500
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["oom_score_adj"] = 1001
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "oom_score_adj")
+	raw["oom_kill_disable"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["memory"] = "2g"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_stop(t *testing.T) {
+	raw := testConfig()
+	raw["stop_signal"] = "SIGINT"
+	raw["stop_timeout"] = "30s"
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.StopTimeout != 30*time.Second {
+		t.Fatalf("bad stop timeout: %s", c.StopTimeout)
+	}
+
+	raw["stop_signal"] = "sigint; rm"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["stop_signal"] = "15"
+	raw["pause_before_commit"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "pause_before_commit")
+	for _, driver := range []string{DriverPodman, DriverNerdctl} {
+		raw["driver"] = driver
+		raw["stop_signal"] = "15"
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigErr(t, warns, errs)
+
+		delete(raw, "stop_signal")
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigOk(t, warns, errs)
+	}
+}
+
+func TestConfigPrepare_labels(t *testing.T) {
+	raw := testConfig()
+	delete(raw, "export_path")
+	raw["commit"] = true
+	raw["labels"] = map[string]string{
+		"org.opencontainers.image.revision": "{{.GitCommit}}",
+		"org.opencontainers.image.created":  "{{.Created}}",
+	}
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.Labels["org.opencontainers.image.revision"] != "{{.GitCommit}}" {
+		t.Fatalf("the labels should be rendered at commit: %v", c.Labels)
+	}
+
+	raw["labels"] = map[string]string{"version": "{{.GitCommit"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["labels"] = map[string]string{"a b": "c"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["labels"] = map[string]string{"version": "1"}
+	raw["commit"] = false
+	raw["export_path"] = "image.tar"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_binfmt(t *testing.T) {
+	raw := testConfig()
+	raw["binfmt_install"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["platform"] = "linux/arm64"
+	var c Config
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.BinfmtImage != defaultBinfmtImage {
+		t.Fatalf("bad binfmt image: %s", c.BinfmtImage)
+	}
+
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_timeouts(t *testing.T) {
+	raw := testConfig()
+	raw["pull_timeout"] = "10m"
+	raw["build_timeout"] = "2h"
+	c := &Config{}
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.PullTimeout != 10*time.Minute || c.BuildTimeout != 2*time.Hour {
+		t.Fatalf("bad timeouts: %s, %s", c.PullTimeout, c.BuildTimeout)
+	}
+
+	raw["commit_timeout"] = "-1m"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_exec(t *testing.T) {
+	raw := testConfig()
+	raw["exec_tty"] = true
+	raw["exec_privileged"] = true
+	c := &Config{}
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if !c.Pty {
+		t.Fatal("exec_tty should allocate a TTY")
+	}
+
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_publishCommPort(t *testing.T) {
+	raw := testConfig()
+	raw["publish_comm_port"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["communicator"] = "ssh"
+	raw["ssh_username"] = "packer"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_winrm(t *testing.T) {
+	raw := testConfig()
+	delete(raw, "export_path")
+	raw["commit"] = true
+	raw["windows_container"] = true
+	raw["communicator"] = "winrm"
+
+	// The user and its password default, and the port is published
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.Comm.WinRMUser != defaultWinRMUser {
+		t.Fatalf("bad default winrm_username: %s", c.Comm.WinRMUser)
+	}
+	if c.Comm.WinRMPassword == "" {
+		t.Fatal("winrm_password should default to a random password")
+	}
+	if !c.PublishCommPort {
+		t.Fatal("the winrm port should be published")
+	}
+
+	// The configured credentials are kept
+	raw["winrm_username"] = "builder"
+	raw["winrm_password"] = "Secret1!"
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.Comm.WinRMUser != "builder" || c.Comm.WinRMPassword != "Secret1!" {
+		t.Fatalf("bad credentials: %s %s", c.Comm.WinRMUser, c.Comm.WinRMPassword)
+	}
+
+	// Without the setup, the username is required
+	delete(raw, "winrm_username")
+	raw["skip_winrm_setup"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["winrm_username"] = "builder"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// The setup is only for windows containers
+	raw["windows_container"] = false
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_exportFormat(t *testing.T) {
+	raw := testConfig()
+
+	// Bad format
+	raw["export_format"] = "zip"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// An OCI archive
+	raw["export_format"] = ExportFormatOCI
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// The layout directory defaults the format
+	delete(raw, "export_format")
+	delete(raw, "export_path")
+	raw["oci_layout_path"] = filepath.Join(t.TempDir(), "layout")
+	var c Config
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.ExportFormat != ExportFormatOCI {
+		t.Fatalf("bad default export_format: %s", c.ExportFormat)
+	}
+
+	// An existing empty directory
+	raw["oci_layout_path"] = t.TempDir()
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// A directory that's not empty
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	raw["oci_layout_path"] = dir
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// The tar format has no layout
+	raw["oci_layout_path"] = t.TempDir()
+	raw["export_format"] = ExportFormatTar
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// The layout can't be set with export_path
+	raw["export_format"] = ExportFormatOCI
+	raw["export_path"] = "image.tar"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Compressed exports
+	delete(raw, "oci_layout_path")
+	raw["export_compression"] = CompressionZstd
+	raw["export_compression_level"] = 19
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["export_compression"] = "xz"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+	delete(raw, "export_compression")
+	delete(raw, "export_compression_level")
+
+	// buildah can export OCI images
+	delete(raw, "oci_layout_path")
+	raw["driver"] = DriverBuildah
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+}
+
+func TestConfigPrepare_push(t *testing.T) {
+	raw := testConfig()
+	raw["push"] = map[string]interface{}{
+		"repository": "ghcr.io/example/app",
+	}
+
+	// Pushing can't be done with an export
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// The container is committed to push it
+	delete(raw, "export_path")
+	var c Config
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if !c.Commit {
+		t.Fatal("push should commit the container")
+	}
+
+	raw["push"] = map[string]interface{}{
+		"repository": "ghcr.io/example/app:latest",
+	}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_windows(t *testing.T) {
+	raw := testConfig()
+	delete(raw, "export_path")
+	raw["commit"] = true
+
+	// A windows platform selects a windows container
+	raw["platform"] = "windows/amd64"
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if !c.WindowsContainer {
+		t.Fatal("windows platform should enable windows_container")
+	}
+	if c.RunCommand[3] != "--entrypoint=powershell" {
+		t.Fatalf("bad default run_command: %v", c.RunCommand)
+	}
+	if c.ContainerDir != "c:/packer-files" {
+		t.Fatalf("bad default container_dir: %s", c.ContainerDir)
+	}
+
+	// cmd shell
+	raw["windows_shell"] = "cmd"
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.RunCommand[3] != "--entrypoint=cmd" {
+		t.Fatalf("bad run_command for cmd: %v", c.RunCommand)
+	}
+
+	// Unknown shell
+	raw["windows_shell"] = "bash"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+	delete(raw, "windows_shell")
+
+	// Numeric users don't exist on windows
+	raw["changes"] = []string{"USER 1000:1000"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["changes"] = []string{"USER ContainerUser"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// windows_shell requires a windows container
+	raw = testConfig()
+	raw["windows_shell"] = "cmd"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_gpus(t *testing.T) {
+	raw := testConfig()
+	raw["gpus"] = "all"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["gpus"] = "foo=bar"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["gpus"] = "all"
+	raw["driver"] = "buildah"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_capabilities(t *testing.T) {
+	raw := testConfig()
+	raw["cap_add"] = []string{"SYS_ADMIN", "cap_net_admin"}
+	raw["cap_drop"] = []string{"ALL"}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["cap_add"] = []string{"SYS ADMIN"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// The errors are reported in the same order every time
+	raw["cap_drop"] = []string{"NET RAW"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+	for i := 0; i < 10; i++ {
+		_, again := (&Config{}).Prepare(raw)
+		if again.Error() != errs.Error() {
+			t.Fatalf("the errors changed order:\n%s\n%s", errs, again)
+		}
+	}
+	if add, drop := strings.Index(errs.Error(), "`cap_add`"), strings.Index(errs.Error(), "`cap_drop`"); add > drop {
+		t.Fatalf("cap_add should be reported first: %s", errs)
+	}
+}
+
+func TestConfigPrepare_containerEnv(t *testing.T) {
+	raw := testConfig()
+	raw["container_env"] = map[string]string{"HTTP_PROXY": "http://proxy:3128"}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["container_env"] = map[string]string{"NOT VALID": "1"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "container_env")
+	raw["env_file"] = "/nope.env"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_resources(t *testing.T) {
+	raw := testConfig()
+	raw["cpus"] = "1.5"
+	raw["cpuset_cpus"] = "0-3,6"
+	raw["memory"] = "2g"
+	raw["memory_swap"] = "4g"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["memory_swap"] = "-1"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	for k, v := range map[string]string{
+		"cpus":        "none",
+		"cpuset_cpus": "0-",
+		"memory":      "-1",
+		"memory_swap": "1g",
+	} {
+		raw := testConfig()
+		raw["memory"] = "2g"
+		raw[k] = v
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigErr(t, warns, errs)
+	}
+
+	// memory_swap requires memory
+	raw = testConfig()
+	raw["memory_swap"] = "4g"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_shmSize(t *testing.T) {
+	raw := testConfig()
+	raw["shm_size"] = "1g"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	for _, size := range []string{"-1", "big"} {
+		raw["shm_size"] = size
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigErr(t, warns, errs)
+	}
+}
+
+func TestConfigPrepare_ulimits(t *testing.T) {
+	raw := testConfig()
+	raw["ulimits"] = map[string]string{
+		"nofile":  "1024:65536",
+		"memlock": "-1",
+	}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	for _, ulimits := range []map[string]string{
+		{"foo": "1"},
+		{"nofile": "many"},
+		{"nofile": "65536:1024"},
+		{"nofile": "-1:1024"},
+	} {
+		raw["ulimits"] = ulimits
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigErr(t, warns, errs)
+	}
+}
+
+func TestConfigPrepare_securityOpts(t *testing.T) {
+	profile, err := ioutil.TempFile("", "seccomp")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	profile.Close()
+	defer os.Remove(profile.Name())
+
+	raw := testConfig()
+	raw["security_opts"] = []string{
+		"seccomp=" + profile.Name(),
+		"apparmor=unconfined",
+		"label=type:container_runtime_t",
+		"no-new-privileges",
+	}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["security_opts"] = []string{"seccomp=/nope.json"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["security_opts"] = []string{"foo=bar"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_devices(t *testing.T) {
+	raw := testConfig()
+	raw["device"] = []string{"/dev/kvm", "/dev/fuse:/dev/fuse:rwm"}
+	raw["device_cgroup_rules"] = []string{"c 189:* rwm", "a *:* r"}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["device"] = []string{"/dev/kvm:/dev/kvm:rwx"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["device"] = []string{"/dev/kvm"}
+	raw["device_cgroup_rules"] = []string{"c 189 rwm"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["device_cgroup_rules"] = []string{"c 189:* rwm"}
+	raw["driver"] = "nerdctl"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_mounts(t *testing.T) {
+	raw := testConfig()
+	raw["mounts"] = []map[string]interface{}{
+		{"type": "volume", "source": "cache", "target": "/var/cache"},
+		{"type": "tmpfs", "target": "/tmp"},
+	}
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if len(c.Mounts) != 2 || c.Mounts[0].Source != "cache" {
+		t.Fatalf("bad mounts: %#v", c.Mounts)
+	}
+
+	raw["mounts"] = []map[string]interface{}{
+		{"type": "volume", "target": "/var/cache"},
+	}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	delete(raw, "mounts")
+	raw["volumes"] = map[string]string{"cache": "/var/cache"}
+	raw["driver"] = "buildah"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_network(t *testing.T) {
+	raw := testConfig()
+	raw["network_create"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["network"] = "packer"
+	raw["network_aliases"] = []string{"build"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["driver"] = "buildah"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_extraHosts(t *testing.T) {
+	raw := testConfig()
+	raw["extra_hosts"] = []string{
+		"registry.internal:10.0.0.5",
+		"host.docker.internal:host-gateway",
+		"v6.internal:fd00::1",
+	}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	for _, host := range []string{"registry.internal", ":10.0.0.5", "registry.internal:nope"} {
+		raw["extra_hosts"] = []string{host}
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigErr(t, warns, errs)
+	}
+}
+
+func TestConfigPrepare_dns(t *testing.T) {
+	raw := testConfig()
+	raw["dns"] = []string{"10.0.0.2", "fd00::53"}
+	raw["dns_search"] = []string{"corp.example.com"}
+	raw["dns_options"] = []string{"ndots:2"}
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["dns"] = []string{"dns.corp.example.com"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_rootless(t *testing.T) {
+	raw := testConfig()
+	raw["rootless"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["privileged"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
 }
 
-// Test variations of a build bootstrap config; including unset
 func TestConfigBuildBootstrapConfig(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -213,6 +1657,25 @@ func TestConfigBuildBootstrapConfig(t *testing.T) {
 			true,
 			true,
 		},
+		{
+			"error - platforms without buildx",
+			map[string]interface{}{
+				"path":      "./test-fixtures/sample_dockerfile",
+				"platforms": []string{"linux/amd64", "linux/arm64"},
+			},
+			false,
+			true,
+		},
+		{
+			"error - platforms without commit",
+			map[string]interface{}{
+				"path":      "./test-fixtures/sample_dockerfile",
+				"buildx":    true,
+				"platforms": []string{"linux/amd64", "linux/arm64"},
+			},
+			false,
+			true,
+		},
 		{
 			"success - with just build path",
 			map[string]interface{}{
@@ -257,3 +1720,41 @@ func TestConfigBuildBootstrapConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigBuildBootstrapConfig_platforms(t *testing.T) {
+	raw := map[string]interface{}{
+		"commit": true,
+		"build": map[string]interface{}{
+			"path":      "./test-fixtures/sample_dockerfile",
+			"buildx":    true,
+			"platforms": []string{"linux/amd64", "linux/arm64"},
+		},
+	}
+
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// Platforms and platform are mutually exclusive
+	raw["platform"] = "linux/amd64"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_windowsContainerRemoteDaemon(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	raw := testConfig()
+	raw["windows_container"] = true
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	raw["docker_host"] = "tcp://docker.example.com:2376"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Without a communicator, no file is copied
+	raw["communicator"] = "none"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sync"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// containerLogPrefix prefixes the lines of the output of the container shown
+// in the Packer output.
+const containerLogPrefix = "container: "
+
+// streamLogs shows the output of the container in the Packer output as the
+// container writes it, until it stops or the returned function is called.
+func streamLogs(driver Driver, ui packersdk.Ui, id string) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w := &logWriter{ui: ui, prefix: containerLogPrefix}
+		err := driver.FollowLogs(ctx, id, w)
+		w.Flush()
+		if err != nil {
+			log.Printf("Failed to stream the logs of the container: %s", err)
+			return
+		}
+		if ctx.Err() == nil {
+			ui.Message("The container stopped, its output ended")
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// logWriter shows each line written to it as a message of the ui, with the
+// prefix. It can be written to concurrently, like by the stdout and the
+// stderr of a command.
+type logWriter struct {
+	ui     packersdk.Ui
+	prefix string
+
+	mu   sync.Mutex
+	line []byte
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.line = append(w.line, b...)
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+		if i < 0 {
+			break
+		}
+		w.message(w.line[:i])
+		w.line = w.line[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush shows the last line written, if it isn't terminated.
+func (w *logWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.line) > 0 {
+		w.message(w.line)
+		w.line = nil
+	}
+}
+
+func (w *logWriter) message(line []byte) {
+	// The containers with a TTY end their lines with \r\n.
+	w.ui.Message(w.prefix + string(bytes.TrimRight(line, "\r")))
+}
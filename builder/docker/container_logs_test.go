@@ -0,0 +1,63 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestLogWriter(t *testing.T) {
+	out := new(bytes.Buffer)
+	w := &logWriter{
+		ui:     &packersdk.BasicUi{Reader: new(bytes.Buffer), Writer: out},
+		prefix: "container: ",
+	}
+
+	for _, s := range []string{"star", "ting\r\n", "exec: foo: not found\nlast"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if strings.Contains(out.String(), "last") {
+		t.Fatalf("the unterminated line shouldn't be shown before the flush: %q", out.String())
+	}
+	w.Flush()
+
+	expected := "container: starting\ncontainer: exec: foo: not found\ncontainer: last\n"
+	if out.String() != expected {
+		t.Fatalf("bad output: %q", out.String())
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	out := new(bytes.Buffer)
+	ui := &packersdk.BasicUi{Reader: new(bytes.Buffer), Writer: out}
+	driver := &MockDriver{FollowLogsResult: "exec /entrypoint.sh: no such file or directory\n"}
+
+	streamLogs(driver, ui, "foo")()
+
+	if driver.FollowLogsID != "foo" {
+		t.Fatalf("bad ID: %s", driver.FollowLogsID)
+	}
+	if !strings.Contains(out.String(), "container: exec /entrypoint.sh: no such file or directory") {
+		t.Fatalf("bad output: %q", out.String())
+	}
+}
+
+func TestStreamLogs_error(t *testing.T) {
+	out := new(bytes.Buffer)
+	ui := &packersdk.BasicUi{Reader: new(bytes.Buffer), Writer: out}
+	driver := &MockDriver{FollowLogsErr: errors.New("foo")}
+
+	streamLogs(driver, ui, "foo")()
+
+	if strings.Contains(out.String(), "stopped") {
+		t.Fatalf("a failure to stream the logs shouldn't be shown as a stopped container: %q", out.String())
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The label of the committed images with the hash of their inputs, with
+// `skip_unchanged`.
+const contentHashLabel = "io.packer.content-hash"
+
+// configHash returns the hash of the raw configuration of the builder,
+// without the options Packer sets for each run, like `packer_on_error`.
+func configHash(raws []interface{}) (string, error) {
+	h := sha256.New()
+	for _, raw := range raws {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("unexpected configuration of type %T", raw)
+		}
+
+		options := map[string]interface{}{}
+		for k, v := range m {
+			if !strings.HasPrefix(k, "packer_") {
+				options[k] = v
+			}
+		}
+		// The keys of the maps are sorted by encoding/json
+		b, err := json.Marshal(options)
+		if err != nil {
+			return "", err
+		}
+		h.Write(b)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contentHash returns the hash of the inputs of a build: the ID of the
+// source image, the platform, the hash of the configuration and the files
+// matched by the patterns, directories being hashed recursively.
+func contentHash(sourceImageID, platform, configHash string, patterns []string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "image %s\nplatform %s\nconfig %s\n", sourceImageID, platform, configHash)
+
+	files := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("no file matches %s", pattern)
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					files[filepath.ToSlash(path)] = true
+				}
+				return nil
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(h, "file %s\n", path)
+		if err := hashFile(h, path); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile writes the sha256 of the content of the file to h.
+func hashFile(h hash.Hash, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fh := sha256.New()
+	if _, err := io.Copy(fh, f); err != nil {
+		return err
+	}
+	fmt.Fprintf(h, "%x\n", fh.Sum(nil))
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigHash(t *testing.T) {
+	raws := []interface{}{map[string]interface{}{
+		"image":           "ubuntu",
+		"commit":          true,
+		"packer_on_error": "cleanup",
+	}}
+	hash, err := configHash(raws)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The options set by Packer for the run don't change the hash
+	raws[0].(map[string]interface{})["packer_on_error"] = "abort"
+	if other, _ := configHash(raws); other != hash {
+		t.Fatalf("the hash changed: %s, %s", hash, other)
+	}
+
+	raws[0].(map[string]interface{})["image"] = "debian"
+	if other, _ := configHash(raws); other == hash {
+		t.Fatal("the hash should change with the configuration")
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	write("scripts/setup.sh", "apt-get update")
+	write("files/app/config.yml", "port: 8080")
+
+	patterns := []string{filepath.Join(dir, "scripts", "*.sh"), filepath.Join(dir, "files")}
+	hash, err := contentHash("sha256:1234", "linux/amd64", "config", patterns)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if other, _ := contentHash("sha256:1234", "linux/amd64", "config", patterns); other != hash {
+		t.Fatalf("the hash isn't stable: %s, %s", hash, other)
+	}
+
+	for _, other := range []string{
+		mustContentHash(t, "sha256:5678", "linux/amd64", "config", patterns),
+		mustContentHash(t, "sha256:1234", "linux/arm64", "config", patterns),
+		mustContentHash(t, "sha256:1234", "linux/amd64", "other", patterns),
+	} {
+		if other == hash {
+			t.Error("the hash should change with the inputs")
+		}
+	}
+
+	// The files of the directories are hashed too
+	write("files/app/config.yml", "port: 9090")
+	if other := mustContentHash(t, "sha256:1234", "linux/amd64", "config", patterns); other == hash {
+		t.Error("the hash should change with the files")
+	}
+
+	if _, err := contentHash("sha256:1234", "", "config", []string{filepath.Join(dir, "missing", "*")}); err == nil {
+		t.Error("should error when a pattern matches no file")
+	}
+}
+
+func mustContentHash(t *testing.T, sourceImageID, platform, configHash string, patterns []string) string {
+	hash, err := contentHash(sourceImageID, platform, configHash, patterns)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return hash
+}
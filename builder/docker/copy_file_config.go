@@ -0,0 +1,51 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type CopyFileConfig
+
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// CopyFileConfig is a file or directory of the host that is copied into the
+// build container with `docker cp` before the provisioners run. Large files
+// are copied much faster than with the file provisioner, since they don't go
+// through the communicator.
+//
+// ```hcl
+//
+//	copy_files {
+//	  source      = "dist/app.tar.gz"
+//	  destination = "/opt/app.tar.gz"
+//	}
+//
+// ```
+type CopyFileConfig struct {
+	// The path of the file or directory on the host.
+	Source string `mapstructure:"source" required:"true"`
+	// The absolute path of the copy in the container, whose parent directory
+	// must exist. The content of a directory is copied to the destination
+	// directory, which is created if needed.
+	Destination string `mapstructure:"destination" required:"true"`
+}
+
+func (c *CopyFileConfig) Prepare() []error {
+	var errs []error
+
+	if c.Source == "" {
+		errs = append(errs, fmt.Errorf("copy of %q: `source` is required", c.Destination))
+	} else if _, err := os.Stat(c.Source); err != nil {
+		errs = append(errs, fmt.Errorf("copy of %q: bad `source`: %s", c.Destination, err))
+	}
+
+	if !path.IsAbs(c.Destination) {
+		errs = append(errs, fmt.Errorf("copy of %q: `destination` must be an absolute path, got %q", c.Source, c.Destination))
+	}
+
+	return errs
+}
@@ -0,0 +1,33 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFileConfig_Prepare(t *testing.T) {
+	dir := t.TempDir()
+
+	tc := []struct {
+		name string
+		file CopyFileConfig
+		err  bool
+	}{
+		{"directory", CopyFileConfig{Source: dir, Destination: "/opt/app"}, false},
+		{"missing source", CopyFileConfig{Source: filepath.Join(dir, "nope"), Destination: "/opt/app"}, true},
+		{"no source", CopyFileConfig{Destination: "/opt/app"}, true},
+		{"relative destination", CopyFileConfig{Source: dir, Destination: "opt/app"}, true},
+	}
+
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			errs := c.file.Prepare()
+			if c.err != (len(errs) > 0) {
+				t.Fatalf("bad errors: %v", errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// dockerContextNameRe matches the names the docker CLI accepts for contexts.
+var dockerContextNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.+-]+$`)
+
+// dockerContextMeta is the part of the metadata of a docker CLI context
+// describing its daemon, stored in contexts/meta/<sha256 of name>/meta.json
+// of the docker configuration directory.
+type dockerContextMeta struct {
+	Name      string `json:"Name"`
+	Endpoints struct {
+		Docker struct {
+			Host          string `json:"Host"`
+			SkipTLSVerify bool   `json:"SkipTLSVerify"`
+		} `json:"docker"`
+	} `json:"Endpoints"`
+}
+
+// dockerConfigDir returns the configuration directory of the docker CLI.
+func dockerConfigDir() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker"), nil
+}
+
+// ResolveContext returns the options with the `docker_context` replaced by
+// the address and TLS files of its daemon, read from the context store of
+// the docker CLI, for the clients that don't run the docker CLI. The
+// `default` context is the environment of the docker CLI, which the empty
+// options already stand for.
+func (c DockerHostConfig) ResolveContext() (DockerHostConfig, error) {
+	if c.DockerContext == "" {
+		return c, nil
+	}
+	name := c.DockerContext
+	c.DockerContext = ""
+	if name == "default" {
+		return c, nil
+	}
+
+	configDir, err := dockerConfigDir()
+	if err != nil {
+		return c, err
+	}
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+
+	raw, err := os.ReadFile(filepath.Join(configDir, "contexts", "meta", id, "meta.json"))
+	if os.IsNotExist(err) {
+		return c, fmt.Errorf("docker context %q not found in %s", name, configDir)
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to read docker context %q: %s", name, err)
+	}
+	var meta dockerContextMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return c, fmt.Errorf("failed to read docker context %q: %s", name, err)
+	}
+	if meta.Endpoints.Docker.Host == "" {
+		return c, fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+	c.DockerHost = meta.Endpoints.Docker.Host
+
+	tlsDir := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	if path := filepath.Join(tlsDir, "ca.pem"); fileExists(path) {
+		c.CACert = path
+	}
+	if path := filepath.Join(tlsDir, "cert.pem"); fileExists(path) {
+		c.ClientCert = path
+		c.ClientKey = filepath.Join(tlsDir, "key.pem")
+	}
+	c.TLSVerify = c.usesTLS() && !meta.Endpoints.Docker.SkipTLSVerify
+
+	return c, nil
+}
+
+// LinkContexts makes the docker contexts of the user available to the
+// docker commands run with the configuration directory configDir, so that
+// the `docker_context` is found in a temporary configuration directory.
+func (c *DockerHostConfig) LinkContexts(configDir string) error {
+	if c.DockerContext == "" || c.DockerContext == "default" {
+		return nil
+	}
+	userConfigDir, err := dockerConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := os.Symlink(filepath.Join(userConfigDir, "contexts"), filepath.Join(configDir, "contexts")); err != nil {
+		return fmt.Errorf("failed to link the docker contexts: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testDockerContext writes a docker context to the docker configuration
+// directory, the way `docker context create` does.
+func testDockerContext(t *testing.T, configDir, id, meta string, tlsFiles ...string) {
+	dir := filepath.Join(configDir, "contexts", "meta", id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meta.json"), []byte(meta), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tlsDir := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	if err := os.MkdirAll(tlsDir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, name := range tlsFiles {
+		if err := os.WriteFile(filepath.Join(tlsDir, name), nil, 0600); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+}
+
+func TestDockerHostConfig_ResolveContext(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+
+	// The SHA-256 of "colima" and "remote"
+	testDockerContext(t, configDir, "f24fd3749c1368328e2b149bec149cb6795619f244c5b584e844961215dadd16",
+		`{"Name": "colima", "Endpoints": {"docker": {"Host": "unix:///home/packer/.colima/default/docker.sock", "SkipTLSVerify": false}}}`)
+	testDockerContext(t, configDir, "b71199ebd070b36beab7317920c2c2f1d777df8d05e5527d8458fda57cb17a7a",
+		`{"Name": "remote", "Endpoints": {"docker": {"Host": "tcp://docker.example.com:2376", "SkipTLSVerify": false}}}`,
+		"ca.pem", "cert.pem", "key.pem")
+
+	c, err := DockerHostConfig{DockerContext: "colima"}.ResolveContext()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c != (DockerHostConfig{DockerHost: "unix:///home/packer/.colima/default/docker.sock"}) {
+		t.Fatalf("bad config: %#v", c)
+	}
+
+	c, err = DockerHostConfig{DockerContext: "remote"}.ResolveContext()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.DockerHost != "tcp://docker.example.com:2376" || !c.TLSVerify || filepath.Base(c.CACert) != "ca.pem" ||
+		filepath.Base(c.ClientCert) != "cert.pem" || filepath.Base(c.ClientKey) != "key.pem" {
+		t.Fatalf("bad config: %#v", c)
+	}
+
+	if c, err := (DockerHostConfig{DockerContext: "default"}).ResolveContext(); err != nil || !c.IsDefault() {
+		t.Fatalf("the default context should use the environment: %#v, %v", c, err)
+	}
+	if _, err := (DockerHostConfig{DockerContext: "missing"}).ResolveContext(); err == nil {
+		t.Fatal("should've failed on a missing context")
+	}
+}
+
+func TestDockerHostConfig_LinkContexts(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	tmpDir := t.TempDir()
+
+	c := DockerHostConfig{DockerContext: "colima"}
+	if err := c.LinkContexts(tmpDir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	target, err := os.Readlink(filepath.Join(tmpDir, "contexts"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if target != filepath.Join(configDir, "contexts") {
+		t.Fatalf("bad link: %s", target)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+
+package docker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DockerHostConfig selects the Docker daemon to talk to, and how to secure
+// the connection to it. When left empty, the DOCKER_HOST, DOCKER_TLS_VERIFY
+// and DOCKER_CERT_PATH environment variables are used, like with the docker
+// CLI.
+type DockerHostConfig struct {
+	// The address of the Docker daemon to connect to, for example
+	// `tcp://docker.example.com:2376`, or `ssh://user@docker.example.com`
+	// to run the build on a remote host over ssh. Defaults to the
+	// `DOCKER_HOST` environment variable, or to the local daemon if not set.
+	DockerHost string `mapstructure:"docker_host" required:"false"`
+	// The name of the docker CLI context to connect to, like `colima`,
+	// `desktop-linux` or a context created with `docker context create`
+	// for a remote daemon. It is passed with `--context` to the docker
+	// commands of the build only, so the default context of the user is
+	// left as is. Can't be set together with `docker_host` and the TLS
+	// options, which the context holds already.
+	DockerContext string `mapstructure:"docker_context" required:"false"`
+	// Use TLS and verify the certificate of the Docker daemon. Defaults to
+	// false.
+	TLSVerify bool `mapstructure:"tls_verify" required:"false"`
+	// The directory containing the `ca.pem`, `cert.pem` and `key.pem` files
+	// used to connect to the Docker daemon, like `DOCKER_CERT_PATH`.
+	CertPath string `mapstructure:"cert_path" required:"false"`
+	// The path to the certificate authority used to verify the certificate
+	// of the Docker daemon. Overrides the `ca.pem` file of `cert_path`.
+	CACert string `mapstructure:"ca_cert" required:"false"`
+	// The path to the client certificate used to authenticate to the Docker
+	// daemon. Overrides the `cert.pem` file of `cert_path`.
+	ClientCert string `mapstructure:"client_cert" required:"false"`
+	// The path to the key of the client certificate. Overrides the `key.pem`
+	// file of `cert_path`.
+	ClientKey string `mapstructure:"client_key" required:"false"`
+	// The private key used to authenticate to an `ssh://` `docker_host`.
+	// Defaults to the keys of the ssh agent, and to the ssh configuration
+	// of the user.
+	DockerHostSSHPrivateKeyFile string `mapstructure:"docker_host_ssh_private_key_file" required:"false"`
+	// The socket of the ssh agent used to authenticate to an `ssh://`
+	// `docker_host`. Defaults to the `SSH_AUTH_SOCK` environment variable.
+	DockerHostSSHAgentSocket string `mapstructure:"docker_host_ssh_agent_socket" required:"false"`
+}
+
+// IsDefault returns true if no option was set, in which case the docker
+// CLI environment applies.
+func (c *DockerHostConfig) IsDefault() bool {
+	return *c == DockerHostConfig{}
+}
+
+// Prepare validates the options for the given driver. Only the cli and api
+// drivers can talk to another daemon.
+func (c *DockerHostConfig) Prepare(driverType string) []error {
+	var errs []error
+
+	switch driverType {
+	case "", DriverCLI, DriverAPI:
+	default:
+		if !c.IsDefault() {
+			errs = append(errs, fmt.Errorf("`docker_host`, `docker_context` and the TLS options are not supported by the %s driver", driverType))
+		}
+	}
+
+	if c.DockerHost != "" {
+		u, err := url.Parse(c.DockerHost)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("`docker_host` is invalid: %s", err))
+		case u.Scheme != "unix" && u.Scheme != "tcp" && u.Scheme != "ssh" && u.Scheme != "npipe":
+			errs = append(errs, fmt.Errorf("`docker_host` scheme %q is not supported, expected one of unix, tcp, ssh or npipe", u.Scheme))
+		}
+	}
+
+	if c.DockerContext != "" {
+		if !dockerContextNameRe.MatchString(c.DockerContext) {
+			errs = append(errs, fmt.Errorf("`docker_context` %q is not a valid context name", c.DockerContext))
+		}
+		if c.DockerHost != "" || c.usesTLS() || c.ClientKey != "" {
+			errs = append(errs, errors.New("`docker_context` can't be set together with `docker_host` or the TLS options"))
+		}
+	}
+
+	if (c.DockerHostSSHPrivateKeyFile != "" || c.DockerHostSSHAgentSocket != "") && !c.isSSH() {
+		errs = append(errs, errors.New("the `docker_host_ssh_*` options require an ssh:// `docker_host`"))
+	}
+	if strings.HasPrefix(c.DockerHostSSHPrivateKeyFile, "~/") {
+		homedir, _ := os.UserHomeDir()
+		c.DockerHostSSHPrivateKeyFile = filepath.Join(homedir, c.DockerHostSSHPrivateKeyFile[2:])
+	}
+	if c.DockerHostSSHPrivateKeyFile != "" {
+		if _, err := os.Stat(c.DockerHostSSHPrivateKeyFile); err != nil {
+			errs = append(errs, fmt.Errorf("`docker_host_ssh_private_key_file` %q: %s", c.DockerHostSSHPrivateKeyFile, err))
+		}
+	}
+
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		errs = append(errs, errors.New("`client_cert` and `client_key` must be set together"))
+	}
+
+	if c.CertPath != "" {
+		if fi, err := os.Stat(c.CertPath); err != nil || !fi.IsDir() {
+			errs = append(errs, fmt.Errorf("`cert_path` %q is not a directory", c.CertPath))
+		}
+	}
+
+	for name, path := range map[string]string{
+		"ca_cert":     c.CACert,
+		"client_cert": c.ClientCert,
+		"client_key":  c.ClientKey,
+	} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("`%s` %q: %s", name, path, err))
+		}
+	}
+
+	return errs
+}
+
+// endpoint returns the address of the daemon the options select, with the
+// environment and the docker context resolved, or an empty string for the
+// local daemon. A context that can't be resolved is returned by name.
+func (c DockerHostConfig) endpoint() string {
+	if c.DockerHost == "" && c.DockerContext == "" {
+		c.DockerHost = os.Getenv("DOCKER_HOST")
+		if c.DockerHost == "" {
+			c.DockerContext = os.Getenv("DOCKER_CONTEXT")
+		}
+	}
+	resolved, err := c.ResolveContext()
+	if err != nil {
+		return "context:" + c.DockerContext
+	}
+	return resolved.DockerHost
+}
+
+// usesTLS returns true if the connection to the daemon uses TLS.
+func (c *DockerHostConfig) usesTLS() bool {
+	return c.TLSVerify || c.CertPath != "" || c.CACert != "" || c.ClientCert != ""
+}
+
+// Apply sets the daemon address, context and TLS options on a docker CLI
+// command.
+//
+// The address and certificate directory are set through the environment,
+// so they only apply to this command, and the context and the individual
+// certificate files are set with the matching global options.
+func (c *DockerHostConfig) Apply(cmd *exec.Cmd) {
+	if c.IsDefault() {
+		return
+	}
+
+	var env []string
+	if c.DockerHost != "" {
+		env = append(env, "DOCKER_HOST="+c.DockerHost)
+	}
+	if c.CertPath != "" {
+		env = append(env, "DOCKER_CERT_PATH="+c.CertPath)
+	}
+	if c.DockerHostSSHAgentSocket != "" {
+		// The docker CLI connects to ssh hosts with the ssh binary, which
+		// authenticates with the agent.
+		env = append(env, "SSH_AUTH_SOCK="+c.DockerHostSSHAgentSocket)
+	}
+	if len(env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
+
+	var globals []string
+	if c.DockerContext != "" {
+		globals = append(globals, "--context", c.DockerContext)
+	}
+	if c.TLSVerify {
+		globals = append(globals, "--tlsverify")
+	} else if c.usesTLS() {
+		globals = append(globals, "--tls")
+	}
+	if c.CACert != "" {
+		globals = append(globals, "--tlscacert", c.CACert)
+	}
+	if c.ClientCert != "" {
+		globals = append(globals, "--tlscert", c.ClientCert, "--tlskey", c.ClientKey)
+	}
+
+	// Global options have to come before the docker subcommand.
+	args := append([]string{cmd.Args[0]}, globals...)
+	cmd.Args = append(args, cmd.Args[1:]...)
+}
+
+// TLSConfig returns the TLS configuration to connect to the daemon with, or
+// nil if the connection is not secured with TLS.
+func (c *DockerHostConfig) TLSConfig() (*tls.Config, error) {
+	if !c.usesTLS() {
+		return nil, nil
+	}
+
+	caCert, clientCert, clientKey := c.CACert, c.ClientCert, c.ClientKey
+	if c.CertPath != "" {
+		if path := filepath.Join(c.CertPath, "ca.pem"); caCert == "" && fileExists(path) {
+			caCert = path
+		}
+		if path := filepath.Join(c.CertPath, "cert.pem"); clientCert == "" && fileExists(path) {
+			clientCert = path
+			clientKey = filepath.Join(c.CertPath, "key.pem")
+		}
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: !c.TLSVerify,
+	}
+
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA certificate: %s", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caCert)
+		}
+	}
+
+	if clientCert != "" {
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
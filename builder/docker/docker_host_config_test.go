@@ -0,0 +1,95 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestDockerHostConfig_Apply(t *testing.T) {
+	c := DockerHostConfig{
+		DockerHost: "tcp://docker.example.com:2376",
+		TLSVerify:  true,
+		CertPath:   "/certs",
+		CACert:     "/certs/other-ca.pem",
+	}
+
+	cmd := exec.Command("docker", "ps", "-a")
+	c.Apply(cmd)
+
+	expected := []string{"docker", "--tlsverify", "--tlscacert", "/certs/other-ca.pem", "ps", "-a"}
+	if !reflect.DeepEqual(cmd.Args, expected) {
+		t.Fatalf("bad args: %v", cmd.Args)
+	}
+
+	env := cmd.Env[len(cmd.Env)-2:]
+	if !reflect.DeepEqual(env, []string{"DOCKER_HOST=tcp://docker.example.com:2376", "DOCKER_CERT_PATH=/certs"}) {
+		t.Fatalf("bad env: %v", env)
+	}
+}
+
+func TestDockerHostConfig_Apply_default(t *testing.T) {
+	var c DockerHostConfig
+
+	cmd := exec.Command("docker", "ps")
+	c.Apply(cmd)
+
+	if !reflect.DeepEqual(cmd.Args, []string{"docker", "ps"}) {
+		t.Fatalf("bad args: %v", cmd.Args)
+	}
+	if cmd.Env != nil {
+		t.Fatalf("environment should be inherited: %v", cmd.Env)
+	}
+}
+
+func TestDockerHostConfig_Prepare(t *testing.T) {
+	c := DockerHostConfig{ClientCert: "cert.pem"}
+	if errs := c.Prepare(DriverCLI); len(errs) == 0 {
+		t.Fatal("client_cert without client_key should fail")
+	}
+
+	c = DockerHostConfig{DockerHost: "tcp://docker.example.com:2375"}
+	if errs := c.Prepare(DriverCLI); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if errs := c.Prepare(DriverPodman); len(errs) == 0 {
+		t.Fatal("docker_host should not be supported by the podman driver")
+	}
+}
+
+func TestDockerHostConfig_Apply_context(t *testing.T) {
+	c := DockerHostConfig{DockerContext: "colima"}
+
+	cmd := exec.Command("docker", "ps")
+	c.Apply(cmd)
+
+	if !reflect.DeepEqual(cmd.Args, []string{"docker", "--context", "colima", "ps"}) {
+		t.Fatalf("bad args: %v", cmd.Args)
+	}
+	if cmd.Env != nil {
+		t.Fatalf("environment should be inherited: %v", cmd.Env)
+	}
+}
+
+func TestDockerHostConfig_Prepare_context(t *testing.T) {
+	c := DockerHostConfig{DockerContext: "desktop-linux"}
+	if errs := c.Prepare(DriverAPI); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if errs := c.Prepare(DriverNerdctl); len(errs) == 0 {
+		t.Fatal("docker_context should not be supported by the nerdctl driver")
+	}
+
+	for _, c := range []DockerHostConfig{
+		{DockerContext: "-remote"},
+		{DockerContext: "remote", DockerHost: "tcp://docker.example.com:2375"},
+		{DockerContext: "remote", TLSVerify: true},
+	} {
+		if errs := c.Prepare(DriverCLI); len(errs) == 0 {
+			t.Fatalf("%#v should be invalid", c)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// The socket of the Docker daemon on ssh hosts, if the `docker_host` has no
+// path.
+const defaultSSHDockerSocket = "/var/run/docker.sock"
+
+// isSSH returns true if the daemon is reached over ssh.
+func (c *DockerHostConfig) isSSH() bool {
+	return strings.HasPrefix(c.DockerHost, "ssh://")
+}
+
+// StartSSHAgent serves the `docker_host_ssh_private_key_file` with an ssh
+// agent for the duration of the build, since the docker CLI authenticates to
+// ssh hosts through the ssh binary, which can't be given a key otherwise.
+//
+// It returns a copy of the options with the socket of the agent, to connect
+// to the daemon with instead of the options, which are left as is. The
+// returned function stops the agent, and must be called once the commands
+// ran.
+func (c DockerHostConfig) StartSSHAgent() (DockerHostConfig, func(), error) {
+	if c.DockerHostSSHPrivateKeyFile == "" || !c.isSSH() {
+		return c, func() {}, nil
+	}
+
+	key, err := readSSHPrivateKey(c.DockerHostSSHPrivateKeyFile)
+	if err != nil {
+		return c, nil, err
+	}
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		return c, nil, fmt.Errorf("failed to add %s to the ssh agent: %s", c.DockerHostSSHPrivateKeyFile, err)
+	}
+
+	dir, err := os.MkdirTemp("", "packer-ssh-agent")
+	if err != nil {
+		return c, nil, fmt.Errorf("failed to create the ssh agent directory: %s", err)
+	}
+
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		return c, nil, fmt.Errorf("failed to start the ssh agent: %s", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := agent.ServeAgent(keyring, conn); err != nil && !errors.Is(err, io.EOF) {
+					log.Printf("[DEBUG] ssh agent: %s", err)
+				}
+			}()
+		}
+	}()
+
+	log.Printf("Serving %s with an ssh agent on %s", c.DockerHostSSHPrivateKeyFile, socket)
+	c.DockerHostSSHAgentSocket = socket
+
+	return c, func() {
+		listener.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// stateDockerHost returns the options of the daemon of the build, with the
+// socket of the ssh agent started for it, or else the ones of the config.
+func stateDockerHost(state multistep.StateBag) DockerHostConfig {
+	if host, ok := state.GetOk("docker_host"); ok {
+		return host.(DockerHostConfig)
+	}
+	return state.Get("config").(*Config).DockerHostConfig
+}
+
+func readSSHPrivateKey(path string) (interface{}, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the ssh private key: %s", err)
+	}
+
+	key, err := ssh.ParseRawPrivateKey(pem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the ssh private key %s: %s", path, err)
+	}
+
+	return key, nil
+}
+
+// sshDialer connects to the ssh host, and returns a function that dials
+// the socket of the Docker daemon through that connection.
+func sshDialer(host *url.URL, privateKeyFile, agentSocket string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	var signers []ssh.Signer
+
+	if privateKeyFile != "" {
+		key, err := readSSHPrivateKey(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to use the ssh private key: %s", err)
+		}
+		signers = append(signers, signer)
+	}
+
+	if agentSocket == "" {
+		agentSocket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if agentSocket != "" {
+		conn, err := net.Dial("unix", agentSocket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to the ssh agent: %s", err)
+		}
+		agentSigners, err := agent.NewClient(conn).Signers()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the keys of the ssh agent: %s", err)
+		}
+		signers = append(signers, agentSigners...)
+	}
+
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no ssh key to authenticate to %s, set `docker_host_ssh_private_key_file` or start an ssh agent", host.Host)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the known ssh hosts: %s", err)
+	}
+
+	user := host.User.Username()
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+
+	addr := host.Host
+	if host.Port() == "" {
+		addr = net.JoinHostPort(host.Hostname(), "22")
+	}
+
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s with ssh: %s", addr, err)
+	}
+
+	socket := host.Path
+	if socket == "" || socket == "/" {
+		socket = defaultSSHDockerSocket
+	}
+
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return client.Dial("unix", socket)
+	}, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestDockerHostConfig_StartSSHAgent(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	c := DockerHostConfig{
+		DockerHost:                  "ssh://packer@docker.example.com",
+		DockerHostSSHPrivateKeyFile: keyFile,
+	}
+	if errs := c.Prepare(DriverCLI); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	host, stop, err := c.StartSSHAgent()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer stop()
+	if c.DockerHostSSHAgentSocket != "" {
+		t.Fatal("the options shouldn't point to the socket of the agent, which is removed once stopped")
+	}
+
+	conn, err := net.Dial("unix", host.DockerHostSSHAgentSocket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer conn.Close()
+
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("expected the agent to serve one key, got %d", len(keys))
+	}
+}
+
+func TestDockerHostConfig_Prepare_ssh(t *testing.T) {
+	c := DockerHostConfig{
+		DockerHost:               "tcp://docker.example.com:2376",
+		DockerHostSSHAgentSocket: "/tmp/agent.sock",
+	}
+	if errs := c.Prepare(DriverCLI); len(errs) == 0 {
+		t.Fatal("ssh options should require an ssh docker_host")
+	}
+
+	c = DockerHostConfig{DockerHost: "ftp://docker.example.com"}
+	if errs := c.Prepare(DriverCLI); len(errs) == 0 {
+		t.Fatal("unsupported docker_host schemes should fail")
+	}
+}
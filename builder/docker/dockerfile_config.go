@@ -51,6 +51,30 @@ type DockerfileBootstrapConfig struct {
 	// can take a significant amount of time, while once compressed, this
 	// can make builds faster, at the price of extra CPU resources.
 	Compress bool `mapstructure:"compress"`
+	// The build stage to target in a multi-stage Dockerfile.
+	//
+	// This maps to the `--target` option of `docker build`, and lets you
+	// reuse an existing multi-stage Dockerfile by only building the stage
+	// you want Packer to provision on top of.
+	Target string `mapstructure:"target"`
+	// Use `docker buildx build` instead of `docker build` to build the base
+	// image.
+	//
+	// This requires the buildx plugin to be installed alongside the docker
+	// CLI. The resulting image is loaded into the local image store so the
+	// rest of the build can use it.
+	Buildx bool `mapstructure:"buildx"`
+	// A list of platforms to build the image for, for example
+	// `["linux/amd64", "linux/arm64"]`.
+	//
+	// When set, the whole build (bootstrap, provisioning and commit) runs
+	// once for each platform, and the resulting artifact keeps track of the
+	// image produced for each of them. The docker-tag post-processor will
+	// then create one tag per platform, so docker-push can push all of them.
+	//
+	// Note: requires `buildx` to be true and `commit` to be set, and is
+	// mutually exclusive with the builder's `platform` option.
+	Platforms []string `mapstructure:"platforms"`
 }
 
 func (c *DockerfileBootstrapConfig) Prepare() ([]string, error) {
@@ -92,6 +116,10 @@ func (c *DockerfileBootstrapConfig) Prepare() ([]string, error) {
 		return nil, fmt.Errorf("specified build_dir %q is not a directory", c.BuildDir)
 	}
 
+	if len(c.Platforms) > 0 && !c.Buildx {
+		return nil, fmt.Errorf("`platforms` requires `buildx` to be enabled")
+	}
+
 	return nil, nil
 }
 
@@ -111,6 +139,10 @@ func (c DockerfileBootstrapConfig) BuildArgs() []string {
 		retArgs = append(retArgs, "--compress")
 	}
 
+	if c.Target != "" {
+		retArgs = append(retArgs, "--target", c.Target)
+	}
+
 	// Loops through map of build arguments to add to build command
 	for key, value := range c.Arguments {
 		arg := key + "=" + value
@@ -122,12 +154,14 @@ func (c DockerfileBootstrapConfig) BuildArgs() []string {
 
 // IsDefault returns whether the DockerfileBootstrapConfig is the empty one or not
 func (c DockerfileBootstrapConfig) IsDefault() bool {
-	// We can ignore arguments for the comparison since a map can be either
-	// nil or empty, and should be considered equal in either case.
+	// We can ignore arguments and platforms for the comparison since a map
+	// or slice can be either nil or empty, and should be considered equal
+	// in either case.
 	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreFields(DockerfileBootstrapConfig{}, "Arguments"),
+		cmpopts.IgnoreFields(DockerfileBootstrapConfig{}, "Arguments", "Platforms"),
 	}
 
 	return cmp.Equal(c, DockerfileBootstrapConfig{}, cmpOpts...) &&
-		(c.Arguments == nil || len(c.Arguments) == 0)
+		len(c.Arguments) == 0 &&
+		len(c.Platforms) == 0
 }
@@ -15,6 +15,9 @@ type FlatDockerfileBootstrapConfig struct {
 	Arguments      map[string]string `mapstructure:"arguments" required:"false" cty:"arguments" hcl:"arguments"`
 	Pull           *bool             `mapstructure:"pull" cty:"pull" hcl:"pull"`
 	Compress       *bool             `mapstructure:"compress" cty:"compress" hcl:"compress"`
+	Target         *string           `mapstructure:"target" cty:"target" hcl:"target"`
+	Buildx         *bool             `mapstructure:"buildx" cty:"buildx" hcl:"buildx"`
+	Platforms      []string          `mapstructure:"platforms" cty:"platforms" hcl:"platforms"`
 }
 
 // FlatMapstructure returns a new FlatDockerfileBootstrapConfig.
@@ -34,6 +37,9 @@ func (*FlatDockerfileBootstrapConfig) HCL2Spec() map[string]hcldec.Spec {
 		"arguments": &hcldec.AttrSpec{Name: "arguments", Type: cty.Map(cty.String), Required: false},
 		"pull":      &hcldec.AttrSpec{Name: "pull", Type: cty.Bool, Required: false},
 		"compress":  &hcldec.AttrSpec{Name: "compress", Type: cty.Bool, Required: false},
+		"target":    &hcldec.AttrSpec{Name: "target", Type: cty.String, Required: false},
+		"buildx":    &hcldec.AttrSpec{Name: "buildx", Type: cty.Bool, Required: false},
+		"platforms": &hcldec.AttrSpec{Name: "platforms", Type: cty.List(cty.String), Required: false},
 	}
 	return s
 }
@@ -4,6 +4,7 @@
 package docker
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
@@ -60,3 +61,48 @@ func TestBuildConfigIsDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildConfigBuildArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		inputConfig  DockerfileBootstrapConfig
+		expectedArgs []string
+	}{
+		{
+			"minimal config - pulls by default",
+			DockerfileBootstrapConfig{
+				DockerfilePath: "Dockerfile",
+				BuildDir:       ".",
+			},
+			[]string{"-f", "Dockerfile", "--pull", "."},
+		},
+		{
+			"pull disabled",
+			DockerfileBootstrapConfig{
+				DockerfilePath: "Dockerfile",
+				BuildDir:       ".",
+				Pull:           config.TriFalse,
+			},
+			[]string{"-f", "Dockerfile", "."},
+		},
+		{
+			"target stage set",
+			DockerfileBootstrapConfig{
+				DockerfilePath: "Dockerfile",
+				BuildDir:       "ctx",
+				Pull:           config.TriFalse,
+				Target:         "builder",
+			},
+			[]string{"-f", "Dockerfile", "--target", "builder", "ctx"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.inputConfig.BuildArgs()
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("expected args %v, got %v", tt.expectedArgs, args)
+			}
+		})
+	}
+}
@@ -4,11 +4,123 @@
 package docker
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"os"
+	"time"
 
 	"github.com/hashicorp/go-version"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 )
 
+// The drivers that can be selected with the `driver` option.
+const (
+	// Runs commands with the docker CLI
+	DriverCLI = "cli"
+	// Talks directly to the Docker Engine API
+	DriverAPI = "api"
+	// Runs commands with the podman CLI
+	DriverPodman = "podman"
+	// Runs commands with nerdctl, against containerd
+	DriverNerdctl = "nerdctl"
+	// Runs commands with buildah, without a daemon
+	DriverBuildah = "buildah"
+)
+
+// DefaultExecutable returns the binary to run commands with for the given
+// driver, if none was configured.
+func DefaultExecutable(driverType string) string {
+	switch driverType {
+	case DriverPodman:
+		return "podman"
+	case DriverNerdctl:
+		return "nerdctl"
+	case DriverBuildah:
+		return "buildah"
+	}
+	return "docker"
+}
+
+// ValidateDriverType returns an error if driverType is not one of the known
+// drivers. An empty driverType selects the default driver.
+func ValidateDriverType(driverType string) error {
+	switch driverType {
+	case "", DriverCLI, DriverAPI, DriverPodman, DriverNerdctl, DriverBuildah:
+		return nil
+	}
+
+	return fmt.Errorf("unknown driver %q, expected one of %s, %s, %s, %s or %s",
+		driverType, DriverCLI, DriverAPI, DriverPodman, DriverNerdctl, DriverBuildah)
+}
+
+// NewDriver returns the Driver selected by driverType. The executable and
+// configDir are only used by the drivers that run commands through a CLI,
+// host only by the cli and api drivers, registries only by the podman,
+// buildah and nerdctl drivers, and proxy by all but the api driver.
+func NewDriver(driverType, executable, configDir string, host DockerHostConfig, registries RegistryTLSConfig, proxy ProxyEnvConfig, ctx *interpolate.Context, ui packersdk.Ui) (Driver, error) {
+	if err := ValidateDriverType(driverType); err != nil {
+		return nil, err
+	}
+
+	switch driverType {
+	case DriverAPI:
+		host, err := host.ResolveContext()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig, err := host.TLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		return &DockerAPIDriver{
+			Host:              host.DockerHost,
+			TLS:               tlsConfig,
+			SSHPrivateKeyFile: host.DockerHostSSHPrivateKeyFile,
+			SSHAgentSocket:    host.DockerHostSSHAgentSocket,
+			Ctx:               ctx,
+			Ui:                ui,
+		}, nil
+	case DriverPodman:
+		return &PodmanDriver{DockerDriver{
+			Executable: executable,
+			ConfigDir:  configDir,
+			Registries: registries,
+			Proxy:      proxy,
+			Ctx:        ctx,
+			Ui:         ui,
+		}}, nil
+	case DriverNerdctl:
+		return &NerdctlDriver{DockerDriver: DockerDriver{
+			Executable: executable,
+			ConfigDir:  configDir,
+			Registries: registries,
+			Proxy:      proxy,
+			Ctx:        ctx,
+			Ui:         ui,
+		}}, nil
+	case DriverBuildah:
+		return &BuildahDriver{PodmanDriver{DockerDriver{
+			Executable: executable,
+			ConfigDir:  configDir,
+			Registries: registries,
+			Proxy:      proxy,
+			Ctx:        ctx,
+			Ui:         ui,
+		}}}, nil
+	default:
+		return &DockerDriver{
+			Executable: executable,
+			ConfigDir:  configDir,
+			HostConfig: host,
+			Proxy:      proxy,
+			Ctx:        ctx,
+			Ui:         ui,
+		}, nil
+	}
+}
+
 // Driver is the interface that has to be implemented to communicate with
 // Docker. The Driver interface also allows the steps to be tested since
 // a mock driver can be shimmed in.
@@ -19,15 +131,48 @@ type Driver interface {
 	// `DockerfileBootstrapConfig.BuildArgs` function.
 	Build(args []string) (string, error)
 
+	// BuildX runs `docker buildx build` on a Dockerfile, and loads the
+	// resulting image in the local image store.
+	//
+	// args is the same as for Build.
+	BuildX(args []string) (string, error)
+
+	// Cmd returns the default command of the image, as a JSON array.
+	// If the image has no default command, this returns `[""]`.
+	Cmd(id string) (string, error)
+
 	// Commit the container to a tag
 	Commit(id string, author string, changes []string, message string) (string, error)
 
+	// CopyToContainer copies the file or directory src of the host to dst in
+	// the container. The content of a directory is copied to dst.
+	CopyToContainer(id string, src string, dst string) error
+
 	// Delete an image that is imported into Docker
 	DeleteImage(id string) error
 
+	// Diff returns the paths the container added or changed in its
+	// filesystem, compared to its image.
+	Diff(id string) ([]string, error)
+
+	// Entrypoint returns the entrypoint of the image, as a JSON array.
+	// If the image has no entrypoint, this returns `[""]`.
+	Entrypoint(id string) (string, error)
+
 	// Export exports the container with the given ID to the given writer.
 	Export(id string, dst io.Writer) error
 
+	// ImageConfig returns the configuration of the image with the given ID.
+	ImageConfig(id string) (*ImageConfig, error)
+
+	// ImageMetadata returns the size, creation time and layers of the image
+	// with the given ID.
+	ImageMetadata(id string) (*ImageMetadata, error)
+
+	// ImagePlatform returns the platform of the image with the given ID, as
+	// `os/arch[/variant]`.
+	ImagePlatform(id string) (string, error)
+
 	// Import imports a container from a tar file
 	Import(path string, changes []string, repo string, platform string) (string, error)
 
@@ -35,12 +180,37 @@ type Driver interface {
 	// for external access.
 	IPAddress(id string) (string, error)
 
+	// PublishedPort returns the port of the host the TCP port of the
+	// container is published on.
+	PublishedPort(id string, port int) (int, error)
+
+	// OOMKilled returns true if a process of the container was killed
+	// because the container ran out of memory.
+	OOMKilled(id string) (bool, error)
+
+	// Logs returns the logs the container wrote so far, its stdout and
+	// stderr.
+	Logs(id string) (string, error)
+
+	// FollowLogs writes the logs of the container to dst as the container
+	// writes them, until it stops or the context is done.
+	FollowLogs(ctx context.Context, id string, dst io.Writer) error
+
+	// HealthStatus returns the health status of the container, like
+	// `starting`, `healthy` or `unhealthy`, or an empty string if its image
+	// has no healthcheck.
+	HealthStatus(id string) (string, error)
+
 	// Sha256 returns the sha256 id of the image
 	Sha256(id string) (string, error)
 
 	// Retrieve the repo digest of the image.
 	Digest(id string) (string, error)
 
+	// RepoDigests returns all the repo digests of the image, as
+	// `name@sha256:...` references.
+	RepoDigests(id string) ([]string, error)
+
 	// Login. This will lock the driver from performing another Login
 	// until Logout is called. Therefore, any users MUST call Logout.
 	Login(repo, username, password string) error
@@ -48,15 +218,74 @@ type Driver interface {
 	// Logout. This can only be called if Login succeeded.
 	Logout(repo string) error
 
+	// NetworkExists returns true if a network with the given name exists.
+	NetworkExists(name string) (bool, error)
+
+	// CreateNetwork creates a bridge network with the given name.
+	CreateNetwork(name string) error
+
+	// RemoveNetwork removes the network with the given name.
+	RemoveNetwork(name string) error
+
+	// VolumeExists returns true if a volume with the given name exists.
+	VolumeExists(name string) (bool, error)
+
+	// CreateVolume creates a named volume.
+	CreateVolume(name string) error
+
+	// RemoveVolume removes the named volume.
+	RemoveVolume(name string) error
+
+	// ImageExists returns true if the image is present locally.
+	ImageExists(image string) (bool, error)
+
+	// RemoteImageExists returns true if the image, with its tag, is in its
+	// registry. Only the manifest is fetched, the image isn't pulled.
+	RemoteImageExists(name string) (bool, error)
+
+	// RemoteDigest returns the digest of the manifest of the image, with its
+	// tag, in its registry, or an empty string if the registry doesn't have
+	// it.
+	RemoteDigest(name string) (string, error)
+
+	// ImagesWithLabel returns the IDs of the local images with the label
+	// set to the given value, from the most recent.
+	ImagesWithLabel(label, value string) ([]string, error)
+
 	// Pull should pull down the given image.
 	Pull(image string, platform string) error
 
 	// Push pushes an image to a Docker index/registry.
 	Push(name string, platform string) error
 
+	// Rootless reports whether the daemon runs without root privileges.
+	Rootless() (bool, error)
+
+	// RunPrivileged runs a privileged container of the image with the
+	// arguments, waits for it to exit, removes it and returns its output.
+	// It runs the tools that set the host of the daemon up, like the binfmt
+	// installer.
+	RunPrivileged(image string, args []string) (string, error)
+
+	// Runtimes returns the names of the container runtimes configured on the
+	// daemon, or nil if the driver can't list them.
+	Runtimes() ([]string, error)
+
 	// Save an image with the given ID to the given writer.
 	SaveImage(id string, dst io.Writer) error
 
+	// SaveOCIImage saves the image with the given ID to the given writer, as
+	// an OCI image layout in a tar file.
+	SaveOCIImage(id string, dst io.Writer) error
+
+	// ServerPlatform returns the platform of the host of the daemon, as
+	// `os/arch`, like `linux/amd64`.
+	ServerPlatform() (string, error)
+
+	// SetContext sets the context the driver runs its commands with, which
+	// stops them once it is done. A nil context never stops them.
+	SetContext(ctx context.Context)
+
 	// StartContainer starts a container and returns the ID for that container,
 	// along with a potential error.
 	StartContainer(*ContainerConfig) (string, error)
@@ -64,8 +293,25 @@ type Driver interface {
 	// KillContainer forcibly stops a container.
 	KillContainer(id string) error
 
-	// StopContainer gently stops a container.
-	StopContainer(id string) error
+	// StopContainer gently stops a container: the signal is sent to its
+	// process, which is killed if it's still running after the timeout. An
+	// empty signal and a zero timeout are the defaults of the container.
+	StopContainer(id string, signal string, timeout time.Duration) error
+
+	// CheckpointContainer checkpoints the processes of the running container
+	// with CRIU, to the checkpoint with the given name in dir, and leaves
+	// them running.
+	CheckpointContainer(id string, name string, dir string) error
+
+	// RestoreContainer starts the stopped container from the checkpoint
+	// with the given name in dir.
+	RestoreContainer(id string, name string, dir string) error
+
+	// PauseContainer suspends all the processes of a container.
+	PauseContainer(id string) error
+
+	// UnpauseContainer resumes the processes of a paused container.
+	UnpauseContainer(id string) error
 
 	// TagImage tags the image with the given ID
 	TagImage(id string, repo string, force bool) error
@@ -79,19 +325,143 @@ type Driver interface {
 
 // ContainerConfig is the configuration used to start a container.
 type ContainerConfig struct {
+	Name       string
 	Image      string
 	RunCommand []string
+	Entrypoint []string
+	Cmd        []string
 	Device     []string
+	Gpus       string
 	CapAdd     []string
 	CapDrop    []string
 	Volumes    map[string]string
 	TmpFs      []string
+	Mounts     []MountConfig
+	ReadOnly   bool
 	Privileged bool
+	Init       bool
 	Runtime    string
 	Platform   string
+	Publish    []string
+	Env        map[string]string
+	Userns     string
+	IpcMode    string
+	PidMode    string
+	UIDMap     []string
+	GIDMap     []string
+
+	CgroupnsMode string
+	CgroupParent string
+
+	Network        string
+	NetworkAliases []string
+	ExtraHosts     []string
+	Dns            []string
+	DnsSearch      []string
+	DnsOptions     []string
+	Hostname       string
+	Domainname     string
+
+	DeviceCgroupRules []string
+	SecurityOpts      []string
+	Ulimits           map[string]string
+
+	Cpus       string
+	CpusetCpus string
+	Memory     string
+	MemorySwap string
+	ShmSize    string
+
+	OomScoreAdj    int
+	OomKillDisable bool
+
+	Labels map[string]string
+}
+
+// ImageConfig is the configuration of an image, as reported by `docker
+// inspect`.
+type ImageConfig struct {
+	User         string
+	Env          []string
+	Cmd          []string
+	Entrypoint   []string
+	WorkingDir   string
+	Labels       map[string]string
+	ExposedPorts map[string]struct{}
+	Volumes      map[string]struct{}
+	StopSignal   string
+	OnBuild      []string
+	Shell        []string
+	Healthcheck  *ImageHealthcheck
+}
+
+// ImageHealthcheck is the healthcheck of an image. The durations are in
+// nanoseconds.
+type ImageHealthcheck struct {
+	Test          []string
+	Interval      int64
+	Timeout       int64
+	StartPeriod   int64
+	StartInterval int64
+	Retries       int
+}
+
+// ImageMetadata is the metadata of an image, as reported by `docker
+// inspect`.
+type ImageMetadata struct {
+	// The size of the image in bytes, or 0 if the driver doesn't report it.
+	Size int64
+	// The creation time of the image, in RFC 3339 format.
+	Created string
+	RootFS  ImageRootFS
+}
+
+// ImageRootFS is the root filesystem of an image.
+type ImageRootFS struct {
+	// The digests of the layers, from the lowest.
+	Layers []string
+}
+
+// imagePlatform is the platform of an image, as reported by `docker
+// inspect`.
+type imagePlatform struct {
+	Os           string
+	Architecture string
+	Variant      string
+}
+
+func (p imagePlatform) String() string {
+	platform := p.Os + "/" + p.Architecture
+	if p.Variant != "" {
+		platform += "/" + p.Variant
+	}
+	return platform
 }
 
 // This is the template that is used for the RunCommand in the ContainerConfig.
 type startContainerTemplate struct {
+	// The image the container runs
 	Image string
+	// The name of the build, like `docker.ubuntu`
+	BuildName string
+	// The UUID of the `packer build` run, shared by its builds
+	RunUUID string
+	// The platform of the container, empty if `platform` isn't set
+	Platform string
+	// The user variables of the template
+	Vars map[string]string
+}
+
+// runCommandContext returns the interpolation context the RunCommand of the
+// config is rendered with.
+func runCommandContext(ctx *interpolate.Context, config *ContainerConfig) interpolate.Context {
+	ictx := *ctx
+	ictx.Data = &startContainerTemplate{
+		Image:     config.Image,
+		BuildName: ctx.BuildName,
+		RunUUID:   os.Getenv("PACKER_RUN_UUID"),
+		Platform:  config.Platform,
+		Vars:      ctx.UserVariables,
+	}
+	return ictx
 }
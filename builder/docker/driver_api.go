@@ -0,0 +1,1665 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/checkpoint"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	"github.com/hashicorp/go-version"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// The daemon the DockerAPIDriver connects to if DOCKER_HOST is not set.
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+// The name under which a Dockerfile that lives outside of the build context
+// is added to the context sent to the daemon.
+const apiBuildDockerfileName = ".packer.Dockerfile"
+
+// DockerAPIDriver is a Driver that talks directly to the Docker Engine API
+// with the client of the Docker SDK, without relying on the docker CLI.
+//
+// The errors returned when the daemon rejects a request can be inspected
+// with the functions of the errdefs package of the SDK, like
+// errdefs.IsNotFound, and the errors reported while streaming progress are
+// *jsonmessage.JSONError.
+type DockerAPIDriver struct {
+	Ui  packersdk.Ui
+	Ctx *interpolate.Context
+
+	// The address of the Docker daemon, in the same format as DOCKER_HOST.
+	// Defaults to the DOCKER_HOST environment variable, or to the default
+	// unix socket if not set.
+	Host string
+	// The TLS configuration to connect to the daemon with, if the daemon
+	// listens on TCP with TLS enabled.
+	TLS *tls.Config
+	// The private key and ssh agent socket used to connect to an ssh://
+	// Host. Default to the keys of the agent set in SSH_AUTH_SOCK.
+	SSHPrivateKeyFile string
+	SSHAgentSocket    string
+
+	client *client.Client
+
+	// The registry credentials set by Login, and sent with pulls and pushes.
+	registryAuth string
+
+	// The context set by SetContext.
+	reqCtx context.Context
+
+	l sync.Mutex
+}
+
+// connect creates the client of the daemon on first use. The client
+// negotiates the version of the API with the daemon on its first request.
+func (d *DockerAPIDriver) connect() (*client.Client, error) {
+	if d.client != nil {
+		return d.client, nil
+	}
+
+	host := d.Host
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = defaultDockerHost
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse docker host %q: %s", host, err)
+	}
+
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+	switch u.Scheme {
+	case "unix", "tcp", "http", "https":
+		// The client talks https as soon as its transport has a TLS
+		// configuration.
+		if d.TLS != nil {
+			opts = append(opts, client.WithHTTPClient(&http.Client{
+				Transport: &http.Transport{TLSClientConfig: d.TLS},
+			}))
+		} else if u.Scheme == "https" {
+			opts = append(opts, client.WithScheme("https"))
+		}
+		opts = append(opts, client.WithHost(host))
+	case "ssh":
+		dial, err := sshDialer(u, d.SSHPrivateKeyFile, d.SSHAgentSocket)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithHost("http://docker"), client.WithDialContext(dial))
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q, expected unix, tcp or ssh", u.Scheme)
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the client of the docker daemon: %s", err)
+	}
+	d.client = cli
+	return cli, nil
+}
+
+func (d *DockerAPIDriver) SetContext(ctx context.Context) {
+	d.reqCtx = ctx
+}
+
+// requestContext returns the context the requests to the daemon are
+// cancelled with.
+func (d *DockerAPIDriver) requestContext() context.Context {
+	if d.reqCtx == nil {
+		return context.Background()
+	}
+	return d.reqCtx
+}
+
+// streamMessages reads a progress stream from the daemon, reports the
+// progress to the UI, and returns the last message received.
+func (d *DockerAPIDriver) streamMessages(r io.Reader) (*jsonmessage.JSONMessage, error) {
+	var last *jsonmessage.JSONMessage
+
+	dec := json.NewDecoder(r)
+	for {
+		msg := &jsonmessage.JSONMessage{}
+		if err := dec.Decode(msg); err == io.EOF {
+			return last, nil
+		} else if err != nil {
+			return last, fmt.Errorf("failed to decode docker daemon response: %s", err)
+		}
+
+		if err := streamError(msg); err != nil {
+			return last, err
+		}
+
+		switch {
+		case msg.Stream != "":
+			if line := strings.TrimRight(msg.Stream, "\n"); line != "" {
+				d.Ui.Message(line)
+			}
+		case msg.Status != "" && msg.ID != "":
+			// Only report the progress bars in the logs, they are far too
+			// verbose for the UI
+			if msg.Progress != nil || msg.ProgressMessage != "" {
+				log.Printf("%s: %s %s", msg.ID, msg.Status, msg.ProgressMessage)
+			} else {
+				d.Ui.Message(fmt.Sprintf("%s: %s", msg.ID, msg.Status))
+			}
+		case msg.Status != "":
+			d.Ui.Message(msg.Status)
+		}
+
+		last = msg
+	}
+}
+
+// streamError returns the error reported in a message of a progress stream,
+// if any.
+func streamError(msg *jsonmessage.JSONMessage) error {
+	if msg.Error != nil && msg.Error.Message != "" {
+		return msg.Error
+	}
+	if msg.ErrorMessage != "" {
+		return &jsonmessage.JSONError{Message: msg.ErrorMessage}
+	}
+	return nil
+}
+
+// encodedRegistryAuth returns the registry credentials set by Login, or
+// empty credentials, which the daemon requires when pushing.
+func (d *DockerAPIDriver) encodedRegistryAuth() (string, error) {
+	if d.registryAuth != "" {
+		return d.registryAuth, nil
+	}
+	return registry.EncodeAuthConfig(registry.AuthConfig{})
+}
+
+func (d *DockerAPIDriver) Build(args []string) (string, error) {
+	options := types.ImageBuildOptions{
+		Remove:    true,
+		BuildArgs: map[string]*string{},
+	}
+	buildDir := "."
+	compress := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		next := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("missing value for build argument %q", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var err error
+		var value string
+		switch arg {
+		case "-f":
+			options.Dockerfile, err = next()
+		case "--platform":
+			options.Platform, err = next()
+		case "--target":
+			options.Target, err = next()
+		case "--build-arg":
+			value, err = next()
+			if name, value, ok := strings.Cut(value, "="); ok {
+				options.BuildArgs[name] = &value
+			}
+		case "--pull":
+			options.PullParent = true
+		case "--compress":
+			compress = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return "", fmt.Errorf("build argument %q is not supported by the api driver", arg)
+			}
+			buildDir = arg
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	cli, err := d.connect()
+	if err != nil {
+		return "", err
+	}
+
+	// The Dockerfile must be part of the build context sent to the daemon,
+	// so if it lives outside of it, we add it under a dedicated name.
+	absBuildDir, err := filepath.Abs(buildDir)
+	if err != nil {
+		return "", err
+	}
+	extraFiles := map[string]string{}
+	if options.Dockerfile != "" {
+		rel, err := filepath.Rel(absBuildDir, options.Dockerfile)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			extraFiles[apiBuildDockerfileName] = options.Dockerfile
+			rel = apiBuildDockerfileName
+		}
+		options.Dockerfile = filepath.ToSlash(rel)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeBuildContext(pw, absBuildDir, extraFiles, compress))
+	}()
+	defer pr.Close()
+
+	log.Printf("Building container with args: %v", args)
+	resp, err := cli.ImageBuild(d.requestContext(), pr, options)
+	if err != nil {
+		return "", fmt.Errorf("docker build failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	imageId := ""
+	dec := json.NewDecoder(resp.Body)
+	for {
+		msg := &jsonmessage.JSONMessage{}
+		if err := dec.Decode(msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("failed to decode docker daemon response: %s", err)
+		}
+		if err := streamError(msg); err != nil {
+			return "", fmt.Errorf("docker build failed: %w", err)
+		}
+		if msg.Aux != nil {
+			var aux types.BuildResult
+			if err := json.Unmarshal(*msg.Aux, &aux); err == nil && aux.ID != "" {
+				imageId = aux.ID
+			}
+		}
+		if line := strings.TrimRight(msg.Stream, "\n"); line != "" {
+			d.Ui.Message(line)
+		}
+	}
+
+	if imageId == "" {
+		return "", fmt.Errorf("docker build did not report the ID of the built image")
+	}
+
+	return imageId, nil
+}
+
+// writeBuildContext writes the content of the dir directory as a tar archive
+// to w, along with the extra files, which map a name in the archive to a
+// path on the host.
+func writeBuildContext(w io.Writer, dir string, extraFiles map[string]string, compress bool) error {
+	if compress {
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		w = gz
+	}
+
+	archive := tar.NewWriter(w)
+	defer archive.Close()
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		return addArchiveFile(archive, rel, path, info)
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, path := range extraFiles {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := addArchiveFile(archive, name, path, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeCopyArchive writes the file or directory src as a tar archive to w,
+// under the given name.
+func writeCopyArchive(w io.Writer, src string, name string) error {
+	archive := tar.NewWriter(w)
+	defer archive.Close()
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		return addArchiveFile(archive, filepath.Join(name, rel), path, info)
+	})
+}
+
+// addArchiveFile adds the file at path of the host to the archive, under the
+// given name.
+func addArchiveFile(archive *tar.Writer, name, path string, info os.FileInfo) error {
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(archive, f)
+	return err
+}
+
+func (d *DockerAPIDriver) BuildX(args []string) (string, error) {
+	return "", fmt.Errorf("buildx is not supported by the api driver")
+}
+
+func (d *DockerAPIDriver) Cmd(id string) (string, error) {
+	return d.imageConfigField(id, func(c ImageConfig) []string { return c.Cmd })
+}
+
+func (d *DockerAPIDriver) Entrypoint(id string) (string, error) {
+	return d.imageConfigField(id, func(c ImageConfig) []string { return c.Entrypoint })
+}
+
+// imageInspect holds the fields of the inspection of an image, decoded from
+// the raw response of the daemon to share the types of the other drivers.
+type imageInspect struct {
+	Id          string
+	RepoDigests []string
+	Config      ImageConfig
+
+	ImageMetadata
+	imagePlatform
+}
+
+func (d *DockerAPIDriver) inspectImage(id string) (*imageInspect, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	_, raw, err := cli.ImageInspectWithRaw(d.requestContext(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	var inspect imageInspect
+	if err := json.Unmarshal(raw, &inspect); err != nil {
+		return nil, fmt.Errorf("failed to decode the image %s: %s", id, err)
+	}
+	return &inspect, nil
+}
+
+// imageConfigField returns a field of the configuration of an image as a
+// JSON array, or `[""]` if it's empty, the same way the DockerDriver does.
+func (d *DockerAPIDriver) imageConfigField(id string, field func(ImageConfig) []string) (string, error) {
+	inspect, err := d.inspectImage(id)
+	if err != nil {
+		return "", err
+	}
+
+	value := field(inspect.Config)
+	if len(value) == 0 {
+		return `[""]`, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (d *DockerAPIDriver) Commit(id string, author string, changes []string, message string) (string, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return "", err
+	}
+
+	options := container.CommitOptions{
+		Author:  author,
+		Comment: message,
+		Changes: changes,
+		Pause:   true,
+	}
+
+	log.Printf("Committing container with args: %#v", options)
+	resp, err := cli.ContainerCommit(d.requestContext(), id, options)
+	if err != nil {
+		return "", fmt.Errorf("Error committing container: %w", err)
+	}
+
+	return resp.ID, nil
+}
+
+func (d *DockerAPIDriver) DeleteImage(id string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Deleting image: %s", id)
+	if _, err := cli.ImageRemove(d.requestContext(), id, image.RemoveOptions{}); err != nil {
+		return fmt.Errorf("Error deleting image: %w", err)
+	}
+	return nil
+}
+
+// CopyToContainer extracts an archive of src, named after dst, in the parent
+// directory of dst.
+func (d *DockerAPIDriver) CopyToContainer(id string, src string, dst string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeCopyArchive(pw, src, path.Base(dst)))
+	}()
+	defer pr.Close()
+
+	log.Printf("Copying %s to %s:%s", src, id, dst)
+	err = cli.CopyToContainer(d.requestContext(), id, path.Dir(dst), pr, types.CopyToContainerOptions{})
+	if err != nil {
+		return fmt.Errorf("Error copying to the container: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) Export(id string, dst io.Writer) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Exporting container: %s", id)
+	r, err := cli.ContainerExport(d.requestContext(), id)
+	if err == nil {
+		defer r.Close()
+		_, err = io.Copy(dst, r)
+	}
+	if err != nil {
+		return fmt.Errorf("Error exporting: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) ImageConfig(id string) (*ImageConfig, error) {
+	inspect, err := d.inspectImage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &inspect.Config, nil
+}
+
+func (d *DockerAPIDriver) ImageMetadata(id string) (*ImageMetadata, error) {
+	inspect, err := d.inspectImage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &inspect.ImageMetadata, nil
+}
+
+func (d *DockerAPIDriver) ImagePlatform(id string) (string, error) {
+	inspect, err := d.inspectImage(id)
+	if err != nil {
+		return "", err
+	}
+
+	return inspect.imagePlatform.String(), nil
+}
+
+func (d *DockerAPIDriver) Import(path string, changes []string, repo string, platform string) (string, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return "", err
+	}
+
+	file, err := openArchive(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	options := image.ImportOptions{
+		Changes:  changes,
+		Platform: platform,
+	}
+
+	log.Printf("Importing tarball with args: %#v", options)
+	source := types.ImageImportSource{Source: file, SourceName: "-"}
+	resp, err := cli.ImageImport(d.requestContext(), source, repo, options)
+	if err != nil {
+		return "", fmt.Errorf("Error importing container: %w", err)
+	}
+	defer resp.Close()
+
+	last, err := d.streamMessages(resp)
+	if err != nil {
+		return "", fmt.Errorf("Error importing container: %w", err)
+	}
+	if last == nil {
+		return "", fmt.Errorf("Error importing container: no image ID reported")
+	}
+
+	return strings.TrimSpace(last.Status), nil
+}
+
+// containerInspect holds the fields of the inspection of a container used by
+// the driver, decoded from the raw response of the daemon.
+type containerInspect struct {
+	Id     string
+	Config struct {
+		User string
+		Tty  bool
+	}
+	NetworkSettings struct {
+		IPAddress string
+		Ports     nat.PortMap
+	}
+	State struct {
+		OOMKilled bool
+		Health    *struct {
+			Status string
+		}
+	}
+}
+
+func (d *DockerAPIDriver) inspectContainer(id string) (*containerInspect, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	_, raw, err := cli.ContainerInspectWithRaw(d.requestContext(), id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var inspect containerInspect
+	if err := json.Unmarshal(raw, &inspect); err != nil {
+		return nil, fmt.Errorf("failed to decode the container %s: %s", id, err)
+	}
+	return &inspect, nil
+}
+
+func (d *DockerAPIDriver) IPAddress(id string) (string, error) {
+	inspect, err := d.inspectContainer(id)
+	if err != nil {
+		return "", fmt.Errorf("Error: %w", err)
+	}
+	return inspect.NetworkSettings.IPAddress, nil
+}
+
+func (d *DockerAPIDriver) PublishedPort(id string, port int) (int, error) {
+	inspect, err := d.inspectContainer(id)
+	if err != nil {
+		return 0, fmt.Errorf("Error: %w", err)
+	}
+	bindings := inspect.NetworkSettings.Ports[nat.Port(fmt.Sprintf("%d/tcp", port))]
+	if len(bindings) == 0 {
+		return 0, fmt.Errorf("port %d of the container is not published", port)
+	}
+	return strconv.Atoi(bindings[0].HostPort)
+}
+
+func (d *DockerAPIDriver) OOMKilled(id string) (bool, error) {
+	inspect, err := d.inspectContainer(id)
+	if err != nil {
+		return false, err
+	}
+	return inspect.State.OOMKilled, nil
+}
+
+func (d *DockerAPIDriver) Logs(id string) (string, error) {
+	inspect, err := d.inspectContainer(id)
+	if err != nil {
+		return "", fmt.Errorf("Error: %w", err)
+	}
+
+	var logs bytes.Buffer
+	r, err := d.client.ContainerLogs(d.requestContext(), id, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err == nil {
+		defer r.Close()
+		_, err = io.Copy(&logs, r)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error reading the logs: %w", err)
+	}
+	if inspect.Config.Tty {
+		return logs.String(), nil
+	}
+	return demuxLogs(logs.Bytes())
+}
+
+func (d *DockerAPIDriver) FollowLogs(ctx context.Context, id string, dst io.Writer) error {
+	inspect, err := d.inspectContainer(id)
+	if err != nil {
+		return fmt.Errorf("Error: %w", err)
+	}
+
+	r, err := d.client.ContainerLogs(ctx, id, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("Error reading the logs: %w", err)
+	}
+	defer r.Close()
+
+	if inspect.Config.Tty {
+		_, err = io.Copy(dst, r)
+		if err != nil {
+			err = fmt.Errorf("Error reading the logs: %w", err)
+		}
+	} else {
+		err = demuxStream(dst, r)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// envList returns the environment variables as `NAME=value` pairs, sorted
+// by name.
+func envList(env map[string]string) []string {
+	var list []string
+	for _, k := range sortedKeys(env) {
+		list = append(list, k+"="+env[k])
+	}
+	return list
+}
+
+// demuxLogs returns the output of the logs of the containers without a TTY,
+// whose stdout and stderr are multiplexed in frames of an 8 bytes header,
+// with the stream and the big endian size of the frame, and its data.
+func demuxLogs(b []byte) (string, error) {
+	var logs strings.Builder
+	if err := demuxStream(&logs, bytes.NewReader(b)); err != nil {
+		return "", err
+	}
+	return logs.String(), nil
+}
+
+// demuxStream writes the data of the frames of multiplexed logs to dst, as
+// they are read from src.
+func demuxStream(dst io.Writer, src io.Reader) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(src, header); err == io.EOF {
+			return nil
+		} else if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("Error reading the logs: truncated frame header")
+		} else if err != nil {
+			return fmt.Errorf("Error reading the logs: %w", err)
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:8]))
+		if n, err := io.CopyN(dst, src, size); err != nil {
+			if n < size && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+				return fmt.Errorf("Error reading the logs: truncated frame")
+			}
+			return fmt.Errorf("Error reading the logs: %w", err)
+		}
+	}
+}
+
+func (d *DockerAPIDriver) Diff(id string) ([]string, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := cli.ContainerDiff(d.requestContext(), id)
+	if err != nil {
+		return nil, fmt.Errorf("Error: %w", err)
+	}
+
+	var paths []string
+	for _, change := range changes {
+		if change.Kind != container.ChangeDelete {
+			paths = append(paths, change.Path)
+		}
+	}
+	return paths, nil
+}
+
+func (d *DockerAPIDriver) HealthStatus(id string) (string, error) {
+	inspect, err := d.inspectContainer(id)
+	if err != nil {
+		return "", fmt.Errorf("Error: %w", err)
+	}
+	if inspect.State.Health == nil {
+		return "", nil
+	}
+	return inspect.State.Health.Status, nil
+}
+
+func (d *DockerAPIDriver) Sha256(id string) (string, error) {
+	inspect, err := d.inspectImage(id)
+	if err != nil {
+		return "", fmt.Errorf("Error: %w", err)
+	}
+	return inspect.Id, nil
+}
+
+func (d *DockerAPIDriver) Digest(id string) (string, error) {
+	inspect, err := d.inspectImage(id)
+	if err != nil {
+		return "", fmt.Errorf("Error: %w", err)
+	}
+	if len(inspect.RepoDigests) == 0 {
+		return "", fmt.Errorf("Error: image %s has no repo digest", id)
+	}
+	return inspect.RepoDigests[0], nil
+}
+
+func (d *DockerAPIDriver) RepoDigests(id string) ([]string, error) {
+	inspect, err := d.inspectImage(id)
+	if err != nil {
+		return nil, fmt.Errorf("Error: %w", err)
+	}
+	return inspect.RepoDigests, nil
+}
+
+func (d *DockerAPIDriver) Login(repo, username, password string) error {
+	d.l.Lock()
+
+	cli, err := d.connect()
+	if err != nil {
+		d.l.Unlock()
+		return err
+	}
+
+	auth := registry.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: repo,
+	}
+	if _, err := cli.RegistryLogin(d.requestContext(), auth); err != nil {
+		d.l.Unlock()
+		return fmt.Errorf("Error logging in: %w", err)
+	}
+
+	d.registryAuth, err = registry.EncodeAuthConfig(auth)
+	if err != nil {
+		d.l.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func (d *DockerAPIDriver) Logout(repo string) error {
+	d.registryAuth = ""
+	d.l.Unlock()
+	return nil
+}
+
+func (d *DockerAPIDriver) ImageExists(image string) (bool, error) {
+	_, err := d.inspectImage(image)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// distributionInspect asks the daemon for the distribution information of
+// the image, which it gets from the manifest in the registry. It returns nil
+// if the image isn't in the registry.
+func (d *DockerAPIDriver) distributionInspect(name string) (*registry.DistributionInspect, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := d.encodedRegistryAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	distribution, err := cli.DistributionInspect(d.requestContext(), name, auth)
+	if errdefs.IsNotFound(err) || (err != nil && manifestNotFoundRe.MatchString(err.Error())) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &distribution, nil
+}
+
+func (d *DockerAPIDriver) RemoteImageExists(name string) (bool, error) {
+	distribution, err := d.distributionInspect(name)
+	if err != nil {
+		return false, err
+	}
+	return distribution != nil, nil
+}
+
+func (d *DockerAPIDriver) RemoteDigest(name string) (string, error) {
+	distribution, err := d.distributionInspect(name)
+	if err != nil || distribution == nil {
+		return "", err
+	}
+	return distribution.Descriptor.Digest.String(), nil
+}
+
+func (d *DockerAPIDriver) ImagesWithLabel(label, value string) ([]string, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := cli.ImageList(d.requestContext(), image.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", label+"="+value)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error: %w", err)
+	}
+
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].Created > images[j].Created
+	})
+	var ids []string
+	for _, image := range images {
+		ids = append(ids, image.ID)
+	}
+	return ids, nil
+}
+
+func (d *DockerAPIDriver) NetworkExists(name string) (bool, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = cli.NetworkInspect(d.requestContext(), name, types.NetworkInspectOptions{})
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d *DockerAPIDriver) CreateNetwork(name string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	if _, err := cli.NetworkCreate(d.requestContext(), name, types.NetworkCreate{}); err != nil {
+		return fmt.Errorf("Error creating network: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) RemoveNetwork(name string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	if err := cli.NetworkRemove(d.requestContext(), name); err != nil {
+		return fmt.Errorf("Error removing network: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) VolumeExists(name string) (bool, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = cli.VolumeInspect(d.requestContext(), name)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d *DockerAPIDriver) CreateVolume(name string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	if _, err := cli.VolumeCreate(d.requestContext(), volume.CreateOptions{Name: name}); err != nil {
+		return fmt.Errorf("Error creating volume: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) RemoveVolume(name string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	if err := cli.VolumeRemove(d.requestContext(), name, false); err != nil {
+		return fmt.Errorf("Error removing volume: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) Pull(name string, platform string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+	auth, err := d.encodedRegistryAuth()
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.ImagePull(d.requestContext(), name, image.PullOptions{
+		RegistryAuth: auth,
+		Platform:     platform,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	_, err = d.streamMessages(resp)
+	return err
+}
+
+// Push pushes the image. The push endpoint of the API takes the tag apart
+// from the repository, so the name is parsed as a reference by the client,
+// and can't be a digest. The client doesn't send the platform, so all the
+// platforms of the image are pushed.
+func (d *DockerAPIDriver) Push(name string, platform string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+	auth, err := d.encodedRegistryAuth()
+	if err != nil {
+		return err
+	}
+
+	if platform != "" {
+		log.Printf("Pushing %s for all its platforms, the api driver can't push %s only", name, platform)
+	}
+	resp, err := cli.ImagePush(d.requestContext(), name, image.PushOptions{RegistryAuth: auth})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	_, err = d.streamMessages(resp)
+	return err
+}
+
+func (d *DockerAPIDriver) SaveImage(id string, dst io.Writer) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Exporting image: %s", id)
+	r, err := cli.ImageSave(d.requestContext(), []string{id})
+	if err == nil {
+		defer r.Close()
+		_, err = io.Copy(dst, r)
+	}
+	if err != nil {
+		return fmt.Errorf("Error exporting: %w", err)
+	}
+	return nil
+}
+
+// SaveOCIImage saves the image like SaveImage, since the archives of the
+// daemon are OCI image layouts since Docker 25.
+func (d *DockerAPIDriver) SaveOCIImage(id string, dst io.Writer) error {
+	return d.SaveImage(id, dst)
+}
+
+// containerCreateRequest is the body of a container creation request, along
+// with the platform of the image, which is sent as a parameter.
+type containerCreateRequest struct {
+	*container.Config
+	HostConfig       *container.HostConfig
+	NetworkingConfig *network.NetworkingConfig
+
+	Platform *ocispec.Platform `json:"-"`
+}
+
+// parseDevice parses a device in the same format as `docker run --device`,
+// i.e. `host[:container[:permissions]]`.
+func parseDevice(device string) container.DeviceMapping {
+	parts := strings.Split(device, ":")
+	dev := container.DeviceMapping{
+		PathOnHost:        parts[0],
+		PathInContainer:   parts[0],
+		CgroupPermissions: "rwm",
+	}
+	if len(parts) > 1 {
+		dev.PathInContainer = parts[1]
+	}
+	if len(parts) > 2 {
+		dev.CgroupPermissions = parts[2]
+	}
+	return dev
+}
+
+// parsePlatform parses a platform in the `os/arch[/variant]` format of
+// `docker run --platform`.
+func parsePlatform(platform string) *ocispec.Platform {
+	if platform == "" {
+		return nil
+	}
+	parts := strings.SplitN(platform, "/", 3)
+	p := &ocispec.Platform{OS: parts[0]}
+	if len(parts) > 1 {
+		p.Architecture = parts[1]
+	}
+	if len(parts) > 2 {
+		p.Variant = parts[2]
+	}
+	return p
+}
+
+// apiSecurityOpt translates a security option of `docker run
+// --security-opt` to the format of the API, which takes the content of the
+// seccomp profiles instead of their path.
+func apiSecurityOpt(opt string) (string, error) {
+	key, value, ok := strings.Cut(opt, "=")
+	if !ok {
+		key, value, ok = strings.Cut(opt, ":")
+	}
+	if !ok || key != "seccomp" || value == "unconfined" {
+		return opt, nil
+	}
+
+	profile, err := os.ReadFile(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the seccomp profile: %s", err)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, profile); err != nil {
+		return "", fmt.Errorf("seccomp profile %s is not valid JSON: %s", value, err)
+	}
+
+	return "seccomp=" + compact.String(), nil
+}
+
+// containerCreateRequest translates the container configuration to a
+// container creation request.
+//
+// Since the run command is made of arguments to `docker run`, only the
+// subset of arguments used by the default run commands is supported.
+func (d *DockerAPIDriver) containerCreateRequest(config *ContainerConfig) (*containerCreateRequest, error) {
+	ictx := runCommandContext(d.Ctx, config)
+
+	req := &containerCreateRequest{
+		Config: &container.Config{
+			AttachStdout: true,
+			AttachStderr: true,
+			Hostname:     config.Hostname,
+			Domainname:   config.Domainname,
+			Labels:       config.Labels,
+			Env:          envList(config.Env),
+		},
+		HostConfig: &container.HostConfig{
+			CapAdd:     config.CapAdd,
+			CapDrop:    config.CapDrop,
+			Privileged: config.Privileged,
+			Runtime:    config.Runtime,
+			UsernsMode: container.UsernsMode(config.Userns),
+			IpcMode:    container.IpcMode(config.IpcMode),
+			PidMode:    container.PidMode(config.PidMode),
+
+			CgroupnsMode: container.CgroupnsMode(config.CgroupnsMode),
+
+			ReadonlyRootfs: config.ReadOnly,
+
+			OomScoreAdj: config.OomScoreAdj,
+
+			Resources: container.Resources{
+				CgroupParent:      config.CgroupParent,
+				DeviceCgroupRules: config.DeviceCgroupRules,
+			},
+		},
+		Platform: parsePlatform(config.Platform),
+	}
+	hostConfig := req.HostConfig
+
+	// Unlike the daemon, the driver has no default for these, so they are
+	// only sent when enabled.
+	if config.Init {
+		hostConfig.Init = &config.Init
+	}
+	if config.OomKillDisable {
+		hostConfig.OomKillDisable = &config.OomKillDisable
+	}
+
+	for _, v := range config.Device {
+		hostConfig.Devices = append(hostConfig.Devices, parseDevice(v))
+	}
+	if config.Gpus != "" {
+		gpus, err := parseGPUs(config.Gpus)
+		if err != nil {
+			return nil, err
+		}
+		hostConfig.DeviceRequests = []container.DeviceRequest{{
+			Driver:       gpus.Driver,
+			Count:        gpus.Count,
+			DeviceIDs:    gpus.DeviceIDs,
+			Capabilities: gpus.Capabilities,
+		}}
+	}
+	var err error
+	if config.Cpus != "" {
+		if hostConfig.NanoCPUs, err = parseCpus(config.Cpus); err != nil {
+			return nil, err
+		}
+	}
+	hostConfig.CpusetCpus = config.CpusetCpus
+	if config.Memory != "" {
+		if hostConfig.Memory, err = parseBytes(config.Memory); err != nil {
+			return nil, err
+		}
+	}
+	if config.MemorySwap != "" {
+		if hostConfig.MemorySwap, err = parseBytes(config.MemorySwap); err != nil {
+			return nil, err
+		}
+	}
+	if config.ShmSize != "" {
+		if hostConfig.ShmSize, err = parseBytes(config.ShmSize); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range sortedKeys(config.Ulimits) {
+		limit, err := parseUlimit(name, config.Ulimits[name])
+		if err != nil {
+			return nil, err
+		}
+		hostConfig.Ulimits = append(hostConfig.Ulimits, &units.Ulimit{
+			Name: limit.Name,
+			Soft: limit.Soft,
+			Hard: limit.Hard,
+		})
+	}
+	for _, opt := range config.SecurityOpts {
+		opt, err := apiSecurityOpt(opt)
+		if err != nil {
+			return nil, err
+		}
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, opt)
+	}
+	for _, p := range config.Publish {
+		// The ports are published on a random port of the host, like with
+		// `docker run --publish <port>`.
+		if !strings.Contains(p, "/") {
+			p += "/tcp"
+		}
+		port := nat.Port(p)
+		if req.ExposedPorts == nil {
+			req.ExposedPorts = nat.PortSet{}
+			hostConfig.PortBindings = nat.PortMap{}
+		}
+		req.ExposedPorts[port] = struct{}{}
+		hostConfig.PortBindings[port] = []nat.PortBinding{{HostPort: ""}}
+	}
+	hostConfig.ExtraHosts = config.ExtraHosts
+	hostConfig.DNS = config.Dns
+	hostConfig.DNSSearch = config.DnsSearch
+	hostConfig.DNSOptions = config.DnsOptions
+	if config.Network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(config.Network)
+		if len(config.NetworkAliases) > 0 {
+			req.NetworkingConfig = &network.NetworkingConfig{
+				EndpointsConfig: map[string]*network.EndpointSettings{
+					config.Network: {Aliases: config.NetworkAliases},
+				},
+			}
+		}
+	}
+	if len(config.TmpFs) > 0 {
+		hostConfig.Tmpfs = map[string]string{}
+		for _, v := range config.TmpFs {
+			parts := strings.SplitN(v, ":", 2)
+			opts := ""
+			if len(parts) == 2 {
+				opts = parts[1]
+			}
+			hostConfig.Tmpfs[parts[0]] = opts
+		}
+	}
+	for host, guest := range config.Volumes {
+		if strings.HasPrefix(host, "~/") {
+			homedir, _ := os.UserHomeDir()
+			host = filepath.Join(homedir, host[2:])
+		}
+		hostConfig.Binds = append(hostConfig.Binds, fmt.Sprintf("%s:%s", host, guest))
+	}
+	for _, m := range config.Mounts {
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:     mount.Type(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		})
+	}
+
+	var positional []string
+	flags := true
+	for i := 0; i < len(config.RunCommand); i++ {
+		v, err := interpolate.Render(config.RunCommand[i], &ictx)
+		if err != nil {
+			return nil, err
+		}
+
+		if !flags || !strings.HasPrefix(v, "-") {
+			positional = append(positional, v)
+			continue
+		}
+
+		switch {
+		case v == "--":
+			flags = false
+		case v == "-d" || v == "--detach":
+		case v == "-i" || v == "--interactive":
+			req.OpenStdin = true
+			req.AttachStdin = true
+		case v == "-t" || v == "--tty":
+			req.Tty = true
+		case v == "-it" || v == "-ti":
+			req.OpenStdin = true
+			req.AttachStdin = true
+			req.Tty = true
+		case strings.HasPrefix(v, "--entrypoint="):
+			req.Entrypoint = []string{strings.TrimPrefix(v, "--entrypoint=")}
+		case v == "--entrypoint":
+			if i+1 >= len(config.RunCommand) {
+				return nil, fmt.Errorf("missing value for run_command argument %q", v)
+			}
+			i++
+			entrypoint, err := interpolate.Render(config.RunCommand[i], &ictx)
+			if err != nil {
+				return nil, err
+			}
+			req.Entrypoint = []string{entrypoint}
+		default:
+			return nil, fmt.Errorf("run_command argument %q is not supported by the api driver", v)
+		}
+	}
+
+	if len(positional) == 0 {
+		return nil, fmt.Errorf("run_command does not specify the image to run")
+	}
+	req.Image = positional[0]
+	req.Cmd = append(positional[1:], config.Cmd...)
+	if len(config.Entrypoint) > 0 {
+		req.Entrypoint = config.Entrypoint
+	}
+
+	return req, nil
+}
+
+func (d *DockerAPIDriver) StartContainer(config *ContainerConfig) (string, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := d.containerCreateRequest(config)
+	if err != nil {
+		return "", err
+	}
+
+	d.Ui.Message(fmt.Sprintf("Creating container from image: %s", req.Image))
+	log.Printf("Creating container with config: %#v", req)
+
+	created, err := cli.ContainerCreate(d.requestContext(), req.Config, req.HostConfig, req.NetworkingConfig, req.Platform, config.Name)
+	if err != nil {
+		return "", fmt.Errorf("Error creating container: %w", err)
+	}
+	for _, warning := range created.Warnings {
+		d.Ui.Message(fmt.Sprintf("Warning: %s", warning))
+	}
+
+	log.Println("Waiting for container to finish starting")
+	if err := cli.ContainerStart(d.requestContext(), created.ID, container.StartOptions{}); err != nil {
+		return "", fmt.Errorf("Error starting container: %w", err)
+	}
+
+	return created.ID, nil
+}
+
+func (d *DockerAPIDriver) KillContainer(id string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	// A container stopped before the commit can't be killed, but must be
+	// removed all the same.
+	if err := cli.ContainerKill(d.requestContext(), id, ""); err != nil {
+		if !errdefs.IsConflict(err) || !containerNotRunningRe.MatchString(err.Error()) {
+			return err
+		}
+		log.Printf("The container %s isn't running, removing it", id)
+	}
+
+	return cli.ContainerRemove(d.requestContext(), id, container.RemoveOptions{})
+}
+
+// StopContainer stops the container. The stop endpoint only takes a signal
+// since API 1.42, so a signal is sent with the kill endpoint instead, and
+// the container is killed if it's still running after the timeout.
+func (d *DockerAPIDriver) StopContainer(id string, signal string, timeout time.Duration) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	if signal == "" {
+		var options container.StopOptions
+		if timeout > 0 {
+			seconds := stopTimeoutSeconds(timeout)
+			options.Timeout = &seconds
+		}
+		return cli.ContainerStop(d.requestContext(), id, options)
+	}
+
+	if err := cli.ContainerKill(d.requestContext(), id, signal); err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = defaultStopTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(d.requestContext(), timeout)
+	defer cancel()
+	// The status is only sent once the container exited
+	statusCh, errCh := cli.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	select {
+	case <-statusCh:
+		return nil
+	case err := <-errCh:
+		if ctx.Err() == nil {
+			return err
+		}
+	}
+
+	log.Printf("The container %s is still running after %s, killing it", id, timeout)
+	return cli.ContainerKill(d.requestContext(), id, "")
+}
+
+func (d *DockerAPIDriver) CheckpointContainer(id string, name string, dir string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	options := checkpoint.CreateOptions{
+		CheckpointID:  name,
+		CheckpointDir: dir,
+	}
+	if err := cli.CheckpointCreate(d.requestContext(), id, options); err != nil {
+		return fmt.Errorf("Error checkpointing container: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) RestoreContainer(id string, name string, dir string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	options := container.StartOptions{
+		CheckpointID:  name,
+		CheckpointDir: dir,
+	}
+	if err := cli.ContainerStart(d.requestContext(), id, options); err != nil {
+		return fmt.Errorf("Error restoring container: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) PauseContainer(id string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	if err := cli.ContainerPause(d.requestContext(), id); err != nil {
+		return fmt.Errorf("Error pausing container: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) UnpauseContainer(id string) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	if err := cli.ContainerUnpause(d.requestContext(), id); err != nil {
+		return fmt.Errorf("Error unpausing container: %w", err)
+	}
+	return nil
+}
+
+// TagImage tags the image. The repository is parsed as a reference by the
+// client, which sends the tag apart from the repository.
+func (d *DockerAPIDriver) TagImage(id string, repo string, force bool) error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	if err := cli.ImageTag(d.requestContext(), id, repo); err != nil {
+		return fmt.Errorf("Error tagging image: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) Verify() error {
+	cli, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	if _, err := cli.Ping(d.requestContext()); err != nil {
+		return fmt.Errorf("failed to reach the docker daemon: %w", err)
+	}
+	return nil
+}
+
+func (d *DockerAPIDriver) Rootless() (bool, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return false, err
+	}
+
+	info, err := cli.Info(d.requestContext())
+	if err != nil {
+		return false, err
+	}
+
+	return isRootless(info.SecurityOptions), nil
+}
+
+func (d *DockerAPIDriver) Runtimes() ([]string, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := cli.Info(d.requestContext())
+	if err != nil {
+		return nil, err
+	}
+
+	runtimes := make(map[string]interface{}, len(info.Runtimes))
+	for name, runtime := range info.Runtimes {
+		runtimes[name] = runtime
+	}
+	return runtimeNames(runtimes), nil
+}
+
+func (d *DockerAPIDriver) RunPrivileged(image string, args []string) (string, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return "", err
+	}
+
+	ctx := d.requestContext()
+	created, err := cli.ContainerCreate(ctx, &container.Config{
+		Image:        image,
+		Cmd:          args,
+		AttachStdout: true,
+		AttachStderr: true,
+	}, &container.HostConfig{Privileged: true}, nil, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("Error creating container: %w", err)
+	}
+	defer func() {
+		if err := cli.ContainerRemove(ctx, created.ID, container.RemoveOptions{}); err != nil {
+			log.Printf("Failed to remove the container %s: %s", created.ID, err)
+		}
+	}()
+
+	if err := cli.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		return "", fmt.Errorf("Error starting container: %w", err)
+	}
+	var status container.WaitResponse
+	statusCh, errCh := cli.ContainerWait(ctx, created.ID, "")
+	select {
+	case status = <-statusCh:
+	case err := <-errCh:
+		return "", fmt.Errorf("Error waiting for the container: %w", err)
+	}
+
+	output, err := d.Logs(created.ID)
+	if err != nil {
+		return "", err
+	}
+	if status.StatusCode != 0 {
+		return "", fmt.Errorf("Error running %s: exit status %d\n\nOutput: %s", image, status.StatusCode, output)
+	}
+	return output, nil
+}
+
+func (d *DockerAPIDriver) ServerPlatform() (string, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return "", err
+	}
+
+	v, err := cli.ServerVersion(d.requestContext())
+	if err != nil {
+		return "", err
+	}
+
+	return v.Os + "/" + v.Arch, nil
+}
+
+func (d *DockerAPIDriver) Version() (*version.Version, error) {
+	cli, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := cli.ServerVersion(d.requestContext())
+	if err != nil {
+		return nil, err
+	}
+
+	return version.NewVersion(v.Version)
+}
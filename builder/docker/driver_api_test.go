@@ -0,0 +1,710 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+func TestDockerAPIDriver_impl(t *testing.T) {
+	var _ Driver = new(DockerAPIDriver)
+}
+
+// The version of the API the test daemons negotiate with the client.
+const testAPIVersion = "v1.41"
+
+func testAPIDriver(t *testing.T, handler http.HandlerFunc) *DockerAPIDriver {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_ping" {
+			w.Header().Set("Api-Version", strings.TrimPrefix(testAPIVersion, "v"))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return &DockerAPIDriver{
+		Ui: &packersdk.BasicUi{
+			Reader: new(bytes.Buffer),
+			Writer: new(bytes.Buffer),
+		},
+		Ctx:  &interpolate.Context{},
+		Host: "tcp://" + strings.TrimPrefix(server.URL, "http://"),
+	}
+}
+
+func TestDockerAPIDriver_containerCreateRequest(t *testing.T) {
+	d := &DockerAPIDriver{Ctx: &interpolate.Context{}}
+	req, err := d.containerCreateRequest(&ContainerConfig{
+		Image:      "ubuntu",
+		RunCommand: []string{"-d", "-i", "-t", "--entrypoint=/bin/sh", "--", "{{.Image}}"},
+		Device:     []string{"/dev/fuse"},
+		TmpFs:      []string{"/run:rw,size=64m"},
+		ReadOnly:   true,
+		Volumes:    map[string]string{"/host": "/container"},
+		Userns:     "host",
+		Init:       true,
+		Hostname:   "build",
+		PidMode:    "host",
+		Publish:    []string{"22"},
+		Env:        map[string]string{"B": "2", "A": "1"},
+
+		CgroupnsMode: "private",
+		CgroupParent: "/ci/jobs",
+
+		OomScoreAdj: -500,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if req.Image != "ubuntu" {
+		t.Errorf("bad image: %s", req.Image)
+	}
+	if !reflect.DeepEqual([]string(req.Entrypoint), []string{"/bin/sh"}) {
+		t.Errorf("bad entrypoint: %v", req.Entrypoint)
+	}
+	if !req.Tty || !req.OpenStdin {
+		t.Errorf("expected tty and stdin to be enabled")
+	}
+	if !reflect.DeepEqual(req.HostConfig.Binds, []string{"/host:/container"}) {
+		t.Errorf("bad binds: %v", req.HostConfig.Binds)
+	}
+	if req.Hostname != "build" {
+		t.Errorf("bad hostname: %s", req.Hostname)
+	}
+	if _, ok := req.ExposedPorts["22/tcp"]; !ok || len(req.HostConfig.PortBindings["22/tcp"]) != 1 {
+		t.Errorf("bad published ports: %v, %v", req.ExposedPorts, req.HostConfig.PortBindings)
+	}
+	if !reflect.DeepEqual(req.Env, []string{"A=1", "B=2"}) {
+		t.Errorf("bad env: %v", req.Env)
+	}
+	if req.HostConfig.PidMode != "host" {
+		t.Errorf("bad pid mode: %s", req.HostConfig.PidMode)
+	}
+	if req.HostConfig.CgroupnsMode != "private" || req.HostConfig.CgroupParent != "/ci/jobs" {
+		t.Errorf("bad cgroup options: %s, %s", req.HostConfig.CgroupnsMode, req.HostConfig.CgroupParent)
+	}
+	if req.HostConfig.Init == nil || !*req.HostConfig.Init {
+		t.Errorf("expected init to be enabled")
+	}
+	if req.HostConfig.UsernsMode != "host" {
+		t.Errorf("bad userns mode: %s", req.HostConfig.UsernsMode)
+	}
+	if req.HostConfig.Tmpfs["/run"] != "rw,size=64m" {
+		t.Errorf("bad tmpfs: %v", req.HostConfig.Tmpfs)
+	}
+	if req.HostConfig.OomScoreAdj != -500 {
+		t.Errorf("bad oom score adjustment: %d", req.HostConfig.OomScoreAdj)
+	}
+	if !req.HostConfig.ReadonlyRootfs {
+		t.Errorf("expected the root filesystem to be read-only")
+	}
+	if req.HostConfig.Devices[0].PathInContainer != "/dev/fuse" {
+		t.Errorf("bad devices: %v", req.HostConfig.Devices)
+	}
+
+	req, err = d.containerCreateRequest(&ContainerConfig{
+		Image:      "ubuntu",
+		RunCommand: []string{"-d", "-i", "-t", "--", "{{.Image}}", "-l"},
+		Entrypoint: []string{"/busybox/sh", "-c"},
+		Cmd:        []string{"sleep infinity"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual([]string(req.Entrypoint), []string{"/busybox/sh", "-c"}) {
+		t.Errorf("bad entrypoint: %v", req.Entrypoint)
+	}
+	if !reflect.DeepEqual([]string(req.Cmd), []string{"-l", "sleep infinity"}) {
+		t.Errorf("bad cmd: %v", req.Cmd)
+	}
+
+	// The value of --entrypoint is interpolated in both forms
+	d.Ctx.UserVariables = map[string]string{"shell": "/bin/bash"}
+	req, err = d.containerCreateRequest(&ContainerConfig{
+		Image:      "ubuntu",
+		RunCommand: []string{"-d", "--entrypoint", "{{.Vars.shell}}", "{{.Image}}"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual([]string(req.Entrypoint), []string{"/bin/bash"}) {
+		t.Errorf("bad entrypoint: %v", req.Entrypoint)
+	}
+
+	_, err = d.containerCreateRequest(&ContainerConfig{
+		Image:      "ubuntu",
+		RunCommand: []string{"-d", "--network=host", "{{.Image}}"},
+	})
+	if err == nil {
+		t.Fatal("unsupported run_command arguments should be rejected")
+	}
+}
+
+func TestDockerAPIDriver_Version(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+testAPIVersion+"/version" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"Version": "24.0.7"}`)
+	})
+
+	v, err := d.Version()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.String() != "24.0.7" {
+		t.Fatalf("bad version: %s", v)
+	}
+}
+
+func TestDockerAPIDriver_StopContainer(t *testing.T) {
+	var signals []string
+	exits := true
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + testAPIVersion + "/containers/foo/kill":
+			signals = append(signals, r.URL.Query().Get("signal"))
+			w.WriteHeader(http.StatusNoContent)
+		case "/" + testAPIVersion + "/containers/foo/wait":
+			if !exits {
+				<-r.Context().Done()
+				return
+			}
+			fmt.Fprint(w, `{"StatusCode": 0}`)
+		default:
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+	})
+
+	if err := d.StopContainer("foo", "SIGINT", time.Second); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(signals, []string{"SIGINT"}) {
+		t.Fatalf("bad signals: %v", signals)
+	}
+
+	// A container still running after the timeout is killed
+	signals, exits = nil, false
+	if err := d.StopContainer("foo", "SIGINT", 10*time.Millisecond); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(signals, []string{"SIGINT", ""}) {
+		t.Fatalf("bad signals: %v", signals)
+	}
+}
+
+func TestDockerAPIDriver_KillContainer(t *testing.T) {
+	var status int
+	var removed bool
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + testAPIVersion + "/containers/foo/kill":
+			w.WriteHeader(status)
+			if status == http.StatusConflict {
+				fmt.Fprint(w, `{"message": "Container foo is not running"}`)
+			} else if status != http.StatusNoContent {
+				fmt.Fprint(w, `{"message": "permission denied"}`)
+			}
+		case "/" + testAPIVersion + "/containers/foo":
+			removed = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+	})
+
+	// A stopped container is removed all the same
+	for _, status = range []int{http.StatusNoContent, http.StatusConflict} {
+		removed = false
+		if err := d.KillContainer("foo"); err != nil {
+			t.Fatalf("%d: %s", status, err)
+		}
+		if !removed {
+			t.Fatalf("%d: the container should've been removed", status)
+		}
+	}
+
+	status = http.StatusInternalServerError
+	if err := d.KillContainer("foo"); err == nil {
+		t.Fatal("should've reported the failure to kill the container")
+	}
+}
+
+func TestDockerAPIDriver_checkpoint(t *testing.T) {
+	var checkpoint map[string]interface{}
+	var restore url.Values
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + testAPIVersion + "/containers/foo/checkpoints":
+			if err := json.NewDecoder(r.Body).Decode(&checkpoint); err != nil {
+				t.Errorf("err: %s", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+		case "/" + testAPIVersion + "/containers/foo/start":
+			restore = r.URL.Query()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+	})
+
+	if err := d.CheckpointContainer("foo", "after-deps", "/checkpoints"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]interface{}{"CheckpointID": "after-deps", "CheckpointDir": "/checkpoints", "Exit": false}
+	if !reflect.DeepEqual(checkpoint, expected) {
+		t.Fatalf("bad checkpoint: %v", checkpoint)
+	}
+
+	if err := d.RestoreContainer("foo", "after-deps", "/checkpoints"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if restore.Get("checkpoint") != "after-deps" || restore.Get("checkpoint-dir") != "/checkpoints" {
+		t.Fatalf("bad restore: %v", restore)
+	}
+}
+
+func TestDockerAPIDriver_CopyToContainer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "v1"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "v1", "model.bin"), []byte("weights"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var names []string
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/"+testAPIVersion+"/containers/foo/archive" {
+			t.Errorf("bad request: %s %s", r.Method, r.URL.Path)
+		}
+		if path := r.URL.Query().Get("path"); path != "/srv" {
+			t.Errorf("bad path: %s", path)
+		}
+		archive := tar.NewReader(r.Body)
+		for {
+			header, err := archive.Next()
+			if err != nil {
+				break
+			}
+			names = append(names, header.Name)
+		}
+	})
+
+	if err := d.CopyToContainer("foo", dir, "/srv/models"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"models", "models/v1", "models/v1/model.bin"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad names: %v", names)
+	}
+}
+
+func TestDockerAPIDriver_errors(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + testAPIVersion + "/images/create":
+			fmt.Fprintln(w, `{"status": "Pulling from library/ubuntu", "id": "latest"}`)
+			fmt.Fprintln(w, `{"errorDetail": {"message": "toomanyrequests"}, "error": "toomanyrequests"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "No such image: nope"}`)
+		}
+	})
+
+	var streamErr *jsonmessage.JSONError
+	err := d.Pull("ubuntu", "")
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("expected a JSONError, got %#v", err)
+	}
+	if streamErr.Message != "toomanyrequests" {
+		t.Errorf("bad message: %s", streamErr.Message)
+	}
+
+	_, err = d.Sha256("nope")
+	if !errdefs.IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %#v", err)
+	}
+	if !strings.Contains(err.Error(), "No such image: nope") {
+		t.Errorf("bad error: %s", err)
+	}
+}
+
+func TestDockerAPIDriver_Push(t *testing.T) {
+	var pushed []string
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Registry-Auth") == "" {
+			t.Errorf("no registry auth header")
+		}
+		pushed = append(pushed, r.URL.Path+"?tag="+r.URL.Query().Get("tag"))
+		fmt.Fprintln(w, `{"status": "Pushed", "id": "1234"}`)
+	})
+
+	for _, name := range []string{"registry:5000/app", "registry:5000/app:1.0"} {
+		if err := d.Push(name, ""); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+	}
+	expected := []string{
+		"/" + testAPIVersion + "/images/registry:5000/app/push?tag=latest",
+		"/" + testAPIVersion + "/images/registry:5000/app/push?tag=1.0",
+	}
+	if !reflect.DeepEqual(pushed, expected) {
+		t.Fatalf("bad pushes: %v", pushed)
+	}
+
+	digest := "registry:5000/app@sha256:" + strings.Repeat("a", 64)
+	if err := d.Push(digest, ""); err == nil {
+		t.Fatal("a digest can't be pushed")
+	}
+}
+
+func TestDockerAPIDriver_TagImage(t *testing.T) {
+	var tags []url.Values
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+testAPIVersion+"/images/sha256:abc/tag" {
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+		tags = append(tags, r.URL.Query())
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, repo := range []string{"registry:5000/app", "registry:5000/app:1.0"} {
+		if err := d.TagImage("sha256:abc", repo, false); err != nil {
+			t.Fatalf("%s: %s", repo, err)
+		}
+	}
+	expected := []url.Values{
+		{"repo": {"registry:5000/app"}, "tag": {"latest"}},
+		{"repo": {"registry:5000/app"}, "tag": {"1.0"}},
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("bad tags: %v", tags)
+	}
+
+	digest := "registry:5000/app@sha256:" + strings.Repeat("a", 64)
+	if err := d.TagImage("sha256:abc", digest, false); err == nil {
+		t.Fatal("a digest can't be used as a tag")
+	}
+}
+
+func TestDockerAPIDriver_NetworkExists(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+testAPIVersion+"/networks/db" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "network not found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"Name": "db"}`)
+	})
+
+	exists, err := d.NetworkExists("db")
+	if err != nil || !exists {
+		t.Fatalf("network db should exist: %v, %s", exists, err)
+	}
+
+	exists, err = d.NetworkExists("nope")
+	if err != nil || exists {
+		t.Fatalf("network nope should not exist: %v, %s", exists, err)
+	}
+}
+
+func TestDockerAPIDriver_RemoteImageExists(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Registry-Auth") == "" {
+			t.Errorf("no registry auth header")
+		}
+		switch r.URL.Path {
+		case "/" + testAPIVersion + "/distribution/ghcr.io/example/app:1.0/json":
+			fmt.Fprint(w, `{"Descriptor": {"digest": "sha256:1234"}}`)
+		case "/" + testAPIVersion + "/distribution/ghcr.io/example/app:2.0/json":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message": "manifest unknown: manifest unknown"}`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "unauthorized"}`)
+		}
+	})
+
+	exists, err := d.RemoteImageExists("ghcr.io/example/app:1.0")
+	if err != nil || !exists {
+		t.Fatalf("ghcr.io/example/app:1.0 should exist: %v, %s", exists, err)
+	}
+
+	exists, err = d.RemoteImageExists("ghcr.io/example/app:2.0")
+	if err != nil || exists {
+		t.Fatalf("ghcr.io/example/app:2.0 should not exist: %v, %s", exists, err)
+	}
+
+	if _, err := d.RemoteImageExists("ghcr.io/example/private:1.0"); err == nil {
+		t.Fatal("should fail without access to the registry")
+	}
+}
+
+func TestDockerAPIDriver_RemoteDigest(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+testAPIVersion+"/distribution/ghcr.io/example/app:1.0/json" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "manifest unknown"}`)
+			return
+		}
+		fmt.Fprint(w, `{"Descriptor": {"mediaType": "application/vnd.oci.image.index.v1+json", "digest": "sha256:1234"}}`)
+	})
+
+	digest, err := d.RemoteDigest("ghcr.io/example/app:1.0")
+	if err != nil || digest != "sha256:1234" {
+		t.Fatalf("bad digest: %q, %v", digest, err)
+	}
+
+	digest, err = d.RemoteDigest("ghcr.io/example/app:2.0")
+	if err != nil || digest != "" {
+		t.Fatalf("ghcr.io/example/app:2.0 should not exist: %q, %v", digest, err)
+	}
+}
+
+func TestDockerAPIDriver_ImagePlatform(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+testAPIVersion+"/images/ubuntu/json" {
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"Id": "sha256:abc", "Os": "linux", "Architecture": "arm64", "Variant": "v8"}`)
+	})
+
+	platform, err := d.ImagePlatform("ubuntu")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if platform != "linux/arm64/v8" {
+		t.Fatalf("bad platform: %s", platform)
+	}
+}
+
+func TestDockerAPIDriver_ImageMetadata(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Id": "sha256:abc", "Size": 1234, "Created": "2024-01-02T03:04:05Z", `+
+			`"RootFS": {"Type": "layers", "Layers": ["sha256:1", "sha256:2"]}}`)
+	})
+
+	metadata, err := d.ImageMetadata("ubuntu")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := &ImageMetadata{
+		Size:    1234,
+		Created: "2024-01-02T03:04:05Z",
+		RootFS:  ImageRootFS{Layers: []string{"sha256:1", "sha256:2"}},
+	}
+	if !reflect.DeepEqual(metadata, expected) {
+		t.Fatalf("bad metadata: %#v", metadata)
+	}
+}
+
+func TestDockerAPIDriver_HealthStatus(t *testing.T) {
+	body := `{"Id": "foo", "State": {"Health": {"Status": "starting"}}}`
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+testAPIVersion+"/containers/foo/json" {
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, body)
+	})
+
+	status, err := d.HealthStatus("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if status != "starting" {
+		t.Fatalf("bad status: %q", status)
+	}
+
+	// The containers without healthchecks have no health
+	body = `{"Id": "foo", "State": {}}`
+	status, err = d.HealthStatus("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if status != "" {
+		t.Fatalf("bad status: %q", status)
+	}
+}
+
+func TestDockerAPIDriver_ImagesWithLabel(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+testAPIVersion+"/images/json" {
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+		args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+		if err != nil || !reflect.DeepEqual(args.Get("label"), []string{"io.packer.content-hash=1234"}) {
+			t.Errorf("bad filters: %s", r.URL.Query().Get("filters"))
+		}
+		fmt.Fprint(w, `[{"Id": "sha256:old", "Created": 1}, {"Id": "sha256:new", "Created": 2}]`)
+	})
+
+	ids, err := d.ImagesWithLabel("io.packer.content-hash", "1234")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"sha256:new", "sha256:old"}) {
+		t.Fatalf("bad ids: %v", ids)
+	}
+}
+
+func TestDockerAPIDriver_Diff(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+testAPIVersion+"/containers/foo/changes" {
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `[{"Path": "/etc", "Kind": 0}, {"Path": "/etc/app.conf", "Kind": 1}, {"Path": "/tmp/build", "Kind": 2}]`)
+	})
+
+	paths, err := d.Diff("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(paths, []string{"/etc", "/etc/app.conf"}) {
+		t.Fatalf("bad paths: %v", paths)
+	}
+}
+
+func TestDockerAPIDriver_Logs(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + testAPIVersion + "/containers/foo/json":
+			fmt.Fprint(w, `{"Id": "foo", "Config": {"Tty": false}}`)
+		case "/" + testAPIVersion + "/containers/foo/logs":
+			if r.URL.Query().Get("stdout") != "1" || r.URL.Query().Get("stderr") != "1" {
+				t.Errorf("bad query: %s", r.URL.RawQuery)
+			}
+			// The frames of stdout and stderr
+			w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 9})
+			w.Write([]byte("starting\n"))
+			w.Write([]byte{2, 0, 0, 0, 0, 0, 0, 6})
+			w.Write([]byte("ready\n"))
+		default:
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+	})
+
+	logs, err := d.Logs("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if logs != "starting\nready\n" {
+		t.Fatalf("bad logs: %q", logs)
+	}
+}
+
+func TestDockerAPIDriver_RunPrivileged(t *testing.T) {
+	removed := false
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + testAPIVersion + "/containers/create":
+			var req containerCreateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("err: %s", err)
+			}
+			if req.Image != "tonistiigi/binfmt" || !req.HostConfig.Privileged || !reflect.DeepEqual([]string(req.Cmd), []string{"--install", "arm64"}) {
+				t.Errorf("bad request: %#v", req)
+			}
+			fmt.Fprint(w, `{"Id": "foo"}`)
+		case "/" + testAPIVersion + "/containers/foo/start":
+			w.WriteHeader(http.StatusNoContent)
+		case "/" + testAPIVersion + "/containers/foo/wait":
+			fmt.Fprint(w, `{"StatusCode": 1}`)
+		case "/" + testAPIVersion + "/containers/foo/json":
+			fmt.Fprint(w, `{"Id": "foo", "Config": {"Tty": true}}`)
+		case "/" + testAPIVersion + "/containers/foo/logs":
+			fmt.Fprint(w, "permission denied")
+		case "/" + testAPIVersion + "/containers/foo":
+			removed = r.Method == "DELETE"
+		default:
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+	})
+
+	_, err := d.RunPrivileged("tonistiigi/binfmt", []string{"--install", "arm64"})
+	if err == nil || !strings.Contains(err.Error(), "exit status 1") || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("bad error: %v", err)
+	}
+	if !removed {
+		t.Fatal("should've removed the container")
+	}
+}
+
+func TestDockerAPIDriver_FollowLogs(t *testing.T) {
+	d := testAPIDriver(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + testAPIVersion + "/containers/foo/json":
+			fmt.Fprint(w, `{"Id": "foo", "Config": {"Tty": false}}`)
+		case "/" + testAPIVersion + "/containers/foo/logs":
+			if r.URL.Query().Get("follow") != "1" {
+				t.Errorf("bad query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte{2, 0, 0, 0, 0, 0, 0, 10})
+			w.Write([]byte("not found\n"))
+		default:
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+	})
+
+	var logs bytes.Buffer
+	if err := d.FollowLogs(context.Background(), "foo", &logs); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if logs.String() != "not found\n" {
+		t.Fatalf("bad logs: %q", logs.String())
+	}
+}
+
+func TestDemuxLogs_truncated(t *testing.T) {
+	if _, err := demuxLogs([]byte{1, 0, 0}); err == nil || !strings.Contains(err.Error(), "truncated frame header") {
+		t.Fatalf("bad error: %v", err)
+	}
+	if _, err := demuxLogs([]byte{1, 0, 0, 0, 0, 0, 0, 9, 's'}); err == nil || !strings.Contains(err.Error(), "truncated frame") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestApiSecurityOpt(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "seccomp.json")
+	if err := os.WriteFile(profile, []byte("{\n  \"defaultAction\": \"SCMP_ACT_ALLOW\"\n}\n"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	opt, err := apiSecurityOpt("seccomp=" + profile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if opt != `seccomp={"defaultAction":"SCMP_ACT_ALLOW"}` {
+		t.Fatalf("bad seccomp option: %s", opt)
+	}
+
+	for _, o := range []string{"seccomp=unconfined", "apparmor=unconfined", "no-new-privileges"} {
+		if opt, err := apiSecurityOpt(o); err != nil || opt != o {
+			t.Fatalf("%s should be passed as is, got %s, %v", o, opt, err)
+		}
+	}
+}
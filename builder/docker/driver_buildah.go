@@ -0,0 +1,620 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BuildahDriver is a Driver that runs commands with buildah.
+//
+// buildah does not run a daemon, nor a long running process in the build
+// container: a working container is created from the image, commands are
+// run in it with `buildah run`, and it is committed to an image. This makes
+// it possible to build images without root privileges.
+//
+// buildah shares its registry and build options with podman, so this reuses
+// the PodmanDriver and overrides the commands that work on containers.
+type BuildahDriver struct {
+	PodmanDriver
+}
+
+// inspect runs `buildah inspect` on the container or image with the given
+// ID, and returns the result of the format template.
+func (d *BuildahDriver) inspect(kind, format, id string) (string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.execCommand("inspect", "--type", kind, "--format", format, id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (d *BuildahDriver) Cmd(id string) (string, error) {
+	return d.inspect("image", "{{if .OCIv1.Config.Cmd}} {{json .OCIv1.Config.Cmd}} {{else}} [\"\"] {{end}}", id)
+}
+
+func (d *BuildahDriver) Entrypoint(id string) (string, error) {
+	return d.inspect("image", "{{if .OCIv1.Config.Entrypoint}} {{json .OCIv1.Config.Entrypoint}} {{else}} [\"\"] {{end}}", id)
+}
+
+// Commit applies the changes to the working container with `buildah
+// config`, since `buildah commit` does not support Dockerfile instructions,
+// then commits it.
+func (d *BuildahDriver) Commit(id string, author string, changes []string, message string) (string, error) {
+	args := []string{"config"}
+	if author != "" {
+		args = append(args, "--author", author)
+	}
+	if message != "" {
+		args = append(args, "--comment", message)
+	}
+	for _, change := range changes {
+		changeArgs, err := buildahConfigArgs(change)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, changeArgs...)
+	}
+
+	if len(args) > 1 {
+		args = append(args, id)
+
+		var stderr bytes.Buffer
+		log.Printf("Configuring container with args: %v", args)
+		cmd := d.execCommand(args...)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("Error configuring container: %s\nStderr: %s", err, stderr.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	log.Printf("Committing container: %s", id)
+	cmd := d.execCommand("commit", "--format", "docker", "--quiet", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error committing container: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// buildahConfigArgs translates a Dockerfile instruction into `buildah
+// config` arguments.
+func buildahConfigArgs(change string) ([]string, error) {
+	instruction, value, _ := strings.Cut(strings.TrimSpace(change), " ")
+	value = strings.TrimSpace(value)
+
+	var flag string
+	switch strings.ToUpper(instruction) {
+	case "CMD":
+		flag = "--cmd"
+	case "ENTRYPOINT":
+		flag = "--entrypoint"
+	case "ENV":
+		// ENV accepts both `ENV key value` and `ENV key=value`
+		if !strings.Contains(value, "=") {
+			key, val, _ := strings.Cut(value, " ")
+			value = key + "=" + strings.TrimSpace(val)
+		}
+		flag = "--env"
+	case "EXPOSE":
+		var args []string
+		for _, port := range strings.Fields(value) {
+			args = append(args, "--port", port)
+		}
+		return args, nil
+	case "HEALTHCHECK":
+		hc, err := parseHealthcheck(value)
+		if err != nil {
+			return nil, fmt.Errorf("the %q change is invalid: %s", change, err)
+		}
+		if hc.Cmd == "NONE" {
+			return []string{"--healthcheck", ""}, nil
+		}
+		args := []string{"--healthcheck", "CMD " + hc.Cmd}
+		for _, option := range sortedKeys(hc.Options) {
+			args = append(args, "--healthcheck-"+option, hc.Options[option])
+		}
+		return args, nil
+	case "LABEL":
+		flag = "--label"
+	case "ONBUILD":
+		flag = "--onbuild"
+	case "SHELL":
+		flag = "--shell"
+	case "STOPSIGNAL":
+		flag = "--stop-signal"
+	case "USER":
+		flag = "--user"
+	case "VOLUME":
+		flag = "--volume"
+	case "WORKDIR":
+		flag = "--workingdir"
+	default:
+		return nil, fmt.Errorf("the %q change is not supported by the buildah driver", change)
+	}
+
+	return []string{flag, value}, nil
+}
+
+// Digest returns the repo digest of the image, composed of the name the
+// image was pulled or pushed with, and its manifest digest.
+func (d *BuildahDriver) Digest(id string) (string, error) {
+	output, err := d.inspect("image", "{{.FromImage}} {{.FromImageDigest}}", id)
+	if err != nil {
+		return "", err
+	}
+
+	name, digest, _ := strings.Cut(output, " ")
+	if name == "" || digest == "" {
+		return "", fmt.Errorf("no repo digest found for image %s", id)
+	}
+
+	// Drop the tag, if any, the digest replaces it.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+
+	return name + "@" + digest, nil
+}
+
+// RepoDigests returns the digest of the image buildah pulled, buildah only
+// records one.
+func (d *BuildahDriver) RepoDigests(id string) ([]string, error) {
+	digest, err := d.Digest(id)
+	if err != nil {
+		return nil, err
+	}
+	return []string{digest}, nil
+}
+
+// CopyToContainer copies with `buildah copy`, which copies the content of
+// directories like `docker cp` with a trailing `/.`.
+func (d *BuildahDriver) CopyToContainer(id string, src string, dst string) error {
+	var stderr bytes.Buffer
+	cmd := d.execCommand("copy", id, src, dst)
+	cmd.Stderr = &stderr
+
+	log.Printf("Copying %s to %s:%s", src, id, dst)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error copying to the container: %s\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+func (d *BuildahDriver) Export(id string, dst io.Writer) error {
+	return errors.New("exporting a container is not supported by the buildah driver, use commit instead")
+}
+
+func (d *BuildahDriver) ImageConfig(id string) (*ImageConfig, error) {
+	out, err := d.inspect("image", "{{json .Docker.Config}}", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ImageConfig
+	if err := json.Unmarshal([]byte(out), &config); err != nil {
+		return nil, fmt.Errorf("Error reading the configuration of image %s: %s", id, err)
+	}
+
+	return &config, nil
+}
+
+// ImageMetadata reads the creation time and layers of the OCI configuration
+// of the image. buildah doesn't report the size of images.
+func (d *BuildahDriver) ImageMetadata(id string) (*ImageMetadata, error) {
+	out, err := d.inspect("image", "{{json .OCIv1}}", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var oci struct {
+		Created string `json:"created"`
+		RootFS  struct {
+			DiffIDs []string `json:"diff_ids"`
+		} `json:"rootfs"`
+	}
+	if err := json.Unmarshal([]byte(out), &oci); err != nil {
+		return nil, fmt.Errorf("Error reading the metadata of image %s: %s", id, err)
+	}
+
+	return &ImageMetadata{
+		Created: oci.Created,
+		RootFS:  ImageRootFS{Layers: oci.RootFS.DiffIDs},
+	}, nil
+}
+
+func (d *BuildahDriver) ImagePlatform(id string) (string, error) {
+	out, err := d.inspect("image", "{{json .OCIv1}}", id)
+	if err != nil {
+		return "", err
+	}
+
+	var platform imagePlatform
+	if err := json.Unmarshal([]byte(out), &platform); err != nil {
+		return "", fmt.Errorf("Error reading the platform of image %s: %s", id, err)
+	}
+
+	return platform.String(), nil
+}
+
+func (d *BuildahDriver) Import(path string, changes []string, repo string, platform string) (string, error) {
+	return "", errors.New("importing a tarball is not supported by the buildah driver")
+}
+
+func (d *BuildahDriver) RemoteDigest(name string) (string, error) {
+	return "", errors.New("remote digests are not supported by the buildah driver")
+}
+
+func (d *BuildahDriver) ImageExists(image string) (bool, error) {
+	cmd := d.execCommand("inspect", "--type", "image", image)
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d *BuildahDriver) NetworkExists(name string) (bool, error) {
+	return false, errors.New("networks are not supported by the buildah driver")
+}
+
+func (d *BuildahDriver) CreateNetwork(name string) error {
+	return errors.New("creating a network is not supported by the buildah driver")
+}
+
+func (d *BuildahDriver) RemoveNetwork(name string) error {
+	return errors.New("removing a network is not supported by the buildah driver")
+}
+
+func (d *BuildahDriver) VolumeExists(name string) (bool, error) {
+	return false, errors.New("named volumes are not supported by the buildah driver")
+}
+
+func (d *BuildahDriver) CreateVolume(name string) error {
+	return errors.New("creating a volume is not supported by the buildah driver")
+}
+
+func (d *BuildahDriver) RemoveVolume(name string) error {
+	return errors.New("removing a volume is not supported by the buildah driver")
+}
+
+// IPAddress returns an empty address, since buildah working containers do
+// not run a process one could connect to.
+func (d *BuildahDriver) IPAddress(id string) (string, error) {
+	return "", nil
+}
+
+// PublishedPort returns an error, since buildah working containers do not
+// run a process one could connect to.
+func (d *BuildahDriver) PublishedPort(id string, port int) (int, error) {
+	return 0, errors.New("publishing ports is not supported by the buildah driver")
+}
+
+// OOMKilled returns false, since buildah doesn't keep track of the processes
+// run in working containers.
+func (d *BuildahDriver) OOMKilled(id string) (bool, error) {
+	return false, nil
+}
+
+// Logs returns an error, since buildah working containers run no process
+// that would write logs.
+func (d *BuildahDriver) Logs(id string) (string, error) {
+	return "", errors.New("container logs are not supported by the buildah driver")
+}
+
+// FollowLogs returns an error, since buildah working containers run no
+// process that would write logs.
+func (d *BuildahDriver) FollowLogs(ctx context.Context, id string, dst io.Writer) error {
+	return errors.New("container logs are not supported by the buildah driver")
+}
+
+// Diff returns an error, since buildah has no command to list the changes
+// of a working container.
+func (d *BuildahDriver) Diff(id string) ([]string, error) {
+	return nil, errors.New("container diffs are not supported by the buildah driver")
+}
+
+// HealthStatus returns an error, since buildah doesn't run the healthchecks
+// of the images.
+func (d *BuildahDriver) HealthStatus(id string) (string, error) {
+	return "", errors.New("healthchecks are not supported by the buildah driver")
+}
+
+// RunPrivileged returns an error, since buildah doesn't run containers.
+func (d *BuildahDriver) RunPrivileged(image string, args []string) (string, error) {
+	return "", errors.New("running privileged containers is not supported by the buildah driver")
+}
+
+// ServerPlatform returns the platform of the local host, buildah having no
+// daemon.
+func (d *BuildahDriver) ServerPlatform() (string, error) {
+	return runtime.GOOS + "/" + runtime.GOARCH, nil
+}
+
+func (d *BuildahDriver) Rootless() (bool, error) {
+	return d.rootless("{{.host.rootless}}")
+}
+
+func (d *BuildahDriver) Sha256(id string) (string, error) {
+	imageId, err := d.inspect("image", "{{.FromImageID}}", id)
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256:" + strings.TrimPrefix(imageId, "sha256:"), nil
+}
+
+// SaveImage writes the image to a docker archive with `buildah push`, since
+// buildah has no save command.
+func (d *BuildahDriver) SaveImage(id string, dst io.Writer) error {
+	return d.pushArchive(id, "docker-archive", dst)
+}
+
+// SaveOCIImage writes the image to an OCI archive with `buildah push`.
+func (d *BuildahDriver) SaveOCIImage(id string, dst io.Writer) error {
+	return d.pushArchive(id, "oci-archive", dst)
+}
+
+// pushArchive pushes the image to a temporary archive of the given
+// transport, and copies it to the writer.
+func (d *BuildahDriver) pushArchive(id, transport string, dst io.Writer) error {
+	archive, err := os.CreateTemp("", "packer-buildah-save")
+	if err != nil {
+		return fmt.Errorf("Error creating temporary archive: %s", err)
+	}
+	archive.Close()
+	// buildah refuses to write to an existing archive
+	os.Remove(archive.Name())
+	defer os.Remove(archive.Name())
+
+	var stderr bytes.Buffer
+	cmd := d.execCommand("push", id, transport+":"+archive.Name())
+	cmd.Stderr = &stderr
+
+	log.Printf("Exporting image: %s", id)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error exporting: %s\nStderr: %s", err, stderr.String())
+	}
+
+	f, err := os.Open(archive.Name())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(dst, f)
+	return err
+}
+
+// StartContainer creates a working container from the image with `buildah
+// from`. buildah does not start a process in the container, so the
+// `run_command` is ignored, and working containers have no labels, so the
+// labels are ignored too.
+func (d *BuildahDriver) StartContainer(config *ContainerConfig) (string, error) {
+	if config.Privileged {
+		return "", errors.New("privileged containers are not supported by the buildah driver")
+	}
+	if len(config.TmpFs) > 0 {
+		return "", errors.New("tmpfs mounts are not supported by the buildah driver")
+	}
+	if config.ReadOnly {
+		return "", errors.New("read-only containers are not supported by the buildah driver")
+	}
+	if config.OomScoreAdj != 0 || config.OomKillDisable {
+		return "", errors.New("oom score adjustments are not supported by the buildah driver")
+	}
+	if config.Gpus != "" {
+		return "", errors.New("gpus are not supported by the buildah driver")
+	}
+	if config.Network != "" {
+		return "", errors.New("networks are not supported by the buildah driver")
+	}
+	if len(config.DeviceCgroupRules) > 0 {
+		return "", errors.New("device cgroup rules are not supported by the buildah driver")
+	}
+	if len(config.Mounts) > 0 {
+		return "", errors.New("mounts are not supported by the buildah driver, use volumes instead")
+	}
+	if len(config.Entrypoint) > 0 || len(config.Cmd) > 0 {
+		return "", errors.New("entrypoint and cmd are not supported by the buildah driver, which runs no process in the container")
+	}
+
+	args := []string{"from"}
+	if authFile := d.authFile(); authFile != "" {
+		args = append(args, "--authfile", authFile)
+	}
+	for _, v := range config.Device {
+		args = append(args, "--device", v)
+	}
+	for _, v := range config.CapAdd {
+		args = append(args, "--cap-add", v)
+	}
+	for _, v := range config.CapDrop {
+		args = append(args, "--cap-drop", v)
+	}
+	for _, v := range config.SecurityOpts {
+		args = append(args, "--security-opt", v)
+	}
+	for _, name := range sortedKeys(config.Ulimits) {
+		args = append(args, "--ulimit", fmt.Sprintf("%s=%s", name, config.Ulimits[name]))
+	}
+	if config.Cpus != "" {
+		// buildah has no --cpus, which is a quota over the default period
+		nanoCpus, err := parseCpus(config.Cpus)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "--cpu-period", "100000", "--cpu-quota", strconv.FormatInt(nanoCpus/10000, 10))
+	}
+	for _, v := range config.ExtraHosts {
+		args = append(args, "--add-host", v)
+	}
+	for _, v := range config.Dns {
+		args = append(args, "--dns", v)
+	}
+	for _, v := range config.DnsSearch {
+		args = append(args, "--dns-search", v)
+	}
+	for _, v := range config.DnsOptions {
+		args = append(args, "--dns-option", v)
+	}
+	if config.CpusetCpus != "" {
+		args = append(args, "--cpuset-cpus", config.CpusetCpus)
+	}
+	if config.Memory != "" {
+		args = append(args, "--memory", config.Memory)
+	}
+	if config.MemorySwap != "" {
+		args = append(args, "--memory-swap", config.MemorySwap)
+	}
+	if config.ShmSize != "" {
+		args = append(args, "--shm-size", config.ShmSize)
+	}
+	if config.Runtime != "" {
+		args = append(args, "--runtime", config.Runtime)
+	}
+	if config.Userns != "" {
+		args = append(args, "--userns", config.Userns)
+	}
+	if config.IpcMode != "" {
+		args = append(args, "--ipc", config.IpcMode)
+	}
+	if config.PidMode != "" {
+		args = append(args, "--pid", config.PidMode)
+	}
+	if config.CgroupnsMode != "" {
+		args = append(args, "--cgroupns", config.CgroupnsMode)
+	}
+	if config.CgroupParent != "" {
+		args = append(args, "--cgroup-parent", config.CgroupParent)
+	}
+	for _, v := range config.UIDMap {
+		args = append(args, "--userns-uid-map", v)
+	}
+	for _, v := range config.GIDMap {
+		args = append(args, "--userns-gid-map", v)
+	}
+	if config.Platform != "" {
+		args = append(args, "--platform", config.Platform)
+	}
+	if config.Name != "" {
+		args = append(args, "--name", config.Name)
+	}
+	for host, guest := range config.Volumes {
+		if strings.HasPrefix(host, "~/") {
+			homedir, _ := os.UserHomeDir()
+			host = filepath.Join(homedir, host[2:])
+		}
+		args = append(args, "-v", fmt.Sprintf("%s:%s", host, guest))
+	}
+	args = append(args, config.Image)
+
+	d.Ui.Message(fmt.Sprintf(
+		"Run command: %s %s", d.Executable, strings.Join(args, " ")))
+
+	var stdout, stderr bytes.Buffer
+	cmd := d.execCommand(args...)
+	d.Proxy.Apply(cmd)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	log.Printf("Creating working container with args: %v", args)
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			err = fmt.Errorf("buildah exited with a non-zero exit status.\nStderr: %s",
+				stderr.String())
+		}
+
+		return "", err
+	}
+
+	// buildah prints the name of the working container, use its ID
+	// instead to match the other drivers.
+	name := strings.TrimSpace(stdout.String())
+	containerId, err := d.inspect("container", "{{.ContainerID}}", name)
+	if err != nil {
+		return name, nil
+	}
+
+	return containerId, nil
+}
+
+// StopContainer does nothing, since no process runs in buildah working
+// containers.
+func (d *BuildahDriver) StopContainer(id string, signal string, timeout time.Duration) error {
+	return nil
+}
+
+// PauseContainer and UnpauseContainer do nothing either, for the same
+// reason.
+func (d *BuildahDriver) CheckpointContainer(id string, name string, dir string) error {
+	return errors.New("checkpoints are not supported by the buildah driver")
+}
+
+func (d *BuildahDriver) RestoreContainer(id string, name string, dir string) error {
+	return errors.New("checkpoints are not supported by the buildah driver")
+}
+
+func (d *BuildahDriver) PauseContainer(id string) error {
+	return nil
+}
+
+func (d *BuildahDriver) UnpauseContainer(id string) error {
+	return nil
+}
+
+func (d *BuildahDriver) KillContainer(id string) error {
+	return d.execCommand("rm", id).Run()
+}
+
+// TagImage tags the image with buildah. buildah tag has no --force option,
+// existing tags are always overwritten, so force is ignored.
+func (d *BuildahDriver) TagImage(id string, repo string, force bool) error {
+	var stderr bytes.Buffer
+	cmd := d.execCommand("tag", id, repo)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error tagging image: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// buildahContainerUser returns the user commands run as in the working
+// container.
+func buildahContainerUser(config *Config, _ DockerHostConfig, containerId string) (string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := exec.Command(config.Executable, "inspect", "--type", "container", "--format", "{{.OCIv1.Config.User}}", containerId)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Failed to inspect the container: %s, %s", err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildahDriver_impl(t *testing.T) {
+	var _ Driver = new(BuildahDriver)
+}
+
+func TestBuildahConfigArgs(t *testing.T) {
+	tc := []struct {
+		change   string
+		expected []string
+	}{
+		{`CMD ["sh", "-c", "echo"]`, []string{"--cmd", `["sh", "-c", "echo"]`}},
+		{"ENTRYPOINT /bin/sh", []string{"--entrypoint", "/bin/sh"}},
+		{"ENV FOO=bar", []string{"--env", "FOO=bar"}},
+		{"ENV FOO bar baz", []string{"--env", "FOO=bar baz"}},
+		{"EXPOSE 80 443/tcp", []string{"--port", "80", "--port", "443/tcp"}},
+		{"LABEL version=1", []string{"--label", "version=1"}},
+		{"USER nobody", []string{"--user", "nobody"}},
+		{"WORKDIR /app", []string{"--workingdir", "/app"}},
+		{"workdir /app", []string{"--workingdir", "/app"}},
+		{"HEALTHCHECK NONE", []string{"--healthcheck", ""}},
+		{
+			"HEALTHCHECK --interval=5s --retries=3 CMD curl -f http://localhost/",
+			[]string{"--healthcheck", "CMD curl -f http://localhost/", "--healthcheck-interval", "5s", "--healthcheck-retries", "3"},
+		},
+	}
+
+	for _, c := range tc {
+		args, err := buildahConfigArgs(c.change)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.change, err)
+		}
+		if !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.change, c.expected, args)
+		}
+	}
+
+	if _, err := buildahConfigArgs("ADD foo /foo"); err == nil {
+		t.Fatal("expected an error for an unsupported change")
+	}
+}
@@ -5,15 +5,21 @@ package docker
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
@@ -30,11 +36,30 @@ type DockerDriver struct {
 	ConfigDir string
 	// The executable to run commands with.
 	Executable string
+	// The daemon to run commands against. Defaults to the docker CLI
+	// environment.
+	HostConfig DockerHostConfig
+	// The TLS options of the registries, used by the podman, buildah and
+	// nerdctl drivers.
+	Registries RegistryTLSConfig
+	// The proxy variables of the commands.
+	Proxy ProxyEnvConfig
+
+	// The context set by SetContext.
+	cmdCtx context.Context
 
 	l sync.Mutex
 }
 
 func (d *DockerDriver) Build(args []string) (string, error) {
+	return d.build([]string{"build"}, args)
+}
+
+func (d *DockerDriver) BuildX(args []string) (string, error) {
+	return d.build([]string{"buildx", "build", "--load"}, args)
+}
+
+func (d *DockerDriver) build(subcommand []string, args []string) (string, error) {
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
 
@@ -46,7 +71,7 @@ func (d *DockerDriver) Build(args []string) (string, error) {
 	imageIdFile.Close()
 
 	log.Printf("Building container with args: %v", args)
-	cmd := exec.Command(d.Executable, "build")
+	cmd := d.command(subcommand...)
 	cmd.Args = append(cmd.Args, "--iidfile", imageIdFilePath)
 	cmd.Args = append(cmd.Args, args...)
 	cmd.Stdout = stdout
@@ -54,7 +79,7 @@ func (d *DockerDriver) Build(args []string) (string, error) {
 
 	err = cmd.Run()
 	if err != nil {
-		return "", fmt.Errorf("%s build failed: %s; stdout: %s; stderr: %s", d.Executable, err, stdout.String(), stderr.String())
+		return "", fmt.Errorf("%s %s failed: %s; stdout: %s; stderr: %s", d.Executable, strings.Join(subcommand, " "), err, stdout.String(), stderr.String())
 	}
 
 	imageId, err := os.ReadFile(imageIdFilePath)
@@ -67,7 +92,7 @@ func (d *DockerDriver) Build(args []string) (string, error) {
 
 func (d *DockerDriver) DeleteImage(id string) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command(d.Executable, "rmi", id)
+	cmd := d.command("rmi", id)
 	cmd.Stderr = &stderr
 
 	log.Printf("Deleting image: %s", id)
@@ -101,7 +126,7 @@ func (d *DockerDriver) Commit(id string, author string, changes []string, messag
 	args = append(args, id)
 
 	log.Printf("Committing container with args: %v", args)
-	cmd := exec.Command(d.Executable, args...)
+	cmd := d.command(args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
@@ -118,9 +143,31 @@ func (d *DockerDriver) Commit(id string, author string, changes []string, messag
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+func (d *DockerDriver) CopyToContainer(id string, src string, dst string) error {
+	var stderr bytes.Buffer
+	cmd := d.command(copyArgs(id, src, dst)...)
+	cmd.Stderr = &stderr
+
+	log.Printf("Copying %s to %s:%s", src, id, dst)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error copying to the container: %s\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+// copyArgs returns the arguments of the cp command copying src to dst in the
+// container. The content of a directory is copied with a trailing `/.`, so
+// that dst is the copy whether it exists or not.
+func copyArgs(id, src, dst string) []string {
+	if info, err := os.Stat(src); err == nil && info.IsDir() {
+		src = filepath.Clean(src) + string(filepath.Separator) + "."
+	}
+	return []string{"cp", src, id + ":" + dst}
+}
+
 func (d *DockerDriver) Export(id string, dst io.Writer) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command(d.Executable, "export", id)
+	cmd := d.command("export", id)
 	cmd.Stdout = dst
 	cmd.Stderr = &stderr
 
@@ -138,6 +185,57 @@ func (d *DockerDriver) Export(id string, dst io.Writer) error {
 	return nil
 }
 
+func (d *DockerDriver) ImageConfig(id string) (*ImageConfig, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("inspect", "--type", "image", "--format", "{{json .Config}}", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	var config ImageConfig
+	if err := json.Unmarshal(stdout.Bytes(), &config); err != nil {
+		return nil, fmt.Errorf("Error reading the configuration of image %s: %s", id, err)
+	}
+
+	return &config, nil
+}
+
+func (d *DockerDriver) ImageMetadata(id string) (*ImageMetadata, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("inspect", "--type", "image", "--format", "{{json .}}", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	var metadata ImageMetadata
+	if err := json.Unmarshal(stdout.Bytes(), &metadata); err != nil {
+		return nil, fmt.Errorf("Error reading the metadata of image %s: %s", id, err)
+	}
+
+	return &metadata, nil
+}
+
+func (d *DockerDriver) ImagePlatform(id string) (string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("inspect", "--type", "image", "--format", "{{json .}}", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	var platform imagePlatform
+	if err := json.Unmarshal(stdout.Bytes(), &platform); err != nil {
+		return "", fmt.Errorf("Error reading the platform of image %s: %s", id, err)
+	}
+
+	return platform.String(), nil
+}
+
 func (d *DockerDriver) Import(path string, changes []string, repo string, platform string) (string, error) {
 	var stdout, stderr bytes.Buffer
 
@@ -152,9 +250,11 @@ func (d *DockerDriver) Import(path string, changes []string, repo string, platfo
 	}
 
 	args = append(args, "-")
-	args = append(args, repo)
+	if repo != "" {
+		args = append(args, repo)
+	}
 
-	cmd := exec.Command(d.Executable, args...)
+	cmd := d.command(args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	stdin, err := cmd.StdinPipe()
@@ -164,7 +264,7 @@ func (d *DockerDriver) Import(path string, changes []string, repo string, platfo
 	}
 
 	// There should be only one artifact of the Docker builder
-	file, err := os.Open(path)
+	file, err := openArchive(path)
 	if err != nil {
 		return "", err
 	}
@@ -191,8 +291,7 @@ func (d *DockerDriver) Import(path string, changes []string, repo string, platfo
 
 func (d *DockerDriver) IPAddress(id string) (string, error) {
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		d.Executable,
+	cmd := d.command(
 		"inspect",
 		"--format",
 		"{{ .NetworkSettings.IPAddress }}",
@@ -206,11 +305,111 @@ func (d *DockerDriver) IPAddress(id string) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+func (d *DockerDriver) PublishedPort(id string, port int) (int, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("port", id, fmt.Sprintf("%d/tcp", port))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	// The port is published on each address, like `0.0.0.0:49153` and
+	// `[::]:49153`, always on the same port.
+	binding, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	return parseHostPort(binding)
+}
+
+// parseHostPort returns the port of a binding like `0.0.0.0:49153`.
+func parseHostPort(binding string) (int, error) {
+	_, port, err := net.SplitHostPort(strings.TrimSpace(binding))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the published port %q: %s", binding, err)
+	}
+	return strconv.Atoi(port)
+}
+
+func (d *DockerDriver) OOMKilled(id string) (bool, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("inspect", "--format", "{{ .State.OOMKilled }}", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return false, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return strconv.ParseBool(strings.TrimSpace(stdout.String()))
+}
+
+func (d *DockerDriver) Logs(id string) (string, error) {
+	var stderr, logs bytes.Buffer
+	cmd := d.command("logs", id)
+	cmd.Stdout = &logs
+	cmd.Stderr = io.MultiWriter(&logs, &stderr)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return logs.String(), nil
+}
+
+func (d *DockerDriver) FollowLogs(ctx context.Context, id string, dst io.Writer) error {
+	var stderr bytes.Buffer
+	cmd := d.commandContext(ctx, "logs", "--follow", id)
+	cmd.Stdout = dst
+	cmd.Stderr = io.MultiWriter(dst, &stderr)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+func (d *DockerDriver) Diff(id string) ([]string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("diff", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return parseDiff(stdout.String()), nil
+}
+
+// parseDiff returns the added and changed paths of the output of `docker
+// diff`, whose lines are a path prefixed with `A`, `C` or `D`.
+func parseDiff(output string) []string {
+	var paths []string
+	for _, line := range strings.Split(output, "\n") {
+		kind, path, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok || kind == "D" {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func (d *DockerDriver) HealthStatus(id string) (string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("inspect", "--format", "{{ if .State.Health }}{{ .State.Health.Status }}{{ end }}", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
 // Sha256 retrieves the image Id using Docker inspect.
 func (d *DockerDriver) Sha256(id string) (string, error) {
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		d.Executable,
+	cmd := d.command(
 		"inspect",
 		"--format",
 		"{{ .Id }}",
@@ -232,8 +431,7 @@ func (d *DockerDriver) Sha256(id string) (string, error) {
 // at a specific point in time.
 func (d *DockerDriver) Digest(id string) (string, error) {
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		d.Executable,
+	cmd := d.command(
 		"inspect",
 		"--format",
 		"{{ ( index .RepoDigests 0 ) }}",
@@ -247,10 +445,25 @@ func (d *DockerDriver) Digest(id string) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+func (d *DockerDriver) RepoDigests(id string) ([]string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("inspect", "--type", "image", "--format", "{{json .RepoDigests}}", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	var digests []string
+	if err := json.Unmarshal(stdout.Bytes(), &digests); err != nil {
+		return nil, fmt.Errorf("Error reading the repo digests of image %s: %s", id, err)
+	}
+	return digests, nil
+}
+
 func (d *DockerDriver) Cmd(id string) (string, error) {
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		d.Executable,
+	cmd := d.command(
 		"inspect",
 		"--format",
 		"{{if .Config.Cmd}} {{json .Config.Cmd}} {{else}} [\"\"] {{end}}",
@@ -266,8 +479,7 @@ func (d *DockerDriver) Cmd(id string) (string, error) {
 
 func (d *DockerDriver) Entrypoint(id string) (string, error) {
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		d.Executable,
+	cmd := d.command(
 		"inspect",
 		"--format",
 		"{{if .Config.Entrypoint}} {{json .Config.Entrypoint}} {{else}} [\"\"] {{end}}",
@@ -350,6 +562,156 @@ func (d *DockerDriver) Logout(repo string) error {
 	return err
 }
 
+// ImageExists returns true if the image is present on the daemon.
+func (d *DockerDriver) ImageExists(image string) (bool, error) {
+	cmd := d.command("image", "inspect", image)
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// manifestNotFoundRe matches the errors of the registries for the manifests
+// they don't have.
+var manifestNotFoundRe = regexp.MustCompile(`(?i)no such manifest|manifest unknown|not found`)
+
+// RemoteImageExists inspects the manifest of the image in its registry,
+// which fails if the registry doesn't have it.
+func (d *DockerDriver) RemoteImageExists(name string) (bool, error) {
+	var stderr bytes.Buffer
+	cmd := d.newCommandWithConfig("manifest", "inspect", name)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	return runManifestInspect(cmd, &stderr)
+}
+
+// RemoteDigest inspects the image in its registry with buildx, since `docker
+// manifest inspect` doesn't report the digest of manifest lists.
+func (d *DockerDriver) RemoteDigest(name string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := d.newCommandWithConfig("buildx", "imagetools", "inspect", "--format", "{{json .Manifest}}", name)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if exists, err := runManifestInspect(cmd, &stderr); err != nil || !exists {
+		return "", err
+	}
+
+	var manifest struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &manifest); err != nil {
+		return "", fmt.Errorf("Error reading the manifest of %s: %s", name, err)
+	}
+	return manifest.Digest, nil
+}
+
+// runManifestInspect runs the command inspecting a manifest, and returns
+// whether the manifest was found.
+func runManifestInspect(cmd *exec.Cmd, stderr *bytes.Buffer) (bool, error) {
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok && manifestNotFoundRe.MatchString(stderr.String()) {
+			return false, nil
+		}
+		return false, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+	return true, nil
+}
+
+func (d *DockerDriver) ImagesWithLabel(label, value string) ([]string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("images", "--quiet", "--no-trunc", "--filter", "label="+label+"="+value)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	// An image is listed once per tag
+	var ids []string
+	seen := map[string]bool{}
+	for _, id := range strings.Fields(stdout.String()) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// NetworkExists inspects the network, which fails if it doesn't exist.
+func (d *DockerDriver) NetworkExists(name string) (bool, error) {
+	cmd := d.command("network", "inspect", name)
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d *DockerDriver) CreateNetwork(name string) error {
+	var stderr bytes.Buffer
+	cmd := d.command("network", "create", name)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error creating network: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+func (d *DockerDriver) RemoveNetwork(name string) error {
+	var stderr bytes.Buffer
+	cmd := d.command("network", "rm", name)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error removing network: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// VolumeExists inspects the volume, which fails if it doesn't exist.
+func (d *DockerDriver) VolumeExists(name string) (bool, error) {
+	cmd := d.command("volume", "inspect", name)
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d *DockerDriver) CreateVolume(name string) error {
+	var stderr bytes.Buffer
+	cmd := d.command("volume", "create", name)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error creating volume: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+func (d *DockerDriver) RemoveVolume(name string) error {
+	var stderr bytes.Buffer
+	cmd := d.command("volume", "rm", name)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error removing volume: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
 func (d *DockerDriver) Pull(image string, platform string) error {
 	cmd := d.newCommandWithConfig("pull", image)
 
@@ -370,9 +732,106 @@ func (d *DockerDriver) Push(name string, platform string) error {
 	return runAndStream(cmd, d.Ui)
 }
 
+// Rootless reports whether the daemon runs in rootless mode, which it lists
+// in its security options.
+func (d *DockerDriver) Rootless() (bool, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("info", "--format", "{{json .SecurityOptions}}")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return false, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	var securityOptions []string
+	if err := json.Unmarshal(stdout.Bytes(), &securityOptions); err != nil {
+		return false, fmt.Errorf("failed to read the security options of the daemon: %s", err)
+	}
+
+	return isRootless(securityOptions), nil
+}
+
+func (d *DockerDriver) RunPrivileged(image string, args []string) (string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command(append([]string{"run", "--rm", "--privileged", image}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error running %s: %s\n\nStderr: %s", image, err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+func (d *DockerDriver) ServerPlatform() (string, error) {
+	return d.serverPlatform("version", "--format", "{{.Server.Os}}/{{.Server.Arch}}")
+}
+
+// serverPlatform runs the command, which is expected to print the platform
+// of the host of the daemon.
+func (d *DockerDriver) serverPlatform(args ...string) (string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command(args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// isRootless returns true if the security options of the daemon include
+// rootless, like `name=rootless`.
+func isRootless(securityOptions []string) bool {
+	for _, opt := range securityOptions {
+		if strings.Contains(opt, "name=rootless") {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DockerDriver) Runtimes() ([]string, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("info", "--format", "{{json .Runtimes}}")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	var runtimes map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &runtimes); err != nil {
+		return nil, fmt.Errorf("failed to read the runtimes of the daemon: %s", err)
+	}
+
+	return runtimeNames(runtimes), nil
+}
+
+// sortedKeys returns the keys of the map in order, so that the arguments
+// or errors built from it are always the same.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runtimeNames returns the sorted names of the runtimes listed by the
+// daemon.
+func runtimeNames(runtimes map[string]interface{}) []string {
+	names := make([]string, 0, len(runtimes))
+	for name := range runtimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (d *DockerDriver) SaveImage(id string, dst io.Writer) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command(d.Executable, "save", id)
+	cmd := d.command("save", id)
 	cmd.Stdout = dst
 	cmd.Stderr = &stderr
 
@@ -390,18 +849,35 @@ func (d *DockerDriver) SaveImage(id string, dst io.Writer) error {
 	return nil
 }
 
+// SaveOCIImage saves the image with `docker save`, whose archives are OCI
+// image layouts since Docker 25.
+func (d *DockerDriver) SaveOCIImage(id string, dst io.Writer) error {
+	return d.SaveImage(id, dst)
+}
+
 func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 	// Build up the template data
-	var tplData startContainerTemplate
-	tplData.Image = config.Image
-	ictx := *d.Ctx
-	ictx.Data = &tplData
+	ictx := runCommandContext(d.Ctx, config)
 
 	// Args that we're going to pass to Docker
 	args := []string{"run"}
+	if config.Name != "" {
+		args = append(args, "--name", config.Name)
+	}
+	if len(config.Entrypoint) > 0 {
+		// --entrypoint only takes the executable, its arguments are given
+		// before the command.
+		args = append(args, "--entrypoint", config.Entrypoint[0])
+	}
 	for _, v := range config.Device {
 		args = append(args, "--device", v)
 	}
+	for _, v := range config.DeviceCgroupRules {
+		args = append(args, "--device-cgroup-rule", v)
+	}
+	if config.Gpus != "" {
+		args = append(args, "--gpus", config.Gpus)
+	}
 	for _, v := range config.CapAdd {
 		args = append(args, "--cap-add", v)
 	}
@@ -411,12 +887,101 @@ func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 	if config.Privileged {
 		args = append(args, "--privileged")
 	}
+	if config.Init {
+		args = append(args, "--init")
+	}
+	for _, v := range config.SecurityOpts {
+		args = append(args, "--security-opt", v)
+	}
+	for _, name := range sortedKeys(config.Ulimits) {
+		args = append(args, "--ulimit", fmt.Sprintf("%s=%s", name, config.Ulimits[name]))
+	}
+	if config.Cpus != "" {
+		args = append(args, "--cpus", config.Cpus)
+	}
+	if config.CpusetCpus != "" {
+		args = append(args, "--cpuset-cpus", config.CpusetCpus)
+	}
+	if config.Memory != "" {
+		args = append(args, "--memory", config.Memory)
+	}
+	if config.MemorySwap != "" {
+		args = append(args, "--memory-swap", config.MemorySwap)
+	}
+	if config.ShmSize != "" {
+		args = append(args, "--shm-size", config.ShmSize)
+	}
+	if config.OomScoreAdj != 0 {
+		args = append(args, "--oom-score-adj", strconv.Itoa(config.OomScoreAdj))
+	}
+	if config.OomKillDisable {
+		args = append(args, "--oom-kill-disable")
+	}
+	for _, k := range sortedKeys(config.Labels) {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, config.Labels[k]))
+	}
+	// Only the names are given to docker run, which reads the values from
+	// its environment, so that they don't show in the logs.
+	for _, k := range sortedKeys(config.Env) {
+		args = append(args, "--env", k)
+	}
 	if config.Runtime != "" {
 		args = append(args, "--runtime", config.Runtime)
 	}
+	if config.Userns != "" {
+		args = append(args, "--userns", config.Userns)
+	}
+	if config.IpcMode != "" {
+		args = append(args, "--ipc", config.IpcMode)
+	}
+	if config.PidMode != "" {
+		args = append(args, "--pid", config.PidMode)
+	}
+	if config.CgroupnsMode != "" {
+		args = append(args, "--cgroupns", config.CgroupnsMode)
+	}
+	if config.CgroupParent != "" {
+		args = append(args, "--cgroup-parent", config.CgroupParent)
+	}
+	for _, v := range config.UIDMap {
+		args = append(args, "--uidmap", v)
+	}
+	for _, v := range config.GIDMap {
+		args = append(args, "--gidmap", v)
+	}
 	if config.Platform != "" {
 		args = append(args, "--platform", config.Platform)
 	}
+	for _, v := range config.Publish {
+		args = append(args, "--publish", v)
+	}
+	if config.Network != "" {
+		args = append(args, "--network", config.Network)
+	}
+	for _, v := range config.NetworkAliases {
+		args = append(args, "--network-alias", v)
+	}
+	for _, v := range config.ExtraHosts {
+		args = append(args, "--add-host", v)
+	}
+	for _, v := range config.Dns {
+		args = append(args, "--dns", v)
+	}
+	for _, v := range config.DnsSearch {
+		args = append(args, "--dns-search", v)
+	}
+	for _, v := range config.DnsOptions {
+		args = append(args, "--dns-option", v)
+	}
+	if config.Hostname != "" {
+		args = append(args, "--hostname", config.Hostname)
+	}
+	if config.Domainname != "" {
+		args = append(args, "--domainname", config.Domainname)
+	}
+	if config.ReadOnly {
+		args = append(args, "--read-only")
+	}
 	for _, v := range config.TmpFs {
 		args = append(args, "--tmpfs", v)
 	}
@@ -427,6 +992,9 @@ func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 		}
 		args = append(args, "-v", fmt.Sprintf("%s:%s", host, guest))
 	}
+	for _, m := range config.Mounts {
+		args = append(args, "--mount", m.flag())
+	}
 	for _, v := range config.RunCommand {
 		v, err := interpolate.Render(v, &ictx)
 		if err != nil {
@@ -435,14 +1003,26 @@ func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 
 		args = append(args, v)
 	}
+	if len(config.Entrypoint) > 0 {
+		args = append(args, config.Entrypoint[1:]...)
+	}
+	args = append(args, config.Cmd...)
 	d.Ui.Message(fmt.Sprintf(
 		"Run command: %s %s", d.Executable, strings.Join(args, " ")))
 
 	// Start the container
 	var stdout, stderr bytes.Buffer
-	cmd := exec.Command(d.Executable, args...)
+	cmd := d.command(args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if len(config.Env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		for _, k := range sortedKeys(config.Env) {
+			cmd.Env = append(cmd.Env, k+"="+config.Env[k])
+		}
+	}
 
 	log.Printf("Starting container with args: %v", args)
 	if err := cmd.Start(); err != nil {
@@ -463,19 +1043,84 @@ func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
-func (d *DockerDriver) StopContainer(id string) error {
-	if err := exec.Command(d.Executable, "stop", id).Run(); err != nil {
+func (d *DockerDriver) StopContainer(id string, signal string, timeout time.Duration) error {
+	if err := d.command(stopArgs(id, signal, timeout)...).Run(); err != nil {
 		return err
 	}
 	return nil
 }
 
+// stopArgs returns the arguments of `docker stop`, whose timeout is in
+// seconds.
+func stopArgs(id string, signal string, timeout time.Duration) []string {
+	args := []string{"stop"}
+	if signal != "" {
+		args = append(args, "--signal", signal)
+	}
+	if timeout > 0 {
+		args = append(args, "--time", strconv.Itoa(stopTimeoutSeconds(timeout)))
+	}
+	return append(args, id)
+}
+
+func (d *DockerDriver) CheckpointContainer(id string, name string, dir string) error {
+	var stderr bytes.Buffer
+	cmd := d.command("checkpoint", "create", "--leave-running", "--checkpoint-dir", dir, id, name)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error checkpointing container: %s\n\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+func (d *DockerDriver) RestoreContainer(id string, name string, dir string) error {
+	var stderr bytes.Buffer
+	cmd := d.command("start", "--checkpoint", name, "--checkpoint-dir", dir, id)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error restoring container: %s\n\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+func (d *DockerDriver) PauseContainer(id string) error {
+	var stderr bytes.Buffer
+	cmd := d.command("pause", id)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error pausing container: %s\n\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+func (d *DockerDriver) UnpauseContainer(id string) error {
+	var stderr bytes.Buffer
+	cmd := d.command("unpause", id)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error unpausing container: %s\n\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+// containerNotRunningRe matches the errors of killing a container that
+// isn't running, like one stopped before the commit.
+var containerNotRunningRe = regexp.MustCompile(`(?i)is not running|can only kill running containers`)
+
 func (d *DockerDriver) KillContainer(id string) error {
-	if err := exec.Command(d.Executable, "kill", id).Run(); err != nil {
-		return err
+	// A container stopped before the commit can't be killed, but must be
+	// removed all the same.
+	var stderr bytes.Buffer
+	cmd := d.command("kill", id)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if !containerNotRunningRe.MatchString(stderr.String()) {
+			return fmt.Errorf("Error killing container: %s\n\nStderr: %s", err, stderr.String())
+		}
+		log.Printf("The container %s isn't running, removing it", id)
 	}
 
-	return exec.Command(d.Executable, "rm", id).Run()
+	return d.command("rm", id).Run()
 }
 
 func (d *DockerDriver) TagImage(id string, repo string, force bool) error {
@@ -512,7 +1157,7 @@ func (d *DockerDriver) TagImage(id string, repo string, force bool) error {
 	args = append(args, id, repo)
 
 	var stderr bytes.Buffer
-	cmd := exec.Command(d.Executable, args...)
+	cmd := d.command(args...)
 	cmd.Stderr = &stderr
 
 	if err := cmd.Start(); err != nil {
@@ -537,7 +1182,7 @@ func (d *DockerDriver) Verify() error {
 }
 
 func (d *DockerDriver) Version() (*version.Version, error) {
-	output, err := exec.Command(d.Executable, "-v").Output()
+	output, err := d.command("-v").Output()
 	if err != nil {
 		return nil, err
 	}
@@ -552,8 +1197,40 @@ func (d *DockerDriver) Version() (*version.Version, error) {
 	return version.NewVersion(string(match[0]))
 }
 
+func (d *DockerDriver) SetContext(ctx context.Context) {
+	d.cmdCtx = ctx
+}
+
+// execCommand returns a command running the executable, which is killed
+// once the context of the driver is done.
+func (d *DockerDriver) execCommand(args ...string) *exec.Cmd {
+	ctx := d.cmdCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return exec.CommandContext(ctx, d.Executable, args...)
+}
+
+// command returns a command running the executable against the configured
+// daemon.
+func (d *DockerDriver) command(args ...string) *exec.Cmd {
+	return d.commandContext(d.cmdCtx, args...)
+}
+
+// commandContext returns a command like command, which is killed once the
+// given context is done rather than the one of the driver.
+func (d *DockerDriver) commandContext(ctx context.Context, args ...string) *exec.Cmd {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, d.Executable, args...)
+	d.HostConfig.Apply(cmd)
+	d.Proxy.Apply(cmd)
+	return cmd
+}
+
 func (d *DockerDriver) newCommandWithConfig(args ...string) *exec.Cmd {
-	cmd := exec.Command(d.Executable)
+	cmd := d.command()
 
 	if d.ConfigDir != "" {
 		cmd.Args = append(cmd.Args, "--config", d.ConfigDir)
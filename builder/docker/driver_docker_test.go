@@ -3,8 +3,108 @@
 
 package docker
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
 
 func TestDockerDriver_impl(t *testing.T) {
 	var _ Driver = new(DockerDriver)
 }
+
+func TestIsRootless(t *testing.T) {
+	if !isRootless([]string{"name=seccomp,profile=builtin", "name=rootless", "name=cgroupns"}) {
+		t.Fatal("should be rootless")
+	}
+	if isRootless([]string{"name=seccomp,profile=builtin", "name=cgroupns"}) {
+		t.Fatal("should not be rootless")
+	}
+}
+
+func TestParseHostPort(t *testing.T) {
+	for binding, expected := range map[string]int{
+		"0.0.0.0:49153": 49153,
+		"[::]:49153":    49153,
+	} {
+		port, err := parseHostPort(binding)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if port != expected {
+			t.Errorf("parseHostPort(%q): expected %d, got %d", binding, expected, port)
+		}
+	}
+
+	if _, err := parseHostPort(""); err == nil {
+		t.Fatal("should error without a port")
+	}
+}
+
+func TestParseDiff(t *testing.T) {
+	output := "C /etc\nA /etc/app.conf\nD /tmp/build\n"
+	expected := []string{"/etc", "/etc/app.conf"}
+	if paths := parseDiff(output); !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("bad paths: %v", paths)
+	}
+	if paths := parseDiff(""); len(paths) != 0 {
+		t.Fatalf("bad paths: %v", paths)
+	}
+}
+
+func TestStopArgs(t *testing.T) {
+	if args := stopArgs("foo", "", 0); !reflect.DeepEqual(args, []string{"stop", "foo"}) {
+		t.Fatalf("bad args: %v", args)
+	}
+	expected := []string{"stop", "--signal", "SIGINT", "--time", "2", "foo"}
+	if args := stopArgs("foo", "SIGINT", 1500*time.Millisecond); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad args: %v", args)
+	}
+}
+
+func TestCopyArgs(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.tar.gz")
+	if err := os.WriteFile(file, []byte("app"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"cp", file, "foo:/opt/app.tar.gz"}
+	if args := copyArgs("foo", file, "/opt/app.tar.gz"); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad args: %v", args)
+	}
+	expected = []string{"cp", dir + string(filepath.Separator) + ".", "foo:/srv/models"}
+	if args := copyArgs("foo", dir+string(filepath.Separator), "/srv/models"); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad args: %v", args)
+	}
+}
+
+func TestRunCommandContext(t *testing.T) {
+	t.Setenv("PACKER_RUN_UUID", "1234")
+
+	ctx := &interpolate.Context{
+		BuildName:     "docker.ubuntu",
+		UserVariables: map[string]string{"suffix": "ci"},
+	}
+	ictx := runCommandContext(ctx, &ContainerConfig{
+		Image:    "ubuntu",
+		Platform: "linux/arm64",
+	})
+
+	tpl := "--name={{.BuildName}}-{{.RunUUID}}-{{.Vars.suffix}}-{{user `suffix`}} --platform={{.Platform}} {{.Image}}"
+	result, err := interpolate.Render(tpl, &ictx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := "--name=docker.ubuntu-1234-ci-ci --platform=linux/arm64 ubuntu"
+	if result != expected {
+		t.Fatalf("expected %q, got %q", expected, result)
+	}
+	if ctx.Data != nil {
+		t.Fatal("the context of the driver shouldn't be modified")
+	}
+}
@@ -4,8 +4,11 @@
 package docker
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-version"
 )
@@ -13,20 +16,52 @@ import (
 // MockDriver is a driver implementation that can be used for tests.
 type MockDriver struct {
 	BuildCalled     bool
+	BuildXCalled    bool
 	BuildImageId    string
 	BuildImageError error
+	BuildArgs       []string
+
+	CmdCalled bool
+	CmdResult string
+	CmdErr    error
 
 	CommitCalled      bool
 	CommitContainerId string
 	CommitImageId     string
+	CommitChanges     []string
 	CommitErr         error
 
+	CopyToContainerCalled bool
+	CopyToContainerSrc    []string
+	CopyToContainerDst    []string
+	CopyToContainerErr    error
+
+	EntrypointCalled bool
+	EntrypointResult string
+	EntrypointErr    error
+
 	DeleteImageCalled bool
 	DeleteImageId     string
 	DeleteImageErr    error
 
+	ImageConfigCalled bool
+	ImageConfigId     string
+	ImageConfigResult *ImageConfig
+	ImageConfigErr    error
+
+	ImageMetadataCalled bool
+	ImageMetadataId     string
+	ImageMetadataResult *ImageMetadata
+	ImageMetadataErr    error
+
+	ImagePlatformCalled bool
+	ImagePlatformId     string
+	ImagePlatformResult string
+	ImagePlatformErr    error
+
 	ImportCalled   bool
 	ImportPath     string
+	ImportChanges  []string
 	ImportRepo     string
 	ImportId       string
 	ImportPlatform string
@@ -37,6 +72,44 @@ type MockDriver struct {
 	IPAddressResult string
 	IPAddressErr    error
 
+	OOMKilledCalled bool
+	OOMKilledResult bool
+	OOMKilledErr    error
+
+	// The logs returned by Logs, one per call, the last one being returned
+	// once the others are.
+	LogsResults []string
+	LogsCount   int
+	LogsErr     error
+
+	FollowLogsCalled bool
+	FollowLogsID     string
+	FollowLogsResult string
+	FollowLogsErr    error
+
+	ImagesWithLabelCalled bool
+	ImagesWithLabelLabel  string
+	ImagesWithLabelValue  string
+	ImagesWithLabelResult []string
+	ImagesWithLabelErr    error
+
+	DiffCalled bool
+	DiffID     string
+	DiffResult []string
+	DiffErr    error
+
+	// The statuses returned by HealthStatus, one per call, the last one
+	// being returned once the others are.
+	HealthStatusResults []string
+	HealthStatusCount   int
+	HealthStatusErr     error
+
+	PublishedPortCalled bool
+	PublishedPortId     string
+	PublishedPortPort   int
+	PublishedPortResult int
+	PublishedPortErr    error
+
 	Sha256Called bool
 	Sha256Id     string
 	Sha256Result string
@@ -47,6 +120,11 @@ type MockDriver struct {
 	DigestResult string
 	DigestErr    error
 
+	RepoDigestsCalled bool
+	RepoDigestsId     string
+	RepoDigestsResult []string
+	RepoDigestsErr    error
+
 	KillCalled bool
 	KillID     string
 	KillError  error
@@ -55,28 +133,96 @@ type MockDriver struct {
 	LoginUsername string
 	LoginPassword string
 	LoginRepo     string
+	LoginRepos    []string
 	LoginErr      error
 
 	LogoutCalled bool
 	LogoutRepo   string
 	LogoutErr    error
 
+	ImageExistsCalled bool
+	ImageExistsName   string
+	ImageExistsResult bool
+	ImageExistsErr    error
+
+	// RemoteImageExistsResult lists the images in their registry.
+	RemoteImageExistsNames  []string
+	RemoteImageExistsResult map[string]bool
+	RemoteImageExistsErr    error
+
+	RemoteDigestResult map[string]string
+	RemoteDigestErr    error
+
+	NetworkExistsCalled bool
+	NetworkExistsName   string
+	NetworkExistsResult bool
+	NetworkExistsErr    error
+
+	CreateNetworkCalled bool
+	CreateNetworkName   string
+	CreateNetworkErr    error
+
+	RemoveNetworkCalled bool
+	RemoveNetworkName   string
+	RemoveNetworkErr    error
+
+	VolumeExistsResult map[string]bool
+	VolumeExistsErr    error
+
+	CreateVolumeNames []string
+	CreateVolumeErr   error
+
+	RemoveVolumeNames []string
+	RemoveVolumeErr   error
+
 	PushCalled   bool
 	PushName     string
+	PushNames    []string
 	PushPlatform string
 	PushErr      error
+	// PushErrs are returned by the first pushes, before PushErr.
+	PushErrs []error
+	pushLock sync.Mutex
+
+	RootlessCalled bool
+	RootlessResult bool
+	RootlessErr    error
+
+	RunPrivilegedCalled bool
+	RunPrivilegedImage  string
+	RunPrivilegedArgs   []string
+	RunPrivilegedErr    error
+
+	RuntimesCalled bool
+	RuntimesResult []string
+	RuntimesErr    error
+
+	ServerPlatformCalled bool
+	ServerPlatformResult string
+	ServerPlatformErr    error
+
+	SetContextCalled bool
+	SetContextCtx    context.Context
 
 	SaveImageCalled bool
 	SaveImageId     string
 	SaveImageReader io.Reader
 	SaveImageError  error
 
+	SaveOCIImageCalled bool
+	SaveOCIImageId     string
+	SaveOCIImageReader io.Reader
+	SaveOCIImageError  error
+
 	TagImageCalled  int
 	TagImageImageId string
 	TagImageRepo    []string
 	TagImageForce   bool
 	TagImageErr     error
 
+	// The errors of the first pulls, before PullError is returned.
+	PullErrors []error
+
 	ExportReader io.Reader
 	ExportError  error
 	PullError    error
@@ -90,18 +236,56 @@ type MockDriver struct {
 	PullCalled   bool
 	PullImage    string
 	PullPlatform string
+	PullCount    int
+	PulledImages []string
+	pullLock     sync.Mutex
 	StartCalled  bool
 	StartConfig  *ContainerConfig
 	StopCalled   bool
 	StopID       string
+	StopSignal   string
+	StopTimeout  time.Duration
 	VerifyCalled bool
 
+	CheckpointCalled bool
+	CheckpointID     string
+	CheckpointNames  []string
+	CheckpointDir    string
+	CheckpointErr    error
+	RestoreCalled    bool
+	RestoreID        string
+	RestoreName      string
+	RestoreDir       string
+	RestoreErr       error
+
+	PauseCalled   bool
+	PauseID       string
+	PauseError    error
+	UnpauseCalled bool
+	UnpauseID     string
+	UnpauseError  error
+
 	VersionCalled  bool
 	VersionVersion string
 }
 
 func (d *MockDriver) Build(args []string) (string, error) {
 	d.BuildCalled = true
+	d.BuildArgs = args
+
+	if d.BuildImageError != nil {
+		return "", d.BuildImageError
+	}
+
+	if d.BuildImageId == "" {
+		return "", fmt.Errorf("missing config argument for mock driver: BuildImageId")
+	}
+
+	return d.BuildImageId, nil
+}
+
+func (d *MockDriver) BuildX(args []string) (string, error) {
+	d.BuildXCalled = true
 
 	if d.BuildImageError != nil {
 		return "", d.BuildImageError
@@ -114,18 +298,36 @@ func (d *MockDriver) Build(args []string) (string, error) {
 	return d.BuildImageId, nil
 }
 
+func (d *MockDriver) Cmd(id string) (string, error) {
+	d.CmdCalled = true
+	return d.CmdResult, d.CmdErr
+}
+
 func (d *MockDriver) Commit(id string, author string, changes []string, message string) (string, error) {
 	d.CommitCalled = true
 	d.CommitContainerId = id
+	d.CommitChanges = changes
 	return d.CommitImageId, d.CommitErr
 }
 
+func (d *MockDriver) CopyToContainer(id string, src string, dst string) error {
+	d.CopyToContainerCalled = true
+	d.CopyToContainerSrc = append(d.CopyToContainerSrc, src)
+	d.CopyToContainerDst = append(d.CopyToContainerDst, dst)
+	return d.CopyToContainerErr
+}
+
 func (d *MockDriver) DeleteImage(id string) error {
 	d.DeleteImageCalled = true
 	d.DeleteImageId = id
 	return d.DeleteImageErr
 }
 
+func (d *MockDriver) Entrypoint(id string) (string, error) {
+	d.EntrypointCalled = true
+	return d.EntrypointResult, d.EntrypointErr
+}
+
 func (d *MockDriver) Export(id string, dst io.Writer) error {
 	d.ExportCalled = true
 	d.ExportID = id
@@ -140,9 +342,34 @@ func (d *MockDriver) Export(id string, dst io.Writer) error {
 	return d.ExportError
 }
 
+func (d *MockDriver) ImageConfig(id string) (*ImageConfig, error) {
+	d.ImageConfigCalled = true
+	d.ImageConfigId = id
+	if d.ImageConfigResult == nil && d.ImageConfigErr == nil {
+		return &ImageConfig{}, nil
+	}
+	return d.ImageConfigResult, d.ImageConfigErr
+}
+
+func (d *MockDriver) ImageMetadata(id string) (*ImageMetadata, error) {
+	d.ImageMetadataCalled = true
+	d.ImageMetadataId = id
+	if d.ImageMetadataResult == nil && d.ImageMetadataErr == nil {
+		return &ImageMetadata{}, nil
+	}
+	return d.ImageMetadataResult, d.ImageMetadataErr
+}
+
+func (d *MockDriver) ImagePlatform(id string) (string, error) {
+	d.ImagePlatformCalled = true
+	d.ImagePlatformId = id
+	return d.ImagePlatformResult, d.ImagePlatformErr
+}
+
 func (d *MockDriver) Import(path string, changes []string, repo string, platform string) (string, error) {
 	d.ImportCalled = true
 	d.ImportPath = path
+	d.ImportChanges = changes
 	d.ImportRepo = repo
 	d.ImportPlatform = platform
 	return d.ImportId, d.ImportErr
@@ -154,6 +381,64 @@ func (d *MockDriver) IPAddress(id string) (string, error) {
 	return d.IPAddressResult, d.IPAddressErr
 }
 
+func (d *MockDriver) OOMKilled(id string) (bool, error) {
+	d.OOMKilledCalled = true
+	return d.OOMKilledResult, d.OOMKilledErr
+}
+
+func (d *MockDriver) Logs(id string) (string, error) {
+	d.LogsCount++
+	if len(d.LogsResults) == 0 {
+		return "", d.LogsErr
+	}
+	logs := d.LogsResults[0]
+	if len(d.LogsResults) > 1 {
+		d.LogsResults = d.LogsResults[1:]
+	}
+	return logs, d.LogsErr
+}
+
+func (d *MockDriver) FollowLogs(ctx context.Context, id string, dst io.Writer) error {
+	d.FollowLogsCalled = true
+	d.FollowLogsID = id
+	if _, err := io.WriteString(dst, d.FollowLogsResult); err != nil {
+		return err
+	}
+	return d.FollowLogsErr
+}
+
+func (d *MockDriver) ImagesWithLabel(label, value string) ([]string, error) {
+	d.ImagesWithLabelCalled = true
+	d.ImagesWithLabelLabel = label
+	d.ImagesWithLabelValue = value
+	return d.ImagesWithLabelResult, d.ImagesWithLabelErr
+}
+
+func (d *MockDriver) Diff(id string) ([]string, error) {
+	d.DiffCalled = true
+	d.DiffID = id
+	return d.DiffResult, d.DiffErr
+}
+
+func (d *MockDriver) HealthStatus(id string) (string, error) {
+	d.HealthStatusCount++
+	if len(d.HealthStatusResults) == 0 {
+		return "", d.HealthStatusErr
+	}
+	status := d.HealthStatusResults[0]
+	if len(d.HealthStatusResults) > 1 {
+		d.HealthStatusResults = d.HealthStatusResults[1:]
+	}
+	return status, d.HealthStatusErr
+}
+
+func (d *MockDriver) PublishedPort(id string, port int) (int, error) {
+	d.PublishedPortCalled = true
+	d.PublishedPortId = id
+	d.PublishedPortPort = port
+	return d.PublishedPortResult, d.PublishedPortErr
+}
+
 func (d *MockDriver) Sha256(id string) (string, error) {
 	d.Sha256Called = true
 	d.Sha256Id = id
@@ -166,9 +451,16 @@ func (d *MockDriver) Digest(id string) (string, error) {
 	return d.DigestResult, d.DigestErr
 }
 
+func (d *MockDriver) RepoDigests(id string) ([]string, error) {
+	d.RepoDigestsCalled = true
+	d.RepoDigestsId = id
+	return d.RepoDigestsResult, d.RepoDigestsErr
+}
+
 func (d *MockDriver) Login(r, u, p string) error {
 	d.LoginCalled = true
 	d.LoginRepo = r
+	d.LoginRepos = append(d.LoginRepos, r)
 	d.LoginUsername = u
 	d.LoginPassword = p
 	return d.LoginErr
@@ -180,20 +472,114 @@ func (d *MockDriver) Logout(r string) error {
 	return d.LogoutErr
 }
 
+func (d *MockDriver) RemoteImageExists(name string) (bool, error) {
+	d.pushLock.Lock()
+	defer d.pushLock.Unlock()
+	d.RemoteImageExistsNames = append(d.RemoteImageExistsNames, name)
+	return d.RemoteImageExistsResult[name], d.RemoteImageExistsErr
+}
+
+func (d *MockDriver) RemoteDigest(name string) (string, error) {
+	return d.RemoteDigestResult[name], d.RemoteDigestErr
+}
+
+func (d *MockDriver) ImageExists(image string) (bool, error) {
+	d.ImageExistsCalled = true
+	d.ImageExistsName = image
+	return d.ImageExistsResult, d.ImageExistsErr
+}
+
+func (d *MockDriver) NetworkExists(name string) (bool, error) {
+	d.NetworkExistsCalled = true
+	d.NetworkExistsName = name
+	return d.NetworkExistsResult, d.NetworkExistsErr
+}
+
+func (d *MockDriver) CreateNetwork(name string) error {
+	d.CreateNetworkCalled = true
+	d.CreateNetworkName = name
+	return d.CreateNetworkErr
+}
+
+func (d *MockDriver) RemoveNetwork(name string) error {
+	d.RemoveNetworkCalled = true
+	d.RemoveNetworkName = name
+	return d.RemoveNetworkErr
+}
+
+func (d *MockDriver) VolumeExists(name string) (bool, error) {
+	return d.VolumeExistsResult[name], d.VolumeExistsErr
+}
+
+func (d *MockDriver) CreateVolume(name string) error {
+	d.CreateVolumeNames = append(d.CreateVolumeNames, name)
+	return d.CreateVolumeErr
+}
+
+func (d *MockDriver) RemoveVolume(name string) error {
+	d.RemoveVolumeNames = append(d.RemoveVolumeNames, name)
+	return d.RemoveVolumeErr
+}
+
 func (d *MockDriver) Pull(image string, platform string) error {
+	d.pullLock.Lock()
+	defer d.pullLock.Unlock()
+
 	d.PullCalled = true
+	d.PulledImages = append(d.PulledImages, image)
 	d.PullImage = image
 	d.PullPlatform = platform
+	d.PullCount++
+	if len(d.PullErrors) > 0 {
+		err := d.PullErrors[0]
+		d.PullErrors = d.PullErrors[1:]
+		return err
+	}
 	return d.PullError
 }
 
 func (d *MockDriver) Push(name string, platform string) error {
+	d.pushLock.Lock()
+	defer d.pushLock.Unlock()
 	d.PushCalled = true
 	d.PushName = name
+	d.PushNames = append(d.PushNames, name)
 	d.PushPlatform = platform
+	if len(d.PushErrs) > 0 {
+		err := d.PushErrs[0]
+		d.PushErrs = d.PushErrs[1:]
+		return err
+	}
 	return d.PushErr
 }
 
+func (d *MockDriver) Rootless() (bool, error) {
+	d.RootlessCalled = true
+	return d.RootlessResult, d.RootlessErr
+}
+
+func (d *MockDriver) RunPrivileged(image string, args []string) (string, error) {
+	d.RunPrivilegedCalled = true
+	d.RunPrivilegedImage = image
+	d.RunPrivilegedArgs = args
+	return "", d.RunPrivilegedErr
+}
+
+func (d *MockDriver) Runtimes() ([]string, error) {
+	d.RuntimesCalled = true
+	return d.RuntimesResult, d.RuntimesErr
+}
+
+func (d *MockDriver) ServerPlatform() (string, error) {
+	d.ServerPlatformCalled = true
+	return d.ServerPlatformResult, d.ServerPlatformErr
+}
+
+func (d *MockDriver) SetContext(ctx context.Context) {
+	d.SetContextCalled = true
+	d.SetContextCtx = ctx
+}
+
 func (d *MockDriver) SaveImage(id string, dst io.Writer) error {
 	d.SaveImageCalled = true
 	d.SaveImageId = id
@@ -208,6 +594,20 @@ func (d *MockDriver) SaveImage(id string, dst io.Writer) error {
 	return d.SaveImageError
 }
 
+func (d *MockDriver) SaveOCIImage(id string, dst io.Writer) error {
+	d.SaveOCIImageCalled = true
+	d.SaveOCIImageId = id
+
+	if d.SaveOCIImageReader != nil {
+		_, err := io.Copy(dst, d.SaveOCIImageReader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.SaveOCIImageError
+}
+
 func (d *MockDriver) StartContainer(config *ContainerConfig) (string, error) {
 	d.StartCalled = true
 	d.StartConfig = config
@@ -220,9 +620,39 @@ func (d *MockDriver) KillContainer(id string) error {
 	return d.KillError
 }
 
-func (d *MockDriver) StopContainer(id string) error {
+func (d *MockDriver) CheckpointContainer(id string, name string, dir string) error {
+	d.CheckpointCalled = true
+	d.CheckpointID = id
+	d.CheckpointNames = append(d.CheckpointNames, name)
+	d.CheckpointDir = dir
+	return d.CheckpointErr
+}
+
+func (d *MockDriver) RestoreContainer(id string, name string, dir string) error {
+	d.RestoreCalled = true
+	d.RestoreID = id
+	d.RestoreName = name
+	d.RestoreDir = dir
+	return d.RestoreErr
+}
+
+func (d *MockDriver) PauseContainer(id string) error {
+	d.PauseCalled = true
+	d.PauseID = id
+	return d.PauseError
+}
+
+func (d *MockDriver) UnpauseContainer(id string) error {
+	d.UnpauseCalled = true
+	d.UnpauseID = id
+	return d.UnpauseError
+}
+
+func (d *MockDriver) StopContainer(id string, signal string, timeout time.Duration) error {
 	d.StopCalled = true
 	d.StopID = id
+	d.StopSignal = signal
+	d.StopTimeout = timeout
 	return d.StopError
 }
 
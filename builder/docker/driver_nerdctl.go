@@ -0,0 +1,309 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// NerdctlDriver is a Driver that runs commands with nerdctl, on hosts that
+// run containerd without dockerd.
+//
+// nerdctl aims to be compatible with the docker CLI, so this reuses the
+// DockerDriver and only overrides the commands where both CLIs differ.
+// Since nerdctl gained commands over time, the commands that are not
+// available in every release are detected before being run.
+type NerdctlDriver struct {
+	DockerDriver
+
+	capabilitiesLock sync.Mutex
+	capabilities     map[string]bool
+}
+
+// supports reports whether the nerdctl executable implements the given
+// subcommand. The result is cached for the lifetime of the driver.
+func (d *NerdctlDriver) supports(subcommand string) bool {
+	d.capabilitiesLock.Lock()
+	defer d.capabilitiesLock.Unlock()
+
+	if d.capabilities == nil {
+		d.capabilities = map[string]bool{}
+	}
+
+	if supported, ok := d.capabilities[subcommand]; ok {
+		return supported
+	}
+
+	args := append(strings.Fields(subcommand), "--help")
+	err := d.execCommand(args...).Run()
+	if err != nil {
+		log.Printf("nerdctl %s is not supported: %s", subcommand, err)
+	}
+
+	d.capabilities[subcommand] = err == nil
+	return err == nil
+}
+
+func (d *NerdctlDriver) requires(subcommand string) error {
+	if !d.supports(subcommand) {
+		return fmt.Errorf("%s does not support `nerdctl %s`, a more recent release of nerdctl is required",
+			d.Executable, subcommand)
+	}
+	return nil
+}
+
+// nerdctl has no --config global option, the client configuration
+// directory is selected with DOCKER_CONFIG instead.
+func (d *NerdctlDriver) newCommandWithConfig(args ...string) *exec.Cmd {
+	cmd := d.execCommand(args...)
+	d.Proxy.Apply(cmd)
+
+	if d.ConfigDir != "" {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, "DOCKER_CONFIG="+d.ConfigDir)
+	}
+
+	return cmd
+}
+
+func (d *NerdctlDriver) Build(args []string) (string, error) {
+	// nerdctl delegates builds to BuildKit.
+	if _, err := exec.LookPath("buildctl"); err != nil {
+		return "", fmt.Errorf("nerdctl build requires buildkitd and buildctl to be installed: %s", err)
+	}
+
+	return d.build([]string{"build"}, args)
+}
+
+// BuildX runs `nerdctl build`, which is already backed by BuildKit and
+// supports the same options as buildx.
+func (d *NerdctlDriver) BuildX(args []string) (string, error) {
+	return d.Build(args)
+}
+
+func (d *NerdctlDriver) Commit(id string, author string, changes []string, message string) (string, error) {
+	if err := d.requires("commit"); err != nil {
+		return "", err
+	}
+
+	// The changes of the template are validated when the config is
+	// prepared, so the ones left are the labels the builder adds itself,
+	// like the content hash and the snapshot name, which are dropped.
+	supported, dropped := nerdctlCommitChanges(changes)
+	for _, change := range dropped {
+		log.Printf("nerdctl commit does not support the %q change, ignoring it", change)
+	}
+
+	return d.DockerDriver.Commit(id, author, supported, message)
+}
+
+// nerdctlCommitChanges splits the changes between the ones nerdctl commit
+// applies, which only change the default command and the entrypoint of the
+// image, and the others.
+func nerdctlCommitChanges(changes []string) ([]string, []string) {
+	var supported, unsupported []string
+	for _, change := range changes {
+		if instruction, _ := changeInstruction(change); containsString(nerdctlCommitInstructions, instruction) {
+			supported = append(supported, change)
+		} else {
+			unsupported = append(unsupported, change)
+		}
+	}
+	return supported, unsupported
+}
+
+func (d *NerdctlDriver) Diff(id string) ([]string, error) {
+	if err := d.requires("diff"); err != nil {
+		return nil, err
+	}
+
+	return d.DockerDriver.Diff(id)
+}
+
+func (d *NerdctlDriver) Export(id string, dst io.Writer) error {
+	if err := d.requires("export"); err != nil {
+		return err
+	}
+
+	return d.DockerDriver.Export(id, dst)
+}
+
+func (d *NerdctlDriver) Import(path string, changes []string, repo string, platform string) (string, error) {
+	if err := d.requires("import"); err != nil {
+		return "", err
+	}
+
+	return d.DockerDriver.Import(path, changes, repo, platform)
+}
+
+// registryCommand returns the command to run against the registry at host,
+// with the registry TLS options, and the function to clean up after it ran.
+// The CA file is given in a hosts directory of its own, searched before the
+// default ones.
+func (d *NerdctlDriver) registryCommand(host string, args ...string) (*exec.Cmd, func(), error) {
+	var options []string
+
+	if d.Registries.IsInsecure(host) {
+		options = append(options, "--insecure-registry")
+	}
+
+	hostsDir, cleanup, err := d.Registries.writeCA(host)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hostsDir != "" {
+		options = append(options,
+			"--hosts-dir", hostsDir,
+			"--hosts-dir", "/etc/containerd/certs.d",
+			"--hosts-dir", "/etc/docker/certs.d")
+	}
+
+	return d.newCommandWithConfig(append(options, args...)...), cleanup, nil
+}
+
+func (d *NerdctlDriver) Login(repo, user, pass string) error {
+	d.l.Lock()
+
+	cmd, cleanup, err := d.registryCommand(loginRegistryHost(repo), "login")
+	if err != nil {
+		d.l.Unlock()
+		return err
+	}
+	defer cleanup()
+
+	if user != "" {
+		cmd.Args = append(cmd.Args, "-u", user)
+	}
+
+	if pass != "" {
+		cmd.Args = append(cmd.Args, "--password-stdin")
+		cmd.Stdin = strings.NewReader(pass)
+	}
+
+	if repo != "" {
+		cmd.Args = append(cmd.Args, repo)
+	}
+
+	if err := runAndStream(cmd, d.Ui); err != nil {
+		d.l.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func (d *NerdctlDriver) Logout(repo string) error {
+	cmd := d.newCommandWithConfig("logout")
+
+	if repo != "" {
+		cmd.Args = append(cmd.Args, repo)
+	}
+
+	err := runAndStream(cmd, d.Ui)
+	d.l.Unlock()
+	return err
+}
+
+func (d *NerdctlDriver) Pull(image string, platform string) error {
+	cmd, cleanup, err := d.registryCommand(ImageRegistryHost(image), "pull", image)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if platform != "" {
+		cmd.Args = append(cmd.Args, "--platform", platform)
+	}
+
+	return runAndStream(cmd, d.Ui)
+}
+
+func (d *NerdctlDriver) Push(name string, platform string) error {
+	cmd, cleanup, err := d.registryCommand(ImageRegistryHost(name), "push", name)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if platform != "" {
+		cmd.Args = append(cmd.Args, "--platform", platform)
+	}
+
+	return runAndStream(cmd, d.Ui)
+}
+
+func (d *NerdctlDriver) RemoteImageExists(name string) (bool, error) {
+	if err := d.requires("manifest inspect"); err != nil {
+		return false, err
+	}
+
+	cmd, cleanup, err := d.registryCommand(ImageRegistryHost(name), "manifest", "inspect", name)
+	if err != nil {
+		return false, err
+	}
+	defer cleanup()
+
+	var stderr bytes.Buffer
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	return runManifestInspect(cmd, &stderr)
+}
+
+func (d *NerdctlDriver) RemoteDigest(name string) (string, error) {
+	return "", errors.New("remote digests are not supported by the nerdctl driver")
+}
+
+// TagImage tags the image with nerdctl. nerdctl tag has no --force option,
+// existing tags are always overwritten, so force is ignored.
+func (d *NerdctlDriver) TagImage(id string, repo string, force bool) error {
+	var stderr bytes.Buffer
+	cmd := d.execCommand("tag", id, repo)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error tagging image: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// Verify checks that nerdctl can reach containerd, since nerdctl is
+// only a client and fails late otherwise.
+// Runtimes returns nil, since nerdctl gives GPUs to containers with the
+// nvidia-container-cli hook rather than with a dedicated runtime.
+func (d *NerdctlDriver) Runtimes() ([]string, error) {
+	return nil, nil
+}
+
+// ServerPlatform returns the platform of the local host, since nerdctl only
+// talks to the local containerd.
+func (d *NerdctlDriver) ServerPlatform() (string, error) {
+	return runtime.GOOS + "/" + runtime.GOARCH, nil
+}
+
+func (d *NerdctlDriver) Verify() error {
+	if err := d.DockerDriver.Verify(); err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := d.execCommand("info")
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error connecting to containerd with nerdctl: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
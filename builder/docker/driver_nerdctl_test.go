@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNerdctlDriver_impl(t *testing.T) {
+	var _ Driver = new(NerdctlDriver)
+}
+
+func TestNerdctlDriver_supports(t *testing.T) {
+	d := &NerdctlDriver{DockerDriver: DockerDriver{Executable: "true"}}
+	if !d.supports("commit") {
+		t.Fatal("expected commit to be supported")
+	}
+
+	d = &NerdctlDriver{DockerDriver: DockerDriver{Executable: "false"}}
+	if d.supports("import") {
+		t.Fatal("expected import to be unsupported")
+	}
+	if err := d.requires("import"); err == nil {
+		t.Fatal("expected an error for an unsupported command")
+	}
+}
+
+func TestNerdctlCommitChanges(t *testing.T) {
+	supported, dropped := nerdctlCommitChanges([]string{
+		"CMD [\"sh\"]", "entrypoint /bin/app", "LABEL io.packer.content-hash=abc",
+	})
+	if !reflect.DeepEqual(supported, []string{"CMD [\"sh\"]", "entrypoint /bin/app"}) {
+		t.Fatalf("bad supported changes: %v", supported)
+	}
+	if !reflect.DeepEqual(dropped, []string{"LABEL io.packer.content-hash=abc"}) {
+		t.Fatalf("bad dropped changes: %v", dropped)
+	}
+}
+
+func TestNerdctlDriver_registryCommand(t *testing.T) {
+	d := &NerdctlDriver{DockerDriver: DockerDriver{
+		Executable: "nerdctl",
+		Registries: RegistryTLSConfig{
+			InsecureRegistries: []string{"registry.example.com:5000"},
+			RegistryCAFile:     testRegistryCAFile(t),
+		},
+	}}
+
+	cmd, cleanup, err := d.registryCommand("registry.example.com:5000", "push", "registry.example.com:5000/app")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer cleanup()
+
+	if cmd.Args[1] != "--insecure-registry" || cmd.Args[2] != "--hosts-dir" {
+		t.Fatalf("expected the global options first: %v", cmd.Args)
+	}
+	if _, err := os.Stat(filepath.Join(cmd.Args[3], "registry.example.com:5000", "ca.crt")); err != nil {
+		t.Fatalf("expected the CA in the hosts directory: %s", err)
+	}
+	if last := cmd.Args[len(cmd.Args)-2:]; last[0] != "push" || last[1] != "registry.example.com:5000/app" {
+		t.Fatalf("bad args: %v", cmd.Args)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PodmanDriver is a Driver that runs commands with the podman CLI.
+//
+// Most podman commands accept the same arguments as their docker
+// counterparts, so this reuses the DockerDriver, and only overrides the
+// commands where both CLIs differ.
+type PodmanDriver struct {
+	DockerDriver
+}
+
+// authFile returns the path to the registry credentials file podman should
+// use, if the driver has an isolated configuration directory.
+func (d *PodmanDriver) authFile() string {
+	if d.ConfigDir == "" {
+		return ""
+	}
+	return filepath.Join(d.ConfigDir, "auth.json")
+}
+
+func (d *PodmanDriver) newCommandWithAuth(args ...string) *exec.Cmd {
+	cmd := d.execCommand(args...)
+	d.Proxy.Apply(cmd)
+
+	if authFile := d.authFile(); authFile != "" {
+		cmd.Args = append(cmd.Args, "--authfile", authFile)
+	}
+
+	return cmd
+}
+
+// BuildX runs `podman build`, since podman builds images with buildah and
+// already supports the same options as buildx, and stores the resulting
+// image locally.
+func (d *PodmanDriver) BuildX(args []string) (string, error) {
+	return d.build([]string{"build"}, args)
+}
+
+func (d *PodmanDriver) Import(path string, changes []string, repo string, platform string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	args := []string{"import"}
+
+	for _, change := range changes {
+		args = append(args, "--change", change)
+	}
+
+	// podman import has no --platform option, the platform needs to be
+	// split into its components.
+	if platform != "" {
+		parts := strings.Split(platform, "/")
+		args = append(args, "--os", parts[0])
+		if len(parts) > 1 {
+			args = append(args, "--arch", parts[1])
+		}
+		if len(parts) > 2 {
+			args = append(args, "--variant", parts[2])
+		}
+	}
+
+	args = append(args, "-")
+	if repo != "" {
+		args = append(args, repo)
+	}
+
+	cmd := d.execCommand(args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	file, err := openArchive(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	cmd.Stdin = file
+
+	log.Printf("Importing tarball with args: %v", args)
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error importing container: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// registryCommand returns the command to run against the registry at host,
+// with the registry TLS options, and the function to clean up after it ran.
+func (d *PodmanDriver) registryCommand(host string, args ...string) (*exec.Cmd, func(), error) {
+	cmd := d.newCommandWithAuth(args...)
+
+	if d.Registries.IsInsecure(host) {
+		cmd.Args = append(cmd.Args, "--tls-verify=false")
+	}
+
+	certDir, cleanup, err := d.Registries.writeCA("")
+	if err != nil {
+		return nil, nil, err
+	}
+	if certDir != "" {
+		cmd.Args = append(cmd.Args, "--cert-dir", certDir)
+	}
+
+	return cmd, cleanup, nil
+}
+
+func (d *PodmanDriver) Login(repo, user, pass string) error {
+	d.l.Lock()
+
+	cmd, cleanup, err := d.registryCommand(loginRegistryHost(repo), "login")
+	if err != nil {
+		d.l.Unlock()
+		return err
+	}
+	defer cleanup()
+
+	if user != "" {
+		cmd.Args = append(cmd.Args, "-u", user)
+	}
+
+	if pass != "" {
+		cmd.Args = append(cmd.Args, "--password-stdin")
+		cmd.Stdin = strings.NewReader(pass)
+	}
+
+	if repo != "" {
+		cmd.Args = append(cmd.Args, repo)
+	}
+
+	if err := runAndStream(cmd, d.Ui); err != nil {
+		d.l.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func (d *PodmanDriver) Logout(repo string) error {
+	cmd := d.newCommandWithAuth("logout")
+
+	if repo != "" {
+		cmd.Args = append(cmd.Args, repo)
+	}
+
+	err := runAndStream(cmd, d.Ui)
+	d.l.Unlock()
+	return err
+}
+
+func (d *PodmanDriver) Pull(image string, platform string) error {
+	cmd, cleanup, err := d.registryCommand(ImageRegistryHost(image), "pull", image)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if platform != "" {
+		cmd.Args = append(cmd.Args, "--platform", platform)
+	}
+
+	return runAndStream(cmd, d.Ui)
+}
+
+// Push pushes the image with podman. podman push has no --platform option,
+// so the platform is ignored.
+func (d *PodmanDriver) Push(name string, platform string) error {
+	if platform != "" {
+		log.Printf("[WARN] platform %q is ignored by podman push", platform)
+	}
+
+	cmd, cleanup, err := d.registryCommand(ImageRegistryHost(name), "push", name)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return runAndStream(cmd, d.Ui)
+}
+
+// RemoteImageExists inspects the manifest of the image in its registry with
+// `manifest inspect`, which podman and buildah look up in the registry when
+// there is no local manifest list of that name.
+func (d *PodmanDriver) RemoteImageExists(name string) (bool, error) {
+	var stderr bytes.Buffer
+	cmd := d.newCommandWithAuth("manifest", "inspect")
+	if d.Registries.IsInsecure(ImageRegistryHost(name)) {
+		cmd.Args = append(cmd.Args, "--tls-verify=false")
+	}
+	cmd.Args = append(cmd.Args, name)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	return runManifestInspect(cmd, &stderr)
+}
+
+func (d *PodmanDriver) RemoteDigest(name string) (string, error) {
+	return "", errors.New("remote digests are not supported by the podman driver")
+}
+
+// Runtimes returns nil, since podman gives GPUs to containers with CDI
+// rather than with a dedicated runtime.
+func (d *PodmanDriver) Runtimes() ([]string, error) {
+	return nil, nil
+}
+
+// ServerPlatform reads the platform from `podman info`, which also
+// describes the host of remote engines.
+func (d *PodmanDriver) ServerPlatform() (string, error) {
+	return d.serverPlatform("info", "--format", "{{.Host.OS}}/{{.Host.Arch}}")
+}
+
+func (d *PodmanDriver) Rootless() (bool, error) {
+	return d.rootless("{{.Host.Security.Rootless}}")
+}
+
+// rootless runs `info` with the given format, which is expected to print
+// whether the engine runs rootless.
+func (d *PodmanDriver) rootless(format string) (bool, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.execCommand("info", "--format", format)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return false, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return strconv.ParseBool(strings.TrimSpace(stdout.String()))
+}
+
+func (d *PodmanDriver) SaveImage(id string, dst io.Writer) error {
+	return d.save(id, "docker-archive", dst)
+}
+
+func (d *PodmanDriver) SaveOCIImage(id string, dst io.Writer) error {
+	return d.save(id, "oci-archive", dst)
+}
+
+// save writes the image to the writer, in an archive of the given format.
+func (d *PodmanDriver) save(id, format string, dst io.Writer) error {
+	var stderr bytes.Buffer
+	cmd := d.execCommand("save", "--format", format, id)
+	cmd.Stdout = dst
+	cmd.Stderr = &stderr
+
+	log.Printf("Exporting image: %s", id)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error exporting: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
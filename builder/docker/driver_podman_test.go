@@ -0,0 +1,44 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPodmanDriver_impl(t *testing.T) {
+	var _ Driver = new(PodmanDriver)
+}
+
+func TestPodmanDriver_registryCommand(t *testing.T) {
+	d := &PodmanDriver{DockerDriver{
+		Executable: "podman",
+		Registries: RegistryTLSConfig{
+			InsecureRegistries: []string{"registry.example.com:5000"},
+			RegistryCAFile:     testRegistryCAFile(t),
+		},
+	}}
+
+	cmd, cleanup, err := d.registryCommand("registry.example.com:5000", "pull", "registry.example.com:5000/app")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer cleanup()
+
+	args := strings.Join(cmd.Args, " ")
+	if !strings.Contains(args, "--tls-verify=false") || !strings.Contains(args, "--cert-dir ") {
+		t.Fatalf("bad args: %s", args)
+	}
+
+	cmd, cleanup, err = d.registryCommand("quay.io", "pull", "quay.io/app")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer cleanup()
+
+	if args := strings.Join(cmd.Args, " "); strings.Contains(args, "--tls-verify") {
+		t.Fatalf("expected the other registry to be verified: %s", args)
+	}
+}
@@ -10,8 +10,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	awsCredentials "github.com/aws/aws-sdk-go/aws/credentials"
@@ -32,13 +35,78 @@ type AwsAccessConfig struct {
 	// probably don't need it. This will also be read from the AWS_SESSION_TOKEN
 	// environmental variable.
 	Token string `mapstructure:"aws_token" required:"false"`
-	// The AWS shared credentials profile used to communicate with AWS.
+	// The AWS shared credentials profile used to communicate with AWS. The
+	// profile can get its credentials from AWS IAM Identity Center (SSO),
+	// with the token cached by `aws sso login`.
 	Profile string `mapstructure:"aws_profile" required:"false"`
+	// The ARN of the role to assume with a web identity token, like the
+	// OIDC token of a GitHub Actions job or the token of an EKS service
+	// account (IRSA), instead of using static credentials.
+	WebIdentityRoleARN string `mapstructure:"aws_web_identity_role_arn" required:"false"`
+	// The path of the file holding the web identity token. Defaults to the
+	// `AWS_WEB_IDENTITY_TOKEN_FILE` environment variable, which EKS sets for
+	// IRSA. In a GitHub Actions job with the `id-token: write` permission,
+	// the OIDC token of the job is requested from GitHub if no file is set.
+	WebIdentityTokenFile string `mapstructure:"aws_web_identity_token_file" required:"false"`
+	// The name of the session of the assumed role, as shown in CloudTrail.
+	// Defaults to `packer`.
+	WebIdentitySessionName string `mapstructure:"aws_web_identity_session_name" required:"false"`
+	// The time the credentials of the assumed role are valid for, from
+	// `15m` to `12h`, within the maximum session duration of the role.
+	// Defaults to `1h`.
+	WebIdentityDuration time.Duration `mapstructure:"aws_web_identity_duration" required:"false"`
 	// The flag to identify whether to push docker image to Public _or_ Private
-	// ECR. If the user sets this to `true` from the config, we will forcefully
-	// try to push to Public ECR otherwise set this from code based on the
-	// given LoginServer value.
+	// ECR. If true, the login token is requested from the ECR Public API, in
+	// `us-east-1`, and `login_server` defaults to `public.ecr.aws`. Otherwise
+	// it is set from code for the login servers of ECR Public, like
+	// `public.ecr.aws`.
 	PublicEcrGallery bool `mapstructure:"aws_force_use_public_ecr" required:"false"`
+	// The URL of the ECR API endpoint the login token is requested from,
+	// instead of the public endpoint of the region of the `login_server`,
+	// like the FIPS endpoint `https://ecr-fips.us-gov-west-1.amazonaws.com`
+	// or the interface VPC endpoint
+	// `https://vpce-0123456789abcdef0-abcdefgh.api.ecr.us-east-1.vpce.amazonaws.com`.
+	// Only used for the private registries, not for ECR Public.
+	EcrEndpoint string `mapstructure:"ecr_endpoint" required:"false"`
+}
+
+// Prepare validates the ECR endpoint and the web identity settings.
+func (c *AwsAccessConfig) Prepare() []error {
+	var errs []error
+	if c.EcrEndpoint != "" {
+		if u, err := url.Parse(c.EcrEndpoint); err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			errs = append(errs, fmt.Errorf("`ecr_endpoint` %q is not an endpoint URL, like `https://ecr-fips.us-east-1.amazonaws.com`", c.EcrEndpoint))
+		}
+	}
+	return append(errs, c.prepareWebIdentity()...)
+}
+
+// ecrEndpointConfig returns the config of the ECR clients, with the
+// `ecr_endpoint`. The endpoint only applies to ECR, not to the services the
+// credentials are requested from.
+func (c *AwsAccessConfig) ecrEndpointConfig() *aws.Config {
+	config := aws.NewConfig()
+	if c.EcrEndpoint != "" {
+		log.Printf("Using the ECR endpoint %s", c.EcrEndpoint)
+		config = config.WithEndpoint(c.EcrEndpoint)
+	}
+	return config
+}
+
+// ecrRegistryRe matches the hosts of the private ECR registries, like
+// `123456789012.dkr.ecr.us-east-1.amazonaws.com`, with their FIPS, China and
+// interface VPC endpoint variants. The VPC endpoints have no account ID.
+var ecrRegistryRe = regexp.MustCompile(`^(?:https?://)?(?:([0-9]*)\.dkr\.ecr(?:-fips)?|vpce-[0-9a-z-]+\.dkr\.ecr)\.([a-z0-9-]+)\.(?:vpce\.)?amazonaws\.com(?:\.cn)?(?:[:/].*)?$`)
+
+// parseEcrLoginServer returns the account ID and the region of the private
+// ECR registry of the login server. The account ID is empty for the
+// registries reached through a VPC endpoint.
+func parseEcrLoginServer(ecrUrl string) (string, string, error) {
+	match := ecrRegistryRe.FindStringSubmatch(ecrUrl)
+	if match == nil {
+		return "", "", fmt.Errorf("Failed to parse the ECR URL: %s it should be on the form <account number>.dkr.ecr.<region>.amazonaws.com", ecrUrl)
+	}
+	return match[1], match[2], nil
 }
 
 type ECRType string
@@ -49,20 +117,47 @@ const EcrPublicHost = "public.ecr.aws/"
 // so you need to specify --region us-east-1 each time you authenticate
 const EcrPublicApiRegion = "us-east-1"
 
+// isEcrPublicLoginServer returns true if the login server is the one of
+// ECR Public, with or without a scheme or a path, like `public.ecr.aws` or
+// `https://public.ecr.aws/a1b2c3/app`.
+func isEcrPublicLoginServer(ecrUrl string) bool {
+	host := strings.TrimPrefix(strings.TrimPrefix(ecrUrl, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	return host == strings.TrimSuffix(EcrPublicHost, "/")
+}
+
+// usePublicEcr returns true if the token of the login server is requested
+// from the ECR Public API, because it is forced or the login server is the
+// one of ECR Public.
+func (c *AwsAccessConfig) usePublicEcr(ecrUrl string) bool {
+	return c.PublicEcrGallery || isEcrPublicLoginServer(ecrUrl)
+}
+
+// ecrPublicEndpoint is the endpoint of the ECR Public API, which is left to
+// the AWS SDK unless set by the tests. The `ecr_endpoint` is the one of the
+// private registries, and isn't used for ECR Public.
+var ecrPublicEndpoint string
+
 // SetPublicEcrGallery sets PublicEcrGallery flag to `true` if the user given
 // LoginServer is the ECR Public URL
 func (c *AwsAccessConfig) SetPublicEcrGallery(ecrUrl string) {
-	forcePushEcr := c.PublicEcrGallery
-	notPublicEcrUrl := !strings.Contains(ecrUrl, EcrPublicHost)
-	if forcePushEcr || notPublicEcrUrl {
-		return
+	if isEcrPublicLoginServer(ecrUrl) {
+		c.PublicEcrGallery = true
 	}
-	c.PublicEcrGallery = true
 }
 
 // PublicEcrLogin : Get a login token for Amazon AWS ECR Public. Returns username and password
 // or an error.
 func (c *AwsAccessConfig) PublicEcrLogin(ecrUrl string) (string, string, error) {
+	token, err := c.publicEcrToken(ecrUrl)
+	if err != nil {
+		return "", "", err
+	}
+	return token.Username, token.Password, nil
+}
+
+// publicEcrToken gets a login token for Amazon AWS ECR Public.
+func (c *AwsAccessConfig) publicEcrToken(ecrUrl string) (*ecrToken, error) {
 	config := aws.NewConfig().WithCredentialsChainVerboseErrors(true)
 	config = config.WithRegion(EcrPublicApiRegion)
 
@@ -75,7 +170,7 @@ func (c *AwsAccessConfig) PublicEcrLogin(ecrUrl string) (string, string, error)
 	// the config.
 	creds, err := c.GetCredentials(config)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 	config.WithCredentials(creds)
 
@@ -91,55 +186,67 @@ func (c *AwsAccessConfig) PublicEcrLogin(ecrUrl string) (string, string, error)
 
 	sess, err := session.NewSessionWithOptions(opts)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 	session := sess
 
 	cp, err := session.Config.Credentials.Get()
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create session: %s", err)
+		return nil, fmt.Errorf("failed to create session: %s", err)
 	}
 	log.Printf("[INFO] AWS authentication used: %q", cp.ProviderName)
 
-	service := ecrpublic.New(session)
+	endpointConfig := aws.NewConfig()
+	if ecrPublicEndpoint != "" {
+		endpointConfig = endpointConfig.WithEndpoint(ecrPublicEndpoint)
+	}
+	service := ecrpublic.New(session, endpointConfig)
 	params := &ecrpublic.GetAuthorizationTokenInput{}
 
 	resp, err := service.GetAuthorizationToken(params)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	auth, err := base64.StdEncoding.DecodeString(*resp.AuthorizationData.AuthorizationToken)
 	if err != nil {
-		return "", "", fmt.Errorf("error decoding ECR Public AuthorizationToken: %s", err)
+		return nil, fmt.Errorf("error decoding ECR Public AuthorizationToken: %s", err)
 	}
 
 	authParts := strings.SplitN(string(auth), ":", 2)
 	log.Printf("Successfully got login for ECR Public: %s", ecrUrl)
 
-	username := authParts[0]
-	password := authParts[1]
-
-	return username, password, nil
+	return &ecrToken{
+		Username:  authParts[0],
+		Password:  authParts[1],
+		ExpiresAt: aws.TimeValue(resp.AuthorizationData.ExpiresAt),
+	}, nil
 }
 
 // EcrGetLogin Get a login token for Amazon AWS ECR. Returns username and password
-// or an error.
+// or an error. The tokens are shared by the builds and post-processors of the
+// Packer run until they expire.
 func (c *AwsAccessConfig) EcrGetLogin(ecrUrl string) (string, string, error) {
-
-	// Check ECR Type and set the flag
-	c.SetPublicEcrGallery(ecrUrl)
-	if c.PublicEcrGallery {
-		return c.PublicEcrLogin(ecrUrl)
+	// The ECR type is checked for each login server rather than saved in the
+	// config, which logs in to both types when pushing to several registries.
+	token, err := cachedEcrToken(c.ecrTokenCacheKey(ecrUrl), func() (*ecrToken, error) {
+		if c.usePublicEcr(ecrUrl) {
+			return c.publicEcrToken(ecrUrl)
+		}
+		return c.privateEcrToken(ecrUrl)
+	})
+	if err != nil {
+		return "", "", err
 	}
+	return token.Username, token.Password, nil
+}
 
-	exp := regexp.MustCompile(`(?:http://|https://|)([0-9]*)\.dkr\.ecr\.(.*)\.amazonaws\.com.*`)
-	splitUrl := exp.FindStringSubmatch(ecrUrl)
-	if len(splitUrl) != 3 {
-		return "", "", fmt.Errorf("Failed to parse the ECR URL: %s it should be on the form <account number>.dkr.ecr.<region>.amazonaws.com", ecrUrl)
+// privateEcrToken gets a login token for a private Amazon AWS ECR registry.
+func (c *AwsAccessConfig) privateEcrToken(ecrUrl string) (*ecrToken, error) {
+	accountId, region, err := parseEcrLoginServer(ecrUrl)
+	if err != nil {
+		return nil, err
 	}
-	accountId := splitUrl[1]
-	region := splitUrl[2]
 
 	log.Printf("Getting ECR token for account: %s in %s..", accountId, region)
 
@@ -156,7 +263,7 @@ func (c *AwsAccessConfig) EcrGetLogin(ecrUrl string) (string, string, error) {
 	// the config.
 	creds, err := c.GetCredentials(config)
 	if err != nil {
-		return "", "", fmt.Errorf(err.Error())
+		return nil, fmt.Errorf(err.Error())
 	}
 	config.WithCredentials(creds)
 
@@ -172,7 +279,7 @@ func (c *AwsAccessConfig) EcrGetLogin(ecrUrl string) (string, string, error) {
 
 	sess, err := session.NewSessionWithOptions(opts)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 	log.Printf("Found region %s", *sess.Config.Region)
 	session := sess
@@ -180,38 +287,66 @@ func (c *AwsAccessConfig) EcrGetLogin(ecrUrl string) (string, string, error) {
 	cp, err := session.Config.Credentials.Get()
 
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create session: %s", err)
+		return nil, fmt.Errorf("failed to create session: %s", err)
 	}
 
 	log.Printf("[INFO] AWS authentication used: %q", cp.ProviderName)
 
-	service := ecr.New(session)
-	params := &ecr.GetAuthorizationTokenInput{
-		RegistryIds: []*string{
-			aws.String(accountId),
-		},
+	service := ecr.New(session, c.ecrEndpointConfig())
+	params := &ecr.GetAuthorizationTokenInput{}
+	if accountId != "" {
+		params.RegistryIds = []*string{aws.String(accountId)}
 	}
 	resp, err := service.GetAuthorizationToken(params)
 	if err != nil {
-		return "", "", fmt.Errorf(err.Error())
+		return nil, fmt.Errorf(err.Error())
 	}
 
 	auth, err := base64.StdEncoding.DecodeString(*resp.AuthorizationData[0].AuthorizationToken)
 	if err != nil {
-		return "", "", fmt.Errorf("Error decoding ECR AuthorizationToken: %s", err)
+		return nil, fmt.Errorf("Error decoding ECR AuthorizationToken: %s", err)
 	}
 
 	authParts := strings.SplitN(string(auth), ":", 2)
 	log.Printf("Successfully got login for ECR: %s", ecrUrl)
 
-	return authParts[0], authParts[1], nil
+	return &ecrToken{
+		Username:  authParts[0],
+		Password:  authParts[1],
+		ExpiresAt: aws.TimeValue(resp.AuthorizationData[0].ExpiresAt),
+	}, nil
 }
 
 // GetCredentials gets credentials from the environment, shared credentials,
-// the session (which may include a credential process), or ECS/EC2 metadata
-// endpoints. GetCredentials also validates the credentials and the ability to
-// assume a role or will return an error if unsuccessful.
+// the session (which may include a credential process or an SSO profile), or
+// ECS/EC2 metadata endpoints. GetCredentials also validates the credentials
+// and the ability to assume a role or will return an error if unsuccessful.
 func (c *AwsAccessConfig) GetCredentials(config *aws.Config) (*awsCredentials.Credentials, error) {
+	if c.WebIdentityRoleARN != "" {
+		return c.webIdentityCredentials(config)
+	}
+
+	// The profiles of an IAM Identity Center session are resolved here, as
+	// the AWS SDK only understands the legacy SSO profiles. Static keys, of
+	// the config or of the environment, still come first.
+	if c.AccessKey == "" && os.Getenv("AWS_ACCESS_KEY_ID") == "" {
+		profile := c.Profile
+		if profile == "" {
+			profile = os.Getenv("AWS_PROFILE")
+		}
+		if profile == "" {
+			profile = "default"
+		}
+		ssoProfile, err := lookupSSOSessionProfile(profile)
+		if err != nil {
+			return nil, err
+		}
+		if ssoProfile != nil {
+			log.Printf("[INFO] Using the SSO session %s of AWS profile %s", ssoProfile.Session, profile)
+			return ssoSessionCredentials(config, ssoProfile)
+		}
+	}
+
 	// Reload values into the config used by the Packer-Terraform shared SDK
 	awsbaseConfig := &awsbase.Config{
 		AccessKey:    c.AccessKey,
@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseEcrLoginServer(t *testing.T) {
+	cases := []struct {
+		loginServer, account, region string
+	}{
+		{"https://123456789012.dkr.ecr.us-east-1.amazonaws.com/", "123456789012", "us-east-1"},
+		{"123456789012.dkr.ecr.us-gov-west-1.amazonaws.com", "123456789012", "us-gov-west-1"},
+		{"123456789012.dkr.ecr-fips.us-east-2.amazonaws.com/app", "123456789012", "us-east-2"},
+		{"123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", "123456789012", "cn-north-1"},
+		{"vpce-0123456789abcdef0-abcdefgh.dkr.ecr.eu-west-1.vpce.amazonaws.com", "", "eu-west-1"},
+	}
+	for _, tc := range cases {
+		account, region, err := parseEcrLoginServer(tc.loginServer)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.loginServer, err)
+		}
+		if account != tc.account || region != tc.region {
+			t.Fatalf("%s: bad account %q or region %q", tc.loginServer, account, region)
+		}
+	}
+
+	for _, loginServer := range []string{"ghcr.io", "registry.example.com/123456789012.dkr.ecr.us-east-1.amazonaws.com"} {
+		if _, _, err := parseEcrLoginServer(loginServer); err == nil {
+			t.Fatalf("%s shouldn't be an ECR registry", loginServer)
+		}
+	}
+}
+
+func TestIsEcrPublicLoginServer(t *testing.T) {
+	for _, loginServer := range []string{"public.ecr.aws", "public.ecr.aws/", "https://public.ecr.aws/a1b2c3/app"} {
+		if !isEcrPublicLoginServer(loginServer) {
+			t.Fatalf("%s should be ECR Public", loginServer)
+		}
+	}
+	for _, loginServer := range []string{"123456789012.dkr.ecr.us-east-1.amazonaws.com", "registry.example.com/public.ecr.aws/app"} {
+		if isEcrPublicLoginServer(loginServer) {
+			t.Fatalf("%s shouldn't be ECR Public", loginServer)
+		}
+	}
+}
+
+func TestAwsAccessConfig_Prepare(t *testing.T) {
+	for _, endpoint := range []string{"", "https://ecr-fips.us-east-1.amazonaws.com", "https://vpce-0123-abcd.api.ecr.us-east-1.vpce.amazonaws.com"} {
+		c := &AwsAccessConfig{EcrEndpoint: endpoint}
+		if errs := c.Prepare(); len(errs) > 0 {
+			t.Fatalf("%q: %v", endpoint, errs)
+		}
+	}
+	for _, endpoint := range []string{"ecr-fips.us-east-1.amazonaws.com", "ftp://ecr.example.com"} {
+		c := &AwsAccessConfig{EcrEndpoint: endpoint}
+		if errs := c.Prepare(); len(errs) == 0 {
+			t.Fatalf("%q should be invalid", endpoint)
+		}
+	}
+}
+
+func TestAwsAccessConfig_EcrGetLoginEndpoint(t *testing.T) {
+	var target string
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		token := base64.StdEncoding.EncodeToString([]byte("AWS:password"))
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		fmt.Fprintf(w, `{"authorizationData": [{"authorizationToken": %q}]}`, token)
+	}))
+	defer server.Close()
+
+	c := &AwsAccessConfig{
+		AccessKey:   "AKIA",
+		SecretKey:   "secret",
+		EcrEndpoint: server.URL,
+	}
+	username, password, err := c.EcrGetLogin("123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if username != "AWS" || password != "password" {
+		t.Fatalf("bad login: %s %s", username, password)
+	}
+	if target != "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken" {
+		t.Fatalf("bad target: %s", target)
+	}
+	if ids, _ := request["registryIds"].([]interface{}); len(ids) != 1 || ids[0] != "123456789012" {
+		t.Fatalf("bad request: %v", request)
+	}
+}
+
+func TestAwsAccessConfig_EcrGetLoginPublic(t *testing.T) {
+	var target string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+		token := base64.StdEncoding.EncodeToString([]byte("AWS:public-password"))
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		fmt.Fprintf(w, `{"authorizationData": {"authorizationToken": %q}}`, token)
+	}))
+	defer server.Close()
+
+	ecrPublicEndpoint = server.URL
+	defer func() { ecrPublicEndpoint = "" }()
+
+	// The endpoint of the private registries isn't used for ECR Public
+	c := &AwsAccessConfig{
+		AccessKey:   "AKIA",
+		SecretKey:   "secret",
+		EcrEndpoint: "https://ecr-fips.us-east-1.amazonaws.com",
+	}
+	username, password, err := c.EcrGetLogin("public.ecr.aws")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if username != "AWS" || password != "public-password" {
+		t.Fatalf("bad login: %s %s", username, password)
+	}
+	if target != "SpencerFrontendService.GetAuthorizationToken" {
+		t.Fatalf("should've requested the token from the ECR Public API: %s", target)
+	}
+	if c.PublicEcrGallery {
+		t.Fatal("the private registries pushed to after ECR Public should still use the ECR API")
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// ecrTokenCache holds the ECR login tokens fetched by the plugin process, by
+// key, in front of the files shared by the processes of the Packer run.
+var ecrTokenCache = struct {
+	sync.Mutex
+	tokens map[string]*ecrToken
+}{tokens: map[string]*ecrToken{}}
+
+// ecrTokenCacheDir is the directory of the ECR login tokens shared by the
+// builds and post-processors of a Packer run. It defaults to a directory of
+// the temporary directory that only the user running Packer can access.
+var ecrTokenCacheDir = filepath.Join(os.TempDir(), fmt.Sprintf("packer-docker-ecr-%d", os.Getuid()))
+
+// ecrTokenExpiryMargin is the time before its expiry a cached token is
+// fetched again, so that it doesn't expire during a pull or a push.
+const ecrTokenExpiryMargin = 15 * time.Minute
+
+// ecrToken is a login token of ECR.
+type ecrToken struct {
+	Username  string    `json:"username"`
+	Password  string    `json:"password"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// valid returns true if the token doesn't expire within the margin.
+func (t *ecrToken) valid(now time.Time) bool {
+	return t.Password != "" && now.Add(ecrTokenExpiryMargin).Before(t.ExpiresAt)
+}
+
+// ecrTokenCacheKey returns the key of the token of the registry of the login
+// server, which holds the account and the region, and of the credentials it
+// is fetched with, since the tokens have the permissions of the credentials.
+func (c *AwsAccessConfig) ecrTokenCacheKey(ecrUrl string) string {
+	registry := strings.TrimPrefix(strings.TrimPrefix(ecrUrl, "https://"), "http://")
+	registry, _, _ = strings.Cut(registry, "/")
+	if c.usePublicEcr(ecrUrl) {
+		registry = strings.TrimSuffix(EcrPublicHost, "/")
+	}
+	return strings.Join([]string{registry, c.AccessKey, c.Profile, c.WebIdentityRoleARN, c.EcrEndpoint}, "\x00")
+}
+
+// cachedEcrToken returns the token of the key cached by the process, or by
+// the builds and post-processors of the current Packer run, or fetches and
+// caches it if it is missing or about to expire. The processes of the run
+// fetch the token of a key one at a time. Outside of a Packer run, the token
+// is only cached by the process.
+func cachedEcrToken(key string, fetch func() (*ecrToken, error)) (*ecrToken, error) {
+	ecrTokenCache.Lock()
+	defer ecrTokenCache.Unlock()
+
+	if token, ok := ecrTokenCache.tokens[key]; ok && token.valid(time.Now()) {
+		log.Printf("Using the cached ECR token, valid until %s", token.ExpiresAt)
+		return token, nil
+	}
+
+	token, err := runCachedEcrToken(key, fetch)
+	if err != nil {
+		return nil, err
+	}
+	if !token.ExpiresAt.IsZero() {
+		ecrTokenCache.tokens[key] = token
+	}
+	return token, nil
+}
+
+// runCachedEcrToken returns the token of the key cached in the files of the
+// current Packer run, or fetches and caches it.
+func runCachedEcrToken(key string, fetch func() (*ecrToken, error)) (*ecrToken, error) {
+	runUUID := os.Getenv("PACKER_RUN_UUID")
+	if runUUID == "" {
+		return fetch()
+	}
+	if err := ecrTokenCacheDirReady(); err != nil {
+		log.Printf("Not sharing the ECR token with the Packer run: %s", err)
+		return fetch()
+	}
+
+	sum := sha256.Sum256([]byte(runUUID + "\x00" + key))
+	name := filepath.Join(ecrTokenCacheDir, hex.EncodeToString(sum[:])[:16])
+	lock := flock.New(name + ".lock")
+	if err := lock.Lock(); err != nil {
+		log.Printf("Failed to take the lock of the ECR token cache, fetching the token: %s", err)
+		return fetch()
+	}
+	defer func() {
+		if err := lock.Unlock(); err != nil {
+			log.Printf("Failed to release the lock of the ECR token cache: %s", err)
+		}
+	}()
+
+	if raw, err := os.ReadFile(name + ".json"); err == nil {
+		var token ecrToken
+		if err := json.Unmarshal(raw, &token); err == nil && token.valid(time.Now()) {
+			log.Printf("Using the ECR token cached by the Packer run, valid until %s", token.ExpiresAt)
+			return &token, nil
+		}
+	}
+
+	token, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if token.ExpiresAt.IsZero() {
+		return token, nil
+	}
+	if err := writeEcrToken(name+".json", token); err != nil {
+		log.Printf("Failed to cache the ECR token in %s: %s", name, err)
+	}
+	removeExpiredEcrTokens()
+	return token, nil
+}
+
+// ecrTokenCacheDirReady creates the directory of the tokens, and makes sure it's
+// only accessible by the user running Packer, who owns it.
+func ecrTokenCacheDirReady() error {
+	if err := os.MkdirAll(ecrTokenCacheDir, 0700); err != nil {
+		return err
+	}
+	fi, err := os.Lstat(ecrTokenCacheDir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() || fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s must be a directory only its owner can access", ecrTokenCacheDir)
+	}
+	return nil
+}
+
+// writeEcrToken writes the token to a file that only the user running Packer
+// can read, replacing the file at once so that it's never read partially.
+func writeEcrToken(name string, token *ecrToken) error {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), ".token-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// removeExpiredEcrTokens removes the tokens of the previous runs, which
+// expire after 12 hours, with their locks.
+func removeExpiredEcrTokens() {
+	entries, err := os.ReadDir(ecrTokenCacheDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		fi, err := entry.Info()
+		if err == nil && time.Since(fi.ModTime()) > 24*time.Hour {
+			os.Remove(filepath.Join(ecrTokenCacheDir, entry.Name()))
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedEcrToken(t *testing.T) {
+	ecrTokenCacheDir = t.TempDir()
+	t.Setenv("PACKER_RUN_UUID", "")
+
+	fetches := 0
+	fetch := func(expiresIn time.Duration) func() (*ecrToken, error) {
+		return func() (*ecrToken, error) {
+			fetches++
+			return &ecrToken{Username: "AWS", Password: "password", ExpiresAt: time.Now().Add(expiresIn)}, nil
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := cachedEcrToken("test-registry", fetch(12*time.Hour))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if token.Username != "AWS" || token.Password != "password" {
+			t.Fatalf("bad token: %#v", token)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("the token should've been fetched once, got %d fetches", fetches)
+	}
+
+	// Another registry has a token of its own
+	if _, err := cachedEcrToken("test-other", fetch(12*time.Hour)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if fetches != 2 {
+		t.Fatalf("bad fetches: %d", fetches)
+	}
+
+	// A token about to expire is fetched again
+	if _, err := cachedEcrToken("test-expiring", fetch(time.Minute)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := cachedEcrToken("test-expiring", fetch(time.Minute)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if fetches != 4 {
+		t.Fatalf("bad fetches: %d", fetches)
+	}
+
+	if _, err := cachedEcrToken("test-failing", func() (*ecrToken, error) { return nil, errors.New("denied") }); err == nil {
+		t.Fatal("should've failed to fetch the token")
+	}
+}
+
+// TestCachedEcrToken_run runs the test binary as other processes of the
+// same Packer run, like the plugin processes of a build and a post-processor.
+func TestCachedEcrToken_run(t *testing.T) {
+	if os.Getenv("ECR_TOKEN_CACHE_PROCESS") != "" {
+		token, err := cachedEcrToken("test-run", func() (*ecrToken, error) {
+			return &ecrToken{Username: "AWS", Password: fmt.Sprint(os.Getpid()), ExpiresAt: time.Now().Add(12 * time.Hour)}, nil
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		fmt.Printf("password=%s\n", token.Password)
+		return
+	}
+
+	dir := t.TempDir()
+	process := func(runUUID string) string {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCachedEcrToken_run$")
+		cmd.Env = append(os.Environ(), "ECR_TOKEN_CACHE_PROCESS=1", "TMPDIR="+dir, "PACKER_RUN_UUID="+runUUID)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("err: %s\n%s", err, out)
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			if password, ok := strings.CutPrefix(line, "password="); ok {
+				return password
+			}
+		}
+		t.Fatalf("no token in the output:\n%s", out)
+		return ""
+	}
+
+	first := process("1234")
+	if second := process("1234"); second != first {
+		t.Fatalf("the processes of the run should share the token fetched by the first, got %s and %s", first, second)
+	}
+	if other := process("5678"); other == first {
+		t.Fatal("another run shouldn't share the token")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 1 || !strings.HasPrefix(entries[0].Name(), "packer-docker-ecr-") {
+		t.Fatalf("expected the cache directory, got %v", entries)
+	}
+	cacheDir := filepath.Join(dir, entries[0].Name())
+	if fi, err := os.Stat(cacheDir); err != nil || fi.Mode().Perm() != 0700 {
+		t.Fatalf("bad cache directory: %v %v", fi, err)
+	}
+	tokens, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("expected a token for each run: %v %v", tokens, err)
+	}
+	for _, token := range tokens {
+		if fi, err := os.Stat(token); err != nil || fi.Mode().Perm() != 0600 {
+			t.Fatalf("bad token file: %v %v", fi, err)
+		}
+	}
+}
+
+func TestAwsAccessConfig_ecrTokenCacheKey(t *testing.T) {
+	c := &AwsAccessConfig{}
+	key := c.ecrTokenCacheKey("https://123456789012.dkr.ecr.us-east-1.amazonaws.com/app")
+	if key != c.ecrTokenCacheKey("123456789012.dkr.ecr.us-east-1.amazonaws.com") {
+		t.Fatal("the repositories of a registry should share the token")
+	}
+	if key == c.ecrTokenCacheKey("123456789012.dkr.ecr.us-west-2.amazonaws.com") {
+		t.Fatal("the registries of other regions shouldn't share the token")
+	}
+	if key == (&AwsAccessConfig{Profile: "push"}).ecrTokenCacheKey("123456789012.dkr.ecr.us-east-1.amazonaws.com") {
+		t.Fatal("other credentials shouldn't share the token")
+	}
+
+	public := &AwsAccessConfig{PublicEcrGallery: true}
+	if public.ecrTokenCacheKey("public.ecr.aws/a1b2c3/app") != public.ecrTokenCacheKey("public.ecr.aws/d4e5f6/tool") {
+		t.Fatal("the repositories of ECR Public should share the token")
+	}
+}
@@ -4,7 +4,10 @@
 package docker
 
 import (
+	"fmt"
 	"os/exec"
+	"strings"
+	"sync"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/shell-local/localexec"
@@ -24,6 +27,32 @@ func runAndStream(cmd *exec.Cmd, ui packersdk.Ui) error {
 		}
 	}
 
-	// run local command and stream output to UI.
-	return localexec.RunAndStream(cmd, ui, []string{capturedPassword})
+	// run local command and stream output to UI. The last line of the
+	// output, usually the error of the command, is added to the error.
+	recorder := &lastMessageUi{Ui: ui}
+	if err := localexec.RunAndStream(cmd, recorder, []string{capturedPassword}); err != nil {
+		if last := recorder.last; last != "" {
+			if capturedPassword != "" {
+				last = strings.ReplaceAll(last, capturedPassword, "<sensitive>")
+			}
+			return fmt.Errorf("%s: %s", err, last)
+		}
+		return err
+	}
+	return nil
+}
+
+// lastMessageUi records the last message it forwards.
+type lastMessageUi struct {
+	packersdk.Ui
+
+	l    sync.Mutex
+	last string
+}
+
+func (u *lastMessageUi) Message(message string) {
+	u.l.Lock()
+	u.last = message
+	u.l.Unlock()
+	u.Ui.Message(message)
 }
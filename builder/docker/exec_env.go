@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The path the socket of the ssh agent of the host is mounted on with
+// `forward_ssh_agent`.
+const sshAgentSocket = "/run/ssh-agent.sock"
+
+// readEnvFile reads a file of environment variables in the format of
+// `docker run --env-file`: one `KEY=value` per line, with comments starting
+// with `#`. A line with only a name takes the value of the variable from
+// the environment of Packer.
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the `env_file`: %s", err)
+	}
+	defer f.Close()
+
+	env := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if strings.ContainsAny(key, " \t") || key == "" {
+			return nil, fmt.Errorf("%s:%d: %q is not a valid variable name", path, lineNo, key)
+		}
+		if !ok {
+			value, ok = os.LookupEnv(key)
+			if !ok {
+				continue
+			}
+		}
+		env[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the `env_file`: %s", err)
+	}
+
+	return env, nil
+}
+
+// execEnv returns the environment variables set for the commands run in
+// the container, as sorted `KEY=value` pairs.
+func execEnv(config *Config) ([]string, error) {
+	env := map[string]string{}
+	if config.ProxyEnv.Inject {
+		for _, pair := range proxyEnvPairs(config.ProxyEnv.Values()) {
+			name, value, _ := strings.Cut(pair, "=")
+			env[name] = value
+		}
+	}
+	if config.EnvFile != "" {
+		fileEnv, err := readEnvFile(config.EnvFile)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fileEnv {
+			env[k] = v
+		}
+	}
+	if config.ForwardSSHAgent {
+		env["SSH_AUTH_SOCK"] = sshAgentSocket
+	}
+	for k, v := range config.ContainerEnv {
+		env[k] = v
+	}
+
+	pairs := make([]string, 0, len(env))
+	for k, v := range env {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs, nil
+}
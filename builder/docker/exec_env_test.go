@@ -0,0 +1,86 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExecEnv(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "build.env")
+	content := "# proxies\nHTTP_PROXY=http://proxy:3128\n\nFEATURE=off\nPACKER_TEST_FROM_ENV\nPACKER_TEST_UNSET\n"
+	if err := os.WriteFile(envFile, []byte(content), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Setenv("PACKER_TEST_FROM_ENV", "yes")
+
+	env, err := execEnv(&Config{
+		EnvFile:      envFile,
+		ContainerEnv: map[string]string{"FEATURE": "on"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"FEATURE=on",
+		"HTTP_PROXY=http://proxy:3128",
+		"PACKER_TEST_FROM_ENV=yes",
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Fatalf("bad env: %v", env)
+	}
+}
+
+func TestExecEnv_forwardSSHAgent(t *testing.T) {
+	env, err := execEnv(&Config{ForwardSSHAgent: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(env, []string{"SSH_AUTH_SOCK=/run/ssh-agent.sock"}) {
+		t.Fatalf("bad env: %v", env)
+	}
+}
+
+func TestExecEnv_proxyEnv(t *testing.T) {
+	t.Setenv("NO_PROXY", "localhost")
+
+	env, err := execEnv(&Config{
+		ProxyEnv: ProxyEnvConfig{
+			HTTPSProxy: "http://proxy:3128",
+			Inject:     true,
+		},
+		ContainerEnv: map[string]string{"https_proxy": "http://other-proxy:3128"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"HTTPS_PROXY=http://proxy:3128",
+		"NO_PROXY=localhost",
+		"https_proxy=http://other-proxy:3128",
+		"no_proxy=localhost",
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Fatalf("bad env: %v", env)
+	}
+}
+
+func TestReadEnvFile_invalid(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "build.env")
+	if err := os.WriteFile(envFile, []byte("NOT VALID=1\n"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := readEnvFile(envFile); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := readEnvFile(filepath.Join(t.TempDir(), "nope.env")); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxOCIDocumentSize is the size of the largest blob of an OCI image
+// layout that is read as an index, a manifest or a configuration.
+const maxOCIDocumentSize = 4 << 20
+
+// filtersExport returns true if layers or files are left out of the export.
+func (c *Config) filtersExport() bool {
+	return c.ExportDiffOnly || len(c.ExportExcludeLayers) > 0 || len(c.ExportExcludeLabeledLayers) > 0
+}
+
+// excludedLayers returns the digests of the layers left out of OCI exports:
+// the `export_exclude_layers`, the layers of the local images with the
+// `export_exclude_labeled_layers`, and with `export_diff_only` the layers of
+// the source image.
+func excludedLayers(driver Driver, config *Config) (map[string]bool, error) {
+	layers := map[string]bool{}
+	for _, digest := range config.ExportExcludeLayers {
+		layers[digest] = true
+	}
+
+	addImageLayers := func(id string) error {
+		metadata, err := driver.ImageMetadata(id)
+		if err != nil {
+			return fmt.Errorf("Error reading the layers of image %s: %s", id, err)
+		}
+		for _, digest := range metadata.RootFS.Layers {
+			layers[digest] = true
+		}
+		return nil
+	}
+
+	if config.ExportDiffOnly {
+		if err := addImageLayers(config.Image); err != nil {
+			return nil, err
+		}
+	}
+	for _, label := range sortedKeys(config.ExportExcludeLabeledLayers) {
+		ids, err := driver.ImagesWithLabel(label, config.ExportExcludeLabeledLayers[label])
+		if err != nil {
+			return nil, fmt.Errorf("Error listing the images labelled %s: %s", label, err)
+		}
+		for _, id := range ids {
+			if err := addImageLayers(id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return layers, nil
+}
+
+// ociDescriptor is the descriptor of a blob of an OCI image layout.
+type ociDescriptor struct {
+	Digest string `json:"digest"`
+}
+
+// ociDocument holds the fields of the indexes, manifests and configurations
+// of an OCI image layout that lead to the layers.
+type ociDocument struct {
+	Manifests []ociDescriptor `json:"manifests"`
+	Config    *ociDescriptor  `json:"config"`
+	Layers    []ociDescriptor `json:"layers"`
+	RootFS    struct {
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+// ociBlobName returns the name of the blob with the given digest in an OCI
+// image layout.
+func ociBlobName(digest string) string {
+	return path.Join("blobs", strings.Replace(digest, ":", "/", 1))
+}
+
+// readOCIDocuments returns the `index.json` and the small blobs of the OCI
+// image layout of the archive, by name, among which are the manifests and
+// configurations.
+func readOCIDocuments(archive string) (map[string][]byte, error) {
+	f, err := openArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	docs := map[string][]byte{}
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the image archive: %s", err)
+		}
+
+		name, ok := ociLayoutEntry(hdr.Name)
+		if !ok || hdr.Typeflag != tar.TypeReg || hdr.Size > maxOCIDocumentSize {
+			continue
+		}
+		if name != "index.json" && !strings.HasPrefix(name, "blobs/") {
+			continue
+		}
+		raw, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the image archive: %s", err)
+		}
+		docs[name] = raw
+	}
+}
+
+// excludedBlobs returns the names of the blobs of the excluded layers in the
+// OCI image layout, whose documents are given by name. A layer is excluded
+// if its digest or its diff ID, which differ for compressed layers, is one
+// of the layers.
+func excludedBlobs(docs map[string][]byte, layers map[string]bool) (map[string]bool, error) {
+	blobs := map[string]bool{}
+
+	var walk func(name string) error
+	walk = func(name string) error {
+		raw, ok := docs[name]
+		if !ok {
+			return fmt.Errorf("%s is missing from the OCI image layout", name)
+		}
+		var doc ociDocument
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return fmt.Errorf("Error reading %s of the OCI image layout: %s", name, err)
+		}
+
+		for _, manifest := range doc.Manifests {
+			if err := walk(ociBlobName(manifest.Digest)); err != nil {
+				return err
+			}
+		}
+		if doc.Config == nil {
+			return nil
+		}
+
+		var config ociDocument
+		if raw, ok := docs[ociBlobName(doc.Config.Digest)]; ok {
+			if err := json.Unmarshal(raw, &config); err != nil {
+				return fmt.Errorf("Error reading the configuration %s: %s", doc.Config.Digest, err)
+			}
+		}
+		diffIDs := config.RootFS.DiffIDs
+		for i, layer := range doc.Layers {
+			if layers[layer.Digest] || (i < len(diffIDs) && layers[diffIDs[i]]) {
+				blobs[ociBlobName(layer.Digest)] = true
+			}
+		}
+		return nil
+	}
+
+	if err := walk("index.json"); err != nil {
+		return nil, err
+	}
+	return blobs, nil
+}
+
+// filterOCIArchive rewrites the OCI image archive without the blobs of the
+// excluded layers. The manifests are left untouched, so that the layout can
+// be completed with the blobs of the excluded layers from elsewhere.
+func filterOCIArchive(archive string, config *Config, layers map[string]bool) (int, error) {
+	docs, err := readOCIDocuments(archive)
+	if err != nil {
+		return 0, err
+	}
+	blobs, err := excludedBlobs(docs, layers)
+	if err != nil {
+		return 0, err
+	}
+	if len(blobs) == 0 {
+		return 0, nil
+	}
+
+	if err := rewriteOCIArchive(archive, config, blobs, nil); err != nil {
+		return 0, fmt.Errorf("Error filtering the layers of the export: %s", err)
+	}
+	return len(blobs), nil
+}
+
+// rewriteOCIArchive rewrites the OCI image archive, compressed with the
+// `export_compression`, without the entries to remove, and with the
+// entries to add, by name.
+func rewriteOCIArchive(archive string, config *Config, remove map[string]bool, add map[string][]byte) error {
+	src, err := openArchive(archive)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(filepath.Dir(archive), filepath.Base(archive)+".rewritten")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+
+	err = exportCompressed(dst, config, func(w io.Writer) error {
+		tr := tar.NewReader(src)
+		tw := tar.NewWriter(w)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if name, ok := ociLayoutEntry(hdr.Name); ok && (remove[name] || add[name] != nil) {
+				continue
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				return err
+			}
+		}
+
+		names := make([]string, 0, len(add))
+		for name := range add {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			hdr := &tar.Header{
+				Name:     name,
+				Mode:     0644,
+				Size:     int64(len(add[name])),
+				Typeflag: tar.TypeReg,
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := tw.Write(add[name]); err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	})
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(dst.Name(), archive)
+}
+
+// diffPaths returns the set of the paths of the container diff, as the
+// names of the entries of `docker export`.
+func diffPaths(paths []string) map[string]bool {
+	names := map[string]bool{}
+	for _, p := range paths {
+		names[strings.TrimPrefix(path.Clean(p), "/")] = true
+	}
+	return names
+}
+
+// filterTar copies the entries of the tar stream r that keep returns true
+// for to w.
+func filterTar(r io.Reader, w io.Writer, keep func(*tar.Header) bool) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !keep(hdr) {
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// testLayeredImageArchive returns an OCI image archive of an image with two
+// layers, l1 and l2, whose diff IDs are d1 and d2.
+func testLayeredImageArchive(t *testing.T) []byte {
+	return testImageArchive(t, map[string]string{
+		"oci-layout":           `{"imageLayoutVersion":"1.0.0"}`,
+		"index.json":           `{"schemaVersion":2,"manifests":[{"digest":"sha256:m"}]}`,
+		"blobs/sha256/m":       `{"schemaVersion":2,"config":{"digest":"sha256:c"},"layers":[{"digest":"sha256:l1"},{"digest":"sha256:l2"}]}`,
+		"blobs/sha256/c":       `{"rootfs":{"type":"layers","diff_ids":["sha256:d1","sha256:d2"]}}`,
+		"blobs/sha256/l1":      "base",
+		"blobs/sha256/l2":      "app",
+		"manifest.json":        "[]",
+		"blobs/sha256/unknown": "{",
+	})
+}
+
+func TestExcludedBlobs(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(archive, testLayeredImageArchive(t), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	docs, err := readOCIDocuments(archive)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tc := []struct {
+		name   string
+		layers map[string]bool
+		blobs  map[string]bool
+	}{
+		{"by diff ID", map[string]bool{"sha256:d1": true}, map[string]bool{"blobs/sha256/l1": true}},
+		{"by digest", map[string]bool{"sha256:l2": true}, map[string]bool{"blobs/sha256/l2": true}},
+		{"unknown", map[string]bool{"sha256:l3": true}, map[string]bool{}},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			blobs, err := excludedBlobs(docs, c.layers)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(blobs, c.blobs) {
+				t.Fatalf("bad blobs: %v", blobs)
+			}
+		})
+	}
+
+	delete(docs, "blobs/sha256/m")
+	if _, err := excludedBlobs(docs, nil); err == nil {
+		t.Fatal("a missing manifest should be an error")
+	}
+}
+
+func TestFilterOCIArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(archive, testLayeredImageArchive(t), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	n, err := filterOCIArchive(archive, &Config{}, map[string]bool{"sha256:d1": true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("bad count of excluded layers: %d", n)
+	}
+
+	raw, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(raw))
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	expected := []string{
+		"blobs/sha256/c",
+		"blobs/sha256/l2",
+		"blobs/sha256/m",
+		"blobs/sha256/unknown",
+		"index.json",
+		"manifest.json",
+		"oci-layout",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad entries: %v", names)
+	}
+}
+
+func TestDiffPaths(t *testing.T) {
+	names := diffPaths([]string{"/etc", "/etc/app.conf", "/opt/app/"})
+	expected := map[string]bool{"etc": true, "etc/app.conf": true, "opt/app": true}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad names: %v", names)
+	}
+}
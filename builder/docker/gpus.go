@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// gpuRequest is the device request created by `docker run --gpus`.
+type gpuRequest struct {
+	Driver       string     `json:",omitempty"`
+	Count        int        `json:",omitempty"`
+	DeviceIDs    []string   `json:",omitempty"`
+	Capabilities [][]string `json:",omitempty"`
+}
+
+// parseGPUs parses the `gpus` option in the same format as `docker run
+// --gpus`: either `all`, a number of GPUs, or comma separated `count`,
+// `device`, `driver` and `capabilities` options, like `"device=0,1"`.
+func parseGPUs(value string) (*gpuRequest, error) {
+	// Quoted values like "device=0,1" contain commas, so the options are
+	// read as a CSV record, like the docker CLI does.
+	fields, err := csv.NewReader(strings.NewReader(value)).Read()
+	if err != nil {
+		return nil, fmt.Errorf("`gpus` %q is invalid: %s", value, err)
+	}
+
+	req := &gpuRequest{}
+	seen := map[string]bool{}
+	for _, field := range fields {
+		key, val, ok := strings.Cut(field, "=")
+		if !ok {
+			// A bare value is the number of GPUs
+			key, val = "count", field
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("`gpus` %q sets %s more than once", value, key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "count":
+			if val == "all" {
+				req.Count = -1
+				continue
+			}
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("`gpus` %q: count must be `all` or a positive number, got %q", value, val)
+			}
+			req.Count = count
+		case "device":
+			req.DeviceIDs = strings.Split(val, ",")
+		case "driver":
+			req.Driver = val
+		case "capabilities":
+			req.Capabilities = [][]string{strings.Split(val, ",")}
+		default:
+			return nil, fmt.Errorf("`gpus` %q: unknown option %q", value, key)
+		}
+	}
+
+	if req.Count != 0 && len(req.DeviceIDs) > 0 {
+		return nil, fmt.Errorf("`gpus` %q: count and device can't be set together", value)
+	}
+	if req.Capabilities == nil {
+		req.Capabilities = [][]string{{"gpu"}}
+	}
+
+	return req, nil
+}
+
+// verifyGPUs returns an error if the daemon can't give GPUs to containers.
+// Docker daemons need the nvidia runtime of the NVIDIA Container Toolkit.
+func verifyGPUs(driver Driver) error {
+	runtimes, err := driver.Runtimes()
+	if err != nil {
+		return fmt.Errorf("Failed to list the runtimes of the daemon to check for GPU support: %s", err)
+	}
+	if runtimes == nil {
+		return nil
+	}
+
+	for _, runtime := range runtimes {
+		if runtime == "nvidia" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("`gpus` is set, but the daemon has no nvidia runtime (found: %s). "+
+		"Install the NVIDIA Container Toolkit on the Docker host and configure it with "+
+		"`nvidia-ctk runtime configure --runtime=docker`, then restart the daemon.",
+		strings.Join(runtimes, ", "))
+}
@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseGPUs(t *testing.T) {
+	tc := []struct {
+		value    string
+		expected *gpuRequest
+		err      bool
+	}{
+		{"all", &gpuRequest{Count: -1, Capabilities: [][]string{{"gpu"}}}, false},
+		{"2", &gpuRequest{Count: 2, Capabilities: [][]string{{"gpu"}}}, false},
+		{`"device=0,1"`, &gpuRequest{DeviceIDs: []string{"0", "1"}, Capabilities: [][]string{{"gpu"}}}, false},
+		{"count=1,driver=nvidia,capabilities=compute", &gpuRequest{Count: 1, Driver: "nvidia", Capabilities: [][]string{{"compute"}}}, false},
+		{"0", nil, true},
+		{"count=1,count=2", nil, true},
+		{`count=1,"device=0"`, nil, true},
+		{"foo=bar", nil, true},
+	}
+
+	for _, c := range tc {
+		req, err := parseGPUs(c.value)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: should error", c.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(req, c.expected) {
+			t.Errorf("%s: got %#v, expected %#v", c.value, req, c.expected)
+		}
+	}
+}
+
+func TestVerifyGPUs(t *testing.T) {
+	driver := &MockDriver{RuntimesResult: []string{"nvidia", "runc"}}
+	if err := verifyGPUs(driver); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver = &MockDriver{RuntimesResult: []string{"runc"}}
+	if err := verifyGPUs(driver); err == nil {
+		t.Fatal("should error without the nvidia runtime")
+	}
+
+	// Drivers that can't list the runtimes are trusted
+	driver = &MockDriver{}
+	if err := verifyGPUs(driver); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver = &MockDriver{RuntimesErr: errors.New("foo")}
+	if err := verifyGPUs(driver); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// imageLabelsTemplate is the data the `labels` are rendered with at commit.
+type imageLabelsTemplate struct {
+	// The name of the build, like `docker.ubuntu`
+	BuildName string
+	// The source image, as given by `image`
+	SourceImage string
+	// The repo digest of the source image, empty if it has none
+	SourceImageDigest string
+	// The version of Packer, like `1.11.2`
+	PackerVersion string
+	// The time of the commit, in RFC 3339 format and UTC
+	Created string
+	// The commit, branch and URL of the git repository the build runs
+	// from, as given by the environment of the CI system
+	GitCommit     string
+	GitBranch     string
+	GitRepository string
+	// The user variables of the template
+	Vars map[string]string
+}
+
+// The environment variables the git metadata is read from, in order, for
+// GitHub Actions, GitLab CI, Jenkins, Bitbucket Pipelines and CircleCI.
+var (
+	gitCommitEnv = []string{"GITHUB_SHA", "CI_COMMIT_SHA", "GIT_COMMIT", "BITBUCKET_COMMIT", "CIRCLE_SHA1"}
+	gitBranchEnv = []string{"GITHUB_REF_NAME", "CI_COMMIT_REF_NAME", "GIT_BRANCH", "BITBUCKET_BRANCH", "CIRCLE_BRANCH"}
+	gitRepoEnv   = []string{"CI_PROJECT_URL", "GIT_URL", "BITBUCKET_GIT_HTTP_ORIGIN", "CIRCLE_REPOSITORY_URL"}
+)
+
+// firstEnv returns the value of the first environment variable of names
+// that is set.
+func firstEnv(names []string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// gitRepository returns the URL of the git repository the build runs from.
+func gitRepository() string {
+	if repo := os.Getenv("GITHUB_REPOSITORY"); repo != "" {
+		server := os.Getenv("GITHUB_SERVER_URL")
+		if server == "" {
+			server = "https://github.com"
+		}
+		return server + "/" + repo
+	}
+	return firstEnv(gitRepoEnv)
+}
+
+// imageLabelsContext returns the interpolation context the `labels` are
+// rendered with at commit.
+func imageLabelsContext(state multistep.StateBag, config *Config, now time.Time) interpolate.Context {
+	data := &imageLabelsTemplate{
+		BuildName:     config.PackerBuildName,
+		SourceImage:   config.Image,
+		PackerVersion: config.PackerCoreVersion,
+		Created:       now.UTC().Format(time.RFC3339),
+		GitCommit:     firstEnv(gitCommitEnv),
+		GitBranch:     firstEnv(gitBranchEnv),
+		GitRepository: gitRepository(),
+		Vars:          config.ctx.UserVariables,
+	}
+	if generatedData, ok := state.GetOk("generated_data"); ok {
+		digest, _ := generatedData.(map[string]interface{})["SourceImageDigest"].(string)
+		if !strings.HasPrefix(digest, "ERR_") {
+			data.SourceImageDigest = digest
+		}
+	}
+
+	ictx := config.ctx
+	ictx.Data = data
+	return ictx
+}
+
+// imageLabelChanges renders the `labels` and returns the LABEL changes
+// setting them, sorted by key.
+func imageLabelChanges(labels map[string]string, ictx *interpolate.Context) ([]string, error) {
+	var changes []string
+	for _, key := range sortedKeys(labels) {
+		value, err := interpolate.Render(labels[key], ictx)
+		if err != nil {
+			return nil, fmt.Errorf("Error rendering the label %s: %s", key, err)
+		}
+		changes = append(changes, fmt.Sprintf("LABEL %s=%q", key, value))
+	}
+	return changes, nil
+}
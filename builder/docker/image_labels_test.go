@@ -0,0 +1,60 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestImageLabelChanges(t *testing.T) {
+	for _, name := range append(append(append([]string{"GITHUB_REPOSITORY"}, gitCommitEnv...), gitBranchEnv...), gitRepoEnv...) {
+		t.Setenv(name, "")
+	}
+	t.Setenv("GITHUB_SHA", "0123abc")
+	t.Setenv("GITHUB_REF_NAME", "main")
+	t.Setenv("GITHUB_REPOSITORY", "acme/app")
+
+	state := testState(t)
+	state.Put("generated_data", map[string]interface{}{"SourceImageDigest": "sha256:feed"})
+	config := state.Get("config").(*Config)
+	config.PackerBuildName = "docker.app"
+	config.PackerCoreVersion = "1.11.2"
+
+	labels := map[string]string{
+		"org.opencontainers.image.revision":    "{{.GitCommit}}",
+		"org.opencontainers.image.source":      "{{.GitRepository}}",
+		"org.opencontainers.image.created":     "{{.Created}}",
+		"org.opencontainers.image.base.digest": "{{.SourceImageDigest}}",
+		"built-by":                             "packer {{.PackerVersion}} {{.BuildName}} on {{.GitBranch}}",
+	}
+	ictx := imageLabelsContext(state, config, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	changes, err := imageLabelChanges(labels, &ictx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		`LABEL built-by="packer 1.11.2 docker.app on main"`,
+		`LABEL org.opencontainers.image.base.digest="sha256:feed"`,
+		`LABEL org.opencontainers.image.created="2024-05-01T12:00:00Z"`,
+		`LABEL org.opencontainers.image.revision="0123abc"`,
+		`LABEL org.opencontainers.image.source="https://github.com/acme/app"`,
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("bad changes: %#v", changes)
+	}
+
+	// The placeholder of a digest that wasn't found isn't a digest
+	state.Put("generated_data", map[string]interface{}{"SourceImageDigest": "ERR_SOURCE_IMAGE_DIGEST_NOT_FOUND"})
+	ictx = imageLabelsContext(state, config, time.Now())
+	changes, err = imageLabelChanges(map[string]string{"base": "{{.SourceImageDigest}}"}, &ictx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(changes, []string{`LABEL base=""`}) {
+		t.Fatalf("bad changes: %#v", changes)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type MountConfig
+
+package docker
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MountConfig is a mount of the build container, in the same way as the
+// `--mount` option of `docker run`. Mounts expose host directories, named
+// volumes or in-memory filesystems to the provisioners, without uploading
+// the files through the communicator.
+//
+// ```hcl
+//
+//	mounts {
+//	  type   = "volume"
+//	  source = "packer-cache"
+//	  target = "/var/cache/apt"
+//	}
+//
+// ```
+type MountConfig struct {
+	// The type of the mount: `bind` to mount a directory or file of the
+	// host, `volume` to mount a named volume, or `tmpfs` to mount an
+	// in-memory filesystem.
+	Type string `mapstructure:"type" required:"true"`
+	// The host path for `bind` mounts, or the name of the volume for
+	// `volume` mounts. Volumes that don't exist are created before the
+	// container starts, and removed once the build is complete, unless
+	// `keep_volumes` is set. Must be empty for `tmpfs` mounts.
+	Source string `mapstructure:"source" required:"false"`
+	// The absolute path the mount is mounted at in the container.
+	Target string `mapstructure:"target" required:"true"`
+	// Mount it read-only. Defaults to false.
+	ReadOnly bool `mapstructure:"read_only" required:"false"`
+}
+
+func (c *MountConfig) Prepare() []error {
+	var errs []error
+
+	switch c.Type {
+	case "bind", "volume":
+		if c.Source == "" {
+			errs = append(errs, fmt.Errorf("mount of %q: `source` is required for %s mounts", c.Target, c.Type))
+		}
+	case "tmpfs":
+		if c.Source != "" {
+			errs = append(errs, fmt.Errorf("mount of %q: `source` can't be set for tmpfs mounts", c.Target))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mount of %q: `type` must be one of bind, volume or tmpfs, got %q", c.Target, c.Type))
+	}
+
+	if c.Type == "volume" && !isNamedVolume(c.Source) {
+		errs = append(errs, fmt.Errorf("mount of %q: %q is not a valid volume name", c.Target, c.Source))
+	}
+
+	// Windows containers use windows paths
+	if c.Target == "" || !(path.IsAbs(c.Target) || strings.Contains(c.Target, `:\`) || strings.Contains(c.Target, ":/")) {
+		errs = append(errs, fmt.Errorf("mount `target` must be an absolute path, got %q", c.Target))
+	}
+
+	return errs
+}
+
+// flag returns the mount in the format of `docker run --mount`.
+func (c *MountConfig) flag() string {
+	opts := []string{"type=" + c.Type}
+	if c.Source != "" {
+		opts = append(opts, "source="+c.Source)
+	}
+	opts = append(opts, "target="+c.Target)
+	if c.ReadOnly {
+		opts = append(opts, "readonly")
+	}
+	return strings.Join(opts, ",")
+}
+
+// isNamedVolume returns true if the source of a volume is the name of a
+// volume rather than a host path, like with `docker run -v`.
+func isNamedVolume(source string) bool {
+	if source == "" || strings.ContainsAny(source, `/\:`) {
+		return false
+	}
+	return !strings.HasPrefix(source, ".") && !strings.HasPrefix(source, "~")
+}
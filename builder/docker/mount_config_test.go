@@ -0,0 +1,80 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import "testing"
+
+func TestMountConfig_Prepare(t *testing.T) {
+	tc := []struct {
+		name  string
+		mount MountConfig
+		flag  string
+		err   bool
+	}{
+		{
+			"bind",
+			MountConfig{Type: "bind", Source: "/data", Target: "/data", ReadOnly: true},
+			"type=bind,source=/data,target=/data,readonly",
+			false,
+		},
+		{
+			"volume",
+			MountConfig{Type: "volume", Source: "cache", Target: "/var/cache"},
+			"type=volume,source=cache,target=/var/cache",
+			false,
+		},
+		{
+			"tmpfs",
+			MountConfig{Type: "tmpfs", Target: "/tmp"},
+			"type=tmpfs,target=/tmp",
+			false,
+		},
+		{
+			"windows target",
+			MountConfig{Type: "bind", Source: `C:\data`, Target: `c:\data`},
+			`type=bind,source=C:\data,target=c:\data`,
+			false,
+		},
+		{"unknown type", MountConfig{Type: "foo", Target: "/tmp"}, "", true},
+		{"bind without source", MountConfig{Type: "bind", Target: "/data"}, "", true},
+		{"tmpfs with source", MountConfig{Type: "tmpfs", Source: "/tmp", Target: "/tmp"}, "", true},
+		{"volume with a path", MountConfig{Type: "volume", Source: "/data", Target: "/data"}, "", true},
+		{"relative target", MountConfig{Type: "tmpfs", Target: "tmp"}, "", true},
+	}
+
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			errs := c.mount.Prepare()
+			if c.err {
+				if len(errs) == 0 {
+					t.Fatal("should error")
+				}
+				return
+			}
+			if len(errs) > 0 {
+				t.Fatalf("bad: %v", errs)
+			}
+			if flag := c.mount.flag(); flag != c.flag {
+				t.Fatalf("bad flag: %s, expected %s", flag, c.flag)
+			}
+		})
+	}
+}
+
+func TestIsNamedVolume(t *testing.T) {
+	for source, named := range map[string]bool{
+		"cache":       true,
+		"packer-apt":  true,
+		"/var/cache":  false,
+		"./cache":     false,
+		"~/cache":     false,
+		`C:\cache`:    false,
+		"":            false,
+		"relative/ok": false,
+	} {
+		if isNamedVolume(source) != named {
+			t.Errorf("%q: expected %v", source, named)
+		}
+	}
+}
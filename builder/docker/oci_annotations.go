@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// attestationReferenceAnnotation marks the manifests of the attestations in
+// the indexes of buildx, which aren't images.
+const attestationReferenceAnnotation = "vnd.docker.reference.type"
+
+// renderAnnotations renders the values of the `annotations`.
+func renderAnnotations(annotations map[string]string, ictx *interpolate.Context) (map[string]string, error) {
+	rendered := make(map[string]string, len(annotations))
+	for _, key := range sortedKeys(annotations) {
+		value, err := interpolate.Render(annotations[key], ictx)
+		if err != nil {
+			return nil, fmt.Errorf("Error rendering the annotation %s: %s", key, err)
+		}
+		rendered[key] = value
+	}
+	return rendered, nil
+}
+
+// annotateOCIDocuments adds the annotations to the image manifests of the
+// OCI image layout, whose documents are given by name. Since the digests of
+// the manifests change, the indexes leading to them are rewritten too. It
+// returns the rewritten documents by name, and the names of the blobs they
+// replace.
+func annotateOCIDocuments(docs map[string][]byte, annotations map[string]string) (map[string][]byte, map[string]bool, error) {
+	added := map[string][]byte{}
+	replaced := map[string]bool{}
+
+	// rewrite annotates the document with the given name, and returns the
+	// descriptor of the new blob, or nil if it's unchanged.
+	var rewrite func(name string) (map[string]interface{}, error)
+	rewrite = func(name string) (map[string]interface{}, error) {
+		raw, ok := docs[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is missing from the OCI image layout", name)
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("Error reading %s of the OCI image layout: %s", name, err)
+		}
+
+		changed := false
+		manifests, _ := doc["manifests"].([]interface{})
+		for _, m := range manifests {
+			descriptor, ok := m.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if annotations, ok := descriptor["annotations"].(map[string]interface{}); ok {
+				if _, ok := annotations[attestationReferenceAnnotation]; ok {
+					continue
+				}
+			}
+			digest, _ := descriptor["digest"].(string)
+			updated, err := rewrite(ociBlobName(digest))
+			if err != nil {
+				return nil, err
+			}
+			if updated != nil {
+				descriptor["digest"] = updated["digest"]
+				descriptor["size"] = updated["size"]
+				changed = true
+			}
+		}
+
+		if _, ok := doc["config"]; ok {
+			merged, _ := doc["annotations"].(map[string]interface{})
+			if merged == nil {
+				merged = map[string]interface{}{}
+			}
+			for key, value := range annotations {
+				merged[key] = value
+			}
+			doc["annotations"] = merged
+			changed = true
+		}
+
+		if !changed {
+			return nil, nil
+		}
+		out, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		replaced[name] = true
+		if name == "index.json" {
+			added[name] = out
+			return nil, nil
+		}
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256(out))
+		added[ociBlobName(digest)] = out
+		return map[string]interface{}{"digest": digest, "size": len(out)}, nil
+	}
+
+	if _, err := rewrite("index.json"); err != nil {
+		return nil, nil, err
+	}
+	return added, replaced, nil
+}
+
+// annotateOCIArchive adds the annotations to the image manifests of the OCI
+// image archive.
+func annotateOCIArchive(archive string, config *Config, annotations map[string]string) error {
+	docs, err := readOCIDocuments(archive)
+	if err != nil {
+		return err
+	}
+	added, replaced, err := annotateOCIDocuments(docs, annotations)
+	if err != nil {
+		return err
+	}
+	if err := rewriteOCIArchive(archive, config, replaced, added); err != nil {
+		return fmt.Errorf("Error annotating the export: %s", err)
+	}
+	return nil
+}
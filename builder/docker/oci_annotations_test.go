@@ -0,0 +1,94 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnnotateOCIDocuments(t *testing.T) {
+	docs := map[string][]byte{
+		"index.json": []byte(`{"schemaVersion":2,"manifests":[{"digest":"sha256:i","size":2}]}`),
+		"blobs/sha256/i": []byte(`{"schemaVersion":2,"manifests":[` +
+			`{"digest":"sha256:m","size":3,"platform":{"os":"linux"}},` +
+			`{"digest":"sha256:a","annotations":{"vnd.docker.reference.type":"attestation-manifest"}}]}`),
+		"blobs/sha256/m": []byte(`{"schemaVersion":2,"config":{"digest":"sha256:c"},"annotations":{"a":"1"}}`),
+		"blobs/sha256/a": []byte(`{"schemaVersion":2,"config":{"digest":"sha256:c"}}`),
+	}
+
+	added, replaced, err := annotateOCIDocuments(docs, map[string]string{"org.opencontainers.image.source": "https://example.com/app"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]bool{"index.json": true, "blobs/sha256/i": true, "blobs/sha256/m": true}
+	if !reflect.DeepEqual(replaced, expected) {
+		t.Fatalf("bad replaced documents: %v", replaced)
+	}
+
+	var index struct {
+		Manifests []struct {
+			Digest string
+			Size   int
+		}
+	}
+	if err := json.Unmarshal(added["index.json"], &index); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	nested := added[ociBlobName(index.Manifests[0].Digest)]
+	if nested == nil || index.Manifests[0].Size != len(nested) {
+		t.Fatalf("bad index: %s", added["index.json"])
+	}
+	if digest := fmt.Sprintf("sha256:%x", sha256.Sum256(nested)); digest != index.Manifests[0].Digest {
+		t.Fatalf("bad digest: %s", index.Manifests[0].Digest)
+	}
+
+	if err := json.Unmarshal(nested, &index); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if index.Manifests[1].Digest != "sha256:a" {
+		t.Fatalf("the attestation manifest shouldn't change: %s", nested)
+	}
+	var manifest struct {
+		Annotations map[string]string
+	}
+	if err := json.Unmarshal(added[ociBlobName(index.Manifests[0].Digest)], &manifest); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expectedAnnotations := map[string]string{"a": "1", "org.opencontainers.image.source": "https://example.com/app"}
+	if !reflect.DeepEqual(manifest.Annotations, expectedAnnotations) {
+		t.Fatalf("bad annotations: %v", manifest.Annotations)
+	}
+}
+
+func TestAnnotateOCIArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(archive, testLayeredImageArchive(t), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := annotateOCIArchive(archive, &Config{}, map[string]string{"a": "1"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	docs, err := readOCIDocuments(archive)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := docs["blobs/sha256/m"]; ok {
+		t.Fatal("the old manifest should be removed")
+	}
+	// The layers are still found from the new manifest
+	blobs, err := excludedBlobs(docs, map[string]bool{"sha256:d2": true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !blobs["blobs/sha256/l2"] {
+		t.Fatalf("bad blobs: %v", blobs)
+	}
+}
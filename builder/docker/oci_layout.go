@@ -0,0 +1,124 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// The formats the container can be exported in with `export_format`.
+const (
+	// A flat tar file of the filesystem of the container
+	ExportFormatTar = "tar"
+	// An OCI image layout of the image committed from the container
+	ExportFormatOCI = "oci"
+)
+
+// errNotOCILayout is returned for image archives that aren't OCI image
+// layouts, like the ones of Docker before 25.
+var errNotOCILayout = errors.New("the image archive is not an OCI image layout, " +
+	"saving images as OCI image layouts requires Docker 25 or later")
+
+// ociLayoutEntry returns the cleaned name of the entry of an image archive
+// if it is part of the OCI image layout, or false for the other entries,
+// like the `manifest.json` of docker archives.
+func ociLayoutEntry(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	switch {
+	case name == "oci-layout", name == "index.json":
+		return name, true
+	case name == "blobs", strings.HasPrefix(name, "blobs/"):
+		return name, true
+	}
+	return "", false
+}
+
+// verifyOCIArchive returns errNotOCILayout if the image archive at the given
+// path, which can be compressed, has no `oci-layout` file.
+func verifyOCIArchive(archive string) error {
+	f, err := openArchive(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return errNotOCILayout
+		}
+		if err != nil {
+			return fmt.Errorf("Error reading the image archive: %s", err)
+		}
+		if name, ok := ociLayoutEntry(hdr.Name); ok && name == "oci-layout" {
+			return nil
+		}
+	}
+}
+
+// extractOCILayout extracts the OCI image layout of the image archive to
+// the directory, leaving out the entries that aren't part of it.
+func extractOCILayout(r io.Reader, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	found := false
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Error reading the image archive: %s", err)
+		}
+
+		name, ok := ociLayoutEntry(hdr.Name)
+		if !ok {
+			continue
+		}
+		if name == "oci-layout" {
+			found = true
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return fmt.Errorf("Error extracting %s: %s", name, err)
+			}
+		default:
+			return fmt.Errorf("unexpected entry %s in the OCI image layout", hdr.Name)
+		}
+	}
+
+	if !found {
+		return errNotOCILayout
+	}
+	return nil
+}
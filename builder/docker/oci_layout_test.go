@@ -0,0 +1,105 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testImageArchive returns a tar file with the given files.
+func testImageArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, contents := range files {
+		hdr := &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractOCILayout(t *testing.T) {
+	archive := testImageArchive(t, map[string]string{
+		"oci-layout":            `{"imageLayoutVersion":"1.0.0"}`,
+		"index.json":            `{"schemaVersion":2}`,
+		"blobs/sha256/abcdef":   "layer",
+		"manifest.json":         "[]",
+		"blobs/../../escape":    "nope",
+		"legacy/layer.tar":      "legacy",
+		"./blobs/sha256/012345": "config",
+	})
+
+	dir := filepath.Join(t.TempDir(), "layout")
+	if err := extractOCILayout(bytes.NewReader(archive), dir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for name, expected := range map[string]string{
+		"oci-layout":          `{"imageLayoutVersion":"1.0.0"}`,
+		"index.json":          `{"schemaVersion":2}`,
+		"blobs/sha256/abcdef": "layer",
+		"blobs/sha256/012345": "config",
+	} {
+		contents, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if string(contents) != expected {
+			t.Errorf("bad contents of %s: %q", name, contents)
+		}
+	}
+
+	for _, name := range []string{"manifest.json", "legacy", "../escape"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s shouldn't be extracted", name)
+		}
+	}
+}
+
+func TestExtractOCILayout_notOCI(t *testing.T) {
+	archive := testImageArchive(t, map[string]string{
+		"manifest.json": "[]",
+	})
+
+	err := extractOCILayout(bytes.NewReader(archive), t.TempDir())
+	if err != errNotOCILayout {
+		t.Fatalf("expected errNotOCILayout, got %v", err)
+	}
+}
+
+func TestVerifyOCIArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	oci := filepath.Join(dir, "oci.tar")
+	if err := os.WriteFile(oci, testImageArchive(t, map[string]string{"oci-layout": "{}"}), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := verifyOCIArchive(oci); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	docker := filepath.Join(dir, "docker.tar")
+	if err := os.WriteFile(docker, testImageArchive(t, map[string]string{"manifest.json": "[]"}), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := verifyOCIArchive(docker); err != errNotOCILayout {
+		t.Fatalf("expected errNotOCILayout, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type ProxyEnvConfig
+
+package docker
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+)
+
+// The proxy variables, by their lowercase names. Both the lowercase and the
+// uppercase variables are set, since tools disagree on which one to read.
+var proxyEnvNames = []string{"http_proxy", "https_proxy", "no_proxy"}
+
+// ProxyEnvConfig sets the proxy variables of the engine commands Packer
+// runs, like the pulls, builds and pushes of the podman, buildah and
+// nerdctl drivers, and of the docker CLI. The docker daemon, and the api
+// driver, use the proxy configuration of the daemon instead.
+//
+// ```hcl
+//
+//	proxy_env {
+//	  https_proxy = "http://proxy.example.com:3128"
+//	  no_proxy    = "localhost,.example.com"
+//	  passthrough = false
+//	  inject      = true
+//	}
+//
+// ```
+type ProxyEnvConfig struct {
+	// The proxy of the HTTP requests, set as `HTTP_PROXY` and `http_proxy`.
+	HTTPProxy string `mapstructure:"http_proxy" required:"false"`
+	// The proxy of the HTTPS requests, set as `HTTPS_PROXY` and
+	// `https_proxy`.
+	HTTPSProxy string `mapstructure:"https_proxy" required:"false"`
+	// The hosts to reach without a proxy, set as `NO_PROXY` and
+	// `no_proxy`.
+	NoProxy string `mapstructure:"no_proxy" required:"false"`
+	// Whether the proxy variables of the environment of Packer apply to the
+	// engine commands, for the ones not set in this block. Defaults to
+	// true, set it to false to only use the values of this block.
+	Passthrough config.Trilean `mapstructure:"passthrough" required:"false"`
+	// If true, the proxy variables are also set for the commands run in the
+	// container during the build, like `container_env`, which takes
+	// precedence. They are not baked into the committed image.
+	Inject bool `mapstructure:"inject" required:"false"`
+}
+
+// Prepare validates the proxy URLs.
+func (c *ProxyEnvConfig) Prepare() []error {
+	var errs []error
+	for name, value := range map[string]string{"http_proxy": c.HTTPProxy, "https_proxy": c.HTTPSProxy} {
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("`proxy_env`: %s %q is not a proxy URL, like `http://proxy.example.com:3128`", name, value))
+		}
+	}
+	return errs
+}
+
+// configured returns the values of the block, by lowercase variable name.
+func (c *ProxyEnvConfig) configured() map[string]string {
+	values := map[string]string{}
+	for name, value := range map[string]string{
+		"http_proxy":  c.HTTPProxy,
+		"https_proxy": c.HTTPSProxy,
+		"no_proxy":    c.NoProxy,
+	} {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// Values returns the proxy variables that apply, by lowercase name: the
+// values of the block, and the ones of the environment of Packer unless
+// passthrough is disabled.
+func (c *ProxyEnvConfig) Values() map[string]string {
+	values := c.configured()
+	if c.Passthrough.False() {
+		return values
+	}
+
+	for _, name := range proxyEnvNames {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		if value := os.Getenv(strings.ToUpper(name)); value != "" {
+			values[name] = value
+		} else if value := os.Getenv(name); value != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// Apply sets the proxy variables in the environment of the command. The
+// environment is left alone when the block is empty, so the command
+// inherits the proxy variables of Packer.
+func (c *ProxyEnvConfig) Apply(cmd *exec.Cmd) {
+	configured := c.configured()
+	if len(configured) == 0 && !c.Passthrough.False() {
+		return
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+
+	env := make([]string, 0, len(cmd.Env))
+	for _, pair := range cmd.Env {
+		name, _, _ := strings.Cut(pair, "=")
+		if _, ok := configured[strings.ToLower(name)]; ok || (c.Passthrough.False() && isProxyEnvName(name)) {
+			continue
+		}
+		env = append(env, pair)
+	}
+	cmd.Env = append(env, proxyEnvPairs(configured)...)
+}
+
+// proxyEnvPairs returns the `KEY=value` pairs of the values, in lowercase
+// and uppercase.
+func proxyEnvPairs(values map[string]string) []string {
+	var pairs []string
+	for _, name := range proxyEnvNames {
+		if value, ok := values[name]; ok {
+			pairs = append(pairs, name+"="+value, strings.ToUpper(name)+"="+value)
+		}
+	}
+	return pairs
+}
+
+func isProxyEnvName(name string) bool {
+	for _, proxyName := range proxyEnvNames {
+		if strings.ToLower(name) == proxyName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+)
+
+func testProxyEnv(cmd *exec.Cmd) []string {
+	var env []string
+	for _, pair := range cmd.Env {
+		name, _, _ := strings.Cut(pair, "=")
+		if isProxyEnvName(name) {
+			env = append(env, pair)
+		}
+	}
+	sort.Strings(env)
+	return env
+}
+
+func TestProxyEnvConfig_Apply(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+
+	cmd := exec.Command("true")
+	(&ProxyEnvConfig{}).Apply(cmd)
+	if cmd.Env != nil {
+		t.Fatalf("expected the environment to be inherited: %v", cmd.Env)
+	}
+
+	cmd = exec.Command("true")
+	(&ProxyEnvConfig{HTTPSProxy: "http://proxy:3128"}).Apply(cmd)
+	expected := []string{
+		"HTTPS_PROXY=http://proxy:3128",
+		"HTTP_PROXY=http://env-proxy:3128",
+		"https_proxy=http://proxy:3128",
+	}
+	if env := testProxyEnv(cmd); !reflect.DeepEqual(env, expected) {
+		t.Fatalf("bad env: %v", env)
+	}
+
+	cmd = exec.Command("true")
+	(&ProxyEnvConfig{NoProxy: "localhost", Passthrough: config.TriFalse}).Apply(cmd)
+	expected = []string{"NO_PROXY=localhost", "no_proxy=localhost"}
+	if env := testProxyEnv(cmd); !reflect.DeepEqual(env, expected) {
+		t.Fatalf("bad env: %v", env)
+	}
+}
+
+func TestProxyEnvConfig_Values(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("http_proxy", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("https_proxy", "")
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+
+	c := ProxyEnvConfig{HTTPSProxy: "http://proxy:3128"}
+	expected := map[string]string{
+		"http_proxy":  "http://env-proxy:3128",
+		"https_proxy": "http://proxy:3128",
+	}
+	if values := c.Values(); !reflect.DeepEqual(values, expected) {
+		t.Fatalf("bad values: %v", values)
+	}
+
+	c.Passthrough = config.TriFalse
+	expected = map[string]string{"https_proxy": "http://proxy:3128"}
+	if values := c.Values(); !reflect.DeepEqual(values, expected) {
+		t.Fatalf("bad values: %v", values)
+	}
+}
+
+func TestProxyEnvConfig_Prepare(t *testing.T) {
+	c := ProxyEnvConfig{HTTPProxy: "http://proxy:3128", HTTPSProxy: "socks5://proxy:1080", NoProxy: "*"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	c = ProxyEnvConfig{HTTPProxy: "proxy", HTTPSProxy: "://proxy"}
+	if errs := c.Prepare(); len(errs) != 2 {
+		t.Fatalf("expected an error per invalid proxy, got %v", errs)
+	}
+}
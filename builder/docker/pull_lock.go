@@ -0,0 +1,100 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// pullLockRetryDelay is the time between the attempts to take the lock of a
+// pull held by another build.
+var pullLockRetryDelay = 500 * time.Millisecond
+
+// pullLockDir is the directory of the lock files of the pulls.
+var pullLockDir = os.TempDir()
+
+// pullLock is a file lock keyed by the daemon, the image and the platform,
+// that the builds of a run take around their pull, since each build runs in a
+// process of its own. The builds waiting for the lock don't pull the image
+// again if a build of the same run pulled it.
+type pullLock struct {
+	lock *flock.Flock
+	// The file the UUID of the last run that pulled the image is written to.
+	// It isn't the lock file, which can't be written to while it's locked on
+	// Windows.
+	marker string
+}
+
+// pullDaemon returns the daemon, or the image store, the builder pulls the
+// images to, so that the builds against other daemons neither wait for the
+// pull nor skip it. The cli and api drivers share the images of a daemon.
+func pullDaemon(config *Config) string {
+	switch config.DriverType {
+	case DriverPodman, DriverNerdctl, DriverBuildah:
+		return config.DriverType
+	}
+	return "docker\x00" + config.DockerHostConfig.endpoint()
+}
+
+// lockPull takes the lock of the pull of the image for the platform on the
+// daemon, and calls wait before waiting for it if another build holds it.
+func lockPull(ctx context.Context, daemon, image, platform string, wait func()) (*pullLock, error) {
+	sum := sha256.Sum256([]byte(daemon + "\x00" + image + "\x00" + platform))
+	name := "packer-docker-pull-" + hex.EncodeToString(sum[:])[:16]
+	l := &pullLock{
+		lock:   flock.New(filepath.Join(pullLockDir, name+".lock")),
+		marker: filepath.Join(pullLockDir, name+".pulled"),
+	}
+
+	locked, err := l.lock.TryLock()
+	if err != nil {
+		return nil, fmt.Errorf("Error taking the lock of the pull: %s", err)
+	}
+	if locked {
+		return l, nil
+	}
+
+	wait()
+	if _, err := l.lock.TryLockContext(ctx, pullLockRetryDelay); err != nil {
+		return nil, fmt.Errorf("Error waiting for the lock of the pull: %s", err)
+	}
+	return l, nil
+}
+
+// pulledByRun returns true if a build of the current Packer run pulled the
+// image already.
+func (l *pullLock) pulledByRun() bool {
+	runUUID := os.Getenv("PACKER_RUN_UUID")
+	if runUUID == "" {
+		return false
+	}
+	b, err := os.ReadFile(l.marker)
+	return err == nil && string(b) == runUUID
+}
+
+// markPulled records that the current Packer run pulled the image.
+func (l *pullLock) markPulled() {
+	runUUID := os.Getenv("PACKER_RUN_UUID")
+	if runUUID == "" {
+		return
+	}
+	if err := os.WriteFile(l.marker, []byte(runUUID), 0600); err != nil {
+		log.Printf("Failed to record the pull: %s", err)
+	}
+}
+
+func (l *pullLock) unlock() {
+	if err := l.lock.Unlock(); err != nil {
+		log.Printf("Failed to release the lock of the pull: %s", err)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testPullLock takes the locks of the pulls in a temporary directory, and
+// retries them quickly, for the duration of the test.
+func testPullLock(t *testing.T) {
+	dir, delay := pullLockDir, pullLockRetryDelay
+	t.Cleanup(func() { pullLockDir, pullLockRetryDelay = dir, delay })
+	pullLockDir = t.TempDir()
+	pullLockRetryDelay = time.Millisecond
+}
+
+func TestLockPull(t *testing.T) {
+	testPullLock(t)
+	t.Setenv("PACKER_RUN_UUID", "1234")
+
+	first, err := lockPull(context.Background(), "", "ubuntu", "", func() {
+		t.Error("the first build shouldn't wait")
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if first.pulledByRun() {
+		t.Fatal("the image wasn't pulled yet")
+	}
+
+	// Another image isn't locked
+	other, err := lockPull(context.Background(), "", "ubuntu", "linux/arm64", func() {
+		t.Error("another platform shouldn't wait")
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	other.unlock()
+
+	// Nor is the image of another daemon
+	other, err = lockPull(context.Background(), "tcp://docker.example.com:2376", "ubuntu", "", func() {
+		t.Error("another daemon shouldn't wait")
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if other.pulledByRun() {
+		t.Fatal("the image wasn't pulled on the other daemon")
+	}
+	other.unlock()
+
+	locked := make(chan *pullLock)
+	waiting := make(chan struct{})
+	go func() {
+		second, err := lockPull(context.Background(), "", "ubuntu", "", func() { close(waiting) })
+		if err != nil {
+			t.Errorf("err: %s", err)
+		}
+		locked <- second
+	}()
+
+	<-waiting
+	first.markPulled()
+	first.unlock()
+
+	second := <-locked
+	defer second.unlock()
+	if !second.pulledByRun() {
+		t.Fatal("the image was pulled by the first build")
+	}
+
+	// The next runs pull the image again
+	t.Setenv("PACKER_RUN_UUID", "5678")
+	if second.pulledByRun() {
+		t.Fatal("the image wasn't pulled by this run")
+	}
+}
+
+func TestLockPull_cancel(t *testing.T) {
+	testPullLock(t)
+
+	first, err := lockPull(context.Background(), "", "ubuntu", "", func() {})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer first.unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := lockPull(ctx, "", "ubuntu", "", cancel); err == nil {
+		t.Fatal("should error once cancelled")
+	}
+}
+
+func TestPullDaemon(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	local := pullDaemon(&Config{DriverType: DriverCLI})
+	if local != pullDaemon(&Config{DriverType: DriverAPI}) {
+		t.Fatal("the cli and api drivers should share the pulls of the local daemon")
+	}
+
+	remote := pullDaemon(&Config{DriverType: DriverCLI, DockerHostConfig: DockerHostConfig{DockerHost: "tcp://docker.example.com:2376"}})
+	if remote == local {
+		t.Fatal("the pulls of a remote daemon should have a lock of their own")
+	}
+	t.Setenv("DOCKER_HOST", "tcp://docker.example.com:2376")
+	if pullDaemon(&Config{DriverType: DriverCLI}) != remote {
+		t.Fatal("DOCKER_HOST should select the same daemon as docker_host")
+	}
+
+	if pullDaemon(&Config{DriverType: DriverPodman}) == pullDaemon(&Config{DriverType: DriverNerdctl}) {
+		t.Fatal("the image stores of podman and nerdctl should have locks of their own")
+	}
+}
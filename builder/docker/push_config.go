@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type PushConfig
+
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The tag the image is pushed with if no tags are set.
+const defaultPushTag = "latest"
+
+// pushTagRe matches the tags of images: up to 128 letters, digits,
+// underscores, periods and dashes, which don't start with a period or a
+// dash.
+var pushTagRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// PushConfig pushes the committed image to a registry at the end of the
+// build, without the docker-tag and docker-push post-processors. The
+// `login` options are used to log in to the registry. The artifact is then
+// the repo digest of the pushed image, like
+// `ghcr.io/example/app@sha256:...`.
+//
+// ```hcl
+//
+//	push {
+//	  repository = "ghcr.io/example/app"
+//	  tags       = ["1.0", "latest"]
+//	}
+//
+// ```
+type PushConfig struct {
+	// The repository the image is pushed to, like `ghcr.io/example/app`.
+	Repository string `mapstructure:"repository" required:"true"`
+	// The tags the image is pushed with. Defaults to `latest`.
+	Tags []string `mapstructure:"tags" required:"false"`
+	// If true, the image is removed from the local image store once it is
+	// pushed, which saves the disk space of the builds that only need the
+	// image in the registry. Defaults to false.
+	RemoveLocal bool `mapstructure:"remove_local" required:"false"`
+}
+
+// IsDefault returns true if the block isn't set, in which case the image
+// isn't pushed.
+func (c *PushConfig) IsDefault() bool {
+	return c.Repository == "" && len(c.Tags) == 0 && !c.RemoveLocal
+}
+
+// Prepare validates the options and sets the defaults.
+func (c *PushConfig) Prepare() []error {
+	if c.IsDefault() {
+		return nil
+	}
+
+	var errs []error
+	if c.Repository == "" {
+		errs = append(errs, fmt.Errorf("`push`: `repository` is required"))
+	} else if name := c.Repository[strings.LastIndex(c.Repository, "/")+1:]; strings.ContainsAny(name, ":@") ||
+		c.Repository != strings.ToLower(c.Repository) {
+		errs = append(errs, fmt.Errorf("`push`: %q is not a repository, expected a lowercase name without a tag or digest, "+
+			"like `ghcr.io/example/app`", c.Repository))
+	}
+
+	if len(c.Tags) == 0 {
+		c.Tags = []string{defaultPushTag}
+	}
+	for _, tag := range c.Tags {
+		if !pushTagRe.MatchString(tag) {
+			errs = append(errs, fmt.Errorf("`push`: %q is not a valid tag", tag))
+		}
+	}
+
+	return errs
+}
+
+// names returns the names the image is pushed with, one per tag.
+func (c *PushConfig) names() []string {
+	names := make([]string, 0, len(c.Tags))
+	for _, tag := range c.Tags {
+		names = append(names, c.Repository+":"+tag)
+	}
+	return names
+}
@@ -0,0 +1,31 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPushConfigPrepare(t *testing.T) {
+	c := PushConfig{Repository: "localhost:5000/example/app"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if !reflect.DeepEqual(c.names(), []string{"localhost:5000/example/app:latest"}) {
+		t.Fatalf("bad names: %#v", c.names())
+	}
+
+	for _, c := range []PushConfig{
+		{Tags: []string{"1.0"}},
+		{Repository: "ghcr.io/example/app:1.0"},
+		{Repository: "ghcr.io/example/app@sha256:1234"},
+		{Repository: "ghcr.io/Example/app"},
+		{Repository: "ghcr.io/example/app", Tags: []string{"-rc"}},
+	} {
+		if errs := c.Prepare(); len(errs) == 0 {
+			t.Errorf("should error: %#v", c)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"golang.org/x/oauth2/google"
+)
+
+// The names of the cloud registries the builder can fetch short-lived
+// credentials for.
+const (
+	registryECR  = "ECR"
+	registryGAR  = "Artifact Registry"
+	registryACR  = "ACR"
+	registryGHCR = "GHCR"
+)
+
+const (
+	// The login server of the GitHub Container Registry.
+	GhcrLoginServer = "ghcr.io"
+	// The username to log in to Artifact Registry with an access token.
+	garUsername = "oauth2accesstoken"
+	// The scope of the access tokens to log in to Artifact Registry with.
+	garScope = "https://www.googleapis.com/auth/cloud-platform"
+	// The username to log in to ACR with a refresh token.
+	acrUsername = "00000000-0000-0000-0000-000000000000"
+	// The resource the Azure access token that is exchanged for an ACR
+	// refresh token is for.
+	azureResource = "https://management.azure.com/"
+	// The endpoint of the managed identities of Azure virtual machines.
+	azureIMDSTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// The authority the access tokens of service principals are requested
+	// from.
+	azureAuthorityURL = "https://login.microsoftonline.com"
+)
+
+// cloudRegistries returns the names of the cloud registries the config logs
+// in to with short-lived credentials.
+func (c *Config) cloudRegistries() []string {
+	var registries []string
+	for _, registry := range []struct {
+		name    string
+		enabled bool
+	}{
+		{registryECR, c.EcrLogin},
+		{registryGAR, c.GarLogin},
+		{registryACR, c.AcrLogin},
+		{registryGHCR, c.GhcrLogin},
+	} {
+		if registry.enabled {
+			registries = append(registries, registry.name)
+		}
+	}
+	return registries
+}
+
+// cloudRegistry returns the name of the cloud registry the builder logs in
+// to with short-lived credentials, or an empty string if none.
+func (c *Config) cloudRegistry() string {
+	if registries := c.cloudRegistries(); len(registries) == 1 {
+		return registries[0]
+	}
+	return ""
+}
+
+// cloudRegistryCredentials returns the username and password to log in to
+// the cloud registry with.
+func (c *Config) cloudRegistryCredentials(ctx context.Context) (string, string, error) {
+	switch c.cloudRegistry() {
+	case registryECR:
+		return c.EcrGetLogin(c.LoginServer)
+	case registryGAR:
+		return GarCredentials(ctx, "")
+	case registryACR:
+		return AcrCredentials(ctx, c.LoginServer, "", "", "")
+	case registryGHCR:
+		return GhcrCredentials("", "")
+	}
+	return "", "", errors.New("no cloud registry login is enabled")
+}
+
+// loginRegistry logs in to the `login_server`, with the short-lived
+// credentials of the cloud registry if one is enabled, and returns the
+// function that logs out. Nothing is done if no login is enabled.
+func loginRegistry(ctx context.Context, ui packersdk.Ui, driver Driver, config *Config) (func(), error) {
+	if registry := config.cloudRegistry(); registry != "" {
+		ui.Message(fmt.Sprintf("Fetching %s credentials...", registry))
+
+		username, password, err := config.cloudRegistryCredentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching %s credentials: %s", registry, err)
+		}
+
+		config.LoginUsername = username
+		config.LoginPassword = password
+	}
+
+	if !config.Login && config.cloudRegistry() == "" {
+		return func() {}, nil
+	}
+
+	ui.Message("Logging in...")
+	err := driver.Login(
+		config.LoginServer,
+		config.LoginUsername,
+		config.LoginPassword)
+	if err != nil {
+		return nil, fmt.Errorf("Error logging in: %s", err)
+	}
+
+	return func() {
+		ui.Message("Logging out...")
+		if err := driver.Logout(config.LoginServer); err != nil {
+			ui.Error(fmt.Sprintf("Error logging out: %s", err))
+		}
+	}, nil
+}
+
+// registryHost returns the host of a login server, which may be given as a
+// URL.
+func registryHost(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host, _, _ := strings.Cut(server, "/")
+	return host
+}
+
+// GarCredentials returns an access token of the service account key file,
+// or of the Google Application Default Credentials if no file is given,
+// which Artifact Registry and Container Registry accept as password.
+func GarCredentials(ctx context.Context, keyFile string) (string, string, error) {
+	var creds *google.Credentials
+	if keyFile != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", "", fmt.Errorf("Error reading the Google service account key file: %s", err)
+		}
+		creds, err = google.CredentialsFromJSON(ctx, key, garScope)
+		if err != nil {
+			return "", "", fmt.Errorf("Error reading the Google service account key file %s: %s", keyFile, err)
+		}
+	} else {
+		var err error
+		creds, err = google.FindDefaultCredentials(ctx, garScope)
+		if err != nil {
+			return "", "", fmt.Errorf("Error finding the Google application default credentials: %s", err)
+		}
+	}
+
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return "", "", fmt.Errorf("Error getting a Google access token: %s", err)
+	}
+
+	return garUsername, token.AccessToken, nil
+}
+
+// AcrCredentials returns the credentials to log in to the Azure Container
+// Registry at loginServer with, a refresh token exchanged for an Azure
+// access token. The access token is requested for the service principal if
+// its secret is set, or else from the Azure CLI, or from the managed
+// identity of the virtual machine, the one of clientId if set.
+func AcrCredentials(ctx context.Context, loginServer, tenantId, clientId, clientSecret string) (string, string, error) {
+	var token string
+	var err error
+	if clientSecret != "" {
+		token, err = azureServicePrincipalToken(ctx, azureAuthorityURL, tenantId, clientId, clientSecret)
+	} else {
+		token, err = azureAccessToken(ctx, clientId)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return acrCredentials(ctx, "https://"+registryHost(loginServer), token)
+}
+
+// azureServicePrincipalToken returns an Azure access token of the service
+// principal, from the authority at the given URL.
+func azureServicePrincipalToken(ctx context.Context, authority, tenantId, clientId, clientSecret string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientId)
+	form.Set("client_secret", clientSecret)
+	form.Set("scope", azureResource+".default")
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%s/oauth2/v2.0/token", authority, url.PathEscape(tenantId)),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doTokenRequest(req, &token); err != nil {
+		return "", fmt.Errorf("Error getting an Azure access token for the service principal %s: %s", clientId, err)
+	}
+	return token.AccessToken, nil
+}
+
+// azureAccessToken returns an Azure access token of the user-assigned
+// managed identity of clientId if set, or else from the Azure CLI if it is
+// installed, or else from the managed identity of the virtual machine, the
+// one of `AZURE_CLIENT_ID` if set.
+func azureAccessToken(ctx context.Context, clientId string) (string, error) {
+	if az, err := exec.LookPath("az"); err == nil && clientId == "" {
+		log.Printf("Getting an Azure access token with %s", az)
+		cmd := exec.CommandContext(ctx, az, "account", "get-access-token",
+			"--resource", azureResource, "--query", "accessToken", "--output", "tsv")
+		out, err := cmd.Output()
+		if err != nil {
+			if ee, ok := err.(*exec.ExitError); ok {
+				return "", fmt.Errorf("Error getting an Azure access token with the Azure CLI: %s\nStderr: %s", err, ee.Stderr)
+			}
+			return "", fmt.Errorf("Error getting an Azure access token with the Azure CLI: %s", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	log.Printf("Getting an Azure access token from the managed identity")
+	query := url.Values{}
+	query.Set("api-version", "2018-02-01")
+	query.Set("resource", azureResource)
+	if clientId == "" {
+		clientId = os.Getenv("AZURE_CLIENT_ID")
+	}
+	if clientId != "" {
+		query.Set("client_id", clientId)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", azureIMDSTokenURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doTokenRequest(req, &token); err != nil {
+		return "", fmt.Errorf("Error getting an Azure access token from the managed identity, "+
+			"install the Azure CLI to use other credentials: %s", err)
+	}
+	return token.AccessToken, nil
+}
+
+// acrCredentials exchanges the Azure access token for a refresh token of the
+// registry at the given endpoint.
+func acrCredentials(ctx context.Context, endpoint string, accessToken string) (string, string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "access_token")
+	form.Set("service", u.Host)
+	form.Set("access_token", accessToken)
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(endpoint, "/")+"/oauth2/exchange", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := doTokenRequest(req, &token); err != nil {
+		return "", "", fmt.Errorf("Error exchanging the Azure access token for an ACR token: %s", err)
+	}
+	return acrUsername, token.RefreshToken, nil
+}
+
+func doTokenRequest(req *http.Request, out interface{}) error {
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// GhcrCredentials returns the username and token to log in to the GitHub
+// Container Registry with, the given ones if set, or else the ones of the
+// environment, like the ones GitHub Actions provide.
+func GhcrCredentials(username, token string) (string, string, error) {
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if token == "" {
+		return "", "", errors.New("GHCR login requires a GitHub token in the GITHUB_TOKEN or GH_TOKEN environment variable")
+	}
+
+	// The registry doesn't check the username of a token.
+	if username == "" {
+		username = os.Getenv("GITHUB_ACTOR")
+	}
+	if username == "" {
+		username = "packer"
+	}
+
+	return username, token, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistryHost(t *testing.T) {
+	for server, expected := range map[string]string{
+		"myregistry.azurecr.io":           "myregistry.azurecr.io",
+		"https://myregistry.azurecr.io/":  "myregistry.azurecr.io",
+		"us-docker.pkg.dev/project/repo":  "us-docker.pkg.dev",
+		"http://localhost:5000/some/repo": "localhost:5000",
+	} {
+		if host := registryHost(server); host != expected {
+			t.Errorf("%s: expected %q, got %q", server, expected, host)
+		}
+	}
+}
+
+func TestAcrCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/exchange" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		if r.Form.Get("grant_type") != "access_token" || r.Form.Get("access_token") != "aad-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors": [{"code": "UNAUTHORIZED"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"refresh_token": "acr-token"}`)
+	}))
+	defer server.Close()
+
+	username, password, err := acrCredentials(context.Background(), server.URL, "aad-token")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if username != acrUsername || password != "acr-token" {
+		t.Fatalf("bad credentials: %q, %q", username, password)
+	}
+
+	_, _, err = acrCredentials(context.Background(), server.URL, "expired")
+	if err == nil || !strings.Contains(err.Error(), "UNAUTHORIZED") {
+		t.Fatalf("should report the error of the registry: %v", err)
+	}
+}
+
+func TestAzureServicePrincipalToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tenant/oauth2/v2.0/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "client" ||
+			r.Form.Get("client_secret") != "secret" || r.Form.Get("scope") != azureResource+".default" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client"}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "aad-token"}`)
+	}))
+	defer server.Close()
+
+	token, err := azureServicePrincipalToken(context.Background(), server.URL, "tenant", "client", "secret")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if token != "aad-token" {
+		t.Fatalf("bad token: %q", token)
+	}
+
+	_, err = azureServicePrincipalToken(context.Background(), server.URL, "tenant", "client", "expired")
+	if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Fatalf("should report the error of the authority: %v", err)
+	}
+}
+
+func TestGhcrCredentials(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	if _, _, err := GhcrCredentials("", ""); err == nil {
+		t.Fatal("should require a token")
+	}
+
+	t.Setenv("GH_TOKEN", "gh-token")
+	t.Setenv("GITHUB_ACTOR", "octocat")
+	username, password, err := GhcrCredentials("", "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if username != "octocat" || password != "gh-token" {
+		t.Fatalf("bad credentials: %q, %q", username, password)
+	}
+
+	username, password, err = GhcrCredentials("bot", "pat")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if username != "bot" || password != "pat" {
+		t.Fatalf("the given credentials should win over the environment: %q, %q", username, password)
+	}
+}
+
+func TestGarCredentials_keyFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("assertion") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "ya29.token", "token_type": "Bearer", "expires_in": 3600}`)
+	}))
+	defer server.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	raw, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "packer@example.iam.gserviceaccount.com",
+		"private_key":  string(keyPEM),
+		"token_uri":    server.URL,
+	})
+	if err := os.WriteFile(keyFile, raw, 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	username, password, err := GarCredentials(context.Background(), keyFile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if username != garUsername || password != "ya29.token" {
+		t.Fatalf("bad credentials: %s %s", username, password)
+	}
+
+	if _, _, err := GarCredentials(context.Background(), filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("should've failed without a key file")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The registry of the images that don't name one.
+const dockerHubRegistry = "docker.io"
+
+// registryMirror is a pull-through cache of a registry.
+type registryMirror struct {
+	// The registry that is mirrored, like `docker.io`.
+	Registry string
+	// The host of the mirror, optionally followed by the path the registry
+	// is mirrored under, like `harbor.example.com/dockerhub`.
+	Mirror string
+}
+
+// parseRegistryMirror parses a `registry_mirrors` entry, either a mirror of
+// Docker Hub, or `<registry>=<mirror>`.
+func parseRegistryMirror(value string) (registryMirror, error) {
+	registry, mirror, ok := strings.Cut(value, "=")
+	if !ok {
+		registry, mirror = dockerHubRegistry, value
+	}
+
+	mirror = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(mirror, "https://"), "http://"), "/")
+	if registry == "" || mirror == "" || strings.Contains(registry, "/") {
+		return registryMirror{}, fmt.Errorf("`registry_mirrors`: %q is not a mirror, expected `<mirror>` or `<registry>=<mirror>`", value)
+	}
+
+	if registry == "index.docker.io" || registry == "registry-1.docker.io" {
+		registry = dockerHubRegistry
+	}
+	return registryMirror{Registry: registry, Mirror: mirror}, nil
+}
+
+// splitImageRegistry splits an image reference into its registry and the
+// rest of the reference, the way docker normalizes names: the first
+// component is a registry only if it looks like a host, and the images of
+// Docker Hub without a namespace are in `library`.
+func splitImageRegistry(image string) (string, string) {
+	first, rest, ok := strings.Cut(image, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first, rest
+	}
+
+	if !ok {
+		return dockerHubRegistry, "library/" + image
+	}
+	return dockerHubRegistry, image
+}
+
+// mirroredImages returns the references of the image in the mirrors of its
+// registry, in the order the mirrors are configured.
+func mirroredImages(image string, mirrors []string) []string {
+	registry, rest := splitImageRegistry(image)
+	if registry == "index.docker.io" || registry == "registry-1.docker.io" {
+		registry = dockerHubRegistry
+	}
+
+	var images []string
+	for _, value := range mirrors {
+		mirror, err := parseRegistryMirror(value)
+		if err != nil || mirror.Registry != registry {
+			continue
+		}
+		images = append(images, mirror.Mirror+"/"+rest)
+	}
+	return images
+}
@@ -0,0 +1,56 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRegistryMirror(t *testing.T) {
+	cases := map[string]registryMirror{
+		"mirror.example.com":                        {Registry: "docker.io", Mirror: "mirror.example.com"},
+		"https://mirror.example.com/":               {Registry: "docker.io", Mirror: "mirror.example.com"},
+		"quay.io=quay-mirror.example.com":           {Registry: "quay.io", Mirror: "quay-mirror.example.com"},
+		"index.docker.io=harbor.example.com/hub":    {Registry: "docker.io", Mirror: "harbor.example.com/hub"},
+		"registry.example.com:5000=localhost:5001/": {Registry: "registry.example.com:5000", Mirror: "localhost:5001"},
+	}
+	for value, expected := range cases {
+		mirror, err := parseRegistryMirror(value)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", value, err)
+		}
+		if mirror != expected {
+			t.Errorf("%s: expected %#v, got %#v", value, expected, mirror)
+		}
+	}
+
+	for _, value := range []string{"", "quay.io=", "=mirror.example.com", "quay.io/org=mirror.example.com"} {
+		if _, err := parseRegistryMirror(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestMirroredImages(t *testing.T) {
+	mirrors := []string{
+		"mirror.example.com",
+		"quay.io=quay-mirror.example.com",
+		"harbor.example.com/dockerhub",
+	}
+
+	cases := map[string][]string{
+		"ubuntu:22.04":          {"mirror.example.com/library/ubuntu:22.04", "harbor.example.com/dockerhub/library/ubuntu:22.04"},
+		"hashicorp/packer":      {"mirror.example.com/hashicorp/packer", "harbor.example.com/dockerhub/hashicorp/packer"},
+		"docker.io/library/foo": {"mirror.example.com/library/foo", "harbor.example.com/dockerhub/library/foo"},
+		"quay.io/coreos/etcd":   {"quay-mirror.example.com/coreos/etcd"},
+		"ghcr.io/org/image":     nil,
+		"localhost/image":       nil,
+	}
+	for image, expected := range cases {
+		if images := mirroredImages(image, mirrors); !reflect.DeepEqual(images, expected) {
+			t.Errorf("%s: expected %q, got %q", image, expected, images)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+
+package docker
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RegistryTLSConfig configures how the registries that images are pulled
+// from and pushed to are reached, without changing the configuration of
+// the container engine. These options are only supported by the podman,
+// buildah and nerdctl drivers, the docker daemon only reads them from its
+// own configuration.
+type RegistryTLSConfig struct {
+	// The registries to reach without verifying their certificate, or over
+	// plain HTTP, like `registry.example.com:5000`.
+	InsecureRegistries []string `mapstructure:"insecure_registries" required:"false"`
+	// The path to a PEM file of the certificate authorities to verify the
+	// certificates of the registries with, in addition to the ones of the
+	// system.
+	RegistryCAFile string `mapstructure:"registry_ca_file" required:"false"`
+}
+
+// IsDefault returns true if no option was set, in which case the registries
+// are reached the way the container engine is configured to.
+func (c *RegistryTLSConfig) IsDefault() bool {
+	return len(c.InsecureRegistries) == 0 && c.RegistryCAFile == ""
+}
+
+// Prepare validates the options for the given driver.
+func (c *RegistryTLSConfig) Prepare(driverType string) []error {
+	var errs []error
+
+	switch driverType {
+	case DriverPodman, DriverBuildah, DriverNerdctl:
+	default:
+		if !c.IsDefault() {
+			errs = append(errs, fmt.Errorf("`insecure_registries` and `registry_ca_file` are only supported by the %s, %s and %s drivers, "+
+				"configure the registries of the docker daemon instead", DriverPodman, DriverBuildah, DriverNerdctl))
+		}
+	}
+
+	for _, registry := range c.InsecureRegistries {
+		if registry == "" || strings.Contains(registry, "/") {
+			errs = append(errs, fmt.Errorf("`insecure_registries`: %q is not a registry host, like `registry.example.com:5000`", registry))
+		}
+	}
+
+	if c.RegistryCAFile != "" {
+		pem, err := os.ReadFile(c.RegistryCAFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("`registry_ca_file` can't be read: %s", err))
+		} else if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			errs = append(errs, fmt.Errorf("`registry_ca_file` %s contains no PEM certificate", c.RegistryCAFile))
+		}
+	}
+
+	return errs
+}
+
+// IsInsecure returns true if the registry at host is one of the insecure
+// registries.
+func (c *RegistryTLSConfig) IsInsecure(host string) bool {
+	for _, registry := range c.InsecureRegistries {
+		if registry == host {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCA copies the registry CA file to a temporary directory, as
+// `<host>/ca.crt` if host is set, or else as `ca.crt`, the layouts the
+// certificate directories of containerd and of podman expect. The returned
+// directory is empty if no CA file is set, and has to be removed with the
+// returned function.
+func (c *RegistryTLSConfig) writeCA(host string) (string, func(), error) {
+	if c.RegistryCAFile == "" {
+		return "", func() {}, nil
+	}
+
+	pem, err := os.ReadFile(c.RegistryCAFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("Error reading the registry CA file: %s", err)
+	}
+
+	dir, err := os.MkdirTemp("", "packer-registry-certs")
+	if err != nil {
+		return "", nil, fmt.Errorf("Error creating the registry certificates directory: %s", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	certDir := filepath.Join(dir, host)
+	if err := os.MkdirAll(certDir, 0700); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("Error creating the registry certificates directory: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(certDir, "ca.crt"), pem, 0600); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("Error writing the registry CA file: %s", err)
+	}
+
+	return dir, cleanup, nil
+}
+
+// ImageRegistryHost returns the host of the registry of an image reference,
+// `docker.io` for the images of Docker Hub.
+func ImageRegistryHost(image string) string {
+	host, _ := splitImageRegistry(image)
+	return host
+}
+
+// loginRegistryHost returns the host of the registry a login is for, Docker
+// Hub if no server is given.
+func loginRegistryHost(server string) string {
+	if server == "" {
+		return dockerHubRegistry
+	}
+	return registryHost(server)
+}
@@ -0,0 +1,137 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testRegistryCAFile writes a self-signed certificate authority to a
+// temporary file.
+func testRegistryCAFile(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Packer Test CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return path
+}
+
+func TestRegistryTLSConfig_Prepare(t *testing.T) {
+	caFile := testRegistryCAFile(t)
+
+	c := RegistryTLSConfig{InsecureRegistries: []string{"registry.example.com:5000"}, RegistryCAFile: caFile}
+	for _, driverType := range []string{DriverPodman, DriverBuildah, DriverNerdctl} {
+		if errs := c.Prepare(driverType); len(errs) > 0 {
+			t.Fatalf("%s: unexpected errors: %v", driverType, errs)
+		}
+	}
+	for _, driverType := range []string{"", DriverCLI, DriverAPI} {
+		if errs := c.Prepare(driverType); len(errs) != 1 {
+			t.Fatalf("%s: expected an error, got %v", driverType, errs)
+		}
+	}
+
+	if errs := (&RegistryTLSConfig{}).Prepare(DriverCLI); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	c = RegistryTLSConfig{InsecureRegistries: []string{"", "registry.example.com/library"}}
+	if errs := c.Prepare(DriverPodman); len(errs) != 2 {
+		t.Fatalf("expected an error per invalid registry, got %v", errs)
+	}
+
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, path := range []string{notPEM, filepath.Join(t.TempDir(), "missing.pem")} {
+		c = RegistryTLSConfig{RegistryCAFile: path}
+		if errs := c.Prepare(DriverPodman); len(errs) != 1 {
+			t.Fatalf("%s: expected an error, got %v", path, errs)
+		}
+	}
+}
+
+func TestRegistryTLSConfig_IsInsecure(t *testing.T) {
+	c := RegistryTLSConfig{InsecureRegistries: []string{"registry.example.com:5000", "localhost"}}
+
+	if !c.IsInsecure(ImageRegistryHost("registry.example.com:5000/app:1.0")) {
+		t.Fatal("expected the registry to be insecure")
+	}
+	if !c.IsInsecure(loginRegistryHost("https://localhost/")) {
+		t.Fatal("expected the login server to be insecure")
+	}
+	if c.IsInsecure(ImageRegistryHost("registry.example.com/app")) {
+		t.Fatal("expected the registry on another port to be secure")
+	}
+	if c.IsInsecure(loginRegistryHost("")) {
+		t.Fatal("expected Docker Hub to be secure")
+	}
+}
+
+func TestRegistryTLSConfig_writeCA(t *testing.T) {
+	caFile := testRegistryCAFile(t)
+	c := RegistryTLSConfig{RegistryCAFile: caFile}
+
+	expected, err := os.ReadFile(caFile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, host := range []string{"", "registry.example.com:5000"} {
+		dir, cleanup, err := c.writeCA(host)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		contents, err := os.ReadFile(filepath.Join(dir, host, "ca.crt"))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if string(contents) != string(expected) {
+			t.Fatalf("bad CA file: %s", contents)
+		}
+
+		cleanup()
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed: %v", dir, err)
+		}
+	}
+
+	dir, cleanup, err := (&RegistryTLSConfig{}).writeCA("")
+	if err != nil || dir != "" {
+		t.Fatalf("expected no directory without a CA file: %q, %v", dir, err)
+	}
+	cleanup()
+}
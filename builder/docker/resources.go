@@ -0,0 +1,57 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cpusetRe matches a list of CPUs, like `0-3` or `0,2`.
+var cpusetRe = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+// parseCpus parses a number of CPUs like `docker run --cpus`, and returns
+// it in billionths of CPUs, as expected by the API.
+func parseCpus(value string) (int64, error) {
+	cpus, err := strconv.ParseFloat(value, 64)
+	if err != nil || cpus <= 0 {
+		return 0, fmt.Errorf("`cpus` must be a positive number, got %q", value)
+	}
+	return int64(cpus * 1e9), nil
+}
+
+// parseBytes parses a size like `docker run --memory`: a number of bytes
+// with an optional b, k, m or g unit, like `512m` or `2g`. `-1` means
+// unlimited.
+func parseBytes(value string) (int64, error) {
+	if value == "-1" {
+		return -1, nil
+	}
+
+	s := strings.ToLower(strings.TrimSpace(value))
+	// Both `512m` and `512mb` are accepted
+	if len(s) > 2 && strings.HasSuffix(s, "b") && strings.ContainsAny(s[len(s)-2:len(s)-1], "kmg") {
+		s = s[:len(s)-1]
+	}
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "m"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "g"):
+		multiplier = 1 << 30
+	}
+	s = strings.TrimRight(s, "bkmg")
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not a valid size, expected a number with an optional b, k, m or g unit", value)
+	}
+
+	return n * multiplier, nil
+}
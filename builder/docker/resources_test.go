@@ -0,0 +1,54 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import "testing"
+
+func TestParseCpus(t *testing.T) {
+	for value, expected := range map[string]int64{
+		"1":   1000000000,
+		"1.5": 1500000000,
+		"0.5": 500000000,
+	} {
+		nanoCpus, err := parseCpus(value)
+		if err != nil {
+			t.Fatalf("%s: %s", value, err)
+		}
+		if nanoCpus != expected {
+			t.Errorf("%s: got %d, expected %d", value, nanoCpus, expected)
+		}
+	}
+
+	for _, value := range []string{"0", "-1", "two"} {
+		if _, err := parseCpus(value); err == nil {
+			t.Errorf("%s: should error", value)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for value, expected := range map[string]int64{
+		"1024":  1024,
+		"512b":  512,
+		"4k":    4 << 10,
+		"512m":  512 << 20,
+		"512MB": 512 << 20,
+		"2g":    2 << 30,
+		"-1":    -1,
+	} {
+		n, err := parseBytes(value)
+		if err != nil {
+			t.Fatalf("%s: %s", value, err)
+		}
+		if n != expected {
+			t.Errorf("%s: got %d, expected %d", value, n, expected)
+		}
+	}
+
+	for _, value := range []string{"", "0", "2t", "g", "lots"} {
+		if _, err := parseBytes(value); err == nil {
+			t.Errorf("%q: should error", value)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// verifyRuntime returns an error if the daemon doesn't have the runtime the
+// container should run with, so that a build that must run in a sandboxed
+// runtime, like gVisor or Kata Containers, fails before anything is pulled
+// rather than when the container is started.
+func verifyRuntime(driver Driver, runtime string) error {
+	runtimes, err := driver.Runtimes()
+	if err != nil {
+		return fmt.Errorf("Failed to list the runtimes of the daemon to check for the %s runtime: %s", runtime, err)
+	}
+	if runtimes == nil {
+		return nil
+	}
+
+	for _, r := range runtimes {
+		if r == runtime {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("`runtime` is %s, but the daemon has no such runtime (found: %s). "+
+		"Install and register it in the `runtimes` of the daemon.json of the Docker host, then restart the daemon.",
+		runtime, strings.Join(runtimes, ", "))
+}
@@ -0,0 +1,31 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerifyRuntime(t *testing.T) {
+	driver := &MockDriver{RuntimesResult: []string{"runc", "runsc"}}
+	if err := verifyRuntime(driver, "runsc"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := verifyRuntime(driver, "kata-runtime"); err == nil {
+		t.Fatal("should error without the runtime")
+	}
+
+	// Drivers that can't list the runtimes are trusted
+	driver = &MockDriver{}
+	if err := verifyRuntime(driver, "runsc"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver = &MockDriver{RuntimesErr: errors.New("foo")}
+	if err := verifyRuntime(driver, "runsc"); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type ScanConfig
+
+package docker
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The scanners the source image can be scanned with.
+const (
+	ScanToolTrivy = "trivy"
+	ScanToolGrype = "grype"
+	ScanToolScout = "docker-scout"
+)
+
+// The severity threshold of the scans.
+const defaultScanSeverity = "critical"
+
+// The severities of the vulnerabilities, from the lowest.
+var scanSeverities = []string{"low", "medium", "high", "critical"}
+
+// ScanConfig scans the source image for known vulnerabilities before the
+// container is started, and fails the build if the image has
+// vulnerabilities of the severity threshold or above, rather than after
+// the provisioning ran.
+//
+// ```hcl
+//
+//	scan_image {
+//	  tool           = "trivy"
+//	  severity       = "high"
+//	  ignore_unfixed = true
+//	}
+//
+// ```
+type ScanConfig struct {
+	// The scanner: `trivy` (the default), `grype`, or `docker-scout` with
+	// the cli and api drivers.
+	Tool string `mapstructure:"tool" required:"false"`
+	// The path to the executable of the scanner. Defaults to the name of the
+	// scanner, or to the docker executable for `docker-scout`, which is a
+	// plugin of the docker CLI.
+	Path string `mapstructure:"path" required:"false"`
+	// The lowest severity of the vulnerabilities that fail the build: `low`,
+	// `medium`, `high` or `critical`. Defaults to `critical`.
+	Severity string `mapstructure:"severity" required:"false"`
+	// If true, the vulnerabilities that have no fix yet don't fail the
+	// build. Not supported by `docker-scout`.
+	IgnoreUnfixed bool `mapstructure:"ignore_unfixed" required:"false"`
+}
+
+// IsDefault returns true if the block isn't set, in which case the image
+// isn't scanned.
+func (c *ScanConfig) IsDefault() bool {
+	return *c == ScanConfig{}
+}
+
+// Prepare validates the options for the given driver and sets the
+// defaults.
+func (c *ScanConfig) Prepare(driverType string) []error {
+	if c.IsDefault() {
+		return nil
+	}
+
+	var errs []error
+	if c.Tool == "" {
+		c.Tool = ScanToolTrivy
+	}
+	if c.Severity == "" {
+		c.Severity = defaultScanSeverity
+	}
+	c.Severity = strings.ToLower(c.Severity)
+
+	switch c.Tool {
+	case ScanToolTrivy, ScanToolGrype:
+		if c.Path == "" {
+			c.Path = c.Tool
+		}
+	case ScanToolScout:
+		switch driverType {
+		case "", DriverCLI, DriverAPI:
+		default:
+			errs = append(errs, fmt.Errorf("`scan_image`: %s requires the docker daemon, it's not supported by the %s driver", c.Tool, driverType))
+		}
+		if c.IgnoreUnfixed {
+			errs = append(errs, fmt.Errorf("`scan_image`: `ignore_unfixed` is not supported by %s", c.Tool))
+		}
+		if c.Path == "" {
+			c.Path = DefaultExecutable(DriverCLI)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("`scan_image`: unknown tool %q, expected one of %s, %s or %s",
+			c.Tool, ScanToolTrivy, ScanToolGrype, ScanToolScout))
+	}
+
+	if c.severities() == nil {
+		errs = append(errs, fmt.Errorf("`scan_image`: unknown severity %q, expected one of %s",
+			c.Severity, strings.Join(scanSeverities, ", ")))
+	}
+
+	return errs
+}
+
+// severities returns the severity threshold and the ones above it.
+func (c *ScanConfig) severities() []string {
+	for i, severity := range scanSeverities {
+		if severity == c.Severity {
+			return scanSeverities[i:]
+		}
+	}
+	return nil
+}
+
+// command returns the command that scans the image, which exits with an
+// error if the image has vulnerabilities above the threshold. The image is
+// read from the store of the engine of the driver.
+func (c *ScanConfig) command(driverType, image string) *exec.Cmd {
+	var args []string
+	switch c.Tool {
+	case ScanToolTrivy:
+		args = []string{"image", "--exit-code", "1",
+			"--severity", strings.ToUpper(strings.Join(c.severities(), ","))}
+		if c.IgnoreUnfixed {
+			args = append(args, "--ignore-unfixed")
+		}
+		switch driverType {
+		case DriverPodman, DriverBuildah:
+			args = append(args, "--image-src", "podman")
+		case DriverNerdctl:
+			args = append(args, "--image-src", "containerd")
+		}
+		args = append(args, image)
+	case ScanToolGrype:
+		source := "docker:"
+		switch driverType {
+		case DriverPodman, DriverBuildah:
+			source = "podman:"
+		case DriverNerdctl:
+			// grype can't read the containerd store, the image is scanned
+			// from its registry.
+			source = "registry:"
+		}
+		args = []string{source + image, "--fail-on", c.Severity}
+		if c.IgnoreUnfixed {
+			args = append(args, "--only-fixed")
+		}
+	case ScanToolScout:
+		args = []string{"scout", "cves", "--exit-code",
+			"--only-severity", strings.Join(c.severities(), ","), "local://" + image}
+	}
+	return exec.Command(c.Path, args...)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanConfig_Prepare(t *testing.T) {
+	c := ScanConfig{Severity: "HIGH"}
+	if errs := c.Prepare(DriverCLI); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if c.Tool != ScanToolTrivy || c.Path != "trivy" || c.Severity != "high" {
+		t.Fatalf("bad defaults: %#v", c)
+	}
+
+	c = ScanConfig{Tool: ScanToolScout}
+	if errs := c.Prepare(""); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if c.Path != "docker" || c.Severity != defaultScanSeverity {
+		t.Fatalf("bad defaults: %#v", c)
+	}
+
+	invalid := map[string]ScanConfig{
+		DriverPodman: {Tool: ScanToolScout},
+		DriverCLI:    {Tool: ScanToolScout, IgnoreUnfixed: true},
+		"":           {Tool: "clair"},
+		DriverAPI:    {Severity: "severe"},
+	}
+	for driverType, c := range invalid {
+		if errs := c.Prepare(driverType); len(errs) != 1 {
+			t.Errorf("%#v: expected an error, got %v", c, errs)
+		}
+	}
+}
+
+func TestScanConfig_command(t *testing.T) {
+	cases := []struct {
+		config     ScanConfig
+		driverType string
+		expected   []string
+	}{
+		{
+			ScanConfig{Severity: "high", IgnoreUnfixed: true},
+			DriverCLI,
+			[]string{"trivy", "image", "--exit-code", "1", "--severity", "HIGH,CRITICAL", "--ignore-unfixed", "ubuntu"},
+		},
+		{
+			ScanConfig{Tool: ScanToolTrivy},
+			DriverPodman,
+			[]string{"trivy", "image", "--exit-code", "1", "--severity", "CRITICAL", "--image-src", "podman", "ubuntu"},
+		},
+		{
+			ScanConfig{Tool: ScanToolGrype, Severity: "medium", IgnoreUnfixed: true},
+			DriverCLI,
+			[]string{"grype", "docker:ubuntu", "--fail-on", "medium", "--only-fixed"},
+		},
+		{
+			ScanConfig{Tool: ScanToolGrype},
+			DriverNerdctl,
+			[]string{"grype", "registry:ubuntu", "--fail-on", "critical"},
+		},
+		{
+			ScanConfig{Tool: ScanToolScout, Severity: "high"},
+			DriverAPI,
+			[]string{"docker", "scout", "cves", "--exit-code", "--only-severity", "high,critical", "local://ubuntu"},
+		},
+	}
+	for _, tc := range cases {
+		if errs := tc.config.Prepare(tc.driverType); len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		if args := tc.config.command(tc.driverType, "ubuntu").Args; !reflect.DeepEqual(args, tc.expected) {
+			t.Errorf("bad args: %q", args)
+		}
+	}
+}
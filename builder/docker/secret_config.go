@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type SecretConfig
+
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// The directory of the container the secrets are written to. It is a tmpfs,
+// which is never part of the committed or exported image.
+const secretsDir = "/run/secrets"
+
+// idRe matches the ids of the secrets and the keys of the caches, which
+// are used in the names of files and volumes.
+var idRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// SecretConfig is a secret the provisioners need, like a token to download
+// private packages, which must not end up in the image. It is written to a
+// file of a tmpfs mounted on `/run/secrets` once the communicator is
+// connected, and the changes of the container are checked to not contain
+// it before it is committed.
+//
+// ```hcl
+//
+//	secrets {
+//	  id  = "npm_token"
+//	  env = "NPM_TOKEN"
+//	}
+//
+// ```
+type SecretConfig struct {
+	// The name of the secret, like `npm_token`.
+	ID string `mapstructure:"id" required:"true"`
+	// The file of the host the secret is read from. One of `source` or
+	// `env` is required.
+	Source string `mapstructure:"source" required:"false"`
+	// The environment variable of the host the secret is read from. Its
+	// value is never shown in the logs, nor set in the environment of the
+	// container, since it would be committed with the image.
+	Env string `mapstructure:"env" required:"false"`
+	// The path of the secret in the container, which must be in
+	// `/run/secrets`. Defaults to `/run/secrets/<id>`.
+	Target string `mapstructure:"target" required:"false"`
+}
+
+// Prepare validates the options and sets the default target.
+func (c *SecretConfig) Prepare() []error {
+	var errs []error
+	if c.ID == "" {
+		errs = append(errs, fmt.Errorf("`secrets`: `id` is required"))
+	} else if !idRe.MatchString(c.ID) {
+		errs = append(errs, fmt.Errorf("`secrets`: %q is not a valid id", c.ID))
+	}
+
+	switch {
+	case c.Source == "" && c.Env == "":
+		errs = append(errs, fmt.Errorf("secret %q: one of `source` or `env` is required", c.ID))
+	case c.Source != "" && c.Env != "":
+		errs = append(errs, fmt.Errorf("secret %q: only one of `source` or `env` can be set", c.ID))
+	case c.Source != "":
+		if fi, err := os.Stat(c.Source); err != nil {
+			errs = append(errs, fmt.Errorf("secret %q: `source`: %s", c.ID, err))
+		} else if !fi.Mode().IsRegular() {
+			errs = append(errs, fmt.Errorf("secret %q: `source`: %s is not a file", c.ID, c.Source))
+		}
+	case c.Env != "":
+		if _, ok := os.LookupEnv(c.Env); !ok {
+			errs = append(errs, fmt.Errorf("secret %q: the environment variable %s is not set", c.ID, c.Env))
+		}
+	}
+
+	if c.Target == "" {
+		c.Target = path.Join(secretsDir, c.ID)
+	}
+	if path.Clean(c.Target) != c.Target || !strings.HasPrefix(c.Target, secretsDir+"/") ||
+		strings.ContainsAny(c.Target, "'\n") {
+		errs = append(errs, fmt.Errorf("secret %q: `target` must be a path in %s", c.ID, secretsDir))
+	}
+
+	return errs
+}
+
+// value reads the secret from the host.
+func (c *SecretConfig) value() ([]byte, error) {
+	if c.Env != "" {
+		return []byte(os.Getenv(c.Env)), nil
+	}
+	return os.ReadFile(c.Source)
+}
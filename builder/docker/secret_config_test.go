@@ -0,0 +1,53 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecretConfigPrepare(t *testing.T) {
+	t.Setenv("PACKER_TEST_TOKEN", "s3cr3t")
+	source := filepath.Join(t.TempDir(), "netrc")
+	if err := os.WriteFile(source, []byte("machine example.com"), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	c := SecretConfig{ID: "token", Env: "PACKER_TEST_TOKEN"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.Target != "/run/secrets/token" {
+		t.Fatalf("bad target: %q", c.Target)
+	}
+	if value, err := c.value(); err != nil || string(value) != "s3cr3t" {
+		t.Fatalf("bad value: %q, %v", value, err)
+	}
+
+	c = SecretConfig{ID: "netrc", Source: source, Target: "/run/secrets/git/netrc"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if value, err := c.value(); err != nil || string(value) != "machine example.com" {
+		t.Fatalf("bad value: %q, %v", value, err)
+	}
+
+	for _, c := range []SecretConfig{
+		{Env: "PACKER_TEST_TOKEN"},
+		{ID: "../token", Env: "PACKER_TEST_TOKEN"},
+		{ID: "token"},
+		{ID: "token", Env: "PACKER_TEST_TOKEN", Source: source},
+		{ID: "token", Env: "PACKER_TEST_UNSET"},
+		{ID: "token", Source: filepath.Dir(source)},
+		{ID: "token", Env: "PACKER_TEST_TOKEN", Target: "/root/.npmrc"},
+		{ID: "token", Env: "PACKER_TEST_TOKEN", Target: "/run/secrets/../token"},
+		{ID: "token", Env: "PACKER_TEST_TOKEN", Target: "/run/secrets"},
+	} {
+		if errs := c.Prepare(); len(errs) == 0 {
+			t.Errorf("should error: %#v", c)
+		}
+	}
+}
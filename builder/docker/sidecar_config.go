@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type SidecarConfig
+
+package docker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SidecarConfig is an auxiliary container started before the build
+// container, and removed once the build is complete, like a temporary
+// database the provisioners run migrations or integration checks against.
+// It is attached to the network of the build, where the build container
+// reaches it by its name.
+//
+// ```hcl
+//
+//	sidecar {
+//	  name  = "db"
+//	  image = "postgres:16"
+//	  env = {
+//	    POSTGRES_PASSWORD = "packer"
+//	  }
+//	}
+//
+// ```
+type SidecarConfig struct {
+	// The name the sidecar is reached with on the network, like `db`.
+	Name string `mapstructure:"name" required:"true"`
+	// The image of the sidecar. It is pulled if it is missing.
+	Image string `mapstructure:"image" required:"true"`
+	// The environment variables of the sidecar. Their values are not
+	// shown in the logs.
+	Env map[string]string `mapstructure:"env" required:"false"`
+	// The command of the sidecar, given as arguments to its entrypoint.
+	// Defaults to the command of its image.
+	Cmd []string `mapstructure:"cmd" required:"false"`
+	// The network the sidecar is attached to. Defaults to the `network` of
+	// the build container, which must then be set.
+	Network string `mapstructure:"network" required:"false"`
+}
+
+// Prepare validates the options and defaults the network to the one of the
+// build container.
+func (c *SidecarConfig) Prepare(network string) []error {
+	var errs []error
+	if c.Name == "" {
+		errs = append(errs, fmt.Errorf("`sidecar`: `name` is required"))
+	} else if !hostnameRe.MatchString(c.Name) {
+		errs = append(errs, fmt.Errorf("`sidecar`: %q is not a valid name", c.Name))
+	}
+	if c.Image == "" {
+		errs = append(errs, fmt.Errorf("sidecar %q: `image` is required", c.Name))
+	}
+	for k := range c.Env {
+		if k == "" || strings.ContainsAny(k, "= ") {
+			errs = append(errs, fmt.Errorf("sidecar %q: `env`: %q is not a valid variable name", c.Name, k))
+		}
+	}
+
+	if c.Network == "" {
+		c.Network = network
+	}
+	switch c.Network {
+	case "":
+		errs = append(errs, fmt.Errorf("sidecar %q: a `network` is required for the build container to reach it by name", c.Name))
+	case "host", "none", "bridge", "default":
+		errs = append(errs, fmt.Errorf("sidecar %q: the %s network doesn't resolve the names of the containers, "+
+			"use a network created with `network_create`", c.Name, c.Network))
+	}
+
+	return errs
+}
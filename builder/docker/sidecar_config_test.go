@@ -0,0 +1,38 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import "testing"
+
+func TestSidecarConfigPrepare(t *testing.T) {
+	c := SidecarConfig{Name: "db", Image: "postgres:16"}
+	if errs := c.Prepare("packer"); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.Network != "packer" {
+		t.Fatalf("bad network: %q", c.Network)
+	}
+
+	c = SidecarConfig{Name: "db", Image: "postgres:16", Network: "other"}
+	if errs := c.Prepare("packer"); len(errs) > 0 || c.Network != "other" {
+		t.Fatalf("bad: %#v, %q", errs, c.Network)
+	}
+
+	for _, c := range []SidecarConfig{
+		{Image: "postgres:16"},
+		{Name: "db_1", Image: "postgres:16"},
+		{Name: "db"},
+		{Name: "db", Image: "postgres:16", Network: "host"},
+		{Name: "db", Image: "postgres:16", Env: map[string]string{"A B": "1"}},
+	} {
+		if errs := c.Prepare("packer"); len(errs) == 0 {
+			t.Errorf("should error: %#v", c)
+		}
+	}
+
+	c = SidecarConfig{Name: "db", Image: "postgres:16"}
+	if errs := c.Prepare(""); len(errs) == 0 {
+		t.Error("should error without a network")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type SignatureConfig
+
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// The tools the signature of the source image can be verified with.
+const (
+	SignatureToolCosign   = "cosign"
+	SignatureToolNotation = "notation"
+)
+
+// SignatureConfig verifies the signature of the source image before the
+// container is started, so that nothing runs from an image that isn't
+// trusted. The verified reference is the digest of the image that was
+// pulled, in the repository of `image`.
+//
+// ```hcl
+//
+//	verify_signature {
+//	  identity = "https://github.com/example/base-images/.github/workflows/release.yml@refs/heads/main"
+//	  issuer   = "https://token.actions.githubusercontent.com"
+//	}
+//
+// ```
+type SignatureConfig struct {
+	// The tool to verify the signature with: `cosign` (the default), or
+	// `notation`, which verifies the image against its own trust policy
+	// and trust store, so `key`, `identity` and `issuer` don't apply.
+	Tool string `mapstructure:"tool" required:"false"`
+	// The path to the executable of the tool. Defaults to the name of the
+	// tool.
+	Path string `mapstructure:"path" required:"false"`
+	// The public key of the signer with cosign, as a path, an URL or a KMS
+	// URI, like `cosign verify --key`.
+	Key string `mapstructure:"key" required:"false"`
+	// The identity of the signer, for keyless signatures with cosign, like
+	// `cosign verify --certificate-identity`. Requires `issuer`.
+	Identity string `mapstructure:"identity" required:"false"`
+	// The OIDC issuer of the identity of the signer, like `cosign verify
+	// --certificate-oidc-issuer`. Requires `identity`.
+	Issuer string `mapstructure:"issuer" required:"false"`
+}
+
+// IsDefault returns true if the block isn't set, in which case the
+// signature isn't verified.
+func (c *SignatureConfig) IsDefault() bool {
+	return *c == SignatureConfig{}
+}
+
+// Prepare validates the options and sets the defaults.
+func (c *SignatureConfig) Prepare() []error {
+	if c.IsDefault() {
+		return nil
+	}
+
+	var errs []error
+	if c.Tool == "" {
+		c.Tool = SignatureToolCosign
+	}
+	if c.Path == "" {
+		c.Path = c.Tool
+	}
+
+	switch c.Tool {
+	case SignatureToolCosign:
+		switch {
+		case c.Key != "" && (c.Identity != "" || c.Issuer != ""):
+			errs = append(errs, errors.New("`verify_signature`: `key` can't be set with `identity` and `issuer`"))
+		case c.Key == "" && (c.Identity == "" || c.Issuer == ""):
+			errs = append(errs, errors.New("`verify_signature`: cosign requires either `key`, or both `identity` and `issuer`"))
+		}
+	case SignatureToolNotation:
+		if c.Key != "" || c.Identity != "" || c.Issuer != "" {
+			errs = append(errs, errors.New("`verify_signature`: `key`, `identity` and `issuer` are not supported by notation, "+
+				"which uses its trust policy"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("`verify_signature`: unknown tool %q, expected %s or %s",
+			c.Tool, SignatureToolCosign, SignatureToolNotation))
+	}
+
+	return errs
+}
+
+// command returns the command that verifies the signature of the image
+// reference.
+func (c *SignatureConfig) command(reference string) *exec.Cmd {
+	args := []string{"verify"}
+	if c.Tool == SignatureToolCosign {
+		if c.Key != "" {
+			args = append(args, "--key", c.Key)
+		} else {
+			args = append(args, "--certificate-identity", c.Identity, "--certificate-oidc-issuer", c.Issuer)
+		}
+		args = append(args, "--output", "text")
+	}
+	return exec.Command(c.Path, append(args, reference)...)
+}
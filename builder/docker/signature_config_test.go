@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSignatureConfig_Prepare(t *testing.T) {
+	valid := []SignatureConfig{
+		{},
+		{Key: "cosign.pub"},
+		{Identity: "release@example.com", Issuer: "https://accounts.example.com"},
+		{Tool: SignatureToolNotation},
+	}
+	for _, c := range valid {
+		if errs := c.Prepare(); len(errs) > 0 {
+			t.Errorf("%#v: unexpected errors: %v", c, errs)
+		}
+	}
+
+	invalid := []SignatureConfig{
+		{Tool: SignatureToolCosign},
+		{Identity: "release@example.com"},
+		{Key: "cosign.pub", Issuer: "https://accounts.example.com"},
+		{Tool: SignatureToolNotation, Key: "cosign.pub"},
+		{Tool: "gpg", Key: "key.asc"},
+	}
+	for _, c := range invalid {
+		if errs := c.Prepare(); len(errs) != 1 {
+			t.Errorf("%#v: expected an error, got %v", c, errs)
+		}
+	}
+}
+
+func TestSignatureConfig_command(t *testing.T) {
+	const reference = "ubuntu@sha256:a0d9e826ab87bd665cfc640598a871b748b4b70a01a4f3d174d4fb02adad07a9"
+
+	cases := []struct {
+		config   SignatureConfig
+		expected []string
+	}{
+		{
+			SignatureConfig{Key: "cosign.pub"},
+			[]string{"cosign", "verify", "--key", "cosign.pub", "--output", "text", reference},
+		},
+		{
+			SignatureConfig{Identity: "release@example.com", Issuer: "https://accounts.example.com"},
+			[]string{"cosign", "verify", "--certificate-identity", "release@example.com",
+				"--certificate-oidc-issuer", "https://accounts.example.com", "--output", "text", reference},
+		},
+		{
+			SignatureConfig{Tool: SignatureToolNotation, Path: "/usr/local/bin/notation"},
+			[]string{"/usr/local/bin/notation", "verify", reference},
+		},
+	}
+	for _, tc := range cases {
+		if errs := tc.config.Prepare(); len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		if args := tc.config.command(reference).Args; !reflect.DeepEqual(args, tc.expected) {
+			t.Errorf("bad args: %q", args)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// SnapshotCommand is the command the docker-snapshot provisioner runs to ask
+// the communicator for a snapshot, followed by the name of the snapshot. It
+// is never run in the container.
+const SnapshotCommand = "packer-docker-snapshot"
+
+const (
+	// The label with the name of the snapshot an image was committed for.
+	snapshotLabel = "io.packer.snapshot"
+	// The tag of the last snapshot that was taken in the repository.
+	latestSnapshotTag = "latest"
+)
+
+var snapshotNameRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// ValidateSnapshotName returns an error if name can't be used as the tag of a
+// snapshot.
+func ValidateSnapshotName(name string) error {
+	if !snapshotNameRe.MatchString(name) {
+		return fmt.Errorf("%q is not a valid snapshot name, it must be a valid image tag", name)
+	}
+	if name == latestSnapshotTag {
+		return fmt.Errorf("%q is reserved and can't be used as a snapshot name", name)
+	}
+	return nil
+}
+
+// snapshotName returns the name of the snapshot if command was sent by the
+// docker-snapshot provisioner.
+func snapshotName(command string) (string, bool) {
+	name, ok := strings.CutPrefix(command, SnapshotCommand+" ")
+	return strings.TrimSpace(name), ok
+}
+
+// snapshotter commits the build container to the snapshot repository when
+// the docker-snapshot provisioner asks for it.
+//
+// When the build resumes from a snapshot, the container is started from it,
+// and the commands and uploads of the provisioners that ran before the
+// snapshot was taken are skipped until the provisioner of the snapshot is
+// reached.
+type snapshotter struct {
+	driver      Driver
+	repository  string
+	containerId string
+
+	// The directory the processes of the container are checkpointed to with
+	// each snapshot, if set.
+	checkpointDir string
+
+	lock sync.Mutex
+	// The name of the snapshot the build resumes from, until it is reached.
+	resumeFrom string
+	// The names of the snapshots the provisioners asked for.
+	names []string
+}
+
+// skipping returns true while the provisioners that ran before the resumed
+// snapshot are replayed.
+func (s *snapshotter) skipping() bool {
+	if s == nil {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.resumeFrom != ""
+}
+
+// handle runs the snapshot request of the provisioner, and reports it as
+// the result of the remote command.
+func (s *snapshotter) handle(remote *packersdk.RemoteCmd, name string) error {
+	if s == nil {
+		return fmt.Errorf("snapshot %q requires `snapshot_repository` to be set in the docker builder", name)
+	}
+	if err := ValidateSnapshotName(name); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.names = append(s.names, name)
+	if s.resumeFrom != "" {
+		if s.resumeFrom == name {
+			log.Printf("Reached snapshot %s, resuming the provisioning", name)
+			s.resumeFrom = ""
+		}
+		remote.SetExited(0)
+		return nil
+	}
+
+	if err := s.take(name); err != nil {
+		return err
+	}
+
+	remote.SetExited(0)
+	return nil
+}
+
+// take commits the container and tags it in the repository with the name
+// of the snapshot, and as the latest snapshot. With a checkpoint directory,
+// the processes of the container are checkpointed first.
+func (s *snapshotter) take(name string) error {
+	if s.checkpointDir != "" {
+		// A checkpoint of a previous run would clash with the new one
+		if err := os.RemoveAll(filepath.Join(s.checkpointDir, name)); err != nil {
+			return fmt.Errorf("Error removing the previous checkpoint %s: %s", name, err)
+		}
+		if err := s.driver.CheckpointContainer(s.containerId, name, s.checkpointDir); err != nil {
+			return fmt.Errorf("Error checkpointing snapshot %s: %s", name, err)
+		}
+	}
+
+	change := fmt.Sprintf("LABEL %s=%s", snapshotLabel, quoteChangeValue(name))
+	id, err := s.driver.Commit(s.containerId, "", []string{change}, "Packer snapshot "+name)
+	if err != nil {
+		return fmt.Errorf("Error taking snapshot %s: %s", name, err)
+	}
+
+	for _, tag := range []string{name, latestSnapshotTag} {
+		if err := s.driver.TagImage(id, s.repository+":"+tag, true); err != nil {
+			return fmt.Errorf("Error tagging snapshot %s: %s", name, err)
+		}
+	}
+
+	log.Printf("Took snapshot %s: %s", name, id)
+	return nil
+}
+
+// remove removes the tags of all the snapshots of the build.
+func (s *snapshotter) remove() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, tag := range append(s.names, latestSnapshotTag) {
+		image := s.repository + ":" + tag
+		if err := s.driver.DeleteImage(image); err != nil {
+			log.Printf("Error removing snapshot %s: %s", image, err)
+		}
+	}
+	if s.checkpointDir != "" {
+		for _, name := range s.names {
+			if err := os.RemoveAll(filepath.Join(s.checkpointDir, name)); err != nil {
+				log.Printf("Error removing checkpoint %s: %s", name, err)
+			}
+		}
+	}
+}
+
+// checkpointDirName returns the name of the directory of the Packer cache
+// the checkpoints of the snapshots of the repository are written to.
+func checkpointDirName(repository string) string {
+	return strings.NewReplacer("/", "_", ":", "_").Replace(repository)
+}
+
+// hasCheckpoint returns true if the checkpoint of the snapshot with the
+// given name exists in the directory.
+func hasCheckpoint(dir, name string) bool {
+	if dir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+// restoreCheckpoint restores the processes of the container from the
+// checkpoint of the snapshot it was started from, which requires the
+// container to be stopped first.
+func restoreCheckpoint(driver Driver, containerId, name, dir string) error {
+	if err := driver.StopContainer(containerId, "", 0); err != nil {
+		return fmt.Errorf("Error stopping the container to restore the checkpoint %s: %s", name, err)
+	}
+	if err := driver.RestoreContainer(containerId, name, dir); err != nil {
+		return fmt.Errorf("Error restoring the checkpoint %s: %s", name, err)
+	}
+	return nil
+}
+
+// latestSnapshot returns the image and name of the last snapshot taken in
+// the repository, or empty strings if there is none.
+func latestSnapshot(driver Driver, repository string) (string, string) {
+	image := repository + ":" + latestSnapshotTag
+	config, err := driver.ImageConfig(image)
+	if err != nil {
+		log.Printf("No snapshot to resume from: %s", err)
+		return "", ""
+	}
+
+	name := config.Labels[snapshotLabel]
+	if name == "" {
+		log.Printf("Image %s isn't a snapshot, not resuming from it", image)
+		return "", ""
+	}
+
+	return image, name
+}
@@ -0,0 +1,146 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestValidateSnapshotName(t *testing.T) {
+	for _, name := range []string{"after-deps", "step_1", "v1.0"} {
+		if err := ValidateSnapshotName(name); err != nil {
+			t.Errorf("%q: unexpected error: %s", name, err)
+		}
+	}
+	for _, name := range []string{"", "latest", "-deps", "after deps", "a/b"} {
+		if err := ValidateSnapshotName(name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
+
+func TestSnapshotName(t *testing.T) {
+	if name, ok := snapshotName(SnapshotCommand + " after-deps"); !ok || name != "after-deps" {
+		t.Fatalf("bad: %q, %t", name, ok)
+	}
+	if _, ok := snapshotName("echo " + SnapshotCommand); ok {
+		t.Fatal("only the snapshot command should be a snapshot request")
+	}
+}
+
+func TestSnapshotter_handle(t *testing.T) {
+	driver := &MockDriver{CommitImageId: "sha256:abc"}
+	s := &snapshotter{driver: driver, repository: "build", containerId: "foo"}
+
+	remote := &packersdk.RemoteCmd{Command: SnapshotCommand + " after-deps"}
+	if err := s.handle(remote, "after-deps"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if remote.ExitStatus() != 0 {
+		t.Fatalf("bad exit status: %d", remote.ExitStatus())
+	}
+	if !driver.CommitCalled || !reflect.DeepEqual(driver.CommitChanges, []string{`LABEL io.packer.snapshot="after-deps"`}) {
+		t.Fatalf("should've committed the snapshot: %v", driver.CommitChanges)
+	}
+	if !reflect.DeepEqual(driver.TagImageRepo, []string{"build:after-deps", "build:latest"}) {
+		t.Fatalf("bad tags: %v", driver.TagImageRepo)
+	}
+
+	driver.CommitErr = errors.New("commit failed")
+	if err := s.handle(&packersdk.RemoteCmd{}, "after-app"); err == nil {
+		t.Fatal("should've failed to take the snapshot")
+	}
+
+	var disabled *snapshotter
+	if err := disabled.handle(&packersdk.RemoteCmd{}, "after-deps"); err == nil {
+		t.Fatal("snapshots should require a repository")
+	}
+}
+
+func TestSnapshotter_resume(t *testing.T) {
+	driver := &MockDriver{}
+	s := &snapshotter{driver: driver, repository: "build", resumeFrom: "after-app"}
+
+	for _, name := range []string{"after-deps", "after-app"} {
+		if !s.skipping() {
+			t.Fatalf("should skip until %s is reached", s.resumeFrom)
+		}
+		if err := s.handle(&packersdk.RemoteCmd{}, name); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if s.skipping() {
+		t.Fatal("should've stopped skipping at the resumed snapshot")
+	}
+	if driver.CommitCalled {
+		t.Fatal("shouldn't take the snapshots that are resumed")
+	}
+}
+
+func TestSnapshotter_checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	// A checkpoint left by a previous run
+	if err := os.MkdirAll(filepath.Join(dir, "after-deps", "old"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &MockDriver{CommitImageId: "sha256:abc"}
+	s := &snapshotter{driver: driver, repository: "build", containerId: "foo", checkpointDir: dir}
+	if err := s.handle(&packersdk.RemoteCmd{}, "after-deps"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if driver.CheckpointID != "foo" || driver.CheckpointDir != dir || !reflect.DeepEqual(driver.CheckpointNames, []string{"after-deps"}) {
+		t.Fatalf("should've checkpointed the container: %q %q %v", driver.CheckpointID, driver.CheckpointDir, driver.CheckpointNames)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "after-deps", "old")); !os.IsNotExist(err) {
+		t.Fatal("should've removed the previous checkpoint")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "after-deps"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !hasCheckpoint(dir, "after-deps") || hasCheckpoint(dir, "after-app") || hasCheckpoint("", "after-deps") {
+		t.Fatal("bad checkpoint lookup")
+	}
+	s.remove()
+	if hasCheckpoint(dir, "after-deps") {
+		t.Fatal("should've removed the checkpoint with the snapshots")
+	}
+
+	driver.CommitCalled = false
+	driver.CheckpointErr = errors.New("criu failed")
+	if err := s.take("after-app"); err == nil {
+		t.Fatal("should've failed to checkpoint the snapshot")
+	}
+	if driver.CommitCalled {
+		t.Fatal("shouldn't commit a snapshot without its checkpoint")
+	}
+}
+
+func TestLatestSnapshot(t *testing.T) {
+	driver := &MockDriver{ImageConfigResult: &ImageConfig{
+		Labels: map[string]string{snapshotLabel: "after-deps"},
+	}}
+	image, name := latestSnapshot(driver, "build")
+	if image != "build:latest" || name != "after-deps" {
+		t.Fatalf("bad snapshot: %q, %q", image, name)
+	}
+
+	driver.ImageConfigResult = &ImageConfig{}
+	if image, _ := latestSnapshot(driver, "build"); image != "" {
+		t.Fatalf("an image without the snapshot label isn't a snapshot: %q", image)
+	}
+
+	driver.ImageConfigErr = errors.New("no such image")
+	if image, _ := latestSnapshot(driver, "build"); image != "" {
+		t.Fatalf("bad: %q", image)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// squashImage flattens the committed image to a single layer by exporting
+// the container and importing it again. The configuration of the committed
+// image is kept with import changes, except for the instructions `docker
+// import` can't apply, which are returned to be built on top of the image.
+func squashImage(driver Driver, containerId, imageId, platform string) (string, []string, error) {
+	config, err := driver.ImageConfig(imageId)
+	if err != nil {
+		return "", nil, fmt.Errorf("Error reading the configuration of the committed image: %s", err)
+	}
+
+	tarball, err := os.CreateTemp("", "packer-docker-squash-*.tar")
+	if err != nil {
+		return "", nil, fmt.Errorf("Error creating the file to export the container to: %s", err)
+	}
+	defer os.Remove(tarball.Name())
+
+	err = driver.Export(containerId, tarball)
+	if closeErr := tarball.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("Error exporting the container to squash it: %s", err)
+	}
+
+	changes, buildChanges := splitChanges(imageConfigChanges(config))
+	squashedId, err := driver.Import(tarball.Name(), changes, "", platform)
+	if err != nil {
+		return "", nil, fmt.Errorf("Error importing the squashed image: %s", err)
+	}
+
+	if err := driver.DeleteImage(imageId); err != nil {
+		log.Printf("Not removing intermediate image %s: %s", imageId, err)
+	}
+
+	return squashedId, buildChanges, nil
+}
+
+// imageConfigChanges returns the Dockerfile instructions that recreate the
+// configuration of an image.
+func imageConfigChanges(config *ImageConfig) []string {
+	var changes []string
+
+	if config.User != "" {
+		changes = append(changes, "USER "+config.User)
+	}
+	for _, env := range config.Env {
+		name, value, _ := strings.Cut(env, "=")
+		changes = append(changes, fmt.Sprintf("ENV %s=%s", name, quoteChangeValue(value)))
+	}
+	// ENTRYPOINT clears the CMD set before it, so it has to come first.
+	if len(config.Entrypoint) > 0 {
+		changes = append(changes, "ENTRYPOINT "+jsonArray(config.Entrypoint))
+	}
+	if len(config.Cmd) > 0 {
+		changes = append(changes, "CMD "+jsonArray(config.Cmd))
+	}
+	if config.WorkingDir != "" {
+		changes = append(changes, "WORKDIR "+config.WorkingDir)
+	}
+	for _, name := range sortedKeys(config.Labels) {
+		changes = append(changes, fmt.Sprintf("LABEL %s=%s", quoteChangeValue(name), quoteChangeValue(config.Labels[name])))
+	}
+	if ports := sortedSet(config.ExposedPorts); len(ports) > 0 {
+		changes = append(changes, "EXPOSE "+strings.Join(ports, " "))
+	}
+	if volumes := sortedSet(config.Volumes); len(volumes) > 0 {
+		changes = append(changes, "VOLUME "+jsonArray(volumes))
+	}
+	if config.StopSignal != "" {
+		changes = append(changes, "STOPSIGNAL "+config.StopSignal)
+	}
+	for _, trigger := range config.OnBuild {
+		changes = append(changes, "ONBUILD "+trigger)
+	}
+	if len(config.Shell) > 0 {
+		changes = append(changes, "SHELL "+jsonArray(config.Shell))
+	}
+	if config.Healthcheck != nil {
+		if change := healthcheckChange(config.Healthcheck); change != "" {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// healthcheckChange returns the HEALTHCHECK instruction of the healthcheck,
+// or an empty string if the healthcheck is inherited.
+func healthcheckChange(hc *ImageHealthcheck) string {
+	if len(hc.Test) == 0 {
+		return ""
+	}
+
+	var cmd string
+	switch hc.Test[0] {
+	case "NONE":
+		return "HEALTHCHECK NONE"
+	case "CMD":
+		cmd = jsonArray(hc.Test[1:])
+	case "CMD-SHELL":
+		cmd = strings.Join(hc.Test[1:], " ")
+	default:
+		return ""
+	}
+
+	var options []string
+	for _, option := range []struct {
+		name  string
+		value int64
+	}{
+		{"interval", hc.Interval},
+		{"timeout", hc.Timeout},
+		{"start-period", hc.StartPeriod},
+		{"start-interval", hc.StartInterval},
+	} {
+		if option.value > 0 {
+			options = append(options, fmt.Sprintf("--%s=%s", option.name, time.Duration(option.value)))
+		}
+	}
+	if hc.Retries > 0 {
+		options = append(options, fmt.Sprintf("--retries=%d", hc.Retries))
+	}
+
+	return strings.Join(append(append([]string{"HEALTHCHECK"}, options...), "CMD", cmd), " ")
+}
+
+// quoteChangeValue quotes a value of an ENV or LABEL instruction, so that
+// spaces, quotes and variables in it are kept as they are.
+func quoteChangeValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(value) + `"`
+}
+
+func jsonArray(values []string) string {
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+func sortedSet(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestImageConfigChanges(t *testing.T) {
+	config := &ImageConfig{
+		User:         "app",
+		Env:          []string{"PATH=/usr/bin:/bin", `GREETING=say "hi" to $USER`},
+		Cmd:          []string{"serve"},
+		Entrypoint:   []string{"/entrypoint.sh"},
+		WorkingDir:   "/app",
+		Labels:       map[string]string{"version": "1.0", "maintainer": "Jane Doe"},
+		ExposedPorts: map[string]struct{}{"8080/tcp": {}, "53/udp": {}},
+		Volumes:      map[string]struct{}{"/data": {}},
+		StopSignal:   "SIGTERM",
+		OnBuild:      []string{"RUN make"},
+		Shell:        []string{"/bin/bash", "-c"},
+		Healthcheck: &ImageHealthcheck{
+			Test:     []string{"CMD-SHELL", "curl -f http://localhost/"},
+			Interval: int64(30 * time.Second),
+			Retries:  3,
+		},
+	}
+
+	expected := []string{
+		"USER app",
+		`ENV PATH="/usr/bin:/bin"`,
+		`ENV GREETING="say \"hi\" to \$USER"`,
+		`ENTRYPOINT ["/entrypoint.sh"]`,
+		`CMD ["serve"]`,
+		"WORKDIR /app",
+		`LABEL "maintainer"="Jane Doe"`,
+		`LABEL "version"="1.0"`,
+		"EXPOSE 53/udp 8080/tcp",
+		`VOLUME ["/data"]`,
+		"STOPSIGNAL SIGTERM",
+		"ONBUILD RUN make",
+		`SHELL ["/bin/bash","-c"]`,
+		"HEALTHCHECK --interval=30s --retries=3 CMD curl -f http://localhost/",
+	}
+	if changes := imageConfigChanges(config); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("bad changes:\n%q\nexpected:\n%q", changes, expected)
+	}
+
+	if changes := imageConfigChanges(&ImageConfig{}); len(changes) != 0 {
+		t.Fatalf("expected no changes for an empty config, got %q", changes)
+	}
+}
+
+func TestHealthcheckChange(t *testing.T) {
+	cases := []struct {
+		healthcheck ImageHealthcheck
+		expected    string
+	}{
+		{ImageHealthcheck{}, ""},
+		{ImageHealthcheck{Test: []string{"NONE"}}, "HEALTHCHECK NONE"},
+		{ImageHealthcheck{Test: []string{"CMD", "true"}}, `HEALTHCHECK CMD ["true"]`},
+		{ImageHealthcheck{Test: []string{"CMD-SHELL", "exit 0"}, Timeout: int64(5 * time.Second)}, "HEALTHCHECK --timeout=5s CMD exit 0"},
+	}
+
+	for _, tc := range cases {
+		if change := healthcheckChange(&tc.healthcheck); change != tc.expected {
+			t.Errorf("%v: expected %q, got %q", tc.healthcheck.Test, tc.expected, change)
+		}
+	}
+}
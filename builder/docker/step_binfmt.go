@@ -0,0 +1,109 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// defaultBinfmtImage is the image installing the QEMU emulators with
+// `binfmt_install`.
+const defaultBinfmtImage = "tonistiigi/binfmt"
+
+// StepBinfmt installs the QEMU emulators of the architectures of the build
+// that the host of the daemon can't run natively, with `binfmt_install`.
+type StepBinfmt struct{}
+
+func (s *StepBinfmt) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if !config.BinfmtInstall {
+		return multistep.ActionContinue
+	}
+
+	platforms := config.BuildConfig.Platforms
+	if config.Platform != "" {
+		platforms = []string{config.Platform}
+	}
+
+	serverPlatform, err := driver.ServerPlatform()
+	if err != nil {
+		err := fmt.Errorf("Error reading the platform of the daemon: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	archs := emulatedArchs(serverPlatform, platforms)
+	if len(archs) == 0 {
+		log.Printf("The daemon runs on %s, no emulator is needed for %s", serverPlatform, strings.Join(platforms, ", "))
+		return multistep.ActionContinue
+	}
+
+	exists, err := driver.ImageExists(config.BinfmtImage)
+	if err == nil && !exists {
+		ui.Say(fmt.Sprintf("Pulling the binfmt installer %s", config.BinfmtImage))
+		err = driver.Pull(config.BinfmtImage, "")
+	}
+	if err != nil {
+		err := fmt.Errorf("Error pulling the binfmt installer %s: %s", config.BinfmtImage, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Installing the QEMU emulators of %s on the %s host", strings.Join(archs, ", "), serverPlatform))
+	output, err := driver.RunPrivileged(config.BinfmtImage, []string{"--install", strings.Join(archs, ",")})
+	if err != nil {
+		err := fmt.Errorf("Error installing the QEMU emulators, which requires a daemon that can run privileged containers: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	log.Printf("binfmt installer output: %s", output)
+
+	return multistep.ActionContinue
+}
+
+func (s *StepBinfmt) Cleanup(state multistep.StateBag) {}
+
+// emulatedArchs returns the architectures of the platforms, as
+// `os/arch[/variant]`, that the host of the server platform can't run
+// natively, without duplicates.
+func emulatedArchs(serverPlatform string, platforms []string) []string {
+	serverArch := platformArch(serverPlatform)
+
+	var archs []string
+	seen := map[string]bool{}
+	for _, platform := range platforms {
+		arch := platformArch(platform)
+		if arch == "" || arch == serverArch || seen[arch] {
+			continue
+		}
+		// amd64 hosts run 386 binaries natively
+		if arch == "386" && serverArch == "amd64" {
+			continue
+		}
+		seen[arch] = true
+		archs = append(archs, arch)
+	}
+	return archs
+}
+
+// platformArch returns the architecture of a platform, like `arm64` for
+// `linux/arm64/v8`.
+func platformArch(platform string) string {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,61 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepBinfmt(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.BinfmtInstall = true
+	config.BinfmtImage = defaultBinfmtImage
+	config.Platform = "linux/arm64/v8"
+	driver := state.Get("driver").(*MockDriver)
+	driver.ServerPlatformResult = "linux/amd64"
+
+	step := new(StepBinfmt)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !driver.PullCalled || driver.PullImage != defaultBinfmtImage {
+		t.Fatal("should've pulled the installer")
+	}
+	if driver.RunPrivilegedImage != defaultBinfmtImage || !reflect.DeepEqual(driver.RunPrivilegedArgs, []string{"--install", "arm64"}) {
+		t.Fatalf("bad installer run: %s %v", driver.RunPrivilegedImage, driver.RunPrivilegedArgs)
+	}
+
+	// The host runs its own architecture natively
+	driver = &MockDriver{ServerPlatformResult: "linux/arm64"}
+	state.Put("driver", driver)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.RunPrivilegedCalled {
+		t.Fatal("should not have installed emulators")
+	}
+
+	driver = &MockDriver{ServerPlatformResult: "linux/amd64", RunPrivilegedErr: errors.New("permission denied")}
+	state.Put("driver", driver)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
+func TestEmulatedArchs(t *testing.T) {
+	platforms := []string{"linux/amd64", "linux/386", "linux/arm64", "linux/arm/v7", "linux/arm/v6", "linux/riscv64"}
+	expected := []string{"arm64", "arm", "riscv64"}
+	if archs := emulatedArchs("linux/amd64", platforms); !reflect.DeepEqual(archs, expected) {
+		t.Fatalf("bad archs: %v", archs)
+	}
+	if archs := emulatedArchs("linux/arm64", []string{"linux/arm64"}); len(archs) != 0 {
+		t.Fatalf("bad archs: %v", archs)
+	}
+}
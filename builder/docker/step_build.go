@@ -33,12 +33,12 @@ func (s *stepBuild) Run(ctx context.Context, state multistep.StateBag) multistep
 
 	ui.Say("Building base image...")
 
-	if config.EcrLogin {
-		ui.Message("Fetching ECR credentials...")
+	if registry := config.cloudRegistry(); registry != "" {
+		ui.Message(fmt.Sprintf("Fetching %s credentials...", registry))
 
-		username, password, err := config.EcrGetLogin(config.LoginServer)
+		username, password, err := config.cloudRegistryCredentials(ctx)
 		if err != nil {
-			err := fmt.Errorf("Error fetching ECR credentials: %s", err)
+			err := fmt.Errorf("Error fetching %s credentials: %s", registry, err)
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -48,7 +48,7 @@ func (s *stepBuild) Run(ctx context.Context, state multistep.StateBag) multistep
 		config.LoginPassword = password
 	}
 
-	if config.Login || config.EcrLogin {
+	if config.Login || config.cloudRegistry() != "" {
 		ui.Message("Logging in...")
 		err := driver.Login(
 			config.LoginServer,
@@ -69,7 +69,12 @@ func (s *stepBuild) Run(ctx context.Context, state multistep.StateBag) multistep
 		}()
 	}
 
-	imageId, err := driver.Build(s.buildArgs.BuildArgs())
+	build := driver.Build
+	if s.buildArgs.Buildx {
+		build = driver.BuildX
+	}
+
+	imageId, err := build(s.buildArgs.BuildArgs())
 	if err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
@@ -6,6 +6,11 @@ package docker
 import (
 	"context"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
@@ -16,6 +21,9 @@ import (
 type StepCommit struct {
 	imageId       string
 	GeneratedData *packerbuilderdata.GeneratedData
+
+	// The ID of the container, if it was paused before the commit.
+	pausedId string
 }
 
 func (s *StepCommit) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -30,35 +38,154 @@ func (s *StepCommit) Run(ctx context.Context, state multistep.StateBag) multiste
 
 	driver := state.Get("driver").(Driver)
 	containerId := state.Get("container_id").(string)
-	if config.WindowsContainer {
-		// docker can't commit a running Windows container
-		err := driver.StopContainer(containerId)
-		if err != nil {
+	if config.WindowsContainer || config.stopsContainer() {
+		// docker can't commit a running Windows container, and the services
+		// started by the provisioners are given a chance to shut down
+		// cleanly when a stop signal or timeout is set.
+		if err := stopContainer(state, containerId); err != nil {
 			state.Put("error", err)
-			ui.Error(fmt.Sprintf("Error halting windows container for commit: %s",
-				err.Error()))
+			ui.Error(fmt.Sprintf("Error stopping the container for commit: %s", err))
 			return multistep.ActionHalt
 		}
 	}
+	if config.PauseBeforeCommit {
+		ui.Say("Pausing the container")
+		if err := driver.PauseContainer(containerId); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.pausedId = containerId
+	}
+
+	// buildah applies all the changes when committing, docker can't apply
+	// some of them to a container, so they are built on top of the image.
+	labelsCtx := imageLabelsContext(state, config, time.Now())
+	labelChanges, err := imageLabelChanges(config.Labels, &labelsCtx)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	changes := append(append([]string{}, config.Changes...), labelChanges...)
+	var buildChanges []string
+	if config.DriverType != DriverBuildah {
+		changes, buildChanges = splitChanges(changes)
+	}
+
 	ui.Say("Committing the container")
-	imageId, err := driver.Commit(containerId, config.Author, config.Changes, config.Message)
+	imageId, err := driver.Commit(containerId, config.Author, changes, config.Message)
 	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	// Save the container ID to state and to generated data
-	s.imageId = imageId
-	state.Put("image_id", s.imageId)
-	s256, err := driver.Sha256(s.imageId)
-	if err == nil {
-		s.GeneratedData.Put("ImageSha256", s256)
+	if config.Squash {
+		ui.Say("Squashing the image to a single layer")
+		var squashChanges []string
+		imageId, squashChanges, err = squashImage(driver, containerId, imageId, config.Platform)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		buildChanges = append(squashChanges, buildChanges...)
+	}
+
+	if len(buildChanges) > 0 {
+		ui.Say("Building the image with the changes docker commit can't apply")
+		imageId, err = buildChangesOnImage(driver, imageId, buildChanges)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
 	}
 
+	s.imageId = imageId
+	putImage(state, s.GeneratedData, imageId)
 	ui.Message(fmt.Sprintf("Image ID: %s", s.imageId))
 
 	return multistep.ActionContinue
 }
 
-func (s *StepCommit) Cleanup(state multistep.StateBag) {}
+// stopContainer stops the container with the `stop_signal` and
+// `stop_timeout`, and records that it's stopped, so that the cleanup doesn't
+// stop it again.
+func stopContainer(state multistep.StateBag, containerId string) error {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say(fmt.Sprintf("Stopping the container: %s", containerId))
+	if err := driver.StopContainer(containerId, config.StopSignal, config.StopTimeout); err != nil {
+		return err
+	}
+	state.Put("container_stopped", true)
+	return nil
+}
+
+// putImage saves the ID of the image that is the artifact of the build to
+// state and to generated data, along with its sha256, platform and
+// metadata.
+func putImage(state multistep.StateBag, generatedData *packerbuilderdata.GeneratedData, imageId string) {
+	driver := state.Get("driver").(Driver)
+	state.Put("image_id", imageId)
+	generatedData.Put("ImageID", imageId)
+	if s256, err := driver.Sha256(imageId); err == nil {
+		generatedData.Put("ImageSha256", s256)
+	}
+	if platform, err := driver.ImagePlatform(imageId); err == nil {
+		generatedData.Put("ImagePlatform", platform)
+	}
+	if metadata, err := driver.ImageMetadata(imageId); err == nil {
+		state.Put("image_metadata", metadata)
+	} else {
+		log.Printf("Failed to read the metadata of the image: %s", err)
+	}
+}
+
+// buildChangesOnImage builds an image from the committed image with the
+// given changes, and returns its ID.
+func buildChangesOnImage(driver Driver, imageId string, changes []string) (string, error) {
+	dir, err := os.MkdirTemp("", "packer-docker-changes")
+	if err != nil {
+		return "", fmt.Errorf("Error creating the build context for the changes: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dockerfile := fmt.Sprintf("FROM %s\n%s\n", imageId, strings.Join(changes, "\n"))
+	dockerfilePath := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfilePath, []byte(dockerfile), 0644); err != nil {
+		return "", fmt.Errorf("Error writing the Dockerfile of the changes: %s", err)
+	}
+
+	log.Printf("Building the changes with Dockerfile:\n%s", dockerfile)
+	builtId, err := driver.Build([]string{"-f", dockerfilePath, dir})
+	if err != nil {
+		return "", fmt.Errorf("Error applying the changes: %s", err)
+	}
+
+	// The committed image is only an intermediate one now. It can't be
+	// removed if the build kept it as parent, which is fine.
+	if err := driver.DeleteImage(imageId); err != nil {
+		log.Printf("Not removing intermediate image %s: %s", imageId, err)
+	}
+
+	return builtId, nil
+}
+
+func (s *StepCommit) Cleanup(state multistep.StateBag) {
+	if s.pausedId == "" {
+		return
+	}
+
+	// The container has to run again to be stopped and removed.
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+	if err := driver.UnpauseContainer(s.pausedId); err != nil {
+		ui.Error(fmt.Sprintf("Error unpausing container %s: %s", s.pausedId, err))
+	}
+	s.pausedId = ""
+}
@@ -6,7 +6,9 @@ package docker
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
@@ -61,6 +63,201 @@ func TestStepCommit(t *testing.T) {
 	if imSha != driver.Sha256Result {
 		t.Fatalf("Bad: image sha wasn't set properly; received %s", imSha)
 	}
+	if genData["ImageID"] != driver.CommitImageId {
+		t.Fatalf("Bad: image ID wasn't set properly; received %v", genData["ImageID"])
+	}
+}
+
+func TestStepCommit_labels(t *testing.T) {
+	state := testStepCommitState(t)
+
+	config := state.Get("config").(*Config)
+	config.Changes = []string{"USER app"}
+	config.Labels = map[string]string{"org.opencontainers.image.title": "{{.BuildName}}"}
+	config.PackerBuildName = "app"
+	driver := state.Get("driver").(*MockDriver)
+	driver.CommitImageId = "bar"
+
+	step := &StepCommit{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	expected := []string{"USER app", `LABEL org.opencontainers.image.title="app"`}
+	if !reflect.DeepEqual(driver.CommitChanges, expected) {
+		t.Fatalf("bad changes: %#v", driver.CommitChanges)
+	}
+	if len(config.Changes) != 1 {
+		t.Fatalf("the config should not change: %v", config.Changes)
+	}
+}
+
+func TestStepCommit_buildChanges(t *testing.T) {
+	state := testStepCommitState(t)
+
+	config := state.Get("config").(*Config)
+	config.Changes = []string{"CMD /bin/sh", "HEALTHCHECK CMD true"}
+	driver := state.Get("driver").(*MockDriver)
+	driver.CommitImageId = "bar"
+	driver.BuildImageId = "baz"
+
+	step := &StepCommit{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if !reflect.DeepEqual(driver.CommitChanges, []string{"CMD /bin/sh"}) {
+		t.Fatalf("bad commit changes: %v", driver.CommitChanges)
+	}
+	if !driver.BuildCalled || driver.BuildArgs[0] != "-f" {
+		t.Fatalf("should've built the changes: %v", driver.BuildArgs)
+	}
+	if !driver.DeleteImageCalled || driver.DeleteImageId != "bar" {
+		t.Fatal("should've removed the intermediate image")
+	}
+	if id := state.Get("image_id").(string); id != "baz" {
+		t.Fatalf("bad image id: %s", id)
+	}
+}
+
+func TestStepCommit_squash(t *testing.T) {
+	state := testStepCommitState(t)
+
+	config := state.Get("config").(*Config)
+	config.Squash = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.CommitImageId = "bar"
+	driver.ImportId = "baz"
+	driver.ImageConfigResult = &ImageConfig{
+		Cmd:        []string{"/bin/sh"},
+		WorkingDir: "/app",
+	}
+
+	step := &StepCommit{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.ImageConfigId != "bar" {
+		t.Fatalf("should've read the config of the committed image: %q", driver.ImageConfigId)
+	}
+	if !driver.ExportCalled || driver.ExportID != "foo" {
+		t.Fatal("should've exported the container")
+	}
+	expected := []string{`CMD ["/bin/sh"]`, "WORKDIR /app"}
+	if !reflect.DeepEqual(driver.ImportChanges, expected) {
+		t.Fatalf("bad import changes: %v", driver.ImportChanges)
+	}
+	if !driver.DeleteImageCalled || driver.DeleteImageId != "bar" {
+		t.Fatal("should've removed the committed image")
+	}
+	if driver.BuildCalled {
+		t.Fatal("shouldn't build when there are no build changes")
+	}
+	if id := state.Get("image_id").(string); id != "baz" {
+		t.Fatalf("bad image id: %s", id)
+	}
+}
+
+func TestStepCommit_pause(t *testing.T) {
+	state := testStepCommitState(t)
+
+	config := state.Get("config").(*Config)
+	config.PauseBeforeCommit = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.CommitImageId = "bar"
+
+	step := &StepCommit{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if !driver.PauseCalled || driver.PauseID != "foo" {
+		t.Fatal("should've paused the container")
+	}
+	if driver.UnpauseCalled {
+		t.Fatal("should keep the container paused until the cleanup")
+	}
+
+	step.Cleanup(state)
+	if !driver.UnpauseCalled || driver.UnpauseID != "foo" {
+		t.Fatal("should've unpaused the container")
+	}
+}
+
+func TestStepCommit_stop(t *testing.T) {
+	state := testStepCommitState(t)
+
+	config := state.Get("config").(*Config)
+	config.StopSignal = "SIGINT"
+	config.StopTimeout = time.Minute
+	driver := state.Get("driver").(*MockDriver)
+	driver.CommitImageId = "bar"
+
+	step := &StepCommit{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if !driver.StopCalled || driver.StopID != "foo" {
+		t.Fatal("should've stopped the container before the commit")
+	}
+	if driver.StopSignal != "SIGINT" || driver.StopTimeout != time.Minute {
+		t.Fatalf("bad stop: %s, %s", driver.StopSignal, driver.StopTimeout)
+	}
+	if _, ok := state.GetOk("container_stopped"); !ok {
+		t.Fatal("should've recorded that the container is stopped")
+	}
+
+	// A container that fails to stop is not committed
+	state = testStepCommitState(t)
+	state.Get("config").(*Config).StopTimeout = time.Minute
+	driver = state.Get("driver").(*MockDriver)
+	driver.StopError = errors.New("foo")
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.CommitCalled {
+		t.Fatal("shouldn't commit a container that failed to stop")
+	}
+}
+
+func TestStepCommit_pauseError(t *testing.T) {
+	state := testStepCommitState(t)
+
+	config := state.Get("config").(*Config)
+	config.PauseBeforeCommit = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.PauseError = errors.New("foo")
+
+	step := new(StepCommit)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	step.Cleanup(state)
+
+	if driver.CommitCalled {
+		t.Fatal("shouldn't commit a container that failed to pause")
+	}
+	if driver.UnpauseCalled {
+		t.Fatal("shouldn't unpause a container that wasn't paused")
+	}
 }
 
 func TestStepCommit_error(t *testing.T) {
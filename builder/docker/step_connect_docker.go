@@ -33,15 +33,33 @@ func (s *StepConnectDocker) Run(ctx context.Context, state multistep.StateBag) m
 		return multistep.ActionHalt
 	}
 
-	containerUser, err := getContainerUser(config.Executable, containerId)
+	getUser := getContainerUser
+	if config.DriverType == DriverBuildah {
+		getUser = buildahContainerUser
+	}
+
+	host := stateDockerHost(state)
+	containerUser, err := getUser(config, host, containerId)
 	if err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
 
+	// The snapshots are taken from the build container.
+	var snapshots *snapshotter
+	if raw, ok := state.GetOk("snapshotter"); ok {
+		snapshots = raw.(*snapshotter)
+		snapshots.containerId = containerId
+	}
+
 	// Create the communicator that talks to Docker via various
 	// os/exec tricks.
 	if config.WindowsContainer {
+		entryPoint := []string{"powershell"}
+		if config.WindowsShell == "cmd" {
+			entryPoint = []string{"cmd", "/S", "/C"}
+		}
+
 		comm := &WindowsContainerCommunicator{Communicator{
 			Executable:    config.Executable,
 			ContainerID:   containerId,
@@ -49,12 +67,29 @@ func (s *StepConnectDocker) Run(ctx context.Context, state multistep.StateBag) m
 			ContainerDir:  config.ContainerDir,
 			Version:       version,
 			Config:        config,
+			HostConfig:    host,
 			ContainerUser: containerUser,
-			EntryPoint:    []string{"powershell"},
+			EntryPoint:    entryPoint,
+			Snapshots:     snapshots,
 		},
 		}
 		state.Put("communicator", comm)
 
+	} else if config.DriverType == DriverBuildah {
+		comm := &BuildahCommunicator{Communicator{
+			Executable:    config.Executable,
+			ContainerID:   containerId,
+			HostDir:       tempDir,
+			ContainerDir:  config.ContainerDir,
+			Version:       version,
+			Config:        config,
+			HostConfig:    host,
+			ContainerUser: containerUser,
+			EntryPoint:    []string{"/bin/sh", "-c"},
+			Snapshots:     snapshots,
+		},
+		}
+		state.Put("communicator", comm)
 	} else {
 		comm := &Communicator{
 			Executable:    config.Executable,
@@ -63,8 +98,10 @@ func (s *StepConnectDocker) Run(ctx context.Context, state multistep.StateBag) m
 			ContainerDir:  config.ContainerDir,
 			Version:       version,
 			Config:        config,
+			HostConfig:    host,
 			ContainerUser: containerUser,
 			EntryPoint:    []string{"/bin/sh", "-c"},
+			Snapshots:     snapshots,
 		}
 		state.Put("communicator", comm)
 	}
@@ -73,9 +110,10 @@ func (s *StepConnectDocker) Run(ctx context.Context, state multistep.StateBag) m
 
 func (s *StepConnectDocker) Cleanup(state multistep.StateBag) {}
 
-func getContainerUser(executable, containerId string) (string, error) {
-	inspectArgs := []string{executable, "inspect", "--format", "{{.Config.User}}", containerId}
-	stdout, err := exec.Command(inspectArgs[0], inspectArgs[1:]...).Output()
+func getContainerUser(config *Config, host DockerHostConfig, containerId string) (string, error) {
+	cmd := exec.Command(config.Executable, "inspect", "--format", "{{.Config.User}}", containerId)
+	host.Apply(cmd)
+	stdout, err := cmd.Output()
 	if err != nil {
 		errStr := fmt.Sprintf("Failed to inspect the container: %s", err)
 		if ee, ok := err.(*exec.ExitError); ok {
@@ -0,0 +1,125 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+)
+
+// StepContentHash hashes the inputs of the build with `skip_unchanged`. If
+// a local image was committed from the same inputs, it is the artifact of
+// the build and the next steps are skipped, or else the hash is set as a
+// label of the committed image.
+type StepContentHash struct {
+	GeneratedData *packerbuilderdata.GeneratedData
+}
+
+func (s *StepContentHash) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	if !config.SkipUnchanged {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	driver := state.Get("driver").(Driver)
+
+	hash, err := s.hash(driver, config)
+	if err != nil {
+		err := fmt.Errorf("Error hashing the inputs of the build: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	ui.Say(fmt.Sprintf("Content hash: %s", hash))
+
+	ids, err := driver.ImagesWithLabel(contentHashLabel, hash)
+	if err != nil {
+		err := fmt.Errorf("Error looking for an image with the same content hash: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if len(ids) > 0 {
+		ui.Say(fmt.Sprintf("The image %s was built from the same inputs, skipping the build", ids[0]))
+		putImage(state, s.GeneratedData, ids[0])
+		state.Put("unchanged", true)
+		return multistep.ActionContinue
+	}
+
+	config.Changes = append(config.Changes, fmt.Sprintf("LABEL %s=%s", contentHashLabel, hash))
+	return multistep.ActionContinue
+}
+
+func (s *StepContentHash) hash(driver Driver, config *Config) (string, error) {
+	sourceImageID, err := driver.Sha256(config.Image)
+	if err != nil {
+		return "", fmt.Errorf("Error reading the ID of the source image: %s", err)
+	}
+	return contentHash(sourceImageID, config.Platform, config.configHash, config.ContentHashFiles)
+}
+
+func (s *StepContentHash) Cleanup(state multistep.StateBag) {}
+
+// stepUnlessUnchanged runs a step unless StepContentHash found an image
+// built from the same inputs, in which case neither the step nor its
+// cleanup run.
+type stepUnlessUnchanged struct {
+	multistep.Step
+
+	skipped bool
+}
+
+func (s *stepUnlessUnchanged) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if _, ok := state.GetOk("unchanged"); ok {
+		s.skipped = true
+		return multistep.ActionContinue
+	}
+	return s.Step.Run(ctx, state)
+}
+
+func (s *stepUnlessUnchanged) Cleanup(state multistep.StateBag) {
+	if !s.skipped {
+		s.Step.Cleanup(state)
+	}
+}
+
+// InnerStepName returns the name of the wrapped step, for the debug runner.
+func (s *stepUnlessUnchanged) InnerStepName() string {
+	if named, ok := s.Step.(interface{ InnerStepName() string }); ok {
+		return named.InnerStepName()
+	}
+	return reflect.Indirect(reflect.ValueOf(s.Step)).Type().Name()
+}
+
+// unlessUnchanged wraps the steps that follow StepContentHash, so that they
+// are skipped if the build is unchanged. The steps may have been wrapped
+// with their timeouts already.
+func unlessUnchanged(steps []multistep.Step, config *Config) []multistep.Step {
+	if !config.SkipUnchanged {
+		return steps
+	}
+
+	wrapped := make([]multistep.Step, 0, len(steps))
+	hashed := false
+	for _, step := range steps {
+		if hashed {
+			step = &stepUnlessUnchanged{Step: step}
+		}
+		inner := step
+		if s, ok := step.(*stepWithTimeout); ok {
+			inner = s.Step
+		}
+		if _, ok := inner.(*StepContentHash); ok {
+			hashed = true
+		}
+		wrapped = append(wrapped, step)
+	}
+	return wrapped
+}
@@ -0,0 +1,95 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+)
+
+func TestStepContentHash_impl(t *testing.T) {
+	var _ multistep.Step = new(StepContentHash)
+}
+
+func TestStepContentHash(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.SkipUnchanged = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.Sha256Result = "sha256:1234"
+
+	step := &StepContentHash{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if driver.ImagesWithLabelLabel != "io.packer.content-hash" {
+		t.Fatalf("bad label: %s", driver.ImagesWithLabelLabel)
+	}
+	hash := driver.ImagesWithLabelValue
+	if len(config.Changes) != 1 || config.Changes[0] != "LABEL io.packer.content-hash="+hash {
+		t.Fatalf("bad changes: %v", config.Changes)
+	}
+	if _, ok := state.GetOk("unchanged"); ok {
+		t.Fatal("the build should not be unchanged")
+	}
+}
+
+func TestStepContentHash_unchanged(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.SkipUnchanged = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.Sha256Result = "sha256:1234"
+	driver.ImagesWithLabelResult = []string{"sha256:abcd", "sha256:ef01"}
+
+	step := &StepContentHash{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if id := state.Get("image_id"); id != "sha256:abcd" {
+		t.Fatalf("bad image id: %v", id)
+	}
+	if len(config.Changes) != 0 {
+		t.Fatalf("bad changes: %v", config.Changes)
+	}
+
+	// The steps after the hash are skipped, along with their cleanup
+	run := &StepRun{GeneratedData: &packerbuilderdata.GeneratedData{State: state}}
+	steps := unlessUnchanged([]multistep.Step{step, run}, config)
+	if steps[0] != step {
+		t.Fatalf("the hash step should not be wrapped: %#v", steps[0])
+	}
+	if action := steps[1].Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	steps[1].Cleanup(state)
+	if driver.StartCalled || driver.KillCalled {
+		t.Fatal("the step should be skipped")
+	}
+}
+
+func TestStepContentHash_error(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.SkipUnchanged = true
+	config.ContentHashFiles = []string{"/nonexistent/*.sh"}
+
+	step := &StepContentHash{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if err := state.Get("error").(error); !strings.Contains(err.Error(), "no file matches") {
+		t.Fatalf("bad error: %s", err)
+	}
+}
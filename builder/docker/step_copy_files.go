@@ -0,0 +1,42 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepCopyFiles copies the `copy_files` into the container with the driver,
+// rather than through the communicator, before the provisioners run.
+type StepCopyFiles struct{}
+
+func (s *StepCopyFiles) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	if len(config.CopyFiles) == 0 {
+		return multistep.ActionContinue
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+	containerId := state.Get("container_id").(string)
+
+	ui.Say("Copying files into the container...")
+	for _, file := range config.CopyFiles {
+		ui.Message(fmt.Sprintf("%s => %s", file.Source, file.Destination))
+		if err := driver.CopyToContainer(containerId, file.Source, file.Destination); err != nil {
+			err := fmt.Errorf("Error copying %s to %s: %s", file.Source, file.Destination, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepCopyFiles) Cleanup(state multistep.StateBag) {}
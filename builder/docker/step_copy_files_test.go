@@ -0,0 +1,60 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepCopyFiles_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCopyFiles)
+}
+
+func TestStepCopyFiles(t *testing.T) {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	config := state.Get("config").(*Config)
+	config.CopyFiles = []CopyFileConfig{
+		{Source: "dist/app.tar.gz", Destination: "/opt/app.tar.gz"},
+		{Source: "models", Destination: "/srv/models"},
+	}
+	driver := state.Get("driver").(*MockDriver)
+
+	step := new(StepCopyFiles)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !reflect.DeepEqual(driver.CopyToContainerSrc, []string{"dist/app.tar.gz", "models"}) {
+		t.Fatalf("bad sources: %v", driver.CopyToContainerSrc)
+	}
+	if !reflect.DeepEqual(driver.CopyToContainerDst, []string{"/opt/app.tar.gz", "/srv/models"}) {
+		t.Fatalf("bad destinations: %v", driver.CopyToContainerDst)
+	}
+
+	driver.CopyToContainerErr = errors.New("no such directory")
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have an error")
+	}
+}
+
+func TestStepCopyFiles_none(t *testing.T) {
+	state := testState(t)
+	driver := state.Get("driver").(*MockDriver)
+
+	step := new(StepCopyFiles)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.CopyToContainerCalled {
+		t.Fatal("should not copy")
+	}
+}
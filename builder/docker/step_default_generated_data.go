@@ -15,7 +15,8 @@ import (
 // _something_ to read in the provisioners, regardless of whether the value.
 // was created.
 // The true values are put in generated data in the steps where the
-// values are actually created (step pull, and step commit)
+// values are actually created (step pull, step run, step commit and step
+// push)
 type StepDefaultGeneratedData struct {
 	GeneratedData *packerbuilderdata.GeneratedData
 }
@@ -24,6 +25,11 @@ func (s *StepDefaultGeneratedData) Run(_ context.Context, state multistep.StateB
 	s.GeneratedData.Put("ImageSha256", "ERR_IMAGE_SHA256_NOT_FOUND")
 	s.GeneratedData.Put("SourceImageDigest", "ERR_SOURCE_IMAGE_DIGEST_NOT_FOUND")
 	s.GeneratedData.Put("SourceImageSha256", "ERR_SOURCE_IMAGE_SHA256_NOT_FOUND")
+	s.GeneratedData.Put("SourceImagePlatform", "ERR_SOURCE_IMAGE_PLATFORM_NOT_FOUND")
+	s.GeneratedData.Put("ImagePlatform", "ERR_IMAGE_PLATFORM_NOT_FOUND")
+	s.GeneratedData.Put("Digest", "ERR_DIGEST_NOT_FOUND")
+	s.GeneratedData.Put("ImageID", "ERR_IMAGE_ID_NOT_FOUND")
+	s.GeneratedData.Put("ContainerID", "ERR_CONTAINER_ID_NOT_FOUND")
 
 	return multistep.ActionContinue
 }
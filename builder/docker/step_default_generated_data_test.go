@@ -36,4 +36,24 @@ func TestStepSetGeneratedData_Run(t *testing.T) {
 	if sourceSha256 != "ERR_SOURCE_IMAGE_SHA256_NOT_FOUND" {
 		t.Fatalf("Expected SourceImageSha256 to be ERR_SOURCE_IMAGE_SHA256_NOT_FOUND but was %s", sourceSha256)
 	}
+
+	sourcePlatform := genData["SourceImagePlatform"].(string)
+	if sourcePlatform != "ERR_SOURCE_IMAGE_PLATFORM_NOT_FOUND" {
+		t.Fatalf("Expected SourceImagePlatform to be ERR_SOURCE_IMAGE_PLATFORM_NOT_FOUND but was %s", sourcePlatform)
+	}
+
+	imgPlatform := genData["ImagePlatform"].(string)
+	if imgPlatform != "ERR_IMAGE_PLATFORM_NOT_FOUND" {
+		t.Fatalf("Expected ImagePlatform to be ERR_IMAGE_PLATFORM_NOT_FOUND but was %s", imgPlatform)
+	}
+
+	imgID := genData["ImageID"].(string)
+	if imgID != "ERR_IMAGE_ID_NOT_FOUND" {
+		t.Fatalf("Expected ImageID to be ERR_IMAGE_ID_NOT_FOUND but was %s", imgID)
+	}
+
+	containerID := genData["ContainerID"].(string)
+	if containerID != "ERR_CONTAINER_ID_NOT_FOUND" {
+		t.Fatalf("Expected ContainerID to be ERR_CONTAINER_ID_NOT_FOUND but was %s", containerID)
+	}
 }
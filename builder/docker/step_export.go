@@ -4,16 +4,22 @@
 package docker
 
 import (
+	"archive/tar"
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
-// StepExport exports the container to a flat tar file.
+// StepExport exports the container to a flat tar file, or the image
+// committed from it to an OCI image archive or layout.
 type StepExport struct{}
 
 func (s *StepExport) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -26,6 +32,10 @@ func (s *StepExport) Run(ctx context.Context, state multistep.StateBag) multiste
 		return multistep.ActionHalt
 	}
 
+	if config.ExportFormat == ExportFormatOCI {
+		return s.exportOCI(state, config)
+	}
+
 	// We should catch this in validation, but guard anyway
 	if config.ExportPath == "" {
 		err := fmt.Errorf("No output file specified, we can't export anything")
@@ -53,8 +63,37 @@ func (s *StepExport) Run(ctx context.Context, state multistep.StateBag) multiste
 	driver := state.Get("driver").(Driver)
 	containerId := state.Get("container_id").(string)
 
+	if config.stopsContainer() {
+		if err := stopContainer(state, containerId); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+
+			err := fmt.Errorf("Error stopping the container for export: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	export := func(w io.Writer) error {
+		return driver.Export(containerId, w)
+	}
+	if config.ExportDiffOnly {
+		paths, err := driver.Diff(containerId)
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+
+			err := fmt.Errorf("Error reading the changes of the container: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		export = diffExport(driver, containerId, diffPaths(paths))
+	}
+
 	ui.Say("Exporting the container")
-	if err := driver.Export(containerId, f); err != nil {
+	if err := exportCompressed(f, config, export); err != nil {
 		f.Close()
 		os.Remove(f.Name())
 
@@ -68,3 +107,144 @@ func (s *StepExport) Run(ctx context.Context, state multistep.StateBag) multiste
 }
 
 func (s *StepExport) Cleanup(state multistep.StateBag) {}
+
+// exportCompressed runs the export, compressing what it writes to dst with
+// the `export_compression`.
+func exportCompressed(dst io.Writer, config *Config, export func(io.Writer) error) error {
+	w, err := CompressWriter(dst, config.ExportCompression, config.ExportCompressionLevel)
+	if err != nil {
+		return err
+	}
+	if err := export(w); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("Error compressing the export: %s", err)
+	}
+	return nil
+}
+
+// exportDestination returns the file or directory the build is exported to.
+func exportDestination(config *Config) string {
+	if config.OCILayoutPath != "" {
+		return config.OCILayoutPath
+	}
+	return config.ExportPath
+}
+
+// exportOCI saves the image committed from the container as an OCI image
+// archive to the `export_path`, or extracts it to the `oci_layout_path`. The
+// image is only needed for the export, so it is removed afterwards.
+func (s *StepExport) exportOCI(state multistep.StateBag, config *Config) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	driver := state.Get("driver").(Driver)
+	imageId := state.Get("image_id").(string)
+
+	defer func() {
+		if err := driver.DeleteImage(imageId); err != nil {
+			log.Printf("Failed to remove the exported image %s: %s", imageId, err)
+		}
+	}()
+
+	archive := config.ExportPath
+	if config.OCILayoutPath != "" {
+		f, err := os.CreateTemp("", "packer-oci-archive")
+		if err != nil {
+			err := fmt.Errorf("Error creating the temporary image archive: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+		archive = f.Name()
+	} else if err := os.MkdirAll(filepath.Dir(archive), 0755); err != nil {
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Exporting the image as an OCI image layout")
+	err := saveOCIImage(driver, config, imageId, archive)
+	if err == nil && config.filtersExport() {
+		err = filterOCIExport(driver, config, archive)
+	}
+	if err == nil && len(config.Annotations) > 0 {
+		ictx := imageLabelsContext(state, config, time.Now())
+		var annotations map[string]string
+		if annotations, err = renderAnnotations(config.Annotations, &ictx); err == nil {
+			err = annotateOCIArchive(archive, config, annotations)
+		}
+	}
+	if err == nil && config.OCILayoutPath != "" {
+		err = extractOCIArchive(archive, config.OCILayoutPath)
+	}
+	if err != nil {
+		if config.ExportPath != "" {
+			os.Remove(config.ExportPath)
+		}
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+// saveOCIImage saves the image to an OCI image archive at the given path.
+func saveOCIImage(driver Driver, config *Config, imageId, archive string) error {
+	f, err := os.Create(archive)
+	if err != nil {
+		return fmt.Errorf("Error creating output file: %s", err)
+	}
+	err = exportCompressed(f, config, func(w io.Writer) error {
+		return driver.SaveOCIImage(imageId, w)
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return verifyOCIArchive(archive)
+}
+
+// diffExport returns an export of the container that only has the entries
+// with the given names.
+func diffExport(driver Driver, containerId string, names map[string]bool) func(io.Writer) error {
+	return func(w io.Writer) error {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(driver.Export(containerId, pw))
+		}()
+		defer pr.Close()
+
+		return filterTar(pr, w, func(hdr *tar.Header) bool {
+			return names[path.Clean(hdr.Name)]
+		})
+	}
+}
+
+// filterOCIExport leaves the excluded layers out of the OCI image archive.
+func filterOCIExport(driver Driver, config *Config, archive string) error {
+	layers, err := excludedLayers(driver, config)
+	if err != nil {
+		return err
+	}
+	n, err := filterOCIArchive(archive, config, layers)
+	if err != nil {
+		return err
+	}
+	log.Printf("Left %d layers out of the export", n)
+	return nil
+}
+
+// extractOCIArchive extracts the OCI image archive to the directory.
+func extractOCIArchive(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return extractOCILayout(f, dir)
+}
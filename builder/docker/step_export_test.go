@@ -4,11 +4,16 @@
 package docker
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
@@ -102,3 +107,207 @@ func TestStepExport_error(t *testing.T) {
 		t.Fatal("export path shouldn't exist")
 	}
 }
+
+func TestStepExport_oci(t *testing.T) {
+	state := testStepExportState(t)
+	state.Put("image_id", "sha256:1234")
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ExportFormat = ExportFormatOCI
+	config.OCILayoutPath = filepath.Join(t.TempDir(), "layout")
+	driver := state.Get("driver").(*MockDriver)
+	driver.SaveOCIImageReader = bytes.NewReader(testImageArchive(t, map[string]string{
+		"oci-layout": `{"imageLayoutVersion":"1.0.0"}`,
+		"index.json": `{"schemaVersion":2}`,
+	}))
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we did the right thing
+	if !driver.SaveOCIImageCalled || driver.SaveOCIImageId != "sha256:1234" {
+		t.Fatalf("should've saved the image: %#v", driver.SaveOCIImageId)
+	}
+	if driver.ExportCalled {
+		t.Fatal("shouldn't have exported the container")
+	}
+	if !driver.DeleteImageCalled {
+		t.Fatal("should've removed the exported image")
+	}
+	if _, err := os.Stat(filepath.Join(config.OCILayoutPath, "index.json")); err != nil {
+		t.Fatalf("the layout should be extracted: %s", err)
+	}
+}
+
+func TestStepExport_diffOnly(t *testing.T) {
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ExportPath = filepath.Join(t.TempDir(), "diff.tar")
+	config.ExportDiffOnly = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.DiffResult = []string{"/etc", "/etc/app.conf"}
+	driver.ExportReader = bytes.NewReader(testImageArchive(t, map[string]string{
+		"etc/app.conf": "port = 80",
+		"etc/hosts":    "127.0.0.1 localhost",
+		"bin/sh":       "#!",
+	}))
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if driver.DiffID != "foo" {
+		t.Fatalf("bad diff: %s", driver.DiffID)
+	}
+
+	f, err := os.Open(config.ExportPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"etc/app.conf"}) {
+		t.Fatalf("bad entries: %v", names)
+	}
+}
+
+func TestStepExport_ociExcludeLayers(t *testing.T) {
+	state := testStepExportState(t)
+	state.Put("image_id", "sha256:1234")
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Image = "ubuntu"
+	config.ExportFormat = ExportFormatOCI
+	config.OCILayoutPath = filepath.Join(t.TempDir(), "layout")
+	config.ExportDiffOnly = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageMetadataResult = &ImageMetadata{RootFS: ImageRootFS{Layers: []string{"sha256:d1"}}}
+	driver.SaveOCIImageReader = bytes.NewReader(testLayeredImageArchive(t))
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if driver.ImageMetadataId != "ubuntu" {
+		t.Fatalf("bad image: %s", driver.ImageMetadataId)
+	}
+	if _, err := os.Stat(filepath.Join(config.OCILayoutPath, "blobs", "sha256", "l1")); err == nil {
+		t.Fatal("the layer of the source image should be left out")
+	}
+	if _, err := os.Stat(filepath.Join(config.OCILayoutPath, "blobs", "sha256", "l2")); err != nil {
+		t.Fatalf("the layer of the build should be exported: %s", err)
+	}
+}
+
+func TestStepExport_ociAnnotations(t *testing.T) {
+	state := testStepExportState(t)
+	state.Put("image_id", "sha256:1234")
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PackerBuildName = "app"
+	config.ExportFormat = ExportFormatOCI
+	config.ExportPath = filepath.Join(t.TempDir(), "image.tar")
+	config.Annotations = map[string]string{"org.opencontainers.image.title": "{{.BuildName}}"}
+	driver := state.Get("driver").(*MockDriver)
+	driver.SaveOCIImageReader = bytes.NewReader(testLayeredImageArchive(t))
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+
+	docs, err := readOCIDocuments(config.ExportPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var index struct {
+		Manifests []ociDescriptor
+	}
+	if err := json.Unmarshal(docs["index.json"], &index); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var manifest struct {
+		Annotations map[string]string
+	}
+	if err := json.Unmarshal(docs[ociBlobName(index.Manifests[0].Digest)], &manifest); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if manifest.Annotations["org.opencontainers.image.title"] != "app" {
+		t.Fatalf("bad annotations: %v", manifest.Annotations)
+	}
+}
+
+func TestStepExport_ociNotOCI(t *testing.T) {
+	state := testStepExportState(t)
+	state.Put("image_id", "sha256:1234")
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ExportFormat = ExportFormatOCI
+	config.ExportPath = filepath.Join(t.TempDir(), "image.tar")
+	driver := state.Get("driver").(*MockDriver)
+	driver.SaveOCIImageReader = bytes.NewReader(testImageArchive(t, map[string]string{
+		"manifest.json": "[]",
+	}))
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if err, ok := state.GetOk("error"); !ok || err != errNotOCILayout {
+		t.Fatalf("bad error: %v", err)
+	}
+
+	// verify we removed the archive
+	if _, err := os.Stat(config.ExportPath); err == nil {
+		t.Fatal("export path shouldn't exist")
+	}
+}
+
+func TestStepExport_compression(t *testing.T) {
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ExportPath = filepath.Join(t.TempDir(), "export.tar.gz")
+	config.ExportCompression = CompressionGzip
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExportReader = bytes.NewReader([]byte("data!"))
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the data exported to the file is compressed
+	r, err := openArchive(config.ExportPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer r.Close()
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(contents) != "data!" {
+		t.Fatalf("bad: %#v", string(contents))
+	}
+}
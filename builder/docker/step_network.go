@@ -0,0 +1,71 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepNetwork creates the network the container is attached to, if asked
+// to and if it doesn't exist yet. The network is only removed on cleanup if
+// it was created by this step.
+type StepNetwork struct {
+	network string
+}
+
+func (s *StepNetwork) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if !config.NetworkCreate {
+		return multistep.ActionContinue
+	}
+
+	exists, err := driver.NetworkExists(config.Network)
+	if err != nil {
+		err := fmt.Errorf("Error checking for network %s: %s", config.Network, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if exists {
+		ui.Say(fmt.Sprintf("Using existing network: %s", config.Network))
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Creating network: %s", config.Network))
+	if err := driver.CreateNetwork(config.Network); err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.network = config.Network
+
+	return multistep.ActionContinue
+}
+
+func (s *StepNetwork) Cleanup(state multistep.StateBag) {
+	if s.network == "" {
+		return
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if _, ok := state.GetOk("container_kept"); ok {
+		ui.Say(fmt.Sprintf("Keeping the network of the kept container: %s", s.network))
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Removing network: %s", s.network))
+	if err := driver.RemoveNetwork(s.network); err != nil {
+		ui.Error(err.Error())
+	}
+	s.network = ""
+}
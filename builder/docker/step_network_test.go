@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepNetwork_impl(t *testing.T) {
+	var _ multistep.Step = new(StepNetwork)
+}
+
+func TestStepNetwork(t *testing.T) {
+	state := testState(t)
+	step := new(StepNetwork)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Network = "packer"
+	config.NetworkCreate = true
+	driver := state.Get("driver").(*MockDriver)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !driver.CreateNetworkCalled || driver.CreateNetworkName != "packer" {
+		t.Fatalf("should've created the network: %#v", driver)
+	}
+
+	step.Cleanup(state)
+	if !driver.RemoveNetworkCalled || driver.RemoveNetworkName != "packer" {
+		t.Fatal("should've removed the network")
+	}
+}
+
+func TestStepNetwork_existing(t *testing.T) {
+	state := testState(t)
+	step := new(StepNetwork)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Network = "packer"
+	config.NetworkCreate = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.NetworkExistsResult = true
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.CreateNetworkCalled {
+		t.Fatal("should not have created the existing network")
+	}
+
+	step.Cleanup(state)
+	if driver.RemoveNetworkCalled {
+		t.Fatal("should not have removed the existing network")
+	}
+}
+
+func TestStepNetwork_join(t *testing.T) {
+	state := testState(t)
+	step := new(StepNetwork)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Network = "packer"
+	driver := state.Get("driver").(*MockDriver)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.NetworkExistsCalled || driver.CreateNetworkCalled {
+		t.Fatal("should only join the network")
+	}
+}
+
+func TestStepNetwork_error(t *testing.T) {
+	state := testState(t)
+	step := new(StepNetwork)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Network = "packer"
+	config.NetworkCreate = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.CreateNetworkErr = errors.New("foo")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+
+	step.Cleanup(state)
+	if driver.RemoveNetworkCalled {
+		t.Fatal("should not have removed a network it failed to create")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepPauseAfterProvision pauses the build once the provisioners ran, with
+// the command that opens a shell in the container, until enter is pressed.
+type StepPauseAfterProvision struct{}
+
+func (s *StepPauseAfterProvision) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	containerId := state.Get("container_id").(string)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if !config.PauseAfterProvision {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Pausing after the provisioners, open a shell in the container with:")
+	ui.Message(shellCommand(config, containerId))
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := ui.Ask("Press enter to continue the build.")
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			log.Printf("[WARN] Failed to read the input, not pausing: %s", err)
+		}
+		return multistep.ActionContinue
+	case <-ctx.Done():
+		state.Put("error", ctx.Err())
+		return multistep.ActionHalt
+	}
+}
+
+func (s *StepPauseAfterProvision) Cleanup(state multistep.StateBag) {}
+
+// shellCommand returns the command that opens a shell in the container, as
+// the user and in the directory of the commands of the provisioners.
+func shellCommand(config *Config, containerId string) string {
+	var args []string
+	if config.DriverType == DriverBuildah {
+		args = []string{"run", "-t"}
+		if config.ExecUser != "" {
+			args = append(args, "--user", config.ExecUser)
+		}
+		if config.Workdir != "" {
+			args = append(args, "--workingdir", config.Workdir)
+		}
+		args = append(args, containerId, "--", "sh")
+	} else {
+		args = []string{"exec", "-it"}
+		if config.ExecUser != "" {
+			args = append(args, "-u", config.ExecUser)
+		}
+		if config.Workdir != "" {
+			args = append(args, "-w", config.Workdir)
+		}
+		shell := "sh"
+		if config.WindowsContainer {
+			shell = config.WindowsShell
+		}
+		args = append(args, containerId, shell)
+	}
+	return daemonCommand(config, args...)
+}
+
+// daemonCommand returns the command line that runs the executable with the
+// given arguments against the same daemon as the build.
+func daemonCommand(config *Config, args ...string) string {
+	cmd := exec.Command(config.Executable, args...)
+	config.DockerHostConfig.Apply(cmd)
+
+	var env []string
+	if config.DockerHost != "" {
+		env = append(env, "DOCKER_HOST="+config.DockerHost)
+	}
+	if config.CertPath != "" {
+		env = append(env, "DOCKER_CERT_PATH="+config.CertPath)
+	}
+	return strings.Join(append(env, cmd.Args...), " ")
+}
@@ -0,0 +1,84 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepPauseAfterProvision_impl(t *testing.T) {
+	var _ multistep.Step = new(StepPauseAfterProvision)
+}
+
+func TestStepPauseAfterProvision(t *testing.T) {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	step := new(StepPauseAfterProvision)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PauseAfterProvision = true
+	config.ExecUser = "app"
+	output := new(bytes.Buffer)
+	state.Put("ui", &packersdk.BasicUi{
+		Reader: strings.NewReader("\n"),
+		Writer: output,
+	})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !strings.Contains(output.String(), "docker exec -it -u app foo sh") {
+		t.Fatalf("bad output: %s", output.String())
+	}
+}
+
+func TestStepPauseAfterProvision_cancel(t *testing.T) {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	step := new(StepPauseAfterProvision)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PauseAfterProvision = true
+
+	// The input never comes
+	reader, _ := io.Pipe()
+	state.Put("ui", &packersdk.BasicUi{
+		Reader: reader,
+		Writer: new(bytes.Buffer),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if action := step.Run(ctx, state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	config := testConfigStruct(t)
+	config.DockerHost = "tcp://build:2376"
+	config.Workdir = "/app"
+
+	expected := "DOCKER_HOST=tcp://build:2376 docker exec -it -w /app foo sh"
+	if cmd := shellCommand(config, "foo"); cmd != expected {
+		t.Fatalf("bad command: %s", cmd)
+	}
+
+	config = testConfigStruct(t)
+	config.DriverType = DriverBuildah
+	config.Executable = "buildah"
+	expected = "buildah run -t foo -- sh"
+	if cmd := shellCommand(config, "foo"); cmd != expected {
+		t.Fatalf("bad command: %s", cmd)
+	}
+}
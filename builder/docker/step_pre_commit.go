@@ -0,0 +1,47 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepPreCommit runs the `pre_commit_commands` in the container once the
+// provisioners ran, before it is committed or exported.
+type StepPreCommit struct{}
+
+func (s *StepPreCommit) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	if len(config.PreCommitCommands) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	comm := state.Get("communicator").(packersdk.Communicator)
+
+	for _, command := range config.PreCommitCommands {
+		ui.Say(fmt.Sprintf("Running the pre-commit command: %s", command))
+		cmd := &packersdk.RemoteCmd{Command: command}
+		if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+			err := fmt.Errorf("Error running the pre-commit command %q: %s", command, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if status := cmd.ExitStatus(); status != 0 {
+			err := fmt.Errorf("The pre-commit command %q exited with status %d", command, status)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepPreCommit) Cleanup(state multistep.StateBag) {}
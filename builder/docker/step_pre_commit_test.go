@@ -0,0 +1,54 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepPreCommit_impl(t *testing.T) {
+	var _ multistep.Step = new(StepPreCommit)
+}
+
+func TestStepPreCommit(t *testing.T) {
+	state := testState(t)
+	comm := new(packersdk.MockCommunicator)
+	state.Put("communicator", comm)
+	config := state.Get("config").(*Config)
+	config.PreCommitCommands = []string{"apt-get clean", "rm -f /etc/ssh/ssh_host_*"}
+
+	step := new(StepPreCommit)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if comm.StartCmd.Command != "rm -f /etc/ssh/ssh_host_*" {
+		t.Fatalf("bad command: %s", comm.StartCmd.Command)
+	}
+
+	comm.StartExitStatus = 1
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if comm.StartCmd.Command != "apt-get clean" {
+		t.Fatalf("should've stopped at the first command: %s", comm.StartCmd.Command)
+	}
+	if err := state.Get("error").(error); !strings.Contains(err.Error(), "exited with status 1") {
+		t.Fatalf("bad error: %s", err)
+	}
+}
+
+func TestStepPreCommit_none(t *testing.T) {
+	state := testState(t)
+
+	// The communicator isn't needed without commands
+	step := new(StepPreCommit)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
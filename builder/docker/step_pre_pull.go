@@ -0,0 +1,102 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// maxParallelPrePulls is the number of the `pre_pull_images` pulled at once.
+const maxParallelPrePulls = 4
+
+// StepPrePull pulls the `pre_pull_images` in parallel before the build
+// container starts, so that the provisioners using them don't wait for
+// their pulls one after the other.
+type StepPrePull struct{}
+
+func (s *StepPrePull) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if len(config.PrePullImages) == 0 {
+		return multistep.ActionContinue
+	}
+
+	var images []string
+	for _, image := range config.PrePullImages {
+		if config.PullPolicy != PullAlways {
+			exists, err := driver.ImageExists(image)
+			if err != nil {
+				err := fmt.Errorf("Error looking for Docker image %s: %s", image, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+			if exists {
+				log.Printf("Image %s is present, won't pre-pull it", image)
+				continue
+			}
+			if config.PullPolicy == PullNever {
+				err := fmt.Errorf("Docker image %s is not present locally, and `pull_policy` is `never`", image)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
+		images = append(images, image)
+	}
+	if len(images) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Pre-pulling %d Docker images...", len(images)))
+	if err := prePullImages(ctx, ui, driver, config, images); err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepPrePull) Cleanup(state multistep.StateBag) {}
+
+// prePullImages pulls the images, up to maxParallelPrePulls at once, and
+// returns the errors of the pulls that failed.
+func prePullImages(ctx context.Context, ui packersdk.Ui, driver Driver, config *Config, images []string) error {
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var errs *packersdk.MultiError
+	slots := make(chan struct{}, maxParallelPrePulls)
+
+	for _, image := range images {
+		image := image
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			ui.Message(fmt.Sprintf("Pulling Docker image: %s", image))
+			if err := pullWithRetries(ctx, ui, driver, config, image); err != nil {
+				lock.Lock()
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error pre-pulling Docker image %s: %s", image, err))
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
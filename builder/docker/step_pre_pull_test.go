@@ -0,0 +1,63 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepPrePull_impl(t *testing.T) {
+	var _ multistep.Step = new(StepPrePull)
+}
+
+func TestStepPrePull(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.PullPolicy = PullAlways
+	config.PrePullImages = []string{"postgres:16", "redis:7", "nginx", "busybox", "alpine"}
+	driver := state.Get("driver").(*MockDriver)
+
+	step := new(StepPrePull)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	pulled := append([]string(nil), driver.PulledImages...)
+	sort.Strings(pulled)
+	expected := []string{"alpine", "busybox", "nginx", "postgres:16", "redis:7"}
+	if !reflect.DeepEqual(pulled, expected) {
+		t.Fatalf("bad pulled images: %v", pulled)
+	}
+
+	// The images present locally aren't pulled again
+	config.PullPolicy = PullIfNotPresent
+	driver = &MockDriver{ImageExistsResult: true}
+	state.Put("driver", driver)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.PullCalled {
+		t.Fatalf("shouldn't pull the present images: %v", driver.PulledImages)
+	}
+
+	config.PullPolicy = PullNever
+	state.Put("driver", &MockDriver{})
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	config.PullPolicy = PullAlways
+	state.Put("driver", &MockDriver{PullError: errors.New("not found")})
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have an error")
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
@@ -32,6 +35,16 @@ func (s *StepPull) storeSourceImageInfo(driver Driver, ui packersdk.Ui, state mu
 	state.Put("source_sha256", sourceSha256)
 	s.GeneratedData.Put("SourceImageSha256", sourceSha256)
 
+	// The platform that is actually used, which is the one of the host
+	// if none was configured.
+	sourcePlatform, err := driver.ImagePlatform(image)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error determining the platform of the source Docker image: %s", err))
+	} else if config := state.Get("config").(*Config); config.Platform != "" && !strings.HasPrefix(sourcePlatform, config.Platform) {
+		ui.Message(fmt.Sprintf("The source image is for platform %s, not %s", sourcePlatform, config.Platform))
+	}
+	s.GeneratedData.Put("SourceImagePlatform", sourcePlatform)
+
 	// If we're running the build from a bootstrapped image built with `docker build`,
 	// the source digest will not exist, so we return immediately to avoid having
 	// the error printed out to the user.
@@ -65,61 +78,165 @@ func (s *StepPull) Run(ctx context.Context, state multistep.StateBag) multistep.
 		return multistep.ActionHalt
 	}
 
-	if !config.Pull {
-		log.Println("Pull disabled, won't call docker pull")
-		s.storeSourceImageInfo(driver, ui, state, config.Image)
-		return multistep.ActionContinue
+	// The builds of the run that use the same image pull it one at a time,
+	// so that it's only pulled once.
+	lock, err := lockPull(ctx, pullDaemon(config), config.Image, config.Platform, func() {
+		ui.Say(fmt.Sprintf("Waiting for another build to pull %s...", config.Image))
+	})
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
 	}
+	defer lock.unlock()
 
-	ui.Say(fmt.Sprintf("Pulling Docker image: %s", config.Image))
-
-	if config.EcrLogin {
-		ui.Message("Fetching ECR credentials...")
-
-		username, password, err := config.EcrGetLogin(config.LoginServer)
+	if config.PullPolicy != PullAlways || lock.pulledByRun() {
+		exists, err := driver.ImageExists(config.Image)
 		if err != nil {
-			err := fmt.Errorf("Error fetching ECR credentials: %s", err)
+			err := fmt.Errorf("Error looking for Docker image %s: %s", config.Image, err)
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
 
-		config.LoginUsername = username
-		config.LoginPassword = password
-	}
+		if exists {
+			log.Printf("Image %s is present, won't call docker pull", config.Image)
+			if err := verifySourceImageDigest(driver, config); err != nil {
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+			s.storeSourceImageInfo(driver, ui, state, config.Image)
+			return multistep.ActionContinue
+		}
 
-	if config.Login || config.EcrLogin {
-		ui.Message("Logging in...")
-		err := driver.Login(
-			config.LoginServer,
-			config.LoginUsername,
-			config.LoginPassword)
-		if err != nil {
-			err := fmt.Errorf("Error logging in: %s", err)
+		if config.PullPolicy == PullNever {
+			err := fmt.Errorf("Docker image %s is not present locally, and `pull_policy` is `never`", config.Image)
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
+	}
 
-		defer func() {
-			ui.Message("Logging out...")
-			if err := driver.Logout(config.LoginServer); err != nil {
-				ui.Error(fmt.Sprintf("Error logging out: %s", err))
-			}
-		}()
+	ui.Say(fmt.Sprintf("Pulling Docker image: %s", config.Image))
+
+	logout, err := loginRegistry(ctx, ui, driver, config)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
 	}
+	defer logout()
 
-	if err := driver.Pull(config.Image, config.Platform); err != nil {
+	if err := pullImage(ctx, ui, driver, config); err != nil {
 		err := fmt.Errorf("Error pulling Docker image: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
+	lock.markPulled()
+
+	if err := verifySourceImageDigest(driver, config); err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
 
 	s.storeSourceImageInfo(driver, ui, state, config.Image)
 
 	return multistep.ActionContinue
 }
 
+// verifySourceImageDigest returns an error if `source_image_digest` is set
+// and none of the repo digests of the image has it.
+func verifySourceImageDigest(driver Driver, config *Config) error {
+	if config.SourceImageDigest == "" {
+		return nil
+	}
+
+	repoDigests, err := driver.RepoDigests(config.Image)
+	if err != nil {
+		return fmt.Errorf("Error reading the digests of Docker image %s: %s", config.Image, err)
+	}
+
+	for _, repoDigest := range repoDigests {
+		if _, digest, _ := strings.Cut(repoDigest, "@"); digest == config.SourceImageDigest {
+			log.Printf("Image %s has the expected digest %s", config.Image, digest)
+			return nil
+		}
+	}
+
+	if len(repoDigests) == 0 {
+		return fmt.Errorf("Docker image %s has no repo digest to compare to the `source_image_digest` %s, "+
+			"it may have been built locally rather than pulled", config.Image, config.SourceImageDigest)
+	}
+	return fmt.Errorf("Docker image %s has digests %s, not the `source_image_digest` %s",
+		config.Image, strings.Join(repoDigests, ", "), config.SourceImageDigest)
+}
+
+// pullImage pulls the image from the first of the mirrors of its registry
+// that has it, and falls back to the registry itself.
+func pullImage(ctx context.Context, ui packersdk.Ui, driver Driver, config *Config) error {
+	for _, mirrored := range mirroredImages(config.Image, config.RegistryMirrors) {
+		ui.Message(fmt.Sprintf("Pulling from mirror: %s", mirrored))
+		if err := driver.Pull(mirrored, config.Platform); err != nil {
+			ui.Message(fmt.Sprintf("Error pulling from mirror, trying the next one: %s", err))
+			continue
+		}
+
+		// A reference with a digest can't be tagged, so the build uses the
+		// mirrored image directly then.
+		if strings.Contains(config.Image, "@") {
+			config.Image = mirrored
+			return nil
+		}
+		if err := driver.TagImage(mirrored, config.Image, true); err != nil {
+			return fmt.Errorf("Error tagging the image pulled from mirror %s: %s", mirrored, err)
+		}
+		return nil
+	}
+
+	return pullWithRetries(ctx, ui, driver, config, config.Image)
+}
+
+// maxPullRetryBackoff caps the time between retries of a pull, unless the
+// configured backoff is longer.
+const maxPullRetryBackoff = time.Minute
+
+// transientRegistryErrorRe matches the errors of the pulls and pushes that
+// are worth retrying: dropped connections, timeouts, server errors and rate
+// limits.
+var transientRegistryErrorRe = regexp.MustCompile(`(?i)\b(EOF|TLS handshake|timeout|timed out|connection reset|connection refused|` +
+	`temporary failure|too ?many ?requests|rate limit|429|5\d\d\b|bad gateway|service unavailable|internal server error)`)
+
+// IsTransientRegistryError returns true if the pull or push failed in a way
+// that another attempt may not.
+func IsTransientRegistryError(err error) bool {
+	return transientRegistryErrorRe.MatchString(err.Error())
+}
+
+// pullWithRetries pulls the image from its registry, and retries the
+// transient failures with an exponential backoff.
+func pullWithRetries(ctx context.Context, ui packersdk.Ui, driver Driver, config *Config, image string) error {
+	backoff := config.PullRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := driver.Pull(image, config.Platform)
+		if err == nil || attempt >= config.PullRetries || !IsTransientRegistryError(err) {
+			return err
+		}
+
+		ui.Message(fmt.Sprintf("Pull of %s failed, retrying in %s (%d/%d): %s", image, backoff, attempt+1, config.PullRetries, err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > maxPullRetryBackoff && config.PullRetryBackoff <= maxPullRetryBackoff {
+			backoff = maxPullRetryBackoff
+		}
+	}
+}
+
 func (s *StepPull) Cleanup(state multistep.StateBag) {
 }
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
@@ -23,6 +24,7 @@ func TestStepPull(t *testing.T) {
 	driver := state.Get("driver").(*MockDriver)
 	driver.Sha256Result = "sha256:af61410def4ae2aece7c1b8d94b82ef434c8ee76e0e69001230f6636aea58cd1"
 	driver.CommitImageId = "bar"
+	driver.ImagePlatformResult = "linux/arm64"
 
 	step := &StepPull{
 		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
@@ -41,6 +43,12 @@ func TestStepPull(t *testing.T) {
 	if driver.PullImage != config.Image {
 		t.Fatalf("bad: %#v", driver.PullImage)
 	}
+
+	// verify the effective platform is stored in generated data
+	genData := state.Get("generated_data").(map[string]interface{})
+	if platform := genData["SourceImagePlatform"].(string); platform != "linux/arm64" {
+		t.Fatalf("bad source image platform: %s", platform)
+	}
 }
 
 func TestStepPull_error(t *testing.T) {
@@ -105,8 +113,9 @@ func TestStepPull_noPull(t *testing.T) {
 	state := testState(t)
 
 	config := state.Get("config").(*Config)
-	config.Pull = false
+	config.PullPolicy = PullNever
 	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsResult = true
 	driver.Sha256Result = "sha256:af61410def4ae2aece7c1b8d94b82ef434c8ee76e0e69001230f6636aea58cd1"
 	driver.CommitImageId = "bar"
 
@@ -125,3 +134,217 @@ func TestStepPull_noPull(t *testing.T) {
 		t.Fatal("shouldn't have pulled")
 	}
 }
+
+func TestStepPull_ifNotPresent(t *testing.T) {
+	state := testState(t)
+
+	config := state.Get("config").(*Config)
+	config.PullPolicy = PullIfNotPresent
+	driver := state.Get("driver").(*MockDriver)
+
+	step := &StepPull{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !driver.PullCalled {
+		t.Fatal("should've pulled the missing image")
+	}
+
+	driver.PullCalled = false
+	driver.ImageExistsResult = true
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.PullCalled {
+		t.Fatal("shouldn't pull an image that is present")
+	}
+}
+
+func TestStepPull_pulledByRun(t *testing.T) {
+	testPullLock(t)
+	t.Setenv("PACKER_RUN_UUID", "1234")
+	state := testState(t)
+
+	driver := state.Get("driver").(*MockDriver)
+	step := &StepPull{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !driver.PullCalled {
+		t.Fatal("should've pulled")
+	}
+
+	// The next builds of the run don't pull the image again
+	driver.PullCalled = false
+	driver.ImageExistsResult = true
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.PullCalled {
+		t.Fatal("shouldn't pull an image pulled by the run")
+	}
+}
+
+func TestStepPull_neverMissing(t *testing.T) {
+	state := testState(t)
+
+	config := state.Get("config").(*Config)
+	config.PullPolicy = PullNever
+	driver := state.Get("driver").(*MockDriver)
+
+	step := &StepPull{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.PullCalled {
+		t.Fatal("shouldn't have pulled")
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should explain that the image is missing")
+	}
+}
+
+func TestStepPull_registryMirrors(t *testing.T) {
+	state := testState(t)
+
+	config := state.Get("config").(*Config)
+	config.Image = "ubuntu:22.04"
+	config.RegistryMirrors = []string{"mirror.example.com"}
+	driver := state.Get("driver").(*MockDriver)
+
+	step := &StepPull{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.PullImage != "mirror.example.com/library/ubuntu:22.04" {
+		t.Fatalf("should've pulled from the mirror: %s", driver.PullImage)
+	}
+	if driver.TagImageImageId != driver.PullImage || driver.TagImageRepo[0] != "ubuntu:22.04" {
+		t.Fatalf("should've tagged the mirrored image as the image: %s, %v", driver.TagImageImageId, driver.TagImageRepo)
+	}
+}
+
+func TestStepPull_retries(t *testing.T) {
+	cases := []struct {
+		name     string
+		errors   []error
+		err      error
+		action   multistep.StepAction
+		attempts int
+	}{
+		{"transient", []error{errors.New("net/http: TLS handshake timeout")}, nil, multistep.ActionContinue, 2},
+		{"permanent", nil, errors.New("manifest unknown"), multistep.ActionHalt, 1},
+		{"exhausted", nil, errors.New("toomanyrequests: You have reached your pull rate limit"), multistep.ActionHalt, 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := testState(t)
+
+			config := state.Get("config").(*Config)
+			config.PullRetries = 2
+			config.PullRetryBackoff = time.Millisecond
+			driver := state.Get("driver").(*MockDriver)
+			driver.PullErrors = tc.errors
+			driver.PullError = tc.err
+
+			step := &StepPull{
+				GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+			}
+			defer step.Cleanup(state)
+
+			if action := step.Run(context.Background(), state); action != tc.action {
+				t.Fatalf("bad action: %#v", action)
+			}
+			if driver.PullCount != tc.attempts {
+				t.Fatalf("expected %d pulls, got %d", tc.attempts, driver.PullCount)
+			}
+		})
+	}
+}
+
+func TestIsTransientRegistryError(t *testing.T) {
+	transient := []string{
+		"Bad exit status: 1: Error response from daemon: Get \"https://registry-1.docker.io/v2/\": EOF",
+		"Bad exit status: 1: Error response from daemon: Get \"https://quay.io/v2/\": net/http: TLS handshake timeout",
+		"received unexpected HTTP status: 503 Service Unavailable",
+		"toomanyrequests: You have reached your pull rate limit",
+		"read tcp 10.0.0.2:51234->10.0.0.1:443: read: connection reset by peer",
+		"received unexpected HTTP status: 502 Bad Gateway",
+	}
+	for _, msg := range transient {
+		if !IsTransientRegistryError(errors.New(msg)) {
+			t.Errorf("expected %q to be transient", msg)
+		}
+	}
+
+	permanent := []string{
+		"Bad exit status: 1: Error response from daemon: manifest for ubuntu:nope not found: manifest unknown",
+		"Bad exit status: 1: Error response from daemon: pull access denied for private/image",
+		"invalid reference format",
+	}
+	for _, msg := range permanent {
+		if IsTransientRegistryError(errors.New(msg)) {
+			t.Errorf("expected %q not to be transient", msg)
+		}
+	}
+}
+
+func TestStepPull_sourceImageDigest(t *testing.T) {
+	const digest = "sha256:a0d9e826ab87bd665cfc640598a871b748b4b70a01a4f3d174d4fb02adad07a9"
+
+	cases := []struct {
+		name        string
+		repoDigests []string
+		policy      string
+		action      multistep.StepAction
+	}{
+		{"match", []string{"mirror.example.com/library/bar@sha256:0000", "bar@" + digest}, PullAlways, multistep.ActionContinue},
+		{"presentMatch", []string{"bar@" + digest}, PullIfNotPresent, multistep.ActionContinue},
+		{"mismatch", []string{"bar@sha256:0000"}, PullAlways, multistep.ActionHalt},
+		{"presentMismatch", []string{"bar@sha256:0000"}, PullIfNotPresent, multistep.ActionHalt},
+		{"noDigest", nil, PullAlways, multistep.ActionHalt},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := testState(t)
+
+			config := state.Get("config").(*Config)
+			config.SourceImageDigest = digest
+			config.PullPolicy = tc.policy
+			driver := state.Get("driver").(*MockDriver)
+			driver.ImageExistsResult = true
+			driver.RepoDigestsResult = tc.repoDigests
+
+			step := &StepPull{
+				GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+			}
+			defer step.Cleanup(state)
+
+			if action := step.Run(context.Background(), state); action != tc.action {
+				t.Fatalf("bad action: %#v", action)
+			}
+			if driver.RepoDigestsId != config.Image {
+				t.Fatalf("should've read the digests of the image: %s", driver.RepoDigestsId)
+			}
+		})
+	}
+}
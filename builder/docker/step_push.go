@@ -0,0 +1,109 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+)
+
+// StepPush tags the committed image with the names of the `push` block, and
+// pushes it to their registry.
+type StepPush struct {
+	GeneratedData *packerbuilderdata.GeneratedData
+}
+
+func (s *StepPush) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	driver := state.Get("driver").(Driver)
+	config := state.Get("config").(*Config)
+	imageId := state.Get("image_id").(string)
+
+	logout, err := loginRegistry(ctx, ui, driver, config)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	defer logout()
+
+	names := config.Push.names()
+	for _, name := range names {
+		ui.Say(fmt.Sprintf("Pushing the image: %s", name))
+		if err := driver.TagImage(imageId, name, true); err != nil {
+			err := fmt.Errorf("Error tagging the image as %s: %s", name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if err := driver.Push(name, config.Platform); err != nil {
+			err := fmt.Errorf("Error pushing the image %s: %s", name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	digest, err := PushedDigest(driver, imageId, config.Push.Repository)
+	if err != nil {
+		err := fmt.Errorf("Error reading the digest of the pushed image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	ui.Message(fmt.Sprintf("Pushed the image: %s", digest))
+
+	state.Put("pushed_digest", digest)
+	state.Put("docker_tags", names)
+	s.GeneratedData.Put("Digest", digest)
+
+	if config.Push.RemoveLocal {
+		ui.Say("Removing the local image")
+		for _, name := range names {
+			if err := driver.DeleteImage(name); err != nil {
+				log.Printf("Failed to remove the local image %s: %s", name, err)
+			}
+		}
+		if err := driver.DeleteImage(imageId); err != nil {
+			log.Printf("Failed to remove the local image %s: %s", imageId, err)
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// PushedDigest returns the repo digest the image was pushed to the
+// repository with.
+func PushedDigest(driver Driver, imageId, repository string) (string, error) {
+	repoDigests, err := driver.RepoDigests(imageId)
+	if err != nil {
+		return "", err
+	}
+
+	for _, repoDigest := range repoDigests {
+		if name, _, _ := strings.Cut(repoDigest, "@"); name == repository {
+			return repoDigest, nil
+		}
+	}
+	// Docker shortens the names of the Docker Hub repositories, like
+	// `docker.io/library/app` to `app`.
+	short := repository
+	for _, prefix := range []string{"docker.io/", "index.docker.io/", "library/"} {
+		short = strings.TrimPrefix(short, prefix)
+	}
+	for _, repoDigest := range repoDigests {
+		if name, _, _ := strings.Cut(repoDigest, "@"); name == short {
+			return repoDigest, nil
+		}
+	}
+	return "", fmt.Errorf("the image has no repo digest for %s", repository)
+}
+
+func (s *StepPush) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,133 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+)
+
+func testStepPushState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	state.Put("image_id", "sha256:1234")
+	config := state.Get("config").(*Config)
+	config.Push = PushConfig{
+		Repository: "ghcr.io/example/app",
+		Tags:       []string{"1.0", "latest"},
+	}
+	return state
+}
+
+func TestStepPush_impl(t *testing.T) {
+	var _ multistep.Step = new(StepPush)
+}
+
+func TestStepPush(t *testing.T) {
+	state := testStepPushState(t)
+	step := &StepPush{GeneratedData: &packerbuilderdata.GeneratedData{State: state}}
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.RepoDigestsResult = []string{
+		"ubuntu@sha256:abcd",
+		"ghcr.io/example/app@sha256:5678",
+	}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	expected := []string{"ghcr.io/example/app:1.0", "ghcr.io/example/app:latest"}
+	if !reflect.DeepEqual(driver.TagImageRepo, expected) {
+		t.Fatalf("bad tags: %#v", driver.TagImageRepo)
+	}
+	if driver.TagImageImageId != "sha256:1234" {
+		t.Fatalf("bad tagged image: %s", driver.TagImageImageId)
+	}
+	if !driver.PushCalled || driver.PushName != "ghcr.io/example/app:latest" {
+		t.Fatalf("should've pushed the last tag, pushed %q", driver.PushName)
+	}
+	if driver.LoginCalled {
+		t.Fatal("shouldn't log in without login options")
+	}
+	if driver.DeleteImageCalled {
+		t.Fatal("shouldn't remove the local image")
+	}
+
+	if digest := state.Get("pushed_digest"); digest != "ghcr.io/example/app@sha256:5678" {
+		t.Fatalf("bad digest: %v", digest)
+	}
+	if tags := state.Get("docker_tags"); !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("bad docker_tags: %#v", tags)
+	}
+}
+
+func TestStepPush_removeLocal(t *testing.T) {
+	state := testStepPushState(t)
+	config := state.Get("config").(*Config)
+	config.Push.RemoveLocal = true
+	config.Login = true
+	config.LoginServer = "ghcr.io"
+	step := &StepPush{GeneratedData: &packerbuilderdata.GeneratedData{State: state}}
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.RepoDigestsResult = []string{"ghcr.io/example/app@sha256:5678"}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !driver.LoginCalled || driver.LoginRepo != "ghcr.io" {
+		t.Fatalf("should've logged in to the registry, logged in to %q", driver.LoginRepo)
+	}
+	if !driver.LogoutCalled {
+		t.Fatal("should've logged out")
+	}
+	if !driver.DeleteImageCalled || driver.DeleteImageId != "sha256:1234" {
+		t.Fatalf("should've removed the local image, removed %q", driver.DeleteImageId)
+	}
+}
+
+func TestStepPush_error(t *testing.T) {
+	state := testStepPushState(t)
+	step := &StepPush{GeneratedData: &packerbuilderdata.GeneratedData{State: state}}
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.PushErr = errors.New("denied")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+	if _, ok := state.GetOk("pushed_digest"); ok {
+		t.Fatal("shouldn't have a digest")
+	}
+}
+
+func TestPushedDigest(t *testing.T) {
+	driver := &MockDriver{RepoDigestsResult: []string{"example/app@sha256:5678"}}
+
+	digest, err := PushedDigest(driver, "sha256:1234", "docker.io/example/app")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if digest != "example/app@sha256:5678" {
+		t.Fatalf("bad digest: %s", digest)
+	}
+
+	if _, err := PushedDigest(driver, "sha256:1234", "ghcr.io/example/app"); err == nil {
+		t.Fatal("should error without a digest for the repository of another registry")
+	}
+	if _, err := PushedDigest(driver, "sha256:1234", "ghcr.io/example/other"); err == nil {
+		t.Fatal("should error without a digest for the repository")
+	}
+}
@@ -5,14 +5,25 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 )
 
 type StepRun struct {
+	GeneratedData *packerbuilderdata.GeneratedData
+
 	containerId string
+	stopLogs    func()
 }
 
 func (s *StepRun) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -28,23 +39,84 @@ func (s *StepRun) Run(ctx context.Context, state multistep.StateBag) multistep.S
 	runConfig := ContainerConfig{
 		Image:      config.Image,
 		RunCommand: config.RunCommand,
+		Entrypoint: config.Entrypoint,
+		Cmd:        config.Cmd,
 		Device:     config.Device,
+		Gpus:       config.Gpus,
 		TmpFs:      config.TmpFs,
+		ReadOnly:   config.ReadOnly,
+		Mounts:     config.Mounts,
 		Volumes:    make(map[string]string),
 		CapAdd:     config.CapAdd,
 		CapDrop:    config.CapDrop,
 		Privileged: config.Privileged,
+		Init:       config.Init,
 		Runtime:    config.Runtime,
+		Userns:     config.Userns,
+		IpcMode:    config.IpcMode,
+		PidMode:    config.PidMode,
+		UIDMap:     config.UIDMap,
+		GIDMap:     config.GIDMap,
 		Platform:   config.Platform,
+		Publish:    commPublish(config),
+
+		CgroupnsMode: config.CgroupnsMode,
+		CgroupParent: config.CgroupParent,
+
+		Network:        config.Network,
+		NetworkAliases: config.NetworkAliases,
+		ExtraHosts:     config.ExtraHosts,
+		Dns:            config.Dns,
+		DnsSearch:      config.DnsSearch,
+		DnsOptions:     config.DnsOptions,
+		Hostname:       config.Hostname,
+		Domainname:     config.Domainname,
+
+		DeviceCgroupRules: config.DeviceCgroupRules,
+		SecurityOpts:      config.SecurityOpts,
+		Ulimits:           config.Ulimits,
+
+		Cpus:       config.Cpus,
+		CpusetCpus: config.CpusetCpus,
+		Memory:     config.Memory,
+		MemorySwap: config.MemorySwap,
+		ShmSize:    config.ShmSize,
+
+		OomScoreAdj:    config.OomScoreAdj,
+		OomKillDisable: config.OomKillDisable,
+
+		Labels: runLabels(config),
+	}
+
+	if len(config.Secrets) > 0 {
+		runConfig.TmpFs = append(append([]string{}, config.TmpFs...), secretsDir+":rw,noexec,nosuid")
 	}
 
 	for host, container := range config.Volumes {
 		runConfig.Volumes[host] = container
 	}
 
+	for key, target := range config.CacheVolumes {
+		runConfig.Volumes[cacheVolumeName(key)] = target
+	}
+
+	if config.ForwardSSHAgent {
+		runConfig.Volumes[os.Getenv("SSH_AUTH_SOCK")] = sshAgentSocket
+	}
+
 	tempDir := state.Get("temp_dir").(string)
 	runConfig.Volumes[tempDir] = config.ContainerDir
 
+	if config.ContainerName != "" {
+		name, err := containerName(config, &runConfig)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		runConfig.Name = name
+	}
+
 	driver := state.Get("driver").(Driver)
 	ui.Say("Starting docker container...")
 	containerId, err := driver.StartContainer(&runConfig)
@@ -61,25 +133,172 @@ func (s *StepRun) Run(ctx context.Context, state multistep.StateBag) multistep.S
 	// instance_id is the generic term used so that users can have access to the
 	// instance id inside of the provisioners, used in step_provision.
 	state.Put("instance_id", s.containerId)
+	s.GeneratedData.Put("ContainerID", s.containerId)
 	ui.Message(fmt.Sprintf("Container ID: %s", s.containerId))
+
+	if name, ok := state.GetOk("restore_checkpoint"); ok {
+		ui.Say(fmt.Sprintf("Restoring the processes of the container from the checkpoint %s", name))
+		if err := restoreCheckpoint(driver, s.containerId, name.(string), config.CheckpointDir); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	if config.StreamLogs {
+		s.stopLogs = streamLogs(driver, ui, s.containerId)
+	}
+
+	if config.WaitForHealthy {
+		ui.Say("Waiting for the container to be healthy...")
+		if err := waitForHealthy(ctx, driver, s.containerId, config.HealthyTimeout); err != nil {
+			err := fmt.Errorf("Error waiting for the container to be healthy: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
 	return multistep.ActionContinue
 }
 
+// waitPollInterval is the time between the checks of the health or of the
+// readiness of the container.
+var waitPollInterval = time.Second
+
+// waitForHealthy waits until the healthcheck of the container passes, and
+// returns an error if it fails, if the container has no healthcheck or if
+// it isn't healthy in time.
+func waitForHealthy(ctx context.Context, driver Driver, id string, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		status, err := driver.HealthStatus(id)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case "healthy":
+			return nil
+		case "unhealthy":
+			return errors.New("the container is unhealthy")
+		case "":
+			return errors.New("the image of the container has no healthcheck")
+		}
+		log.Printf("The container is %s, waiting for it to be healthy", status)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return fmt.Errorf("the container isn't healthy after %s", timeout)
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// commPublish returns the port of the communicator to publish, if it has to
+// be.
+func commPublish(config *Config) []string {
+	if !config.PublishCommPort {
+		return nil
+	}
+	return []string{strconv.Itoa(config.Comm.Port())}
+}
+
+// containerNameRe matches the names docker accepts for containers.
+var containerNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// containerName renders the `container_name`, with the same data as the
+// `run_command`.
+func containerName(config *Config, runConfig *ContainerConfig) (string, error) {
+	ictx := runCommandContext(&config.ctx, runConfig)
+	name, err := interpolate.Render(config.ContainerName, &ictx)
+	if err != nil {
+		return "", fmt.Errorf("Error rendering the `container_name`: %s", err)
+	}
+	if !containerNameRe.MatchString(name) {
+		return "", fmt.Errorf("`container_name`: %q is not a valid container name", name)
+	}
+	return name, nil
+}
+
+// runLabels returns the labels of the build container: the ones identifying
+// the build, along with the `run_labels`.
+func runLabels(config *Config) map[string]string {
+	labels := map[string]string{
+		"io.packer.build-name": config.PackerBuildName,
+	}
+	if uuid := os.Getenv("PACKER_RUN_UUID"); uuid != "" {
+		labels["io.packer.run-uuid"] = uuid
+	}
+	for k, v := range config.RunLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
 func (s *StepRun) Cleanup(state multistep.StateBag) {
 	if s.containerId == "" {
 		return
 	}
 
+	if s.stopLogs != nil {
+		s.stopLogs()
+		s.stopLogs = nil
+	}
+
+	config := state.Get("config").(*Config)
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packersdk.Ui)
 
-	// Kill the container. We don't handle errors because errors usually
-	// just mean that the container doesn't exist anymore, which isn't a
-	// big deal.
-	ui.Say(fmt.Sprintf("Killing the container: %s", s.containerId))
+	// A build that fails because the container ran out of memory usually
+	// looks like a provisioner that got killed, so tell why.
+	if rawErr, ok := state.GetOk("error"); ok {
+		if oom, err := driver.OOMKilled(s.containerId); err != nil {
+			log.Printf("Failed to check if the container ran out of memory: %s", err)
+		} else if oom {
+			hint := "Raise the `memory` limit of the container, or lower the memory usage of the provisioners."
+			if config.Memory == "" {
+				// Without limit, the container was picked by the OOM killer
+				// of the host.
+				hint = "The host ran out of memory; lower the `oom_score_adj` of the container to have " +
+					"other processes killed first, or lower the memory usage of the provisioners."
+			}
+			err := fmt.Errorf("%s\n\nThe container was OOM killed: it ran out of memory, and processes "+
+				"were killed by the kernel. %s", rawErr, hint)
+			state.Put("error", err)
+			ui.Error(err.Error())
+		}
+	}
+
+	// Leave the failed container for debugging. The steps that clean up what
+	// it uses, like its network or volumes, are told to keep them.
+	if _, ok := state.GetOk("error"); ok && config.KeepContainerOnError {
+		rm := []string{"rm", "-f", s.containerId}
+		if config.DriverType == DriverBuildah {
+			rm = []string{"rm", s.containerId}
+		}
+		ui.Say(fmt.Sprintf("Keeping the container %s, since the build failed. Inspect it with "+
+			"`%s`, and remove it with `%s`.",
+			s.containerId, shellCommand(config, s.containerId), daemonCommand(config, rm...)))
+		state.Put("container_kept", true)
+		s.containerId = ""
+		return
+	}
+
+	// Give the services of the container a chance to shut down cleanly,
+	// unless it was already stopped for the commit or the export.
+	if _, stopped := state.GetOk("container_stopped"); config.stopsContainer() && !stopped {
+		if err := stopContainer(state, s.containerId); err != nil {
+			log.Printf("Failed to stop the container %s: %s", s.containerId, err)
+		}
+	}
 
-	//nolint:errcheck
-	driver.KillContainer(s.containerId)
+	// Kill the container. The container that isn't running anymore is
+	// only removed, so an error means it may still be running.
+	ui.Say(fmt.Sprintf("Killing the container: %s", s.containerId))
+	if err := driver.KillContainer(s.containerId); err != nil {
+		ui.Error(fmt.Sprintf("Error killing the container %s: %s", s.containerId, err))
+	}
 
 	// Reset the container ID so that we're idempotent
 	s.containerId = ""
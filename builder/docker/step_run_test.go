@@ -4,11 +4,17 @@
 package docker
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 )
 
 func testStepRunState(t *testing.T) multistep.StateBag {
@@ -23,7 +29,9 @@ func TestStepRun_impl(t *testing.T) {
 
 func TestStepRun(t *testing.T) {
 	state := testStepRunState(t)
-	step := new(StepRun)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
 	defer step.Cleanup(state)
 
 	config := state.Get("config").(*Config)
@@ -54,6 +62,12 @@ func TestStepRun(t *testing.T) {
 		t.Fatalf("bad: %#v", id)
 	}
 
+	// Verify the ID is stored in generated data
+	genData := state.Get("generated_data").(map[string]interface{})
+	if genData["ContainerID"] != "foo" {
+		t.Fatalf("bad ContainerID: %#v", genData["ContainerID"])
+	}
+
 	// Verify we haven't called stop yet
 	if driver.KillCalled {
 		t.Fatal("should not have stopped")
@@ -69,9 +83,46 @@ func TestStepRun(t *testing.T) {
 	}
 }
 
+func TestStepRun_stop(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.StopTimeout = 30 * time.Second
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	step.Cleanup(state)
+	if !driver.StopCalled || driver.StopTimeout != 30*time.Second {
+		t.Fatal("should've stopped the container before removing it")
+	}
+	if !driver.KillCalled {
+		t.Fatal("should've removed the container")
+	}
+
+	// A container stopped for the commit isn't stopped again
+	driver.StopCalled = false
+	state.Put("container_stopped", true)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	step.Cleanup(state)
+	if driver.StopCalled {
+		t.Fatal("should not have stopped the container again")
+	}
+}
+
 func TestStepRun_error(t *testing.T) {
 	state := testStepRunState(t)
-	step := new(StepRun)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
 	defer step.Cleanup(state)
 
 	driver := state.Get("driver").(*MockDriver)
@@ -98,3 +149,331 @@ func TestStepRun_error(t *testing.T) {
 		t.Fatal("should not have stopped")
 	}
 }
+
+func TestStepRun_oomKilled(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+	driver.OOMKilledResult = true
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// A successful build doesn't check for OOM kills
+	step.Cleanup(state)
+	if driver.OOMKilledCalled {
+		t.Fatal("should not have checked for OOM kills")
+	}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	state.Put("error", errors.New("Script exited with non-zero exit status: 137"))
+	step.Cleanup(state)
+	if !driver.OOMKilledCalled {
+		t.Fatal("should have checked for OOM kills")
+	}
+	err := state.Get("error").(error)
+	if !strings.Contains(err.Error(), "ran out of memory") || !strings.Contains(err.Error(), "137") {
+		t.Fatalf("bad error: %s", err)
+	}
+	if !strings.Contains(err.Error(), "oom_score_adj") {
+		t.Fatalf("the error should tell about the host running out of memory: %s", err)
+	}
+
+	// With a limit, the container ran out of its own memory
+	config := state.Get("config").(*Config)
+	config.Memory = "1g"
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	state.Put("error", errors.New("Script exited with non-zero exit status: 137"))
+	step.Cleanup(state)
+	err = state.Get("error").(error)
+	if !strings.Contains(err.Error(), "Raise the `memory` limit") {
+		t.Fatalf("bad error: %s", err)
+	}
+}
+
+func TestStepRun_keepContainerOnError(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.KeepContainerOnError = true
+	config.DockerHost = "tcp://build:2376"
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+	var output bytes.Buffer
+	state.Put("ui", &packersdk.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: &output,
+	})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// A successful build removes the container
+	step.Cleanup(state)
+	if !driver.KillCalled {
+		t.Fatal("should've killed the container")
+	}
+
+	driver.KillCalled = false
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	state.Put("error", errors.New("Script exited with non-zero exit status: 1"))
+	step.Cleanup(state)
+	if driver.KillCalled {
+		t.Fatal("should've kept the failed container")
+	}
+	if _, ok := state.GetOk("container_kept"); !ok {
+		t.Fatal("should've told the other steps the container was kept")
+	}
+
+	// The commands of the hint run against the daemon of the build
+	for _, cmd := range []string{
+		"DOCKER_HOST=tcp://build:2376 docker exec -it foo sh",
+		"DOCKER_HOST=tcp://build:2376 docker rm -f foo",
+	} {
+		if !strings.Contains(output.String(), cmd) {
+			t.Errorf("the hint should include %q: %s", cmd, output.String())
+		}
+	}
+}
+
+func TestStepRun_labels(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	t.Setenv("PACKER_RUN_UUID", "1234")
+	config := state.Get("config").(*Config)
+	config.PackerBuildName = "app"
+	config.RunLabels = map[string]string{"team": "platform"}
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	expected := map[string]string{
+		"io.packer.build-name": "app",
+		"io.packer.run-uuid":   "1234",
+		"team":                 "platform",
+	}
+	if !reflect.DeepEqual(driver.StartConfig.Labels, expected) {
+		t.Fatalf("bad labels: %v", driver.StartConfig.Labels)
+	}
+}
+
+func TestStepRun_containerName(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	t.Setenv("PACKER_RUN_UUID", "1234")
+	config := state.Get("config").(*Config)
+	config.ctx.BuildName = "app"
+	config.ContainerName = "packer-{{.BuildName}}-{{.RunUUID}}"
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.StartConfig.Name != "packer-app-1234" {
+		t.Fatalf("bad name: %s", driver.StartConfig.Name)
+	}
+
+	state = testStepRunState(t)
+	config = state.Get("config").(*Config)
+	config.ContainerName = "packer {{.BuildName}}"
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have an error")
+	}
+}
+
+func TestStepRun_restoreCheckpoint(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.CheckpointDir = "/var/cache/checkpoints"
+	state.Put("restore_checkpoint", "after-deps")
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !driver.StopCalled || driver.StopID != "foo" {
+		t.Fatal("should've stopped the container before the restore")
+	}
+	if !driver.RestoreCalled || driver.RestoreID != "foo" || driver.RestoreName != "after-deps" || driver.RestoreDir != "/var/cache/checkpoints" {
+		t.Fatalf("bad restore: %q %q %q", driver.RestoreID, driver.RestoreName, driver.RestoreDir)
+	}
+
+	driver.RestoreErr = errors.New("criu failed")
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
+func TestStepRun_forwardSSHAgent(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	t.Setenv("SSH_AUTH_SOCK", "/tmp/ssh-XXXX/agent.1234")
+	config := state.Get("config").(*Config)
+	config.ForwardSSHAgent = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if v := driver.StartConfig.Volumes["/tmp/ssh-XXXX/agent.1234"]; v != "/run/ssh-agent.sock" {
+		t.Fatalf("bad volumes: %v", driver.StartConfig.Volumes)
+	}
+}
+
+func TestStepRun_cacheVolumes(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.CacheVolumes = map[string]string{"maven": "/root/.m2/repository"}
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if v := driver.StartConfig.Volumes["packer-cache-maven"]; v != "/root/.m2/repository" {
+		t.Fatalf("bad volumes: %v", driver.StartConfig.Volumes)
+	}
+}
+
+func TestStepRun_streamLogs(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+
+	config := state.Get("config").(*Config)
+	config.StreamLogs = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	step.Cleanup(state)
+
+	if !driver.FollowLogsCalled || driver.FollowLogsID != "foo" {
+		t.Fatalf("should've followed the logs of the container: %s", driver.FollowLogsID)
+	}
+}
+
+func TestStepRun_secrets(t *testing.T) {
+	state := testStepRunState(t)
+	step := &StepRun{
+		GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+	}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.TmpFs = []string{"/tmp"}
+	config.Secrets = []SecretConfig{{ID: "token", Target: "/run/secrets/token"}}
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	expected := []string{"/tmp", "/run/secrets:rw,noexec,nosuid"}
+	if !reflect.DeepEqual(driver.StartConfig.TmpFs, expected) {
+		t.Fatalf("bad tmpfs: %v", driver.StartConfig.TmpFs)
+	}
+	if len(config.TmpFs) != 1 {
+		t.Fatalf("the config should not change: %v", config.TmpFs)
+	}
+}
+
+func TestStepRun_waitForHealthy(t *testing.T) {
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = time.Millisecond
+
+	cases := []struct {
+		name     string
+		statuses []string
+		timeout  time.Duration
+		action   multistep.StepAction
+		err      string
+	}{
+		{"healthy", []string{"starting", "starting", "healthy"}, time.Minute, multistep.ActionContinue, ""},
+		{"unhealthy", []string{"starting", "unhealthy"}, time.Minute, multistep.ActionHalt, "unhealthy"},
+		{"no healthcheck", []string{""}, time.Minute, multistep.ActionHalt, "no healthcheck"},
+		{"timeout", []string{"starting"}, 10 * time.Millisecond, multistep.ActionHalt, "isn't healthy after"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := testStepRunState(t)
+			step := &StepRun{
+				GeneratedData: &packerbuilderdata.GeneratedData{State: state},
+			}
+			defer step.Cleanup(state)
+
+			config := state.Get("config").(*Config)
+			config.WaitForHealthy = true
+			config.HealthyTimeout = tc.timeout
+			driver := state.Get("driver").(*MockDriver)
+			driver.StartID = "foo"
+			driver.HealthStatusResults = tc.statuses
+
+			if action := step.Run(context.Background(), state); action != tc.action {
+				t.Fatalf("bad action: %#v", action)
+			}
+			if tc.err == "" {
+				if driver.HealthStatusCount != len(tc.statuses) {
+					t.Fatalf("bad number of checks: %d", driver.HealthStatusCount)
+				}
+				return
+			}
+			err := state.Get("error").(error)
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("bad error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepScanImage scans the source image for vulnerabilities before the
+// container is started, and fails the build if some are at or above the
+// severity threshold.
+type StepScanImage struct{}
+
+func (s *StepScanImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if config.ScanImage.IsDefault() {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Scanning %s with %s for %s vulnerabilities or above",
+		config.Image, config.ScanImage.Tool, config.ScanImage.Severity))
+	cmd := config.ScanImage.command(config.DriverType, config.Image)
+	config.ProxyEnv.Apply(cmd)
+	if err := runAndStream(cmd, ui); err != nil {
+		err := fmt.Errorf("The scan of the source image %s failed, or found %s vulnerabilities or above: %s",
+			config.Image, config.ScanImage.Severity, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepScanImage) Cleanup(state multistep.StateBag) {}
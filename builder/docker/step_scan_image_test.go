@@ -0,0 +1,39 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepScanImage_impl(t *testing.T) {
+	var _ multistep.Step = new(StepScanImage)
+}
+
+func TestStepScanImage(t *testing.T) {
+	for path, expected := range map[string]multistep.StepAction{
+		"true":  multistep.ActionContinue,
+		"false": multistep.ActionHalt,
+	} {
+		state := testState(t)
+		config := state.Get("config").(*Config)
+		config.ScanImage = ScanConfig{Path: path}
+		if errs := config.ScanImage.Prepare(config.DriverType); len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+
+		step := new(StepScanImage)
+		defer step.Cleanup(state)
+
+		if action := step.Run(context.Background(), state); action != expected {
+			t.Fatalf("%s: bad action: %#v", path, action)
+		}
+		if _, ok := state.GetOk("error"); ok != (expected == multistep.ActionHalt) {
+			t.Fatalf("%s: bad error state", path)
+		}
+	}
+}
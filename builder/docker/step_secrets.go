@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepSecrets writes the `secrets` to the tmpfs of the container once the
+// communicator is connected, before the provisioners run.
+type StepSecrets struct{}
+
+func (s *StepSecrets) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	if len(config.Secrets) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	comm := state.Get("communicator").(packersdk.Communicator)
+
+	ui.Say("Writing the secrets to the container...")
+	for _, secret := range config.Secrets {
+		if err := writeSecret(ctx, comm, &secret); err != nil {
+			err := fmt.Errorf("Error writing the secret %q: %s", secret.ID, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Message(fmt.Sprintf("Secret %s: %s", secret.ID, secret.Target))
+	}
+
+	return multistep.ActionContinue
+}
+
+// writeSecret writes the secret to its target through the stdin of a shell
+// command, since `docker cp` can't copy to a tmpfs. Its output isn't shown,
+// in case the secret is echoed.
+func writeSecret(ctx context.Context, comm packersdk.Communicator, secret *SecretConfig) error {
+	value, err := secret.value()
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	cmd := &packersdk.RemoteCmd{
+		Command: fmt.Sprintf("umask 077 && mkdir -p '%s' && cat > '%s'", path.Dir(secret.Target), secret.Target),
+		Stdin:   bytes.NewReader(value),
+		Stdout:  &output,
+		Stderr:  &output,
+	}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return err
+	}
+	if status := cmd.Wait(); status != 0 {
+		return fmt.Errorf("the command writing it exited with status %d", status)
+	}
+	return nil
+}
+
+func (s *StepSecrets) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,62 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepSecrets_impl(t *testing.T) {
+	var _ multistep.Step = new(StepSecrets)
+}
+
+func TestStepSecrets(t *testing.T) {
+	t.Setenv("PACKER_TEST_TOKEN", "s3cr3t")
+	state := testState(t)
+	comm := new(packersdk.MockCommunicator)
+	state.Put("communicator", comm)
+	config := state.Get("config").(*Config)
+	config.Secrets = []SecretConfig{{ID: "token", Env: "PACKER_TEST_TOKEN", Target: "/run/secrets/npm/token"}}
+
+	ui := state.Get("ui").(*packersdk.BasicUi)
+	step := new(StepSecrets)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	expected := "umask 077 && mkdir -p '/run/secrets/npm' && cat > '/run/secrets/npm/token'"
+	if comm.StartCmd.Command != expected {
+		t.Fatalf("bad command: %s", comm.StartCmd.Command)
+	}
+	if comm.StartStdin != "s3cr3t" {
+		t.Fatalf("bad stdin: %q", comm.StartStdin)
+	}
+
+	comm.StartExitStatus = 1
+	comm.StartStdout = "s3cr3t"
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have an error")
+	}
+	if out := ui.Writer.(*bytes.Buffer).String(); strings.Contains(out, "s3cr3t") {
+		t.Fatalf("the secret is in the output: %s", out)
+	}
+}
+
+func TestStepSecrets_none(t *testing.T) {
+	state := testState(t)
+
+	// The communicator isn't needed without secrets
+	step := new(StepSecrets)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
@@ -13,7 +13,7 @@ import (
 type StepSetDefaults struct{}
 
 func (s *StepSetDefaults) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	driver := state.Get("driver").(*DockerDriver)
+	driver := state.Get("driver").(Driver)
 	config := state.Get("config").(*Config)
 
 	// Fetch default CMD and ENTRYPOINT
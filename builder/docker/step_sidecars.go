@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepSidecars starts the sidecar containers before the build container,
+// and removes them once the build is complete.
+type StepSidecars struct {
+	containerIds []string
+}
+
+func (s *StepSidecars) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	for _, sidecar := range config.Sidecars {
+		exists, err := driver.ImageExists(sidecar.Image)
+		if err != nil {
+			err := fmt.Errorf("Error checking for the image %s of sidecar %s: %s", sidecar.Image, sidecar.Name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if !exists {
+			ui.Say(fmt.Sprintf("Pulling the image of sidecar %s: %s", sidecar.Name, sidecar.Image))
+			if err := driver.Pull(sidecar.Image, ""); err != nil {
+				err := fmt.Errorf("Error pulling the image %s of sidecar %s: %s", sidecar.Image, sidecar.Name, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
+
+		ui.Say(fmt.Sprintf("Starting sidecar: %s", sidecar.Name))
+		containerId, err := driver.StartContainer(&ContainerConfig{
+			Image:          sidecar.Image,
+			RunCommand:     []string{"-d", "--", "{{.Image}}"},
+			Cmd:            sidecar.Cmd,
+			Env:            sidecar.Env,
+			Network:        sidecar.Network,
+			NetworkAliases: []string{sidecar.Name},
+			Labels:         runLabels(config),
+		})
+		if err != nil {
+			err := fmt.Errorf("Error starting sidecar %s: %s", sidecar.Name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.containerIds = append(s.containerIds, containerId)
+		ui.Message(fmt.Sprintf("Sidecar %s ID: %s", sidecar.Name, containerId))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepSidecars) Cleanup(state multistep.StateBag) {
+	if len(s.containerIds) == 0 {
+		return
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if _, ok := state.GetOk("container_kept"); ok {
+		ui.Say(fmt.Sprintf("Keeping the sidecars of the kept container: %v", s.containerIds))
+		return
+	}
+
+	for _, containerId := range s.containerIds {
+		ui.Say(fmt.Sprintf("Removing sidecar: %s", containerId))
+		//nolint:errcheck
+		driver.KillContainer(containerId)
+	}
+	s.containerIds = nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepSidecars_impl(t *testing.T) {
+	var _ multistep.Step = new(StepSidecars)
+}
+
+func TestStepSidecars(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Sidecars = []SidecarConfig{{
+		Name:    "db",
+		Image:   "postgres:16",
+		Env:     map[string]string{"POSTGRES_PASSWORD": "packer"},
+		Network: "packer",
+	}}
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "sidecar"
+
+	step := new(StepSidecars)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+
+	// The missing image is pulled
+	if !driver.PullCalled || driver.PullImage != "postgres:16" {
+		t.Fatalf("should've pulled the image: %q", driver.PullImage)
+	}
+	start := driver.StartConfig
+	if start.Image != "postgres:16" || start.Network != "packer" {
+		t.Fatalf("bad config: %#v", start)
+	}
+	if !reflect.DeepEqual(start.NetworkAliases, []string{"db"}) {
+		t.Fatalf("bad aliases: %v", start.NetworkAliases)
+	}
+	if start.Env["POSTGRES_PASSWORD"] != "packer" {
+		t.Fatalf("bad env: %v", start.Env)
+	}
+
+	step.Cleanup(state)
+	if !driver.KillCalled || driver.KillID != "sidecar" {
+		t.Fatalf("should've removed the sidecar: %q", driver.KillID)
+	}
+}
+
+func TestStepSidecars_keptContainer(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Sidecars = []SidecarConfig{{Name: "db", Image: "postgres:16", Network: "packer"}}
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsResult = true
+	driver.StartID = "sidecar"
+
+	step := new(StepSidecars)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if driver.PullCalled {
+		t.Fatal("should not have pulled the present image")
+	}
+
+	state.Put("container_kept", true)
+	step.Cleanup(state)
+	if driver.KillCalled {
+		t.Fatal("should have kept the sidecar of the kept container")
+	}
+}
+
+func TestStepSidecars_error(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Sidecars = []SidecarConfig{{Name: "db", Image: "postgres:16", Network: "packer"}}
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsResult = true
+	driver.StartError = errors.New("foo")
+
+	step := new(StepSidecars)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	step.Cleanup(state)
+	if driver.KillCalled {
+		t.Fatal("should not have removed a sidecar that didn't start")
+	}
+}
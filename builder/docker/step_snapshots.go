@@ -0,0 +1,102 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepSnapshots sets up the snapshots the docker-snapshot provisioner takes
+// during the provisioning. With `resume`, it starts the build from the last
+// snapshot of a previous failed run.
+//
+// The snapshots are removed once the build succeeds, unless
+// `keep_snapshots` is set.
+type StepSnapshots struct {
+	snapshots *snapshotter
+}
+
+func (s *StepSnapshots) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if config.SnapshotRepository == "" {
+		return multistep.ActionContinue
+	}
+
+	s.snapshots = &snapshotter{
+		driver:     driver,
+		repository: config.SnapshotRepository,
+	}
+	if config.Checkpoint {
+		s.snapshots.checkpointDir = config.CheckpointDir
+	}
+
+	if config.Resume {
+		if image, name := latestSnapshot(driver, config.SnapshotRepository); image != "" {
+			ui.Say(fmt.Sprintf("Resuming from snapshot %s (%s)", name, image))
+			config.Image = image
+			config.PullPolicy = PullNever
+			// The snapshot was committed locally from the verified source
+			// image, so it has no repo digest or signature to verify.
+			config.SourceImageDigest = ""
+			config.VerifySignature = SignatureConfig{}
+			s.snapshots.resumeFrom = name
+			if hasCheckpoint(s.snapshots.checkpointDir, name) {
+				state.Put("restore_checkpoint", name)
+			} else if config.Checkpoint {
+				ui.Message(fmt.Sprintf("Snapshot %s has no checkpoint, its processes aren't restored", name))
+			}
+		}
+	}
+
+	state.Put("snapshotter", s.snapshots)
+	return multistep.ActionContinue
+}
+
+func (s *StepSnapshots) Cleanup(state multistep.StateBag) {
+	if s.snapshots == nil {
+		return
+	}
+
+	config := state.Get("config").(*Config)
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if cancelled || halted || config.KeepSnapshots {
+		return
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	ui.Say("Removing the snapshots of the build")
+	s.snapshots.remove()
+}
+
+// StepVerifySnapshots fails the build if the provisioning never reached the
+// snapshot it resumed from, since none of the provisioners ran then.
+type StepVerifySnapshots struct{}
+
+func (s *StepVerifySnapshots) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	raw, ok := state.GetOk("snapshotter")
+	if !ok {
+		return multistep.ActionContinue
+	}
+
+	snapshots := raw.(*snapshotter)
+	if snapshots.skipping() {
+		err := fmt.Errorf("The build resumed from snapshot %s, but no docker-snapshot provisioner took it. "+
+			"Remove the snapshot or disable `resume` to build from the start.", snapshots.resumeFrom)
+		state.Put("error", err)
+		state.Get("ui").(packersdk.Ui).Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepVerifySnapshots) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,103 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepSnapshots_impl(t *testing.T) {
+	var _ multistep.Step = new(StepSnapshots)
+	var _ multistep.Step = new(StepVerifySnapshots)
+}
+
+func TestStepSnapshots_resume(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Image = "ubuntu"
+	config.PullPolicy = PullAlways
+	config.SnapshotRepository = "build"
+	config.Resume = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageConfigResult = &ImageConfig{
+		Labels: map[string]string{snapshotLabel: "after-deps"},
+	}
+
+	step := new(StepSnapshots)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if config.Image != "build:latest" || config.PullPolicy != PullNever {
+		t.Fatalf("should start from the snapshot: %q, pull %s", config.Image, config.PullPolicy)
+	}
+	snapshots := state.Get("snapshotter").(*snapshotter)
+	if snapshots.resumeFrom != "after-deps" {
+		t.Fatalf("bad resumed snapshot: %q", snapshots.resumeFrom)
+	}
+
+	// The provisioning never reached the snapshot
+	if action := new(StepVerifySnapshots).Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
+func TestStepSnapshots_resumeCheckpoint(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Image = "ubuntu"
+	config.SnapshotRepository = "build"
+	config.Resume = true
+	config.Checkpoint = true
+	config.CheckpointDir = t.TempDir()
+	if err := os.Mkdir(filepath.Join(config.CheckpointDir, "after-deps"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageConfigResult = &ImageConfig{
+		Labels: map[string]string{snapshotLabel: "after-deps"},
+	}
+
+	step := new(StepSnapshots)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if step.snapshots.checkpointDir != config.CheckpointDir {
+		t.Fatalf("bad checkpoint directory: %q", step.snapshots.checkpointDir)
+	}
+	if name, _ := state.Get("restore_checkpoint").(string); name != "after-deps" {
+		t.Fatalf("should restore the checkpoint of the snapshot: %q", name)
+	}
+}
+
+func TestStepSnapshots_cleanup(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.SnapshotRepository = "build"
+	driver := state.Get("driver").(*MockDriver)
+
+	step := new(StepSnapshots)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	step.snapshots.names = []string{"after-deps"}
+
+	// The snapshots are kept for the next run when the build fails
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+	if driver.DeleteImageCalled {
+		t.Fatal("shouldn't remove the snapshots of a failed build")
+	}
+
+	state.Remove(multistep.StateHalted)
+	step.Cleanup(state)
+	if !driver.DeleteImageCalled || driver.DeleteImageId != "build:latest" {
+		t.Fatalf("should've removed the snapshots: %q", driver.DeleteImageId)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepVerifySecrets checks that none of the `secrets` is in the changes of
+// the container, which would be committed or exported with it.
+type StepVerifySecrets struct{}
+
+func (s *StepVerifySecrets) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	if len(config.Secrets) == 0 || config.Discard {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	driver := state.Get("driver").(Driver)
+	containerId := state.Get("container_id").(string)
+
+	ui.Say("Verifying the secrets are not in the changes of the container...")
+	paths, err := driver.Diff(containerId)
+	if err != nil {
+		err := fmt.Errorf("Error reading the changes of the container: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	var leaked []string
+	for _, p := range paths {
+		if strings.HasPrefix(p, secretsDir+"/") {
+			leaked = append(leaked, p)
+		}
+	}
+	if len(leaked) > 0 {
+		err := fmt.Errorf("The changes of the container contain files in %s, which would be committed: %s",
+			secretsDir, strings.Join(leaked, ", "))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepVerifySecrets) Cleanup(state multistep.StateBag) {}
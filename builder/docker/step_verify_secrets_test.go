@@ -0,0 +1,57 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepVerifySecrets_impl(t *testing.T) {
+	var _ multistep.Step = new(StepVerifySecrets)
+}
+
+func TestStepVerifySecrets(t *testing.T) {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	config := state.Get("config").(*Config)
+	config.Secrets = []SecretConfig{{ID: "token", Target: "/run/secrets/token"}}
+	driver := state.Get("driver").(*MockDriver)
+	// The mount point of the tmpfs is in the changes
+	driver.DiffResult = []string{"/run", "/run/secrets", "/usr/lib/node_modules"}
+
+	step := new(StepVerifySecrets)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if driver.DiffID != "foo" {
+		t.Fatalf("bad id: %s", driver.DiffID)
+	}
+
+	driver.DiffResult = append(driver.DiffResult, "/run/secrets/token")
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if err := state.Get("error").(error); !strings.Contains(err.Error(), "/run/secrets/token") {
+		t.Fatalf("bad error: %s", err)
+	}
+}
+
+func TestStepVerifySecrets_discard(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Secrets = []SecretConfig{{ID: "token", Target: "/run/secrets/token"}}
+	config.Discard = true
+
+	step := new(StepVerifySecrets)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if state.Get("driver").(*MockDriver).DiffCalled {
+		t.Fatal("should not read the changes of a discarded container")
+	}
+}
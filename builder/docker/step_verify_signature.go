@@ -0,0 +1,80 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepVerifySignature verifies the signature of the source image with
+// cosign or notation, before the container is started.
+type StepVerifySignature struct{}
+
+func (s *StepVerifySignature) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if config.VerifySignature.IsDefault() {
+		return multistep.ActionContinue
+	}
+
+	reference, err := sourceImageReference(driver, config.Image)
+	if err != nil {
+		err := fmt.Errorf("Error finding the digest of the source image to verify its signature: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Verifying the signature of %s with %s", reference, config.VerifySignature.Tool))
+	cmd := config.VerifySignature.command(reference)
+	config.ProxyEnv.Apply(cmd)
+	if err := runAndStream(cmd, ui); err != nil {
+		err := fmt.Errorf("The signature of the source image %s could not be verified: %s", reference, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepVerifySignature) Cleanup(state multistep.StateBag) {}
+
+// sourceImageReference returns the reference of the image by digest, in
+// the repository of the image, which is where its signatures are.
+func sourceImageReference(driver Driver, image string) (string, error) {
+	if strings.Contains(image, "@") {
+		return image, nil
+	}
+
+	repository := image
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repository = image[:i]
+	}
+
+	repoDigests, err := driver.RepoDigests(image)
+	if err != nil {
+		return "", err
+	}
+	if len(repoDigests) == 0 {
+		return "", fmt.Errorf("image %s has no repo digest, it wasn't pulled from a registry", image)
+	}
+
+	// The image may also have been pulled from a mirror, its digest is
+	// the same in every repository.
+	for _, repoDigest := range repoDigests {
+		if name, _, _ := strings.Cut(repoDigest, "@"); name == repository {
+			return repoDigest, nil
+		}
+	}
+	_, digest, _ := strings.Cut(repoDigests[0], "@")
+	return repository + "@" + digest, nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+const testDigest = "sha256:a0d9e826ab87bd665cfc640598a871b748b4b70a01a4f3d174d4fb02adad07a9"
+
+func TestStepVerifySignature_impl(t *testing.T) {
+	var _ multistep.Step = new(StepVerifySignature)
+}
+
+func TestStepVerifySignature(t *testing.T) {
+	for path, expected := range map[string]multistep.StepAction{
+		"true":  multistep.ActionContinue,
+		"false": multistep.ActionHalt,
+	} {
+		state := testState(t)
+		config := state.Get("config").(*Config)
+		config.VerifySignature = SignatureConfig{Key: "cosign.pub", Path: path}
+		driver := state.Get("driver").(*MockDriver)
+		driver.RepoDigestsResult = []string{config.Image + "@" + testDigest}
+
+		step := new(StepVerifySignature)
+		defer step.Cleanup(state)
+
+		if action := step.Run(context.Background(), state); action != expected {
+			t.Fatalf("%s: bad action: %#v", path, action)
+		}
+	}
+}
+
+func TestStepVerifySignature_disabled(t *testing.T) {
+	state := testState(t)
+	driver := state.Get("driver").(*MockDriver)
+
+	step := new(StepVerifySignature)
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.RepoDigestsCalled {
+		t.Fatal("shouldn't have looked for the digest")
+	}
+}
+
+func TestSourceImageReference(t *testing.T) {
+	cases := []struct {
+		image       string
+		repoDigests []string
+		expected    string
+	}{
+		{"ubuntu@" + testDigest, nil, "ubuntu@" + testDigest},
+		{"ubuntu:22.04", []string{"ubuntu@" + testDigest}, "ubuntu@" + testDigest},
+		{"registry.example.com:5000/app", []string{"registry.example.com:5000/app@" + testDigest}, "registry.example.com:5000/app@" + testDigest},
+		{"ubuntu:22.04", []string{"mirror.example.com/library/ubuntu@" + testDigest}, "ubuntu@" + testDigest},
+	}
+	for _, tc := range cases {
+		driver := &MockDriver{RepoDigestsResult: tc.repoDigests}
+		reference, err := sourceImageReference(driver, tc.image)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.image, err)
+		}
+		if reference != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.image, tc.expected, reference)
+		}
+	}
+
+	if _, err := sourceImageReference(&MockDriver{}, "local-image"); err == nil {
+		t.Fatal("expected an error for an image without repo digest")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepVolumes creates the named volumes mounted in the container that don't
+// exist yet. Docker would create them when starting the container too, but
+// creating them here tells which ones can be removed on cleanup, without
+// touching the volumes that existed before the build. The volumes of the
+// `cache_volumes` are created too, but never removed.
+type StepVolumes struct {
+	volumes []string
+}
+
+func (s *StepVolumes) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	for _, name := range namedVolumes(config) {
+		created, err := createVolume(driver, ui, name)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if created {
+			s.volumes = append(s.volumes, name)
+		}
+	}
+
+	for _, key := range sortedKeys(config.CacheVolumes) {
+		name := cacheVolumeName(key)
+		created, err := createVolume(driver, ui, name)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if !created {
+			ui.Say(fmt.Sprintf("Reusing cache volume: %s", name))
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// createVolume creates the named volume if it doesn't exist, and returns
+// whether it did.
+func createVolume(driver Driver, ui packersdk.Ui, name string) (bool, error) {
+	exists, err := driver.VolumeExists(name)
+	if err != nil {
+		return false, fmt.Errorf("Error checking for volume %s: %s", name, err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	ui.Say(fmt.Sprintf("Creating volume: %s", name))
+	if err := driver.CreateVolume(name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// cacheVolumeName returns the name of the named volume of the cache with
+// the given key.
+func cacheVolumeName(key string) string {
+	return "packer-cache-" + key
+}
+
+func (s *StepVolumes) Cleanup(state multistep.StateBag) {
+	if len(s.volumes) == 0 {
+		return
+	}
+
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if config.KeepVolumes {
+		ui.Say(fmt.Sprintf("Keeping the volumes created for the build: %v", s.volumes))
+		return
+	}
+	if _, ok := state.GetOk("container_kept"); ok {
+		ui.Say(fmt.Sprintf("Keeping the volumes of the kept container: %v", s.volumes))
+		return
+	}
+
+	for _, name := range s.volumes {
+		ui.Say(fmt.Sprintf("Removing volume: %s", name))
+		if err := driver.RemoveVolume(name); err != nil {
+			ui.Error(err.Error())
+		}
+	}
+	s.volumes = nil
+}
+
+// namedVolumes returns the sorted names of the named volumes mounted in the
+// container, from both `volumes` and `mounts`.
+func namedVolumes(config *Config) []string {
+	seen := map[string]bool{}
+	for host := range config.Volumes {
+		if isNamedVolume(host) {
+			seen[host] = true
+		}
+	}
+	for _, m := range config.Mounts {
+		if m.Type == "volume" {
+			seen[m.Source] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepVolumes_impl(t *testing.T) {
+	var _ multistep.Step = new(StepVolumes)
+}
+
+func testStepVolumesState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Volumes = map[string]string{
+		"/host": "/host",
+		"data":  "/data",
+	}
+	config.Mounts = []MountConfig{
+		{Type: "volume", Source: "cache", Target: "/var/cache"},
+		{Type: "tmpfs", Target: "/tmp"},
+	}
+	return state
+}
+
+func TestStepVolumes(t *testing.T) {
+	state := testStepVolumesState(t)
+	step := new(StepVolumes)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.VolumeExistsResult = map[string]bool{"data": true}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !reflect.DeepEqual(driver.CreateVolumeNames, []string{"cache"}) {
+		t.Fatalf("bad created volumes: %v", driver.CreateVolumeNames)
+	}
+
+	// Only the volume created by the step is removed
+	step.Cleanup(state)
+	if !reflect.DeepEqual(driver.RemoveVolumeNames, []string{"cache"}) {
+		t.Fatalf("bad removed volumes: %v", driver.RemoveVolumeNames)
+	}
+}
+
+func TestStepVolumes_cacheVolumes(t *testing.T) {
+	state := testState(t)
+	step := new(StepVolumes)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.CacheVolumes = map[string]string{
+		"maven": "/root/.m2/repository",
+		"npm":   "/root/.npm",
+	}
+	driver := state.Get("driver").(*MockDriver)
+	driver.VolumeExistsResult = map[string]bool{"packer-cache-npm": true}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !reflect.DeepEqual(driver.CreateVolumeNames, []string{"packer-cache-maven"}) {
+		t.Fatalf("bad created volumes: %v", driver.CreateVolumeNames)
+	}
+
+	// The caches are kept for the next builds
+	step.Cleanup(state)
+	if len(driver.RemoveVolumeNames) > 0 {
+		t.Fatalf("bad removed volumes: %v", driver.RemoveVolumeNames)
+	}
+}
+
+func TestStepVolumes_keep(t *testing.T) {
+	state := testStepVolumesState(t)
+	step := new(StepVolumes)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.KeepVolumes = true
+	driver := state.Get("driver").(*MockDriver)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !reflect.DeepEqual(driver.CreateVolumeNames, []string{"cache", "data"}) {
+		t.Fatalf("bad created volumes: %v", driver.CreateVolumeNames)
+	}
+
+	step.Cleanup(state)
+	if len(driver.RemoveVolumeNames) > 0 {
+		t.Fatalf("should have kept the volumes: %v", driver.RemoveVolumeNames)
+	}
+}
+
+func TestStepVolumes_keptContainer(t *testing.T) {
+	state := testStepVolumesState(t)
+	step := new(StepVolumes)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	state.Put("container_kept", true)
+	step.Cleanup(state)
+	if len(driver.RemoveVolumeNames) > 0 {
+		t.Fatalf("should have kept the volumes: %v", driver.RemoveVolumeNames)
+	}
+}
+
+func TestStepVolumes_error(t *testing.T) {
+	state := testStepVolumesState(t)
+	step := new(StepVolumes)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.CreateVolumeErr = errors.New("foo")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepWaitFor waits for the port of the `wait_for` block to be listening
+// and for a line of the logs of the container to match its `log_regex`.
+type StepWaitFor struct{}
+
+func (s *StepWaitFor) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	if config.WaitFor.IsDefault() {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	driver := state.Get("driver").(Driver)
+	containerId := state.Get("container_id").(string)
+
+	ui.Say("Waiting for the container to be ready...")
+	if err := waitForReady(ctx, driver, containerId, config); err != nil {
+		err := fmt.Errorf("Error waiting for the container to be ready: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+// waitForReady polls the container until it is ready, or until the timeout
+// of the `wait_for` block expires.
+func waitForReady(ctx context.Context, driver Driver, id string, config *Config) error {
+	waitFor := &config.WaitFor
+	deadline := time.After(waitFor.Timeout)
+
+	portReady := waitFor.Port == 0
+	logReady := waitFor.logRe == nil
+	for {
+		if !portReady {
+			addr, err := waitForAddress(driver, id, config)
+			if err != nil {
+				return err
+			}
+			if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+				conn.Close()
+				log.Printf("The port %d of the container is listening on %s", waitFor.Port, addr)
+				portReady = true
+			} else {
+				log.Printf("The port %d of the container isn't listening yet: %s", waitFor.Port, err)
+			}
+		}
+		if !logReady {
+			logs, err := driver.Logs(id)
+			if err != nil {
+				return err
+			}
+			for _, line := range strings.Split(logs, "\n") {
+				if waitFor.logRe.MatchString(strings.TrimRight(line, "\r")) {
+					log.Printf("The logs of the container matched: %s", line)
+					logReady = true
+					break
+				}
+			}
+		}
+		if portReady && logReady {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			if !portReady {
+				return fmt.Errorf("the port %d of the container isn't listening after %s", waitFor.Port, waitFor.Timeout)
+			}
+			return fmt.Errorf("no line of the logs of the container matched %q after %s", waitFor.LogRegex, waitFor.Timeout)
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// waitForAddress returns the address the port of the `wait_for` block is
+// reached on: the published port on the host of the daemon if the port is
+// published, or else the port on the IP address of the container.
+func waitForAddress(driver Driver, id string, config *Config) (string, error) {
+	if port, err := driver.PublishedPort(id, config.WaitFor.Port); err == nil {
+		return net.JoinHostPort(daemonHost(config.DockerHostConfig.endpoint()), strconv.Itoa(port)), nil
+	}
+
+	ip, err := driver.IPAddress(id)
+	if err != nil {
+		return "", fmt.Errorf("Error reading the IP address of the container: %s", err)
+	}
+	if ip == "" {
+		return "", fmt.Errorf("the container has no IP address to reach the port %d on, publish it in `run_command`", config.WaitFor.Port)
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(config.WaitFor.Port)), nil
+}
+
+func (s *StepWaitFor) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,143 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepWaitFor_impl(t *testing.T) {
+	var _ multistep.Step = new(StepWaitFor)
+}
+
+func testStepWaitForState(t *testing.T, waitFor WaitForConfig) multistep.StateBag {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	config := state.Get("config").(*Config)
+	config.WaitFor = waitFor
+	if errs := config.WaitFor.Prepare(config.DriverType); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	return state
+}
+
+func TestStepWaitFor_logs(t *testing.T) {
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = time.Millisecond
+
+	state := testStepWaitForState(t, WaitForConfig{LogRegex: "^ready to accept connections$"})
+	driver := state.Get("driver").(*MockDriver)
+	driver.LogsResults = []string{"", "starting\r\n", "starting\r\nready to accept connections\r\n"}
+
+	step := new(StepWaitFor)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if driver.LogsCount != 3 {
+		t.Fatalf("bad number of checks: %d", driver.LogsCount)
+	}
+
+	state = testStepWaitForState(t, WaitForConfig{LogRegex: "ready", Timeout: 10 * time.Millisecond})
+	driver = state.Get("driver").(*MockDriver)
+	driver.LogsResults = []string{"starting"}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if err := state.Get("error").(error); !strings.Contains(err.Error(), "no line of the logs") {
+		t.Fatalf("bad error: %s", err)
+	}
+}
+
+func TestStepWaitFor_port(t *testing.T) {
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = time.Millisecond
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	// The port isn't published, so it is reached on the IP of the container
+	state := testStepWaitForState(t, WaitForConfig{Port: port})
+	driver := state.Get("driver").(*MockDriver)
+	driver.PublishedPortErr = errors.New("not published")
+	driver.IPAddressResult = "127.0.0.1"
+
+	step := new(StepWaitFor)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if !driver.IPAddressCalled {
+		t.Fatal("should've read the IP address")
+	}
+
+	// The published port is reached on the host of the daemon
+	state = testStepWaitForState(t, WaitForConfig{Port: 8080})
+	driver = state.Get("driver").(*MockDriver)
+	driver.PublishedPortResult = port
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %v", action, state.Get("error"))
+	}
+	if driver.PublishedPortPort != 8080 || driver.IPAddressCalled {
+		t.Fatalf("should've used the published port: %d", driver.PublishedPortPort)
+	}
+
+	l.Close()
+	state = testStepWaitForState(t, WaitForConfig{Port: port, Timeout: 10 * time.Millisecond})
+	driver = state.Get("driver").(*MockDriver)
+	driver.PublishedPortErr = errors.New("not published")
+	driver.IPAddressResult = "127.0.0.1"
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if err := state.Get("error").(error); !strings.Contains(err.Error(), "port "+strconv.Itoa(port)) {
+		t.Fatalf("bad error: %s", err)
+	}
+}
+
+func TestStepWaitFor_default(t *testing.T) {
+	state := testState(t)
+	driver := state.Get("driver").(*MockDriver)
+
+	step := new(StepWaitFor)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.LogsCount > 0 || driver.IPAddressCalled || driver.PublishedPortCalled {
+		t.Fatal("should not have waited")
+	}
+}
+
+func TestWaitForAddress_remoteContext(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	testDockerContext(t, configDir, "b71199ebd070b36beab7317920c2c2f1d777df8d05e5527d8458fda57cb17a7a",
+		`{"Name": "remote", "Endpoints": {"docker": {"Host": "tcp://docker.example.com:2376", "SkipTLSVerify": false}}}`)
+
+	state := testStepWaitForState(t, WaitForConfig{Port: 8080})
+	config := state.Get("config").(*Config)
+	config.DockerContext = "remote"
+	driver := state.Get("driver").(*MockDriver)
+	driver.PublishedPortResult = 49153
+
+	address, err := waitForAddress(driver, "foo", config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if address != "docker.example.com:49153" {
+		t.Fatalf("should reach the published port on the host of the daemon of the context: %s", address)
+	}
+}
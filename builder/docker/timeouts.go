@@ -0,0 +1,101 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepWithTimeout runs a step with the commands of the driver bound to the
+// context of the step, so that they are stopped when the build is cancelled
+// or times out, rather than when they return. If timeout is set, the step
+// fails once it ran for that long. The cleanup of the step runs without a
+// context, so that it still runs once the build timed out.
+type stepWithTimeout struct {
+	multistep.Step
+
+	// What the step does, for the error, like `pull`.
+	name    string
+	timeout time.Duration
+}
+
+func (s *stepWithTimeout) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
+
+	stepCtx := ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	driver.SetContext(stepCtx)
+	defer driver.SetContext(nil)
+
+	action := s.Step.Run(stepCtx, state)
+
+	// The timeout of the build is reported by the builder.
+	if ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		ui := state.Get("ui").(packersdk.Ui)
+		err := fmt.Errorf("The %s timed out after %s", s.name, s.timeout)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return action
+}
+
+// InnerStepName returns the name of the wrapped step, for the debug runner.
+func (s *stepWithTimeout) InnerStepName() string {
+	return reflect.Indirect(reflect.ValueOf(s.Step)).Type().Name()
+}
+
+// withTimeouts wraps the steps that have a timeout, and all the steps if the
+// build has one, so that the commands of the driver honor it.
+func withTimeouts(steps []multistep.Step, config *Config) []multistep.Step {
+	wrapped := make([]multistep.Step, 0, len(steps))
+	for _, step := range steps {
+		var name string
+		var timeout time.Duration
+		switch step.(type) {
+		case *StepPull:
+			name, timeout = "pull", config.PullTimeout
+		case *StepPrePull:
+			name, timeout = "pre-pull", config.PullTimeout
+		case *commonsteps.StepProvision:
+			name, timeout = "provisioning", config.ProvisionTimeout
+		case *StepCommit:
+			name, timeout = "commit", config.CommitTimeout
+		case *StepExport:
+			name, timeout = "export", config.CommitTimeout
+		}
+
+		if timeout == 0 && config.BuildTimeout == 0 {
+			wrapped = append(wrapped, step)
+			continue
+		}
+		wrapped = append(wrapped, &stepWithTimeout{Step: step, name: name, timeout: timeout})
+	}
+	return wrapped
+}
+
+// defaultStopTimeout is the time `docker stop` gives containers to stop by
+// default.
+const defaultStopTimeout = 10 * time.Second
+
+// stopTimeoutSeconds returns the `stop_timeout` in seconds, the unit of
+// `docker stop`, rounded up so that a timeout under a second still lets the
+// container stop.
+func stopTimeoutSeconds(timeout time.Duration) int {
+	return int((timeout + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+)
+
+// stepWait waits for its context to be done.
+type stepWait struct{}
+
+func (s *stepWait) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	<-ctx.Done()
+	state.Put("error", ctx.Err())
+	return multistep.ActionHalt
+}
+
+func (s *stepWait) Cleanup(state multistep.StateBag) {}
+
+func TestStepWithTimeout(t *testing.T) {
+	state := testState(t)
+	driver := state.Get("driver").(*MockDriver)
+	step := &stepWithTimeout{Step: &stepWait{}, name: "pull", timeout: time.Millisecond}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	err := state.Get("error").(error)
+	if !strings.Contains(err.Error(), "pull timed out after 1ms") {
+		t.Fatalf("bad error: %s", err)
+	}
+	if !driver.SetContextCalled || driver.SetContextCtx != nil {
+		t.Fatal("should've unset the context of the driver")
+	}
+	if name := step.InnerStepName(); name != "stepWait" {
+		t.Fatalf("bad name: %s", name)
+	}
+}
+
+func TestWithTimeouts(t *testing.T) {
+	steps := []multistep.Step{&StepTempDir{}, &StepPull{}, &commonsteps.StepProvision{}}
+
+	config := testConfigStruct(t)
+	if wrapped := withTimeouts(steps, config); wrapped[1] != steps[1] {
+		t.Fatal("should not wrap the steps without a timeout")
+	}
+
+	config.PullTimeout = time.Minute
+	wrapped := withTimeouts(steps, config)
+	if wrapped[0] != steps[0] || wrapped[2] != steps[2] {
+		t.Fatal("should only wrap the pull")
+	}
+	if step, ok := wrapped[1].(*stepWithTimeout); !ok || step.timeout != time.Minute {
+		t.Fatalf("bad step: %#v", wrapped[1])
+	}
+
+	// The commands of every step honor the timeout of the build
+	config.BuildTimeout = time.Hour
+	for _, step := range withTimeouts(steps, config) {
+		if _, ok := step.(*stepWithTimeout); !ok {
+			t.Fatalf("should wrap %#v", step)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ulimit is a resource limit of the container.
+type ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// The resource limits supported by `docker run --ulimit`.
+var ulimitNames = map[string]bool{
+	"core": true, "cpu": true, "data": true, "fsize": true, "locks": true,
+	"memlock": true, "msgqueue": true, "nice": true, "nofile": true,
+	"nproc": true, "rss": true, "rtprio": true, "rttime": true,
+	"sigpending": true, "stack": true,
+}
+
+// parseUlimit parses a ulimit value, either `limit` or `soft:hard`.
+func parseUlimit(name, value string) (*ulimit, error) {
+	if !ulimitNames[name] {
+		return nil, fmt.Errorf("`ulimits`: unknown limit %q", name)
+	}
+
+	soft, hard, ok := strings.Cut(value, ":")
+	if !ok {
+		hard = soft
+	}
+
+	limit := &ulimit{Name: name}
+	var err error
+	if limit.Soft, err = strconv.ParseInt(soft, 10, 64); err != nil {
+		return nil, fmt.Errorf("`ulimits`: %s value %q is not a number", name, value)
+	}
+	if limit.Hard, err = strconv.ParseInt(hard, 10, 64); err != nil {
+		return nil, fmt.Errorf("`ulimits`: %s value %q is not a number", name, value)
+	}
+	if limit.Hard != -1 && (limit.Soft == -1 || limit.Soft > limit.Hard) {
+		return nil, fmt.Errorf("`ulimits`: the soft %s limit can't be greater than the hard limit, got %q", name, value)
+	}
+
+	return limit, nil
+}
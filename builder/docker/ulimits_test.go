@@ -0,0 +1,24 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import "testing"
+
+func TestParseUlimit(t *testing.T) {
+	limit, err := parseUlimit("nofile", "1024:65536")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if *limit != (ulimit{Name: "nofile", Soft: 1024, Hard: 65536}) {
+		t.Fatalf("bad limit: %#v", limit)
+	}
+
+	limit, err = parseUlimit("memlock", "-1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if *limit != (ulimit{Name: "memlock", Soft: -1, Hard: -1}) {
+		t.Fatalf("bad limit: %#v", limit)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type WaitForConfig
+
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// The time to wait for the container to be ready.
+const defaultWaitForTimeout = 5 * time.Minute
+
+// WaitForConfig waits for the container to be ready before connecting to it
+// and running the provisioners, for the images that start services but
+// have no healthcheck. With both `port` and `log_regex`, the container is
+// ready once the port is listening and a line of its logs matches.
+//
+// ```hcl
+//
+//	wait_for {
+//	  port      = 8080
+//	  log_regex = "Server started"
+//	  timeout   = "2m"
+//	}
+//
+// ```
+type WaitForConfig struct {
+	// A TCP port of the container to wait for, until it accepts
+	// connections. The port is reached on the host the port is published
+	// on if it is, or else on the IP address of the container.
+	Port int `mapstructure:"port" required:"false"`
+	// A regular expression to wait for a line of the logs of the container
+	// to match, like `ready to accept connections`.
+	LogRegex string `mapstructure:"log_regex" required:"false"`
+	// The time to wait for the container to be ready, like `10m`. Defaults
+	// to `5m`.
+	Timeout time.Duration `mapstructure:"timeout" required:"false"`
+
+	logRe *regexp.Regexp
+}
+
+// IsDefault returns true if the block isn't set, in which case the
+// provisioners run as soon as the communicator connects.
+func (c *WaitForConfig) IsDefault() bool {
+	return c.Port == 0 && c.LogRegex == "" && c.Timeout == 0
+}
+
+// Prepare validates the options for the given driver and sets the
+// defaults.
+func (c *WaitForConfig) Prepare(driverType string) []error {
+	if c.IsDefault() {
+		return nil
+	}
+
+	var errs []error
+	if driverType == DriverBuildah {
+		errs = append(errs, fmt.Errorf("`wait_for` is not supported by the buildah driver, which runs no process in the container"))
+	}
+	if c.Port == 0 && c.LogRegex == "" {
+		errs = append(errs, fmt.Errorf("`wait_for`: one of `port` or `log_regex` is required"))
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("`wait_for`: %d is not a valid port", c.Port))
+	}
+	if c.LogRegex != "" {
+		re, err := regexp.Compile(c.LogRegex)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("`wait_for`: `log_regex` is not a valid regular expression: %s", err))
+		}
+		c.logRe = re
+	}
+
+	if c.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("`wait_for`: `timeout` can't be negative"))
+	} else if c.Timeout == 0 {
+		c.Timeout = defaultWaitForTimeout
+	}
+
+	return errs
+}
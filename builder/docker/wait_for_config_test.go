@@ -0,0 +1,38 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitForConfigPrepare(t *testing.T) {
+	c := WaitForConfig{Port: 8080, LogRegex: "ready"}
+	if errs := c.Prepare(DriverCLI); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.Timeout != defaultWaitForTimeout {
+		t.Fatalf("bad timeout: %s", c.Timeout)
+	}
+	if c.logRe == nil || !c.logRe.MatchString("server ready") {
+		t.Fatalf("bad regex: %v", c.logRe)
+	}
+
+	for _, c := range []WaitForConfig{
+		{Timeout: time.Minute},
+		{Port: 70000},
+		{LogRegex: "("},
+		{Port: 8080, Timeout: -time.Minute},
+	} {
+		if errs := c.Prepare(DriverCLI); len(errs) == 0 {
+			t.Errorf("should error: %#v", c)
+		}
+	}
+
+	c = WaitForConfig{Port: 8080}
+	if errs := c.Prepare(DriverBuildah); len(errs) == 0 {
+		t.Error("should error with buildah")
+	}
+}
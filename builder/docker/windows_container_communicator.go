@@ -11,7 +11,9 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
@@ -51,8 +53,8 @@ func (c *WindowsContainerCommunicator) Upload(dst string, src io.Reader, fi *os.
 	// Copy the file into place by copying the temporary file we put
 	// into the shared folder into the proper location in the container
 	cmd := &packersdk.RemoteCmd{
-		Command: fmt.Sprintf("Copy-Item -Path %s/%s -Destination %s", c.ContainerDir,
-			filepath.Base(tempfile.Name()), dst),
+		Command: c.uploadCommand(windowsPath(c.ContainerDir, filepath.Base(tempfile.Name())),
+			windowsPath(dst), false),
 	}
 	ctx := context.TODO()
 	if err := c.Start(ctx, cmd); err != nil {
@@ -129,16 +131,15 @@ func (c *WindowsContainerCommunicator) UploadDir(dst string, src string, exclude
 	}
 
 	// Determine the destination directory
-	containerSrc := filepath.Join(c.ContainerDir, filepath.Base(td))
-	containerDst := dst
-	if src[len(src)-1] != '/' {
-		containerDst = filepath.Join(dst, filepath.Base(src))
+	containerSrc := windowsPath(c.ContainerDir, filepath.Base(td))
+	containerDst := windowsPath(dst)
+	if !strings.HasSuffix(src, "/") && !strings.HasSuffix(src, `\`) {
+		containerDst = windowsPath(dst, filepath.Base(src))
 	}
 
 	// Make the directory, then copy into it
 	cmd := &packersdk.RemoteCmd{
-		Command: fmt.Sprintf("Copy-Item %s -Destination %s -Recurse",
-			containerSrc, containerDst),
+		Command: c.uploadCommand(containerSrc, containerDst, true),
 	}
 	ctx := context.TODO()
 	if err := c.Start(ctx, cmd); err != nil {
@@ -161,10 +162,9 @@ func (c *WindowsContainerCommunicator) Download(src string, dst io.Writer) error
 	// Copy file onto temp file on mounted volume inside container
 	var stdout, stderr bytes.Buffer
 	cmd := &packersdk.RemoteCmd{
-		Command: fmt.Sprintf("Copy-Item -Path %s -Destination %s/%s", src, c.ContainerDir,
-			filepath.Base(src)),
-		Stdout: &stdout,
-		Stderr: &stderr,
+		Command: c.copyCommand(windowsPath(src), windowsPath(c.ContainerDir, windowsBase(src)), false),
+		Stdout:  &stdout,
+		Stderr:  &stderr,
 	}
 	ctx := context.TODO()
 	if err := c.Start(ctx, cmd); err != nil {
@@ -179,7 +179,7 @@ func (c *WindowsContainerCommunicator) Download(src string, dst io.Writer) error
 	}
 
 	// Read that copied file into a new file opened on host machine
-	fsrc, err := os.Open(filepath.Join(c.HostDir, filepath.Base(src)))
+	fsrc, err := os.Open(filepath.Join(c.HostDir, windowsBase(src)))
 	if err != nil {
 		return err
 	}
@@ -193,3 +193,70 @@ func (c *WindowsContainerCommunicator) Download(src string, dst io.Writer) error
 
 	return nil
 }
+
+// copyCommand returns the command copying src to dst in the container,
+// with the shell the container runs commands with.
+func (c *WindowsContainerCommunicator) copyCommand(src, dst string, recurse bool) string {
+	if c.Config.WindowsShell == "cmd" {
+		if recurse {
+			return fmt.Sprintf(`xcopy "%s" "%s" /E /I /Y /Q`, src, dst)
+		}
+		return fmt.Sprintf(`copy /Y "%s" "%s"`, src, dst)
+	}
+
+	// -LiteralPath doesn't expand the wildcards, like the brackets that are
+	// valid in file names.
+	command := fmt.Sprintf("Copy-Item -LiteralPath %s -Destination %s -Force", powershellQuote(src), powershellQuote(dst))
+	if recurse {
+		command += " -Recurse"
+	}
+	return command
+}
+
+// uploadCommand returns the command copying src to dst in the container,
+// after creating the parent directory of dst, which the provisioners expect
+// to be created like with docker cp.
+func (c *WindowsContainerCommunicator) uploadCommand(src, dst string, recurse bool) string {
+	parent := windowsDir(dst)
+	if parent == "" {
+		return c.copyCommand(src, dst, recurse)
+	}
+
+	if c.Config.WindowsShell == "cmd" {
+		return fmt.Sprintf(`(if not exist "%s\" mkdir "%s") && %s`, parent, parent, c.copyCommand(src, dst, recurse))
+	}
+	return fmt.Sprintf("New-Item -ItemType Directory -Force -Path %s | Out-Null; %s",
+		powershellQuote(parent), c.copyCommand(src, dst, recurse))
+}
+
+// powershellQuote quotes s as a literal PowerShell string, in which single
+// quotes are escaped by doubling them.
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// windowsBase returns the last element of the Windows path, which can have
+// forward or back slashes.
+func windowsBase(p string) string {
+	return path.Base(strings.ReplaceAll(p, `\`, "/"))
+}
+
+// windowsDir returns the parent directory of the Windows path, or an empty
+// string if it is a drive or has no parent, like `C:\` or `file.txt`.
+func windowsDir(p string) string {
+	dir := path.Dir(strings.TrimRight(strings.ReplaceAll(p, `\`, "/"), "/"))
+	if dir == "." || dir == "/" || strings.HasSuffix(dir, ":") {
+		return ""
+	}
+	return strings.ReplaceAll(dir, "/", `\`)
+}
+
+// windowsPath joins the path elements with backslashes, whatever the OS
+// Packer runs on, so that paths are valid in the container when building
+// with a remote Windows daemon.
+func windowsPath(elem ...string) string {
+	for i, e := range elem {
+		elem[i] = strings.ReplaceAll(e, `\`, "/")
+	}
+	return strings.ReplaceAll(path.Join(elem...), "/", `\`)
+}
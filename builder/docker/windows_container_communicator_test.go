@@ -0,0 +1,70 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import "testing"
+
+func TestWindowsPath(t *testing.T) {
+	tc := []struct {
+		elem     []string
+		expected string
+	}{
+		{[]string{"c:/packer-files", "upload123"}, `c:\packer-files\upload123`},
+		{[]string{`C:\app\`, "bin"}, `C:\app\bin`},
+		{[]string{`C:\Program Files\app`}, `C:\Program Files\app`},
+	}
+
+	for _, c := range tc {
+		if got := windowsPath(c.elem...); got != c.expected {
+			t.Errorf("windowsPath(%v): expected %s, got %s", c.elem, c.expected, got)
+		}
+	}
+}
+
+func TestWindowsContainerCommunicator_copyCommand(t *testing.T) {
+	c := &WindowsContainerCommunicator{Communicator{Config: &Config{WindowsShell: "powershell"}}}
+	if got := c.copyCommand(`c:\a`, `c:\b`, true); got != `Copy-Item -LiteralPath 'c:\a' -Destination 'c:\b' -Force -Recurse` {
+		t.Errorf("bad powershell command: %s", got)
+	}
+	if got := c.copyCommand(`c:\a`, `c:\O'Brien`, false); got != `Copy-Item -LiteralPath 'c:\a' -Destination 'c:\O''Brien' -Force` {
+		t.Errorf("bad quoting: %s", got)
+	}
+
+	c.Config.WindowsShell = "cmd"
+	if got := c.copyCommand(`c:\a`, `c:\b`, false); got != `copy /Y "c:\a" "c:\b"` {
+		t.Errorf("bad cmd command: %s", got)
+	}
+}
+
+func TestWindowsContainerCommunicator_uploadCommand(t *testing.T) {
+	c := &WindowsContainerCommunicator{Communicator{Config: &Config{WindowsShell: "powershell"}}}
+	expected := `New-Item -ItemType Directory -Force -Path 'C:\app\bin' | Out-Null; ` +
+		`Copy-Item -LiteralPath 'c:\a' -Destination 'C:\app\bin\tool.exe' -Force`
+	if got := c.uploadCommand(`c:\a`, `C:\app\bin\tool.exe`, false); got != expected {
+		t.Errorf("bad powershell command: %s", got)
+	}
+
+	// Files uploaded at the root of a drive have no directory to create
+	if got := c.uploadCommand(`c:\a`, `C:\tool.exe`, false); got != c.copyCommand(`c:\a`, `C:\tool.exe`, false) {
+		t.Errorf("bad powershell command: %s", got)
+	}
+
+	c.Config.WindowsShell = "cmd"
+	expected = `(if not exist "C:\app\" mkdir "C:\app") && copy /Y "c:\a" "C:\app\tool.exe"`
+	if got := c.uploadCommand(`c:\a`, `C:\app\tool.exe`, false); got != expected {
+		t.Errorf("bad cmd command: %s", got)
+	}
+}
+
+func TestWindowsBase(t *testing.T) {
+	for p, expected := range map[string]string{
+		`C:\logs\out.txt`: "out.txt",
+		"C:/logs/out.txt": "out.txt",
+		"out.txt":         "out.txt",
+	} {
+		if got := windowsBase(p); got != expected {
+			t.Errorf("windowsBase(%s): expected %s, got %s", p, expected, got)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// defaultWinRMUser is the user created in Windows containers for the winrm
+// communicator, if no `winrm_username` is set.
+const defaultWinRMUser = "packer"
+
+// winrmSetup reports whether the WinRM service of the container has to be
+// set up before Packer connects to it.
+func winrmSetup(config *Config) bool {
+	return config.WindowsContainer && config.Comm.Type == "winrm" && !config.SkipWinRMSetup
+}
+
+// randomWinRMPassword returns a random password that meets the complexity
+// requirements of Windows: it has upper and lower case letters, digits and
+// a symbol.
+func randomWinRMPassword() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Failed to generate the WinRM password: %s", err)
+	}
+	return "Pk" + hex.EncodeToString(b) + "1!", nil
+}
+
+// winrmSetupScript returns the PowerShell script that creates the WinRM user
+// as an administrator, and replaces the listeners of the WinRM service with
+// one on the port of the communicator that accepts basic authentication.
+func winrmSetupScript(config *Config) string {
+	var script strings.Builder
+	fmt.Fprintf(&script, "$ErrorActionPreference = 'Stop'\n")
+	fmt.Fprintf(&script, "$user = %s\n", powershellQuote(config.Comm.WinRMUser))
+	fmt.Fprintf(&script, "$password = %s\n", powershellQuote(config.Comm.WinRMPassword))
+	script.WriteString(`net user $user > $null 2>&1
+if ($LASTEXITCODE -eq 0) {
+  net user $user $password /active:yes | Out-Null
+} else {
+  net user $user $password /add /y | Out-Null
+}
+if ($LASTEXITCODE -ne 0) { throw "Failed to set up the user $user" }
+net localgroup Administrators $user /add > $null 2>&1
+Set-ItemProperty -Path HKLM:\SOFTWARE\Microsoft\Windows\CurrentVersion\Policies\System -Name LocalAccountTokenFilterPolicy -Value 1 -Type DWord
+Set-Service -Name WinRM -StartupType Automatic
+Start-Service -Name WinRM
+Get-ChildItem -Path WSMan:\localhost\Listener | Remove-Item -Recurse -Force
+Set-Item -Path WSMan:\localhost\Service\Auth\Basic -Value $true
+`)
+	port := config.Comm.Port()
+	if config.Comm.WinRMUseSSL {
+		script.WriteString("$cert = New-SelfSignedCertificate -DnsName $env:COMPUTERNAME -CertStoreLocation Cert:\\LocalMachine\\My\n")
+		fmt.Fprintf(&script, "New-Item -Path WSMan:\\localhost\\Listener -Transport HTTPS -Address * -Port %d "+
+			"-CertificateThumbPrint $cert.Thumbprint -Force | Out-Null\n", port)
+	} else {
+		fmt.Fprintf(&script, "New-Item -Path WSMan:\\localhost\\Listener -Transport HTTP -Address * -Port %d -Force | Out-Null\n", port)
+		script.WriteString("Set-Item -Path WSMan:\\localhost\\Service\\AllowUnencrypted -Value $true\n")
+	}
+	return script.String()
+}
+
+// StepSetupWinRM sets up the WinRM service of a Windows container for the
+// winrm communicator, which most images don't run.
+type StepSetupWinRM struct{}
+
+func (s *StepSetupWinRM) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	if !winrmSetup(config) {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	containerId := state.Get("container_id").(string)
+	tempDir := state.Get("temp_dir").(string)
+
+	ui.Say(fmt.Sprintf("Setting up WinRM for the user %s...", config.Comm.WinRMUser))
+
+	// The script goes through the shared directory rather than the command
+	// line, which is logged, since it has the password.
+	script, err := os.CreateTemp(tempDir, "winrm-setup-*.ps1")
+	if err != nil {
+		err := fmt.Errorf("Error creating the WinRM setup script: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	defer os.Remove(script.Name())
+
+	_, err = script.WriteString(winrmSetupScript(config))
+	if cerr := script.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		err := fmt.Errorf("Error writing the WinRM setup script: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	cmd := exec.CommandContext(ctx, config.Executable, "exec", containerId,
+		"powershell", "-NoProfile", "-ExecutionPolicy", "Bypass",
+		"-File", windowsPath(config.ContainerDir, filepath.Base(script.Name())))
+	host := stateDockerHost(state)
+	host.Apply(cmd)
+	if err := runAndStream(cmd, ui); err != nil {
+		err := fmt.Errorf("Error setting up WinRM in the container: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepSetupWinRM) Cleanup(state multistep.StateBag) {}
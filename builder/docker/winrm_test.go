@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+)
+
+func TestRandomWinRMPassword(t *testing.T) {
+	a, err := randomWinRMPassword()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	b, err := randomWinRMPassword()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if a == b {
+		t.Fatal("the passwords should be random")
+	}
+	if !strings.ContainsAny(a, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") || !strings.ContainsAny(a, "0123456789") ||
+		!strings.ContainsAny(a, "!") {
+		t.Fatalf("the password doesn't meet the complexity requirements: %s", a)
+	}
+}
+
+func TestWinRMSetupScript(t *testing.T) {
+	config := &Config{
+		Comm: communicator.Config{
+			Type: "winrm",
+			WinRM: communicator.WinRM{
+				WinRMUser:     "it's",
+				WinRMPassword: "Secret1!",
+				WinRMPort:     5985,
+			},
+		},
+	}
+
+	script := winrmSetupScript(config)
+	for _, expected := range []string{
+		"$user = 'it''s'\n",
+		"$password = 'Secret1!'\n",
+		"-Transport HTTP -Address * -Port 5985",
+		"AllowUnencrypted -Value $true",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("the script should contain %q:\n%s", expected, script)
+		}
+	}
+
+	config.Comm.WinRMUseSSL = true
+	config.Comm.WinRMPort = 5986
+	script = winrmSetupScript(config)
+	if !strings.Contains(script, "-Transport HTTPS -Address * -Port 5986") {
+		t.Errorf("the script should create an HTTPS listener:\n%s", script)
+	}
+	if strings.Contains(script, "AllowUnencrypted") {
+		t.Errorf("the script shouldn't allow unencrypted traffic over HTTPS:\n%s", script)
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"os"
 
 	"github.com/hashicorp/packer-plugin-docker/builder/docker"
+	dockercompose "github.com/hashicorp/packer-plugin-docker/builder/docker-compose"
 	dockerimport "github.com/hashicorp/packer-plugin-docker/post-processor/docker-import"
 	dockerpush "github.com/hashicorp/packer-plugin-docker/post-processor/docker-push"
 	dockersave "github.com/hashicorp/packer-plugin-docker/post-processor/docker-save"
 	dockertag "github.com/hashicorp/packer-plugin-docker/post-processor/docker-tag"
+	dockersnapshot "github.com/hashicorp/packer-plugin-docker/provisioner/docker-snapshot"
 	"github.com/hashicorp/packer-plugin-docker/version"
 
 	"github.com/hashicorp/packer-plugin-sdk/plugin"
@@ -20,10 +22,12 @@ import (
 func main() {
 	pps := plugin.NewSet()
 	pps.RegisterBuilder(plugin.DEFAULT_NAME, new(docker.Builder))
+	pps.RegisterBuilder("compose", new(dockercompose.Builder))
 	pps.RegisterPostProcessor("import", new(dockerimport.PostProcessor))
 	pps.RegisterPostProcessor("push", new(dockerpush.PostProcessor))
 	pps.RegisterPostProcessor("save", new(dockersave.PostProcessor))
 	pps.RegisterPostProcessor("tag", new(dockertag.PostProcessor))
+	pps.RegisterProvisioner("snapshot", new(dockersnapshot.Provisioner))
 	pps.SetVersion(version.PluginVersion)
 	err := pps.Run()
 	if err != nil {
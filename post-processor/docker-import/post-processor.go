@@ -23,11 +23,14 @@ type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
 	Executable string   `mapstructure:"docker_path"`
+	DriverType string   `mapstructure:"driver"`
 	Repository string   `mapstructure:"repository"`
 	Tag        string   `mapstructure:"tag"`
 	Changes    []string `mapstructure:"changes"`
 	Platform   string   `mapstructure:"platform"`
 
+	docker.DockerHostConfig `mapstructure:",squash"`
+
 	ctx interpolate.Context
 }
 
@@ -50,8 +53,16 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	if err := docker.ValidateDriverType(p.config.DriverType); err != nil {
+		return err
+	}
+
+	if errs := p.config.DockerHostConfig.Prepare(p.config.DriverType); len(errs) > 0 {
+		return &packersdk.MultiError{Errors: errs}
+	}
+
 	if p.config.Executable == "" {
-		p.config.Executable = "docker"
+		p.config.Executable = docker.DefaultExecutable(p.config.DriverType)
 	}
 
 	return nil
@@ -78,10 +89,15 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 		importRepo += ":" + p.config.Tag
 	}
 
-	driver := &docker.DockerDriver{
-		Executable: p.config.Executable,
-		Ctx:        &p.config.ctx,
-		Ui:         ui,
+	host, stopSSHAgent, err := p.config.DockerHostConfig.StartSSHAgent()
+	if err != nil {
+		return nil, false, false, err
+	}
+	defer stopSSHAgent()
+
+	driver, err := docker.NewDriver(p.config.DriverType, p.config.Executable, "", host, docker.RegistryTLSConfig{}, docker.ProxyEnvConfig{}, &p.config.ctx, ui)
+	if err != nil {
+		return nil, false, false, err
 	}
 
 	ui.Message("Importing image: " + artifact.Id())
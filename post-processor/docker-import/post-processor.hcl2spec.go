@@ -10,19 +10,29 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName     *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType   *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion   *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug         *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce         *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError       *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	Executable          *string           `mapstructure:"docker_path" cty:"docker_path" hcl:"docker_path"`
-	Repository          *string           `mapstructure:"repository" cty:"repository" hcl:"repository"`
-	Tag                 *string           `mapstructure:"tag" cty:"tag" hcl:"tag"`
-	Changes             []string          `mapstructure:"changes" cty:"changes" hcl:"changes"`
-	Platform            *string           `mapstructure:"platform" cty:"platform" hcl:"platform"`
+	PackerBuildName             *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType           *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion           *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug                 *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce                 *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError               *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars              map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars         []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	Executable                  *string           `mapstructure:"docker_path" cty:"docker_path" hcl:"docker_path"`
+	DriverType                  *string           `mapstructure:"driver" cty:"driver" hcl:"driver"`
+	Repository                  *string           `mapstructure:"repository" cty:"repository" hcl:"repository"`
+	Tag                         *string           `mapstructure:"tag" cty:"tag" hcl:"tag"`
+	Changes                     []string          `mapstructure:"changes" cty:"changes" hcl:"changes"`
+	Platform                    *string           `mapstructure:"platform" cty:"platform" hcl:"platform"`
+	DockerHost                  *string           `mapstructure:"docker_host" required:"false" cty:"docker_host" hcl:"docker_host"`
+	DockerContext               *string           `mapstructure:"docker_context" required:"false" cty:"docker_context" hcl:"docker_context"`
+	TLSVerify                   *bool             `mapstructure:"tls_verify" required:"false" cty:"tls_verify" hcl:"tls_verify"`
+	CertPath                    *string           `mapstructure:"cert_path" required:"false" cty:"cert_path" hcl:"cert_path"`
+	CACert                      *string           `mapstructure:"ca_cert" required:"false" cty:"ca_cert" hcl:"ca_cert"`
+	ClientCert                  *string           `mapstructure:"client_cert" required:"false" cty:"client_cert" hcl:"client_cert"`
+	ClientKey                   *string           `mapstructure:"client_key" required:"false" cty:"client_key" hcl:"client_key"`
+	DockerHostSSHPrivateKeyFile *string           `mapstructure:"docker_host_ssh_private_key_file" required:"false" cty:"docker_host_ssh_private_key_file" hcl:"docker_host_ssh_private_key_file"`
+	DockerHostSSHAgentSocket    *string           `mapstructure:"docker_host_ssh_agent_socket" required:"false" cty:"docker_host_ssh_agent_socket" hcl:"docker_host_ssh_agent_socket"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -37,19 +47,29 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"docker_path":                &hcldec.AttrSpec{Name: "docker_path", Type: cty.String, Required: false},
-		"repository":                 &hcldec.AttrSpec{Name: "repository", Type: cty.String, Required: false},
-		"tag":                        &hcldec.AttrSpec{Name: "tag", Type: cty.String, Required: false},
-		"changes":                    &hcldec.AttrSpec{Name: "changes", Type: cty.List(cty.String), Required: false},
-		"platform":                   &hcldec.AttrSpec{Name: "platform", Type: cty.String, Required: false},
+		"packer_build_name":                &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":              &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":              &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                     &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                     &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":                  &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":            &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":       &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"docker_path":                      &hcldec.AttrSpec{Name: "docker_path", Type: cty.String, Required: false},
+		"driver":                           &hcldec.AttrSpec{Name: "driver", Type: cty.String, Required: false},
+		"repository":                       &hcldec.AttrSpec{Name: "repository", Type: cty.String, Required: false},
+		"tag":                              &hcldec.AttrSpec{Name: "tag", Type: cty.String, Required: false},
+		"changes":                          &hcldec.AttrSpec{Name: "changes", Type: cty.List(cty.String), Required: false},
+		"platform":                         &hcldec.AttrSpec{Name: "platform", Type: cty.String, Required: false},
+		"docker_host":                      &hcldec.AttrSpec{Name: "docker_host", Type: cty.String, Required: false},
+		"docker_context":                   &hcldec.AttrSpec{Name: "docker_context", Type: cty.String, Required: false},
+		"tls_verify":                       &hcldec.AttrSpec{Name: "tls_verify", Type: cty.Bool, Required: false},
+		"cert_path":                        &hcldec.AttrSpec{Name: "cert_path", Type: cty.String, Required: false},
+		"ca_cert":                          &hcldec.AttrSpec{Name: "ca_cert", Type: cty.String, Required: false},
+		"client_cert":                      &hcldec.AttrSpec{Name: "client_cert", Type: cty.String, Required: false},
+		"client_key":                       &hcldec.AttrSpec{Name: "client_key", Type: cty.String, Required: false},
+		"docker_host_ssh_private_key_file": &hcldec.AttrSpec{Name: "docker_host_ssh_private_key_file", Type: cty.String, Required: false},
+		"docker_host_ssh_agent_socket":     &hcldec.AttrSpec{Name: "docker_host_ssh_agent_socket", Type: cty.String, Required: false},
 	}
 	return s
 }
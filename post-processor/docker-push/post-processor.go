@@ -9,6 +9,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-docker/builder/docker"
@@ -22,18 +25,45 @@ import (
 
 const BuilderIdImport = "packer.post-processor.docker-import"
 
+// The time to wait before the first retry of a push, and the cap of the time
+// between retries, unless the configured delay is longer.
+const (
+	defaultPushRetryDelay = 5 * time.Second
+	maxPushRetryDelay     = time.Minute
+)
+
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
 	Executable             string `mapstructure:"docker_path"`
+	DriverType             string `mapstructure:"driver"`
 	Login                  bool
-	LoginUsername          string `mapstructure:"login_username"`
-	LoginPassword          string `mapstructure:"login_password"`
-	LoginServer            string `mapstructure:"login_server"`
-	EcrLogin               bool   `mapstructure:"ecr_login"`
-	Platform               string `mapstructure:"platform"`
+	LoginUsername          string           `mapstructure:"login_username"`
+	LoginPassword          string           `mapstructure:"login_password"`
+	LoginServer            string           `mapstructure:"login_server"`
+	EcrLogin               bool             `mapstructure:"ecr_login"`
+	GarLogin               bool             `mapstructure:"gar_login"`
+	GcrLogin               bool             `mapstructure:"gcr_login"`
+	GarKeyFile             string           `mapstructure:"gar_key_file"`
+	AcrLogin               bool             `mapstructure:"acr_login"`
+	AzureTenantID          string           `mapstructure:"azure_tenant_id"`
+	AzureClientID          string           `mapstructure:"azure_client_id"`
+	AzureClientSecret      string           `mapstructure:"azure_client_secret"`
+	GhcrLogin              bool             `mapstructure:"ghcr_login"`
+	Platform               string           `mapstructure:"platform"`
+	PushRetries            int              `mapstructure:"push_retries"`
+	PushRetryDelay         time.Duration    `mapstructure:"push_retry_delay"`
+	PushConcurrency        int              `mapstructure:"push_concurrency"`
+	SkipIfExists           bool             `mapstructure:"skip_if_exists"`
+	ImmutableTags          bool             `mapstructure:"immutable_tags"`
+	Registries             []RegistryConfig `mapstructure:"registries"`
 	docker.AwsAccessConfig `mapstructure:",squash"`
 
+	docker.DockerHostConfig  `mapstructure:",squash"`
+	docker.RegistryTLSConfig `mapstructure:",squash"`
+
+	ProxyEnv docker.ProxyEnvConfig `mapstructure:"proxy_env"`
+
 	ctx interpolate.Context
 }
 
@@ -58,12 +88,82 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	if err := docker.ValidateDriverType(p.config.DriverType); err != nil {
+		return err
+	}
+
+	if errs := p.config.DockerHostConfig.Prepare(p.config.DriverType); len(errs) > 0 {
+		return &packersdk.MultiError{Errors: errs}
+	}
+
+	if errs := p.config.RegistryTLSConfig.Prepare(p.config.DriverType); len(errs) > 0 {
+		return &packersdk.MultiError{Errors: errs}
+	}
+
+	if errs := p.config.ProxyEnv.Prepare(); len(errs) > 0 {
+		return &packersdk.MultiError{Errors: errs}
+	}
+
+	if errs := p.config.AwsAccessConfig.Prepare(); len(errs) > 0 {
+		return &packersdk.MultiError{Errors: errs}
+	}
+
 	if p.config.Executable == "" {
-		p.config.Executable = "docker"
+		p.config.Executable = docker.DefaultExecutable(p.config.DriverType)
 	}
 
-	if p.config.EcrLogin && p.config.LoginServer == "" {
-		return fmt.Errorf("ECR login requires login server to be provided.")
+	if p.config.PushRetries < 0 {
+		return fmt.Errorf("push_retries can't be negative")
+	}
+	if p.config.PushRetryDelay < 0 {
+		return fmt.Errorf("push_retry_delay can't be negative")
+	}
+	if p.config.PushRetryDelay == 0 {
+		p.config.PushRetryDelay = defaultPushRetryDelay
+	}
+	if p.config.PushConcurrency < 0 {
+		return fmt.Errorf("push_concurrency can't be negative")
+	}
+	if p.config.PushConcurrency == 0 {
+		p.config.PushConcurrency = 1
+	}
+
+	switch p.config.DriverType {
+	case "", docker.DriverCLI, docker.DriverAPI:
+	default:
+		if p.config.ImmutableTags {
+			return fmt.Errorf("immutable_tags is not supported by the %s driver", p.config.DriverType)
+		}
+	}
+
+	// ECR Public has a single login server.
+	if p.config.EcrLogin && p.config.PublicEcrGallery && p.config.LoginServer == "" {
+		p.config.LoginServer = strings.TrimSuffix(docker.EcrPublicHost, "/")
+	}
+	// gcr_login is an alias of gar_login, which logs in to Container
+	// Registry as well.
+	p.config.GarLogin = p.config.GarLogin || p.config.GcrLogin
+	if p.config.GhcrLogin && p.config.LoginServer == "" {
+		p.config.LoginServer = docker.GhcrLoginServer
+	}
+	defaultRegistry := p.config.defaultRegistry()
+	if errs := defaultRegistry.prepareLogin(); len(errs) > 0 {
+		return &packersdk.MultiError{Errors: errs}
+	}
+
+	if len(p.config.Registries) > 0 && (p.config.Login || len(defaultRegistry.cloudRegistries()) > 0 ||
+		defaultRegistry.GarKeyFile != "" || defaultRegistry.AzureClientID != "") {
+		return fmt.Errorf("login, ecr_login, gar_login, acr_login, ghcr_login, gar_key_file and the azure options " +
+			"can't be set with registries, set them in each of the registries instead")
+	}
+	var errs *packersdk.MultiError
+	for i := range p.config.Registries {
+		for _, err := range p.config.Registries[i].Prepare() {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("registries[%d]: %s", i, err))
+		}
+	}
+	if errs != nil {
+		return errs
 	}
 	return nil
 }
@@ -97,46 +197,23 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 						fmt.Sprintf("Error removing temporary Docker configuration directory: %s", err))
 				}
 			}()
-		}
 
-		// If no driver is set, then we use the real driver
-		driver = &docker.DockerDriver{
-			Executable: p.config.Executable,
-			Ctx:        &p.config.ctx,
-			Ui:         ui,
-			ConfigDir:  configDir,
+			if err := p.config.DockerHostConfig.LinkContexts(tmpDir); err != nil {
+				return nil, false, false, err
+			}
 		}
-	}
 
-	if p.config.EcrLogin {
-		ui.Message("Fetching ECR credentials...")
-
-		username, password, err := p.config.EcrGetLogin(p.config.LoginServer)
+		// If no driver is set, then we use the real driver
+		host, stopSSHAgent, err := p.config.DockerHostConfig.StartSSHAgent()
 		if err != nil {
 			return nil, false, false, err
 		}
+		defer stopSSHAgent()
 
-		p.config.LoginUsername = username
-		p.config.LoginPassword = password
-	}
-
-	if p.config.Login || p.config.EcrLogin {
-		ui.Message("Logging in...")
-		err := driver.Login(
-			p.config.LoginServer,
-			p.config.LoginUsername,
-			p.config.LoginPassword)
+		driver, err = docker.NewDriver(p.config.DriverType, p.config.Executable, configDir, host, p.config.RegistryTLSConfig, p.config.ProxyEnv, &p.config.ctx, ui)
 		if err != nil {
-			return nil, false, false, fmt.Errorf(
-				"Error logging in to Docker: %s", err)
+			return nil, false, false, err
 		}
-
-		defer func() {
-			ui.Message("Logging out...")
-			if err := driver.Logout(p.config.LoginServer); err != nil {
-				ui.Error(fmt.Sprintf("Error logging out: %s", err))
-			}
-		}()
 	}
 
 	var tags []string
@@ -154,12 +231,32 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	names := []string{artifact.Id()}
 	names = append(names, tags...)
 
-	// Get the name.
-	for _, name := range names {
-		ui.Message("Pushing: " + name)
-		if err := driver.Push(name, p.config.Platform); err != nil {
+	// The names are pushed to their own repository, or to the repository of
+	// each of the registries instead.
+	registries := p.config.Registries
+	if len(registries) == 0 {
+		registries = []RegistryConfig{p.config.defaultRegistry()}
+	}
+
+	var pushed []string
+	for i := range registries {
+		// The credentials fetched for the registry are set in a copy, and
+		// not in the config of the following pushes.
+		r := registries[i]
+		registryNames := names
+		if r.Repository != "" {
+			registryNames = r.names(names)
+			for _, name := range registryNames {
+				if err := driver.TagImage(artifact.Id(), name, true); err != nil {
+					return nil, false, false, fmt.Errorf("Error tagging %s: %s", name, err)
+				}
+			}
+		}
+
+		if err := p.pushToRegistry(ctx, ui, driver, artifact.Id(), r, registryNames); err != nil {
 			return nil, false, false, err
 		}
+		pushed = append(pushed, registryNames...)
 	}
 
 	// Store digest in state's generated data.
@@ -168,7 +265,22 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 		ui.Message("Unable to determine digest for source image, ignoring it for now")
 	}
 
-	stateData := map[string]interface{}{"docker_tags": tags}
+	// Store the repo digest of every pushed name, so that each one can be
+	// pinned by digest.
+	digests := map[string]string{}
+	for _, name := range pushed {
+		repoDigest, err := docker.PushedDigest(driver, artifact.Id(), repository(name))
+		if err != nil {
+			ui.Message(fmt.Sprintf("Unable to determine the digest of %s, ignoring it for now", name))
+			continue
+		}
+		digests[name] = repoDigest
+	}
+
+	stateData := map[string]interface{}{
+		"docker_tags":    tags,
+		"docker_digests": digests,
+	}
 	// Update the state's generated data with the digest, if it exists, and
 	// continue.
 	data := artifact.State("generated_data")
@@ -182,6 +294,7 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	}
 
 	newGenData["Digest"] = digest
+	newGenData["Digests"] = digests
 	// The RPC turns our original map[string]interface{} into a
 	// map[interface]interface so we need to turn it back
 	stateData["generated_data"] = newGenData
@@ -195,3 +308,196 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 
 	return artifact, true, false, nil
 }
+
+// repository returns the repository of the image name, without its tag or
+// digest.
+func repository(name string) string {
+	name, _, _ = strings.Cut(name, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name
+}
+
+// defaultRegistry returns the registry of the login options of the
+// post-processor, which the names are pushed to if no registries are set.
+func (c *Config) defaultRegistry() RegistryConfig {
+	return RegistryConfig{
+		Login:         c.Login,
+		LoginUsername: c.LoginUsername,
+		LoginPassword: c.LoginPassword,
+		LoginServer:   c.LoginServer,
+		EcrLogin:      c.EcrLogin,
+		GarLogin:      c.GarLogin,
+		AcrLogin:      c.AcrLogin,
+		GhcrLogin:     c.GhcrLogin,
+
+		GarKeyFile:        c.GarKeyFile,
+		AzureTenantID:     c.AzureTenantID,
+		AzureClientID:     c.AzureClientID,
+		AzureClientSecret: c.AzureClientSecret,
+	}
+}
+
+// pushToRegistry logs in to the registry, if its login options are set,
+// and pushes the names.
+func (p *PostProcessor) pushToRegistry(ctx context.Context, ui packersdk.Ui, driver docker.Driver, imageId string, r RegistryConfig, names []string) error {
+	if registry := r.cloudRegistry(); registry != "" {
+		ui.Message(fmt.Sprintf("Fetching %s credentials...", registry))
+
+		var username, password string
+		var err error
+		switch registry {
+		case registryECR:
+			username, password, err = p.config.EcrGetLogin(r.LoginServer)
+		case registryGAR:
+			username, password, err = docker.GarCredentials(ctx, r.GarKeyFile)
+		case registryACR:
+			username, password, err = docker.AcrCredentials(ctx, r.LoginServer, r.AzureTenantID, r.AzureClientID, r.AzureClientSecret)
+		case registryGHCR:
+			username, password, err = docker.GhcrCredentials(r.LoginUsername, r.LoginPassword)
+		}
+		if err != nil {
+			return err
+		}
+
+		r.LoginUsername = username
+		r.LoginPassword = password
+	}
+
+	if r.Login || r.cloudRegistry() != "" {
+		ui.Message("Logging in...")
+		err := driver.Login(
+			r.LoginServer,
+			r.LoginUsername,
+			r.LoginPassword)
+		if err != nil {
+			return fmt.Errorf(
+				"Error logging in to Docker: %s", err)
+		}
+
+		defer func() {
+			ui.Message("Logging out...")
+			if err := driver.Logout(r.LoginServer); err != nil {
+				ui.Error(fmt.Sprintf("Error logging out: %s", err))
+			}
+		}()
+	}
+
+	if p.config.ImmutableTags {
+		var err error
+		if names, err = p.checkImmutableTags(ui, driver, imageId, names); err != nil {
+			return err
+		}
+	}
+
+	return p.pushNames(ctx, ui, driver, names)
+}
+
+// checkImmutableTags compares the digests of the names in their registry
+// with the repo digests of the image, before anything is pushed. It returns
+// the names the registry doesn't have, and fails if the registry has one of
+// the names for another image.
+func (p *PostProcessor) checkImmutableTags(ui packersdk.Ui, driver docker.Driver, imageId string, names []string) ([]string, error) {
+	var missing []string
+	for _, name := range names {
+		remoteDigest, err := driver.RemoteDigest(name)
+		if err != nil {
+			return nil, fmt.Errorf("Error looking for %s in its registry: %s", name, err)
+		}
+		if remoteDigest == "" {
+			missing = append(missing, name)
+			continue
+		}
+
+		repoDigest, err := docker.PushedDigest(driver, imageId, repository(name))
+		if err != nil || !strings.HasSuffix(repoDigest, "@"+remoteDigest) {
+			return nil, fmt.Errorf("%s is already in the registry with digest %s, which isn't the image, "+
+				"and immutable_tags is set", name, remoteDigest)
+		}
+		ui.Message(fmt.Sprintf("Skipping %s, the registry already has the image with that name", name))
+	}
+	return missing, nil
+}
+
+// pushNames pushes the names, up to push_concurrency at once. The layers are
+// shared by all the names, so the pushes after the first one mostly upload
+// manifests.
+func (p *PostProcessor) pushNames(ctx context.Context, ui packersdk.Ui, driver docker.Driver, names []string) error {
+	if p.config.PushConcurrency <= 1 {
+		for _, name := range names {
+			if err := p.push(ctx, ui, driver, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var errs *packersdk.MultiError
+	slots := make(chan struct{}, p.config.PushConcurrency)
+
+	for _, name := range names {
+		name := name
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if err := p.push(ctx, ui, driver, name); err != nil {
+				lock.Lock()
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error pushing %s: %s", name, err))
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// push pushes the name, unless skip_if_exists is set and the registry
+// already has it.
+func (p *PostProcessor) push(ctx context.Context, ui packersdk.Ui, driver docker.Driver, name string) error {
+	if p.config.SkipIfExists {
+		exists, err := driver.RemoteImageExists(name)
+		if err != nil {
+			return fmt.Errorf("Error looking for %s in its registry: %s", name, err)
+		}
+		if exists {
+			ui.Message(fmt.Sprintf("Skipping %s, it is already in the registry", name))
+			return nil
+		}
+	}
+
+	ui.Message("Pushing: " + name)
+	return p.pushWithRetries(ctx, ui, driver, name)
+}
+
+// pushWithRetries pushes the image to its registry, and retries the
+// transient failures with an exponential backoff.
+func (p *PostProcessor) pushWithRetries(ctx context.Context, ui packersdk.Ui, driver docker.Driver, name string) error {
+	delay := p.config.PushRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := driver.Push(name, p.config.Platform)
+		if err == nil || attempt >= p.config.PushRetries || !docker.IsTransientRegistryError(err) {
+			return err
+		}
+
+		ui.Message(fmt.Sprintf("Push of %s failed, retrying in %s (%d/%d): %s", name, delay, attempt+1, p.config.PushRetries, err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > maxPushRetryDelay && p.config.PushRetryDelay <= maxPushRetryDelay {
+			delay = maxPushRetryDelay
+		}
+	}
+}
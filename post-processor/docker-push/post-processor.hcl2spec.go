@@ -4,32 +4,65 @@ package dockerpush
 
 import (
 	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-docker/builder/docker"
 	"github.com/zclconf/go-cty/cty"
 )
 
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName     *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType   *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion   *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug         *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce         *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError       *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	Executable          *string           `mapstructure:"docker_path" cty:"docker_path" hcl:"docker_path"`
-	Login               *bool             `cty:"login" hcl:"login"`
-	LoginUsername       *string           `mapstructure:"login_username" cty:"login_username" hcl:"login_username"`
-	LoginPassword       *string           `mapstructure:"login_password" cty:"login_password" hcl:"login_password"`
-	LoginServer         *string           `mapstructure:"login_server" cty:"login_server" hcl:"login_server"`
-	EcrLogin            *bool             `mapstructure:"ecr_login" cty:"ecr_login" hcl:"ecr_login"`
-	Platform            *string           `mapstructure:"platform" cty:"platform" hcl:"platform"`
-	AccessKey           *string           `mapstructure:"aws_access_key" required:"false" cty:"aws_access_key" hcl:"aws_access_key"`
-	SecretKey           *string           `mapstructure:"aws_secret_key" required:"false" cty:"aws_secret_key" hcl:"aws_secret_key"`
-	Token               *string           `mapstructure:"aws_token" required:"false" cty:"aws_token" hcl:"aws_token"`
-	Profile             *string           `mapstructure:"aws_profile" required:"false" cty:"aws_profile" hcl:"aws_profile"`
-	PublicEcrGallery    *bool             `mapstructure:"aws_force_use_public_ecr" required:"false" cty:"aws_force_use_public_ecr" hcl:"aws_force_use_public_ecr"`
+	PackerBuildName             *string                    `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType           *string                    `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion           *string                    `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug                 *bool                      `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce                 *bool                      `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError               *string                    `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars              map[string]string          `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars         []string                   `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	Executable                  *string                    `mapstructure:"docker_path" cty:"docker_path" hcl:"docker_path"`
+	DriverType                  *string                    `mapstructure:"driver" cty:"driver" hcl:"driver"`
+	Login                       *bool                      `cty:"login" hcl:"login"`
+	LoginUsername               *string                    `mapstructure:"login_username" cty:"login_username" hcl:"login_username"`
+	LoginPassword               *string                    `mapstructure:"login_password" cty:"login_password" hcl:"login_password"`
+	LoginServer                 *string                    `mapstructure:"login_server" cty:"login_server" hcl:"login_server"`
+	EcrLogin                    *bool                      `mapstructure:"ecr_login" cty:"ecr_login" hcl:"ecr_login"`
+	GarLogin                    *bool                      `mapstructure:"gar_login" cty:"gar_login" hcl:"gar_login"`
+	GcrLogin                    *bool                      `mapstructure:"gcr_login" cty:"gcr_login" hcl:"gcr_login"`
+	GarKeyFile                  *string                    `mapstructure:"gar_key_file" cty:"gar_key_file" hcl:"gar_key_file"`
+	AcrLogin                    *bool                      `mapstructure:"acr_login" cty:"acr_login" hcl:"acr_login"`
+	AzureTenantID               *string                    `mapstructure:"azure_tenant_id" cty:"azure_tenant_id" hcl:"azure_tenant_id"`
+	AzureClientID               *string                    `mapstructure:"azure_client_id" cty:"azure_client_id" hcl:"azure_client_id"`
+	AzureClientSecret           *string                    `mapstructure:"azure_client_secret" cty:"azure_client_secret" hcl:"azure_client_secret"`
+	GhcrLogin                   *bool                      `mapstructure:"ghcr_login" cty:"ghcr_login" hcl:"ghcr_login"`
+	Platform                    *string                    `mapstructure:"platform" cty:"platform" hcl:"platform"`
+	PushRetries                 *int                       `mapstructure:"push_retries" cty:"push_retries" hcl:"push_retries"`
+	PushRetryDelay              *string                    `mapstructure:"push_retry_delay" cty:"push_retry_delay" hcl:"push_retry_delay"`
+	PushConcurrency             *int                       `mapstructure:"push_concurrency" cty:"push_concurrency" hcl:"push_concurrency"`
+	SkipIfExists                *bool                      `mapstructure:"skip_if_exists" cty:"skip_if_exists" hcl:"skip_if_exists"`
+	ImmutableTags               *bool                      `mapstructure:"immutable_tags" cty:"immutable_tags" hcl:"immutable_tags"`
+	Registries                  []FlatRegistryConfig       `mapstructure:"registries" cty:"registries" hcl:"registries"`
+	AccessKey                   *string                    `mapstructure:"aws_access_key" required:"false" cty:"aws_access_key" hcl:"aws_access_key"`
+	SecretKey                   *string                    `mapstructure:"aws_secret_key" required:"false" cty:"aws_secret_key" hcl:"aws_secret_key"`
+	Token                       *string                    `mapstructure:"aws_token" required:"false" cty:"aws_token" hcl:"aws_token"`
+	Profile                     *string                    `mapstructure:"aws_profile" required:"false" cty:"aws_profile" hcl:"aws_profile"`
+	WebIdentityRoleARN          *string                    `mapstructure:"aws_web_identity_role_arn" required:"false" cty:"aws_web_identity_role_arn" hcl:"aws_web_identity_role_arn"`
+	WebIdentityTokenFile        *string                    `mapstructure:"aws_web_identity_token_file" required:"false" cty:"aws_web_identity_token_file" hcl:"aws_web_identity_token_file"`
+	WebIdentitySessionName      *string                    `mapstructure:"aws_web_identity_session_name" required:"false" cty:"aws_web_identity_session_name" hcl:"aws_web_identity_session_name"`
+	WebIdentityDuration         *string                    `mapstructure:"aws_web_identity_duration" required:"false" cty:"aws_web_identity_duration" hcl:"aws_web_identity_duration"`
+	PublicEcrGallery            *bool                      `mapstructure:"aws_force_use_public_ecr" required:"false" cty:"aws_force_use_public_ecr" hcl:"aws_force_use_public_ecr"`
+	EcrEndpoint                 *string                    `mapstructure:"ecr_endpoint" required:"false" cty:"ecr_endpoint" hcl:"ecr_endpoint"`
+	DockerHost                  *string                    `mapstructure:"docker_host" required:"false" cty:"docker_host" hcl:"docker_host"`
+	DockerContext               *string                    `mapstructure:"docker_context" required:"false" cty:"docker_context" hcl:"docker_context"`
+	TLSVerify                   *bool                      `mapstructure:"tls_verify" required:"false" cty:"tls_verify" hcl:"tls_verify"`
+	CertPath                    *string                    `mapstructure:"cert_path" required:"false" cty:"cert_path" hcl:"cert_path"`
+	CACert                      *string                    `mapstructure:"ca_cert" required:"false" cty:"ca_cert" hcl:"ca_cert"`
+	ClientCert                  *string                    `mapstructure:"client_cert" required:"false" cty:"client_cert" hcl:"client_cert"`
+	ClientKey                   *string                    `mapstructure:"client_key" required:"false" cty:"client_key" hcl:"client_key"`
+	DockerHostSSHPrivateKeyFile *string                    `mapstructure:"docker_host_ssh_private_key_file" required:"false" cty:"docker_host_ssh_private_key_file" hcl:"docker_host_ssh_private_key_file"`
+	DockerHostSSHAgentSocket    *string                    `mapstructure:"docker_host_ssh_agent_socket" required:"false" cty:"docker_host_ssh_agent_socket" hcl:"docker_host_ssh_agent_socket"`
+	InsecureRegistries          []string                   `mapstructure:"insecure_registries" required:"false" cty:"insecure_registries" hcl:"insecure_registries"`
+	RegistryCAFile              *string                    `mapstructure:"registry_ca_file" required:"false" cty:"registry_ca_file" hcl:"registry_ca_file"`
+	ProxyEnv                    *docker.FlatProxyEnvConfig `mapstructure:"proxy_env" cty:"proxy_env" hcl:"proxy_env"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -44,26 +77,58 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"docker_path":                &hcldec.AttrSpec{Name: "docker_path", Type: cty.String, Required: false},
-		"login":                      &hcldec.AttrSpec{Name: "login", Type: cty.Bool, Required: false},
-		"login_username":             &hcldec.AttrSpec{Name: "login_username", Type: cty.String, Required: false},
-		"login_password":             &hcldec.AttrSpec{Name: "login_password", Type: cty.String, Required: false},
-		"login_server":               &hcldec.AttrSpec{Name: "login_server", Type: cty.String, Required: false},
-		"ecr_login":                  &hcldec.AttrSpec{Name: "ecr_login", Type: cty.Bool, Required: false},
-		"platform":                   &hcldec.AttrSpec{Name: "platform", Type: cty.String, Required: false},
-		"aws_access_key":             &hcldec.AttrSpec{Name: "aws_access_key", Type: cty.String, Required: false},
-		"aws_secret_key":             &hcldec.AttrSpec{Name: "aws_secret_key", Type: cty.String, Required: false},
-		"aws_token":                  &hcldec.AttrSpec{Name: "aws_token", Type: cty.String, Required: false},
-		"aws_profile":                &hcldec.AttrSpec{Name: "aws_profile", Type: cty.String, Required: false},
-		"aws_force_use_public_ecr":   &hcldec.AttrSpec{Name: "aws_force_use_public_ecr", Type: cty.Bool, Required: false},
+		"packer_build_name":                &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":              &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":              &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                     &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                     &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":                  &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":            &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":       &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"docker_path":                      &hcldec.AttrSpec{Name: "docker_path", Type: cty.String, Required: false},
+		"driver":                           &hcldec.AttrSpec{Name: "driver", Type: cty.String, Required: false},
+		"login":                            &hcldec.AttrSpec{Name: "login", Type: cty.Bool, Required: false},
+		"login_username":                   &hcldec.AttrSpec{Name: "login_username", Type: cty.String, Required: false},
+		"login_password":                   &hcldec.AttrSpec{Name: "login_password", Type: cty.String, Required: false},
+		"login_server":                     &hcldec.AttrSpec{Name: "login_server", Type: cty.String, Required: false},
+		"ecr_login":                        &hcldec.AttrSpec{Name: "ecr_login", Type: cty.Bool, Required: false},
+		"gar_login":                        &hcldec.AttrSpec{Name: "gar_login", Type: cty.Bool, Required: false},
+		"gcr_login":                        &hcldec.AttrSpec{Name: "gcr_login", Type: cty.Bool, Required: false},
+		"gar_key_file":                     &hcldec.AttrSpec{Name: "gar_key_file", Type: cty.String, Required: false},
+		"acr_login":                        &hcldec.AttrSpec{Name: "acr_login", Type: cty.Bool, Required: false},
+		"azure_tenant_id":                  &hcldec.AttrSpec{Name: "azure_tenant_id", Type: cty.String, Required: false},
+		"azure_client_id":                  &hcldec.AttrSpec{Name: "azure_client_id", Type: cty.String, Required: false},
+		"azure_client_secret":              &hcldec.AttrSpec{Name: "azure_client_secret", Type: cty.String, Required: false},
+		"ghcr_login":                       &hcldec.AttrSpec{Name: "ghcr_login", Type: cty.Bool, Required: false},
+		"platform":                         &hcldec.AttrSpec{Name: "platform", Type: cty.String, Required: false},
+		"push_retries":                     &hcldec.AttrSpec{Name: "push_retries", Type: cty.Number, Required: false},
+		"push_retry_delay":                 &hcldec.AttrSpec{Name: "push_retry_delay", Type: cty.String, Required: false},
+		"push_concurrency":                 &hcldec.AttrSpec{Name: "push_concurrency", Type: cty.Number, Required: false},
+		"skip_if_exists":                   &hcldec.AttrSpec{Name: "skip_if_exists", Type: cty.Bool, Required: false},
+		"immutable_tags":                   &hcldec.AttrSpec{Name: "immutable_tags", Type: cty.Bool, Required: false},
+		"registries":                       &hcldec.BlockListSpec{TypeName: "registries", Nested: hcldec.ObjectSpec((*FlatRegistryConfig)(nil).HCL2Spec())},
+		"aws_access_key":                   &hcldec.AttrSpec{Name: "aws_access_key", Type: cty.String, Required: false},
+		"aws_secret_key":                   &hcldec.AttrSpec{Name: "aws_secret_key", Type: cty.String, Required: false},
+		"aws_token":                        &hcldec.AttrSpec{Name: "aws_token", Type: cty.String, Required: false},
+		"aws_profile":                      &hcldec.AttrSpec{Name: "aws_profile", Type: cty.String, Required: false},
+		"aws_web_identity_role_arn":        &hcldec.AttrSpec{Name: "aws_web_identity_role_arn", Type: cty.String, Required: false},
+		"aws_web_identity_token_file":      &hcldec.AttrSpec{Name: "aws_web_identity_token_file", Type: cty.String, Required: false},
+		"aws_web_identity_session_name":    &hcldec.AttrSpec{Name: "aws_web_identity_session_name", Type: cty.String, Required: false},
+		"aws_web_identity_duration":        &hcldec.AttrSpec{Name: "aws_web_identity_duration", Type: cty.String, Required: false},
+		"aws_force_use_public_ecr":         &hcldec.AttrSpec{Name: "aws_force_use_public_ecr", Type: cty.Bool, Required: false},
+		"ecr_endpoint":                     &hcldec.AttrSpec{Name: "ecr_endpoint", Type: cty.String, Required: false},
+		"docker_host":                      &hcldec.AttrSpec{Name: "docker_host", Type: cty.String, Required: false},
+		"docker_context":                   &hcldec.AttrSpec{Name: "docker_context", Type: cty.String, Required: false},
+		"tls_verify":                       &hcldec.AttrSpec{Name: "tls_verify", Type: cty.Bool, Required: false},
+		"cert_path":                        &hcldec.AttrSpec{Name: "cert_path", Type: cty.String, Required: false},
+		"ca_cert":                          &hcldec.AttrSpec{Name: "ca_cert", Type: cty.String, Required: false},
+		"client_cert":                      &hcldec.AttrSpec{Name: "client_cert", Type: cty.String, Required: false},
+		"client_key":                       &hcldec.AttrSpec{Name: "client_key", Type: cty.String, Required: false},
+		"docker_host_ssh_private_key_file": &hcldec.AttrSpec{Name: "docker_host_ssh_private_key_file", Type: cty.String, Required: false},
+		"docker_host_ssh_agent_socket":     &hcldec.AttrSpec{Name: "docker_host_ssh_agent_socket", Type: cty.String, Required: false},
+		"insecure_registries":              &hcldec.AttrSpec{Name: "insecure_registries", Type: cty.List(cty.String), Required: false},
+		"registry_ca_file":                 &hcldec.AttrSpec{Name: "registry_ca_file", Type: cty.String, Required: false},
+		"proxy_env":                        &hcldec.BlockSpec{TypeName: "proxy_env", Nested: hcldec.ObjectSpec((*docker.FlatProxyEnvConfig)(nil).HCL2Spec())},
 	}
 	return s
 }
@@ -7,8 +7,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-docker/builder/docker"
 	dockerimport "github.com/hashicorp/packer-plugin-docker/post-processor/docker-import"
@@ -214,3 +217,422 @@ func TestPostProcessor_PostProcess_digestWarning(t *testing.T) {
 		t.Fatal("bad image id")
 	}
 }
+
+func TestPostProcessor_Configure_pushRetries(t *testing.T) {
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"push_retries": 3}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.PushRetryDelay != defaultPushRetryDelay {
+		t.Fatalf("bad push_retry_delay: %s", p.config.PushRetryDelay)
+	}
+
+	for _, raw := range []map[string]interface{}{
+		{"push_retries": -1},
+		{"push_retry_delay": "-5s"},
+	} {
+		p := &PostProcessor{}
+		if err := p.Configure(raw); err == nil {
+			t.Fatalf("%v should be invalid", raw)
+		}
+	}
+}
+
+func TestPostProcessor_PostProcess_pushRetries(t *testing.T) {
+	driver := &docker.MockDriver{
+		PushErrs: []error{
+			fmt.Errorf("received unexpected HTTP status: 503 Service Unavailable"),
+			fmt.Errorf("net/http: TLS handshake timeout"),
+		},
+	}
+	p := &PostProcessor{Driver: driver}
+	p.config.PushRetries = 2
+	p.config.PushRetryDelay = time.Millisecond
+	artifact := &packersdk.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "foo/bar",
+	}
+
+	if _, _, _, err := p.PostProcess(context.Background(), testUi(), artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(driver.PushNames) != 3 {
+		t.Fatalf("should've pushed 3 times: %v", driver.PushNames)
+	}
+}
+
+func TestPostProcessor_PostProcess_pushRetriesPermanent(t *testing.T) {
+	driver := &docker.MockDriver{
+		PushErrs: []error{
+			fmt.Errorf("denied: requested access to the resource is denied"),
+		},
+	}
+	p := &PostProcessor{Driver: driver}
+	p.config.PushRetries = 2
+	p.config.PushRetryDelay = time.Millisecond
+	artifact := &packersdk.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "foo/bar",
+	}
+
+	if _, _, _, err := p.PostProcess(context.Background(), testUi(), artifact); err == nil {
+		t.Fatal("should've failed")
+	}
+	if len(driver.PushNames) != 1 {
+		t.Fatalf("permanent errors shouldn't be retried: %v", driver.PushNames)
+	}
+}
+
+func TestPostProcessor_PostProcess_pushConcurrency(t *testing.T) {
+	driver := &docker.MockDriver{}
+	p := &PostProcessor{Driver: driver}
+	p.config.PushConcurrency = 3
+	artifact := &packersdk.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "hashicorp/ubuntu",
+		StateValues: map[string]interface{}{
+			"docker_tags": []string{"hashicorp/ubuntu:1", "hashicorp/ubuntu:1.2", "hashicorp/ubuntu:1.2.3", "hashicorp/ubuntu:latest"},
+		},
+	}
+
+	if _, _, _, err := p.PostProcess(context.Background(), testUi(), artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	pushed := append([]string{}, driver.PushNames...)
+	sort.Strings(pushed)
+	expected := []string{"hashicorp/ubuntu", "hashicorp/ubuntu:1", "hashicorp/ubuntu:1.2", "hashicorp/ubuntu:1.2.3", "hashicorp/ubuntu:latest"}
+	if !reflect.DeepEqual(pushed, expected) {
+		t.Fatalf("bad pushes: %v", driver.PushNames)
+	}
+
+	driver = &docker.MockDriver{PushErr: fmt.Errorf("denied: requested access to the resource is denied")}
+	p.Driver = driver
+	if _, _, _, err := p.PostProcess(context.Background(), testUi(), artifact); err == nil {
+		t.Fatal("should've failed")
+	}
+	if len(driver.PushNames) != 5 {
+		t.Fatalf("the other pushes should run: %v", driver.PushNames)
+	}
+
+	p = &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"push_concurrency": -1}); err == nil {
+		t.Fatal("push_concurrency can't be negative")
+	}
+}
+
+func TestRepository(t *testing.T) {
+	cases := map[string]string{
+		"hashicorp/ubuntu":                      "hashicorp/ubuntu",
+		"hashicorp/ubuntu:precise":              "hashicorp/ubuntu",
+		"localhost:5000/ubuntu":                 "localhost:5000/ubuntu",
+		"localhost:5000/ubuntu:precise":         "localhost:5000/ubuntu",
+		"ghcr.io/example/app@sha256:1234":       "ghcr.io/example/app",
+		"ghcr.io/example/app:1.2.3@sha256:1234": "ghcr.io/example/app",
+	}
+	for name, expected := range cases {
+		if repo := repository(name); repo != expected {
+			t.Errorf("%s: bad repository %s", name, repo)
+		}
+	}
+}
+
+func TestPostProcessor_PostProcess_digests(t *testing.T) {
+	driver := &docker.MockDriver{
+		RepoDigestsResult: []string{"hashicorp/ubuntu@sha256:1234", "ghcr.io/hashicorp/ubuntu@sha256:5678"},
+	}
+	p := &PostProcessor{Driver: driver}
+	artifact := &packersdk.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "hashicorp/ubuntu",
+		StateValues: map[string]interface{}{
+			"docker_tags": []string{"hashicorp/ubuntu:precise", "ghcr.io/hashicorp/ubuntu:precise", "quay.io/hashicorp/ubuntu:precise"},
+		},
+	}
+
+	result, _, _, err := p.PostProcess(context.Background(), testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{
+		"hashicorp/ubuntu":                 "hashicorp/ubuntu@sha256:1234",
+		"hashicorp/ubuntu:precise":         "hashicorp/ubuntu@sha256:1234",
+		"ghcr.io/hashicorp/ubuntu:precise": "ghcr.io/hashicorp/ubuntu@sha256:5678",
+	}
+	if digests := result.State("docker_digests"); !reflect.DeepEqual(digests, expected) {
+		t.Fatalf("bad digests: %#v", digests)
+	}
+	generatedData := result.State("generated_data").(map[string]interface{})
+	if !reflect.DeepEqual(generatedData["Digests"], expected) {
+		t.Fatalf("bad generated data: %#v", generatedData)
+	}
+}
+
+func TestPostProcessor_PostProcess_skipIfExists(t *testing.T) {
+	driver := &docker.MockDriver{
+		RemoteImageExistsResult: map[string]bool{"hashicorp/ubuntu:1.0": true},
+	}
+	p := &PostProcessor{Driver: driver}
+	p.config.SkipIfExists = true
+	artifact := &packersdk.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "hashicorp/ubuntu",
+		StateValues: map[string]interface{}{
+			"docker_tags": []string{"hashicorp/ubuntu:1.0", "hashicorp/ubuntu:latest"},
+		},
+	}
+
+	if _, _, _, err := p.PostProcess(context.Background(), testUi(), artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(driver.PushNames, []string{"hashicorp/ubuntu", "hashicorp/ubuntu:latest"}) {
+		t.Fatalf("bad pushes: %v", driver.PushNames)
+	}
+
+	driver = &docker.MockDriver{RemoteImageExistsErr: fmt.Errorf("unauthorized")}
+	p.Driver = driver
+	if _, _, _, err := p.PostProcess(context.Background(), testUi(), artifact); err == nil {
+		t.Fatal("should fail when the registry can't be queried")
+	}
+	if driver.PushCalled {
+		t.Fatal("shouldn't push")
+	}
+}
+
+func TestPostProcessor_PostProcess_immutableTags(t *testing.T) {
+	artifact := &packersdk.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "hashicorp/ubuntu",
+		StateValues: map[string]interface{}{
+			"docker_tags": []string{"hashicorp/ubuntu:1.0", "hashicorp/ubuntu:latest"},
+		},
+	}
+
+	// The image was pushed as 1.0 by a previous run
+	driver := &docker.MockDriver{
+		RemoteDigestResult: map[string]string{"hashicorp/ubuntu:1.0": "sha256:1234"},
+		RepoDigestsResult:  []string{"hashicorp/ubuntu@sha256:1234"},
+	}
+	p := &PostProcessor{Driver: driver}
+	p.config.ImmutableTags = true
+	if _, _, _, err := p.PostProcess(context.Background(), testUi(), artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(driver.PushNames, []string{"hashicorp/ubuntu", "hashicorp/ubuntu:latest"}) {
+		t.Fatalf("bad pushes: %v", driver.PushNames)
+	}
+
+	// 1.0 is another image
+	driver = &docker.MockDriver{
+		RemoteDigestResult: map[string]string{"hashicorp/ubuntu:1.0": "sha256:5678"},
+		RepoDigestsResult:  []string{"hashicorp/ubuntu@sha256:1234"},
+	}
+	p.Driver = driver
+	_, _, _, err := p.PostProcess(context.Background(), testUi(), artifact)
+	if err == nil || !strings.Contains(err.Error(), "hashicorp/ubuntu:1.0 is already in the registry") {
+		t.Fatalf("should refuse to overwrite 1.0: %v", err)
+	}
+	if driver.PushCalled {
+		t.Fatal("shouldn't push anything")
+	}
+}
+
+func TestPostProcessor_Configure_immutableTags(t *testing.T) {
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"immutable_tags": true, "driver": "api"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p = &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"immutable_tags": true, "driver": "podman"}); err == nil {
+		t.Fatal("immutable_tags should not be supported by the podman driver")
+	}
+}
+
+func TestPostProcessor_PostProcess_registries(t *testing.T) {
+	driver := &docker.MockDriver{}
+	p := &PostProcessor{Driver: driver}
+	if err := p.Configure(map[string]interface{}{
+		"registries": []map[string]interface{}{
+			{"repository": "ghcr.io/example/app", "login": true, "login_username": "octocat", "login_password": "token"},
+			{"repository": "harbor.example.com/mirror/app"},
+		},
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	artifact := &packersdk.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "app",
+		StateValues: map[string]interface{}{
+			"docker_tags": []string{"app:1.0"},
+		},
+	}
+
+	if _, _, _, err := p.PostProcess(context.Background(), testUi(), artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"ghcr.io/example/app", "ghcr.io/example/app:1.0", "harbor.example.com/mirror/app", "harbor.example.com/mirror/app:1.0"}
+	if !reflect.DeepEqual(driver.TagImageRepo, expected) {
+		t.Fatalf("bad tags: %v", driver.TagImageRepo)
+	}
+	if !reflect.DeepEqual(driver.PushNames, expected) {
+		t.Fatalf("bad pushes: %v", driver.PushNames)
+	}
+	if !reflect.DeepEqual(driver.LoginRepos, []string{"ghcr.io"}) || driver.LogoutRepo != "ghcr.io" {
+		t.Fatalf("should only log in to ghcr.io: %v", driver.LoginRepos)
+	}
+}
+
+func TestPostProcessor_PostProcess_registriesCredentials(t *testing.T) {
+	driver := &docker.MockDriver{}
+	p := &PostProcessor{Driver: driver}
+	if err := p.Configure(map[string]interface{}{
+		"registries": []map[string]interface{}{
+			{"repository": "ghcr.io/example/app", "ghcr_login": true},
+		},
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	artifact := &packersdk.MockArtifact{BuilderIdValue: dockerimport.BuilderId, IdValue: "app"}
+
+	// The token fetched for a push isn't reused by the next one
+	for _, token := range []string{"first-token", "second-token"} {
+		t.Setenv("GITHUB_TOKEN", token)
+		if _, _, _, err := p.PostProcess(context.Background(), testUi(), artifact); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if driver.LoginPassword != token {
+			t.Fatalf("should've logged in with %s: %s", token, driver.LoginPassword)
+		}
+	}
+	if p.config.Registries[0].LoginPassword != "" {
+		t.Fatalf("the credentials shouldn't be set in the config: %#v", p.config.Registries[0])
+	}
+}
+
+func TestPostProcessor_Configure_registries(t *testing.T) {
+	for _, raw := range []map[string]interface{}{
+		{"registries": []map[string]interface{}{{"login": true}}},
+		{"login": true, "registries": []map[string]interface{}{{"repository": "ghcr.io/example/app"}}},
+	} {
+		p := &PostProcessor{}
+		if err := p.Configure(raw); err == nil {
+			t.Fatalf("%v should be invalid", raw)
+		}
+	}
+}
+
+func TestPostProcessor_Configure_garLogin(t *testing.T) {
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"gar_login": true, "login_server": "us-docker.pkg.dev"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// gcr_login is an alias of gar_login
+	p = &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"gcr_login": true, "login_server": "gcr.io"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if r := p.config.defaultRegistry(); r.cloudRegistry() != registryGAR {
+		t.Fatalf("gcr_login should log in to Artifact Registry: %#v", p.config)
+	}
+	r := RegistryConfig{Repository: "gcr.io/example/app", GcrLogin: true}
+	if errs := r.Prepare(); len(errs) > 0 || r.cloudRegistry() != registryGAR {
+		t.Fatalf("gcr_login should log in to Artifact Registry: %v", errs)
+	}
+
+	for _, raw := range []map[string]interface{}{
+		{"gar_login": true},
+		{"gar_login": true, "ecr_login": true, "login_server": "us-docker.pkg.dev"},
+		{"gar_login": true, "login_server": "us-docker.pkg.dev", "gar_key_file": "/nonexistent/key.json"},
+	} {
+		p := &PostProcessor{}
+		if err := p.Configure(raw); err == nil {
+			t.Fatalf("%v should be invalid", raw)
+		}
+	}
+}
+
+func TestPostProcessor_Configure_acrLogin(t *testing.T) {
+	for _, raw := range []map[string]interface{}{
+		{"acr_login": true, "login_server": "example.azurecr.io"},
+		{"acr_login": true, "login_server": "example.azurecr.io", "azure_client_id": "client"},
+		{"acr_login": true, "login_server": "example.azurecr.io", "azure_tenant_id": "tenant", "azure_client_id": "client",
+			"azure_client_secret": "secret"},
+	} {
+		p := &PostProcessor{}
+		if err := p.Configure(raw); err != nil {
+			t.Fatalf("%v: %s", raw, err)
+		}
+	}
+
+	for _, raw := range []map[string]interface{}{
+		{"acr_login": true},
+		{"acr_login": true, "gar_login": true, "login_server": "example.azurecr.io"},
+		{"acr_login": true, "login_server": "example.azurecr.io", "azure_client_secret": "secret"},
+		{"acr_login": true, "login_server": "example.azurecr.io", "azure_tenant_id": "tenant", "azure_client_id": "client"},
+		{"acr_login": true, "login_server": "example.azurecr.io", "registries": []map[string]interface{}{{"repository": "example.azurecr.io/app"}}},
+		{"azure_client_id": "client", "registries": []map[string]interface{}{{"repository": "example.azurecr.io/app", "acr_login": true}}},
+	} {
+		p := &PostProcessor{}
+		if err := p.Configure(raw); err == nil {
+			t.Fatalf("%v should be invalid", raw)
+		}
+	}
+}
+
+func TestPostProcessor_Configure_ghcrLogin(t *testing.T) {
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"ghcr_login": true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.LoginServer != "ghcr.io" {
+		t.Fatalf("login_server should default to ghcr.io: %q", p.config.LoginServer)
+	}
+
+	for _, raw := range []map[string]interface{}{
+		{"ghcr_login": true, "ecr_login": true},
+		{"ghcr_login": true, "registries": []map[string]interface{}{{"repository": "ghcr.io/example/app"}}},
+	} {
+		p := &PostProcessor{}
+		if err := p.Configure(raw); err == nil {
+			t.Fatalf("%v should be invalid", raw)
+		}
+	}
+}
+
+func TestPostProcessor_Configure_ecrPublic(t *testing.T) {
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"ecr_login": true, "aws_force_use_public_ecr": true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.LoginServer != "public.ecr.aws" {
+		t.Fatalf("login_server should default to public.ecr.aws: %q", p.config.LoginServer)
+	}
+
+	r := &RegistryConfig{Repository: "public.ecr.aws/a1b2c3/app", EcrLogin: true}
+	if errs := r.Prepare(); len(errs) > 0 {
+		t.Fatalf("errs: %v", errs)
+	}
+	if r.LoginServer != "public.ecr.aws" {
+		t.Fatalf("bad login server: %q", r.LoginServer)
+	}
+}
+
+func TestPostProcessor_Configure_registriesCredentials(t *testing.T) {
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{
+		"registries": []map[string]interface{}{
+			{"repository": "prod.azurecr.io/app", "acr_login": true, "azure_tenant_id": "tenant",
+				"azure_client_id": "prod", "azure_client_secret": "prod-secret"},
+			{"repository": "dev.azurecr.io/app", "acr_login": true, "azure_tenant_id": "tenant",
+				"azure_client_id": "dev", "azure_client_secret": "dev-secret"},
+		},
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.Registries[0].AzureClientID != "prod" || p.config.Registries[1].AzureClientID != "dev" {
+		t.Fatalf("each registry should have its own credentials: %#v", p.config.Registries)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type RegistryConfig
+
+package dockerpush
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-docker/builder/docker"
+)
+
+// RegistryConfig is a registry the image is pushed to, with the login
+// options of that registry. The names of the image are pushed to its
+// repository instead of their own, with their tags.
+type RegistryConfig struct {
+	Repository    string `mapstructure:"repository"`
+	Login         bool   `mapstructure:"login"`
+	LoginUsername string `mapstructure:"login_username"`
+	LoginPassword string `mapstructure:"login_password"`
+	LoginServer   string `mapstructure:"login_server"`
+	EcrLogin      bool   `mapstructure:"ecr_login"`
+	GarLogin      bool   `mapstructure:"gar_login"`
+	GcrLogin      bool   `mapstructure:"gcr_login"`
+	AcrLogin      bool   `mapstructure:"acr_login"`
+	GhcrLogin     bool   `mapstructure:"ghcr_login"`
+
+	// The credentials of the cloud registries, instead of the default ones
+	// of the environment.
+	GarKeyFile        string `mapstructure:"gar_key_file"`
+	AzureTenantID     string `mapstructure:"azure_tenant_id"`
+	AzureClientID     string `mapstructure:"azure_client_id"`
+	AzureClientSecret string `mapstructure:"azure_client_secret"`
+}
+
+// The names of the cloud registries the post-processor fetches short-lived
+// credentials for.
+const (
+	registryECR  = "ECR"
+	registryGAR  = "Artifact Registry"
+	registryACR  = "ACR"
+	registryGHCR = "GHCR"
+)
+
+// Prepare validates the options and sets the defaults.
+func (c *RegistryConfig) Prepare() []error {
+	var errs []error
+
+	if c.Repository == "" {
+		errs = append(errs, fmt.Errorf("repository is required"))
+	} else if name := c.Repository[strings.LastIndex(c.Repository, "/")+1:]; strings.ContainsAny(name, ":@") ||
+		c.Repository != strings.ToLower(c.Repository) {
+		errs = append(errs, fmt.Errorf("%q is not a repository, expected a lowercase name without a tag or digest, "+
+			"like `ghcr.io/example/app`", c.Repository))
+	}
+
+	// The login server defaults to the registry of the repository, or to
+	// Docker Hub, which the driver logs in to without a server.
+	if c.LoginServer == "" && c.Repository != "" {
+		if host := docker.ImageRegistryHost(c.Repository); host != "docker.io" {
+			c.LoginServer = host
+		}
+	}
+	errs = append(errs, c.prepareLogin()...)
+
+	return errs
+}
+
+// prepareLogin validates the login options.
+func (c *RegistryConfig) prepareLogin() []error {
+	var errs []error
+
+	c.GarLogin = c.GarLogin || c.GcrLogin
+
+	registries := c.cloudRegistries()
+	if len(registries) > 1 {
+		errs = append(errs, fmt.Errorf("only one of ecr_login, gar_login, acr_login and ghcr_login can be set, got %s", strings.Join(registries, ", ")))
+	} else if len(registries) == 1 && c.LoginServer == "" {
+		errs = append(errs, fmt.Errorf("%s login requires login server to be provided.", registries[0]))
+	}
+
+	if c.GarKeyFile != "" {
+		if _, err := os.Stat(c.GarKeyFile); err != nil {
+			errs = append(errs, fmt.Errorf("gar_key_file %q: %s", c.GarKeyFile, err))
+		}
+	}
+	if c.AzureClientSecret != "" && (c.AzureTenantID == "" || c.AzureClientID == "") {
+		errs = append(errs, fmt.Errorf("azure_client_secret requires azure_tenant_id and azure_client_id"))
+	}
+	if c.AzureTenantID != "" && c.AzureClientSecret == "" {
+		errs = append(errs, fmt.Errorf("azure_tenant_id requires azure_client_secret"))
+	}
+
+	return errs
+}
+
+// cloudRegistries returns the names of the cloud registries the config logs
+// in to with short-lived credentials.
+func (c *RegistryConfig) cloudRegistries() []string {
+	var registries []string
+	for _, registry := range []struct {
+		name    string
+		enabled bool
+	}{
+		{registryECR, c.EcrLogin},
+		{registryGAR, c.GarLogin},
+		{registryACR, c.AcrLogin},
+		{registryGHCR, c.GhcrLogin},
+	} {
+		if registry.enabled {
+			registries = append(registries, registry.name)
+		}
+	}
+	return registries
+}
+
+// cloudRegistry returns the name of the cloud registry to log in to with
+// short-lived credentials, or an empty string if none.
+func (c *RegistryConfig) cloudRegistry() string {
+	if registries := c.cloudRegistries(); len(registries) == 1 {
+		return registries[0]
+	}
+	return ""
+}
+
+// names returns the names of the image in the repository of the registry,
+// with the tags of the given names.
+func (c *RegistryConfig) names(names []string) []string {
+	rewritten := make([]string, 0, len(names))
+	for _, name := range names {
+		rewritten = append(rewritten, c.Repository+strings.TrimPrefix(name, repository(name)))
+	}
+	return rewritten
+}
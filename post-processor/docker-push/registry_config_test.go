@@ -0,0 +1,50 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package dockerpush
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistryConfig_Prepare(t *testing.T) {
+	c := RegistryConfig{Repository: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app", EcrLogin: true}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("err: %v", errs)
+	}
+	if c.LoginServer != "123456789012.dkr.ecr.us-east-1.amazonaws.com" {
+		t.Fatalf("bad login_server: %s", c.LoginServer)
+	}
+
+	c = RegistryConfig{Repository: "example/app", Login: true}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("err: %v", errs)
+	}
+	if c.LoginServer != "" {
+		t.Fatalf("Docker Hub should have no login_server: %s", c.LoginServer)
+	}
+
+	for _, c := range []RegistryConfig{
+		{},
+		{Repository: "ghcr.io/example/app:latest"},
+		{Repository: "ghcr.io/Example/App"},
+		{Repository: "example/app", EcrLogin: true},
+		{Repository: "us-docker.pkg.dev/example/app", GarLogin: true, GarKeyFile: "/nonexistent/key.json"},
+		{Repository: "example.azurecr.io/app", AcrLogin: true, AzureClientSecret: "secret"},
+		{Repository: "example.azurecr.io/app", AcrLogin: true, AzureTenantID: "tenant", AzureClientID: "client"},
+	} {
+		if errs := c.Prepare(); len(errs) == 0 {
+			t.Fatalf("%#v should be invalid", c)
+		}
+	}
+}
+
+func TestRegistryConfig_names(t *testing.T) {
+	c := RegistryConfig{Repository: "harbor.example.com/mirror/app"}
+	names := c.names([]string{"app", "app:1.0", "localhost:5000/app:latest"})
+	expected := []string{"harbor.example.com/mirror/app", "harbor.example.com/mirror/app:1.0", "harbor.example.com/mirror/app:latest"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad names: %v", names)
+	}
+}
@@ -26,8 +26,14 @@ type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
 	Executable string `mapstructure:"docker_path"`
+	DriverType string `mapstructure:"driver"`
 	Path       string `mapstructure:"path"`
 
+	Compression      string `mapstructure:"compression"`
+	CompressionLevel int    `mapstructure:"compression_level"`
+
+	docker.DockerHostConfig `mapstructure:",squash"`
+
 	ctx interpolate.Context
 }
 
@@ -52,8 +58,20 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	if err := docker.ValidateDriverType(p.config.DriverType); err != nil {
+		return err
+	}
+
+	if errs := p.config.DockerHostConfig.Prepare(p.config.DriverType); len(errs) > 0 {
+		return &packersdk.MultiError{Errors: errs}
+	}
+
+	if err := docker.ValidateCompression(p.config.Compression, p.config.CompressionLevel); err != nil {
+		return fmt.Errorf("compression: %s", err)
+	}
+
 	if p.config.Executable == "" {
-		p.config.Executable = "docker"
+		p.config.Executable = docker.DefaultExecutable(p.config.DriverType)
 	}
 
 	return nil
@@ -81,22 +99,43 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	driver := p.Driver
 	if driver == nil {
 		// If no driver is set, then we use the real driver
-		driver = &docker.DockerDriver{
-			Executable: p.config.Executable,
-			Ctx:        &p.config.ctx,
-			Ui:         ui,
+		host, stopSSHAgent, err := p.config.DockerHostConfig.StartSSHAgent()
+		if err != nil {
+			return nil, false, false, err
+		}
+		defer stopSSHAgent()
+
+		driver, err = docker.NewDriver(p.config.DriverType, p.config.Executable, "", host, docker.RegistryTLSConfig{}, docker.ProxyEnvConfig{}, &p.config.ctx, ui)
+		if err != nil {
+			return nil, false, false, err
 		}
 	}
 
 	ui.Message("Saving image: " + artifact.Id())
 
-	if err := driver.SaveImage(artifact.Id(), f); err != nil {
+	w, err := docker.CompressWriter(f, p.config.Compression, p.config.CompressionLevel)
+	if err != nil {
 		f.Close()
 		os.Remove(f.Name())
 
 		return nil, false, false, err
 	}
 
+	if err := driver.SaveImage(artifact.Id(), w); err != nil {
+		w.Close()
+		f.Close()
+		os.Remove(f.Name())
+
+		return nil, false, false, err
+	}
+
+	if err := w.Close(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return nil, false, false, fmt.Errorf("Error compressing the image: %s", err)
+	}
+
 	f.Close()
 	ui.Message("Saved to: " + path)
 
@@ -12,3 +12,22 @@ import (
 func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
 	var _ packersdk.PostProcessor = new(PostProcessor)
 }
+
+func TestPostProcessorConfigure_compression(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{
+		"path":              "image.tar.zst",
+		"compression":       "zstd",
+		"compression_level": 19,
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{
+		"path":        "image.tar.xz",
+		"compression": "xz",
+	}); err == nil {
+		t.Fatal("should error with an unknown compression")
+	}
+}
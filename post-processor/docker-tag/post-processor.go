@@ -8,6 +8,8 @@ package dockertag
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-docker/builder/docker"
@@ -24,12 +26,15 @@ type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
 	Executable string `mapstructure:"docker_path"`
+	DriverType string `mapstructure:"driver"`
 	Repository string `mapstructure:"repository"`
 	// Kept for backwards compatibility
 	Tag   []string `mapstructure:"tag"`
 	Tags  []string `mapstructure:"tags"`
 	Force bool
 
+	docker.DockerHostConfig `mapstructure:",squash"`
+
 	ctx interpolate.Context
 }
 
@@ -60,8 +65,16 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 
 	p.config.Tags = allTags
 
+	if err := docker.ValidateDriverType(p.config.DriverType); err != nil {
+		return err
+	}
+
+	if errs := p.config.DockerHostConfig.Prepare(p.config.DriverType); len(errs) > 0 {
+		return &packersdk.MultiError{Errors: errs}
+	}
+
 	if p.config.Executable == "" {
-		p.config.Executable = "docker"
+		p.config.Executable = docker.DefaultExecutable(p.config.DriverType)
 	}
 
 	return nil
@@ -86,10 +99,15 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	driver := p.Driver
 	if driver == nil {
 		// If no driver is set, then we use the real driver
-		driver = &docker.DockerDriver{
-			Executable: p.config.Executable,
-			Ctx:        &p.config.ctx,
-			Ui:         ui,
+		host, stopSSHAgent, err := p.config.DockerHostConfig.StartSSHAgent()
+		if err != nil {
+			return nil, false, false, err
+		}
+		defer stopSSHAgent()
+
+		driver, err = docker.NewDriver(p.config.DriverType, p.config.Executable, "", host, docker.RegistryTLSConfig{}, docker.ProxyEnvConfig{}, &p.config.ctx, ui)
+		if err != nil {
+			return nil, false, false, err
 		}
 	}
 
@@ -120,9 +138,44 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 		}
 	}
 
+	// If the image was built for several platforms, tag the image of each
+	// platform too, with the platform appended to the tag, so all of them
+	// can be pushed afterwards.
+	platformImages := loadPlatformImages(artifact)
+	if len(platformImages) > 0 {
+		repos := RepoTags
+		if len(repos) == 0 {
+			repos = []string{importRepo}
+		}
+
+		platforms := make([]string, 0, len(platformImages))
+		for platform := range platformImages {
+			platforms = append(platforms, platform)
+		}
+		sort.Strings(platforms)
+
+		for _, platform := range platforms {
+			for _, repo := range repos {
+				local := platformRepo(repo, platform)
+				ui.Message("Tagging image: " + platformImages[platform])
+				ui.Message("Repository: " + local)
+
+				err := driver.TagImage(platformImages[platform], local, p.config.Force)
+				if err != nil {
+					return nil, false, true, err
+				}
+
+				RepoTags = append(RepoTags, local)
+			}
+		}
+	}
+
 	// If artifact is a docker input artifact, re-store the state data.
 	// Otherwise, write what we want to the state data.
 	stateData := map[string]interface{}{"docker_tags": RepoTags}
+	if len(platformImages) > 0 {
+		stateData["platform_images"] = platformImages
+	}
 
 	// Update the state's generated data with the digest, if it exists, and
 	// continue.
@@ -151,3 +204,43 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	// tag. Override users to force us to always keep the input artifact.
 	return artifact, true, true, nil
 }
+
+// loadPlatformImages returns the image ID for each platform the artifact was
+// built for, if the docker builder was configured with `build.platforms`.
+func loadPlatformImages(artifact packersdk.Artifact) map[string]string {
+	images := map[string]string{}
+	switch t := artifact.State("platform_images").(type) {
+	case map[string]string:
+		for platform, id := range t {
+			images[platform] = id
+		}
+	case map[string]interface{}:
+		for platform, id := range t {
+			if i, ok := id.(string); ok {
+				images[platform] = i
+			}
+		}
+	case map[interface{}]interface{}:
+		// The RPC turns our original map[string]string into a
+		// map[interface]interface so we need to turn it back
+		for platform, id := range t {
+			p, pok := platform.(string)
+			i, iok := id.(string)
+			if pok && iok {
+				images[p] = i
+			}
+		}
+	}
+	return images
+}
+
+// platformRepo appends the platform to the tag of repo, or uses it as the
+// tag if repo has none, e.g. `foo:1.0` becomes `foo:1.0-linux-arm64` for the
+// `linux/arm64` platform.
+func platformRepo(repo, platform string) string {
+	suffix := strings.ReplaceAll(platform, "/", "-")
+	if strings.LastIndex(repo, ":") > strings.LastIndex(repo, "/") {
+		return repo + "-" + suffix
+	}
+	return repo + ":" + suffix
+}
@@ -179,3 +179,63 @@ func TestPostProcessor_PostProcess_Tag_vs_Tags(t *testing.T) {
 			p.config.Tags)
 	}
 }
+
+func TestPostProcessor_PostProcess_PlatformImages(t *testing.T) {
+	driver := &docker.MockDriver{}
+	p := &PostProcessor{Driver: driver}
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packersdk.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "1234567890abcdef",
+		StateValues: map[string]interface{}{
+			"platform_images": map[interface{}]interface{}{
+				"linux/amd64": "1234567890abcdef",
+				"linux/arm64": "fedcba0987654321",
+			},
+		},
+	}
+
+	result, _, _, err := p.PostProcess(context.Background(), testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if driver.TagImageCalled != 6 {
+		t.Fatalf("should call TagImage 6 times, called %d times", driver.TagImageCalled)
+	}
+
+	assert.Equal(t, []string{
+		"foo:bar",
+		"foo:buzz",
+		"foo:bar-linux-amd64",
+		"foo:buzz-linux-amd64",
+		"foo:bar-linux-arm64",
+		"foo:buzz-linux-arm64",
+	}, result.State("docker_tags"))
+}
+
+func TestPlatformRepo(t *testing.T) {
+	assert.Equal(t, "foo:linux-arm64", platformRepo("foo", "linux/arm64"))
+	assert.Equal(t, "foo:1.0-linux-arm64", platformRepo("foo:1.0", "linux/arm64"))
+	assert.Equal(t, "localhost:5000/foo:linux-amd64", platformRepo("localhost:5000/foo", "linux/amd64"))
+}
+
+func TestPostProcessor_Configure_Driver(t *testing.T) {
+	config := testConfig()
+	config["driver"] = "podman"
+
+	p := &PostProcessor{}
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assert.Equal(t, "podman", p.config.Executable, "podman driver should default docker_path to podman")
+
+	config["driver"] = "bogus"
+	p = &PostProcessor{}
+	if err := p.Configure(config); err == nil {
+		t.Fatal("expected an error for an unknown driver")
+	}
+}
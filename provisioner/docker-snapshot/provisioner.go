@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package dockersnapshot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-docker/builder/docker"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The name of the snapshot, used as its tag in the snapshot repository
+	// of the docker builder.
+	Name string `mapstructure:"name"`
+
+	ctx interpolate.Context
+}
+
+type Provisioner struct {
+	config Config
+}
+
+func (p *Provisioner) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *Provisioner) Prepare(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "docker-snapshot",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.Name == "" {
+		return fmt.Errorf("`name` must be set")
+	}
+
+	return docker.ValidateSnapshotName(p.config.Name)
+}
+
+// Provision asks the communicator of the docker builder to snapshot the
+// container. The snapshot is taken by the builder, which owns the
+// container, so this only works with the docker communicator.
+func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, _ map[string]interface{}) error {
+	ui.Say(fmt.Sprintf("Taking snapshot %s", p.config.Name))
+
+	cmd := &packersdk.RemoteCmd{Command: docker.SnapshotCommand + " " + p.config.Name}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		return fmt.Errorf("Error taking snapshot %s: %s", p.config.Name, err)
+	}
+	if cmd.ExitStatus() != 0 {
+		return fmt.Errorf("Error taking snapshot %s: the docker-snapshot provisioner only works with the docker builder and communicator", p.config.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package dockersnapshot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-docker/builder/docker"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestProvisioner_impl(t *testing.T) {
+	var _ packersdk.Provisioner = new(Provisioner)
+}
+
+func TestProvisioner_Prepare(t *testing.T) {
+	if err := new(Provisioner).Prepare(map[string]interface{}{}); err == nil {
+		t.Fatal("name should be required")
+	}
+	if err := new(Provisioner).Prepare(map[string]interface{}{"name": "after deps"}); err == nil {
+		t.Fatal("name should be a valid tag")
+	}
+	if err := new(Provisioner).Prepare(map[string]interface{}{"name": "after-deps"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestProvisioner_Provision(t *testing.T) {
+	p := new(Provisioner)
+	if err := p.Prepare(map[string]interface{}{"name": "after-deps"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := new(packersdk.MockCommunicator)
+	if err := p.Provision(context.Background(), packersdk.TestUi(t), comm, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if comm.StartCmd.Command != docker.SnapshotCommand+" after-deps" {
+		t.Fatalf("bad command: %q", comm.StartCmd.Command)
+	}
+
+	comm = &packersdk.MockCommunicator{StartExitStatus: 127}
+	if err := p.Provision(context.Background(), packersdk.TestUi(t), comm, nil); err == nil {
+		t.Fatal("should fail when the communicator can't take snapshots")
+	}
+}